@@ -0,0 +1,131 @@
+"""Streams agent progress to live clients through the fanout service.
+
+Events are published to `workflow:events:{username}`, the channel the fanout
+service relays to WebSocket clients, while the agent node is still running:
+
+    agent_token  {"type", "run_id", "node_id", "job_id", "seq", "text", "timestamp"}
+    agent_step   {"type", "run_id", "node_id", "job_id", "seq", "step", "tool",
+                  "status", "timestamp"}
+
+`seq` increases by one per event within a job, so clients can order events and
+notice gaps. `step` is "tool_call" before a tool runs and "tool_result" after,
+with `status` set on results.
+
+Text is coalesced so a token-by-token LLM stream doesn't flood the socket:
+chunks are buffered until `min_chars` are pending or `flush_interval` seconds
+have passed since the first buffered chunk. Pending text is flushed before
+every step and on close, so events keep the order the agent produced them in.
+"""
+import logging
+import time
+from typing import Any, Callable, Dict, Optional
+
+logger = logging.getLogger(__name__)
+
+# Coalescing defaults: roughly a sentence, or a quarter second of tokens
+DEFAULT_MIN_CHARS = 64
+DEFAULT_FLUSH_INTERVAL = 0.25
+
+
+class AgentEventStream:
+    """Publishes a job's text and tool steps as ordered, coalesced events."""
+
+    def __init__(
+        self,
+        publish: Callable[[Dict[str, Any]], None],
+        run_id: str,
+        node_id: str,
+        job_id: Optional[str] = None,
+        min_chars: int = DEFAULT_MIN_CHARS,
+        flush_interval: float = DEFAULT_FLUSH_INTERVAL,
+        clock: Callable[[], float] = time.monotonic,
+    ):
+        """Initialize the stream.
+
+        Args:
+            publish: Sends one event to the user's workflow events channel
+            run_id: Workflow run ID
+            node_id: Agent node being executed
+            job_id: Job identifier, if known
+            min_chars: Pending text size that triggers a flush
+            flush_interval: Seconds text may stay buffered before a flush
+            clock: Monotonic clock, replaceable in tests
+        """
+        self.publish = publish
+        self.run_id = run_id
+        self.node_id = node_id
+        self.job_id = job_id
+        self.min_chars = min_chars
+        self.flush_interval = flush_interval
+        self.clock = clock
+
+        self.seq = 0
+        self._pending = []
+        self._pending_chars = 0
+        self._pending_since = None
+
+    def text(self, chunk: str):
+        """Buffer a chunk of agent text, flushing once the policy allows.
+
+        Args:
+            chunk: Text as emitted by the LLM
+        """
+        if not chunk:
+            return
+
+        if not self._pending:
+            self._pending_since = self.clock()
+        self._pending.append(chunk)
+        self._pending_chars += len(chunk)
+
+        if (self._pending_chars >= self.min_chars or
+                self.clock() - self._pending_since >= self.flush_interval):
+            self.flush()
+
+    def step(self, step: str, tool: str, status: Optional[str] = None):
+        """Publish a tool step, after any text that preceded it.
+
+        Args:
+            step: "tool_call" or "tool_result"
+            tool: Tool name
+            status: Outcome of a tool_result (e.g. "success", "error")
+        """
+        self.flush()
+
+        event = {"step": step, "tool": tool}
+        if status:
+            event["status"] = status
+        self._emit("agent_step", event)
+
+    def flush(self):
+        """Publish pending text as a single agent_token event."""
+        if not self._pending:
+            return
+
+        text = "".join(self._pending)
+        self._pending = []
+        self._pending_chars = 0
+        self._pending_since = None
+        self._emit("agent_token", {"text": text})
+
+    def close(self):
+        """Flush remaining text; call once the agent is done."""
+        self.flush()
+
+    def _emit(self, event_type: str, fields: Dict[str, Any]):
+        self.seq += 1
+        event = {
+            "type": event_type,
+            "run_id": self.run_id,
+            "node_id": self.node_id,
+            "job_id": self.job_id,
+            "seq": self.seq,
+            "timestamp": int(time.time()),
+        }
+        event.update(fields)
+
+        # Best effort: the node's result still arrives through the completion signal
+        try:
+            self.publish(event)
+        except Exception as e:
+            logger.warning(f"Failed to publish {event_type} event: {e}")
@@ -1,6 +1,6 @@
 """OpenAI LLM client with prompt caching and connection pooling."""
 from openai import OpenAI
-from typing import Dict, Any, List, Optional
+from typing import Callable, Dict, Any, List, Optional
 import logging
 import time
 import httpx
@@ -61,12 +61,15 @@ class LLMClient:
 
         logger.info(f"LLM client initialized with model: {self.model}, connection pooling enabled")
 
-    def chat(self, user_prompt: str, context: Optional[Dict[str, Any]] = None) -> Dict[str, Any]:
+    def chat(self, user_prompt: str, context: Optional[Dict[str, Any]] = None,
+             on_text: Optional[Callable[[str], None]] = None) -> Dict[str, Any]:
         """Send chat request to LLM with function calling.
 
         Args:
             user_prompt: User's natural language instruction
             context: Optional context (previous results, session info, current_workflow)
+            on_text: Optional callback; when set the response is streamed and
+                each text delta is passed to it as it arrives
 
         Returns:
             Dictionary with tool calls and metadata
@@ -94,7 +97,7 @@ class LLMClient:
         try:
             logger.info(f"Calling OpenAI with prompt: {user_prompt[:100]}...")
 
-            response = self.client.chat.completions.create(
+            request = dict(
                 model='gpt-5-mini',
                 messages=messages,
                 tools=self.tools,
@@ -104,24 +107,37 @@ class LLMClient:
                 timeout=self.timeout
             )
 
-            execution_time = int((time.time() - start_time) * 1000)
-
-            # Extract tool calls
-            message = response.choices[0].message
-            tool_calls = []
+            if on_text:
+                content, tool_calls, usage, finish_reason = self._collect_stream(
+                    self.client.chat.completions.create(
+                        **request, stream=True, stream_options={"include_usage": True}
+                    ),
+                    on_text
+                )
+            else:
+                response = self.client.chat.completions.create(**request)
+
+                # Extract tool calls
+                message = response.choices[0].message
+                content = message.content
+                tool_calls = []
+
+                if message.tool_calls:
+                    for tool_call in message.tool_calls:
+                        tool_calls.append({
+                            "id": tool_call.id,
+                            "function": {
+                                "name": tool_call.function.name,
+                                "arguments": tool_call.function.arguments
+                            }
+                        })
+
+                usage = response.usage
+                finish_reason = response.choices[0].finish_reason
 
-            if message.tool_calls:
-                for tool_call in message.tool_calls:
-                    tool_calls.append({
-                        "id": tool_call.id,
-                        "function": {
-                            "name": tool_call.function.name,
-                            "arguments": tool_call.function.arguments
-                        }
-                    })
+            execution_time = int((time.time() - start_time) * 1000)
 
             # Get usage stats
-            usage = response.usage
             tokens_used = usage.total_tokens if usage else 0
 
             # Check if cache was hit (OpenAI doesn't expose this directly yet,
@@ -131,12 +147,12 @@ class LLMClient:
 
             result = {
                 "tool_calls": tool_calls,
-                "message": message.content if message.content else "",
+                "message": content if content else "",
                 "tokens_used": tokens_used,
                 "cache_hit": cache_hit,
                 "execution_time_ms": execution_time,
                 "model": self.model,
-                "finish_reason": response.choices[0].finish_reason
+                "finish_reason": finish_reason
             }
 
             logger.info(f"LLM response: {len(tool_calls)} tool calls, {tokens_used} tokens, {execution_time}ms")
@@ -147,6 +163,55 @@ class LLMClient:
             logger.error(f"LLM request failed: {e}")
             raise
 
+    def _collect_stream(self, stream, on_text: Callable[[str], None]):
+        """Assemble a streamed response, passing text deltas to on_text.
+
+        Tool call arguments arrive in fragments keyed by the call's index and
+        are joined here; the usage stats come in a final chunk without choices.
+
+        Args:
+            stream: Iterator of chat completion chunks
+            on_text: Callback for each text delta
+
+        Returns:
+            Tuple of (content, tool_calls, usage, finish_reason)
+        """
+        content_parts = []
+        calls = {}
+        usage = None
+        finish_reason = None
+
+        for chunk in stream:
+            if getattr(chunk, 'usage', None):
+                usage = chunk.usage
+            if not chunk.choices:
+                continue
+
+            choice = chunk.choices[0]
+            delta = choice.delta
+            if choice.finish_reason:
+                finish_reason = choice.finish_reason
+
+            if delta.content:
+                content_parts.append(delta.content)
+                on_text(delta.content)
+
+            for fragment in delta.tool_calls or []:
+                call = calls.setdefault(fragment.index, {
+                    "id": None,
+                    "function": {"name": "", "arguments": ""}
+                })
+                if fragment.id:
+                    call["id"] = fragment.id
+                if fragment.function:
+                    if fragment.function.name:
+                        call["function"]["name"] += fragment.function.name
+                    if fragment.function.arguments:
+                        call["function"]["arguments"] += fragment.function.arguments
+
+        tool_calls = [calls[i] for i in sorted(calls)]
+        return "".join(content_parts), tool_calls, usage, finish_reason
+
     def _build_user_message(self, prompt: str, context: Optional[Dict[str, Any]]) -> str:
         """Build user message with context.
 
@@ -27,6 +27,7 @@ import uvicorn
 from agent.llm_client import LLMClient
 from agent.workflow_schema import WorkflowSchema
 from agent.intent_classifier import IntentClassifier
+from agent.event_stream import AgentEventStream, DEFAULT_MIN_CHARS, DEFAULT_FLUSH_INTERVAL
 from storage.memory import MemoryStorage
 from storage.redis_client import RedisClient
 from pipeline.executor import execute_pipeline_tool
@@ -81,6 +82,9 @@ class AgentService:
         # Storage config
         self.storage_config = config['storage']
 
+        # Live agent output relayed by the fanout service
+        self.streaming_config = config.get('streaming') or {}
+
         logger.info(f"Agent service initialized with {self.num_workers} workers")
 
     def start(self):
@@ -171,6 +175,16 @@ class AgentService:
             logger.error(f"Invalid job: missing required fields: {', '.join(missing_fields)}")
             return
 
+        self.redis.record_node_started(run_id, node_id, start_time)
+
+        # Node logs are readable through the run's logs endpoint
+        def node_log(level: str, message: str, **fields):
+            self.redis.append_node_log(run_id, node_id, level, message, fields)
+
+        node_log('info', 'processing agent task', job_id=job_id)
+
+        events = self._event_stream(job)
+
         try:
             # Enhance context with current workflow if provided in job
             enhanced_context = context.copy() if context else {}
@@ -190,16 +204,19 @@ class AgentService:
             logger.info(f"Intent classified: {intent_result['intent']} "
                        f"(confidence: {intent_result['confidence']:.2f}) - "
                        f"{intent_result['reasoning']}")
+            node_log('info', 'intent classified',
+                     intent=intent_result['intent'], confidence=intent_result['confidence'])
 
             # Store intent classification in context for potential use
             enhanced_context['intent_classification'] = intent_result
 
             # Call LLM with tools
             logger.info(f"Calling LLM for job {job_id}")
-            llm_result = self.llm.chat(task, enhanced_context)
+            llm_result = self.llm.chat(task, enhanced_context, on_text=events.text)
 
             tool_calls = llm_result.get('tool_calls', [])
             logger.info(f"LLM returned {len(tool_calls)} tool calls")
+            node_log('info', 'LLM responded', tool_calls=len(tool_calls), model=llm_result.get('model'))
 
             # Handle cases with or without tool calls
             if not tool_calls:
@@ -219,13 +236,25 @@ class AgentService:
 
                 if expected_intent == 'patch' and chosen_tool != 'patch_workflow':
                     logger.warning(f"Intent mismatch: classified as 'patch' but LLM chose '{chosen_tool}'")
+                    node_log('warn', 'intent mismatch', intent=expected_intent, tool=chosen_tool)
                 elif expected_intent == 'execute' and chosen_tool != 'execute_pipeline':
                     logger.warning(f"Intent mismatch: classified as 'execute' but LLM chose '{chosen_tool}'")
+                    node_log('warn', 'intent mismatch', intent=expected_intent, tool=chosen_tool)
 
                 # For MVP, we'll execute the first tool call
                 # In production, we might need to handle multiple tool calls in sequence
                 tool_call = tool_calls[0]
-                result_data = self._execute_tool(job, tool_call)
+                tool_name = tool_call.get('function', {}).get('name')
+                events.step("tool_call", tool_name)
+                node_log('info', 'executing tool', tool=tool_name)
+                try:
+                    result_data = self._execute_tool(job, tool_call)
+                except Exception:
+                    events.step("tool_result", tool_name, status="error")
+                    raise
+                events.step("tool_result", tool_name, status=result_data.get('status', 'completed'))
+
+            events.close()
 
             # Finalize runtime metrics
             end_time = time.time()
@@ -288,13 +317,16 @@ class AgentService:
             })
 
             logger.info(f"Job {job_id} completed successfully")
+            node_log('info', 'agent task completed', execution_time_ms=metrics_dict['execution_time_ms'])
 
             # ACK message from stream
             if job.get('message_id'):
-                self.redis.ack_message(job['message_id'])
+                self.redis.ack_message(job['message_id'], job.get('stream'))
 
         except Exception as e:
             logger.error(f"Job {job_id} failed: {e}", exc_info=True)
+            node_log('error', 'agent task failed', error_type=type(e).__name__, error=str(e))
+            events.close()
 
             # Finalize metrics even on failure
             end_time = time.time()
@@ -305,7 +337,7 @@ class AgentService:
 
             # ACK message even on failure to remove from pending
             if job.get('message_id'):
-                self.redis.ack_message(job['message_id'])
+                self.redis.ack_message(job['message_id'], job.get('stream'))
 
             # Signal failure to coordinator (new architecture)
             failure_signal = {
@@ -336,6 +368,31 @@ class AgentService:
                 }
             })
 
+    def _event_stream(self, job: Dict[str, Any]) -> AgentEventStream:
+        """Create the stream that publishes a job's live output.
+
+        Args:
+            job: Job dictionary from Redis queue
+
+        Returns:
+            Event stream for the job (publishes nothing when streaming is disabled)
+        """
+        username = job.get('workflow_owner')
+        enabled = self.streaming_config.get('enabled', True) and username
+
+        def publish(event: Dict[str, Any]):
+            if enabled:
+                self.redis.publish_workflow_event(username, event)
+
+        return AgentEventStream(
+            publish,
+            run_id=job.get('run_id'),
+            node_id=job.get('node_id'),
+            job_id=job.get('job_id'),
+            min_chars=self.streaming_config.get('min_chars', DEFAULT_MIN_CHARS),
+            flush_interval=self.streaming_config.get('flush_interval_sec', DEFAULT_FLUSH_INTERVAL)
+        )
+
     def _execute_tool(self, job: Dict[str, Any], tool_call: Dict[str, Any]) -> Dict[str, Any]:
         """Execute a tool call.
 
@@ -4,9 +4,15 @@ import json
 from typing import Optional, Dict, Any
 import logging
 import uuid
+from datetime import datetime, timezone
 
 logger = logging.getLogger(__name__)
 
+# Node log limits and run state lifetime, as in common/sdk
+NODE_LOG_MAX_ENTRIES = 1000
+NODE_LOG_MAX_MESSAGE_LENGTH = 4096
+RUN_STATE_TTL_SECONDS = 24 * 60 * 60
+
 
 class RedisClient:
     """Redis client for job queue (streams) and result publishing."""
@@ -24,6 +30,12 @@ class RedisClient:
         self.stream = config.get('stream', 'wf.tasks.agent')
         self.consumer_group = config.get('consumer_group', 'agent_workers')
         self.consumer_name = f"agent_worker_{uuid.uuid4().hex[:8]}"
+        # Priority variants of the stream, most urgent first (see common/redis/priority.go)
+        self.streams = [f"{{{self.stream}}}.high", self.stream, f"{{{self.stream}}}.low"]
+        # Messages claimed by a blocking read beyond the one returned, as
+        # (stream, message_id, message_data). They're pending on this consumer,
+        # so they must be handled here: nothing else would reclaim them.
+        self._buffered = []
         self.timeout = config.get('timeout', 5) * 1000  # Convert to milliseconds
 
         # Backward compatibility: legacy queue names
@@ -38,49 +50,79 @@ class RedisClient:
             logger.error(f"Failed to connect to Redis: {e}")
             raise
 
-        # Create consumer group if it doesn't exist
-        try:
-            self.client.xgroup_create(self.stream, self.consumer_group, id='0', mkstream=True)
-            logger.info(f"Created consumer group {self.consumer_group} for stream {self.stream}")
-        except redis.ResponseError as e:
-            if "BUSYGROUP" not in str(e):
-                logger.error(f"Failed to create consumer group: {e}")
-                raise
-            # Group already exists, continue
-            logger.info(f"Consumer group {self.consumer_group} already exists")
+        # Create consumer groups if they don't exist
+        for stream in self.streams:
+            try:
+                self.client.xgroup_create(stream, self.consumer_group, id='0', mkstream=True)
+                logger.info(f"Created consumer group {self.consumer_group} for stream {stream}")
+            except redis.ResponseError as e:
+                if "BUSYGROUP" not in str(e):
+                    logger.error(f"Failed to create consumer group: {e}")
+                    raise
+                # Group already exists, continue
+                logger.info(f"Consumer group {self.consumer_group} already exists for stream {stream}")
 
-    def pop_job(self) -> Optional[Dict[str, Any]]:
-        """Pop a job from the stream (blocking with XREADGROUP).
+    def _read_next(self):
+        """Read one message, most urgent priority first.
+
+        Buffered messages are served first. Otherwise each priority stream is
+        checked without blocking, then the read blocks on all of them. The
+        blocking read may claim one message per stream; the ones not returned
+        are buffered for the next calls.
 
         Returns:
-            Job dictionary or None if timeout
+            (stream, message_id, message_data), or None if nothing arrived
         """
-        try:
-            # Read from stream using consumer group
+        if self._buffered:
+            return self._buffered.pop(0)
+
+        for stream in self.streams:
             messages = self.client.xreadgroup(
                 groupname=self.consumer_group,
                 consumername=self.consumer_name,
-                streams={self.stream: '>'},
-                count=1,
-                block=self.timeout
+                streams={stream: '>'},
+                count=1
             )
+            if messages and messages[0][1]:
+                message_id, message_data = messages[0][1][0]
+                return messages[0][0], message_id, message_data
 
-            if not messages:
-                return None
+        messages = self.client.xreadgroup(
+            groupname=self.consumer_group,
+            consumername=self.consumer_name,
+            streams={stream: '>' for stream in self.streams},
+            count=1,
+            block=self.timeout
+        )
+        for stream_name, message_list in messages or []:
+            for message_id, message_data in message_list:
+                self._buffered.append((stream_name, message_id, message_data))
+        self._buffered.sort(key=lambda message: self.streams.index(message[0]))
 
-            # Extract message from stream
-            stream_name, message_list = messages[0]
-            if not message_list:
+        if not self._buffered:
+            return None
+        return self._buffered.pop(0)
+
+    def pop_job(self) -> Optional[Dict[str, Any]]:
+        """Pop a job from the stream (blocking with XREADGROUP).
+
+        Returns:
+            Job dictionary or None if timeout
+        """
+        try:
+            # Read from the priority streams using consumer group
+            message = self._read_next()
+            if not message:
                 return None
 
-            message_id, message_data = message_list[0]
+            stream_name, message_id, message_data = message
 
             # Parse token from message
             token_json = message_data.get('token')
             if not token_json:
                 logger.error(f"Message {message_id} missing token field")
                 # ACK the message to remove it from pending
-                self.client.xack(self.stream, self.consumer_group, message_id)
+                self.client.xack(stream_name, self.consumer_group, message_id)
                 return None
 
             token = json.loads(token_json)
@@ -119,7 +161,8 @@ class RedisClient:
                 'current_workflow': current_workflow,  # Fetched from Redis IR
                 'current_node_id': token.get('to_node'),  # The node that will execute (for patch edge creation)
                 'token': token,  # Store full token for later
-                'message_id': message_id  # Store for ACK
+                'message_id': message_id,  # Store for ACK
+                'stream': stream_name  # Priority stream the message came from
             }
 
             logger.info(f"Converted job: job_id={job.get('job_id')}, task='{job.get('task')}', "
@@ -168,14 +211,82 @@ class RedisClient:
             logger.error(f"Failed to signal completion: {e}")
             raise
 
-    def ack_message(self, message_id: str):
+    def record_node_started(self, run_id: str, node_id: str, started_at: float):
+        """Record when execution of a node started (see sdk.RecordNodeStarted).
+
+        Best effort: the timeline in run details falls back to the metrics.
+
+        Args:
+            run_id: Workflow run ID
+            node_id: Node being executed
+            started_at: Unix timestamp
+        """
+        try:
+            key = f"run:{run_id}:node_started"
+            started = datetime.fromtimestamp(started_at, tz=timezone.utc).isoformat().replace("+00:00", "Z")
+            pipe = self.client.pipeline()
+            pipe.hset(key, node_id, started)
+            pipe.expire(key, RUN_STATE_TTL_SECONDS)
+            pipe.execute()
+        except Exception as e:
+            logger.warning(f"Failed to record node start: {e}")
+
+    def append_node_log(self, run_id: str, node_id: str, level: str, message: str,
+                        fields: Optional[Dict[str, Any]] = None):
+        """Add a line to a node's log (see sdk.AppendNodeLog).
+
+        Lines are readable through the run's logs endpoint. The log is added to
+        the run's key set (sdk.RunKeysKey) so it's cleaned up with the run's
+        state. Best effort: a failure is only logged locally.
+
+        Args:
+            run_id: Workflow run ID
+            node_id: Node being executed
+            level: debug, info, warn or error
+            message: Log message, truncated to NODE_LOG_MAX_MESSAGE_LENGTH
+            fields: Structured fields of the line
+        """
+        entry = {
+            'level': level,
+            'message': message[:NODE_LOG_MAX_MESSAGE_LENGTH],
+            'timestamp': datetime.now(timezone.utc).isoformat().replace("+00:00", "Z"),
+        }
+        if fields:
+            entry['fields'] = fields
+        try:
+            key = f"logs:{run_id}:{node_id}"
+            pipe = self.client.pipeline()
+            pipe.rpush(key, json.dumps(entry, default=str))
+            pipe.ltrim(key, -NODE_LOG_MAX_ENTRIES, -1)
+            pipe.expire(key, RUN_STATE_TTL_SECONDS)
+            pipe.sadd(f"run:{run_id}:keys", key)
+            pipe.expire(f"run:{run_id}:keys", RUN_STATE_TTL_SECONDS)
+            pipe.execute()
+        except Exception as e:
+            logger.warning(f"Failed to append node log: {e}")
+
+    def publish_workflow_event(self, username: str, event: Dict[str, Any]):
+        """Publish an event to the user's workflow events channel.
+
+        The fanout service relays `workflow:events:{username}` to the user's
+        WebSocket clients.
+
+        Args:
+            username: Workflow owner
+            event: Event dictionary (must include "type")
+        """
+        channel = f"workflow:events:{username}"
+        self.client.publish(channel, json.dumps(event))
+
+    def ack_message(self, message_id: str, stream: Optional[str] = None):
         """Acknowledge a message from the stream.
 
         Args:
             message_id: Message ID to acknowledge
+            stream: Stream the message was read from (defaults to the base stream)
         """
         try:
-            self.client.xack(self.stream, self.consumer_group, message_id)
+            self.client.xack(stream or self.stream, self.consumer_group, message_id)
             logger.info(f"ACKed message: {message_id}")
         except Exception as e:
             logger.error(f"Failed to ACK message {message_id}: {e}")
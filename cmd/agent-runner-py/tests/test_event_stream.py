@@ -0,0 +1,117 @@
+"""Tests for streaming agent output events."""
+import pytest
+from agent.event_stream import AgentEventStream
+
+
+class FakeClock:
+    """Monotonic clock advanced by hand."""
+
+    def __init__(self):
+        self.now = 0.0
+
+    def __call__(self):
+        return self.now
+
+
+class TestAgentEventStream:
+    """Test suite for AgentEventStream."""
+
+    @pytest.fixture
+    def published(self):
+        """Collect published events in order."""
+        return []
+
+    @pytest.fixture
+    def clock(self):
+        """Create a hand-driven clock."""
+        return FakeClock()
+
+    @pytest.fixture
+    def stream(self, published, clock):
+        """Create a stream for one agent job."""
+        return AgentEventStream(
+            published.append, run_id="run-1", node_id="agent", job_id="job-1",
+            min_chars=10, flush_interval=0.25, clock=clock
+        )
+
+    def test_multi_step_agent_streams_in_order(self, stream, published, clock):
+        """Test a simulated tool loop publishes text and steps in the order produced."""
+        # Step 1: the agent explains itself token by token, then calls a tool
+        for token in ["Let", " me", " fetch", " the", " flights", "."]:
+            stream.text(token)
+        stream.step("tool_call", "execute_pipeline")
+        clock.now += 2.0
+        stream.step("tool_result", "execute_pipeline", status="success")
+
+        # Step 2: more text, then a patch
+        for token in ["Adding", " an", " alert"]:
+            stream.text(token)
+        stream.step("tool_call", "patch_workflow")
+        stream.step("tool_result", "patch_workflow", status="error")
+
+        # Final answer, shorter than the flush threshold until close
+        stream.text("Done")
+        stream.close()
+
+        summary = [(e["type"], e.get("text") or e.get("step")) for e in published]
+        assert summary == [
+            ("agent_token", "Let me fetch"),
+            ("agent_token", " the flights"),
+            ("agent_token", "."),
+            ("agent_step", "tool_call"),
+            ("agent_step", "tool_result"),
+            ("agent_token", "Adding an alert"),
+            ("agent_step", "tool_call"),
+            ("agent_step", "tool_result"),
+            ("agent_token", "Done"),
+        ]
+
+        assert [e["seq"] for e in published] == list(range(1, len(published) + 1))
+        for event in published:
+            assert event["run_id"] == "run-1"
+            assert event["node_id"] == "agent"
+            assert event["job_id"] == "job-1"
+
+        assert published[3]["tool"] == "execute_pipeline"
+        assert "status" not in published[3]
+        assert published[4]["status"] == "success"
+        assert published[7] == {**published[7], "tool": "patch_workflow", "status": "error"}
+
+    def test_coalesces_small_chunks(self, stream, published):
+        """Test tiny chunks are buffered into one event."""
+        for char in "abcdefghi":
+            stream.text(char)
+        assert published == []
+
+        stream.text("j")
+        assert len(published) == 1
+        assert published[0]["text"] == "abcdefghij"
+
+    def test_flushes_after_interval(self, stream, published, clock):
+        """Test buffered text is published once it has waited long enough."""
+        stream.text("slow")
+        clock.now += 0.1
+        stream.text(" llm")
+        assert published == []
+
+        clock.now += 0.2
+        stream.text("!")
+        assert [e["text"] for e in published] == ["slow llm!"]
+
+    def test_empty_chunks_and_close_publish_nothing(self, stream, published):
+        """Test a stream without text publishes no events."""
+        stream.text("")
+        stream.flush()
+        stream.close()
+        assert published == []
+
+    def test_publish_failure_does_not_raise(self, clock):
+        """Test publish errors are logged, not raised into the job."""
+        def publish(event):
+            raise ConnectionError("redis down")
+
+        stream = AgentEventStream(publish, run_id="run-1", node_id="agent", clock=clock)
+        stream.step("tool_call", "execute_pipeline")
+        stream.text("hello")
+        stream.close()
+        assert stream.seq == 2
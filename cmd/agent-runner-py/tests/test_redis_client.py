@@ -0,0 +1,129 @@
+"""Tests for the agent Redis client: priority stream reads and node logs."""
+import json
+
+from storage.redis_client import RedisClient
+
+
+class FakeStreams:
+    """Answers XREADGROUP from canned replies, recording each read."""
+
+    def __init__(self, replies):
+        self.replies = list(replies)
+        self.reads = []
+
+    def xreadgroup(self, groupname, consumername, streams, count, block=None):
+        self.reads.append((list(streams), block))
+        return self.replies.pop(0) if self.replies else []
+
+
+def make_client(replies):
+    """Create a RedisClient over fake streams, skipping the connection setup."""
+    client = RedisClient.__new__(RedisClient)
+    client.client = FakeStreams(replies)
+    client.stream = 'wf.tasks.agent'
+    client.consumer_group = 'agent_workers'
+    client.consumer_name = 'agent_worker_test'
+    client.streams = ['{wf.tasks.agent}.high', 'wf.tasks.agent', '{wf.tasks.agent}.low']
+    client.timeout = 5000
+    client._buffered = []
+    return client
+
+
+class TestReadNext:
+    """Test suite for RedisClient._read_next."""
+
+    def test_blocking_read_keeps_every_claimed_message(self):
+        """Test messages a blocking read claims from several streams are all served, most urgent first."""
+        empty = [[], [], []]
+        blocking = [
+            ['wf.tasks.agent', [('2-0', {'token': 'normal'})]],
+            ['{wf.tasks.agent}.low', [('3-0', {'token': 'low'})]],
+            ['{wf.tasks.agent}.high', [('1-0', {'token': 'high'})]],
+        ]
+        client = make_client(empty + [blocking])
+
+        served = [client._read_next() for _ in range(3)]
+        assert [message[1] for message in served] == ['1-0', '2-0', '3-0']
+        assert served[0] == ('{wf.tasks.agent}.high', '1-0', {'token': 'high'})
+
+        # The buffered messages were served without reading again
+        assert len(client.client.reads) == 4
+        assert client.client.reads[-1] == (client.streams, 5000)
+
+    def test_non_blocking_read_prefers_urgent_stream(self):
+        """Test a message waiting on a stream is returned without blocking."""
+        client = make_client([[], [['wf.tasks.agent', [('5-0', {'token': 'normal'})]]]])
+
+        assert client._read_next() == ('wf.tasks.agent', '5-0', {'token': 'normal'})
+        assert [block for _, block in client.client.reads] == [None, None]
+
+    def test_nothing_arrived(self):
+        """Test a read that times out returns None."""
+        client = make_client([])
+
+        assert client._read_next() is None
+
+
+class FakePipeline:
+    """Records the commands queued on a pipeline."""
+
+    def __init__(self, commands):
+        self.commands = commands
+
+    def rpush(self, key, value):
+        self.commands.append(('rpush', key, value))
+
+    def ltrim(self, key, start, end):
+        self.commands.append(('ltrim', key, start, end))
+
+    def expire(self, key, ttl):
+        self.commands.append(('expire', key, ttl))
+
+    def sadd(self, key, member):
+        self.commands.append(('sadd', key, member))
+
+    def execute(self):
+        pass
+
+
+class FakeLists:
+    """Hands out recording pipelines."""
+
+    def __init__(self):
+        self.commands = []
+
+    def pipeline(self):
+        return FakePipeline(self.commands)
+
+
+class TestAppendNodeLog:
+    """Test suite for RedisClient.append_node_log."""
+
+    def test_appends_entry_like_go_workers(self):
+        """Test a line is pushed as an sdk.NodeLogEntry, then the log trimmed, expired and tracked."""
+        client = make_client([])
+        client.client = FakeLists()
+
+        client.append_node_log('run-1', 'agent', 'info', 'x' * 5000, {'tool': 'patch_workflow'})
+
+        push, trim, expire, track, expire_set = client.client.commands
+        assert push[:2] == ('rpush', 'logs:run-1:agent')
+        entry = json.loads(push[2])
+        assert entry['level'] == 'info'
+        assert entry['message'] == 'x' * 4096
+        assert entry['fields'] == {'tool': 'patch_workflow'}
+        assert entry['timestamp'].endswith('Z')
+        assert trim == ('ltrim', 'logs:run-1:agent', -1000, -1)
+        assert expire == ('expire', 'logs:run-1:agent', 24 * 60 * 60)
+        assert track == ('sadd', 'run:run-1:keys', 'logs:run-1:agent')
+        assert expire_set == ('expire', 'run:run-1:keys', 24 * 60 * 60)
+
+    def test_omits_empty_fields(self):
+        """Test a line without fields has no fields key, as omitempty drops it in Go."""
+        client = make_client([])
+        client.client = FakeLists()
+
+        client.append_node_log('run-1', 'agent', 'warn', 'careful')
+
+        entry = json.loads(client.client.commands[0][2])
+        assert 'fields' not in entry
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -11,37 +12,70 @@ const (
 	// Time allowed to write a message to the peer
 	writeWait = 10 * time.Second
 
-	// Time allowed to read the next pong message from the peer
-	pongWait = 30 * time.Second
-
-	// Send pings to peer with this period (must be less than pongWait)
-	pingPeriod = 25 * time.Second
-
 	// Maximum message size allowed from peer (clients only send pongs, not data)
 	maxMessageSize = 512
 )
 
+// wsConn is the subset of *websocket.Conn used by Client
+type wsConn interface {
+	SetReadLimit(limit int64)
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	Close() error
+}
+
 // Client represents a WebSocket connection
 type Client struct {
 	hub      *Hub
-	conn     *websocket.Conn
+	conn     wsConn
 	username string
 	send     chan []byte
+
+	// Time allowed between pongs before the connection is considered dead
+	pongWait time.Duration
+
+	// Unix nanos of the last pong (or connect time), read by the hub reaper
+	lastPong atomic.Int64
+
+	// Last event ID delivered to this client (replay cursor)
+	// Only accessed from the hub goroutine.
+	lastEventID string
 }
 
 // NewClient creates a new Client instance
-func NewClient(hub *Hub, conn *websocket.Conn, username string) *Client {
-	return &Client{
-		hub:      hub,
-		conn:     conn,
-		username: username,
-		send:     make(chan []byte, 512), // Increased buffer for bursts
+// lastEventID is the client's replay cursor (empty for a fresh connection).
+func NewClient(hub *Hub, conn wsConn, username, lastEventID string) *Client {
+	c := &Client{
+		hub:         hub,
+		conn:        conn,
+		username:    username,
+		send:        make(chan []byte, 512), // Increased buffer for bursts
+		pongWait:    hub.heartbeat.PongTimeout,
+		lastEventID: lastEventID,
 	}
+	c.lastPong.Store(time.Now().UnixNano())
+	return c
+}
+
+// lastSeen returns when the client last answered a ping
+func (c *Client) lastSeen() time.Time {
+	return time.Unix(0, c.lastPong.Load())
+}
+
+// ping sends a ping control frame
+// WriteControl is safe to call concurrently with writePump.
+func (c *Client) ping() error {
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
 // We don't expect messages from clients (server-push only), but we need this
-// to handle ping/pong and detect disconnects
+// to handle ping/pong and detect disconnects. Each pong extends the read
+// deadline, so a peer that stops answering the hub's pings times out here.
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
@@ -49,9 +83,10 @@ func (c *Client) readPump() {
 	}()
 
 	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.lastPong.Store(time.Now().UnixNano())
+		c.conn.SetReadDeadline(time.Now().Add(c.pongWait))
 		return nil
 	})
 
@@ -68,44 +103,35 @@ func (c *Client) readPump() {
 }
 
 // writePump pumps messages from the hub to the WebSocket connection
+// Pings are sent by the hub (see Hub.checkHeartbeats), not here.
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
-	defer func() {
-		ticker.Stop()
-		c.conn.Close()
-	}()
+	defer c.conn.Close()
 
-	for {
-		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+	for message := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 
-			// Send each message as a separate WebSocket frame
-			// This ensures frontend can parse each JSON object individually
-			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				return
-			}
+		// Send each message as a separate WebSocket frame
+		// This ensures frontend can parse each JSON object individually
+		if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			return
+		}
 
-			// Send any queued messages as separate frames
-			// Don't batch them together to avoid JSON parsing issues
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-				if err := c.conn.WriteMessage(websocket.TextMessage, <-c.send); err != nil {
-					return
-				}
+		// Send any queued messages as separate frames
+		// Don't batch them together to avoid JSON parsing issues
+		n := len(c.send)
+		for i := 0; i < n; i++ {
+			next, ok := <-c.send
+			if !ok {
+				break
 			}
-
-		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.conn.WriteMessage(websocket.TextMessage, next); err != nil {
 				return
 			}
 		}
 	}
+
+	// The hub closed the channel
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Trim policy: keep at most the last 1000 events per user, and nothing
+	// older than 1 hour. A buffer with no new events expires after 1 hour.
+	eventBufferMaxLen = 1000
+	eventBufferMaxAge = 1 * time.Hour
+)
+
+// BufferedEvent is an event read back from a user's buffer
+type BufferedEvent struct {
+	ID   string
+	Data []byte
+}
+
+// EventBuffer stores recent events per user in a capped Redis stream so
+// reconnecting clients can replay what they missed
+type EventBuffer struct {
+	redis  redis.UniversalClient
+	maxLen int64
+	maxAge time.Duration
+}
+
+// NewEventBuffer creates a new EventBuffer instance
+func NewEventBuffer(redisClient redis.UniversalClient) *EventBuffer {
+	return &EventBuffer{
+		redis:  redisClient,
+		maxLen: eventBufferMaxLen,
+		maxAge: eventBufferMaxAge,
+	}
+}
+
+// eventBufferKey returns the buffer stream key for a user
+func eventBufferKey(username string) string {
+	return fmt.Sprintf("events:buffer:%s", username)
+}
+
+// Append adds an event to the user's buffer and returns its stream ID
+// The ID doubles as the replay cursor clients send back as last_event_id.
+func (b *EventBuffer) Append(ctx context.Context, username string, payload []byte) (string, error) {
+	key := eventBufferKey(username)
+	minID := strconv.FormatInt(time.Now().Add(-b.maxAge).UnixMilli(), 10)
+
+	pipe := b.redis.Pipeline()
+	add := pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{"data": string(payload)},
+	})
+	pipe.XTrimMinIDApprox(ctx, key, minID, 0)
+	pipe.Expire(ctx, key, b.maxAge)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to buffer event: %w", err)
+	}
+
+	return add.Val(), nil
+}
+
+// Since returns buffered events strictly after lastID, oldest first
+func (b *EventBuffer) Since(ctx context.Context, username, lastID string) ([]BufferedEvent, error) {
+	messages, err := b.redis.XRange(ctx, eventBufferKey(username), "("+lastID, "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event buffer: %w", err)
+	}
+
+	events := make([]BufferedEvent, 0, len(messages))
+	for _, msg := range messages {
+		data, _ := msg.Values["data"].(string)
+		events = append(events, BufferedEvent{ID: msg.ID, Data: []byte(data)})
+	}
+	return events, nil
+}
+
+// withEventID adds an event_id field to a JSON object payload
+// Non-object payloads are returned unchanged.
+func withEventID(payload []byte, id string) []byte {
+	var event map[string]interface{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return payload
+	}
+	event["event_id"] = id
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return payload
+	}
+	return data
+}
+
+// validStreamID reports whether id looks like a Redis stream ID (ms or ms-seq)
+func validStreamID(id string) bool {
+	ms, seq, hasSeq := strings.Cut(id, "-")
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return false
+	}
+	if hasSeq {
+		if _, err := strconv.ParseUint(seq, 10, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// compareStreamIDs compares two stream IDs, returning -1, 0 or 1
+// Both IDs must be valid (see validStreamID).
+func compareStreamIDs(a, b string) int {
+	aMs, aSeq := splitStreamID(a)
+	bMs, bSeq := splitStreamID(b)
+
+	switch {
+	case aMs < bMs:
+		return -1
+	case aMs > bMs:
+		return 1
+	case aSeq < bSeq:
+		return -1
+	case aSeq > bSeq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// splitStreamID parses a stream ID into its millisecond and sequence parts
+func splitStreamID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
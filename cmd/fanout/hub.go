@@ -1,10 +1,34 @@
 package main
 
 import (
+	"context"
 	"log"
 	"sync"
+	"time"
 )
 
+const (
+	// Default interval between hub pings
+	defaultPingInterval = 30 * time.Second
+
+	// Default time a connection may go without a pong before it is reaped
+	defaultPongTimeout = 60 * time.Second
+)
+
+// HeartbeatConfig controls WebSocket liveness checks
+type HeartbeatConfig struct {
+	PingInterval time.Duration // How often every client is pinged
+	PongTimeout  time.Duration // Clients silent for longer than this are reaped
+}
+
+// DefaultHeartbeatConfig returns the default ping interval and pong timeout
+func DefaultHeartbeatConfig() HeartbeatConfig {
+	return HeartbeatConfig{
+		PingInterval: defaultPingInterval,
+		PongTimeout:  defaultPongTimeout,
+	}
+}
+
 // Hub maintains active WebSocket connections and broadcasts messages
 type Hub struct {
 	// Map: username → []*Client
@@ -19,30 +43,54 @@ type Hub struct {
 
 	// Channel for broadcasting messages
 	broadcast chan *Message
+
+	// Recent events per user, replayed to reconnecting clients
+	buffer *EventBuffer
+
+	// Ping/pong settings for reaping dead connections
+	heartbeat HeartbeatConfig
 }
 
 // Message represents a message to be broadcast
 type Message struct {
 	Username string
+	ID       string // Event buffer stream ID (empty if buffering failed)
 	Data     []byte
 }
 
 // NewHub creates a new Hub instance
-func NewHub() *Hub {
+// Zero heartbeat values fall back to the defaults.
+func NewHub(buffer *EventBuffer, heartbeat HeartbeatConfig) *Hub {
+	if heartbeat.PingInterval <= 0 {
+		heartbeat.PingInterval = defaultPingInterval
+	}
+	if heartbeat.PongTimeout <= 0 {
+		heartbeat.PongTimeout = defaultPongTimeout
+	}
+
 	return &Hub{
 		connections: make(map[string][]*Client),
 		register:    make(chan *Client),
 		unregister:  make(chan *Client),
 		broadcast:   make(chan *Message, 256),
+		buffer:      buffer,
+		heartbeat:   heartbeat,
 	}
 }
 
 // Run starts the hub's main loop
 func (h *Hub) Run() {
-	log.Println("Hub started")
+	log.Printf("Hub started (ping_interval=%s, pong_timeout=%s)",
+		h.heartbeat.PingInterval, h.heartbeat.PongTimeout)
+
+	ticker := time.NewTicker(h.heartbeat.PingInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
+		case <-ticker.C:
+			h.checkHeartbeats()
+
 		case client := <-h.register:
 			h.registerClient(client)
 
@@ -58,11 +106,47 @@ func (h *Hub) Run() {
 // registerClient adds a client to the hub
 func (h *Hub) registerClient(client *Client) {
 	h.mutex.Lock()
-	defer h.mutex.Unlock()
-
 	h.connections[client.username] = append(h.connections[client.username], client)
 	log.Printf("Client registered: username=%s, total_for_user=%d", 
 		client.username, len(h.connections[client.username]))
+	h.mutex.Unlock()
+
+	// Replay missed events before any further live broadcast is processed.
+	// Live messages already queued with an ID <= the replayed cursor are
+	// skipped in broadcastToUsername, so each event is delivered once.
+	// Broadcasts run on this goroutine too, so the order holds without the
+	// lock; a slow buffer read or client doesn't block connection lookups.
+	if client.lastEventID != "" {
+		h.replay(client)
+	}
+}
+
+// replay sends buffered events newer than the client's cursor
+func (h *Hub) replay(client *Client) {
+	if h.buffer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeWait)
+	defer cancel()
+
+	events, err := h.buffer.Since(ctx, client.username, client.lastEventID)
+	if err != nil {
+		log.Printf("Failed to replay events: username=%s, error=%v", client.username, err)
+		return
+	}
+
+	for _, event := range events {
+		select {
+		case client.send <- withEventID(event.Data, event.ID):
+			client.lastEventID = event.ID
+		case <-time.After(writeWait):
+			log.Printf("Replay stalled, stopping: username=%s, sent_up_to=%s", client.username, client.lastEventID)
+			return
+		}
+	}
+
+	log.Printf("Replayed events: username=%s, count=%d", client.username, len(events))
 }
 
 // unregisterClient removes a client from the hub
@@ -104,9 +188,17 @@ func (h *Hub) broadcastToUsername(message *Message) {
 		message.Username, len(clients))
 
 	for _, client := range clients {
+		// Skip events the client already received via replay
+		if message.ID != "" && client.lastEventID != "" && compareStreamIDs(message.ID, client.lastEventID) <= 0 {
+			continue
+		}
+
 		select {
 		case client.send <- message.Data:
 			// Message sent successfully
+			if message.ID != "" {
+				client.lastEventID = message.ID
+			}
 		default:
 			// Client's send buffer is full, close the connection
 			log.Printf("Client send buffer full, closing connection: username=%s", client.username)
@@ -115,6 +207,36 @@ func (h *Hub) broadcastToUsername(message *Message) {
 	}
 }
 
+// checkHeartbeats pings every client and reaps those that stopped answering
+// Pings are sent from separate goroutines so a stuck peer can't stall the hub.
+func (h *Hub) checkHeartbeats() {
+	now := time.Now()
+	var stale []*Client
+
+	h.mutex.RLock()
+	for _, clients := range h.connections {
+		for _, client := range clients {
+			if now.Sub(client.lastSeen()) > h.heartbeat.PongTimeout {
+				stale = append(stale, client)
+				continue
+			}
+			go func(c *Client) {
+				if err := c.ping(); err != nil {
+					log.Printf("Ping failed: username=%s, error=%v", c.username, err)
+				}
+			}(client)
+		}
+	}
+	h.mutex.RUnlock()
+
+	for _, client := range stale {
+		log.Printf("Reaping stale connection: username=%s, last_pong=%s",
+			client.username, client.lastSeen().Format(time.RFC3339))
+		h.unregisterClient(client)
+		client.conn.Close()
+	}
+}
+
 // GetConnectionCount returns the total number of active connections
 func (h *Hub) GetConnectionCount() int {
 	h.mutex.RLock()
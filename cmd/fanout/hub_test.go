@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn is an in-memory wsConn
+// Reads block until Close. If autoPong is set, every ping is answered.
+type fakeConn struct {
+	autoPong bool
+
+	mu          sync.Mutex
+	pongHandler func(string) error
+
+	pings  atomic.Int32
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newFakeConn(autoPong bool) *fakeConn {
+	return &fakeConn{autoPong: autoPong, closed: make(chan struct{})}
+}
+
+func (f *fakeConn) SetReadLimit(int64)               {}
+func (f *fakeConn) SetReadDeadline(time.Time) error  { return nil }
+func (f *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+func (f *fakeConn) WriteMessage(int, []byte) error   { return nil }
+func (f *fakeConn) ReadMessage() (int, []byte, error) {
+	<-f.closed
+	return 0, nil, websocket.ErrCloseSent
+}
+func (f *fakeConn) isClosed() bool {
+	select {
+	case <-f.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *fakeConn) SetPongHandler(h func(string) error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pongHandler = h
+}
+
+func (f *fakeConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != websocket.PingMessage {
+		return nil
+	}
+	f.pings.Add(1)
+
+	f.mu.Lock()
+	h := f.pongHandler
+	f.mu.Unlock()
+	if f.autoPong && h != nil {
+		return h(string(data))
+	}
+	return nil
+}
+
+func (f *fakeConn) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func TestHub_ReapsConnectionsThatNeverPong(t *testing.T) {
+	hub := NewHub(nil, HeartbeatConfig{
+		PingInterval: 20 * time.Millisecond,
+		PongTimeout:  100 * time.Millisecond,
+	})
+	go hub.Run()
+
+	dead := newFakeConn(false)
+	alive := newFakeConn(true)
+
+	for _, conn := range []*fakeConn{dead, alive} {
+		client := NewClient(hub, conn, "alice", "")
+		hub.register <- client
+		go client.writePump()
+		go client.readPump()
+	}
+	require.Eventually(t, func() bool { return hub.GetConnectionCount() == 2 }, time.Second, time.Millisecond)
+
+	// The silent connection is closed and removed within a few timeouts
+	require.Eventually(t, dead.isClosed, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return hub.GetConnectionCount() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Greater(t, dead.pings.Load(), int32(0), "dead connection should have been pinged")
+
+	// The connection answering pings survives well past the timeout
+	time.Sleep(300 * time.Millisecond)
+	assert.False(t, alive.isClosed())
+	assert.Equal(t, 1, hub.GetConnectionCount())
+	assert.Equal(t, 1, hub.GetUserCount())
+}
+
+func TestHub_ReplayDoesNotHoldLock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	buffer := NewEventBuffer(rdb)
+	ctx := context.Background()
+
+	cursor, err := buffer.Append(ctx, "alice", []byte(`{"type":"seen"}`))
+	require.NoError(t, err)
+	_, err = buffer.Append(ctx, "alice", []byte(`{"type":"missed"}`))
+	require.NoError(t, err)
+
+	hub := NewHub(buffer, DefaultHeartbeatConfig())
+	go hub.Run()
+
+	// Nobody drains this client, so its replay blocks on the send
+	client := NewClient(hub, newFakeConn(true), "alice", cursor)
+	client.send = make(chan []byte)
+	hub.register <- client
+
+	counted := make(chan int)
+	go func() { counted <- hub.GetConnectionCount() }()
+	select {
+	case count := <-counted:
+		assert.Equal(t, 1, count)
+	case <-time.After(time.Second):
+		t.Fatal("connection count blocked behind a stalled replay")
+	}
+
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(<-client.send, &event))
+	assert.Equal(t, "missed", event["type"])
+}
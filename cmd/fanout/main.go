@@ -10,40 +10,54 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/lyzr/orchestrator/common/auth"
+	"github.com/lyzr/orchestrator/common/health"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
 )
 
 func main() {
 	log.Println("Fanout Service starting...")
 
 	// Get configuration from environment
-	redisHost := getEnv("REDIS_HOST", "localhost")
-	redisPort := getEnv("REDIS_PORT", "6379")
 	port := getEnv("PORT", "8084")
 
-	// Initialize Redis
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
-		Password: getEnv("REDIS_PASSWORD", ""),
-		DB:       0,
-	})
+	// Initialize Redis (standalone, sentinel, or cluster based on REDIS_MODE)
+	redisClient, err := rediscommon.NewUniversalClientFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to create Redis client: %v", err)
+	}
 
 	ctx := context.Background()
 	if err := redisClient.Ping(ctx).Err(); err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
-	log.Printf("Connected to Redis at %s:%s", redisHost, redisPort)
+	log.Printf("Connected to Redis (mode=%s)", getEnv("REDIS_MODE", "standalone"))
+
+	// WebSocket tokens are issued by the orchestrator with the same secret
+	tokens, err := auth.NewTokenManagerFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize token verifier: %v", err)
+	}
 
 	// Create Hub (connection manager)
-	hub := NewHub()
+	// Per-user capped event buffer for replay on reconnect
+	buffer := NewEventBuffer(redisClient)
+
+	// Heartbeat: ping clients and reap connections that stop answering
+	heartbeat := HeartbeatConfig{
+		PingInterval: getEnvDuration("FANOUT_PING_INTERVAL", defaultPingInterval),
+		PongTimeout:  getEnvDuration("FANOUT_PONG_TIMEOUT", defaultPongTimeout),
+	}
+
+	hub := NewHub(buffer, heartbeat)
 	go hub.Run()
 
 	// Create Redis subscriber
-	subscriber := NewRedisSubscriber(redisClient, hub)
+	subscriber := NewRedisSubscriber(redisClient, hub, buffer)
 	go subscriber.Start(ctx)
 
 	// Create HTTP server with WebSocket handler
-	server := NewServer(hub, redisClient)
+	server := NewServer(hub, redisClient, tokens)
 
 	// Setup HTTP routes
 	http.HandleFunc("/ws", server.HandleWebSocket)
@@ -52,6 +66,10 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	// Readiness: 503 while Redis (events and approvals) can't be reached
+	http.HandleFunc("/ready", health.NewDependencyChecker().
+		Add("redis", health.RedisProbe(redisClient)).
+		Handler())
 
 	// Start HTTP server
 	addr := fmt.Sprintf(":%s", port)
@@ -59,7 +77,8 @@ func main() {
 		Addr:    addr,
 		Handler: http.DefaultServeMux,
 		// No timeouts - WebSocket connections are long-lived
-		// Timeouts would kill active connections
+		// Timeouts would kill active connections; dead peers are
+		// reaped by the hub's ping/pong heartbeat instead
 		ReadTimeout:  0,
 		WriteTimeout: 0,
 		// Optional: Set IdleTimeout for non-WebSocket connections
@@ -98,3 +117,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration for %s=%q, using default %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
@@ -10,15 +10,17 @@ import (
 
 // RedisSubscriber listens to Redis PubSub and forwards messages to Hub
 type RedisSubscriber struct {
-	redis *redis.Client
-	hub   *Hub
+	redis  redis.UniversalClient
+	hub    *Hub
+	buffer *EventBuffer
 }
 
 // NewRedisSubscriber creates a new RedisSubscriber instance
-func NewRedisSubscriber(redisClient *redis.Client, hub *Hub) *RedisSubscriber {
+func NewRedisSubscriber(redisClient redis.UniversalClient, hub *Hub, buffer *EventBuffer) *RedisSubscriber {
 	return &RedisSubscriber{
-		redis: redisClient,
-		hub:   hub,
+		redis:  redisClient,
+		hub:    hub,
+		buffer: buffer,
 	}
 }
 
@@ -62,11 +64,20 @@ func (s *RedisSubscriber) Start(ctx context.Context) {
 
 			log.Printf("Received event for username=%s, size=%d bytes", username, len(msg.Payload))
 
-			// Forward to hub
-			s.hub.broadcast <- &Message{
+			// Buffer for replay, tagging the event with its buffer ID
+			message := &Message{
 				Username: username,
 				Data:     []byte(msg.Payload),
 			}
+			if id, err := s.buffer.Append(ctx, username, message.Data); err != nil {
+				log.Printf("Failed to buffer event for username=%s: %v", username, err)
+			} else {
+				message.ID = id
+				message.Data = withEventID(message.Data, id)
+			}
+
+			// Forward to hub
+			s.hub.broadcast <- message
 		}
 	}
 }
@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/lyzr/orchestrator/common/auth"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -22,25 +24,41 @@ var upgrader = websocket.Upgrader{
 
 // Server handles WebSocket connections and approval requests
 type Server struct {
-	hub   *Hub
-	redis *redis.Client
+	hub    *Hub
+	redis  redis.UniversalClient
+	tokens *auth.TokenManager
 }
 
 // NewServer creates a new Server instance
-func NewServer(hub *Hub, redisClient *redis.Client) *Server {
+func NewServer(hub *Hub, redisClient redis.UniversalClient, tokens *auth.TokenManager) *Server {
 	return &Server{
-		hub:   hub,
-		redis: redisClient,
+		hub:    hub,
+		redis:  redisClient,
+		tokens: tokens,
 	}
 }
 
 // HandleWebSocket handles WebSocket upgrade and registration
-// URL: /ws?username=test-user
+// URL: /ws?token=<token>[&last_event_id=<id>] (or Authorization: Bearer <token>)
+//
+// The token is issued by the orchestrator (POST /api/v1/auth/ws-token) and
+// the connection is bound to the username inside it, so a client only ever
+// receives events from its own workflow:events:{username} channel.
+// Rejections happen before the upgrade, as plain HTTP responses.
+//
+// A reconnecting client passes the event_id of the last event it received as
+// last_event_id; buffered events after it are replayed before live events.
 func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	// Extract username from query parameter
-	username := r.URL.Query().Get("username")
-	if username == "" {
-		http.Error(w, "username query parameter required", http.StatusBadRequest)
+	username, status, err := s.authenticate(r)
+	if err != nil {
+		log.Printf("WebSocket auth rejected: remote=%s, error=%v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	lastEventID := r.URL.Query().Get("last_event_id")
+	if lastEventID != "" && !validStreamID(lastEventID) {
+		http.Error(w, "invalid last_event_id", http.StatusBadRequest)
 		return
 	}
 
@@ -52,7 +70,7 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create client
-	client := NewClient(s.hub, conn, username)
+	client := NewClient(s.hub, conn, username, lastEventID)
 
 	// Register client with hub
 	s.hub.register <- client
@@ -64,6 +82,31 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
+// authenticate resolves the username for a WebSocket handshake
+// Returns the HTTP status to reject with when authentication fails.
+func (s *Server) authenticate(r *http.Request) (string, int, error) {
+	// Browsers cannot set headers on WebSocket requests, so accept a query param too
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = auth.TokenFromHeader(r.Header.Get("Authorization"))
+	}
+	if token == "" {
+		return "", http.StatusUnauthorized, fmt.Errorf("token required")
+	}
+
+	username, err := s.tokens.Verify(token)
+	if err != nil {
+		return "", http.StatusUnauthorized, err
+	}
+
+	// Legacy clients still send ?username=; it must match the token subject
+	if requested := r.URL.Query().Get("username"); requested != "" && requested != username {
+		return "", http.StatusForbidden, fmt.Errorf("token does not grant access to user %s", requested)
+	}
+
+	return username, http.StatusOK, nil
+}
+
 // ApprovalRequest represents an approval decision from the user
 type ApprovalRequest struct {
 	RunID    string                 `json:"run_id"`
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/lyzr/orchestrator/common/auth"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	mr     *miniredis.Miniredis
+	redis  redis.UniversalClient
+	hub    *Hub
+	tokens *auth.TokenManager
+	server *httptest.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	tokens, err := auth.NewTokenManager([]byte("test-secret-0123456789"))
+	require.NoError(t, err)
+
+	buffer := NewEventBuffer(rdb)
+	hub := NewHub(buffer, DefaultHeartbeatConfig())
+	go hub.Run()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go NewRedisSubscriber(rdb, hub, buffer).Start(ctx)
+	require.Eventually(t, func() bool { return mr.PubSubNumPat() > 0 }, 2*time.Second, 10*time.Millisecond)
+
+	server := NewServer(hub, rdb, tokens)
+	ts := httptest.NewServer(http.HandlerFunc(server.HandleWebSocket))
+	t.Cleanup(ts.Close)
+
+	return &testEnv{mr: mr, redis: rdb, hub: hub, tokens: tokens, server: ts}
+}
+
+func (e *testEnv) wsURL(query string) string {
+	return "ws" + strings.TrimPrefix(e.server.URL, "http") + "/ws" + query
+}
+
+func (e *testEnv) token(t *testing.T, username string) string {
+	t.Helper()
+	token, err := e.tokens.Issue(username, time.Minute)
+	require.NoError(t, err)
+	return token
+}
+
+func TestHandleWebSocket_Handshake(t *testing.T) {
+	env := newTestEnv(t)
+
+	expired, err := env.tokens.Issue("alice", -time.Minute)
+	require.NoError(t, err)
+
+	otherSigner, err := auth.NewTokenManager([]byte("another-secret-0123456789"))
+	require.NoError(t, err)
+	forged, err := otherSigner.Issue("alice", time.Minute)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		query      string
+		header     http.Header
+		wantStatus int
+	}{
+		{name: "query token", query: "?token=" + env.token(t, "alice"), wantStatus: http.StatusSwitchingProtocols},
+		{name: "bearer header", header: http.Header{"Authorization": {"Bearer " + env.token(t, "alice")}}, wantStatus: http.StatusSwitchingProtocols},
+		{name: "matching legacy username", query: "?username=alice&token=" + env.token(t, "alice"), wantStatus: http.StatusSwitchingProtocols},
+		{name: "missing token", query: "?username=alice", wantStatus: http.StatusUnauthorized},
+		{name: "garbage token", query: "?token=not-a-token", wantStatus: http.StatusUnauthorized},
+		{name: "expired token", query: "?token=" + expired, wantStatus: http.StatusUnauthorized},
+		{name: "wrong signing secret", query: "?token=" + forged, wantStatus: http.StatusUnauthorized},
+		{name: "non-bearer header", header: http.Header{"Authorization": {"Basic " + env.token(t, "alice")}}, wantStatus: http.StatusUnauthorized},
+		{name: "other user's channel", query: "?username=bob&token=" + env.token(t, "alice"), wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := websocket.DefaultDialer.Dial(env.wsURL(tt.query), tt.header)
+			require.NotNil(t, resp)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			if tt.wantStatus == http.StatusSwitchingProtocols {
+				require.NoError(t, err)
+				conn.Close()
+			} else {
+				assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+			}
+		})
+	}
+}
+
+func TestHandleWebSocket_CrossUserIsolation(t *testing.T) {
+	env := newTestEnv(t)
+
+	alice, _, err := websocket.DefaultDialer.Dial(env.wsURL("?token="+env.token(t, "alice")), nil)
+	require.NoError(t, err)
+	defer alice.Close()
+
+	bob, _, err := websocket.DefaultDialer.Dial(env.wsURL("?token="+env.token(t, "bob")), nil)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	require.Eventually(t, func() bool { return env.hub.GetUserCount() == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	ctx := context.Background()
+	require.NoError(t, env.redis.Publish(ctx, "workflow:events:alice", `{"type":"for-alice"}`).Err())
+	require.NoError(t, env.redis.Publish(ctx, "workflow:events:bob", `{"type":"for-bob"}`).Err())
+
+	assert.Equal(t, "for-alice", readEvent(t, alice)["type"])
+	assert.Equal(t, "for-bob", readEvent(t, bob)["type"])
+
+	// Neither connection receives anything further (in particular, not the other user's event)
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		assertNoMessage(t, conn)
+	}
+}
+
+func TestHandleWebSocket_ReplayOnReconnect(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	publish := func(eventType string) {
+		require.NoError(t, env.redis.Publish(ctx, "workflow:events:alice", `{"type":"`+eventType+`"}`).Err())
+	}
+
+	// 1. Connected client receives the first event and remembers its ID
+	conn, _, err := websocket.DefaultDialer.Dial(env.wsURL("?token="+env.token(t, "alice")), nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return env.hub.GetUserCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	publish("first")
+	first := readEvent(t, conn)
+	require.Equal(t, "first", first["type"])
+	cursor, ok := first["event_id"].(string)
+	require.True(t, ok, "events carry an event_id")
+
+	// 2. Client drops; events published while offline
+	conn.Close()
+	require.Eventually(t, func() bool { return env.hub.GetUserCount() == 0 }, 2*time.Second, 10*time.Millisecond)
+
+	publish("missed-1")
+	publish("missed-2")
+	require.Eventually(t, func() bool {
+		return env.redis.XLen(ctx, eventBufferKey("alice")).Val() == 3
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// 3. Reconnect with the cursor: missed events are replayed in order, then live resumes
+	conn, _, err = websocket.DefaultDialer.Dial(env.wsURL("?token="+env.token(t, "alice")+"&last_event_id="+cursor), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, "missed-1", readEvent(t, conn)["type"])
+	assert.Equal(t, "missed-2", readEvent(t, conn)["type"])
+
+	publish("live")
+	assert.Equal(t, "live", readEvent(t, conn)["type"])
+
+	// Exactly once: nothing replayed twice
+	assertNoMessage(t, conn)
+
+	// Malformed cursors are rejected before the upgrade
+	_, resp, err := websocket.DefaultDialer.Dial(env.wsURL("?token="+env.token(t, "alice")+"&last_event_id=abc"), nil)
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestCompareStreamIDs(t *testing.T) {
+	assert.Equal(t, -1, compareStreamIDs("1-0", "1-1"))
+	assert.Equal(t, -1, compareStreamIDs("9-5", "10-0"))
+	assert.Equal(t, 1, compareStreamIDs("10-0", "9-99"))
+	assert.Equal(t, 0, compareStreamIDs("5", "5-0"))
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &event))
+	return event
+}
+
+func assertNoMessage(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, data, err := conn.ReadMessage()
+	assert.Error(t, err, "unexpected message: %s", data)
+}
@@ -7,6 +7,13 @@ set -e
 # Change to script directory
 cd "$(dirname "$0")"
 
+# Load common environment (AUTH_TOKEN_SECRET must match the orchestrator)
+if [ -f "../../.env" ]; then
+    set -a
+    source "../../.env"
+    set +a
+fi
+
 # Configuration
 export REDIS_HOST="${REDIS_HOST:-localhost}"
 export REDIS_PORT="${REDIS_PORT:-6379}"
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/config"
 	"github.com/lyzr/orchestrator/common/metrics"
 	redisWrapper "github.com/lyzr/orchestrator/common/redis"
 	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
 	"github.com/lyzr/orchestrator/common/worker"
 	"github.com/redis/go-redis/v9"
 )
@@ -27,10 +30,12 @@ type HITLWorker struct {
 	requestConsumerGroup  string
 	responseConsumerGroup string
 	consumerName          string
+	workers               int
+	consumer              config.ConsumerConfig
 }
 
 // NewHITLWorker creates a new HITL worker
-func NewHITLWorker(redisClient *redis.Client, workflowSDK *sdk.SDK, logger sdk.Logger) *HITLWorker {
+func NewHITLWorker(redisClient redis.UniversalClient, workflowSDK *sdk.SDK, logger sdk.Logger) *HITLWorker {
 	return &HITLWorker{
 		redis:                 redisWrapper.NewClient(redisClient, logger),
 		sdk:                   workflowSDK,
@@ -40,9 +45,22 @@ func NewHITLWorker(redisClient *redis.Client, workflowSDK *sdk.SDK, logger sdk.L
 		requestConsumerGroup:  "hitl_request_workers",
 		responseConsumerGroup: "hitl_response_workers",
 		consumerName:          fmt.Sprintf("hitl_worker_%s", uuid.New().String()[:8]),
+		workers:               redisWrapper.DefaultConsumerWorkers,
 	}
 }
 
+// WithWorkers sets how many messages of each stream are processed in parallel
+func (w *HITLWorker) WithWorkers(workers int) *HITLWorker {
+	w.workers = workers
+	return w
+}
+
+// WithConsumerConfig sets the read block and error backoff for both streams
+func (w *HITLWorker) WithConsumerConfig(cfg config.ConsumerConfig) *HITLWorker {
+	w.consumer = cfg
+	return w
+}
+
 // Start begins processing HITL tasks from both streams
 func (w *HITLWorker) Start(ctx context.Context) error {
 	w.logger.Info("starting HITL worker",
@@ -51,129 +69,43 @@ func (w *HITLWorker) Start(ctx context.Context) error {
 		"consumer_name", w.consumerName)
 
 	// Create consumer groups if they don't exist
-	if err := w.redis.CreateStreamGroup(ctx, w.requestStream, w.requestConsumerGroup); err != nil {
-		return fmt.Errorf("failed to create request consumer group: %w", err)
+	for _, stream := range redisWrapper.PriorityStreams(w.requestStream) {
+		if err := w.redis.CreateStreamGroup(ctx, stream, w.requestConsumerGroup); err != nil {
+			return fmt.Errorf("failed to create request consumer group: %w", err)
+		}
 	}
 
 	if err := w.redis.CreateStreamGroup(ctx, w.responseStream, w.responseConsumerGroup); err != nil {
 		return fmt.Errorf("failed to create response consumer group: %w", err)
 	}
 
-	// Start two goroutines - one for each stream
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	errChan := make(chan error, 2)
+	// Reclaim messages left unacknowledged by crashed workers. Reprocessing is
+	// safe: requests are deduplicated by the SETNX on the approval key, and
+	// responses only apply while the approval is still pending.
+	for _, stream := range redisWrapper.PriorityStreams(w.requestStream) {
+		go redisWrapper.NewReclaimer(w.redis, stream, w.requestConsumerGroup, w.consumerName, w.handleApprovalRequest).Run(ctx)
+	}
+	go redisWrapper.NewReclaimer(w.redis, w.responseStream, w.responseConsumerGroup, w.consumerName, w.handleApprovalResponse).Run(ctx)
 
-	// Goroutine 1: Process approval requests
+	// One consumer pool per stream, each handling up to w.workers messages in parallel
+	var wg sync.WaitGroup
+	wg.Add(2)
 	go func() {
-		w.logger.Info("starting request handler goroutine")
-		errChan <- w.processRequestStream(ctx)
+		defer wg.Done()
+		redisWrapper.NewConsumerPool(w.redis, w.requestStream, w.requestConsumerGroup, w.consumerName, w.handleApprovalRequest,
+			redisWrapper.WithConsumerWorkers(w.workers),
+			redisWrapper.WithConsumerConfig(w.consumer),
+			redisWrapper.WithPriorities(redisWrapper.DefaultPriorityFairness)).Run(ctx)
 	}()
-
-	// Goroutine 2: Process approval responses
 	go func() {
-		w.logger.Info("starting response handler goroutine")
-		errChan <- w.processResponseStream(ctx)
+		defer wg.Done()
+		redisWrapper.NewConsumerPool(w.redis, w.responseStream, w.responseConsumerGroup, w.consumerName, w.handleApprovalResponse,
+			redisWrapper.WithConsumerWorkers(w.workers),
+			redisWrapper.WithConsumerConfig(w.consumer)).Run(ctx)
 	}()
+	wg.Wait()
 
-	// Wait for either goroutine to error or context cancellation
-	select {
-	case <-ctx.Done():
-		w.logger.Info("HITL worker stopping")
-		return nil
-	case err := <-errChan:
-		w.logger.Error("HITL worker goroutine failed", "error", err)
-		cancel() // Cancel the other goroutine
-		return err
-	}
-}
-
-// processRequestStream handles approval requests from wf.tasks.hitl
-func (w *HITLWorker) processRequestStream(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			w.logger.Info("request stream handler stopping")
-			return nil
-		default:
-			if err := w.processNextRequest(ctx); err != nil {
-				w.logger.Error("failed to process request", "error", err)
-				time.Sleep(1 * time.Second) // Back off on error
-			}
-		}
-	}
-}
-
-// processResponseStream handles approval decisions from wf.tasks.hitl.responses
-func (w *HITLWorker) processResponseStream(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			w.logger.Info("response stream handler stopping")
-			return nil
-		default:
-			if err := w.processNextResponse(ctx); err != nil {
-				w.logger.Error("failed to process response", "error", err)
-				time.Sleep(1 * time.Second) // Back off on error
-			}
-		}
-	}
-}
-
-// processNextRequest reads and processes one approval request
-func (w *HITLWorker) processNextRequest(ctx context.Context) error {
-	streams, err := w.redis.ReadFromStreamGroup(ctx, w.requestConsumerGroup, w.consumerName, w.requestStream, 1, 5*time.Second)
-	if err != nil {
-		return fmt.Errorf("XREADGROUP error: %w", err)
-	}
-
-	if streams == nil {
-		// Timeout, no messages
-		return nil
-	}
-
-	for _, stream := range streams {
-		for _, message := range stream.Messages {
-			if err := w.handleApprovalRequest(ctx, message); err != nil {
-				w.logger.Error("failed to handle approval request", "message_id", message.ID, "error", err)
-			}
-
-			// ACK message
-			if err := w.redis.AckStreamMessage(ctx, w.requestStream, w.requestConsumerGroup, message.ID); err != nil {
-				w.logger.Error("failed to ACK request message", "message_id", message.ID, "error", err)
-			}
-		}
-	}
-
-	return nil
-}
-
-// processNextResponse reads and processes one approval decision
-func (w *HITLWorker) processNextResponse(ctx context.Context) error {
-	streams, err := w.redis.ReadFromStreamGroup(ctx, w.responseConsumerGroup, w.consumerName, w.responseStream, 1, 5*time.Second)
-	if err != nil {
-		return fmt.Errorf("XREADGROUP error: %w", err)
-	}
-
-	if streams == nil {
-		// Timeout, no messages
-		return nil
-	}
-
-	for _, stream := range streams {
-		for _, message := range stream.Messages {
-			if err := w.handleApprovalResponse(ctx, message); err != nil {
-				w.logger.Error("failed to handle approval response", "message_id", message.ID, "error", err)
-			}
-
-			// ACK message
-			if err := w.redis.AckStreamMessage(ctx, w.responseStream, w.responseConsumerGroup, message.ID); err != nil {
-				w.logger.Error("failed to ACK response message", "message_id", message.ID, "error", err)
-			}
-		}
-	}
-
+	w.logger.Info("HITL worker stopping")
 	return nil
 }
 
@@ -197,14 +129,24 @@ func (w *HITLWorker) handleApprovalRequest(ctx context.Context, message redis.XM
 		return fmt.Errorf("failed to unmarshal token map: %w", err)
 	}
 
-	w.logger.Info("processing approval request",
-		"run_id", token.RunID,
-		"node_id", token.ToNode,
-		"token_id", token.ID)
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "node.execute",
+		tracing.AttrRunID, token.RunID,
+		tracing.AttrNodeID, token.ToNode,
+		tracing.AttrNodeType, "hitl",
+		tracing.AttrStream, w.requestStream)
+	defer span.End()
+
+	// Node logs are tagged with the run and node, and readable through the run's logs endpoint
+	nodeLog := worker.NewNodeLogger(w.redis.GetUnderlying(), w.logger, &token)
+	nodeLog.Info("processing approval request", "token_id", token.ID)
 
 	// Capture metrics at start
 	runtimeMetrics := metrics.CaptureStart(ctx)
 	startTime := time.Now()
+	if err := sdk.RecordNodeStarted(ctx, w.redis.GetUnderlying(), token.RunID, token.ToNode, startTime); err != nil {
+		w.logger.Warn("failed to record node start", "run_id", token.RunID, "node_id", token.ToNode, "error", err)
+	}
 
 	// Calculate queue time
 	var queueTimeMs int64 = 0
@@ -288,9 +230,7 @@ func (w *HITLWorker) handleApprovalRequest(ctx context.Context, message redis.XM
 	}
 
 	if !wasCreated {
-		w.logger.Warn("approval already exists, skipping",
-			"run_id", token.RunID,
-			"node_id", token.ToNode)
+		nodeLog.Warn("approval already exists, skipping")
 		// INCR still happened for both counters, need to DECR both to maintain accuracy
 		if _, err := w.redis.Decrement(ctx, workflowCounterKey); err != nil {
 			w.logger.Error("failed to decrement workflow counter after duplicate", "error", err)
@@ -301,20 +241,20 @@ func (w *HITLWorker) handleApprovalRequest(ctx context.Context, message redis.XM
 		return nil
 	}
 
+	metrics.HITLPendingApprovals.Inc()
+
 	workflowCount, _ := tx.GetIntResult(workflowIncrLabel)
 	runCount, _ := tx.GetIntResult(runIncrLabel)
-	w.logger.Info("approval request created",
-		"run_id", token.RunID,
-		"node_id", token.ToNode,
+	nodeLog.Info("approval request created",
 		"username", username,
 		"workflow_tag", workflowTag,
 		"workflow_pending_count", workflowCount,
 		"run_pending_count", runCount)
 
 	// Set node status to "waiting_for_approval" in Redis
-	nodeStatusKey := fmt.Sprintf("run:%s:node:%s:status", token.RunID, token.ToNode)
-	if err := w.redis.Set(ctx, nodeStatusKey, "waiting_for_approval", 24*time.Hour); err != nil {
-		w.logger.Error("failed to set node status", "error", err)
+	// (no-op if the node was cancelled before the request was created)
+	if _, err := sdk.SetNodeStatus(ctx, w.redis.GetUnderlying(), token.RunID, token.ToNode, sdk.NodeStatusWaitingForApproval); err != nil {
+		nodeLog.Error("failed to set node status", "error", err)
 	}
 
 	// Set run status to "WAITING_FOR_APPROVAL"
@@ -325,7 +265,7 @@ func (w *HITLWorker) handleApprovalRequest(ctx context.Context, message redis.XM
 
 	// Publish event to notify user via fanout
 	if err := w.publishApprovalRequest(ctx, token.RunID, token.ToNode, workflowTag, config); err != nil {
-		w.logger.Error("failed to publish approval request event", "error", err)
+		nodeLog.Error("failed to publish approval request event", "error", err)
 	}
 
 	// Finalize metrics
@@ -333,9 +273,7 @@ func (w *HITLWorker) handleApprovalRequest(ctx context.Context, message redis.XM
 	runtimeMetrics.Finalize(ctx)
 	executionTimeMs := endTime.Sub(startTime).Milliseconds()
 
-	w.logger.Info("approval request processed",
-		"run_id", token.RunID,
-		"node_id", token.ToNode,
+	nodeLog.Info("approval request processed",
 		"queue_time_ms", queueTimeMs,
 		"execution_time_ms", executionTimeMs)
 
@@ -365,10 +303,16 @@ func (w *HITLWorker) handleApprovalResponse(ctx context.Context, message redis.X
 		return fmt.Errorf("approval missing run_id or node_id")
 	}
 
-	w.logger.Info("processing approval response",
-		"run_id", runID,
-		"node_id", nodeID,
-		"approved", approved)
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "hitl.decision",
+		tracing.AttrRunID, runID,
+		tracing.AttrNodeID, nodeID,
+		tracing.AttrNodeType, "hitl",
+		tracing.AttrStream, w.responseStream)
+	defer span.End()
+
+	nodeLog := worker.NewNodeLogger(w.redis.GetUnderlying(), w.logger, &sdk.Token{RunID: runID, ToNode: nodeID})
+	nodeLog.Info("processing approval response", "approved", approved)
 
 	// Capture metrics
 	runtimeMetrics := metrics.CaptureStart(ctx)
@@ -380,7 +324,7 @@ func (w *HITLWorker) handleApprovalResponse(ctx context.Context, message redis.X
 
 	// Retry logic for race condition (approval might not exist yet)
 	if err != nil {
-		w.logger.Warn("approval not found, retrying", "run_id", runID, "node_id", nodeID)
+		nodeLog.Warn("approval not found, retrying")
 		for i := 0; i < 3; i++ {
 			time.Sleep(time.Duration(i+1) * time.Second)
 			data, err = w.redis.Get(ctx, approvalKey)
@@ -403,10 +347,19 @@ func (w *HITLWorker) handleApprovalResponse(ctx context.Context, message redis.X
 
 	// Idempotency check: only proceed if status was "pending"
 	if previousStatus != "pending" {
-		w.logger.Warn("approval already processed",
-			"run_id", runID,
-			"node_id", nodeID,
-			"previous_status", previousStatus)
+		nodeLog.Warn("approval already processed", "previous_status", previousStatus)
+		return nil
+	}
+
+	// Claim the decision: responses are handled in parallel, and only one
+	// decision per approval may get past the pending check above
+	decisionKey := approvalKey + ":decision"
+	claimed, err := w.redis.SetNX(ctx, decisionKey, message.ID, 24*time.Hour)
+	if err != nil {
+		return fmt.Errorf("failed to claim approval decision: %w", err)
+	}
+	if !claimed {
+		nodeLog.Warn("approval already being decided")
 		return nil
 	}
 
@@ -494,10 +447,7 @@ func (w *HITLWorker) handleApprovalResponse(ctx context.Context, message redis.X
 	}
 
 	// Signal completion to coordinator
-	w.logger.Info("sending completion signal",
-		"run_id", runID,
-		"node_id", nodeID,
-		"approved", approved)
+	nodeLog.Info("sending completion signal", "approved", approved)
 
 	err = worker.SignalCompletion(ctx, w.redis.GetUnderlying(), w.logger, &worker.CompletionOpts{
 		Token:      &token,
@@ -509,8 +459,11 @@ func (w *HITLWorker) handleApprovalResponse(ctx context.Context, message redis.X
 	})
 
 	if err != nil {
+		// Release the claim so a redelivered decision can try again
+		w.redis.Delete(ctx, decisionKey)
 		return fmt.Errorf("failed to signal completion: %w", err)
 	}
+	metrics.HITLPendingApprovals.Dec()
 
 	// Update approval status in Redis to prevent duplicate processing
 	// This must happen AFTER successful completion signal
@@ -533,17 +486,14 @@ func (w *HITLWorker) handleApprovalResponse(ctx context.Context, message redis.X
 			w.logger.Error("failed to update approval status", "error", err)
 			// Don't return error - completion signal already sent successfully
 		} else {
-			w.logger.Info("updated approval status",
-				"run_id", runID,
-				"node_id", nodeID,
-				"status", newStatus)
+			nodeLog.Info("updated approval status", "status", newStatus)
 		}
 	}
 
 	// Clear node waiting status (node is now completed)
-	nodeStatusKey := fmt.Sprintf("run:%s:node:%s:status", runID, nodeID)
-	if err := w.redis.Set(ctx, nodeStatusKey, "completed", 24*time.Hour); err != nil {
-		w.logger.Error("failed to update node status", "error", err)
+	// (a cancelled node keeps its cancelled status)
+	if _, err := sdk.SetNodeStatus(ctx, w.redis.GetUnderlying(), runID, nodeID, sdk.NodeStatusCompleted); err != nil {
+		nodeLog.Error("failed to update node status", "error", err)
 	}
 
 	// Note: Run status will be updated by coordinator/status manager based on overall workflow state
@@ -10,8 +10,8 @@ import (
 	"github.com/lyzr/orchestrator/cmd/http-worker/worker"
 	"github.com/lyzr/orchestrator/common/bootstrap"
 	"github.com/lyzr/orchestrator/common/clients"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
 	"github.com/lyzr/orchestrator/common/sdk"
-	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -29,7 +29,7 @@ func main() {
 	components.Logger.Info("http-worker starting")
 
 	// Create Redis client
-	redisClient, err := createRedisClient()
+	redisClient, err := rediscommon.NewUniversalClientFromEnv()
 	if err != nil {
 		components.Logger.Error("failed to create Redis client", "error", err)
 		os.Exit(1)
@@ -50,7 +50,11 @@ func main() {
 	}
 
 	// Create CAS client
-	casClient := clients.NewRedisCASClient(redisClient, components.Logger)
+	casClient, err := clients.NewCASClient(components.Config.CAS, redisClient, components.Logger)
+	if err != nil {
+		components.Logger.Error("failed to create CAS client", "error", err)
+		os.Exit(1)
+	}
 
 	// Create SDK
 	workflowSDK := sdk.NewSDK(redisClient, casClient, components.Logger, string(luaScript))
@@ -83,27 +87,3 @@ func main() {
 
 	components.Logger.Info("http-worker shutting down gracefully")
 }
-
-// createRedisClient creates a Redis client from environment variables
-func createRedisClient() (*redis.Client, error) {
-	redisHost := getEnv("REDIS_HOST", "localhost")
-	redisPort := getEnv("REDIS_PORT", "6379")
-	redisPassword := getEnv("REDIS_PASSWORD", "")
-	redisDB := 0
-
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
-		Password: redisPassword,
-		DB:       redisDB,
-	})
-
-	return client, nil
-}
-
-// getEnv gets an environment variable or returns a default
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
@@ -12,14 +12,16 @@ import (
 	"github.com/google/uuid"
 	"github.com/lyzr/orchestrator/cmd/http-worker/security"
 	"github.com/lyzr/orchestrator/common/metrics"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
 	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
 	"github.com/lyzr/orchestrator/common/worker"
 	"github.com/redis/go-redis/v9"
 )
 
 // HTTPWorker processes HTTP tasks from Redis stream
 type HTTPWorker struct {
-	redis         *redis.Client
+	redis         redis.UniversalClient
 	sdk           *sdk.SDK
 	logger        sdk.Logger
 	stream        string
@@ -30,7 +32,7 @@ type HTTPWorker struct {
 }
 
 // NewHTTPWorker creates a new HTTP worker
-func NewHTTPWorker(redisClient *redis.Client, workflowSDK *sdk.SDK, logger sdk.Logger) *HTTPWorker {
+func NewHTTPWorker(redisClient redis.UniversalClient, workflowSDK *sdk.SDK, logger sdk.Logger) *HTTPWorker {
 	return &HTTPWorker{
 		redis:         redisClient,
 		sdk:           workflowSDK,
@@ -52,60 +54,22 @@ func (w *HTTPWorker) Start(ctx context.Context) error {
 		"consumer_group", w.consumerGroup,
 		"consumer_name", w.consumerName)
 
-	// Create consumer group if it doesn't exist
-	if err := w.redis.XGroupCreateMkStream(ctx, w.stream, w.consumerGroup, "0").Err(); err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
-		return fmt.Errorf("failed to create consumer group: %w", err)
-	}
-
-	// Process messages in a loop
-	for {
-		select {
-		case <-ctx.Done():
-			w.logger.Info("HTTP worker stopping")
-			return nil
-		default:
-			if err := w.processNextMessage(ctx); err != nil {
-				w.logger.Error("failed to process message", "error", err)
-				time.Sleep(1 * time.Second) // Back off on error
-			}
+	client := redisWrapper.NewClient(w.redis, w.logger)
+	for _, stream := range redisWrapper.PriorityStreams(w.stream) {
+		// Create consumer group if it doesn't exist
+		if err := client.CreateStreamGroup(ctx, stream, w.consumerGroup); err != nil {
+			return fmt.Errorf("failed to create consumer group: %w", err)
 		}
-	}
-}
 
-// processNextMessage reads and processes one message from the stream
-func (w *HTTPWorker) processNextMessage(ctx context.Context) error {
-	// Read message from stream (XREADGROUP)
-	streams, err := w.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
-		Group:    w.consumerGroup,
-		Consumer: w.consumerName,
-		Streams:  []string{w.stream, ">"},
-		Count:    1,
-		Block:    5 * time.Second,
-	}).Result()
-
-	if err == redis.Nil {
-		// No messages, continue
-		return nil
-	}
-	if err != nil {
-		return fmt.Errorf("XREADGROUP error: %w", err)
+		// Reclaim tasks left unacknowledged by crashed workers
+		go redisWrapper.NewReclaimer(client, stream, w.consumerGroup, w.consumerName, w.handleMessage).Run(ctx)
 	}
 
-	// Process each message
-	for _, stream := range streams {
-		for _, message := range stream.Messages {
-			if err := w.handleMessage(ctx, message); err != nil {
-				w.logger.Error("failed to handle message", "message_id", message.ID, "error", err)
-				// Continue to next message even if this one fails
-			}
-
-			// Acknowledge message
-			if err := w.redis.XAck(ctx, w.stream, w.consumerGroup, message.ID).Err(); err != nil {
-				w.logger.Error("failed to ACK message", "message_id", message.ID, "error", err)
-			}
-		}
-	}
+	// One request at a time, most urgent priority first
+	redisWrapper.NewConsumerPool(client, w.stream, w.consumerGroup, w.consumerName, w.handleMessage,
+		redisWrapper.WithPriorities(redisWrapper.DefaultPriorityFairness)).Run(ctx)
 
+	w.logger.Info("HTTP worker stopping")
 	return nil
 }
 
@@ -128,23 +92,26 @@ func (w *HTTPWorker) handleMessage(ctx context.Context, message redis.XMessage)
 		return fmt.Errorf("failed to unmarshal token map: %w", err)
 	}
 
-	w.logger.Info("processing HTTP task",
-		"run_id", token.RunID,
-		"node_id", token.ToNode,
-		"token_id", token.ID)
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "node.execute",
+		tracing.AttrRunID, token.RunID,
+		tracing.AttrNodeID, token.ToNode,
+		tracing.AttrNodeType, "http",
+		tracing.AttrStream, w.stream)
+	defer span.End()
+
+	// Node logs are tagged with the run and node, and readable through the run's logs endpoint
+	nodeLog := worker.NewNodeLogger(w.redis, w.logger, &token)
+	nodeLog.Info("processing HTTP task", "token_id", token.ID)
 
 	// Use pre-resolved config from token (coordinator has already resolved variables)
 	var config map[string]interface{}
 	if token.Config != nil {
 		config = token.Config
-		w.logger.Debug("using pre-resolved config from token",
-			"run_id", token.RunID,
-			"node_id", token.ToNode)
+		nodeLog.Debug("using pre-resolved config from token")
 	} else {
 		// Fallback: Load config from IR (backward compatibility)
-		w.logger.Warn("token missing config, falling back to IR",
-			"run_id", token.RunID,
-			"node_id", token.ToNode)
+		nodeLog.Warn("token missing config, falling back to IR")
 
 		irKey := fmt.Sprintf("ir:%s", token.RunID)
 		irJSON, err := w.redis.Get(ctx, irKey).Result()
@@ -181,6 +148,9 @@ func (w *HTTPWorker) handleMessage(ctx context.Context, message redis.XMessage)
 
 	// Track timing for metrics
 	startTime := time.Now()
+	if err := sdk.RecordNodeStarted(ctx, w.redis, token.RunID, token.ToNode, startTime); err != nil {
+		w.logger.Warn("failed to record node start", "run_id", token.RunID, "node_id", token.ToNode, "error", err)
+	}
 
 	// Calculate queue time (time from token sent_at to now)
 	var queueTimeMs int64 = 0
@@ -223,7 +193,7 @@ func (w *HTTPWorker) handleMessage(ctx context.Context, message redis.XMessage)
 	metricsMap["system"] = systemInfo.ToMap()
 
 	if err != nil {
-		w.logger.Error("HTTP request failed", "error", err)
+		nodeLog.Error("HTTP request failed", "error", err, "execution_time_ms", executionTimeMs)
 		// Signal failure with error metadata AND metrics
 		failureResult := map[string]interface{}{
 			"status":  "failed",
@@ -241,6 +211,10 @@ func (w *HTTPWorker) handleMessage(ctx context.Context, message redis.XMessage)
 		})
 	}
 
+	nodeLog.Info("HTTP request succeeded",
+		"status_code", result["status_code"],
+		"execution_time_ms", executionTimeMs)
+
 	// Add metrics to successful result
 	result["metrics"] = metricsMap
 
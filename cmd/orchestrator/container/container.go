@@ -2,11 +2,13 @@ package container
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/lyzr/orchestrator/common/repository"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/auth"
 	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/health"
 	"github.com/lyzr/orchestrator/common/ratelimit"
 	rediscommon "github.com/lyzr/orchestrator/common/redis"
 	"github.com/redis/go-redis/v9"
@@ -17,14 +19,20 @@ type Container struct {
 	// Components
 	Components *bootstrap.Components
 	Redis      *rediscommon.Client
-	RedisRaw   *redis.Client // Keep for backward compatibility if needed
+	RedisRaw   redis.UniversalClient // Keep for backward compatibility if needed
 	RateLimiter *ratelimit.RateLimiter
+	Tokens      *auth.TokenManager // nil if AUTH_TOKEN_SECRET is not configured
+	Readiness   *health.ReadinessChecker
+	Dependencies *health.DependencyChecker // DB and Redis reachability for /ready
+	ResultCAS    clients.CASClient         // Execution CAS the workflow runner writes node results to
 
 	// Repositories
 	RunRepo      *repository.RunRepository
 	ArtifactRepo *repository.ArtifactRepository
 	CASBlobRepo  *repository.CASBlobRepository
 	TagRepo      *repository.TagRepository
+	AuditRepo    *repository.AuditRepository
+	UserDataRepo *repository.UserDataRepository
 
 	// Services
 	CASService          *service.CASService
@@ -34,39 +42,80 @@ type Container struct {
 	WorkflowService     *service.WorkflowServiceV2
 	RunPatchService     *service.RunPatchService
 	RunService          *service.RunService
+	AuditService        *service.AuditService
+	UserDataService     *service.UserDataService
+	CompactionService   *service.CompactionService
+	AutoCompactor       *service.AutoCompactor
+	CASCollector        *service.CASGarbageCollector
+	StreamTrimmer       *rediscommon.StreamTrimmer
 }
 
 // NewContainer initializes all services and repositories once
 func NewContainer(components *bootstrap.Components) (*Container, error) {
 	// Create Redis client (raw)
-	redisRaw, err := createRedisClient()
+	redisConfig, err := rediscommon.ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis config: %w", err)
+	}
+
+	redisRaw, err := rediscommon.NewUniversalClient(redisConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create redis client: %w", err)
 	}
 
 	// Wrap with common redis client for instrumentation and common operations
-	redisClient := rediscommon.NewClient(redisRaw, components.Logger)
+	redisClient := rediscommon.NewClient(redisRaw, components.Logger, rediscommon.WithOperationTimeout(redisConfig.OperationTimeout))
 
 	// Initialize rate limiter for workflow-aware rate limiting
-	rateLimiter := ratelimit.NewRateLimiter(redisRaw, components.Logger)
+	rateLimiter := ratelimit.NewRateLimiter(redisRaw, components.Logger).
+		WithAlgorithm(ratelimit.Algorithm(components.Config.RateLimit.Algorithm)).
+		WithCompositeLimits(ratelimit.CompositeLimits{
+			Workflows: components.Config.RateLimit.WorkflowLimits,
+			Orgs:      components.Config.RateLimit.OrgLimits,
+		})
+
+	// Initialize token issuer for fanout WebSocket auth (optional)
+	tokens, err := auth.NewTokenManagerFromEnv()
+	if err != nil {
+		components.Logger.Warn("WebSocket token issuing disabled", "error", err)
+		tokens = nil
+	}
+
+	// Initialize readiness checker (stream backlog + consumer liveness)
+	readiness, err := health.NewReadinessChecker(redisRaw, components.Config.Readiness)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create readiness checker: %w", err)
+	}
+
+	// Initialize the execution CAS, on the same backend the workflow runner uses
+	resultCAS, err := clients.NewCASClient(components.Config.CAS, redisRaw, components.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CAS client: %w", err)
+	}
 
 	// Initialize repositories
 	runRepo := repository.NewRunRepository(components.DB)
 	artifactRepo := repository.NewArtifactRepository(components.DB)
 	casBlobRepo := repository.NewCASBlobRepository(components.DB)
 	tagRepo := repository.NewTagRepository(components.DB)
+	auditRepo := repository.NewAuditRepository(components.DB)
+	userDataRepo := repository.NewUserDataRepository(components.DB)
 
 	// Initialize services (bottom-up: dependencies first)
-	casService := service.NewCASService(casBlobRepo, components.Logger)
+	auditService := service.NewAuditService(auditRepo, components.Logger)
+	casService := service.NewCASService(casBlobRepo, components.Logger, components.Config.CAS)
 	artifactService := service.NewArtifactService(artifactRepo, components.Logger)
-	tagService := service.NewTagService(tagRepo, components.Logger)
-	materializerService := service.NewMaterializerService(components.Logger)
+	tagService := service.NewTagService(tagRepo, components.Logger).WithAudit(auditService)
+	materializerService := service.NewMaterializerService(components.Logger).WithCache(casService, redisClient)
 	workflowService := service.NewWorkflowServiceV2(
 		casService,
 		artifactService,
 		tagService,
+		materializerService,
 		components.Logger,
-	)
+	).WithLimits(components.Config.Limits).
+		WithCostWeights(components.Config.RateLimit.NodeCostWeights).
+		WithAudit(auditService)
 
 	// Initialize RunPatchRepository and RunPatchService
 	runPatchRepo := repository.NewRunPatchRepository(components.DB)
@@ -76,7 +125,7 @@ func NewContainer(components *bootstrap.Components) (*Container, error) {
 		casService,
 		artifactRepo,
 		components,
-	)
+	).WithAudit(auditService)
 
 	runService := service.NewRunService(&service.RunServiceOpts{
 		RunRepo:         runRepo,
@@ -87,18 +136,47 @@ func NewContainer(components *bootstrap.Components) (*Container, error) {
 		RunPatchService: runPatchService,
 		Components:      components,
 		Redis:           redisClient,
+		ResultCAS:       resultCAS,
 		RateLimiter:     rateLimiter,
+		Audit:           auditService,
 	})
 
+	userDataService := service.NewUserDataService(userDataRepo, redisClient, components.Logger).WithAudit(auditService)
+
+	// Initialize compaction (auto compaction runs only if enabled, see main.go)
+	compactionService := service.NewCompactionService(
+		artifactRepo,
+		casBlobRepo,
+		tagRepo,
+		tagService,
+		casService,
+		materializerService,
+		redisClient,
+		components.Logger,
+	)
+	autoCompactor := service.NewAutoCompactor(compactionService, redisClient, components.Config.Compaction, components.Logger)
+
+	// Initialize CAS garbage collection (runs only if enabled, see main.go)
+	casCollector := service.NewCASGarbageCollector(casBlobRepo, redisClient, components.Config.CASGC, components.Logger)
+
+	// Initialize stream trimming (runs only if enabled, see main.go)
+	streamTrimmer := rediscommon.NewStreamTrimmer(redisClient, components.Config.StreamTrim)
+
 	return &Container{
 		Components:          components,
 		Redis:               redisClient,
 		RedisRaw:            redisRaw,
 		RateLimiter:         rateLimiter,
+		Tokens:              tokens,
+		Readiness:           readiness,
+		Dependencies:        components.Dependencies(redisRaw),
+		ResultCAS:           resultCAS,
 		RunRepo:             runRepo,
 		ArtifactRepo:        artifactRepo,
 		CASBlobRepo:         casBlobRepo,
 		TagRepo:             tagRepo,
+		AuditRepo:           auditRepo,
+		UserDataRepo:        userDataRepo,
 		CASService:          casService,
 		ArtifactService:     artifactService,
 		TagService:          tagService,
@@ -106,28 +184,11 @@ func NewContainer(components *bootstrap.Components) (*Container, error) {
 		WorkflowService:     workflowService,
 		RunPatchService:     runPatchService,
 		RunService:          runService,
+		AuditService:        auditService,
+		UserDataService:     userDataService,
+		CompactionService:   compactionService,
+		AutoCompactor:       autoCompactor,
+		CASCollector:        casCollector,
+		StreamTrimmer:       streamTrimmer,
 	}, nil
 }
-
-// createRedisClient creates a Redis client from environment variables
-func createRedisClient() (*redis.Client, error) {
-	redisHost := getEnv("REDIS_HOST", "localhost")
-	redisPort := getEnv("REDIS_PORT", "6379")
-	redisPassword := getEnv("REDIS_PASSWORD", "")
-
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
-		Password: redisPassword,
-		DB:       0,
-	})
-
-	return client, nil
-}
-
-// getEnv gets an environment variable or returns a default
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
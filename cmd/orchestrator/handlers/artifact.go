@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
 	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/models"
 )
 
 // ArtifactHandler handles artifact-related operations
@@ -26,8 +28,11 @@ func NewArtifactHandler(components *bootstrap.Components, casService *service.CA
 	}
 }
 
-// GetArtifact retrieves an artifact and its content by ID
+// GetArtifact retrieves an artifact, its content and its lineage by ID
 // GET /api/v1/artifacts/:id
+//
+// The lineage shows where the artifact comes from: a patch_set's base version
+// and ordered patch chain, or the patch a compacted base was compacted from.
 func (h *ArtifactHandler) GetArtifact(c echo.Context) error {
 	artifactIDStr := c.Param("id")
 
@@ -67,13 +72,117 @@ func (h *ArtifactHandler) GetArtifact(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to parse artifact content")
 	}
 
-	// Return artifact metadata and content
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"artifact_id": artifact.ArtifactID,
-		"kind":        artifact.Kind,
-		"cas_id":      artifact.CasID,
-		"created_by":  artifact.CreatedBy,
-		"created_at":  artifact.CreatedAt,
-		"content":     contentJSON, // Now returns as JSON object, not base64
-	})
+	lineage, err := h.artifactSvc.GetLineage(c.Request().Context(), artifact)
+	if err != nil {
+		h.components.Logger.Error("failed to get artifact lineage", "artifact_id", artifactID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve artifact lineage")
+	}
+
+	// Return artifact metadata, content and lineage
+	response := artifactResponse(artifact, contentJSON)
+	response["lineage"] = lineage
+	return c.JSON(http.StatusOK, response)
+}
+
+// ArtifactsRequest is the body of a bulk artifact lookup
+type ArtifactsRequest struct {
+	ArtifactIDs []string `json:"artifact_ids"`
+}
+
+// GetArtifacts retrieves many artifacts and their content at once
+// POST /api/v1/artifacts/batch
+// Body: {"artifact_ids": ["<artifact_id>", ...]} (at most service.MaxArtifactBatch)
+// Returns {artifact_id: artifact} shaped like GetArtifact without lineage;
+// unknown artifacts are omitted. Metadata and content each take one query.
+func (h *ArtifactHandler) GetArtifacts(c echo.Context) error {
+	var req ArtifactsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if len(req.ArtifactIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "artifact_ids is required")
+	}
+	if len(req.ArtifactIDs) > service.MaxArtifactBatch {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d artifacts can be requested at once", service.MaxArtifactBatch))
+	}
+
+	artifactIDs := make([]uuid.UUID, 0, len(req.ArtifactIDs))
+	seen := make(map[uuid.UUID]bool, len(req.ArtifactIDs))
+	for _, id := range req.ArtifactIDs {
+		artifactID, err := uuid.Parse(id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid artifact_id format: %q", id))
+		}
+		if !seen[artifactID] {
+			seen[artifactID] = true
+			artifactIDs = append(artifactIDs, artifactID)
+		}
+	}
+
+	ctx := c.Request().Context()
+
+	artifacts, err := h.artifactSvc.GetByIDs(ctx, artifactIDs)
+	if err != nil {
+		h.components.Logger.Error("failed to get artifacts", "count", len(artifactIDs), "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get artifacts")
+	}
+
+	casIDs := make([]string, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		casIDs = append(casIDs, artifact.CasID)
+	}
+	contents, err := h.casService.GetContentBulk(ctx, casIDs)
+	if err != nil {
+		h.components.Logger.Error("failed to get artifact contents", "count", len(casIDs), "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve artifact content")
+	}
+
+	response := make(map[string]interface{}, len(artifacts))
+	for artifactID, artifact := range artifacts {
+		content, ok := contents.Found[artifact.CasID]
+		if !ok {
+			h.components.Logger.Error("artifact content unavailable",
+				"artifact_id", artifactID,
+				"cas_id", artifact.CasID,
+				"error", contents.Unreadable[artifact.CasID])
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve artifact content")
+		}
+
+		var contentJSON interface{}
+		if err := json.Unmarshal(content, &contentJSON); err != nil {
+			h.components.Logger.Error("failed to unmarshal artifact content",
+				"artifact_id", artifactID,
+				"error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to parse artifact content")
+		}
+
+		response[artifactID.String()] = artifactResponse(artifact, contentJSON)
+	}
+
+	h.components.Logger.Info("artifacts fetched successfully",
+		"requested", len(artifactIDs),
+		"found", len(artifacts))
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// artifactResponse is an artifact's metadata with its parsed content
+func artifactResponse(artifact *models.Artifact, content interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"artifact_id":       artifact.ArtifactID,
+		"kind":              artifact.Kind,
+		"cas_id":            artifact.CasID,
+		"name":              artifact.Name,
+		"version_hash":      artifact.VersionHash,
+		"depth":             artifact.Depth,
+		"base_version":      artifact.BaseVersion,
+		"compacted_from_id": artifact.CompactedFromID,
+		"op_count":          artifact.OpCount,
+		"nodes_count":       artifact.NodesCount,
+		"edges_count":       artifact.EdgesCount,
+		"meta":              artifact.Meta,
+		"created_by":        artifact.CreatedBy,
+		"created_at":        artifact.CreatedAt,
+		"content":           content, // Returned as a JSON object, not base64
+	}
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+// AuditHandler serves the audit log of workflow, tag and run mutations
+type AuditHandler struct {
+	components *bootstrap.Components
+	audit      *service.AuditService
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(components *bootstrap.Components, audit *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		components: components,
+		audit:      audit,
+	}
+}
+
+// ListAuditLog returns a page of the audit log, newest first
+// GET /api/v1/audit?owner=alice&tag=main&user=bob&since=2025-01-01T00:00:00Z&limit=50&cursor=...
+// Tag names are per user, so filtering on tag needs the tag's owner.
+func (h *AuditHandler) ListAuditLog(c echo.Context) error {
+	opts, err := parseAuditListOptions(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.audit.List(c.Request().Context(), opts)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusOK, page)
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	default:
+		h.components.Logger.Error("failed to list audit log", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list audit log")
+	}
+}
+
+// parseAuditListOptions reads audit log filters from the query string
+func parseAuditListOptions(c echo.Context) (repository.AuditListOptions, error) {
+	opts := repository.AuditListOptions{
+		Actor:    c.QueryParam("user"),
+		TagOwner: c.QueryParam("owner"),
+		TagName:  c.QueryParam("tag"),
+		Cursor:   c.QueryParam("cursor"),
+	}
+	if opts.TagName != "" && opts.TagOwner == "" {
+		return opts, fmt.Errorf("tag %q needs its owner: tag names are per user", opts.TagName)
+	}
+
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return opts, fmt.Errorf("invalid limit %q", limitStr)
+		}
+		opts.Limit = limit
+	}
+
+	if since := c.QueryParam("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return opts, fmt.Errorf("invalid since %q: expected RFC3339", since)
+		}
+		opts.Since = t
+	}
+
+	return opts, nil
+}
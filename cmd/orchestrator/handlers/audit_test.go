@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuditListOptions(t *testing.T) {
+	parse := func(query string) (string, string, error) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/audit"+query, nil)
+		opts, err := parseAuditListOptions(echo.New().NewContext(req, httptest.NewRecorder()))
+		return opts.TagOwner, opts.TagName, err
+	}
+
+	owner, tag, err := parse("?owner=alice&tag=main")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", owner)
+	assert.Equal(t, "main", tag)
+
+	// Every one of an owner's tags
+	owner, tag, err = parse("?owner=alice")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", owner)
+	assert.Empty(t, tag)
+
+	// A bare tag name would match every user's tag of that name
+	_, _, err = parse("?tag=main")
+	assert.ErrorContains(t, err, "needs its owner")
+}
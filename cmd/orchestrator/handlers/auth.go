@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+	"github.com/lyzr/orchestrator/common/auth"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+)
+
+// AuthHandler issues short-lived tokens for services outside the API (fanout)
+type AuthHandler struct {
+	components *bootstrap.Components
+	tokens     *auth.TokenManager
+}
+
+// NewAuthHandler creates a new auth handler
+// tokens may be nil, in which case token issuing returns 503.
+func NewAuthHandler(components *bootstrap.Components, tokens *auth.TokenManager) *AuthHandler {
+	return &AuthHandler{
+		components: components,
+		tokens:     tokens,
+	}
+}
+
+// IssueWebSocketToken issues a token binding a fanout WebSocket to the caller
+// POST /api/v1/auth/ws-token
+func (h *AuthHandler) IssueWebSocketToken(c echo.Context) error {
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	if h.tokens == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]interface{}{
+			"error": "token issuing is not configured",
+		})
+	}
+
+	token, err := h.tokens.Issue(username, auth.DefaultTokenTTL)
+	if err != nil {
+		h.components.Logger.Error("failed to issue websocket token", "username", username, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "failed to issue token",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"expires_in": int(auth.DefaultTokenTTL.Seconds()),
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseJSONPointer splits an RFC 6901 JSON Pointer into unescaped reference tokens
+// e.g. "/nodes/2/config/a~1b" → ["nodes", "2", "config", "a/b"]. The empty
+// pointer (whole document) yields no tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with '/'", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// Only ~0 and ~1 are valid escapes
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 >= len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid JSON pointer %q: bad escape in %q", pointer, token)
+			}
+		}
+		// Order matters: ~1 first, so "~01" becomes "~1" and not "/"
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// parseArrayIndex parses an array reference token
+// Leading zeros and signs are rejected per RFC 6901. If allowEnd is set, "-"
+// (one past the last element) is accepted and returned as length.
+func parseArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" {
+		if allowEnd {
+			return length, nil
+		}
+		return 0, fmt.Errorf("index '-' is only valid for add")
+	}
+
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid array index %q", token)
+		}
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of range (length %d)", index, length)
+	}
+
+	return index, nil
+}
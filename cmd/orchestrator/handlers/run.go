@@ -3,9 +3,12 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -13,7 +16,9 @@ import (
 	"github.com/lyzr/orchestrator/common/compiler"
 	"github.com/lyzr/orchestrator/common/bootstrap"
 	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/models"
 	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/repository"
 	"github.com/lyzr/orchestrator/common/sdk"
 )
 
@@ -23,12 +28,15 @@ type RunHandler struct {
 	redis      *rediscommon.Client
 	casClient  clients.CASClient
 	runService *service.RunService
+	runEvents  runEventSource
+	runWait    runWaitSource
 }
 
 // PatchRequest represents a request to patch a workflow
 type PatchRequest struct {
 	Operations  []PatchOperation `json:"operations"`
 	Description string           `json:"description"`
+	AutoSuffix  bool             `json:"auto_suffix"` // Rename added nodes whose id is taken instead of rejecting them
 }
 
 // PatchOperation represents a JSON Patch operation
@@ -45,6 +53,8 @@ func NewRunHandler(components *bootstrap.Components, redis *rediscommon.Client,
 		redis:      redis,
 		casClient:  casClient,
 		runService: runService,
+		runEvents:  runService,
+		runWait:    runService,
 	}
 }
 
@@ -66,8 +76,7 @@ func (h *RunHandler) PatchRun(c echo.Context) error {
 	irKey := fmt.Sprintf("ir:%s", runID)
 	irJSON, err := h.redis.Get(c.Request().Context(), irKey)
 	if err != nil {
-		// Check if it's a "not found" error
-		if err.Error() == fmt.Sprintf("key not found: %s", irKey) {
+		if errors.Is(err, rediscommon.ErrKeyNotFound) {
 			return echo.NewHTTPError(http.StatusNotFound, "run not found")
 		}
 		h.components.Logger.Error("failed to load IR", "run_id", runID, "error", err)
@@ -80,11 +89,18 @@ func (h *RunHandler) PatchRun(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to parse workflow IR")
 	}
 
-	// 2. Convert IR to workflow schema
+	// 2. Reject if the workflow is locked against runtime patching
+	if !currentIR.AllowsRuntimePatch() {
+		h.components.Logger.Warn("runtime patch rejected, disabled by workflow",
+			"run_id", runID)
+		return echo.NewHTTPError(http.StatusForbidden, "runtime patching is disabled for this workflow")
+	}
+
+	// 3. Convert IR to workflow schema
 	workflowSchema := h.irToWorkflowSchema(&currentIR)
 
-	// 3. Apply JSON Patch operations
-	patchedSchema, err := h.applyPatch(workflowSchema, req.Operations)
+	// 4. Apply JSON Patch operations
+	patchedSchema, renamedNodes, err := h.applyPatch(workflowSchema, req.Operations, PatchOptions{AutoSuffix: req.AutoSuffix})
 	if err != nil {
 		h.components.Logger.Warn("failed to apply patch",
 			"run_id", runID,
@@ -92,7 +108,7 @@ func (h *RunHandler) PatchRun(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to apply patch: %v", err))
 	}
 
-	// 4. Recompile to IR
+	// 5. Recompile to IR
 	newIR, err := compiler.CompileWorkflowSchema(patchedSchema, h.casClient)
 	if err != nil {
 		h.components.Logger.Warn("failed to compile patched workflow",
@@ -101,41 +117,46 @@ func (h *RunHandler) PatchRun(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("failed to compile patched workflow: %v", err))
 	}
 
-	// 5. Update Redis with new IR
+	// 6. Update Redis with new IR
 	newIRJSON, err := json.Marshal(newIR)
 	if err != nil {
 		h.components.Logger.Error("failed to marshal new IR", "run_id", runID, "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to serialize new IR")
 	}
 
-	if err := h.redis.Set(c.Request().Context(), irKey, string(newIRJSON), 0); err != nil {
+	if err := h.redis.Set(c.Request().Context(), irKey, string(newIRJSON), sdk.RunStateTTL); err != nil {
 		h.components.Logger.Error("failed to update IR in Redis",
 			"run_id", runID,
 			"error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update workflow IR")
 	}
 
-	// 6. Log event
+	// 7. Log event
 	h.components.Logger.Info("workflow patched successfully",
 		"run_id", runID,
 		"old_nodes", len(currentIR.Nodes),
 		"new_nodes", len(newIR.Nodes),
 		"description", req.Description)
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"run_id":      runID,
 		"patched":     true,
 		"old_nodes":   len(currentIR.Nodes),
 		"new_nodes":   len(newIR.Nodes),
 		"description": req.Description,
-	})
+	}
+	if len(renamedNodes) > 0 {
+		response["renamed_nodes"] = renamedNodes
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
 // irToWorkflowSchema converts IR back to workflow schema format
 func (h *RunHandler) irToWorkflowSchema(ir *sdk.IR) *compiler.WorkflowSchema {
 	schema := &compiler.WorkflowSchema{
-		Nodes: make([]compiler.WorkflowNode, 0, len(ir.Nodes)),
-		Edges: []compiler.WorkflowEdge{},
+		Nodes:    make([]compiler.WorkflowNode, 0, len(ir.Nodes)),
+		Edges:    []compiler.WorkflowEdge{},
+		Metadata: ir.Metadata, // Keep run metadata (username, tag, flags) across patches
 	}
 
 	// Convert nodes
@@ -144,10 +165,14 @@ func (h *RunHandler) irToWorkflowSchema(ir *sdk.IR) *compiler.WorkflowSchema {
 			ID:     node.ID,
 			Type:   node.Type,
 			Config: make(map[string]interface{}),
+			Retry:  node.Retry,
 		}
 
-		// Load config from CAS if available
-		if node.ConfigRef != "" {
+		// Use inline config, or load it from CAS if it was externalized
+		for key, value := range node.Config {
+			wfNode.Config[key] = value
+		}
+		if len(node.Config) == 0 && node.ConfigRef != "" {
 			configData, err := h.casClient.Get(context.Background(), node.ConfigRef)
 			if err == nil {
 				if bytes, ok := configData.([]byte); ok {
@@ -172,6 +197,12 @@ func (h *RunHandler) irToWorkflowSchema(ir *sdk.IR) *compiler.WorkflowSchema {
 			}
 		}
 
+		// Handle map config
+		if node.Map != nil {
+			wfNode.Config["over"] = node.Map.Over
+			wfNode.Config["body"] = node.Map.Body
+		}
+
 		// Handle branch config
 		if node.Branch != nil && node.Branch.Enabled {
 			wfNode.Type = "conditional"
@@ -217,8 +248,17 @@ func (h *RunHandler) irToWorkflowSchema(ir *sdk.IR) *compiler.WorkflowSchema {
 }
 
 // applyPatch applies JSON Patch operations to the workflow schema
-func (h *RunHandler) applyPatch(schema *compiler.WorkflowSchema, operations []PatchOperation) (*compiler.WorkflowSchema, error) {
+// An added node whose id is taken is rejected with a *DuplicateNodeIDError, or
+// renamed with opts.AutoSuffix; renames are returned, new id to requested id.
+// Edges added after a renamed node use its new id for the requested one.
+func (h *RunHandler) applyPatch(schema *compiler.WorkflowSchema, operations []PatchOperation, opts PatchOptions) (*compiler.WorkflowSchema, map[string]string, error) {
 	// For MVP, we'll handle the most common operation: adding a node
+	taken := make(map[string]bool, len(schema.Nodes))
+	for _, node := range schema.Nodes {
+		taken[node.ID] = true
+	}
+	renamed := make(map[string]string)
+	rewired := make(map[string]string) // Requested id → the node added under it
 
 	for _, op := range operations {
 		switch op.Op {
@@ -227,17 +267,28 @@ func (h *RunHandler) applyPatch(schema *compiler.WorkflowSchema, operations []Pa
 				// Add node to the end
 				nodeMap, ok := op.Value.(map[string]interface{})
 				if !ok {
-					return nil, fmt.Errorf("invalid node value")
+					return nil, nil, fmt.Errorf("invalid node value")
 				}
 
 				node := compiler.WorkflowNode{}
 				nodeJSON, err := json.Marshal(nodeMap)
 				if err != nil {
-					return nil, fmt.Errorf("failed to marshal node: %w", err)
+					return nil, nil, fmt.Errorf("failed to marshal node: %w", err)
 				}
 				if err := json.Unmarshal(nodeJSON, &node); err != nil {
-					return nil, fmt.Errorf("failed to unmarshal node: %w", err)
+					return nil, nil, fmt.Errorf("failed to unmarshal node: %w", err)
+				}
+
+				if taken[node.ID] {
+					if !opts.AutoSuffix {
+						return nil, nil, &DuplicateNodeIDError{NodeID: node.ID}
+					}
+					renamedID := uniqueNodeID(node.ID, taken)
+					renamed[renamedID] = node.ID
+					rewired[node.ID] = renamedID
+					node.ID = renamedID
 				}
+				taken[node.ID] = true
 
 				schema.Nodes = append(schema.Nodes, node)
 
@@ -245,53 +296,72 @@ func (h *RunHandler) applyPatch(schema *compiler.WorkflowSchema, operations []Pa
 				// Add edge to the end
 				edgeMap, ok := op.Value.(map[string]interface{})
 				if !ok {
-					return nil, fmt.Errorf("invalid edge value")
+					return nil, nil, fmt.Errorf("invalid edge value")
 				}
 
 				edge := compiler.WorkflowEdge{}
 				edgeJSON, err := json.Marshal(edgeMap)
 				if err != nil {
-					return nil, fmt.Errorf("failed to marshal edge: %w", err)
+					return nil, nil, fmt.Errorf("failed to marshal edge: %w", err)
 				}
 				if err := json.Unmarshal(edgeJSON, &edge); err != nil {
-					return nil, fmt.Errorf("failed to unmarshal edge: %w", err)
+					return nil, nil, fmt.Errorf("failed to unmarshal edge: %w", err)
+				}
+				if id, ok := rewired[edge.From]; ok {
+					edge.From = id
+				}
+				if id, ok := rewired[edge.To]; ok {
+					edge.To = id
 				}
 
 				schema.Edges = append(schema.Edges, edge)
 
 			} else {
-				return nil, fmt.Errorf("unsupported add path: %s", op.Path)
+				return nil, nil, fmt.Errorf("unsupported add path: %s", op.Path)
 			}
 
 		case "remove":
 			// TODO: Implement remove operation
-			return nil, fmt.Errorf("remove operation not yet implemented")
+			return nil, nil, fmt.Errorf("remove operation not yet implemented")
 
 		case "replace":
 			// TODO: Implement replace operation
-			return nil, fmt.Errorf("replace operation not yet implemented")
+			return nil, nil, fmt.Errorf("replace operation not yet implemented")
 
 		default:
-			return nil, fmt.Errorf("unsupported operation: %s", op.Op)
+			return nil, nil, fmt.Errorf("unsupported operation: %s", op.Op)
 		}
 	}
 
-	return schema, nil
+	return schema, renamed, nil
 }
 
+// maxIdempotencyKeyLength bounds the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
 // ExecuteWorkflow creates a new workflow run with materialized workflow
+// An Idempotency-Key header makes retries return the run the first request created.
 func (h *RunHandler) ExecuteWorkflow(c echo.Context) error {
 	ctx := c.Request().Context()
 	tagName := c.Param("tag")
 
 	// Parse request
 	var req struct {
-		Inputs map[string]interface{} `json:"inputs"`
+		Inputs         map[string]interface{} `json:"inputs"`
+		Priority       string                 `json:"priority"`
+		MaxConcurrency int                    `json:"max_concurrency"`
 	}
 
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
 	}
+	if !rediscommon.ValidPriority(req.Priority) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("invalid priority %q (expected high, normal or low)", req.Priority))
+	}
+	if req.MaxConcurrency < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "max_concurrency must not be negative")
+	}
 
 	// Extract username from context
 	username, ok := c.Get("username").(string)
@@ -306,9 +376,16 @@ func (h *RunHandler) ExecuteWorkflow(c echo.Context) error {
 	// Create run using RunService
 	// This will: materialize workflow, store as artifact, create run entry, publish to stream
 	createReq := &service.CreateRunRequest{
-		Tag:      tagName,
-		Username: username,
-		Inputs:   req.Inputs,
+		Tag:            tagName,
+		Username:       username,
+		Inputs:         req.Inputs,
+		Priority:       req.Priority,
+		MaxConcurrency: req.MaxConcurrency,
+		IdempotencyKey: c.Request().Header.Get("Idempotency-Key"),
+	}
+	if len(createReq.IdempotencyKey) > maxIdempotencyKeyLength {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
 	}
 
 	response, err := h.runService.CreateRun(ctx, createReq)
@@ -318,6 +395,7 @@ func (h *RunHandler) ExecuteWorkflow(c echo.Context) error {
 			h.components.Logger.Warn("rate limit exceeded",
 				"username", username,
 				"tier", rateLimitErr.Tier,
+				"dimension", rateLimitErr.Dimension,
 				"limit", rateLimitErr.Limit)
 
 			return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
@@ -325,6 +403,8 @@ func (h *RunHandler) ExecuteWorkflow(c echo.Context) error {
 				"message": rateLimitErr.Error(),
 				"details": map[string]interface{}{
 					"tier":                rateLimitErr.Tier.String(),
+					"dimension":           rateLimitErr.Dimension,
+					"target":              rateLimitErr.Target,
 					"limit":               rateLimitErr.Limit,
 					"window":              "60 seconds",
 					"current_count":       rateLimitErr.CurrentCount,
@@ -333,6 +413,21 @@ func (h *RunHandler) ExecuteWorkflow(c echo.Context) error {
 			})
 		}
 
+		var inputsErr *service.InputsError
+		if errors.As(err, &inputsErr) {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error":    "invalid_inputs",
+				"message":  inputsErr.Error(),
+				"problems": inputsErr.Problems,
+			})
+		}
+		if errors.Is(err, service.ErrInvalidSubworkflow) || errors.Is(err, service.ErrInvalidInputSchema) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		if errors.Is(err, service.ErrIdempotencyKeyInUse) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+
 		h.components.Logger.Error("failed to create run", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("failed to create run: %v", err))
 	}
@@ -340,7 +435,13 @@ func (h *RunHandler) ExecuteWorkflow(c echo.Context) error {
 	h.components.Logger.Info("run created successfully",
 		"run_id", response.RunID,
 		"artifact_id", response.ArtifactID,
-		"tag", tagName)
+		"tag", tagName,
+		"replayed", response.Replayed)
+
+	// Replays answer exactly like the original submission did
+	if response.Replayed {
+		c.Response().Header().Set("Idempotent-Replayed", "true")
+	}
 
 	return c.JSON(http.StatusCreated, map[string]interface{}{
 		"run_id":      response.RunID.String(),
@@ -370,6 +471,120 @@ func (h *RunHandler) GetRun(c echo.Context) error {
 	return c.JSON(http.StatusOK, run)
 }
 
+// RunStatusesRequest is the body of a bulk run status lookup
+type RunStatusesRequest struct {
+	RunIDs []string `json:"run_ids"`
+}
+
+// GetRunStatuses returns the compact status of many runs at once
+// POST /api/v1/runs/status
+// Body: {"run_ids": ["<run_id>", ...]} (at most service.MaxRunStatusBatch)
+// Returns {run_id: {status, completed_nodes, total_nodes}}; unknown runs are omitted.
+func (h *RunHandler) GetRunStatuses(c echo.Context) error {
+	var req RunStatusesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if len(req.RunIDs) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "run_ids is required")
+	}
+	if len(req.RunIDs) > service.MaxRunStatusBatch {
+		return echo.NewHTTPError(http.StatusBadRequest, service.ErrTooManyRuns.Error())
+	}
+
+	runIDs := make([]uuid.UUID, 0, len(req.RunIDs))
+	seen := make(map[uuid.UUID]bool, len(req.RunIDs))
+	for _, id := range req.RunIDs {
+		runID, err := uuid.Parse(id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid run_id format: %q", id))
+		}
+		if !seen[runID] {
+			seen[runID] = true
+			runIDs = append(runIDs, runID)
+		}
+	}
+
+	statuses, err := h.runService.GetRunStatuses(c.Request().Context(), runIDs)
+	if err != nil {
+		h.components.Logger.Error("failed to get run statuses", "count", len(runIDs), "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get run statuses")
+	}
+
+	return c.JSON(http.StatusOK, statuses)
+}
+
+// ListRuns returns a page of the caller's runs
+// GET /api/v1/runs?status=RUNNING,FAILED&submitted_after=<RFC3339>&submitted_before=<RFC3339>&order=asc|desc&limit=20&cursor=<next_cursor>
+func (h *RunHandler) ListRuns(c echo.Context) error {
+	username, _ := c.Get("username").(string)
+
+	opts, err := parseRunListOptions(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	page, err := h.runService.ListUserRuns(c.Request().Context(), username, opts)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusOK, page)
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	default:
+		h.components.Logger.Error("failed to list runs", "username", username, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list runs")
+	}
+}
+
+// parseRunListOptions reads run listing filters from the query string
+func parseRunListOptions(c echo.Context) (repository.RunListOptions, error) {
+	var opts repository.RunListOptions
+
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return opts, fmt.Errorf("invalid limit %q", limitStr)
+		}
+		opts.Limit = limit
+	}
+
+	if statuses := c.QueryParam("status"); statuses != "" {
+		for _, status := range strings.Split(statuses, ",") {
+			runStatus := models.RunStatus(strings.ToUpper(strings.TrimSpace(status)))
+			switch runStatus {
+			case models.StatusQueued, models.StatusRunning, models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+				opts.Statuses = append(opts.Statuses, runStatus)
+			default:
+				return opts, fmt.Errorf("invalid status %q", status)
+			}
+		}
+	}
+
+	for param, target := range map[string]*time.Time{
+		"submitted_after":  &opts.SubmittedAfter,
+		"submitted_before": &opts.SubmittedBefore,
+	} {
+		if value := c.QueryParam(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s %q: expected RFC3339", param, value)
+			}
+			*target = t
+		}
+	}
+
+	switch order := strings.ToLower(c.QueryParam("order")); order {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		return opts, fmt.Errorf("invalid order %q: expected asc or desc", order)
+	}
+
+	opts.Cursor = c.QueryParam("cursor")
+	return opts, nil
+}
+
 // ListWorkflowRuns returns runs for a workflow tag
 func (h *RunHandler) ListWorkflowRuns(c echo.Context) error {
 	tag := c.Param("tag")
@@ -393,6 +608,168 @@ func (h *RunHandler) ListWorkflowRuns(c echo.Context) error {
 	})
 }
 
+// CancelNode cancels a single in-flight node, leaving the rest of the run running
+// POST /api/v1/runs/:id/nodes/:node_id/cancel
+func (h *RunHandler) CancelNode(c echo.Context) error {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+	nodeID := c.Param("node_id")
+
+	username, ok := c.Get("username").(string)
+	if !ok || username == "" {
+		username = "system"
+	}
+
+	err = h.runService.CancelNode(c.Request().Context(), runID, nodeID, username)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"run_id":  runID.String(),
+			"node_id": nodeID,
+			"status":  "cancelled",
+		})
+	case errors.Is(err, service.ErrNodeNotFound), errors.Is(err, service.ErrRunNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrRunNotActive), errors.Is(err, service.ErrNodeNotCancellable):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	default:
+		h.components.Logger.Error("failed to cancel node", "run_id", runID, "node_id", nodeID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to cancel node")
+	}
+}
+
+// PauseRun stops a run from scheduling new nodes until it is resumed
+// POST /api/v1/runs/:id/pause
+func (h *RunHandler) PauseRun(c echo.Context) error {
+	return h.setRunPaused(c, true)
+}
+
+// ResumeRun resumes a paused run, replaying work that completed while paused
+// POST /api/v1/runs/:id/resume
+func (h *RunHandler) ResumeRun(c echo.Context) error {
+	return h.setRunPaused(c, false)
+}
+
+// setRunPaused pauses or resumes the run in the request path
+func (h *RunHandler) setRunPaused(c echo.Context, pause bool) error {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	username, ok := c.Get("username").(string)
+	if !ok || username == "" {
+		username = "system"
+	}
+
+	action, status := "resume", models.StatusRunning
+	if pause {
+		action, status = "pause", models.StatusPaused
+		err = h.runService.PauseRun(c.Request().Context(), runID, username)
+	} else {
+		err = h.runService.ResumeRun(c.Request().Context(), runID, username)
+	}
+
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"run_id": runID.String(),
+			"status": status,
+		})
+	case errors.Is(err, service.ErrRunNotActive), errors.Is(err, service.ErrRunAlreadyPaused), errors.Is(err, service.ErrRunNotPaused):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	default:
+		h.components.Logger.Error("failed to "+action+" run", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to "+action+" run")
+	}
+}
+
+// RetryRunRequest is the optional body of a run retry
+type RetryRunRequest struct {
+	FromNode string `json:"from_node,omitempty"` // Defaults to the node that failed
+}
+
+// RetryRun re-runs a failed run from a node, reusing completed upstream outputs
+// POST /api/v1/runs/:id/retry
+func (h *RunHandler) RetryRun(c echo.Context) error {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	var req RetryRunRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	username, ok := c.Get("username").(string)
+	if !ok || username == "" {
+		username = "system"
+	}
+
+	nodeID, err := h.runService.RetryRun(c.Request().Context(), runID, req.FromNode, username)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"run_id":  runID.String(),
+			"node_id": nodeID,
+			"status":  models.StatusRunning,
+		})
+	case errors.Is(err, service.ErrNodeNotFound), errors.Is(err, service.ErrRunNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrRunNotRetryable), errors.Is(err, service.ErrNodeNotRetryable):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	default:
+		h.components.Logger.Error("failed to retry run", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retry run")
+	}
+}
+
+// CompleteWebhook delivers the result of an async webhook node
+// POST /api/v1/runs/:id/webhook/:node_id?token=<callback token>
+// Body: {"status": "completed"|"failed", "result": {...}, "error": "..."}
+func (h *RunHandler) CompleteWebhook(c echo.Context) error {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+	nodeID := c.Param("node_id")
+
+	callbackToken := c.QueryParam("token")
+	if callbackToken == "" {
+		callbackToken = c.Request().Header.Get("X-Webhook-Token")
+	}
+	if callbackToken == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "missing callback token")
+	}
+
+	var callback sdk.WebhookCallback
+	if err := c.Bind(&callback); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	err = h.runService.CompleteWebhook(c.Request().Context(), runID, nodeID, callbackToken, &callback)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusAccepted, map[string]interface{}{
+			"run_id":  runID.String(),
+			"node_id": nodeID,
+			"status":  callback.Status,
+		})
+	case errors.Is(err, service.ErrWebhookInvalidToken):
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidCallback):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWebhookNotPending):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	default:
+		h.components.Logger.Error("failed to complete webhook", "run_id", runID, "node_id", nodeID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to complete webhook")
+	}
+}
+
 // GetRunDetails returns comprehensive run details
 func (h *RunHandler) GetRunDetails(c echo.Context) error {
 	runIDStr := c.Param("id")
@@ -410,3 +787,114 @@ func (h *RunHandler) GetRunDetails(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, details)
 }
+
+// GetRunResult returns the run's final result
+// Raw terminal node outputs, or the shape declared by the workflow's result_mapping.
+func (h *RunHandler) GetRunResult(c echo.Context) error {
+	runIDStr := c.Param("id")
+
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	result, err := h.runService.GetRunResult(c.Request().Context(), runID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidResultMapping) {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+		}
+		if errors.Is(err, service.ErrRunNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "run not found")
+		}
+		if errors.Is(err, service.ErrRunOutputUnavailable) {
+			return echo.NewHTTPError(http.StatusGone, err.Error())
+		}
+		h.components.Logger.Error("failed to get run result", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get run result")
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// GetRunFixture exports the run as a replayable test fixture
+// The fixture (IR, inputs and each node's recorded output as a mock) can be
+// saved next to a test and replayed with the workflow runner's Simulator.
+func (h *RunHandler) GetRunFixture(c echo.Context) error {
+	runIDStr := c.Param("id")
+
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	fixture, err := h.runService.ExportRunFixture(c.Request().Context(), runID)
+	if err != nil {
+		if errors.Is(err, service.ErrRunNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "run not found")
+		}
+		if errors.Is(err, service.ErrRunOutputUnavailable) {
+			return echo.NewHTTPError(http.StatusGone, err.Error())
+		}
+		h.components.Logger.Error("failed to export run fixture", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export run fixture")
+	}
+
+	return c.JSON(http.StatusOK, fixture)
+}
+
+// GetRunLogs returns a node's structured log lines
+// GET /api/v1/runs/:id/logs?node_id=fetch&level=warn
+// level, if set, is the minimum level returned (debug, info, warn or error).
+func (h *RunHandler) GetRunLogs(c echo.Context) error {
+	runIDStr := c.Param("id")
+
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	nodeID := c.QueryParam("node_id")
+	if nodeID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "node_id is required")
+	}
+	level := c.QueryParam("level")
+	if level != "" && !sdk.ValidLogLevel(level) {
+		return echo.NewHTTPError(http.StatusBadRequest, "level must be one of debug, info, warn, error")
+	}
+
+	logs, err := h.runService.GetNodeLogs(c.Request().Context(), runID, nodeID, level)
+	if err != nil {
+		h.components.Logger.Error("failed to get node logs", "run_id", runID, "node_id", nodeID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get node logs")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"run_id":  runID,
+		"node_id": nodeID,
+		"logs":    logs,
+	})
+}
+
+// GetRunState returns a run's raw coordinator state (admin only)
+// GET /api/v1/runs/:id/state
+// Internal debugging endpoint: see service.RunState.
+func (h *RunHandler) GetRunState(c echo.Context) error {
+	runIDStr := c.Param("id")
+
+	runID, err := uuid.Parse(runIDStr)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	state, err := h.runService.GetRunState(c.Request().Context(), runID)
+	if err != nil {
+		if errors.Is(err, service.ErrRunNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "run not found")
+		}
+		h.components.Logger.Error("failed to get run state", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get run state")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, state)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// sseKeepAliveInterval is how often a comment frame is sent on an idle stream
+const sseKeepAliveInterval = 15 * time.Second
+
+// runEventSource is the subset of RunService used for run event streaming
+type runEventSource interface {
+	GetRun(ctx context.Context, runID uuid.UUID) (*models.Run, error)
+	GetRunDetails(ctx context.Context, runID uuid.UUID) (*service.RunDetails, error)
+}
+
+// StreamRunEvents streams run progress as Server-Sent Events
+// Sends a "snapshot" event with the current run details, then one event per
+// node/workflow event for the run (node_started, node_completed, ...). The
+// stream ends when the run reaches a terminal status or the client disconnects.
+func (h *RunHandler) StreamRunEvents(c echo.Context) error {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	ctx := c.Request().Context()
+
+	// 1. Look up the run to find its owner's event channel
+	run, err := h.runEvents.GetRun(ctx, runID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "run not found")
+	}
+
+	// 2. Subscribe before taking the snapshot so no event falls in between
+	var messages <-chan *redis.Message
+	if run.SubmittedBy != nil && *run.SubmittedBy != "" {
+		channel := fmt.Sprintf("workflow:events:%s", *run.SubmittedBy)
+		pubsub := h.redis.GetUnderlying().Subscribe(ctx, channel)
+		defer pubsub.Close()
+
+		if _, err := pubsub.Receive(ctx); err != nil {
+			h.components.Logger.Error("failed to subscribe to run events", "run_id", runID, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to subscribe to run events")
+		}
+		messages = pubsub.Channel()
+	}
+
+	// 3. Load the initial snapshot
+	details, err := h.runEvents.GetRunDetails(ctx, runID)
+	if err != nil {
+		h.components.Logger.Error("failed to get run details", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load run details")
+	}
+
+	// 4. Start the stream (no write deadline: the stream outlives the server's WriteTimeout)
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set(echo.HeaderCacheControl, "no-cache")
+	resp.Header().Set(echo.HeaderConnection, "keep-alive")
+	resp.Header().Set("X-Accel-Buffering", "no")
+	resp.WriteHeader(http.StatusOK)
+	_ = http.NewResponseController(resp.Writer).SetWriteDeadline(time.Time{})
+
+	snapshot, err := json.Marshal(details)
+	if err != nil {
+		h.components.Logger.Error("failed to marshal run snapshot", "run_id", runID, "error", err)
+		return nil
+	}
+	if err := writeSSE(resp, "snapshot", snapshot); err != nil {
+		return nil
+	}
+
+	if messages == nil || (details.Run != nil && isTerminalRunStatus(details.Run.Status)) {
+		return nil
+	}
+
+	// 5. Forward this run's events until it finishes or the client goes away
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.components.Logger.Debug("run event stream closed by client", "run_id", runID)
+			return nil
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(resp, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			resp.Flush()
+
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			var event struct {
+				Type  string `json:"type"`
+				RunID string `json:"run_id"`
+			}
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil || event.RunID != runID.String() {
+				continue
+			}
+
+			if err := writeSSE(resp, event.Type, []byte(msg.Payload)); err != nil {
+				return nil
+			}
+
+			if event.Type == "workflow_completed" || event.Type == "workflow_failed" {
+				return nil
+			}
+		}
+	}
+}
+
+// writeSSE writes a single Server-Sent Event frame and flushes it
+func writeSSE(resp *echo.Response, event string, data []byte) error {
+	if _, err := fmt.Fprintf(resp, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	resp.Flush()
+	return nil
+}
+
+// isTerminalRunStatus reports whether a run can no longer change
+func isTerminalRunStatus(status models.RunStatus) bool {
+	switch status {
+	case models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
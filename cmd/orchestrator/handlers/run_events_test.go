@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+// fakeRunEvents serves a fixed run for streaming tests
+type fakeRunEvents struct {
+	run *models.Run
+}
+
+func (f *fakeRunEvents) GetRun(ctx context.Context, runID uuid.UUID) (*models.Run, error) {
+	if runID != f.run.RunID {
+		return nil, errors.New("not found")
+	}
+	return f.run, nil
+}
+
+func (f *fakeRunEvents) GetRunDetails(ctx context.Context, runID uuid.UUID) (*service.RunDetails, error) {
+	run, err := f.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return &service.RunDetails{Run: run, NodeExecutions: map[string]*service.NodeExecution{}}, nil
+}
+
+type sseFrame struct {
+	Event string
+	Data  map[string]interface{}
+}
+
+// readSSEFrame reads the next event frame, skipping comments
+func readSSEFrame(t *testing.T, r *bufio.Reader) sseFrame {
+	t.Helper()
+
+	var frame sseFrame
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case line == "" && frame.Event != "":
+			return frame
+		case strings.HasPrefix(line, "event: "):
+			frame.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &frame.Data))
+		}
+	}
+}
+
+func newStreamingServer(t *testing.T, run *models.Run) (*httptest.Server, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	handler := NewRunHandler(&bootstrap.Components{Logger: log}, rediscommon.NewClient(rdb, log), nil, nil)
+	handler.runEvents = &fakeRunEvents{run: run}
+
+	e := echo.New()
+	e.GET("/api/v1/runs/:id/events", handler.StreamRunEvents)
+	ts := httptest.NewServer(e)
+	t.Cleanup(ts.Close)
+
+	return ts, rdb
+}
+
+func TestStreamRunEvents_StreamsUntilTerminal(t *testing.T) {
+	username := "alice"
+	run := &models.Run{RunID: uuid.New(), Status: models.StatusRunning, SubmittedBy: &username}
+	ts, rdb := newStreamingServer(t, run)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/runs/"+run.RunID.String()+"/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+
+	// 1. Initial snapshot (subscription is in place once it arrives)
+	snapshot := readSSEFrame(t, reader)
+	assert.Equal(t, "snapshot", snapshot.Event)
+	assert.Equal(t, run.RunID.String(), snapshot.Data["run"].(map[string]interface{})["run_id"])
+
+	// 2. Drive node events as the coordinator publishes them
+	publish := func(event map[string]interface{}) {
+		payload, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.NoError(t, rdb.Publish(ctx, "workflow:events:"+username, payload).Err())
+	}
+	publish(map[string]interface{}{"type": "node_started", "run_id": run.RunID.String(), "node_id": "a"})
+	publish(map[string]interface{}{"type": "node_completed", "run_id": "some-other-run", "node_id": "x"})
+	publish(map[string]interface{}{"type": "node_completed", "run_id": run.RunID.String(), "node_id": "a", "status": "completed"})
+	publish(map[string]interface{}{"type": "node_failed", "run_id": run.RunID.String(), "node_id": "b"})
+	publish(map[string]interface{}{"type": "workflow_failed", "run_id": run.RunID.String(), "node_id": "b"})
+
+	// 3. Only this run's events arrive, in order
+	for _, want := range []struct{ event, node string }{
+		{"node_started", "a"},
+		{"node_completed", "a"},
+		{"node_failed", "b"},
+		{"workflow_failed", "b"},
+	} {
+		frame := readSSEFrame(t, reader)
+		assert.Equal(t, want.event, frame.Event)
+		assert.Equal(t, want.node, frame.Data["node_id"])
+		assert.Equal(t, run.RunID.String(), frame.Data["run_id"])
+	}
+
+	// 4. Stream ends after the terminal event
+	_, err = io.ReadAll(reader)
+	require.NoError(t, err)
+}
+
+func TestStreamRunEvents_TerminalRunSendsSnapshotOnly(t *testing.T) {
+	username := "alice"
+	run := &models.Run{RunID: uuid.New(), Status: models.StatusCompleted, SubmittedBy: &username}
+	ts, _ := newStreamingServer(t, run)
+
+	resp, err := http.Get(ts.URL + "/api/v1/runs/" + run.RunID.String() + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(body), "event: "))
+	assert.Contains(t, string(body), "event: snapshot")
+
+	resp, err = http.Get(ts.URL + "/api/v1/runs/" + uuid.NewString() + "/events")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+const addNodePatch = `{
+	"description": "add follow-up node",
+	"operations": [
+		{"op": "add", "path": "/nodes/-", "value": {"id": "b", "type": "function"}},
+		{"op": "add", "path": "/edges/-", "value": {"from": "a", "to": "b"}}
+	]
+}`
+
+func TestPatchRun_AllowRuntimePatchFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		metadata   map[string]interface{}
+		wantStatus int
+		wantNodes  int
+	}{
+		{
+			name:       "allowed by default",
+			metadata:   map[string]interface{}{"username": "alice"},
+			wantStatus: http.StatusOK,
+			wantNodes:  2,
+		},
+		{
+			name:       "explicitly allowed",
+			metadata:   map[string]interface{}{sdk.MetadataAllowRuntimePatch: true},
+			wantStatus: http.StatusOK,
+			wantNodes:  2,
+		},
+		{
+			name:       "disabled by workflow",
+			metadata:   map[string]interface{}{sdk.MetadataAllowRuntimePatch: false},
+			wantStatus: http.StatusForbidden,
+			wantNodes:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rdb.Close() })
+
+			log := logger.New("error", "text")
+			handler := NewRunHandler(
+				&bootstrap.Components{Logger: log},
+				rediscommon.NewClient(rdb, log),
+				clients.NewRedisCASClient(rdb, log),
+				nil,
+			)
+
+			runID := "run-1"
+			ir := sdk.IR{
+				Version:  "1.0",
+				Nodes:    map[string]*sdk.Node{"a": {ID: "a", Type: "function"}},
+				Metadata: tt.metadata,
+			}
+			irJSON, err := json.Marshal(ir)
+			require.NoError(t, err)
+			mr.Set("ir:"+runID, string(irJSON))
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/runs/"+runID+"/patch", strings.NewReader(addNodePatch))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(runID)
+
+			err = handler.PatchRun(c)
+			status := rec.Code
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				status = httpErr.Code
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStatus, status)
+
+			// IR in Redis is only replaced when the patch is allowed
+			stored, err := rdb.Get(context.Background(), "ir:"+runID).Result()
+			require.NoError(t, err)
+			var current sdk.IR
+			require.NoError(t, json.Unmarshal([]byte(stored), &current))
+			assert.Len(t, current.Nodes, tt.wantNodes)
+			assert.Equal(t, tt.metadata, current.Metadata)
+		})
+	}
+}
+
+func TestPatchRun_RunNotFound(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	handler := NewRunHandler(
+		&bootstrap.Components{Logger: log},
+		rediscommon.NewClient(rdb, log),
+		clients.NewRedisCASClient(rdb, log),
+		nil,
+	)
+
+	patch := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-1/patch", strings.NewReader(addNodePatch))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues("run-1")
+
+		err := handler.PatchRun(c)
+		httpErr, ok := err.(*echo.HTTPError)
+		require.True(t, ok, "expected HTTP error, got %v", err)
+		return httpErr.Code
+	}
+
+	// No IR for the run
+	assert.Equal(t, http.StatusNotFound, patch())
+
+	// Redis failing is not reported as a missing run
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	assert.Equal(t, http.StatusInternalServerError, patch())
+}
+
+func TestPatchRun_DuplicateNodeID(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	handler := NewRunHandler(
+		&bootstrap.Components{Logger: log},
+		rediscommon.NewClient(rdb, log),
+		clients.NewRedisCASClient(rdb, log),
+		nil,
+	)
+
+	ir := sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"a": {ID: "a", Type: "function", Dependents: []string{"b"}},
+			"b": {ID: "b", Type: "function", Dependencies: []string{"a"}},
+		},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+
+	patch := func(body string) (int, map[string]interface{}) {
+		mr.Set("ir:run-1", string(irJSON))
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/runs/run-1/patch", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("run-1")
+
+		if err := handler.PatchRun(c); err != nil {
+			httpErr, ok := err.(*echo.HTTPError)
+			require.True(t, ok, "expected HTTP error, got %v", err)
+			return httpErr.Code, map[string]interface{}{"message": httpErr.Message}
+		}
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return rec.Code, response
+	}
+	storedNodes := func() map[string]*sdk.Node {
+		stored, err := rdb.Get(context.Background(), "ir:run-1").Result()
+		require.NoError(t, err)
+		var current sdk.IR
+		require.NoError(t, json.Unmarshal([]byte(stored), &current))
+		return current.Nodes
+	}
+
+	// Colliding add is rejected, naming the id, and the IR is left alone
+	status, response := patch(addNodePatch)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, response["message"], `node id "b" already exists`)
+	assert.Equal(t, "function", storedNodes()["b"].Type)
+	assert.Len(t, storedNodes(), 2)
+
+	// auto_suffix renames the added node, and the edge added after it follows
+	status, response = patch(`{
+		"auto_suffix": true,
+		"operations": [
+			{"op": "add", "path": "/nodes/-", "value": {"id": "b", "type": "http", "config": {"url": "https://example.com"}}},
+			{"op": "add", "path": "/edges/-", "value": {"from": "a", "to": "b"}}
+		]
+	}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"b_2": "b"}, response["renamed_nodes"])
+	nodes := storedNodes()
+	require.Len(t, nodes, 3)
+	assert.Equal(t, "function", nodes["b"].Type)
+	assert.Equal(t, "http", nodes["b_2"].Type)
+	assert.Equal(t, []string{"a"}, nodes["b_2"].Dependencies)
+	assert.ElementsMatch(t, []string{"b", "b_2"}, nodes["a"].Dependents)
+}
+
+func TestGetRunStatuses_Validation(t *testing.T) {
+	tooMany := make([]string, service.MaxRunStatusBatch+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+	tooManyBody, err := json.Marshal(map[string]interface{}{"run_ids": tooMany})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed body", body: `{"run_ids": "nope"}`},
+		{name: "no run ids", body: `{"run_ids": []}`},
+		{name: "invalid uuid", body: `{"run_ids": ["` + uuid.NewString() + `", "run-1"]}`},
+		{name: "too many run ids", body: string(tooManyBody)},
+	}
+
+	// Validation fails before the run service is reached
+	handler := NewRunHandler(&bootstrap.Components{Logger: logger.New("error", "text")}, nil, nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/runs/status", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			err := handler.GetRunStatuses(c)
+			httpErr, ok := err.(*echo.HTTPError)
+			require.True(t, ok, "expected HTTP error, got %v", err)
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultRunWaitTimeout is how long a wait blocks without a timeout parameter
+	defaultRunWaitTimeout = 30 * time.Second
+
+	// maxRunWaitTimeout caps the timeout parameter
+	maxRunWaitTimeout = 5 * time.Minute
+)
+
+// runStatusSettleDelays are the re-reads of a run's status after a completion signal
+// The runner publishes completion before it writes the status back, so the
+// status can lag the signal by a moment.
+var runStatusSettleDelays = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+}
+
+// runWaitSource is the subset of RunService used for waiting on runs
+type runWaitSource interface {
+	GetRun(ctx context.Context, runID uuid.UUID) (*models.Run, error)
+	GetRunStatus(ctx context.Context, runID uuid.UUID) (models.RunStatus, error)
+}
+
+// WaitForRun blocks until a run reaches a terminal status or the timeout elapses
+// GET /api/v1/runs/:id/wait?timeout=30s
+//
+// Listens for the run's completion signals instead of polling: the counter
+// reaching zero (completion_events) and workflow_completed/workflow_failed
+// events. Returns {run_id, status, done} either way; done is false on timeout.
+func (h *RunHandler) WaitForRun(c echo.Context) error {
+	runID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run_id format")
+	}
+
+	timeout := defaultRunWaitTimeout
+	if timeoutStr := c.QueryParam("timeout"); timeoutStr != "" {
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid timeout: must be a positive duration such as 30s")
+		}
+		if timeout > maxRunWaitTimeout {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid timeout: at most %s", maxRunWaitTimeout))
+		}
+	}
+
+	ctx := c.Request().Context()
+
+	// 1. Look up the run to find its owner's event channel
+	run, err := h.runWait.GetRun(ctx, runID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "run not found")
+	}
+
+	// 2. Subscribe before reading the status so no signal falls in between
+	channels := []string{sdk.CompletionEventsChannel}
+	if run.SubmittedBy != nil && *run.SubmittedBy != "" {
+		channels = append(channels, fmt.Sprintf("workflow:events:%s", *run.SubmittedBy))
+	}
+	pubsub := h.redis.GetUnderlying().Subscribe(ctx, channels...)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		h.components.Logger.Error("failed to subscribe to run completion", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to subscribe to run completion")
+	}
+	messages := pubsub.Channel()
+
+	status, err := h.runWait.GetRunStatus(ctx, runID)
+	if err != nil {
+		h.components.Logger.Error("failed to get run status", "run_id", runID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get run status")
+	}
+
+	// 3. Wait for this run's completion (no write deadline: the wait can outlive the server's WriteTimeout)
+	_ = http.NewResponseController(c.Response().Writer).SetWriteDeadline(time.Time{})
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for !isTerminalRunStatus(status) {
+		select {
+		case <-waitCtx.Done():
+			return h.runWaitResponse(c, runID, status)
+
+		case msg, ok := <-messages:
+			if !ok {
+				return h.runWaitResponse(c, runID, status)
+			}
+			if !isRunCompletionSignal(msg, runID.String()) {
+				continue
+			}
+			status = h.settledRunStatus(waitCtx, runID, status)
+		}
+	}
+
+	return h.runWaitResponse(c, runID, status)
+}
+
+// settledRunStatus re-reads a run's status after a completion signal until it is terminal
+// Returns the last status read once runStatusSettleDelays are used up: the
+// signal didn't finish the run (a pending approval, or a retry).
+func (h *RunHandler) settledRunStatus(ctx context.Context, runID uuid.UUID, status models.RunStatus) models.RunStatus {
+	for attempt := 0; ; attempt++ {
+		current, err := h.runWait.GetRunStatus(ctx, runID)
+		if err != nil {
+			h.components.Logger.Warn("failed to get run status", "run_id", runID, "error", err)
+		} else {
+			status = current
+		}
+		if isTerminalRunStatus(status) || attempt == len(runStatusSettleDelays) {
+			return status
+		}
+
+		select {
+		case <-ctx.Done():
+			return status
+		case <-time.After(runStatusSettleDelays[attempt]):
+		}
+	}
+}
+
+// runWaitResponse writes the outcome of a wait
+func (h *RunHandler) runWaitResponse(c echo.Context, runID uuid.UUID, status models.RunStatus) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"run_id": runID,
+		"status": status,
+		"done":   isTerminalRunStatus(status),
+	})
+}
+
+// isRunCompletionSignal reports whether a pub/sub message may have finished a run
+// completion_events carries the bare run ID; workflow events are JSON.
+func isRunCompletionSignal(msg *redis.Message, runID string) bool {
+	if msg.Channel == sdk.CompletionEventsChannel {
+		return msg.Payload == runID
+	}
+
+	var event struct {
+		Type  string `json:"type"`
+		RunID string `json:"run_id"`
+	}
+	if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil || event.RunID != runID {
+		return false
+	}
+	return event.Type == "workflow_completed" || event.Type == "workflow_failed"
+}
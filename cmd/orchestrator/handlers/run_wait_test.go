@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// fakeRunWait serves a single run whose status tests can change
+type fakeRunWait struct {
+	mu  sync.Mutex
+	run *models.Run
+}
+
+func (f *fakeRunWait) GetRun(ctx context.Context, runID uuid.UUID) (*models.Run, error) {
+	if runID != f.run.RunID {
+		return nil, errors.New("not found")
+	}
+	return f.run, nil
+}
+
+func (f *fakeRunWait) GetRunStatus(ctx context.Context, runID uuid.UUID) (models.RunStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.run.Status, nil
+}
+
+func (f *fakeRunWait) setStatus(status models.RunStatus) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.run.Status = status
+}
+
+type runWaitResult struct {
+	Status models.RunStatus `json:"status"`
+	Done   bool             `json:"done"`
+}
+
+func newWaitServer(t *testing.T, status models.RunStatus) (*httptest.Server, *redis.Client, *fakeRunWait) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	username := "alice"
+	runs := &fakeRunWait{run: &models.Run{RunID: uuid.New(), Status: status, SubmittedBy: &username}}
+
+	log := logger.New("error", "text")
+	handler := NewRunHandler(&bootstrap.Components{Logger: log}, rediscommon.NewClient(rdb, log), nil, nil)
+	handler.runWait = runs
+
+	e := echo.New()
+	e.GET("/api/v1/runs/:id/wait", handler.WaitForRun)
+	ts := httptest.NewServer(e)
+	t.Cleanup(ts.Close)
+
+	return ts, rdb, runs
+}
+
+// getWait calls the wait endpoint and decodes its response
+// Safe to call from another goroutine: failures are reported, not fatal.
+func getWait(t *testing.T, url string) runWaitResult {
+	t.Helper()
+
+	var result runWaitResult
+	resp, err := http.Get(url)
+	if !assert.NoError(t, err) {
+		return result
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result
+}
+
+func TestWaitForRun_ReturnsOnCompletion(t *testing.T) {
+	ts, rdb, runs := newWaitServer(t, models.StatusRunning)
+	ctx := context.Background()
+	runID := runs.run.RunID.String()
+
+	results := make(chan runWaitResult, 1)
+	go func() {
+		results <- getWait(t, ts.URL+"/api/v1/runs/"+runID+"/wait?timeout=30s")
+	}()
+
+	// Wait until the request is listening
+	require.Eventually(t, func() bool {
+		subs, err := rdb.PubSubNumSub(ctx, sdk.CompletionEventsChannel).Result()
+		return err == nil && subs[sdk.CompletionEventsChannel] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Another run's completion doesn't end the wait
+	require.NoError(t, rdb.Publish(ctx, sdk.CompletionEventsChannel, uuid.NewString()).Err())
+
+	// The counter reaches zero, then the runner writes the status back
+	completedAt := time.Now()
+	require.NoError(t, rdb.Publish(ctx, sdk.CompletionEventsChannel, runID).Err())
+	runs.setStatus(models.StatusCompleted)
+
+	select {
+	case result := <-results:
+		assert.Equal(t, models.StatusCompleted, result.Status)
+		assert.True(t, result.Done)
+		assert.Less(t, time.Since(completedAt), time.Second)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after the run completed")
+	}
+}
+
+func TestWaitForRun_ReturnsOnWorkflowFailed(t *testing.T) {
+	ts, rdb, runs := newWaitServer(t, models.StatusRunning)
+	ctx := context.Background()
+	runID := runs.run.RunID.String()
+
+	results := make(chan runWaitResult, 1)
+	go func() {
+		results <- getWait(t, ts.URL+"/api/v1/runs/"+runID+"/wait")
+	}()
+
+	require.Eventually(t, func() bool {
+		subs, err := rdb.PubSubNumSub(ctx, "workflow:events:alice").Result()
+		return err == nil && subs["workflow:events:alice"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	runs.setStatus(models.StatusFailed)
+	require.NoError(t, rdb.Publish(ctx, "workflow:events:alice", `{"type":"workflow_failed","run_id":"`+runID+`"}`).Err())
+
+	select {
+	case result := <-results:
+		assert.Equal(t, models.StatusFailed, result.Status)
+		assert.True(t, result.Done)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after the run failed")
+	}
+}
+
+func TestWaitForRun_Timeout(t *testing.T) {
+	ts, _, runs := newWaitServer(t, models.StatusRunning)
+
+	start := time.Now()
+	result := getWait(t, ts.URL+"/api/v1/runs/"+runs.run.RunID.String()+"/wait?timeout=100ms")
+	assert.Equal(t, models.StatusRunning, result.Status)
+	assert.False(t, result.Done)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestWaitForRun_FinishedRunReturnsImmediately(t *testing.T) {
+	ts, _, runs := newWaitServer(t, models.StatusCompleted)
+
+	start := time.Now()
+	result := getWait(t, ts.URL+"/api/v1/runs/"+runs.run.RunID.String()+"/wait?timeout=30s")
+	assert.Equal(t, models.StatusCompleted, result.Status)
+	assert.True(t, result.Done)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWaitForRun_BadRequests(t *testing.T) {
+	ts, _, runs := newWaitServer(t, models.StatusRunning)
+	runID := runs.run.RunID.String()
+
+	for url, want := range map[string]int{
+		"/api/v1/runs/not-a-uuid/wait":               http.StatusBadRequest,
+		"/api/v1/runs/" + runID + "/wait?timeout=x":  http.StatusBadRequest,
+		"/api/v1/runs/" + runID + "/wait?timeout=1h": http.StatusBadRequest,
+		"/api/v1/runs/" + uuid.NewString() + "/wait": http.StatusNotFound,
+	} {
+		resp, err := http.Get(ts.URL + url)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, want, resp.StatusCode, url)
+	}
+}
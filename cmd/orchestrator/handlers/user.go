@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+// UserHandler serves admin operations on a user's data
+type UserHandler struct {
+	components *bootstrap.Components
+	userData   *service.UserDataService
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(components *bootstrap.Components, userData *service.UserDataService) *UserHandler {
+	return &UserHandler{
+		components: components,
+		userData:   userData,
+	}
+}
+
+// PurgeUserWorkflows deletes all of a user's workflow tags and runs
+// DELETE /api/v1/users/:username/workflows
+// Responds with the deleted tag names and run IDs, or 409 while any of the
+// user's runs is still active.
+func (h *UserHandler) PurgeUserWorkflows(c echo.Context) error {
+	username := c.Param("username")
+	if username == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "username is required")
+	}
+
+	report, err := h.userData.PurgeUser(c.Request().Context(), middleware.GetUsername(c), username)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusOK, report)
+	case errors.Is(err, service.ErrGlobalUserPurge):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrUserHasActiveRuns):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	default:
+		h.components.Logger.Error("failed to purge user workflows", "username", username, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to purge user workflows")
+	}
+}
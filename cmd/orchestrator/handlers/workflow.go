@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/container"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
@@ -87,6 +89,11 @@ func (h *WorkflowHandler) CreateWorkflow(c echo.Context) error {
 
 	// Use workflow service orchestrator
 	resp, err := h.workflowService.CreateWorkflow(ctx, &req)
+	if errors.Is(err, service.ErrWorkflowTooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		h.components.Logger.Error("failed to create workflow", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -180,13 +187,14 @@ func (h *WorkflowHandler) GetWorkflow(c echo.Context) error {
 }
 
 // ListWorkflows lists workflows (tags) for the authenticated user
-// GET /api/v1/workflows?scope=user|global|all
+// GET /api/v1/workflows?scope=user|global|all&summary=true
 //
 // Query parameters:
 //   - scope: "user" (default), "global", or "all"
 //   - user: List only the user's tags
 //   - global: List only global (system-wide) tags
 //   - all: List user's tags + global tags
+//   - summary: If "true", include node/edge counts and patch depth per tag
 func (h *WorkflowHandler) ListWorkflows(c echo.Context) error {
 	ctx := c.Request().Context()
 
@@ -225,6 +233,18 @@ func (h *WorkflowHandler) ListWorkflows(c echo.Context) error {
 		})
 	}
 
+	// Optional per-tag counts and depth, resolved in one batch
+	var summaries map[uuid.UUID]*service.WorkflowSummary
+	if c.QueryParam("summary") == "true" {
+		summaries, err = h.workflowService.SummarizeTags(ctx, tags)
+		if err != nil {
+			h.components.Logger.Error("failed to summarize workflows", "scope", scope, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "failed to summarize workflows",
+			})
+		}
+	}
+
 	// Build response
 	workflows := make([]map[string]interface{}, len(tags))
 	for i, tag := range tags {
@@ -242,6 +262,9 @@ func (h *WorkflowHandler) ListWorkflows(c echo.Context) error {
 		if tag.MovedBy != nil {
 			workflows[i]["moved_by"] = *tag.MovedBy
 		}
+		if summary, ok := summaries[tag.TargetID]; ok {
+			workflows[i]["summary"] = summary
+		}
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -322,8 +345,10 @@ func (h *WorkflowHandler) PatchWorkflow(c echo.Context) error {
 
 	// Parse request body
 	var req struct {
-		Operations  []map[string]interface{} `json:"operations"`
-		Description string                   `json:"description"`
+		Operations      []map[string]interface{} `json:"operations"`
+		Description     string                   `json:"description"`
+		ExpectedVersion *int64                   `json:"expected_version"` // Tag version the operations were written against
+		AutoSuffix      bool                     `json:"auto_suffix"`      // Rename added nodes whose id is taken instead of rejecting them
 	}
 
 	if err := c.Bind(&req); err != nil {
@@ -376,27 +401,57 @@ func (h *WorkflowHandler) PatchWorkflow(c echo.Context) error {
 	}
 
 	// Validate patch operations by trying to apply them
-	_, err = h.patcher.ApplyJSONPatchToWorkflow(currentWorkflow, req.Operations)
+	// With auto_suffix, renamed nodes are rewritten into req.Operations, so the
+	// stored patch replays the rename.
+	patchedWorkflow, renamedNodes, err := h.patcher.ApplyJSONPatchToWorkflowWithOptions(currentWorkflow, req.Operations,
+		PatchOptions{AutoSuffix: req.AutoSuffix})
 	if err != nil {
 		h.components.Logger.Warn("failed to validate patch operations",
 			"username", username,
 			"tag", tagName,
 			"error", err)
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		body := map[string]interface{}{
 			"error": fmt.Sprintf("invalid patch operations: %v", err),
+		}
+		var duplicate *DuplicateNodeIDError
+		if errors.As(err, &duplicate) {
+			body["node_id"] = duplicate.NodeID
+		}
+		return c.JSON(http.StatusBadRequest, body)
+	}
+	if err := h.workflowService.CheckWorkflowSize(patchedWorkflow); err != nil {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"error": err.Error(),
 		})
 	}
 
 	// Create patch artifact (stores operations, not the full patched workflow)
+	// Without an expected_version, the patch must at least land on the version
+	// it was validated against above.
+	expectedVersion := components.TagVersion
+	if req.ExpectedVersion != nil {
+		expectedVersion = *req.ExpectedVersion
+	}
 	patchReq := &service.CreatePatchRequest{
-		Username:    username,
-		TagName:     tagName,
-		Operations:  req.Operations,
-		Description: req.Description,
-		CreatedBy:   username,
+		Username:        username,
+		TagName:         tagName,
+		Operations:      req.Operations,
+		Description:     req.Description,
+		CreatedBy:       username,
+		ExpectedVersion: &expectedVersion,
 	}
 
 	resp, err := h.workflowService.CreatePatch(ctx, patchReq)
+	if errors.Is(err, service.ErrWorkflowTooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if errors.Is(err, service.ErrTagMoved) {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		h.components.Logger.Error("failed to create patch",
 			"username", username,
@@ -423,12 +478,275 @@ func (h *WorkflowHandler) PatchWorkflow(c echo.Context) error {
 		"tag":         resp.TagName,
 		"owner":       resp.Username,
 		"description": req.Description,
+		"version":     resp.Version,
 		"created_at":  resp.CreatedAt,
 	}
+	if len(renamedNodes) > 0 {
+		response["renamed_nodes"] = renamedNodes
+	}
 
 	return c.JSON(http.StatusOK, response)
 }
 
+// RollbackWorkflow moves a tag back to a previous version
+// POST /api/v1/workflows/:tag/rollback
+//
+// Body: {"seq": N}. The workflow at seq N is stored as a new dag_version and the
+// tag is moved to it; the existing base and patch chain are kept for history.
+// Returns 404 for an unknown tag or a seq past the end of its history.
+func (h *WorkflowHandler) RollbackWorkflow(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// URL-decode the tag name
+	tagName, err := url.QueryUnescape(c.Param("tag"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid tag name encoding",
+		})
+	}
+
+	// Extract username from context
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	if errMsg := service.ValidateUserTagName(tagName); errMsg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("invalid tag name: %s", errMsg),
+		})
+	}
+
+	// Parse request body
+	var req struct {
+		Seq *int `json:"seq"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.Seq == nil || *req.Seq < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "seq is required and must be a non-negative integer",
+		})
+	}
+
+	resp, err := h.workflowService.RollbackWorkflow(ctx, &service.RollbackWorkflowRequest{
+		Username:  username,
+		TagName:   tagName,
+		Seq:       *req.Seq,
+		CreatedBy: username,
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "workflow not found",
+		})
+	case errors.Is(err, service.ErrVersionNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		h.components.Logger.Error("failed to roll back workflow",
+			"username", username,
+			"tag", tagName,
+			"seq", *req.Seq,
+			"error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": fmt.Sprintf("failed to roll back workflow: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"artifact_id":          resp.ArtifactID,
+		"cas_id":               resp.CASID,
+		"derived_from_seq":     resp.DerivedFromSeq,
+		"previous_artifact_id": resp.PreviousID,
+		"tag":                  resp.TagName,
+		"owner":                resp.Username,
+		"nodes_count":          resp.NodesCount,
+		"edges_count":          resp.EdgesCount,
+		"created_at":           resp.CreatedAt,
+	})
+}
+
+// CloneWorkflow forks a workflow into a new tag in the caller's namespace
+// POST /api/v1/workflows/:tag/clone
+//
+// Body: {"new_tag": "...", "from_user": "..."}. from_user owns the source tag
+// (default: the caller; "_global_" for global templates). Returns 403 for
+// another user's private tag and 409 if new_tag already exists.
+func (h *WorkflowHandler) CloneWorkflow(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// URL-decode the tag name
+	tagName, err := url.QueryUnescape(c.Param("tag"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid tag name encoding",
+		})
+	}
+
+	// Extract username from context
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req struct {
+		NewTag   string `json:"new_tag"`
+		FromUser string `json:"from_user"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	if errMsg := service.ValidateUserTagName(req.NewTag); errMsg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("invalid new_tag: %s", errMsg),
+		})
+	}
+
+	resp, err := h.workflowService.CloneWorkflow(ctx, &service.CloneWorkflowRequest{
+		Username:   username,
+		SourceUser: req.FromUser,
+		SourceTag:  tagName,
+		NewTag:     req.NewTag,
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrTagNotAccessible):
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "workflow not found",
+		})
+	case errors.Is(err, service.ErrTagExists):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrWorkflowTooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		h.components.Logger.Error("failed to clone workflow",
+			"username", username,
+			"source_user", req.FromUser,
+			"source_tag", tagName,
+			"new_tag", req.NewTag,
+			"error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": fmt.Sprintf("failed to clone workflow: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"artifact_id":        resp.ArtifactID,
+		"cas_id":             resp.CASID,
+		"tag":                resp.TagName,
+		"owner":              resp.Username,
+		"source_tag":         resp.SourceTag,
+		"source_owner":       resp.SourceUser,
+		"source_artifact_id": resp.SourceArtifactID,
+		"nodes_count":        resp.NodesCount,
+		"edges_count":        resp.EdgesCount,
+		"created_at":         resp.CreatedAt,
+	})
+}
+
+// UndoWorkflow moves a workflow tag back to its previous target
+// POST /api/v1/workflows/:tag/undo
+//
+// Returns 409 if there is nothing to undo or the tag moved concurrently.
+func (h *WorkflowHandler) UndoWorkflow(c echo.Context) error {
+	return h.moveTagInHistory(c, models.TagMoveActionUndo)
+}
+
+// RedoWorkflow reapplies the most recently undone move of a workflow tag
+// POST /api/v1/workflows/:tag/redo
+//
+// Returns 409 if there is nothing to redo (including after a new patch
+// discarded the redo stack) or the tag moved concurrently.
+func (h *WorkflowHandler) RedoWorkflow(c echo.Context) error {
+	return h.moveTagInHistory(c, models.TagMoveActionRedo)
+}
+
+// moveTagInHistory handles undo and redo
+func (h *WorkflowHandler) moveTagInHistory(c echo.Context, action string) error {
+	ctx := c.Request().Context()
+
+	// URL-decode the tag name
+	tagName, err := url.QueryUnescape(c.Param("tag"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid tag name encoding",
+		})
+	}
+
+	// Extract username from context
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	if errMsg := service.ValidateUserTagName(tagName); errMsg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("invalid tag name: %s", errMsg),
+		})
+	}
+
+	if _, err := h.tagService.GetTag(ctx, username, tagName); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "workflow not found",
+		})
+	}
+
+	var move *models.TagMove
+	if action == models.TagMoveActionUndo {
+		move, err = h.tagService.UndoTag(ctx, username, tagName, username)
+	} else {
+		move, err = h.tagService.RedoTag(ctx, username, tagName, username)
+	}
+
+	switch {
+	case errors.Is(err, service.ErrNothingToUndo), errors.Is(err, service.ErrNothingToRedo), errors.Is(err, service.ErrTagMoved):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": err.Error(),
+		})
+	case err != nil:
+		h.components.Logger.Error("failed to move workflow tag",
+			"username", username,
+			"tag", tagName,
+			"action", action,
+			"error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": fmt.Sprintf("failed to %s workflow: %v", action, err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"action":             move.Action,
+		"tag":                tagName,
+		"owner":              username,
+		"target_kind":        move.ToKind,
+		"target_id":          move.ToID,
+		"previous_target_id": move.FromID,
+		"moved_at":           move.MovedAt,
+	})
+}
+
 // GetWorkflowVersion retrieves a workflow at a specific version/sequence number
 // GET /api/v1/workflows/:tag/versions/:seq?materialize=false
 //
@@ -518,3 +836,151 @@ func (h *WorkflowHandler) GetWorkflowVersion(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, response)
 }
+
+// DiffWorkflowVersions returns a structural diff between two versions of a workflow
+// GET /api/v1/workflows/:tag/diff?from=2&to=5
+//
+// Both versions are materialized and compared at the node/edge level:
+// nodes added/removed/modified (with per-key config changes) and edges added/removed.
+func (h *WorkflowHandler) DiffWorkflowVersions(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// URL-decode the tag name
+	tagName, err := url.QueryUnescape(c.Param("tag"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid tag name encoding",
+		})
+	}
+
+	// Extract username from context (set by middleware)
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	if errMsg := service.ValidateUserTagName(tagName); errMsg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("invalid tag name: %s", errMsg),
+		})
+	}
+
+	// Parse from/to seq
+	var fromSeq, toSeq int
+	if _, err := fmt.Sscanf(c.QueryParam("from"), "%d", &fromSeq); err != nil || fromSeq < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "from must be a non-negative integer",
+		})
+	}
+	if _, err := fmt.Sscanf(c.QueryParam("to"), "%d", &toSeq); err != nil || toSeq < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "to must be a non-negative integer",
+		})
+	}
+
+	// Materialize both versions
+	versions := make([]map[string]interface{}, 0, 2)
+	for _, seq := range []int{fromSeq, toSeq} {
+		components, err := h.workflowService.GetWorkflowComponentsAtVersion(ctx, username, tagName, seq)
+		switch {
+		case err == nil:
+		case errors.Is(err, service.ErrWorkflowNotFound):
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "workflow not found",
+			})
+		case errors.Is(err, service.ErrVersionNotFound):
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": err.Error(),
+			})
+		default:
+			h.components.Logger.Error("failed to get workflow components at version",
+				"username", username,
+				"tag", tagName,
+				"seq", seq,
+				"error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": fmt.Sprintf("failed to get workflow version %d: %v", seq, err),
+			})
+		}
+
+		workflow, err := h.materializerService.Materialize(ctx, components)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": fmt.Sprintf("failed to materialize version %d: %v", seq, err),
+			})
+		}
+		versions = append(versions, workflow)
+	}
+
+	diff := service.DiffWorkflows(versions[0], versions[1])
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tag":       tagName,
+		"from":      fromSeq,
+		"to":        toSeq,
+		"unchanged": diff.IsEmpty(),
+		"diff":      diff,
+	})
+}
+
+// EstimateWorkflow estimates the cost and rate limit tier of running a workflow
+// GET /api/v1/workflows/:tag/estimate?materialize=false
+//
+// Returns the rate limiter's profile of the workflow (tier, agent_count,
+// total_nodes), its cost in cost units per node type, and the tier's rate
+// limit. With materialize=true, the workflow the estimate is based on is included.
+func (h *WorkflowHandler) EstimateWorkflow(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// URL-decode the tag name
+	tagName, err := url.QueryUnescape(c.Param("tag"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid tag name encoding",
+		})
+	}
+
+	// Extract username from context (set by middleware)
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	if errMsg := service.ValidateUserTagName(tagName); errMsg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("invalid tag name: %s", errMsg),
+		})
+	}
+
+	components, err := h.workflowService.GetWorkflowComponents(ctx, username, tagName)
+	if err != nil {
+		h.components.Logger.Error("failed to get workflow components", "username", username, "tag", tagName, "error", err)
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "workflow not found",
+		})
+	}
+
+	workflow, err := h.materializerService.Materialize(ctx, components)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": fmt.Sprintf("failed to materialize workflow: %v", err),
+		})
+	}
+
+	estimate := h.workflowService.EstimateWorkflow(workflow)
+
+	response := map[string]interface{}{
+		"tag":         tagName,
+		"owner":       username,
+		"artifact_id": components.ArtifactID,
+		"version":     components.TagVersion,
+		"profile":     estimate.Profile,
+		"cost":        estimate.Cost,
+		"rate_limit":  estimate.RateLimit,
+	}
+	if c.QueryParam("materialize") == "true" {
+		response["workflow"] = workflow
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// erroringTagStore fails every tag read with err
+type erroringTagStore struct {
+	*historyTagStore
+	err error
+}
+
+func (s *erroringTagStore) GetByName(ctx context.Context, username, tagName string) (*models.Tag, error) {
+	return nil, s.err
+}
+
+func TestDiffWorkflowVersions_ErrorStatus(t *testing.T) {
+	log := logger.New("error", "text")
+
+	tests := map[string]struct {
+		err    error
+		status int
+	}{
+		"missing tag":   {fmt.Errorf("failed to get tag: %w", pgx.ErrNoRows), http.StatusNotFound},
+		"store failure": {errors.New("connection reset"), http.StatusInternalServerError},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := &erroringTagStore{historyTagStore: &historyTagStore{}, err: tt.err}
+			h := &WorkflowHandler{
+				components:      &bootstrap.Components{Logger: log},
+				workflowService: service.NewWorkflowServiceV2(nil, nil, service.NewTagService(store, log), nil, log),
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/workflows/main/diff?from=0&to=1", nil)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.SetParamNames("tag")
+			c.SetParamValues("main")
+			c.Set(string(middleware.UsernameKey), "alice")
+
+			require.NoError(t, h.DiffWorkflowVersions(c))
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
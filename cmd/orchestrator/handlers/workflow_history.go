@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+// GetWorkflowHistory returns a page of a workflow tag's moves, newest first
+// GET /api/v1/workflows/:tag/history?owner=_global_&limit=50&cursor=...
+//
+// Each move records who moved the tag, when, why (move, undo, redo) and from
+// and to which artifact. owner defaults to the caller; users can read the
+// history of their own and global tags, never another user's (403).
+func (h *WorkflowHandler) GetWorkflowHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// URL-decode the tag name
+	tagName, err := url.QueryUnescape(c.Param("tag"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid tag name encoding",
+		})
+	}
+
+	// Extract username from context
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	owner := c.QueryParam("owner")
+	if owner == "" {
+		owner = username
+	}
+
+	if errMsg := service.ValidateUserTagName(tagName); errMsg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("invalid tag name: %s", errMsg),
+		})
+	}
+
+	var limit int
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": fmt.Sprintf("invalid limit %q", limitStr),
+			})
+		}
+	}
+
+	// Checked before the lookup, so private tags don't leak whether they exist
+	if !service.CanAccessTag(username, owner) {
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": fmt.Sprintf("%s: %s/%s", service.ErrTagNotAccessible, owner, tagName),
+		})
+	}
+
+	if _, err := h.tagService.GetTag(ctx, owner, tagName); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "workflow not found",
+		})
+	}
+
+	page, err := h.tagService.ListHistory(ctx, owner, tagName, limit, c.QueryParam("cursor"))
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusOK, page)
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		h.components.Logger.Error("failed to list workflow history",
+			"owner", owner,
+			"tag", tagName,
+			"error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "failed to list workflow history",
+		})
+	}
+}
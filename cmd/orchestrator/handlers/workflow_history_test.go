@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+// historyTagStore is an in-memory tag store serving tags and their moves
+// Paging is the repository's (see repository/tag_test.go); ListHistory only
+// records what the handler asked for and serves the owner's moves as one page.
+type historyTagStore struct {
+	tags  []*models.Tag
+	moves []*models.TagMove
+
+	listedLimit  int
+	listedCursor string
+}
+
+func (s *historyTagStore) Create(ctx context.Context, tag *models.Tag) error {
+	s.tags = append(s.tags, tag)
+	return nil
+}
+
+func (s *historyTagStore) GetByName(ctx context.Context, username, tagName string) (*models.Tag, error) {
+	for _, tag := range s.tags {
+		if tag.Username == username && tag.TagName == tagName {
+			return tag, nil
+		}
+	}
+	return nil, errors.New("tag not found")
+}
+
+func (s *historyTagStore) Update(ctx context.Context, tag *models.Tag) error {
+	return nil
+}
+
+func (s *historyTagStore) CompareAndSwap(ctx context.Context, username, tagName string, expectedVersion int64, newTarget uuid.UUID, newTargetKind, newTargetHash, movedBy string) (bool, error) {
+	return false, nil
+}
+
+func (s *historyTagStore) Delete(ctx context.Context, username, tagName string) error {
+	return nil
+}
+
+func (s *historyTagStore) ListByUsername(ctx context.Context, username string) ([]*models.Tag, error) {
+	return nil, nil
+}
+
+func (s *historyTagStore) Exists(ctx context.Context, username, tagName string) (bool, error) {
+	tag, _ := s.GetByName(ctx, username, tagName)
+	return tag != nil, nil
+}
+
+func (s *historyTagStore) GetHistory(ctx context.Context, username, tagName string, limit int) ([]*models.TagMove, error) {
+	page, err := s.ListHistory(ctx, username, tagName, limit, "")
+	if err != nil {
+		return nil, err
+	}
+	return page.Moves, nil
+}
+
+func (s *historyTagStore) ListHistory(ctx context.Context, username, tagName string, limit int, cursor string) (*repository.TagHistoryPage, error) {
+	s.listedLimit, s.listedCursor = limit, cursor
+	if cursor == "bad" {
+		return nil, repository.ErrInvalidCursor
+	}
+
+	page := &repository.TagHistoryPage{Moves: []*models.TagMove{}, NextCursor: "next"}
+	for i := len(s.moves) - 1; i >= 0; i-- {
+		if move := s.moves[i]; move.Username == username && move.TagName == tagName {
+			page.Moves = append(page.Moves, move)
+		}
+	}
+	return page, nil
+}
+
+func (s *historyTagStore) RecordMove(ctx context.Context, move *models.TagMove) error {
+	move.ID = int64(len(s.moves) + 1)
+	s.moves = append(s.moves, move)
+	return nil
+}
+
+func newHistoryTestHandler(t *testing.T) (*WorkflowHandler, *historyTagStore) {
+	t.Helper()
+	log := logger.New("error", "text")
+	store := &historyTagStore{}
+
+	// alice's main tag moved three times, bob's once
+	for _, owner := range []string{"alice", "bob"} {
+		require.NoError(t, store.Create(context.Background(), &models.Tag{Username: owner, TagName: "main"}))
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, action := range []string{models.TagMoveActionMove, models.TagMoveActionMove, models.TagMoveActionUndo} {
+		require.NoError(t, store.RecordMove(context.Background(), &models.TagMove{
+			Username: "alice",
+			TagName:  "main",
+			ToKind:   models.KindDAGVersion,
+			ToID:     uuid.New(),
+			Action:   action,
+			MovedAt:  start.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	require.NoError(t, store.RecordMove(context.Background(), &models.TagMove{
+		Username: "bob",
+		TagName:  "main",
+		ToKind:   models.KindDAGVersion,
+		ToID:     uuid.New(),
+		Action:   models.TagMoveActionMove,
+	}))
+
+	return &WorkflowHandler{
+		components: &bootstrap.Components{Logger: log},
+		tagService: service.NewTagService(store, log),
+	}, store
+}
+
+func getWorkflowHistory(t *testing.T, h *WorkflowHandler, username, query string) (*httptest.ResponseRecorder, *repository.TagHistoryPage) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/workflows/main/history"+query, nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("tag")
+	c.SetParamValues("main")
+	c.Set(string(middleware.UsernameKey), username)
+
+	require.NoError(t, h.GetWorkflowHistory(c))
+	if rec.Code != http.StatusOK {
+		return rec, nil
+	}
+	var page repository.TagHistoryPage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	return rec, &page
+}
+
+func TestGetWorkflowHistory(t *testing.T) {
+	h, store := newHistoryTestHandler(t)
+
+	t.Run("page of the tag's own moves", func(t *testing.T) {
+		rec, page := getWorkflowHistory(t, h, "alice", "?limit=2&cursor=abc")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 2, store.listedLimit)
+		assert.Equal(t, "abc", store.listedCursor)
+		require.Len(t, page.Moves, 3)
+		assert.Equal(t, models.TagMoveActionUndo, page.Moves[0].Action)
+		for _, move := range page.Moves {
+			assert.Equal(t, "alice", move.Username)
+		}
+		assert.Equal(t, "next", page.NextCursor)
+
+		_, page = getWorkflowHistory(t, h, "bob", "")
+		assert.Zero(t, store.listedLimit, "the repository applies the default")
+		require.Len(t, page.Moves, 1)
+		assert.Equal(t, "bob", page.Moves[0].Username)
+	})
+
+	t.Run("another user's tag is forbidden", func(t *testing.T) {
+		rec, _ := getWorkflowHistory(t, h, "alice", "?owner=bob")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		rec, _ := getWorkflowHistory(t, h, "carol", "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("bad limit and cursor", func(t *testing.T) {
+		rec, _ := getWorkflowHistory(t, h, "alice", "?limit=0")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec, _ = getWorkflowHistory(t, h, "alice", "?limit=ten")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec, _ = getWorkflowHistory(t, h, "alice", "?cursor=bad")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -1,163 +1,441 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"strconv"
 	"strings"
 )
 
 // WorkflowPatcher handles JSON Patch operations on workflows
+// All RFC 6902 operations are supported: add, remove, replace, move, copy and
+// test. Paths are full RFC 6901 JSON Pointers, so operations can target nested
+// node config (e.g. "/nodes/2/config/temperature" or "/nodes/0/config/tools/1/name").
+// A failing test fails the whole patch, so a patch can assert the current
+// value of a node's config before changing it.
+//
+// Node ids must stay unique: compilation keys nodes by id, so a duplicate would
+// silently replace the other node. Operations leaving two nodes with the same
+// id fail with a *DuplicateNodeIDError, unless PatchOptions.AutoSuffix renames
+// the added node.
 type WorkflowPatcher struct{}
 
+// PatchOptions customizes how a patch is applied
+type PatchOptions struct {
+	// AutoSuffix renames a node added under a taken id to the first free
+	// <id>_2, <id>_3, ... instead of rejecting the patch. Edges added later in
+	// the same patch from or to the requested id are rewired to the renamed
+	// node, the one the patch means. The operations' values are rewritten, so
+	// storing the operations replays the rename.
+	AutoSuffix bool
+}
+
+// DuplicateNodeIDError reports a patch that would leave two nodes with the same id
+type DuplicateNodeIDError struct {
+	NodeID string
+}
+
+func (e *DuplicateNodeIDError) Error() string {
+	return fmt.Sprintf("node id %q already exists", e.NodeID)
+}
+
 // ApplyJSONPatchToWorkflow applies JSON Patch operations to a workflow
 func (p *WorkflowPatcher) ApplyJSONPatchToWorkflow(workflow map[string]interface{}, operations []map[string]interface{}) (map[string]interface{}, error) {
+	patchedWorkflow, _, err := p.ApplyJSONPatchToWorkflowWithOptions(workflow, operations, PatchOptions{})
+	return patchedWorkflow, err
+}
+
+// ApplyJSONPatchToWorkflowWithOptions applies JSON Patch operations to a workflow
+// Returns the nodes AutoSuffix renamed, new id to the id the operation asked for.
+func (p *WorkflowPatcher) ApplyJSONPatchToWorkflowWithOptions(workflow map[string]interface{}, operations []map[string]interface{}, opts PatchOptions) (map[string]interface{}, map[string]string, error) {
 	// Create a deep copy of the workflow to avoid modifying the original
-	patchedWorkflow := make(map[string]interface{})
-	for k, v := range workflow {
-		// Deep copy arrays
-		if k == "nodes" || k == "edges" {
-			if arr, ok := v.([]interface{}); ok {
-				copyArr := make([]interface{}, len(arr))
-				copy(copyArr, arr)
-				patchedWorkflow[k] = copyArr
-			} else {
-				patchedWorkflow[k] = v
-			}
-		} else {
-			patchedWorkflow[k] = v
-		}
+	patchedWorkflow, _ := deepCopyJSON(workflow).(map[string]interface{})
+	if patchedWorkflow == nil {
+		patchedWorkflow = make(map[string]interface{})
 	}
+	renamed := make(map[string]string)
+	rewired := make(map[string]string) // Requested id → the node added under it
 
 	// Apply each operation
 	for i, op := range operations {
 		opType, ok := op["op"].(string)
 		if !ok {
-			return nil, fmt.Errorf("operation %d missing 'op' field", i)
+			return nil, nil, fmt.Errorf("operation %d missing 'op' field", i)
 		}
 
 		path, ok := op["path"].(string)
 		if !ok {
-			return nil, fmt.Errorf("operation %d missing 'path' field", i)
+			return nil, nil, fmt.Errorf("operation %d missing 'path' field", i)
+		}
+
+		if len(rewired) > 0 && (opType == "add" || opType == "replace") {
+			rewireEdge(op, path, rewired)
 		}
 
 		switch opType {
 		case "add":
+			if opts.AutoSuffix && isNodePosition(path) {
+				if from, to := suffixNodeID(patchedWorkflow, op); to != "" {
+					renamed[to] = from
+					rewired[from] = to
+				}
+			}
 			if err := p.applyAddOperation(patchedWorkflow, path, op["value"]); err != nil {
-				return nil, fmt.Errorf("operation %d (add) failed: %w", i, err)
+				return nil, nil, fmt.Errorf("operation %d (add) failed: %w", i, err)
 			}
 
 		case "remove":
 			if err := p.applyRemoveOperation(patchedWorkflow, path); err != nil {
-				return nil, fmt.Errorf("operation %d (remove) failed: %w", i, err)
+				return nil, nil, fmt.Errorf("operation %d (remove) failed: %w", i, err)
 			}
 
 		case "replace":
 			if err := p.applyReplaceOperation(patchedWorkflow, path, op["value"]); err != nil {
-				return nil, fmt.Errorf("operation %d (replace) failed: %w", i, err)
+				return nil, nil, fmt.Errorf("operation %d (replace) failed: %w", i, err)
+			}
+
+		case "move", "copy":
+			from, ok := op["from"].(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("operation %d missing 'from' field", i)
+			}
+			apply := p.applyCopyOperation
+			if opType == "move" {
+				apply = p.applyMoveOperation
+			}
+			if err := apply(patchedWorkflow, from, path); err != nil {
+				return nil, nil, fmt.Errorf("operation %d (%s) failed: %w", i, opType, err)
+			}
+
+		case "test":
+			if err := p.applyTestOperation(patchedWorkflow, path, op["value"]); err != nil {
+				return nil, nil, fmt.Errorf("operation %d (test) failed: %w", i, err)
 			}
 
 		default:
-			return nil, fmt.Errorf("unsupported operation type: %s", opType)
+			return nil, nil, fmt.Errorf("unsupported operation type: %s", opType)
+		}
+
+		if changesNodeIDs(path) {
+			if id := duplicateNodeID(patchedWorkflow); id != "" {
+				return nil, nil, fmt.Errorf("operation %d (%s) failed: %w", i, opType, &DuplicateNodeIDError{NodeID: id})
+			}
 		}
 	}
 
-	return patchedWorkflow, nil
+	return patchedWorkflow, renamed, nil
 }
 
-// applyAddOperation handles "add" operations
-func (p *WorkflowPatcher) applyAddOperation(workflow map[string]interface{}, path string, value interface{}) error {
-	if path == "/nodes/-" {
-		// Add node to the end of nodes array
-		nodes, ok := workflow["nodes"].([]interface{})
-		if !ok {
-			workflow["nodes"] = []interface{}{value}
-			return nil
+// isNodePosition reports whether path adds a whole node (/nodes/- or /nodes/<index>)
+func isNodePosition(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/nodes/")
+	return ok && rest != "" && !strings.Contains(rest, "/")
+}
+
+// rewireEdge points an edge an operation adds or sets at renamed nodes
+// rewired maps ids to the node to use instead. An edge value is replaced with a
+// rewired copy; a /edges/<index>/from or /to value is rewired itself.
+func rewireEdge(op map[string]interface{}, path string, rewired map[string]string) {
+	rest, ok := strings.CutPrefix(path, "/edges/")
+	if !ok || rest == "" {
+		return
+	}
+	if _, field, nested := strings.Cut(rest, "/"); nested {
+		if id, ok := op["value"].(string); ok && (field == "from" || field == "to") && rewired[id] != "" {
+			op["value"] = rewired[id]
 		}
-		workflow["nodes"] = append(nodes, value)
-		return nil
+		return
 	}
 
-	if path == "/edges/-" {
-		// Add edge to the end of edges array
-		edges, ok := workflow["edges"].([]interface{})
-		if !ok {
-			workflow["edges"] = []interface{}{value}
-			return nil
+	edge, ok := op["value"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	var copied map[string]interface{}
+	for _, field := range []string{"from", "to"} {
+		id, _ := edge[field].(string)
+		if to := rewired[id]; to != "" {
+			if copied == nil {
+				copied, _ = deepCopyJSON(edge).(map[string]interface{})
+			}
+			copied[field] = to
 		}
-		workflow["edges"] = append(edges, value)
-		return nil
 	}
+	if copied != nil {
+		op["value"] = copied
+	}
+}
 
-	return fmt.Errorf("unsupported add path: %s", path)
+// changesNodeIDs reports whether an operation at path can add or rename nodes
+func changesNodeIDs(path string) bool {
+	return path == "/nodes" || isNodePosition(path) ||
+		(strings.HasPrefix(path, "/nodes/") && strings.HasSuffix(path, "/id") && strings.Count(path, "/") == 3)
 }
 
-// applyRemoveOperation handles "remove" operations
-func (p *WorkflowPatcher) applyRemoveOperation(workflow map[string]interface{}, path string) error {
-	// Parse path like "/nodes/2" or "/edges/1"
-	// Split by "/" and parse components
-	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid remove path format: %s (expected format: /collection/index)", path)
+// nodeIDs returns the ids of the workflow's nodes, in order
+func nodeIDs(workflow map[string]interface{}) []string {
+	nodes, _ := workflow["nodes"].([]interface{})
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if n, ok := node.(map[string]interface{}); ok {
+			if id, ok := n["id"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
 	}
+	return ids
+}
 
-	collection := parts[0]
-	index, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return fmt.Errorf("invalid index in path %s: %v", path, err)
+// duplicateNodeID returns the first node id used by more than one node, if any
+func duplicateNodeID(workflow map[string]interface{}) string {
+	seen := make(map[string]bool)
+	for _, id := range nodeIDs(workflow) {
+		if seen[id] {
+			return id
+		}
+		seen[id] = true
 	}
+	return ""
+}
 
-	if collection == "nodes" {
-		nodes, ok := workflow["nodes"].([]interface{})
-		if !ok || index < 0 || index >= len(nodes) {
-			return fmt.Errorf("invalid node index: %d", index)
+// suffixNodeID renames the node an add operation inserts if its id is taken
+// The operation's value is replaced with a renamed copy. Returns the old and
+// new id, or empty strings when the id was free.
+func suffixNodeID(workflow map[string]interface{}, op map[string]interface{}) (string, string) {
+	node, ok := op["value"].(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	id, ok := node["id"].(string)
+	if !ok {
+		return "", ""
+	}
+
+	taken := make(map[string]bool)
+	for _, existing := range nodeIDs(workflow) {
+		taken[existing] = true
+	}
+	if !taken[id] {
+		return "", ""
+	}
+
+	renamed, _ := deepCopyJSON(node).(map[string]interface{})
+	renamed["id"] = uniqueNodeID(id, taken)
+	op["value"] = renamed
+	return id, renamed["id"].(string)
+}
+
+// uniqueNodeID returns the first of <id>_2, <id>_3, ... not in taken
+func uniqueNodeID(id string, taken map[string]bool) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d", id, n)
+		if !taken[candidate] {
+			return candidate
 		}
-		workflow["nodes"] = append(nodes[:index], nodes[index+1:]...)
-		return nil
 	}
+}
 
-	if collection == "edges" {
-		edges, ok := workflow["edges"].([]interface{})
-		if !ok || index < 0 || index >= len(edges) {
-			return fmt.Errorf("invalid edge index: %d", index)
+// applyAddOperation handles "add" operations
+func (p *WorkflowPatcher) applyAddOperation(workflow map[string]interface{}, path string, value interface{}) error {
+	// Appending to a missing top-level nodes/edges array creates it
+	if path == "/nodes/-" || path == "/edges/-" {
+		collection := path[1 : len(path)-2]
+		if _, ok := workflow[collection].([]interface{}); !ok {
+			workflow[collection] = []interface{}{deepCopyJSON(value)}
+			return nil
 		}
-		workflow["edges"] = append(edges[:index], edges[index+1:]...)
-		return nil
 	}
 
-	return fmt.Errorf("unsupported remove collection: %s", collection)
+	return p.applyAtPointer(workflow, path, "add", value)
+}
+
+// applyRemoveOperation handles "remove" operations
+func (p *WorkflowPatcher) applyRemoveOperation(workflow map[string]interface{}, path string) error {
+	return p.applyAtPointer(workflow, path, "remove", nil)
 }
 
 // applyReplaceOperation handles "replace" operations
 func (p *WorkflowPatcher) applyReplaceOperation(workflow map[string]interface{}, path string, value interface{}) error {
-	// Parse path like "/nodes/2" or "/edges/1"
-	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid replace path format: %s (expected format: /collection/index)", path)
+	return p.applyAtPointer(workflow, path, "replace", value)
+}
+
+// applyMoveOperation handles "move" operations: a remove at from, then an add at path
+func (p *WorkflowPatcher) applyMoveOperation(workflow map[string]interface{}, from, path string) error {
+	if strings.HasPrefix(path, from+"/") {
+		return fmt.Errorf("cannot move %s into its own child %s", from, path)
 	}
 
-	collection := parts[0]
-	index, err := strconv.Atoi(parts[1])
+	value, err := p.valueAtPointer(workflow, from)
 	if err != nil {
-		return fmt.Errorf("invalid index in path %s: %v", path, err)
+		return err
+	}
+	if err := p.applyRemoveOperation(workflow, from); err != nil {
+		return err
 	}
+	return p.applyAddOperation(workflow, path, value)
+}
 
-	if collection == "nodes" {
-		nodes, ok := workflow["nodes"].([]interface{})
-		if !ok || index < 0 || index >= len(nodes) {
-			return fmt.Errorf("invalid node index: %d", index)
+// applyCopyOperation handles "copy" operations: an add at path of the value at from
+func (p *WorkflowPatcher) applyCopyOperation(workflow map[string]interface{}, from, path string) error {
+	value, err := p.valueAtPointer(workflow, from)
+	if err != nil {
+		return err
+	}
+	return p.applyAddOperation(workflow, path, value)
+}
+
+// applyTestOperation handles "test" operations
+// Values are compared as JSON, so 1 and 1.0 are equal and key order is ignored.
+func (p *WorkflowPatcher) applyTestOperation(workflow map[string]interface{}, path string, expected interface{}) error {
+	actual, err := p.valueAtPointer(workflow, path)
+	if err != nil {
+		return err
+	}
+
+	actualJSON, err := json.Marshal(actual)
+	if err != nil {
+		return fmt.Errorf("value at %s is not JSON: %w", path, err)
+	}
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		return fmt.Errorf("test value is not JSON: %w", err)
+	}
+	if !bytes.Equal(actualJSON, expectedJSON) {
+		return fmt.Errorf("value at %s is %s, expected %s", path, actualJSON, expectedJSON)
+	}
+	return nil
+}
+
+// valueAtPointer returns the value path points to in the workflow
+func (p *WorkflowPatcher) valueAtPointer(workflow map[string]interface{}, path string) (interface{}, error) {
+	tokens, err := parseJSONPointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var current interface{} = workflow
+	for _, token := range tokens {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			child, exists := c[token]
+			if !exists {
+				return nil, fmt.Errorf("path %s does not resolve: key %q not found", path, token)
+			}
+			current = child
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, fmt.Errorf("path %s does not resolve: %w", path, err)
+			}
+			current = c[index]
+		default:
+			return nil, fmt.Errorf("path %s does not resolve: cannot traverse into %T at %q", path, current, token)
 		}
-		nodes[index] = value
-		return nil
 	}
+	return current, nil
+}
 
-	if collection == "edges" {
-		edges, ok := workflow["edges"].([]interface{})
-		if !ok || index < 0 || index >= len(edges) {
-			return fmt.Errorf("invalid edge index: %d", index)
+// applyAtPointer resolves path against the workflow and applies op at its target
+func (p *WorkflowPatcher) applyAtPointer(workflow map[string]interface{}, path, op string, value interface{}) error {
+	tokens, err := parseJSONPointer(path)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("path %q targets the whole workflow", path)
+	}
+
+	if _, err := applyAtTokens(workflow, tokens, op, deepCopyJSON(value)); err != nil {
+		return fmt.Errorf("path %s does not resolve: %w", path, err)
+	}
+	return nil
+}
+
+// applyAtTokens walks tokens down from container and applies op at the last one
+// Slices can't be resized in place, so each level returns the (possibly new)
+// container for its parent to store back.
+func applyAtTokens(container interface{}, tokens []string, op string, value interface{}) (interface{}, error) {
+	token := tokens[0]
+	last := len(tokens) == 1
+
+	switch c := container.(type) {
+	case map[string]interface{}:
+		child, exists := c[token]
+		if !last {
+			if !exists {
+				return nil, fmt.Errorf("key %q not found", token)
+			}
+			updated, err := applyAtTokens(child, tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			c[token] = updated
+			return c, nil
+		}
+
+		switch op {
+		case "add":
+			c[token] = value
+		case "remove":
+			if !exists {
+				return nil, fmt.Errorf("key %q not found", token)
+			}
+			delete(c, token)
+		case "replace":
+			if !exists {
+				return nil, fmt.Errorf("key %q not found", token)
+			}
+			c[token] = value
 		}
-		edges[index] = value
-		return nil
+		return c, nil
+
+	case []interface{}:
+		index, err := parseArrayIndex(token, len(c), last && op == "add")
+		if err != nil {
+			return nil, err
+		}
+		if !last {
+			updated, err := applyAtTokens(c[index], tokens[1:], op, value)
+			if err != nil {
+				return nil, err
+			}
+			c[index] = updated
+			return c, nil
+		}
+
+		switch op {
+		case "add":
+			c = append(c, nil)
+			copy(c[index+1:], c[index:])
+			c[index] = value
+		case "remove":
+			c = append(c[:index], c[index+1:]...)
+		case "replace":
+			c[index] = value
+		}
+		return c, nil
+
+	default:
+		return nil, fmt.Errorf("cannot traverse into %T at %q", container, token)
 	}
+}
 
-	return fmt.Errorf("unsupported replace collection: %s", collection)
+// deepCopyJSON copies a decoded JSON value so patches never alias the input
+func deepCopyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			copied[k] = deepCopyJSON(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyJSON(item)
+		}
+		return copied
+	default:
+		return v
+	}
 }
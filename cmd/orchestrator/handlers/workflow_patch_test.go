@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patchTestWorkflow() map[string]interface{} {
+	return map[string]interface{}{
+		"nodes": []interface{}{
+			map[string]interface{}{
+				"id":   "agent",
+				"type": "agent",
+				"config": map[string]interface{}{
+					"tools": []interface{}{
+						map[string]interface{}{"name": "search"},
+						map[string]interface{}{"name": "fetch"},
+					},
+					"a/b": "slash",
+				},
+			},
+			map[string]interface{}{
+				"id":   "llm",
+				"type": "function",
+				"config": map[string]interface{}{
+					"model": map[string]interface{}{"temperature": 0.2},
+				},
+			},
+		},
+		"edges": []interface{}{
+			map[string]interface{}{"from": "agent", "to": "llm"},
+		},
+	}
+}
+
+func nodeConfig(t *testing.T, workflow map[string]interface{}, index int) map[string]interface{} {
+	t.Helper()
+	node := workflow["nodes"].([]interface{})[index].(map[string]interface{})
+	return node["config"].(map[string]interface{})
+}
+
+func TestApplyJSONPatchToWorkflow_NestedConfig(t *testing.T) {
+	patcher := &WorkflowPatcher{}
+	original := patchTestWorkflow()
+
+	patched, err := patcher.ApplyJSONPatchToWorkflow(original, []map[string]interface{}{
+		{"op": "replace", "path": "/nodes/1/config/model/temperature", "value": 0.9},
+		{"op": "replace", "path": "/nodes/0/config/tools/1/name", "value": "browse"},
+		{"op": "add", "path": "/nodes/0/config/tools/-", "value": map[string]interface{}{"name": "calc"}},
+		{"op": "remove", "path": "/nodes/0/config/tools/0"},
+		{"op": "replace", "path": "/nodes/0/config/a~1b", "value": "escaped"},
+	})
+	require.NoError(t, err)
+
+	llm := nodeConfig(t, patched, 1)
+	assert.Equal(t, 0.9, llm["model"].(map[string]interface{})["temperature"])
+
+	agent := nodeConfig(t, patched, 0)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "browse"},
+		map[string]interface{}{"name": "calc"},
+	}, agent["tools"])
+	assert.Equal(t, "escaped", agent["a/b"])
+
+	// The input workflow is left untouched
+	assert.Equal(t, patchTestWorkflow(), original)
+}
+
+func TestApplyJSONPatchToWorkflow_AppendNodeAndEdge(t *testing.T) {
+	patcher := &WorkflowPatcher{}
+
+	patched, err := patcher.ApplyJSONPatchToWorkflow(map[string]interface{}{}, []map[string]interface{}{
+		{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "a"}},
+		{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "b"}},
+		{"op": "add", "path": "/edges/-", "value": map[string]interface{}{"from": "a", "to": "b"}},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, patched["nodes"], 2)
+	assert.Len(t, patched["edges"], 1)
+}
+
+func TestApplyJSONPatchToWorkflow_UnresolvablePaths(t *testing.T) {
+	patcher := &WorkflowPatcher{}
+
+	tests := []struct {
+		name string
+		op   map[string]interface{}
+	}{
+		{"node index out of range", map[string]interface{}{"op": "replace", "path": "/nodes/5/config/x", "value": 1}},
+		{"missing config key", map[string]interface{}{"op": "replace", "path": "/nodes/1/config/missing", "value": 1}},
+		{"missing intermediate key", map[string]interface{}{"op": "add", "path": "/nodes/1/config/missing/x", "value": 1}},
+		{"leading zero index", map[string]interface{}{"op": "replace", "path": "/nodes/01/id", "value": "x"}},
+		{"dash outside add", map[string]interface{}{"op": "remove", "path": "/nodes/0/config/tools/-"}},
+		{"traverse into scalar", map[string]interface{}{"op": "replace", "path": "/nodes/0/id/x", "value": 1}},
+		{"bad escape", map[string]interface{}{"op": "replace", "path": "/nodes/0/config/a~2b", "value": 1}},
+		{"no leading slash", map[string]interface{}{"op": "replace", "path": "nodes/0", "value": 1}},
+		{"whole document", map[string]interface{}{"op": "replace", "path": "", "value": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{tt.op})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyJSONPatchToWorkflow_Test(t *testing.T) {
+	patcher := &WorkflowPatcher{}
+
+	// Passing test: the replace that follows it is applied
+	patched, err := patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{
+		{"op": "test", "path": "/nodes/1/config/model", "value": map[string]interface{}{"temperature": 0.2}},
+		{"op": "replace", "path": "/nodes/1/config/model/temperature", "value": 0.9},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0.9, nodeConfig(t, patched, 1)["model"].(map[string]interface{})["temperature"])
+
+	// Failing test: the whole patch is rejected, naming the value it found
+	_, err = patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{
+		{"op": "replace", "path": "/nodes/0/id", "value": "renamed"},
+		{"op": "test", "path": "/nodes/1/config/model/temperature", "value": 0.5},
+		{"op": "replace", "path": "/nodes/1/config/model/temperature", "value": 0.9},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "operation 1 (test) failed: value at /nodes/1/config/model/temperature is 0.2, expected 0.5", err.Error())
+
+	// Testing a path that doesn't exist fails too
+	_, err = patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{
+		{"op": "test", "path": "/nodes/1/config/missing", "value": nil},
+	})
+	assert.Error(t, err)
+}
+
+func TestApplyJSONPatchToWorkflow_MoveAndCopy(t *testing.T) {
+	patcher := &WorkflowPatcher{}
+
+	patched, err := patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{
+		{"op": "move", "from": "/nodes/1", "path": "/nodes/0"},
+		{"op": "copy", "from": "/edges/0", "path": "/edges/-"},
+		{"op": "move", "from": "/nodes/1/config/tools", "path": "/nodes/1/config/toolbox"},
+	})
+	require.NoError(t, err)
+
+	var ids []string
+	for _, node := range patched["nodes"].([]interface{}) {
+		ids = append(ids, node.(map[string]interface{})["id"].(string))
+	}
+	assert.Equal(t, []string{"llm", "agent"}, ids)
+	assert.Len(t, patched["edges"], 2)
+	assert.Contains(t, nodeConfig(t, patched, 1), "toolbox")
+	assert.NotContains(t, nodeConfig(t, patched, 1), "tools")
+
+	// Copies don't alias their source
+	edges := patched["edges"].([]interface{})
+	edges[1].(map[string]interface{})["to"] = "agent"
+	assert.Equal(t, "llm", edges[0].(map[string]interface{})["to"])
+
+	tests := []struct {
+		name string
+		op   map[string]interface{}
+	}{
+		{"missing from", map[string]interface{}{"op": "move", "path": "/nodes/0"}},
+		{"unresolvable from", map[string]interface{}{"op": "copy", "from": "/nodes/5", "path": "/nodes/-"}},
+		{"move into own child", map[string]interface{}{"op": "move", "from": "/nodes/0", "path": "/nodes/0/config/self"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{tt.op})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplyJSONPatchToWorkflow_DuplicateNodeID(t *testing.T) {
+	patcher := &WorkflowPatcher{}
+
+	tests := []struct {
+		name string
+		op   map[string]interface{}
+	}{
+		{"append", map[string]interface{}{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "llm", "type": "http"}}},
+		{"insert", map[string]interface{}{"op": "add", "path": "/nodes/0", "value": map[string]interface{}{"id": "agent"}}},
+		{"copy", map[string]interface{}{"op": "copy", "from": "/nodes/0", "path": "/nodes/-"}},
+		{"rename", map[string]interface{}{"op": "replace", "path": "/nodes/1/id", "value": "agent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{tt.op})
+			var duplicate *DuplicateNodeIDError
+			require.True(t, errors.As(err, &duplicate), "got %v", err)
+			assert.Contains(t, []string{"agent", "llm"}, duplicate.NodeID)
+		})
+	}
+
+	// Removing the old node first frees its id
+	patched, err := patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), []map[string]interface{}{
+		{"op": "remove", "path": "/nodes/1"},
+		{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "llm", "type": "http"}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, patched["nodes"], 2)
+}
+
+func TestApplyJSONPatchToWorkflow_AutoSuffix(t *testing.T) {
+	patcher := &WorkflowPatcher{}
+	operations := []map[string]interface{}{
+		{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "llm", "type": "http"}},
+		{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "llm", "type": "http"}},
+		{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "fresh"}},
+		// Later edges to the requested id mean the last node added under it
+		{"op": "add", "path": "/edges/-", "value": map[string]interface{}{"from": "llm", "to": "fresh"}},
+		{"op": "replace", "path": "/edges/0/to", "value": "llm"},
+	}
+
+	patched, renamed, err := patcher.ApplyJSONPatchToWorkflowWithOptions(patchTestWorkflow(), operations, PatchOptions{AutoSuffix: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"llm_2": "llm", "llm_3": "llm"}, renamed)
+	edges := patched["edges"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"from": "agent", "to": "llm_3"}, edges[0])
+	assert.Equal(t, map[string]interface{}{"from": "llm_3", "to": "fresh"}, edges[len(edges)-1])
+
+	var ids []string
+	for _, node := range patched["nodes"].([]interface{}) {
+		ids = append(ids, node.(map[string]interface{})["id"].(string))
+	}
+	assert.Equal(t, []string{"agent", "llm", "llm_2", "llm_3", "fresh"}, ids)
+
+	// The operations carry the new ids, so replaying them needs no renaming
+	assert.Equal(t, "llm_2", operations[0]["value"].(map[string]interface{})["id"])
+	assert.Equal(t, "llm_3", operations[1]["value"].(map[string]interface{})["id"])
+	replayed, err := patcher.ApplyJSONPatchToWorkflow(patchTestWorkflow(), operations)
+	require.NoError(t, err)
+	assert.Equal(t, patched, replayed)
+}
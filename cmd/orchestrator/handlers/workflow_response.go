@@ -30,6 +30,7 @@ func (b *WorkflowResponseBuilder) BuildWorkflowResponse(tagName, owner string, c
 		"kind":        components.Kind,
 		"depth":       components.Depth,
 		"patch_count": components.PatchCount,
+		"version":     components.TagVersion, // Pass as expected_version when patching
 		"created_at":  components.CreatedAt,
 	}
 
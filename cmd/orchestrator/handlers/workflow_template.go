@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+)
+
+// ListTemplates returns the catalog of workflow templates and their parameters
+// GET /api/v1/templates
+func (h *WorkflowHandler) ListTemplates(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	templates, err := h.workflowService.ListTemplates(ctx)
+	if err != nil {
+		h.components.Logger.Error("failed to list templates", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list templates")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// InstantiateTemplate creates a workflow in the caller's namespace from a global template
+// POST /api/v1/workflows/from-template
+//
+// Body: {"template": "...", "tag": "...", "parameters": {...}}. Returns 400
+// for a missing required parameter or a workflow that isn't a template, and
+// 409 if tag already exists.
+func (h *WorkflowHandler) InstantiateTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	// Extract username from context
+	username, err := middleware.RequireUsername(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse request body
+	var req struct {
+		Template   string                 `json:"template"`
+		Tag        string                 `json:"tag"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.Template == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "template is required",
+		})
+	}
+	if errMsg := service.ValidateUserTagName(req.Tag); errMsg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("invalid tag: %s", errMsg),
+		})
+	}
+
+	resp, err := h.workflowService.InstantiateTemplate(ctx, &service.InstantiateTemplateRequest{
+		Username:   username,
+		Template:   req.Template,
+		TagName:    req.Tag,
+		Parameters: req.Parameters,
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrInvalidTemplateParameters), errors.Is(err, service.ErrNotATemplate):
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrWorkflowNotFound):
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "template not found",
+		})
+	case errors.Is(err, service.ErrTagExists):
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrWorkflowTooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"error": err.Error(),
+		})
+	default:
+		h.components.Logger.Error("failed to create workflow from template",
+			"username", username,
+			"template", req.Template,
+			"tag", req.Tag,
+			"error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": fmt.Sprintf("failed to create workflow from template: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"artifact_id":          resp.ArtifactID,
+		"cas_id":               resp.CASID,
+		"tag":                  resp.TagName,
+		"owner":                resp.Username,
+		"template":             resp.Template,
+		"template_artifact_id": resp.TemplateArtifactID,
+		"nodes_count":          resp.NodesCount,
+		"edges_count":          resp.EdgesCount,
+		"created_at":           resp.CreatedAt,
+	})
+}
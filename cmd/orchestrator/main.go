@@ -2,19 +2,29 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/container"
+	orchestratormiddleware "github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/routes"
 	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/metrics"
 	commonmiddleware "github.com/lyzr/orchestrator/common/middleware"
 )
 
 func main() {
-	ctx := context.Background()
+	// Cancelled on shutdown to stop the background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Bootstrap common components (DB, logger, queue, cache, telemetry)
 	components, err := bootstrap.Setup(ctx, "orchestrator")
@@ -22,7 +32,8 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to bootstrap orchestrator: %v\n", err)
 		os.Exit(1)
 	}
-	defer components.Shutdown(ctx)
+	// Runs after the server drained, with a context shutdown hasn't cancelled
+	defer components.Shutdown(context.Background())
 
 	// Initialize service container (singleton pattern - all services created once)
 	serviceContainer, err := container.NewContainer(components)
@@ -31,6 +42,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Start background compaction of deep patch chains
+	if components.Config.Compaction.Enabled {
+		go serviceContainer.AutoCompactor.Run(ctx)
+	}
+
+	// Start background collection of unreferenced CAS blobs
+	if components.Config.CASGC.Enabled {
+		go serviceContainer.CASCollector.Run(ctx)
+	}
+
+	// Start background trimming of task and request streams
+	if components.Config.StreamTrim.Enabled {
+		go serviceContainer.StreamTrimmer.Run(ctx)
+	}
+
 	// Initialize Echo server
 	e := setupEcho()
 
@@ -38,13 +64,26 @@ func main() {
 	setupMiddleware(e, serviceContainer)
 
 	// Setup health check
-	setupHealthCheck(e)
+	setupHealthCheck(e, serviceContainer)
 
 	// Register all routes
 	registerRoutes(e, serviceContainer)
 
-	// Start server
-	startServer(e, components)
+	// Serve until SIGINT/SIGTERM, then drain in-flight requests
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	err = startServer(e, components, sigChan)
+
+	// Stop the background jobs before their DB and Redis connections close
+	cancel()
+	if err != nil {
+		components.Logger.Error("Server error", "error", err)
+		components.Shutdown(context.Background())
+		os.Exit(1)
+	}
+
+	components.Logger.Info("orchestrator shut down gracefully")
 }
 
 // setupEcho initializes the Echo server with basic configuration
@@ -59,8 +98,21 @@ func setupMiddleware(e *echo.Echo, c *container.Container) {
 	// Standard Echo middleware
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
-	e.Use(middleware.RequestID())
+	// Cross-origin policy from CORS_* (no origins allowed unless configured)
+	e.Use(orchestratormiddleware.CORS(c.Components.Config.CORS))
+	// The request ID is also put in the request context, for the audit log
+	e.Use(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, requestID string) {
+			c.SetRequest(c.Request().WithContext(clients.WithRequestID(c.Request().Context(), requestID)))
+		},
+	}))
+
+	// Per-request deadline, except on streams and long polls
+	serviceConfig := c.Components.Config.Service
+	e.Use(orchestratormiddleware.RequestTimeout(serviceConfig.RequestTimeout, untimedRoutes...))
+
+	// Body size caps; routes carrying workflows or patches get a larger one
+	e.Use(orchestratormiddleware.BodyLimit(serviceConfig.MaxBodyBytes, workflowBodyLimits(serviceConfig.MaxWorkflowBodyBytes)))
 
 	// Rate limiting middleware (defense in depth)
 	// 1. Global limit - protects entire service from overload
@@ -70,14 +122,53 @@ func setupMiddleware(e *echo.Echo, c *container.Container) {
 	// Note: Applied in route groups where ExtractUsername is used
 }
 
-// setupHealthCheck registers the health check endpoint
-func setupHealthCheck(e *echo.Echo) {
+// untimedRoutes stream or long-poll, bounding their own lifetime instead of REQUEST_TIMEOUT
+var untimedRoutes = []string{
+	"/api/v1/runs/:id/events", // SSE: open until the run finishes
+	"/api/v1/runs/:id/wait",   // Long-poll: own timeout parameter
+}
+
+// workflowBodyRoutes carry whole workflows, templates or patch operations
+var workflowBodyRoutes = []string{
+	http.MethodPost + " /api/v1/workflows",
+	http.MethodPost + " /api/v1/workflows/from-template",
+	http.MethodPatch + " /api/v1/workflows/:tag/patch",
+	http.MethodPost + " /api/v1/runs/:id/patch",
+	http.MethodPost + " /api/v1/runs/:run_id/patches",
+}
+
+// workflowBodyLimits caps each of workflowBodyRoutes at limit bytes
+func workflowBodyLimits(limit int64) map[string]int64 {
+	limits := make(map[string]int64, len(workflowBodyRoutes))
+	for _, route := range workflowBodyRoutes {
+		limits[route] = limit
+	}
+	return limits
+}
+
+// setupHealthCheck registers the liveness, readiness and metrics endpoints
+func setupHealthCheck(e *echo.Echo, serviceContainer *container.Container) {
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(200, map[string]string{
 			"status":  "ok",
 			"service": "orchestrator",
 		})
 	})
+
+	// Readiness: 503 naming the dependency (DB, Redis) that can't be reached
+	e.GET("/ready", echo.WrapHandler(serviceContainer.Dependencies.Handler()))
+
+	// Stream readiness: 503 when stream backlogs are too deep or required consumers are gone
+	e.GET("/readyz", func(c echo.Context) error {
+		report := serviceContainer.Readiness.Check(c.Request().Context())
+		if !report.Ready() {
+			return c.JSON(http.StatusServiceUnavailable, report)
+		}
+		return c.JSON(http.StatusOK, report)
+	})
+
+	// Prometheus metrics (also served on METRICS_PORT by telemetry)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
 }
 
 // registerRoutes registers all application routes using the service container
@@ -86,16 +177,62 @@ func registerRoutes(e *echo.Echo, serviceContainer *container.Container) {
 	routes.RegisterTagRoutes(e, serviceContainer)
 	routes.RegisterRunRoutes(e, serviceContainer)
 	routes.RegisterRunPatchRoutes(e, serviceContainer)
+	routes.RegisterAuthRoutes(e, serviceContainer)
+	routes.RegisterAuditRoutes(e, serviceContainer)
+	routes.RegisterUserRoutes(e, serviceContainer)
 }
 
 // startServer starts the Echo server on the configured port
-func startServer(e *echo.Echo, components *bootstrap.Components) {
+// Returns once the server was shut down after a signal (see serve).
+func startServer(e *echo.Echo, components *bootstrap.Components, signals <-chan os.Signal) error {
 	port := components.Config.Service.Port
-	components.Logger.Info("Starting orchestrator", "port", port)
+	components.Logger.Info("Starting orchestrator",
+		"port", port,
+		"shutdown_timeout", components.Config.Service.ShutdownTimeout)
 
-	// Start with graceful shutdown
-	if err := e.Start(fmt.Sprintf(":%d", port)); err != nil {
-		components.Logger.Error("Server error", "error", err)
-		os.Exit(1)
+	// Slow clients can't hold connections open by trickling headers
+	e.Server.ReadHeaderTimeout = components.Config.Service.ReadHeaderTimeout
+
+	return serve(e, fmt.Sprintf(":%d", port), signals, components.Config.Service.ShutdownTimeout, components.Logger)
+}
+
+// httpServer is the part of *echo.Echo serve drives
+type httpServer interface {
+	Start(address string) error
+	Shutdown(ctx context.Context) error
+}
+
+// shutdownLogger is the logging serve needs
+type shutdownLogger interface {
+	Info(msg string, keysAndValues ...interface{})
+}
+
+// serve runs the server until it fails or a signal arrives
+// On a signal the server stops accepting connections and in-flight requests get
+// up to timeout to finish; requests still running then are cut off and the
+// deadline error is returned.
+func serve(server httpServer, address string, signals <-chan os.Signal, timeout time.Duration, logger shutdownLogger) error {
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Start(address)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case sig := <-signals:
+		logger.Info("received shutdown signal, draining requests", "signal", sig, "timeout", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	// Start returns http.ErrServerClosed once Shutdown stopped it
+	if err := <-errChan; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/cmd/orchestrator/container"
+	orchestratormiddleware "github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/health"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+// startTestEcho returns an Echo server on a free port whose /slow handler
+// signals started and then takes delay
+func startTestEcho(t *testing.T, delay time.Duration) (*echo.Echo, string, chan struct{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{}, 1)
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Listener = listener
+	e.GET("/slow", func(c echo.Context) error {
+		started <- struct{}{}
+		time.Sleep(delay)
+		return c.String(http.StatusOK, "done")
+	})
+	return e, "http://" + listener.Addr().String(), started
+}
+
+func TestServe_DrainsInFlightRequestsOnSignal(t *testing.T) {
+	e, url, started := startTestEcho(t, 200*time.Millisecond)
+	signals := make(chan os.Signal, 1)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(e, "", signals, 5*time.Second, noopLogger{})
+	}()
+
+	// A request is in flight when SIGTERM arrives
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err == nil {
+			responses <- resp
+		}
+		close(responses)
+	}()
+	<-started
+	signals <- syscall.SIGTERM
+
+	select {
+	case err := <-served:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the signal")
+	}
+
+	// The request finished before serve returned
+	resp, ok := <-responses
+	require.True(t, ok, "in-flight request was cut off")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// No new connections are accepted
+	_, err := http.Get(url + "/slow")
+	assert.Error(t, err)
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	e, url, started := startTestEcho(t, 2*time.Second)
+	signals := make(chan os.Signal, 1)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(e, "", signals, 100*time.Millisecond, noopLogger{})
+	}()
+
+	go func() {
+		if resp, err := http.Get(url + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	begin := time.Now()
+	signals <- syscall.SIGTERM
+
+	// A request outliving the timeout doesn't hold the shutdown up
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(begin), time.Second)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the shutdown timeout")
+	}
+}
+
+// fakeServer records Shutdown and blocks Start until then
+type fakeServer struct {
+	stopped  chan struct{}
+	shutdown bool
+	startErr error
+}
+
+func (f *fakeServer) Start(address string) error {
+	if f.startErr != nil {
+		return f.startErr
+	}
+	<-f.stopped
+	return http.ErrServerClosed
+}
+
+func (f *fakeServer) Shutdown(ctx context.Context) error {
+	f.shutdown = true
+	close(f.stopped)
+	return nil
+}
+
+func TestServe_StartErrorSkipsShutdown(t *testing.T) {
+	server := &fakeServer{stopped: make(chan struct{}), startErr: assert.AnError}
+
+	err := serve(server, ":0", make(chan os.Signal), time.Second, noopLogger{})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.False(t, server.shutdown)
+}
+
+func TestServe_SignalInvokesShutdown(t *testing.T) {
+	server := &fakeServer{stopped: make(chan struct{})}
+	signals := make(chan os.Signal, 1)
+	signals <- os.Interrupt
+
+	require.NoError(t, serve(server, ":0", signals, time.Second, noopLogger{}))
+	assert.True(t, server.shutdown)
+}
+
+func TestHealthCheck_ReadyReportsRedisDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+
+	e := echo.New()
+	setupHealthCheck(e, &container.Container{
+		Dependencies: (&bootstrap.Components{}).Dependencies(rdb),
+	})
+	mr.Close()
+
+	// Liveness doesn't depend on Redis
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var report health.DependencyReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusNotReady, report.Status)
+	require.Len(t, report.Dependencies, 1)
+	assert.Equal(t, "redis", report.Dependencies[0].Name)
+	assert.False(t, report.Dependencies[0].Ready)
+	assert.NotEmpty(t, report.Dependencies[0].Error)
+}
+
+func TestWorkflowBodyLimits(t *testing.T) {
+	e := echo.New()
+	e.Use(orchestratormiddleware.BodyLimit(1024, workflowBodyLimits(4096)))
+	readBody := func(c echo.Context) error {
+		if _, err := io.ReadAll(c.Request().Body); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	}
+	send := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(strings.Repeat("x", 2048))))
+		return rec.Code
+	}
+
+	e.POST("/api/v1/workflows", readBody)
+	e.POST("/api/v1/workflows/from-template", readBody)
+	e.PATCH("/api/v1/workflows/:tag/patch", readBody)
+	e.POST("/api/v1/runs/:id/patch", readBody)
+	e.POST("/api/v1/runs/:run_id/patches", readBody)
+	e.POST("/api/v1/workflows/:tag/execute", readBody)
+
+	// Workflows and patches get the larger cap, whichever route carries them
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/workflows"))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/workflows/from-template"))
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/api/v1/workflows/main/patch"))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/runs/run-1/patch"))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/runs/run-1/patches"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(http.MethodPost, "/api/v1/workflows/main/execute"))
+}
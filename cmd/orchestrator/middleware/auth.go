@@ -87,3 +87,31 @@ func RequireUsername(c echo.Context) (string, error) {
 	}
 	return username, nil
 }
+
+// RequireAdmin only lets the listed usernames (X-User-ID) through
+// Guards internal endpoints; with no admins configured every request is refused.
+func RequireAdmin(admins []string) echo.MiddlewareFunc {
+	allowed := make(map[string]bool, len(admins))
+	for _, admin := range admins {
+		allowed[admin] = true
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			username := c.Request().Header.Get("X-User-ID")
+			if username == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]interface{}{
+					"error": "X-User-ID header is required",
+				})
+			}
+			if !allowed[username] {
+				return c.JSON(http.StatusForbidden, map[string]interface{}{
+					"error": "admin access required",
+				})
+			}
+
+			c.Set(string(UsernameKey), username)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+// CORS applies the configured cross-origin policy
+// Allowed origins get Access-Control-Allow-Origin on their requests and
+// preflights. A preflight from any other origin is rejected with 403; other
+// requests from it are served without CORS headers, so browsers won't expose
+// the response.
+//
+// Preflights are answered here, before routing reaches auth or rate limiting,
+// so PATCH and DELETE routes need no OPTIONS handlers of their own.
+func CORS(cfg config.CORSConfig) echo.MiddlewareFunc {
+	allowed := func(origin string) bool {
+		for _, o := range cfg.AllowedOrigins {
+			if o == "*" || o == origin {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Echo allows every origin when AllowOrigins is empty; the origin func
+	// keeps an empty list meaning none.
+	cors := echomiddleware.CORSWithConfig(echomiddleware.CORSConfig{
+		AllowOriginFunc: func(origin string) (bool, error) {
+			return allowed(origin), nil
+		},
+		AllowMethods:     cfg.AllowedMethods,
+		AllowHeaders:     cfg.AllowedHeaders,
+		ExposeHeaders:    cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withCORS := cors(next)
+		return func(c echo.Context) error {
+			req := c.Request()
+			origin := req.Header.Get(echo.HeaderOrigin)
+			preflight := req.Method == http.MethodOptions && req.Header.Get(echo.HeaderAccessControlRequestMethod) != ""
+
+			if preflight && origin != "" && !allowed(origin) {
+				c.Response().Header().Add(echo.HeaderVary, echo.HeaderOrigin)
+				return echo.NewHTTPError(http.StatusForbidden, "origin not allowed")
+			}
+			return withCORS(c)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+const allowedOrigin = "https://app.example.com"
+
+// newCORSServer serves PATCH and DELETE workflow routes behind CORS and strict auth
+func newCORSServer(cfg config.CORSConfig) *echo.Echo {
+	e := echo.New()
+	e.Use(CORS(cfg))
+
+	wf := e.Group("/api/v1/workflows", ExtractUsernameStrict())
+	wf.PATCH("/:tag/patch", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	wf.DELETE("/:tag", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	return e
+}
+
+func testCORSConfig() config.CORSConfig {
+	return config.CORSConfig{
+		AllowedOrigins: []string{allowedOrigin},
+		AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "X-User-ID"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+func serveCORS(e *echo.Echo, method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set(echo.HeaderOrigin, origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func preflight(method string) map[string]string {
+	return map[string]string{
+		echo.HeaderAccessControlRequestMethod:  method,
+		echo.HeaderAccessControlRequestHeaders: "content-type,x-user-id",
+	}
+}
+
+func TestCORS_AllowedOriginPreflight(t *testing.T) {
+	e := newCORSServer(testCORSConfig())
+
+	for path, method := range map[string]string{
+		"/api/v1/workflows/main/patch": http.MethodPatch,
+		"/api/v1/workflows/main":       http.MethodDelete,
+	} {
+		// Answered without reaching the routes' auth
+		rec := serveCORS(e, http.MethodOptions, path, allowedOrigin, preflight(method))
+		assert.Equal(t, http.StatusNoContent, rec.Code, method)
+		assert.Equal(t, allowedOrigin, rec.Header().Get(echo.HeaderAccessControlAllowOrigin), method)
+		assert.Contains(t, rec.Header().Get(echo.HeaderAccessControlAllowMethods), method)
+		assert.Equal(t, "Content-Type,X-User-ID", rec.Header().Get(echo.HeaderAccessControlAllowHeaders))
+		assert.Equal(t, "600", rec.Header().Get(echo.HeaderAccessControlMaxAge))
+		assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowCredentials))
+	}
+}
+
+func TestCORS_AllowedOriginRequest(t *testing.T) {
+	e := newCORSServer(testCORSConfig())
+
+	rec := serveCORS(e, http.MethodDelete, "/api/v1/workflows/main", allowedOrigin, map[string]string{"X-User-ID": "alice"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, allowedOrigin, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, "X-Request-ID", rec.Header().Get(echo.HeaderAccessControlExposeHeaders))
+}
+
+func TestCORS_DisallowedOrigin(t *testing.T) {
+	e := newCORSServer(testCORSConfig())
+
+	// Preflight is rejected
+	rec := serveCORS(e, http.MethodOptions, "/api/v1/workflows/main/patch", "https://evil.example.com", preflight(http.MethodPatch))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowMethods))
+
+	// A request is served without CORS headers, so the browser withholds it
+	rec = serveCORS(e, http.MethodDelete, "/api/v1/workflows/main", "https://evil.example.com", map[string]string{"X-User-ID": "alice"})
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
+
+func TestCORS_NoOriginsConfigured(t *testing.T) {
+	cfg := testCORSConfig()
+	cfg.AllowedOrigins = nil
+	e := newCORSServer(cfg)
+
+	rec := serveCORS(e, http.MethodOptions, "/api/v1/workflows/main", allowedOrigin, preflight(http.MethodDelete))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Non-browser clients (no Origin) are unaffected
+	rec = serveCORS(e, http.MethodDelete, "/api/v1/workflows/main", "", map[string]string{"X-User-ID": "alice"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
+
+func TestCORS_WildcardOrigin(t *testing.T) {
+	cfg := testCORSConfig()
+	cfg.AllowedOrigins = []string{"*"}
+	e := newCORSServer(cfg)
+
+	rec := serveCORS(e, http.MethodOptions, "/api/v1/workflows/main", "https://any.example.com", preflight(http.MethodDelete))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://any.example.com", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
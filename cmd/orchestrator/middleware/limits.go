@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+// BodyLimit caps request bodies at limit bytes, answering 413 beyond it
+// routeLimits sets a different cap for specific routes, keyed by method and
+// route path as registered (e.g. "POST /api/v1/workflows").
+func BodyLimit(limit int64, routeLimits map[string]int64) echo.MiddlewareFunc {
+	defaultLimit := echomiddleware.BodyLimit(strconv.FormatInt(limit, 10))
+	overrides := make(map[string]echo.MiddlewareFunc, len(routeLimits))
+	for route, routeLimit := range routeLimits {
+		overrides[route] = echomiddleware.BodyLimit(strconv.FormatInt(routeLimit, 10))
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		limited := defaultLimit(next)
+		routes := make(map[string]echo.HandlerFunc, len(overrides))
+		for route, override := range overrides {
+			routes[route] = override(next)
+		}
+
+		return func(c echo.Context) error {
+			if handler, ok := routes[c.Request().Method+" "+c.Path()]; ok {
+				return handler(c)
+			}
+			return limited(c)
+		}
+	}
+}
+
+// RequestTimeout puts a deadline on each request's context, answering 503 once it passes
+// Handlers stop at the deadline only where they honor the context (DB and
+// Redis calls do). Routes in untimed (route paths as registered) manage their
+// own lifetime, such as event streams and long polls.
+func RequestTimeout(timeout time.Duration, untimed ...string) echo.MiddlewareFunc {
+	if timeout <= 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	skip := make(map[string]bool, len(untimed))
+	for _, route := range untimed {
+		skip[route] = true
+	}
+
+	return echomiddleware.ContextTimeoutWithConfig(echomiddleware.ContextTimeoutConfig{
+		Timeout: timeout,
+		Skipper: func(c echo.Context) bool {
+			return skip[c.Path()]
+		},
+		// Handlers often report a timed-out call as a generic failure, so the
+		// context decides rather than the error. The handler's error isn't
+		// attached: echo answers with an internal *HTTPError when there is one.
+		ErrorHandler: func(err error, c echo.Context) error {
+			ctxErr := c.Request().Context().Err()
+			if errors.Is(ctxErr, context.DeadlineExceeded) && !c.Response().Committed {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "request timed out").SetInternal(ctxErr)
+			}
+			return err
+		},
+	})
+}
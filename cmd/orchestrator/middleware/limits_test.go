@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// readBody echoes the size of the request body it read
+func readBody(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, map[string]int{"size": len(body)})
+}
+
+func postBody(e *echo.Echo, path string, size int, chunked bool) *httptest.ResponseRecorder {
+	var body io.Reader = bytes.NewReader(make([]byte, size))
+	if chunked {
+		// No Content-Length: the limit applies while the body is read
+		body = io.MultiReader(body)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	if chunked {
+		req.ContentLength = -1
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBodyLimit(t *testing.T) {
+	e := echo.New()
+	e.Use(BodyLimit(1024, map[string]int64{"POST /api/v1/workflows": 4096}))
+	e.POST("/api/v1/workflows", readBody)
+	e.POST("/api/v1/runs", readBody)
+
+	for _, chunked := range []bool{false, true} {
+		assert.Equal(t, http.StatusOK, postBody(e, "/api/v1/runs", 1024, chunked).Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, postBody(e, "/api/v1/runs", 1025, chunked).Code)
+
+		// The workflow upload route has its own, higher cap
+		assert.Equal(t, http.StatusOK, postBody(e, "/api/v1/workflows", 4096, chunked).Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, postBody(e, "/api/v1/workflows", 4097, chunked).Code)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestTimeout(50*time.Millisecond, "/api/v1/runs/:id/wait"))
+
+	// Honors the context but reports a generic failure, like most handlers
+	slow := func(c echo.Context) error {
+		select {
+		case <-c.Request().Context().Done():
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get run")
+		case <-time.After(time.Second):
+			return c.String(http.StatusOK, "done")
+		}
+	}
+	e.GET("/api/v1/runs/:id", slow)
+	e.GET("/api/v1/runs/:id/wait", func(c echo.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		_, hasDeadline := c.Request().Context().Deadline()
+		assert.False(t, hasDeadline)
+		return c.String(http.StatusOK, "done")
+	})
+	e.GET("/api/v1/runs/:id/missing", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "run not found")
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	start := time.Now()
+	rec := serve("/api/v1/runs/r1")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "request timed out"), rec.Body.String())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// Errors before the deadline pass through
+	assert.Equal(t, http.StatusNotFound, serve("/api/v1/runs/r1/missing").Code)
+
+	// Untimed routes run past it
+	assert.Equal(t, http.StatusOK, serve("/api/v1/runs/r1/wait").Code)
+}
+
+func TestRequestTimeout_Disabled(t *testing.T) {
+	e := echo.New()
+	e.Use(RequestTimeout(0))
+	e.GET("/", func(c echo.Context) error {
+		_, hasDeadline := c.Request().Context().Deadline()
+		assert.False(t, hasDeadline)
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -7,6 +7,9 @@ import (
 
 // RegisterArtifactRoutes registers artifact-related routes
 func RegisterArtifactRoutes(e *echo.Group, handler *handlers.ArtifactHandler) {
-	// GET /api/v1/artifacts/:id - Get artifact by ID with content
+	// GET /api/v1/artifacts/:id - Get artifact by ID with content and lineage
 	e.GET("/artifacts/:id", handler.GetArtifact)
+
+	// POST /api/v1/artifacts/batch - Get many artifacts with content in one request
+	e.POST("/artifacts/batch", handler.GetArtifacts)
 }
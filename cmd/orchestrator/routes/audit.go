@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/container"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/handlers"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+)
+
+// RegisterAuditRoutes registers the audit log routes (admin only)
+func RegisterAuditRoutes(e *echo.Echo, c *container.Container) {
+	h := handlers.NewAuditHandler(c.Components, c.AuditService)
+
+	audit := e.Group("/api/v1/audit")
+	audit.Use(middleware.RequireAdmin(c.Components.Config.Admin.Users))
+	{
+		audit.GET("", h.ListAuditLog) // GET /api/v1/audit?owner=alice&tag=main&user=bob&since=...&cursor=...
+	}
+}
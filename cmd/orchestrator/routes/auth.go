@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/container"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/handlers"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+)
+
+// RegisterAuthRoutes registers token issuing routes
+func RegisterAuthRoutes(e *echo.Echo, c *container.Container) {
+	h := handlers.NewAuthHandler(c.Components, c.Tokens)
+
+	authGroup := e.Group("/api/v1/auth")
+	authGroup.Use(middleware.ExtractUsername()) // Extract X-User-ID into context
+	{
+		authGroup.POST("/ws-token", h.IssueWebSocketToken) // POST /api/v1/auth/ws-token
+	}
+}
@@ -1,25 +1,18 @@
 package routes
 
 import (
-	"context"
-	"fmt"
-
 	"github.com/labstack/echo/v4"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/container"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/handlers"
 	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
 	commonmiddleware "github.com/lyzr/orchestrator/common/middleware"
 	_ "github.com/lyzr/orchestrator/common/sdk"
-	"github.com/lyzr/orchestrator/common/logger"
 )
 
 // RegisterRunRoutes registers run and patch routes
 func RegisterRunRoutes(e *echo.Echo, c *container.Container) {
-	// Create CAS client (mock for MVP)
-	casClient := &mockCASClient{logger: c.Components.Logger}
-
 	// Create handlers using services from container
-	runHandler := handlers.NewRunHandler(c.Components, c.Redis, casClient, c.RunService)
+	runHandler := handlers.NewRunHandler(c.Components, c.Redis, c.ResultCAS, c.RunService)
 	artifactHandler := handlers.NewArtifactHandler(c.Components, c.CASService, c.ArtifactService)
 
 	// Placeholder handler for unimplemented routes
@@ -43,9 +36,21 @@ func RegisterRunRoutes(e *echo.Echo, c *container.Container) {
 	{
 		runs.GET("/:id", runHandler.GetRun)                  // GET /api/v1/runs/{run_id}
 		runs.GET("/:id/details", runHandler.GetRunDetails)   // GET /api/v1/runs/{run_id}/details
-		runs.GET("", placeholder.NotImplemented)             // GET /api/v1/runs?status=running (TODO)
+		runs.GET("/:id/events", runHandler.StreamRunEvents)  // GET /api/v1/runs/{run_id}/events (SSE)
+		runs.GET("/:id/wait", runHandler.WaitForRun)         // GET /api/v1/runs/{run_id}/wait?timeout=30s (long-poll)
+		runs.GET("/:id/result", runHandler.GetRunResult)     // GET /api/v1/runs/{run_id}/result
+		runs.GET("/:id/fixture", runHandler.GetRunFixture)   // GET /api/v1/runs/{run_id}/fixture
+		runs.GET("/:id/logs", runHandler.GetRunLogs)         // GET /api/v1/runs/{run_id}/logs?node_id=...&level=warn
+		runs.GET("/:id/state", runHandler.GetRunState, middleware.RequireAdmin(c.Components.Config.Admin.Users)) // GET /api/v1/runs/{run_id}/state (internal, admin only)
+		runs.GET("", runHandler.ListRuns, middleware.ExtractUsernameStrict())                       // GET /api/v1/runs?status=RUNNING&cursor=...
+		runs.POST("/status", runHandler.GetRunStatuses)                                            // POST /api/v1/runs/status (bulk)
 		runs.POST("/:id/cancel", placeholder.NotImplemented) // POST /api/v1/runs/{run_id}/cancel (TODO)
 		runs.POST("/:id/patch", runHandler.PatchRun)         // POST /api/v1/runs/{run_id}/patch
+		runs.POST("/:id/pause", runHandler.PauseRun, middleware.ExtractUsername())                  // POST /api/v1/runs/{run_id}/pause
+		runs.POST("/:id/resume", runHandler.ResumeRun, middleware.ExtractUsername())                // POST /api/v1/runs/{run_id}/resume
+		runs.POST("/:id/retry", runHandler.RetryRun, middleware.ExtractUsername())                  // POST /api/v1/runs/{run_id}/retry
+		runs.POST("/:id/nodes/:node_id/cancel", runHandler.CancelNode, middleware.ExtractUsername()) // POST /api/v1/runs/{run_id}/nodes/{node_id}/cancel
+		runs.POST("/:id/webhook/:node_id", runHandler.CompleteWebhook)                              // POST /api/v1/runs/{run_id}/webhook/{node_id}?token=...
 	}
 
 	// Patch routes (not yet implemented)
@@ -58,28 +63,7 @@ func RegisterRunRoutes(e *echo.Echo, c *container.Container) {
 	// Artifact routes
 	artifacts := e.Group("/api/v1/artifacts")
 	{
-		artifacts.GET("/:id", artifactHandler.GetArtifact) // GET /api/v1/artifacts/{artifact_id}
+		artifacts.GET("/:id", artifactHandler.GetArtifact)     // GET /api/v1/artifacts/{artifact_id}
+		artifacts.POST("/batch", artifactHandler.GetArtifacts) // POST /api/v1/artifacts/batch (bulk)
 	}
 }
-
-// mockCASClient is a placeholder CAS client for MVP
-type mockCASClient struct {
-	logger *logger.Logger
-}
-
-func (m *mockCASClient) Put(ctx context.Context, data []byte, contentType string) (string, error) {
-	casID := fmt.Sprintf("cas://mock/%d", len(data))
-	m.logger.Debug("mock CAS Put", "cas_id", casID, "size", len(data))
-	return casID, nil
-}
-
-func (m *mockCASClient) Get(ctx context.Context, casID string) (interface{}, error) {
-	m.logger.Debug("mock CAS Get", "cas_id", casID)
-	return []byte("{}"), nil
-}
-
-func (m *mockCASClient) Store(ctx context.Context, data interface{}) (string, error) {
-	casID := fmt.Sprintf("cas://mock/store")
-	m.logger.Debug("mock CAS Store", "cas_id", casID)
-	return casID, nil
-}
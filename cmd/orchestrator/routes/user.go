@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/container"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/handlers"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/middleware"
+)
+
+// RegisterUserRoutes registers the user data routes (admin only)
+func RegisterUserRoutes(e *echo.Echo, c *container.Container) {
+	h := handlers.NewUserHandler(c.Components, c.UserDataService)
+
+	users := e.Group("/api/v1/users")
+	users.Use(middleware.RequireAdmin(c.Components.Config.Admin.Users))
+	{
+		users.DELETE("/:username/workflows", h.PurgeUserWorkflows) // DELETE /api/v1/users/alice/workflows
+	}
+}
@@ -18,9 +18,24 @@ func RegisterWorkflowRoutes(e *echo.Echo, c *container.Container) {
 	{
 		wf.GET("/:tag", h.GetWorkflow)                       // GET /api/v1/workflows/main
 		wf.GET("/:tag/versions/:seq", h.GetWorkflowVersion) // GET /api/v1/workflows/main/versions/3
+		wf.GET("/:tag/diff", h.DiffWorkflowVersions)         // GET /api/v1/workflows/main/diff?from=2&to=5
+		wf.GET("/:tag/estimate", h.EstimateWorkflow)         // GET /api/v1/workflows/main/estimate
+		wf.GET("/:tag/history", h.GetWorkflowHistory)        // GET /api/v1/workflows/main/history?limit=50&cursor=...
 		wf.POST("", h.CreateWorkflow)                        // POST /api/v1/workflows
+		wf.POST("/from-template", h.InstantiateTemplate)     // POST /api/v1/workflows/from-template
 		wf.PATCH("/:tag/patch", h.PatchWorkflow)             // PATCH /api/v1/workflows/main/patch
+		wf.POST("/:tag/rollback", h.RollbackWorkflow)        // POST /api/v1/workflows/main/rollback
+		wf.POST("/:tag/clone", h.CloneWorkflow)              // POST /api/v1/workflows/main/clone
+		wf.POST("/:tag/undo", h.UndoWorkflow)                // POST /api/v1/workflows/main/undo
+		wf.POST("/:tag/redo", h.RedoWorkflow)                // POST /api/v1/workflows/main/redo
 		wf.GET("", h.ListWorkflows)                          // GET /api/v1/workflows
 		wf.DELETE("/:tag", h.DeleteWorkflow)                 // DELETE /api/v1/workflows/main
 	}
+
+	// Template catalog (global workflows marked as templates)
+	tpl := e.Group("/api/v1/templates")
+	tpl.Use(middleware.ExtractUsername())
+	{
+		tpl.GET("", h.ListTemplates) // GET /api/v1/templates
+	}
 }
@@ -2,23 +2,38 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lyzr/orchestrator/common/models"
-	"github.com/lyzr/orchestrator/common/repository"
 	"github.com/lyzr/orchestrator/common/logger"
 )
 
+// artifactStore is the subset of ArtifactRepository used by ArtifactService
+type artifactStore interface {
+	Create(ctx context.Context, artifact *models.Artifact) error
+	GetByID(ctx context.Context, artifactID uuid.UUID) (*models.Artifact, error)
+	GetByVersionHash(ctx context.Context, versionHash string) (*models.Artifact, error)
+	GetByPlanHash(ctx context.Context, planHash string) (*models.Artifact, error)
+	ListByKind(ctx context.Context, kind string, limit int) ([]*models.Artifact, error)
+	GetPatchChain(ctx context.Context, headID uuid.UUID) ([]*models.Artifact, error)
+	GetByIDs(ctx context.Context, artifactIDs []uuid.UUID) (map[uuid.UUID]*models.Artifact, error)
+	GetPatchChains(ctx context.Context, headIDs []uuid.UUID) (map[uuid.UUID][]*models.Artifact, error)
+	InsertPatchChain(ctx context.Context, headID uuid.UUID, memberIDs []uuid.UUID) error
+	DeletePatch(ctx context.Context, artifactID uuid.UUID) error
+}
+
 // ArtifactService handles artifact catalog operations
 type ArtifactService struct {
-	repo *repository.ArtifactRepository
+	repo artifactStore
 	log  *logger.Logger
 }
 
 // NewArtifactService creates a new artifact service
-func NewArtifactService(repo *repository.ArtifactRepository, log *logger.Logger) *ArtifactService {
+func NewArtifactService(repo artifactStore, log *logger.Logger) *ArtifactService {
 	return &ArtifactService{
 		repo: repo,
 		log:  log,
@@ -54,6 +69,43 @@ func (s *ArtifactService) CreateDAGVersion(ctx context.Context, casID, versionHa
 	return artifact.ArtifactID, nil
 }
 
+// CreateRollbackVersion creates a DAG version artifact holding a previous version of a tag
+// The meta records which artifact the tag pointed at and which seq was restored,
+// so the rollback itself shows up in history like any other version.
+func (s *ArtifactService) CreateRollbackVersion(ctx context.Context, casID, name, createdBy string, nodesCount, edgesCount int, rolledBackFrom uuid.UUID, seq int) (uuid.UUID, error) {
+	versionHash := casID // For DAG versions, version_hash = cas_id
+	artifact := &models.Artifact{
+		ArtifactID:  uuid.New(),
+		Kind:        models.KindDAGVersion,
+		CasID:       casID,
+		Name:        &name,
+		VersionHash: &versionHash,
+		Depth:       intPtr(0),
+		NodesCount:  &nodesCount,
+		EdgesCount:  &edgesCount,
+		Meta: map[string]interface{}{
+			"rolled_back_at":   time.Now().Format(time.RFC3339),
+			"rolled_back_from": rolledBackFrom.String(),
+			"rollback_seq":     seq,
+		},
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repo.Create(ctx, artifact); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create rollback version artifact: %w", err)
+	}
+
+	s.log.Info("created rollback version artifact",
+		"artifact_id", artifact.ArtifactID,
+		"cas_id", casID,
+		"rolled_back_from", rolledBackFrom,
+		"seq", seq,
+	)
+
+	return artifact.ArtifactID, nil
+}
+
 // CreatePatchSet creates a patch set artifact
 func (s *ArtifactService) CreatePatchSet(ctx context.Context, casID string, baseVersion uuid.UUID, depth, opCount int, createdBy string) (uuid.UUID, error) {
 	artifact := &models.Artifact{
@@ -83,8 +135,29 @@ func (s *ArtifactService) CreatePatchSet(ctx context.Context, casID string, base
 
 // CreatePatch creates a patch artifact with proper chain linking
 // Note: previousPatchSet is used for metadata/logging but not stored in the artifact
-// The patch chain is reconstructed via the patch_chain_member table
+// The patch chain is reconstructed via the patch_chain_member table. depth is
+// the patch's position in its chain and must follow the previous patch set's.
 func (s *ArtifactService) CreatePatch(ctx context.Context, casID string, baseVersion uuid.UUID, previousPatchSet *uuid.UUID, depth, opCount int, createdBy string) (uuid.UUID, error) {
+	// Build patch chain: get all previous patches + add this new one
+	var patchChain []uuid.UUID
+	if previousPatchSet != nil {
+		// Get existing patch chain from previous head
+		previousPatches, err := s.repo.GetPatchChain(ctx, *previousPatchSet)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to get previous patch chain: %w", err)
+		}
+		if err := orderPatchChain(*previousPatchSet, previousPatches); err != nil {
+			return uuid.Nil, err
+		}
+		// Add all previous patch IDs
+		for _, p := range previousPatches {
+			patchChain = append(patchChain, p.ArtifactID)
+		}
+	}
+	if depth != len(patchChain)+1 {
+		return uuid.Nil, fmt.Errorf("%w: new patch has depth %d, previous chain has %d patches", ErrPatchChainInconsistent, depth, len(patchChain))
+	}
+
 	artifact := &models.Artifact{
 		ArtifactID:  uuid.New(),
 		Kind:        models.KindPatchSet,
@@ -101,19 +174,6 @@ func (s *ArtifactService) CreatePatch(ctx context.Context, casID string, baseVer
 		return uuid.Nil, fmt.Errorf("failed to create patch artifact: %w", err)
 	}
 
-	// Build patch chain: get all previous patches + add this new one
-	var patchChain []uuid.UUID
-	if previousPatchSet != nil {
-		// Get existing patch chain from previous head
-		previousPatches, err := s.repo.GetPatchChain(ctx, *previousPatchSet)
-		if err != nil {
-			return uuid.Nil, fmt.Errorf("failed to get previous patch chain: %w", err)
-		}
-		// Add all previous patch IDs
-		for _, p := range previousPatches {
-			patchChain = append(patchChain, p.ArtifactID)
-		}
-	}
 	// Add new patch as the last member
 	patchChain = append(patchChain, artifact.ArtifactID)
 
@@ -133,6 +193,16 @@ func (s *ArtifactService) CreatePatch(ctx context.Context, casID string, baseVer
 	return artifact.ArtifactID, nil
 }
 
+// DeletePatch deletes a patch artifact created by CreatePatch that no tag ever pointed at
+func (s *ArtifactService) DeletePatch(ctx context.Context, artifactID uuid.UUID) error {
+	if err := s.repo.DeletePatch(ctx, artifactID); err != nil {
+		return fmt.Errorf("failed to delete patch artifact: %w", err)
+	}
+
+	s.log.Info("deleted patch artifact", "artifact_id", artifactID)
+	return nil
+}
+
 // CreateRunSnapshot creates a run snapshot artifact
 func (s *ArtifactService) CreateRunSnapshot(ctx context.Context, casID, planHash, versionHash string, nodesCount, edgesCount int, createdBy string) (uuid.UUID, error) {
 	artifact := &models.Artifact{
@@ -206,7 +276,63 @@ func (s *ArtifactService) GetPatchChain(ctx context.Context, headID uuid.UUID) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to get patch chain: %w", err)
 	}
+	if err := orderPatchChain(headID, patches); err != nil {
+		return nil, err
+	}
 
 	s.log.Info("retrieved patch chain", "head_id", headID, "patches", len(patches))
 	return patches, nil
 }
+
+// ErrPatchChainInconsistent is returned for a patch chain whose depths aren't 1..N
+var ErrPatchChainInconsistent = errors.New("patch chain is inconsistent")
+
+// orderPatchChain sorts a patch chain into application order, in place
+// A patch's depth, set when it is created, is its authoritative position in the
+// chain; the order rows come back in is not trusted. Depths must run 1..N
+// without gaps or duplicates, or applying the chain would corrupt the workflow.
+func orderPatchChain(headID uuid.UUID, patches []*models.Artifact) error {
+	for _, patch := range patches {
+		if patch.Depth == nil {
+			return fmt.Errorf("%w: head %s: patch %s has no depth", ErrPatchChainInconsistent, headID, patch.ArtifactID)
+		}
+	}
+
+	sort.SliceStable(patches, func(i, j int) bool { return *patches[i].Depth < *patches[j].Depth })
+
+	for i, patch := range patches {
+		if *patch.Depth != i+1 {
+			return fmt.Errorf("%w: head %s: patch %s at position %d has depth %d", ErrPatchChainInconsistent, headID, patch.ArtifactID, i+1, *patch.Depth)
+		}
+	}
+	return nil
+}
+
+// MaxArtifactBatch caps how many artifacts a single bulk lookup may ask for
+const MaxArtifactBatch = 100
+
+// GetByIDs retrieves several artifacts in one query
+func (s *ArtifactService) GetByIDs(ctx context.Context, artifactIDs []uuid.UUID) (map[uuid.UUID]*models.Artifact, error) {
+	artifacts, err := s.repo.GetByIDs(ctx, artifactIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifacts: %w", err)
+	}
+
+	return artifacts, nil
+}
+
+// GetPatchChains retrieves the patch chains of several heads in one query
+func (s *ArtifactService) GetPatchChains(ctx context.Context, headIDs []uuid.UUID) (map[uuid.UUID][]*models.Artifact, error) {
+	chains, err := s.repo.GetPatchChains(ctx, headIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patch chains: %w", err)
+	}
+	for headID, chain := range chains {
+		if err := orderPatchChain(headID, chain); err != nil {
+			return nil, err
+		}
+	}
+
+	s.log.Info("retrieved patch chains", "heads", len(headIDs))
+	return chains, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// ArtifactLineage is where an artifact comes from
+type ArtifactLineage struct {
+	Base          *models.Artifact   `json:"base,omitempty"`           // patch_set: the DAG version its chain applies to
+	PatchChain    []*models.Artifact `json:"patch_chain,omitempty"`    // patch_set: the chain up to and including it, oldest first
+	CompactedFrom *models.Artifact   `json:"compacted_from,omitempty"` // dag_version: the patch head compacted into it
+}
+
+// GetLineage returns the artifacts an artifact derives from
+// A patch_set derives from its base version through its patch chain; a
+// compacted base from the patch head it was compacted from. Other artifacts
+// (plain DAG versions, snapshots) have an empty lineage.
+func (s *ArtifactService) GetLineage(ctx context.Context, artifact *models.Artifact) (*ArtifactLineage, error) {
+	lineage := &ArtifactLineage{}
+
+	switch {
+	case artifact.IsPatchSet():
+		chain, err := s.repo.GetPatchChain(ctx, artifact.ArtifactID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get patch chain: %w", err)
+		}
+		if err := orderPatchChain(artifact.ArtifactID, chain); err != nil {
+			return nil, err
+		}
+		lineage.PatchChain = chain
+
+		if artifact.BaseVersion != nil {
+			base, err := s.repo.GetByID(ctx, *artifact.BaseVersion)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get base version %s: %w", *artifact.BaseVersion, err)
+			}
+			lineage.Base = base
+		}
+
+	case artifact.IsDAGVersion() && artifact.CompactedFromID != nil:
+		compactedFrom, err := s.repo.GetByID(ctx, *artifact.CompactedFromID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get compacted patch %s: %w", *artifact.CompactedFromID, err)
+		}
+		lineage.CompactedFrom = compactedFrom
+	}
+
+	return lineage, nil
+}
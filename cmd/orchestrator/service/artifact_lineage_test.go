@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+func TestArtifactService_GetLineage(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 3)
+	artifacts := NewArtifactService(f.artifacts, logger.New("error", "text"))
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	patch := f.artifacts.artifacts[head.TargetID]
+	require.Equal(t, models.KindPatchSet, patch.Kind)
+
+	t.Run("patch set", func(t *testing.T) {
+		lineage, err := artifacts.GetLineage(ctx, patch)
+		require.NoError(t, err)
+
+		// Base version, then the chain in order ending at the patch itself
+		require.NotNil(t, lineage.Base)
+		assert.Equal(t, *patch.BaseVersion, lineage.Base.ArtifactID)
+		assert.Equal(t, models.KindDAGVersion, lineage.Base.Kind)
+
+		require.Len(t, lineage.PatchChain, 3)
+		for i, member := range lineage.PatchChain {
+			assert.Equal(t, models.KindPatchSet, member.Kind)
+			assert.Equal(t, i+1, *member.Depth)
+		}
+		assert.Equal(t, patch.ArtifactID, lineage.PatchChain[2].ArtifactID)
+		assert.Nil(t, lineage.CompactedFrom)
+	})
+
+	t.Run("compacted base", func(t *testing.T) {
+		result, err := f.compaction.CompactWorkflow(ctx, patch.ArtifactID, "alice")
+		require.NoError(t, err)
+		base := f.artifacts.artifacts[result.NewBaseID]
+
+		lineage, err := artifacts.GetLineage(ctx, base)
+		require.NoError(t, err)
+		require.NotNil(t, lineage.CompactedFrom)
+		assert.Equal(t, patch.ArtifactID, lineage.CompactedFrom.ArtifactID)
+		assert.Equal(t, patch.ArtifactID, *base.CompactedFromID)
+		assert.Nil(t, lineage.Base)
+		assert.Empty(t, lineage.PatchChain)
+	})
+
+	t.Run("plain version", func(t *testing.T) {
+		lineage, err := artifacts.GetLineage(ctx, f.artifacts.artifacts[*patch.BaseVersion])
+		require.NoError(t, err)
+		assert.Equal(t, &ArtifactLineage{}, lineage)
+	})
+
+	t.Run("missing base", func(t *testing.T) {
+		missing := uuid.New()
+		_, err := artifacts.GetLineage(ctx, &models.Artifact{ArtifactID: uuid.New(), Kind: models.KindPatchSet, BaseVersion: &missing})
+		assert.ErrorContains(t, err, missing.String())
+	})
+}
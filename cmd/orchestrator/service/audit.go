@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+// auditStore is the subset of AuditRepository used by AuditService
+type auditStore interface {
+	Append(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, opts repository.AuditListOptions) (*repository.AuditPage, error)
+}
+
+// AuditService records workflow, tag and run mutations in the audit log
+// Recording is best-effort: the mutation has already happened by then, so a
+// failed append is logged instead of failing the request. A nil *AuditService
+// records nothing, so services work without one.
+type AuditService struct {
+	repo auditStore
+	log  *logger.Logger
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(repo auditStore, log *logger.Logger) *AuditService {
+	return &AuditService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// WithAudit records workflow mutations in the audit log
+func (s *WorkflowServiceV2) WithAudit(audit *AuditService) *WorkflowServiceV2 {
+	s.audit = audit
+	return s
+}
+
+// WithAudit records tag deletes, undos and redos in the audit log
+func (s *TagService) WithAudit(audit *AuditService) *TagService {
+	s.audit = audit
+	return s
+}
+
+// WithAudit records run patches in the audit log
+func (s *RunPatchService) WithAudit(audit *AuditService) *RunPatchService {
+	s.audit = audit
+	return s
+}
+
+// WithAudit records user purges in the audit log
+func (s *UserDataService) WithAudit(audit *AuditService) *UserDataService {
+	s.audit = audit
+	return s
+}
+
+// AuditTarget is what an audited action was done to
+type AuditTarget struct {
+	Kind     string // One of the models.AuditTarget* values
+	ID       string
+	TagOwner string // Workflow tag involved, if any
+	TagName  string
+}
+
+// tagTarget targets a workflow tag
+func tagTarget(owner, tagName string) AuditTarget {
+	return AuditTarget{Kind: models.AuditTargetTag, ID: owner + "/" + tagName, TagOwner: owner, TagName: tagName}
+}
+
+// artifactTarget targets an artifact created for a workflow tag
+func artifactTarget(artifactID uuid.UUID, owner, tagName string) AuditTarget {
+	return AuditTarget{Kind: models.AuditTargetArtifact, ID: artifactID.String(), TagOwner: owner, TagName: tagName}
+}
+
+// runTarget targets a run, of a workflow tag if known
+func runTarget(runID string, owner, tagName string) AuditTarget {
+	return AuditTarget{Kind: models.AuditTargetRun, ID: runID, TagOwner: owner, TagName: tagName}
+}
+
+// Record appends an entry to the audit log
+// The request ID is taken from ctx, if the request had one.
+func (s *AuditService) Record(ctx context.Context, actor, action string, target AuditTarget, details map[string]interface{}) {
+	if s == nil {
+		return
+	}
+
+	entry := &models.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		TargetKind: target.Kind,
+		TargetID:   target.ID,
+		Details:    details,
+		CreatedAt:  time.Now(),
+	}
+	if target.TagName != "" {
+		entry.TagOwner = &target.TagOwner
+		entry.TagName = &target.TagName
+	}
+	if requestID, ok := clients.GetRequestID(ctx); ok {
+		entry.RequestID = &requestID
+	}
+
+	if err := s.repo.Append(ctx, entry); err != nil {
+		s.log.Error("failed to record audit entry",
+			"actor", actor,
+			"action", action,
+			"target_kind", target.Kind,
+			"target_id", target.ID,
+			"error", err)
+	}
+}
+
+// List returns a page of the audit log, newest first
+func (s *AuditService) List(ctx context.Context, opts repository.AuditListOptions) (*repository.AuditPage, error) {
+	page, err := s.repo.List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	return page, nil
+}
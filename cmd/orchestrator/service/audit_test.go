@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+func TestWorkflowService_RecordsAuditEntries(t *testing.T) {
+	ctx := clients.WithRequestID(context.Background(), "req-1")
+	log := logger.New("error", "text")
+	store := &fakeAuditStore{}
+	audit := NewAuditService(store, log)
+	tags := NewTagService(newFakeTagStore(), log).WithAudit(audit)
+	workflows := NewWorkflowServiceV2(
+		newTestCASService(newFakeCASStore(), "none", 0),
+		NewArtifactService(newFakeArtifactStore(), log),
+		tags,
+		NewMaterializerService(log),
+		log,
+	).WithAudit(audit)
+
+	created, err := workflows.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: "alice",
+		TagName:  "main",
+		Workflow: map[string]interface{}{
+			"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "function"}},
+			"edges": []interface{}{},
+		},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	patched, err := workflows.CreatePatch(context.Background(), &CreatePatchRequest{
+		Username: "alice",
+		TagName:  "main",
+		Operations: []map[string]interface{}{
+			{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "b", "type": "function"}},
+		},
+		CreatedBy: "bob",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, store.entries, 2)
+	create, patch := store.entries[0], store.entries[1]
+
+	assert.Equal(t, "alice", create.Actor)
+	assert.Equal(t, models.AuditActionWorkflowCreate, create.Action)
+	assert.Equal(t, models.AuditTargetArtifact, create.TargetKind)
+	assert.Equal(t, created.ArtifactID.String(), create.TargetID)
+	assert.Equal(t, "alice", *create.TagOwner)
+	assert.Equal(t, "main", *create.TagName)
+	require.NotNil(t, create.RequestID)
+	assert.Equal(t, "req-1", *create.RequestID)
+
+	assert.Equal(t, "bob", patch.Actor)
+	assert.Equal(t, models.AuditActionWorkflowPatch, patch.Action)
+	assert.Equal(t, models.AuditTargetArtifact, patch.TargetKind)
+	assert.Equal(t, patched.ArtifactID.String(), patch.TargetID)
+	assert.Equal(t, "alice", *patch.TagOwner)
+	assert.Equal(t, "main", *patch.TagName)
+	assert.Equal(t, 1, patch.Details["op_count"])
+	assert.Nil(t, patch.RequestID)
+
+	t.Run("failed mutations are not recorded", func(t *testing.T) {
+		_, err := workflows.CreatePatch(ctx, &CreatePatchRequest{
+			Username:   "alice",
+			TagName:    "missing",
+			Operations: []map[string]interface{}{{"op": "remove", "path": "/nodes/0"}},
+			CreatedBy:  "alice",
+		})
+		require.Error(t, err)
+		assert.Len(t, store.entries, 2)
+	})
+
+	t.Run("tag deletes", func(t *testing.T) {
+		require.NoError(t, tags.DeleteTag(ctx, "alice", "main"))
+
+		page, err := audit.List(ctx, repository.AuditListOptions{TagOwner: "alice", TagName: "main"})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 3)
+		assert.Equal(t, models.AuditActionTagDelete, page.Entries[0].Action)
+		assert.Equal(t, models.AuditTargetTag, page.Entries[0].TargetKind)
+		assert.Equal(t, "alice/main", page.Entries[0].TargetID)
+	})
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+// autoCompactionActor is recorded as created_by / moved_by for automatic compactions
+const autoCompactionActor = "system:auto-compaction"
+
+// AutoCompactor periodically compacts tags whose patch chain reached the depth threshold
+// Each tag is compacted under a per-tag Redis lock so concurrent orchestrator
+// replicas don't compact the same chain twice; a patch landing mid-compaction
+// is detected by MigrateTagToCompactedBase and the tag is left alone.
+type AutoCompactor struct {
+	compaction *CompactionService
+	redis      *rediscommon.Client
+	cfg        config.CompactionConfig
+	log        *logger.Logger
+}
+
+// CompactedTag describes one tag handled by a compaction pass
+type CompactedTag struct {
+	Username  string    `json:"username"`
+	TagName   string    `json:"tag_name"`
+	PatchID   uuid.UUID `json:"patch_id"`
+	Depth     int       `json:"depth"`
+	NewBaseID uuid.UUID `json:"new_base_id,omitempty"` // Zero in dry-run mode
+}
+
+// AutoCompactionReport summarizes a single compaction pass
+type AutoCompactionReport struct {
+	Candidates int            `json:"candidates"`
+	DryRun     bool           `json:"dry_run"`
+	Compacted  []CompactedTag `json:"compacted"`
+	Skipped    int            `json:"skipped"` // Locked elsewhere or moved by a concurrent patch
+}
+
+// NewAutoCompactor creates a new background compactor
+func NewAutoCompactor(compaction *CompactionService, redisClient *rediscommon.Client, cfg config.CompactionConfig, log *logger.Logger) *AutoCompactor {
+	return &AutoCompactor{
+		compaction: compaction,
+		redis:      redisClient,
+		cfg:        cfg,
+		log:        log,
+	}
+}
+
+// Run compacts on every interval until ctx is cancelled
+func (a *AutoCompactor) Run(ctx context.Context) {
+	a.log.Info("auto compaction started",
+		"interval", a.cfg.Interval,
+		"depth_threshold", a.cfg.DepthThreshold,
+		"dry_run", a.cfg.DryRun,
+	)
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.log.Info("auto compaction stopped")
+			return
+		case <-ticker.C:
+			if _, err := a.RunOnce(ctx); err != nil {
+				a.log.Error("auto compaction pass failed", "error", err)
+			}
+		}
+	}
+}
+
+// RunOnce performs a single compaction pass over all candidates
+func (a *AutoCompactor) RunOnce(ctx context.Context) (*AutoCompactionReport, error) {
+	// 1. Find patches at or above the threshold (deepest first)
+	stats, err := a.compaction.GetCompactionStats(ctx, a.cfg.DepthThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AutoCompactionReport{
+		Candidates: stats.CandidatePatches,
+		DryRun:     a.cfg.DryRun,
+		Compacted:  []CompactedTag{},
+	}
+
+	for _, candidate := range stats.Candidates {
+		if candidate.Depth == nil {
+			continue
+		}
+
+		// 2. Only chain heads that a tag points at are worth compacting;
+		// intermediate patches of the same chain are also candidates
+		tags, err := a.compaction.ListTagsAtPatch(ctx, candidate.ArtifactID)
+		if err != nil {
+			return report, err
+		}
+
+		for _, tag := range tags {
+			entry := CompactedTag{
+				Username: tag.Username,
+				TagName:  tag.TagName,
+				PatchID:  candidate.ArtifactID,
+				Depth:    *candidate.Depth,
+			}
+
+			if a.cfg.DryRun {
+				a.log.Info("auto compaction dry run: would compact tag",
+					"username", tag.Username, "tag", tag.TagName, "patch_id", candidate.ArtifactID, "depth", entry.Depth)
+				report.Compacted = append(report.Compacted, entry)
+				continue
+			}
+
+			// 3. Compact and migrate under the per-tag lock
+			newBaseID, err := a.compactTag(ctx, tag, candidate)
+			if err != nil {
+				a.log.Error("auto compaction failed for tag",
+					"username", tag.Username, "tag", tag.TagName, "patch_id", candidate.ArtifactID, "error", err)
+				report.Skipped++
+				continue
+			}
+			if newBaseID == uuid.Nil {
+				report.Skipped++
+				continue
+			}
+
+			entry.NewBaseID = newBaseID
+			report.Compacted = append(report.Compacted, entry)
+		}
+	}
+
+	a.log.Info("auto compaction pass complete",
+		"candidates", report.Candidates,
+		"compacted", len(report.Compacted),
+		"skipped", report.Skipped,
+		"dry_run", report.DryRun,
+	)
+
+	return report, nil
+}
+
+// compactTag compacts a tag's chain and moves the tag to the new base
+// Returns uuid.Nil (and no error) when the tag was skipped.
+func (a *AutoCompactor) compactTag(ctx context.Context, tag *models.Tag, patch *models.Artifact) (uuid.UUID, error) {
+	lockKey := fmt.Sprintf("compaction:lock:%s:%s", tag.Username, tag.TagName)
+
+	lock, err := a.redis.AcquireLock(ctx, lockKey, a.cfg.LockTTL)
+	if errors.Is(err, rediscommon.ErrLockNotAcquired) {
+		a.log.Info("tag is being compacted elsewhere, skipping", "username", tag.Username, "tag", tag.TagName)
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to acquire compaction lock: %w", err)
+	}
+	defer a.releaseLock(lock)
+
+	// Idempotent and resumable: a base compacted by an earlier pass is reused,
+	// and an interrupted pass continues from its checkpoint
+	result, err := a.compaction.CompactWorkflow(ctx, patch.ArtifactID, autoCompactionActor)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	newBaseID := result.NewBaseID
+
+	err = a.compaction.MigrateTagToCompactedBase(ctx, tag.Username, tag.TagName, newBaseID, autoCompactionActor)
+	if errors.Is(err, ErrTagMoved) {
+		// A patch landed on the old chain; the next pass will pick up the new head
+		a.log.Info("tag moved during compaction, not migrating", "username", tag.Username, "tag", tag.TagName)
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return newBaseID, nil
+}
+
+// releaseLock releases the per-tag lock, even if the pass was cancelled
+func (a *AutoCompactor) releaseLock(lock *rediscommon.Lock) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := lock.Release(ctx); err != nil {
+		// ErrLockNotHeld: the TTL ran out mid-compaction; the CAS in the migration kept it safe
+		a.log.Warn("failed to release compaction lock", "key", lock.Key(), "error", err)
+	}
+}
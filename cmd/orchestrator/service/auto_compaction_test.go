@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+type autoCompactionFixture struct {
+	workflows  *WorkflowServiceV2
+	artifacts  *fakeArtifactStore
+	tags       *fakeTagStore
+	blobs      *fakeCASStore
+	compaction *CompactionService
+	redis      *miniredis.Miniredis
+	rdb        redis.UniversalClient
+}
+
+// newAutoCompactionFixture creates alice/main with a patch chain of the given depth
+func newAutoCompactionFixture(t *testing.T, depth int) *autoCompactionFixture {
+	t.Helper()
+	ctx := context.Background()
+	log := logger.New("error", "text")
+
+	f := &autoCompactionFixture{
+		artifacts: newFakeArtifactStore(),
+		tags:      newFakeTagStore(),
+		blobs:     newFakeCASStore(),
+		redis:     miniredis.RunT(t),
+	}
+	f.rdb = redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+	t.Cleanup(func() { f.rdb.Close() })
+
+	cas := newTestCASService(f.blobs, "none", 0)
+	materializer := NewMaterializerService(log)
+	f.workflows = NewWorkflowServiceV2(cas, NewArtifactService(f.artifacts, log), NewTagService(f.tags, log), materializer, log)
+	f.compaction = NewCompactionService(f.artifacts, nil, f.tags, NewTagService(f.tags, log), cas, materializer, rediscommon.NewClient(f.rdb, log), log)
+
+	_, err := f.workflows.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username:  "alice",
+		TagName:   "main",
+		Workflow:  map[string]interface{}{"nodes": []interface{}{}, "edges": []interface{}{}},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < depth; i++ {
+		_, err := f.workflows.CreatePatch(ctx, &CreatePatchRequest{
+			Username: "alice",
+			TagName:  "main",
+			Operations: []map[string]interface{}{
+				{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": fmt.Sprintf("n%d", i)}},
+			},
+			CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+	}
+
+	return f
+}
+
+func (f *autoCompactionFixture) compactor(dryRun bool) *AutoCompactor {
+	log := logger.New("error", "text")
+	return NewAutoCompactor(f.compaction, rediscommon.NewClient(f.rdb, log), config.CompactionConfig{
+		Enabled:        true,
+		Interval:       time.Minute,
+		DepthThreshold: 20,
+		DryRun:         dryRun,
+		LockTTL:        time.Minute,
+	}, log)
+}
+
+func (f *autoCompactionFixture) materialize(t *testing.T) map[string]interface{} {
+	t.Helper()
+	ctx := context.Background()
+	components, err := f.workflows.GetWorkflowComponents(ctx, "alice", "main")
+	require.NoError(t, err)
+	workflow, err := f.workflows.materializer.Materialize(ctx, components)
+	require.NoError(t, err)
+	return workflow
+}
+
+func TestAutoCompactor_CompactsDeepChain(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 25)
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	before := f.materialize(t)
+
+	report, err := f.compactor(false).RunOnce(ctx)
+	require.NoError(t, err)
+
+	// Patches at depth 20..25 are candidates, but only the tagged head is compacted
+	assert.Equal(t, 6, report.Candidates)
+	require.Len(t, report.Compacted, 1)
+	assert.Equal(t, head.TargetID, report.Compacted[0].PatchID)
+	assert.Equal(t, 25, report.Compacted[0].Depth)
+
+	// Tag migrated to the new base, content unchanged
+	tag, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	assert.Equal(t, report.Compacted[0].NewBaseID, tag.TargetID)
+	assert.Equal(t, models.KindDAGVersion, tag.TargetKind)
+
+	base := f.artifacts.artifacts[tag.TargetID]
+	require.NotNil(t, base.CompactedFromID)
+	assert.Equal(t, head.TargetID, *base.CompactedFromID)
+	assert.Equal(t, before, f.materialize(t))
+
+	// Lock released; a second pass has nothing left to do
+	assert.False(t, f.redis.Exists("compaction:lock:alice:main"))
+	report, err = f.compactor(false).RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, report.Compacted)
+}
+
+func TestAutoCompactor_DryRunAndLocking(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 25)
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+
+	// Dry run reports the tag but changes nothing
+	report, err := f.compactor(true).RunOnce(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Compacted, 1)
+	tag, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	assert.Equal(t, head.TargetID, tag.TargetID)
+
+	// A tag locked by another replica is skipped
+	require.NoError(t, f.redis.Set("compaction:lock:alice:main", "other"))
+	report, err = f.compactor(false).RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, report.Compacted)
+	assert.Equal(t, 1, report.Skipped)
+	tag, err = f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	assert.Equal(t, head.TargetID, tag.TargetID)
+}
+
+func TestCompactionService_MigrateRejectsMovedTag(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 3)
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	result, err := f.compaction.CompactWorkflow(ctx, head.TargetID, "test")
+	require.NoError(t, err)
+
+	// A patch lands after compaction started
+	_, err = f.workflows.CreatePatch(ctx, &CreatePatchRequest{
+		Username:   "alice",
+		TagName:    "main",
+		Operations: []map[string]interface{}{{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "late"}}},
+		CreatedBy:  "alice",
+	})
+	require.NoError(t, err)
+
+	err = f.compaction.MigrateTagToCompactedBase(ctx, "alice", "main", result.NewBaseID, "test")
+	assert.ErrorIs(t, err, ErrTagMoved)
+}
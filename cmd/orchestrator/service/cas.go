@@ -6,32 +6,53 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/lyzr/orchestrator/common/models"
-	"github.com/lyzr/orchestrator/common/repository"
+	"github.com/lyzr/orchestrator/common/bulk"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compression"
+	"github.com/lyzr/orchestrator/common/config"
 	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/lyzr/orchestrator/common/models"
 )
 
+// casBlobStore is the subset of CASBlobRepository used by CASService
+type casBlobStore interface {
+	Create(ctx context.Context, blob *models.CASBlob) error
+	GetByID(ctx context.Context, casID string) (*models.CASBlob, error)
+	Exists(ctx context.Context, casID string) (bool, error)
+	GetContentByID(ctx context.Context, casID string) (*models.CASBlob, error)
+	GetContentBulk(ctx context.Context, casIDs []string) (map[string]*models.CASBlob, error)
+}
+
 // CASService handles content-addressed storage operations
+// Blobs larger than the configured threshold may be stored compressed; CAS IDs
+// are always computed over the uncompressed content so dedup is unaffected.
+// With a signing key configured, blobs are signed like the execution CAS's.
 type CASService struct {
-	repo *repository.CASBlobRepository
-	log  *logger.Logger
+	repo        casBlobStore
+	log         *logger.Logger
+	compression config.CASConfig
+	signer      clients.BlobSigner
 }
 
 // NewCASService creates a new CAS service
-func NewCASService(repo *repository.CASBlobRepository, log *logger.Logger) *CASService {
+func NewCASService(repo casBlobStore, log *logger.Logger, cfg config.CASConfig) *CASService {
 	return &CASService{
-		repo: repo,
-		log:  log,
+		repo:        repo,
+		log:         log,
+		compression: cfg,
+		signer:      clients.NewBlobSigner(cfg.SigningKey),
 	}
 }
 
 // StoreContent stores content and returns its CAS ID (hash)
 func (s *CASService) StoreContent(ctx context.Context, content []byte, mediaType string) (string, error) {
-	// Compute SHA256 hash
-	hash := sha256.Sum256(content)
-	casID := fmt.Sprintf("sha256:%x", hash)
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "put")
 
-	// Check if content already exists (deduplication)
+	// 1. Compute SHA256 hash of the uncompressed content
+	casID := s.ComputeHash(content)
+
+	// 2. Check if content already exists (deduplication)
 	exists, err := s.repo.Exists(ctx, casID)
 	if err != nil {
 		return "", fmt.Errorf("failed to check existence: %w", err)
@@ -42,69 +63,153 @@ func (s *CASService) StoreContent(ctx context.Context, content []byte, mediaType
 		return casID, nil
 	}
 
-	// Store new content
+	// 3. Encode for storage (compressed if enabled and worthwhile)
+	stored, encoding, err := s.encode(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode content: %w", err)
+	}
+
+	// 4. Store new content
 	blob := &models.CASBlob{
-		CasID:      casID,
-		MediaType:  mediaType,
-		SizeBytes:  int64(len(content)),
-		Content:    content,
-		StorageURL: nil, // Inline storage for MVP
-		CreatedAt:  time.Now(),
+		CasID:           casID,
+		MediaType:       mediaType,
+		SizeBytes:       int64(len(content)),
+		Content:         stored,
+		ContentEncoding: encoding,
+		Signature:       s.signer.Sign(casID, content),
+		StorageURL:      nil, // Inline storage for MVP
+		CreatedAt:       time.Now(),
 	}
 
 	if err := s.repo.Create(ctx, blob); err != nil {
 		return "", fmt.Errorf("failed to store content: %w", err)
 	}
 
-	s.log.Info("stored content in CAS", "cas_id", casID, "size_bytes", len(content))
+	s.log.Info("stored content in CAS",
+		"cas_id", casID,
+		"size_bytes", len(content),
+		"stored_bytes", len(stored),
+		"encoding", encoding)
 	return casID, nil
 }
 
 // GetContent retrieves content by CAS ID
 func (s *CASService) GetContent(ctx context.Context, casID string) ([]byte, error) {
-	content, err := s.repo.GetContentByID(ctx, casID)
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "get")
+
+	blob, err := s.repo.GetContentByID(ctx, casID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get content: %w", err)
 	}
 
+	content, err := compression.Decode(blob.Content, blob.ContentEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode content %s: %w", casID, err)
+	}
+	if err := s.verify(casID, content, blob.Signature); err != nil {
+		return nil, err
+	}
+
 	return content, nil
 }
 
-// GetContentBulk retrieves content for multiple CAS IDs in a single query
-// Returns a map of cas_id -> content
-func (s *CASService) GetContentBulk(ctx context.Context, casIDs []string) (map[string][]byte, error) {
+// GetContentBulk retrieves content for multiple CAS IDs
+// IDs with no stored blob are reported missing, and blobs that can't be decoded
+// or fail verification unreadable; an error means the store couldn't be queried.
+// Callers that need every blob check the result's Err.
+func (s *CASService) GetContentBulk(ctx context.Context, casIDs []string) (*clients.CASBulkResult, error) {
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "get_bulk")
+
 	if len(casIDs) == 0 {
-		return make(map[string][]byte), nil
+		return clients.NewCASBulkResult(0), nil
 	}
 
+	casIDs = uniqueStrings(casIDs)
 	s.log.Info("bulk fetching CAS content", "count", len(casIDs))
 
-	results, err := s.repo.GetContentBulk(ctx, casIDs)
+	blobs, err := s.getBlobsChunked(ctx, casIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bulk content: %w", err)
 	}
 
-	// Verify all requested IDs were found
-	if len(results) != len(casIDs) {
-		missing := []string{}
-		for _, id := range casIDs {
-			if _, found := results[id]; !found {
-				missing = append(missing, id)
-			}
+	result := clients.NewCASBulkResult(len(casIDs))
+	for _, id := range casIDs {
+		blob, ok := blobs[id]
+		if !ok {
+			result.Missing = append(result.Missing, id)
+			continue
 		}
-		s.log.Warn("some CAS IDs not found", "missing_count", len(missing), "missing_ids", missing)
+		content, err := compression.Decode(blob.Content, blob.ContentEncoding)
+		if err != nil {
+			err = fmt.Errorf("failed to decode content %s: %w", id, err)
+		} else {
+			err = s.verify(id, content, blob.Signature)
+		}
+		if err != nil {
+			result.Unreadable[id] = err
+			continue
+		}
+		result.Found[id] = content
+	}
+
+	if len(result.Missing) > 0 || len(result.Unreadable) > 0 {
+		s.log.Warn("some CAS content unavailable",
+			"requested", len(casIDs),
+			"missing", len(result.Missing),
+			"unreadable", len(result.Unreadable),
+			"missing_ids", result.Missing)
 	}
 
-	return results, nil
+	return result, nil
+}
+
+// Bulk content queries are split so a huge fetch doesn't become one huge query
+const (
+	casBulkChunkSize   = 1000 // CAS IDs per query
+	casBulkConcurrency = 4    // Queries in flight at once
+)
+
+// getBlobsChunked fetches stored blobs in queries of at most casBulkChunkSize IDs
+// Up to casBulkConcurrency queries run at once; the first failure cancels the rest.
+func (s *CASService) getBlobsChunked(ctx context.Context, casIDs []string) (map[string]*models.CASBlob, error) {
+	chunks := bulk.Chunks(casIDs, casBulkChunkSize)
+	chunkBlobs := make([]map[string]*models.CASBlob, len(chunks))
+	err := bulk.ForEach(ctx, chunks, casBulkConcurrency, func(ctx context.Context, i int, chunk []string) error {
+		var err error
+		chunkBlobs[i], err = s.repo.GetContentBulk(ctx, chunk)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(map[string]*models.CASBlob, len(casIDs))
+	for _, chunk := range chunkBlobs {
+		for id, blob := range chunk {
+			blobs[id] = blob
+		}
+	}
+	return blobs, nil
 }
 
 // GetBlob retrieves full CAS blob metadata
+// Content is returned decoded; ContentEncoding still reports how it is stored.
 func (s *CASService) GetBlob(ctx context.Context, casID string) (*models.CASBlob, error) {
 	blob, err := s.repo.GetByID(ctx, casID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get blob: %w", err)
 	}
 
+	if blob.Content != nil {
+		blob.Content, err = compression.Decode(blob.Content, blob.ContentEncoding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode blob %s: %w", casID, err)
+		}
+		if err := s.verify(casID, blob.Content, blob.Signature); err != nil {
+			return nil, err
+		}
+	}
+
 	return blob, nil
 }
 
@@ -118,3 +223,21 @@ func (s *CASService) ComputeHash(content []byte) string {
 	hash := sha256.Sum256(content)
 	return fmt.Sprintf("sha256:%x", hash)
 }
+
+// verify fails closed on content that doesn't hash to its CAS ID or, with
+// signing enabled, whose signature is missing or wrong
+// Content is stored under its hash, so a mismatch means the row was altered or
+// written under the wrong ID; serving it would run a different workflow.
+func (s *CASService) verify(casID string, content []byte, signature string) error {
+	if err := s.signer.Verify(casID, content, signature); err != nil {
+		s.log.Error("CAS content failed verification", "cas_id", casID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// encode compresses content when compression is enabled and the content is
+// above the threshold. Falls back to identity if compression doesn't help.
+func (s *CASService) encode(content []byte) ([]byte, string, error) {
+	return compression.Encode(content, s.compression.Compression, s.compression.CompressionThreshold)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+// casGCBatchSize bounds how many blobs a single DELETE removes
+const casGCBatchSize = 500
+
+// casIDPattern matches CAS IDs embedded in run contexts and IRs
+var casIDPattern = regexp.MustCompile(`sha256:[0-9a-f]{64}`)
+
+// casGCStore is the subset of CASBlobRepository used by CASGarbageCollector
+type casGCStore interface {
+	ListReferencedIDs(ctx context.Context) ([]string, error)
+	ListCreatedBefore(ctx context.Context, cutoff time.Time) ([]*models.CASBlob, error)
+	DeleteUnreferenced(ctx context.Context, casIDs []string, cutoff time.Time) ([]*models.CASBlob, error)
+}
+
+// CASGarbageCollector deletes CAS blobs that nothing references any more
+// CAS is deduplicated by hash, so a blob is only garbage once no artifact,
+// agent result, node execution or live run (context or IR in Redis) refers to
+// it. Blobs younger than the grace period are kept so content stored just
+// before its artifact is created is never collected.
+type CASGarbageCollector struct {
+	store casGCStore
+	redis *rediscommon.Client
+	cfg   config.CASGCConfig
+	log   *logger.Logger
+	now   func() time.Time
+}
+
+// CASGCReport summarizes a single collection pass
+type CASGCReport struct {
+	DryRun           bool  `json:"dry_run"`
+	Referenced       int   `json:"referenced"`        // Distinct CAS IDs found referenced
+	Candidates       int   `json:"candidates"`        // Blobs older than the grace period
+	Reclaimable      int   `json:"reclaimable"`       // Candidates nothing references
+	ReclaimableBytes int64 `json:"reclaimable_bytes"` // Uncompressed size of reclaimable blobs
+	Deleted          int   `json:"deleted"`           // Zero in dry-run mode
+	DeletedBytes     int64 `json:"deleted_bytes"`
+}
+
+// NewCASGarbageCollector creates a new background CAS collector
+func NewCASGarbageCollector(store casGCStore, redisClient *rediscommon.Client, cfg config.CASGCConfig, log *logger.Logger) *CASGarbageCollector {
+	return &CASGarbageCollector{
+		store: store,
+		redis: redisClient,
+		cfg:   cfg,
+		log:   log,
+		now:   time.Now,
+	}
+}
+
+// Run collects on every interval until ctx is cancelled
+func (g *CASGarbageCollector) Run(ctx context.Context) {
+	g.log.Info("CAS garbage collection started",
+		"interval", g.cfg.Interval,
+		"grace_period", g.cfg.GracePeriod,
+		"dry_run", g.cfg.DryRun,
+	)
+
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			g.log.Info("CAS garbage collection stopped")
+			return
+		case <-ticker.C:
+			if _, err := g.RunOnce(ctx); err != nil {
+				g.log.Error("CAS garbage collection pass failed", "error", err)
+			}
+		}
+	}
+}
+
+// RunOnce performs a single collection pass
+func (g *CASGarbageCollector) RunOnce(ctx context.Context) (*CASGCReport, error) {
+	cutoff := g.now().Add(-g.cfg.GracePeriod)
+
+	// 1. Collect references before listing candidates: anything referenced
+	// later is either younger than the cutoff or protected by the DELETE's
+	// artifact re-check
+	referenced, err := g.referencedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Old enough blobs are candidates
+	candidates, err := g.store.ListCreatedBefore(ctx, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CASGCReport{
+		DryRun:     g.cfg.DryRun,
+		Referenced: len(referenced),
+		Candidates: len(candidates),
+	}
+
+	// 3. Unreferenced candidates are reclaimable
+	var reclaimable []string
+	for _, blob := range candidates {
+		if _, ok := referenced[blob.CasID]; ok {
+			continue
+		}
+		reclaimable = append(reclaimable, blob.CasID)
+		report.ReclaimableBytes += blob.SizeBytes
+	}
+	report.Reclaimable = len(reclaimable)
+
+	if g.cfg.DryRun {
+		g.log.Info("CAS garbage collection dry run",
+			"candidates", report.Candidates,
+			"reclaimable", report.Reclaimable,
+			"reclaimable_bytes", report.ReclaimableBytes,
+		)
+		return report, nil
+	}
+
+	// 4. Delete in batches
+	for start := 0; start < len(reclaimable); start += casGCBatchSize {
+		end := min(start+casGCBatchSize, len(reclaimable))
+
+		deleted, err := g.store.DeleteUnreferenced(ctx, reclaimable[start:end], cutoff)
+		if err != nil {
+			return report, err
+		}
+		for _, blob := range deleted {
+			report.Deleted++
+			report.DeletedBytes += blob.SizeBytes
+		}
+	}
+
+	g.log.Info("CAS garbage collection pass complete",
+		"referenced", report.Referenced,
+		"candidates", report.Candidates,
+		"deleted", report.Deleted,
+		"deleted_bytes", report.DeletedBytes,
+	)
+
+	return report, nil
+}
+
+// referencedIDs returns the set of CAS IDs referenced from the database or a live run
+func (g *CASGarbageCollector) referencedIDs(ctx context.Context) (map[string]struct{}, error) {
+	ids, err := g.store.ListReferencedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		referenced[id] = struct{}{}
+	}
+
+	if err := g.addLiveRunRefs(ctx, referenced); err != nil {
+		return nil, err
+	}
+
+	return referenced, nil
+}
+
+// addLiveRunRefs adds CAS IDs held by runs still in Redis (context:<run> and ir:<run>)
+func (g *CASGarbageCollector) addLiveRunRefs(ctx context.Context, referenced map[string]struct{}) error {
+	addFrom := func(value string) {
+		for _, id := range casIDPattern.FindAllString(value, -1) {
+			referenced[id] = struct{}{}
+		}
+	}
+
+	contextKeys, err := g.redis.ScanKeys(ctx, "context:*", "hash")
+	if err != nil {
+		return fmt.Errorf("failed to scan run contexts: %w", err)
+	}
+	for _, key := range contextKeys {
+		fields, err := g.redis.GetAllHash(ctx, key)
+		if err != nil {
+			return err
+		}
+		for _, value := range fields {
+			addFrom(value)
+		}
+	}
+
+	irKeys, err := g.redis.ScanKeys(ctx, "ir:*", "string")
+	if err != nil {
+		return fmt.Errorf("failed to scan run IRs: %w", err)
+	}
+	for start := 0; start < len(irKeys); start += casGCBatchSize {
+		end := min(start+casGCBatchSize, len(irKeys))
+
+		irs, err := g.redis.GetMultiple(ctx, irKeys[start:end])
+		if err != nil {
+			return err
+		}
+		for _, ir := range irs {
+			addFrom(ir)
+		}
+	}
+
+	return nil
+}
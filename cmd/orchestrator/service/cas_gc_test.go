@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+// fakeCASGCStore answers GC queries from the in-memory blob and artifact stores
+type fakeCASGCStore struct {
+	blobs     *fakeCASStore
+	artifacts *fakeArtifactStore
+}
+
+func (f *fakeCASGCStore) referencedByArtifact(casID string) bool {
+	for _, artifact := range f.artifacts.artifacts {
+		if artifact.CasID == casID {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeCASGCStore) ListReferencedIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	for _, artifact := range f.artifacts.artifacts {
+		ids = append(ids, artifact.CasID)
+	}
+	return ids, nil
+}
+
+func (f *fakeCASGCStore) ListCreatedBefore(ctx context.Context, cutoff time.Time) ([]*models.CASBlob, error) {
+	var blobs []*models.CASBlob
+	for _, blob := range f.blobs.blobs {
+		if blob.CreatedAt.Before(cutoff) {
+			blobs = append(blobs, blob)
+		}
+	}
+	return blobs, nil
+}
+
+func (f *fakeCASGCStore) DeleteUnreferenced(ctx context.Context, casIDs []string, cutoff time.Time) ([]*models.CASBlob, error) {
+	var deleted []*models.CASBlob
+	for _, id := range casIDs {
+		blob, ok := f.blobs.blobs[id]
+		if !ok || !blob.CreatedAt.Before(cutoff) || f.referencedByArtifact(id) {
+			continue
+		}
+		delete(f.blobs.blobs, id)
+		deleted = append(deleted, blob)
+	}
+	return deleted, nil
+}
+
+type casGCFixture struct {
+	store *fakeCASGCStore
+	redis *miniredis.Miniredis
+	rdb   redis.UniversalClient
+	now   time.Time
+}
+
+func newCASGCFixture(t *testing.T) *casGCFixture {
+	f := &casGCFixture{
+		store: &fakeCASGCStore{blobs: newFakeCASStore(), artifacts: newFakeArtifactStore()},
+		redis: miniredis.RunT(t),
+		now:   time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC),
+	}
+	f.rdb = redis.NewClient(&redis.Options{Addr: f.redis.Addr()})
+	t.Cleanup(func() { f.rdb.Close() })
+	return f
+}
+
+// addBlob stores a blob created age ago and returns its CAS ID
+func (f *casGCFixture) addBlob(t *testing.T, content string, age time.Duration) string {
+	casID := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(content)))
+	require.NoError(t, f.store.blobs.Create(context.Background(), &models.CASBlob{
+		CasID:     casID,
+		MediaType: models.MediaTypeDAG,
+		SizeBytes: int64(len(content)),
+		Content:   []byte(content),
+		CreatedAt: f.now.Add(-age),
+	}))
+	return casID
+}
+
+func (f *casGCFixture) addArtifact(t *testing.T, casID string) {
+	require.NoError(t, f.store.artifacts.Create(context.Background(), &models.Artifact{
+		ArtifactID: uuid.New(),
+		Kind:       models.KindDAGVersion,
+		CasID:      casID,
+	}))
+}
+
+func (f *casGCFixture) collector(dryRun bool) *CASGarbageCollector {
+	log := logger.New("error", "text")
+	gc := NewCASGarbageCollector(f.store, rediscommon.NewClient(f.rdb, log), config.CASGCConfig{
+		Enabled:     true,
+		Interval:    time.Hour,
+		GracePeriod: 24 * time.Hour,
+		DryRun:      dryRun,
+	}, log)
+	gc.now = func() time.Time { return f.now }
+	return gc
+}
+
+func TestCASGarbageCollector_DeletesOnlyOldUnreferencedBlobs(t *testing.T) {
+	ctx := context.Background()
+	f := newCASGCFixture(t)
+
+	// Shared by two workflow versions (dedup-by-hash)
+	shared := f.addBlob(t, `{"nodes":["a"]}`, 72*time.Hour)
+	f.addArtifact(t, shared)
+	f.addArtifact(t, shared)
+
+	orphanOld := f.addBlob(t, `{"nodes":["orphan"]}`, 72*time.Hour)
+	orphanNew := f.addBlob(t, `{"nodes":["fresh"]}`, time.Hour)
+
+	// Only referenced by a run still executing in Redis
+	liveOutput := f.addBlob(t, `{"result":"live"}`, 72*time.Hour)
+	liveConfig := f.addBlob(t, `{"url":"https://example.com"}`, 72*time.Hour)
+	require.NoError(t, f.rdb.HSet(ctx, "context:run-1", "fetch:output", liveOutput).Err())
+	require.NoError(t, f.rdb.Set(ctx, "ir:run-1", `{"nodes":{"fetch":{"config_ref":"`+liveConfig+`"}}}`, 0).Err())
+
+	report, err := f.collector(false).RunOnce(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Candidates)
+	assert.Equal(t, 1, report.Reclaimable)
+	assert.Equal(t, 1, report.Deleted)
+	assert.Equal(t, int64(len(`{"nodes":["orphan"]}`)), report.DeletedBytes)
+
+	assert.NotContains(t, f.store.blobs.blobs, orphanOld)
+	for _, kept := range []string{shared, orphanNew, liveOutput, liveConfig} {
+		assert.Contains(t, f.store.blobs.blobs, kept)
+	}
+}
+
+func TestCASGarbageCollector_DryRunReportsWithoutDeleting(t *testing.T) {
+	ctx := context.Background()
+	f := newCASGCFixture(t)
+
+	referenced := f.addBlob(t, `{"nodes":["a"]}`, 72*time.Hour)
+	f.addArtifact(t, referenced)
+	first := f.addBlob(t, `{"nodes":["orphan-1"]}`, 48*time.Hour)
+	second := f.addBlob(t, `{"nodes":["orphan-22"]}`, 48*time.Hour)
+
+	report, err := f.collector(true).RunOnce(ctx)
+	require.NoError(t, err)
+
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 2, report.Reclaimable)
+	assert.Equal(t, int64(len(`{"nodes":["orphan-1"]}`)+len(`{"nodes":["orphan-22"]}`)), report.ReclaimableBytes)
+	assert.Zero(t, report.Deleted)
+	assert.Len(t, f.store.blobs.blobs, 3)
+	assert.Contains(t, f.store.blobs.blobs, first)
+	assert.Contains(t, f.store.blobs.blobs, second)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// fakeCASStore is an in-memory casBlobStore
+type fakeCASStore struct {
+	blobs map[string]*models.CASBlob
+}
+
+func newFakeCASStore() *fakeCASStore {
+	return &fakeCASStore{blobs: make(map[string]*models.CASBlob)}
+}
+
+func (f *fakeCASStore) Create(ctx context.Context, blob *models.CASBlob) error {
+	if _, ok := f.blobs[blob.CasID]; ok {
+		return nil
+	}
+	stored := *blob
+	f.blobs[blob.CasID] = &stored
+	return nil
+}
+
+func (f *fakeCASStore) GetByID(ctx context.Context, casID string) (*models.CASBlob, error) {
+	blob, ok := f.blobs[casID]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", casID)
+	}
+	copied := *blob
+	return &copied, nil
+}
+
+func (f *fakeCASStore) Exists(ctx context.Context, casID string) (bool, error) {
+	_, ok := f.blobs[casID]
+	return ok, nil
+}
+
+func (f *fakeCASStore) GetContentByID(ctx context.Context, casID string) (*models.CASBlob, error) {
+	return f.GetByID(ctx, casID)
+}
+
+func (f *fakeCASStore) GetContentBulk(ctx context.Context, casIDs []string) (map[string]*models.CASBlob, error) {
+	results := make(map[string]*models.CASBlob)
+	for _, id := range casIDs {
+		if blob, ok := f.blobs[id]; ok {
+			results[id] = blob
+		}
+	}
+	return results, nil
+}
+
+func newTestCASService(store casBlobStore, compression string, threshold int) *CASService {
+	return NewCASService(store, logger.New("error", "text"), config.CASConfig{
+		Compression:          compression,
+		CompressionThreshold: threshold,
+	})
+}
+
+func TestCASService_CompressedRoundTrip(t *testing.T) {
+	for _, codec := range []string{models.ContentEncodingGzip, models.ContentEncodingZstd} {
+		t.Run(codec, func(t *testing.T) {
+			ctx := context.Background()
+			store := newFakeCASStore()
+			svc := newTestCASService(store, codec, 1024)
+
+			large := []byte(strings.Repeat(`{"node":"a","status":"completed"}`, 500))
+			small := []byte(`{"tiny":true}`)
+
+			largeID, err := svc.StoreContent(ctx, large, "application/json")
+			require.NoError(t, err)
+			smallID, err := svc.StoreContent(ctx, small, "application/json")
+			require.NoError(t, err)
+
+			// Large blob is stored compressed, small one is not
+			assert.Equal(t, codec, store.blobs[largeID].ContentEncoding)
+			assert.Less(t, len(store.blobs[largeID].Content), len(large))
+			assert.Equal(t, int64(len(large)), store.blobs[largeID].SizeBytes)
+			assert.Equal(t, models.ContentEncodingIdentity, store.blobs[smallID].ContentEncoding)
+
+			got, err := svc.GetContent(ctx, largeID)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(large, got))
+
+			fetched, err := svc.GetContentBulk(ctx, []string{largeID, smallID})
+			require.NoError(t, err)
+			require.NoError(t, fetched.Err())
+			assert.True(t, bytes.Equal(large, fetched.Found[largeID]))
+			assert.True(t, bytes.Equal(small, fetched.Found[smallID]))
+
+			blob, err := svc.GetBlob(ctx, largeID)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(large, blob.Content))
+		})
+	}
+}
+
+func TestCASService_DedupIgnoresCompression(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeCASStore()
+	content := []byte(strings.Repeat("patch-op;", 2000))
+
+	plain := newTestCASService(store, "none", 0)
+	compressed := newTestCASService(store, "gzip", 0)
+
+	// CAS ID is the hash of the uncompressed content
+	assert.Equal(t, plain.ComputeHash(content), compressed.ComputeHash(content))
+
+	id1, err := compressed.StoreContent(ctx, content, "application/json")
+	require.NoError(t, err)
+	id2, err := plain.StoreContent(ctx, content, "application/json")
+	require.NoError(t, err)
+
+	assert.Equal(t, id1, id2)
+	assert.Len(t, store.blobs, 1)
+	assert.Equal(t, models.ContentEncodingGzip, store.blobs[id1].ContentEncoding)
+
+	// Readers decode regardless of their own compression setting
+	got, err := plain.GetContent(ctx, id1)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(content, got))
+}
+
+func TestCASService_RejectsTamperedContent(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeCASStore()
+	svc := newTestCASService(store, "none", 0)
+
+	casID, err := svc.StoreContent(ctx, []byte(`{"nodes":[]}`), "application/json")
+	require.NoError(t, err)
+	content, err := svc.GetContent(ctx, casID)
+	require.NoError(t, err)
+	assert.Equal(t, `{"nodes":[]}`, string(content))
+
+	store.blobs[casID].Content = []byte(`{"nodes":[{"id":"evil"}]}`)
+	_, err = svc.GetContent(ctx, casID)
+	assert.ErrorIs(t, err, clients.ErrCASIntegrity)
+	fetched, err := svc.GetContentBulk(ctx, []string{casID})
+	require.NoError(t, err)
+	assert.Empty(t, fetched.Found)
+	assert.ErrorIs(t, fetched.Unreadable[casID], clients.ErrCASIntegrity)
+	assert.ErrorIs(t, fetched.Err(), clients.ErrCASIntegrity)
+}
+
+func TestCASService_GetContentBulkReportsMissingAndUnreadable(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeCASStore()
+	svc := newTestCASService(store, "none", 0)
+
+	goodID, err := svc.StoreContent(ctx, []byte(`{"good":true}`), "application/json")
+	require.NoError(t, err)
+	badID, err := svc.StoreContent(ctx, []byte(`{"bad":true}`), "application/json")
+	require.NoError(t, err)
+	store.blobs[badID].Content = []byte(`{"bad":false}`)
+	missingID := svc.ComputeHash([]byte(`{"never":"stored"}`))
+
+	fetched, err := svc.GetContentBulk(ctx, []string{missingID, goodID, badID})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{goodID: []byte(`{"good":true}`)}, fetched.Found)
+	assert.Equal(t, []string{missingID}, fetched.Missing)
+	require.Len(t, fetched.Unreadable, 1)
+	assert.ErrorIs(t, fetched.Unreadable[badID], clients.ErrCASIntegrity)
+
+	// Missing blobs are reported before unreadable ones
+	assert.ErrorIs(t, fetched.Err(), clients.ErrCASNotFound)
+	assert.Contains(t, fetched.Err().Error(), missingID)
+}
+
+func TestCASService_Signing(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeCASStore()
+	newService := func(key string) *CASService {
+		return NewCASService(store, logger.New("error", "text"), config.CASConfig{Compression: "none", SigningKey: key})
+	}
+	svc := newService("0123456789abcdef0123456789abcdef")
+
+	// Signed content round-trips through every read
+	casID, err := svc.StoreContent(ctx, []byte(`{"nodes":[]}`), "application/json")
+	require.NoError(t, err)
+	assert.NotEmpty(t, store.blobs[casID].Signature)
+	_, err = svc.GetContent(ctx, casID)
+	require.NoError(t, err)
+	fetched, err := svc.GetContentBulk(ctx, []string{casID})
+	require.NoError(t, err)
+	require.NoError(t, fetched.Err())
+	_, err = svc.GetBlob(ctx, casID)
+	require.NoError(t, err)
+
+	// Content stored unsigned or signed with another key is rejected
+	unsignedID, err := newService("").StoreContent(ctx, []byte(`{"unsigned":true}`), "application/json")
+	require.NoError(t, err)
+	forgedID, err := newService(strings.Repeat("x", 32)).StoreContent(ctx, []byte(`{"forged":true}`), "application/json")
+	require.NoError(t, err)
+	for _, id := range []string{unsignedID, forgedID} {
+		_, err = svc.GetContent(ctx, id)
+		assert.ErrorIs(t, err, clients.ErrCASIntegrity)
+		fetched, err := svc.GetContentBulk(ctx, []string{id})
+		require.NoError(t, err)
+		assert.ErrorIs(t, fetched.Unreadable[id], clients.ErrCASIntegrity)
+		_, err = svc.GetBlob(ctx, id)
+		assert.ErrorIs(t, err, clients.ErrCASIntegrity)
+	}
+
+	// A signature moved onto other content doesn't verify either
+	store.blobs[forgedID].Signature = store.blobs[casID].Signature
+	_, err = svc.GetContent(ctx, forgedID)
+	assert.ErrorIs(t, err, clients.ErrCASIntegrity)
+}
+
+// chunkRecordingCASStore records the size of each bulk content query
+type chunkRecordingCASStore struct {
+	*fakeCASStore
+	mu     sync.Mutex
+	chunks []int
+}
+
+func (f *chunkRecordingCASStore) GetContentBulk(ctx context.Context, casIDs []string) (map[string]*models.CASBlob, error) {
+	f.mu.Lock()
+	f.chunks = append(f.chunks, len(casIDs))
+	f.mu.Unlock()
+	return f.fakeCASStore.GetContentBulk(ctx, casIDs)
+}
+
+func TestCASService_GetContentBulkChunked(t *testing.T) {
+	ctx := context.Background()
+	store := &chunkRecordingCASStore{fakeCASStore: newFakeCASStore()}
+	svc := newTestCASService(store, "none", 0)
+
+	casIDs := make([]string, 0, 2501)
+	for i := 0; i < 2500; i++ {
+		casID, err := svc.StoreContent(ctx, []byte(fmt.Sprintf(`{"n":%d}`, i)), "application/json")
+		require.NoError(t, err)
+		casIDs = append(casIDs, casID)
+	}
+	casIDs = append(casIDs, casIDs[0]) // Duplicates are fetched once
+
+	fetched, err := svc.GetContentBulk(ctx, casIDs)
+	require.NoError(t, err)
+	assert.Len(t, fetched.Found, 2500)
+	assert.Empty(t, fetched.Missing)
+	assert.Equal(t, []byte(`{"n":2499}`), fetched.Found[casIDs[2499]])
+	assert.ElementsMatch(t, []int{1000, 1000, 500}, store.chunks)
+}
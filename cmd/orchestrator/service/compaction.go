@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,34 +11,58 @@ import (
 	"github.com/lyzr/orchestrator/common/models"
 	"github.com/lyzr/orchestrator/common/repository"
 	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
 )
 
+// ErrTagMoved is returned when a tag moved (e.g. a new patch landed) between reading and swapping it
+var ErrTagMoved = errors.New("tag was moved concurrently")
+
+// compactionArtifactStore is the subset of ArtifactRepository used by CompactionService
+type compactionArtifactStore interface {
+	artifactStore
+	FindCompactedBase(ctx context.Context, patchID uuid.UUID) (*models.Artifact, error)
+	GetCompactionCandidates(ctx context.Context, depthThreshold int) ([]*models.Artifact, error)
+}
+
+// compactionTagStore is the subset of TagRepository used by CompactionService
+// Tags are moved through the TagService, which records the move.
+type compactionTagStore interface {
+	GetByName(ctx context.Context, username, tagName string) (*models.Tag, error)
+	ListByTarget(ctx context.Context, targetKind string, targetID uuid.UUID) ([]*models.Tag, error)
+}
+
 // CompactionService handles workflow compaction operations
 // Compaction "squashes" a long patch chain into a new base version
 type CompactionService struct {
-	artifactRepo *repository.ArtifactRepository
+	artifactRepo compactionArtifactStore
 	casRepo      *repository.CASBlobRepository
-	tagRepo      *repository.TagRepository
+	tagRepo      compactionTagStore
+	tagService   *TagService
 	casService   *CASService
 	materializer *MaterializerService
+	redis        *rediscommon.Client // Progress checkpoints; nil disables resuming
 	log          *logger.Logger
 }
 
 // NewCompactionService creates a new compaction service
 func NewCompactionService(
-	artifactRepo *repository.ArtifactRepository,
+	artifactRepo compactionArtifactStore,
 	casRepo *repository.CASBlobRepository,
-	tagRepo *repository.TagRepository,
+	tagRepo compactionTagStore,
+	tagService *TagService,
 	casService *CASService,
 	materializer *MaterializerService,
+	redisClient *rediscommon.Client,
 	log *logger.Logger,
 ) *CompactionService {
 	return &CompactionService{
 		artifactRepo: artifactRepo,
 		casRepo:      casRepo,
 		tagRepo:      tagRepo,
+		tagService:   tagService,
 		casService:   casService,
 		materializer: materializer,
+		redis:        redisClient,
 		log:          log,
 	}
 }
@@ -49,6 +74,8 @@ type CompactionResult struct {
 	CompactedFromID uuid.UUID // P20 artifact ID
 	NewCasID        string    // CAS ID of compacted workflow
 	MaterializedAt  time.Time
+	ResumedFrom     int  // Patches skipped thanks to a checkpoint of an interrupted run
+	AlreadyExisted  bool // The patch was compacted before; NewBaseID is that base
 }
 
 // CompactWorkflow compacts a patch chain into a new base version
@@ -63,6 +90,10 @@ type CompactionResult struct {
 // 8. Create new base artifact (V2)
 // 9. Return new base ID
 //
+// Compaction is idempotent and resumable: if the patch was already compacted
+// the existing base is returned, and progress is checkpointed every few
+// patches so a crashed or cancelled run picks up where it stopped.
+//
 // IMPORTANT: This does NOT delete old chains or move tags!
 // Old chain (V1+P1-P20) is preserved for undo/redo.
 func (s *CompactionService) CompactWorkflow(ctx context.Context, patchID uuid.UUID, compactedBy string) (*CompactionResult, error) {
@@ -85,11 +116,31 @@ func (s *CompactionService) CompactWorkflow(ctx context.Context, patchID uuid.UU
 	depth := *patch.Depth
 	s.log.Info("patch metadata retrieved", "depth", depth, "base_version", patch.BaseVersion)
 
+	// Re-invoking for an already compacted patch returns the existing base
+	existing, err := s.FindCompactedBase(ctx, patchID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		s.clearCheckpoint(ctx, patchID)
+		return &CompactionResult{
+			NewBaseID:       existing.ArtifactID,
+			OldChainDepth:   depth,
+			CompactedFromID: patchID,
+			NewCasID:        existing.CasID,
+			MaterializedAt:  existing.CreatedAt,
+			AlreadyExisted:  true,
+		}, nil
+	}
+
 	// Step 2: Get patch chain (O(1) lookup via patch_chain_member)
 	patchChain, err := s.artifactRepo.GetPatchChain(ctx, patchID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get patch chain: %w", err)
 	}
+	if err := orderPatchChain(patchID, patchChain); err != nil {
+		return nil, err
+	}
 
 	if len(patchChain) == 0 {
 		return nil, fmt.Errorf("patch chain is empty")
@@ -97,56 +148,67 @@ func (s *CompactionService) CompactWorkflow(ctx context.Context, patchID uuid.UU
 
 	s.log.Info("patch chain retrieved", "length", len(patchChain))
 
-	// Step 3: Fetch base version content
+	// Step 3: Fetch the starting content: a checkpoint of an interrupted run, or the base version
 	if patch.BaseVersion == nil {
 		return nil, fmt.Errorf("patch has no base version")
 	}
 
-	baseArtifact, err := s.artifactRepo.GetByID(ctx, *patch.BaseVersion)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get base version artifact: %w", err)
-	}
+	currentJSON, resumedFrom := s.loadCheckpoint(ctx, patchID, patchChain)
+	if currentJSON == nil {
+		baseArtifact, err := s.artifactRepo.GetByID(ctx, *patch.BaseVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get base version artifact: %w", err)
+		}
 
-	baseContent, err := s.casService.GetContent(ctx, baseArtifact.CasID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get base version content: %w", err)
-	}
+		currentJSON, err = s.casService.GetContent(ctx, baseArtifact.CasID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get base version content: %w", err)
+		}
 
-	s.log.Info("base version fetched", "cas_id", baseArtifact.CasID, "size_bytes", len(baseContent))
+		s.log.Info("base version fetched", "cas_id", baseArtifact.CasID, "size_bytes", len(currentJSON))
+	}
+	remaining := patchChain[resumedFrom:]
 
-	// Step 4: Fetch all patch contents
-	patchCasIDs := make([]string, 0, len(patchChain))
-	for _, p := range patchChain {
+	// Step 4: Fetch the contents of the patches still to apply
+	patchCasIDs := make([]string, 0, len(remaining))
+	for _, p := range remaining {
 		patchCasIDs = append(patchCasIDs, p.CasID)
 	}
 
-	patchContents, err := s.casService.GetContentBulk(ctx, patchCasIDs)
+	fetched, err := s.casService.GetContentBulk(ctx, patchCasIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch patch contents: %w", err)
 	}
-
-	if len(patchContents) != len(patchChain) {
-		return nil, fmt.Errorf("expected %d patch contents, got %d", len(patchChain), len(patchContents))
+	if err := fetched.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch patch contents: %w", err)
 	}
+	patchContents := fetched.Found
 
-	s.log.Info("patch contents fetched", "count", len(patchContents))
+	s.log.Info("patch contents fetched", "count", len(patchContents), "resumed_from", resumedFrom)
 
-	// Step 5: Materialize full workflow (apply patches sequentially)
-	currentJSON := baseContent
-	for i, p := range patchChain {
+	// Step 5: Materialize full workflow (apply patches sequentially, checkpointing progress)
+	for i, p := range remaining {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("compaction interrupted after %d patches: %w", resumedFrom+i, err)
+		}
+
+		seq := resumedFrom + i + 1
 		patchJSON, ok := patchContents[p.CasID]
 		if !ok {
 			return nil, fmt.Errorf("missing patch content for cas_id=%s", p.CasID)
 		}
 
-		s.log.Debug("applying patch", "seq", i+1, "artifact_id", p.ArtifactID, "cas_id", p.CasID)
+		s.log.Debug("applying patch", "seq", seq, "artifact_id", p.ArtifactID, "cas_id", p.CasID)
 
 		resultJSON, err := s.materializer.applyPatch(currentJSON, patchJSON)
 		if err != nil {
-			return nil, fmt.Errorf("failed to apply patch %d (artifact=%s): %w", i+1, p.ArtifactID, err)
+			return nil, fmt.Errorf("failed to apply patch %d (artifact=%s): %w", seq, p.ArtifactID, err)
 		}
 
 		currentJSON = resultJSON
+		if seq%compactionCheckpointInterval == 0 || seq == len(patchChain) {
+			s.saveCheckpoint(ctx, patchID, seq, p.ArtifactID, currentJSON)
+		}
 	}
 
 	materializedWorkflow := currentJSON
@@ -201,6 +263,7 @@ func (s *CompactionService) CompactWorkflow(ctx context.Context, patchID uuid.UU
 	if err := s.artifactRepo.Create(ctx, newBase); err != nil {
 		return nil, fmt.Errorf("failed to create compacted base artifact: %w", err)
 	}
+	s.clearCheckpoint(ctx, patchID)
 
 	s.log.Info("created compacted base version",
 		"artifact_id", newBase.ArtifactID,
@@ -217,6 +280,7 @@ func (s *CompactionService) CompactWorkflow(ctx context.Context, patchID uuid.UU
 		CompactedFromID: patchID,
 		NewCasID:        casID,
 		MaterializedAt:  time.Now(),
+		ResumedFrom:     resumedFrom,
 	}
 
 	s.log.Info("compaction complete",
@@ -229,7 +293,9 @@ func (s *CompactionService) CompactWorkflow(ctx context.Context, patchID uuid.UU
 }
 
 // MigrateTagToCompactedBase migrates a tag from old patch chain to new base version
-// This updates the tag and records the move in tag_move for undo/redo support
+// This updates the tag and records the move in tag_move for undo/redo support.
+// The move is a compare-and-swap on the tag version: if the tag no longer points
+// at the compacted patch (a newer patch landed), ErrTagMoved is returned.
 func (s *CompactionService) MigrateTagToCompactedBase(
 	ctx context.Context,
 	username, tagName string,
@@ -263,15 +329,25 @@ func (s *CompactionService) MigrateTagToCompactedBase(
 		return fmt.Errorf("new base is not a dag_version (kind=%s)", newBase.Kind)
 	}
 
-	// Update tag to point to new base
-	tag.TargetID = newBaseID
-	tag.TargetKind = models.KindDAGVersion
-	tag.MovedBy = &movedBy
-	tag.MovedAt = time.Now()
+	// The tag must still point at the patch the base was compacted from
+	if newBase.CompactedFromID != nil && *newBase.CompactedFromID != oldTargetID {
+		return ErrTagMoved
+	}
 
-	if err := s.tagRepo.Update(ctx, tag); err != nil {
+	targetHash := newBase.CasID
+	if newBase.VersionHash != nil {
+		targetHash = *newBase.VersionHash
+	}
+
+	// Update tag to point to new base (only if nobody moved it since we read it)
+	// Going through the tag service records the move, so it can be undone.
+	swapped, err := s.tagService.CompareAndSwap(ctx, username, tagName, tag.Version, newBaseID, models.KindDAGVersion, targetHash, movedBy)
+	if err != nil {
 		return fmt.Errorf("failed to update tag: %w", err)
 	}
+	if !swapped {
+		return ErrTagMoved
+	}
 
 	s.log.Info("tag migrated successfully",
 		"tag_name", tagName,
@@ -283,6 +359,15 @@ func (s *CompactionService) MigrateTagToCompactedBase(
 	return nil
 }
 
+// ListTagsAtPatch returns the tags currently pointing at a patch_set
+func (s *CompactionService) ListTagsAtPatch(ctx context.Context, patchID uuid.UUID) ([]*models.Tag, error) {
+	tags, err := s.tagRepo.ListByTarget(ctx, string(models.KindPatchSet), patchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags at patch: %w", err)
+	}
+	return tags, nil
+}
+
 // ShouldCompact determines if a patch chain should be compacted
 // Returns true if depth exceeds threshold
 func (s *CompactionService) ShouldCompact(ctx context.Context, artifactID uuid.UUID, depthThreshold int) (bool, error) {
@@ -342,6 +427,7 @@ type CompactionStats struct {
 	EstimatedSavings   int     // Estimated rows saved in patch_chain_member
 	LongestChainDepth  int     // Deepest chain
 	LongestChainID     *uuid.UUID
+	Candidates         []*models.Artifact // Candidate patches, deepest first
 }
 
 func (s *CompactionService) GetCompactionStats(ctx context.Context, depthThreshold int) (*CompactionStats, error) {
@@ -350,7 +436,7 @@ func (s *CompactionService) GetCompactionStats(ctx context.Context, depthThresho
 		return nil, fmt.Errorf("failed to get compaction candidates: %w", err)
 	}
 
-	stats := &CompactionStats{}
+	stats := &CompactionStats{Candidates: candidates}
 
 	for _, artifact := range candidates {
 		if artifact.Depth == nil {
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+const (
+	// compactionCheckpointInterval is how many patches are applied between checkpoints
+	compactionCheckpointInterval = 5
+
+	// compactionCheckpointTTL bounds how long an abandoned compaction can be resumed
+	compactionCheckpointTTL = 24 * time.Hour
+)
+
+// compactionCheckpoint records how far a compaction got
+// The partially materialized workflow is stored in CAS (content-addressed, so
+// re-storing it is free) and the checkpoint only keeps its CAS ID.
+type compactionCheckpoint struct {
+	Through   int       `json:"through"`  // Number of chain patches applied
+	PatchID   uuid.UUID `json:"patch_id"` // Last applied patch, to detect a changed chain
+	CasID     string    `json:"cas_id"`   // Workflow materialized through PatchID
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// compactionCheckpointKey returns the checkpoint key for compacting a patch
+func compactionCheckpointKey(patchID uuid.UUID) string {
+	return fmt.Sprintf("compaction:checkpoint:%s", patchID)
+}
+
+// loadCheckpoint returns the workflow materialized by an earlier, interrupted
+// compaction of the same chain, and how many patches it covers
+// Missing, stale or unreadable checkpoints mean starting from the base (0).
+func (s *CompactionService) loadCheckpoint(ctx context.Context, patchID uuid.UUID, chain []*models.Artifact) ([]byte, int) {
+	if s.redis == nil {
+		return nil, 0
+	}
+
+	data, err := s.redis.Get(ctx, compactionCheckpointKey(patchID))
+	if err != nil {
+		return nil, 0
+	}
+
+	var checkpoint compactionCheckpoint
+	if err := json.Unmarshal([]byte(data), &checkpoint); err != nil {
+		s.log.Warn("ignoring unreadable compaction checkpoint", "patch_id", patchID, "error", err)
+		return nil, 0
+	}
+
+	if checkpoint.Through <= 0 || checkpoint.Through > len(chain) || chain[checkpoint.Through-1].ArtifactID != checkpoint.PatchID {
+		s.log.Warn("ignoring stale compaction checkpoint", "patch_id", patchID, "through", checkpoint.Through)
+		return nil, 0
+	}
+
+	content, err := s.casService.GetContent(ctx, checkpoint.CasID)
+	if err != nil {
+		s.log.Warn("compaction checkpoint content missing", "patch_id", patchID, "cas_id", checkpoint.CasID, "error", err)
+		return nil, 0
+	}
+
+	s.log.Info("resuming compaction from checkpoint", "patch_id", patchID, "through", checkpoint.Through)
+	return content, checkpoint.Through
+}
+
+// saveCheckpoint records the workflow materialized through chain[through-1]
+// Checkpointing is best effort: a failure only means a crash redoes more work.
+func (s *CompactionService) saveCheckpoint(ctx context.Context, patchID uuid.UUID, through int, lastPatchID uuid.UUID, content []byte) {
+	if s.redis == nil {
+		return
+	}
+
+	casID, err := s.casService.StoreContent(ctx, content, "application/json;type=dag")
+	if err != nil {
+		s.log.Warn("failed to store compaction checkpoint", "patch_id", patchID, "through", through, "error", err)
+		return
+	}
+
+	data, err := json.Marshal(compactionCheckpoint{
+		Through:   through,
+		PatchID:   lastPatchID,
+		CasID:     casID,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+
+	if err := s.redis.Set(ctx, compactionCheckpointKey(patchID), string(data), compactionCheckpointTTL); err != nil {
+		s.log.Warn("failed to save compaction checkpoint", "patch_id", patchID, "through", through, "error", err)
+		return
+	}
+
+	s.log.Debug("saved compaction checkpoint", "patch_id", patchID, "through", through, "cas_id", casID)
+}
+
+// clearCheckpoint removes the checkpoint once the compacted base exists
+func (s *CompactionService) clearCheckpoint(ctx context.Context, patchID uuid.UUID) {
+	if s.redis == nil {
+		return
+	}
+
+	if err := s.redis.Delete(ctx, compactionCheckpointKey(patchID)); err != nil {
+		s.log.Warn("failed to clear compaction checkpoint", "patch_id", patchID, "error", err)
+	}
+}
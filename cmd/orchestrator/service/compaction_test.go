@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+// crashingCASStore cancels the compaction context on the n-th blob write
+type crashingCASStore struct {
+	*fakeCASStore
+	writes     int
+	crashAfter int
+	cancel     context.CancelFunc
+}
+
+func (s *crashingCASStore) Create(ctx context.Context, blob *models.CASBlob) error {
+	s.writes++
+	if s.writes == s.crashAfter {
+		s.cancel()
+	}
+	return s.fakeCASStore.Create(ctx, blob)
+}
+
+func TestCompactionService_ResumesAfterCrash(t *testing.T) {
+	f := newAutoCompactionFixture(t, 25)
+	log := logger.New("error", "text")
+
+	head, err := f.tags.GetByName(context.Background(), "alice", "main")
+	require.NoError(t, err)
+	before := f.materialize(t)
+	checkpointKey := compactionCheckpointKey(head.TargetID)
+
+	// The process dies while writing the third checkpoint (after patch 15);
+	// checkpoints for patches 5 and 10 made it
+	crashCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	crashing := NewCompactionService(
+		f.artifacts, nil, f.tags, NewTagService(f.tags, log),
+		newTestCASService(&crashingCASStore{fakeCASStore: f.blobs, crashAfter: 3, cancel: cancel}, "none", 0),
+		f.workflows.materializer,
+		rediscommon.NewClient(f.rdb, log),
+		log,
+	)
+	_, err = crashing.CompactWorkflow(crashCtx, head.TargetID, "test")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.True(t, f.redis.Exists(checkpointKey))
+
+	base, err := f.compaction.FindCompactedBase(context.Background(), head.TargetID)
+	require.NoError(t, err)
+	assert.Nil(t, base)
+
+	// Retrying picks up after patch 10
+	result, err := f.compaction.CompactWorkflow(context.Background(), head.TargetID, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.ResumedFrom)
+	assert.False(t, result.AlreadyExisted)
+	assert.False(t, f.redis.Exists(checkpointKey))
+
+	// The resumed base matches a full materialization
+	require.NoError(t, f.compaction.MigrateTagToCompactedBase(context.Background(), "alice", "main", result.NewBaseID, "test"))
+	assert.Equal(t, before, f.materialize(t))
+}
+
+func TestCompactionService_IgnoresStaleCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 7)
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	before := f.materialize(t)
+
+	// A checkpoint that doesn't match the chain is discarded
+	require.NoError(t, f.redis.Set(compactionCheckpointKey(head.TargetID), `{"through":5,"patch_id":"00000000-0000-0000-0000-000000000000","cas_id":"missing"}`))
+
+	result, err := f.compaction.CompactWorkflow(ctx, head.TargetID, "test")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ResumedFrom)
+
+	require.NoError(t, f.compaction.MigrateTagToCompactedBase(ctx, "alice", "main", result.NewBaseID, "test"))
+	assert.Equal(t, before, f.materialize(t))
+}
+
+func TestCompactionService_IdempotentReinvocation(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 6)
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+
+	first, err := f.compaction.CompactWorkflow(ctx, head.TargetID, "test")
+	require.NoError(t, err)
+	assert.False(t, first.AlreadyExisted)
+	artifacts := len(f.artifacts.artifacts)
+
+	// Compacting the same patch again returns the existing base
+	second, err := f.compaction.CompactWorkflow(ctx, head.TargetID, "test")
+	require.NoError(t, err)
+	assert.True(t, second.AlreadyExisted)
+	assert.Equal(t, first.NewBaseID, second.NewBaseID)
+	assert.Equal(t, first.NewCasID, second.NewCasID)
+	assert.Equal(t, first.OldChainDepth, second.OldChainDepth)
+	assert.Len(t, f.artifacts.artifacts, artifacts)
+}
+
+// failingTagStore fails every compare-and-swap with a storage error
+type failingTagStore struct {
+	*fakeTagStore
+	err error
+}
+
+func (s *failingTagStore) CompareAndSwap(ctx context.Context, username, tagName string, expectedVersion int64, newTarget uuid.UUID, newTargetKind, newTargetHash, movedBy string) (bool, error) {
+	return false, s.err
+}
+
+func TestCompactionService_MigrateTagRecordsMove(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 3)
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	result, err := f.compaction.CompactWorkflow(ctx, head.TargetID, "test")
+	require.NoError(t, err)
+
+	require.NoError(t, f.compaction.MigrateTagToCompactedBase(ctx, "alice", "main", result.NewBaseID, "compactor"))
+
+	// The move is in the tag's history, so it can be undone
+	history, err := f.tags.GetHistory(ctx, "alice", "main", 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.TagMoveActionMove, history[0].Action)
+	assert.Equal(t, head.TargetID, *history[0].FromID)
+	assert.Equal(t, result.NewBaseID, history[0].ToID)
+	assert.Equal(t, "compactor", *history[0].MovedBy)
+}
+
+func TestCompactionService_MigrateTagStoreError(t *testing.T) {
+	ctx := context.Background()
+	f := newAutoCompactionFixture(t, 3)
+	log := logger.New("error", "text")
+
+	head, err := f.tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	result, err := f.compaction.CompactWorkflow(ctx, head.TargetID, "test")
+	require.NoError(t, err)
+
+	// A storage failure is reported as such, not as a concurrent move
+	storeErr := errors.New("connection reset")
+	failing := &failingTagStore{fakeTagStore: f.tags, err: storeErr}
+	compaction := NewCompactionService(f.artifacts, nil, f.tags, NewTagService(failing, log), f.compaction.casService, f.workflows.materializer, rediscommon.NewClient(f.rdb, log), log)
+	err = compaction.MigrateTagToCompactedBase(ctx, "alice", "main", result.NewBaseID, "test")
+	assert.ErrorIs(t, err, storeErr)
+	assert.NotErrorIs(t, err, ErrTagMoved)
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+// fakeArtifactStore is an in-memory artifactStore
+type fakeArtifactStore struct {
+	artifacts map[uuid.UUID]*models.Artifact
+	chains    map[uuid.UUID][]uuid.UUID
+}
+
+func newFakeArtifactStore() *fakeArtifactStore {
+	return &fakeArtifactStore{
+		artifacts: make(map[uuid.UUID]*models.Artifact),
+		chains:    make(map[uuid.UUID][]uuid.UUID),
+	}
+}
+
+func (f *fakeArtifactStore) Create(ctx context.Context, artifact *models.Artifact) error {
+	stored := *artifact
+	f.artifacts[artifact.ArtifactID] = &stored
+	return nil
+}
+
+func (f *fakeArtifactStore) GetByID(ctx context.Context, artifactID uuid.UUID) (*models.Artifact, error) {
+	artifact, ok := f.artifacts[artifactID]
+	if !ok {
+		return nil, fmt.Errorf("artifact %s: %w", artifactID, pgx.ErrNoRows)
+	}
+	return artifact, nil
+}
+
+func (f *fakeArtifactStore) GetByVersionHash(ctx context.Context, versionHash string) (*models.Artifact, error) {
+	for _, artifact := range f.artifacts {
+		if artifact.VersionHash != nil && *artifact.VersionHash == versionHash {
+			return artifact, nil
+		}
+	}
+	return nil, fmt.Errorf("artifact not found: %s", versionHash)
+}
+
+func (f *fakeArtifactStore) GetByPlanHash(ctx context.Context, planHash string) (*models.Artifact, error) {
+	return nil, fmt.Errorf("artifact not found: %s", planHash)
+}
+
+func (f *fakeArtifactStore) ListByKind(ctx context.Context, kind string, limit int) ([]*models.Artifact, error) {
+	return nil, nil
+}
+
+func (f *fakeArtifactStore) GetPatchChain(ctx context.Context, headID uuid.UUID) ([]*models.Artifact, error) {
+	var chain []*models.Artifact
+	for _, id := range f.chains[headID] {
+		chain = append(chain, f.artifacts[id])
+	}
+	return chain, nil
+}
+
+func (f *fakeArtifactStore) GetByIDs(ctx context.Context, artifactIDs []uuid.UUID) (map[uuid.UUID]*models.Artifact, error) {
+	result := make(map[uuid.UUID]*models.Artifact)
+	for _, id := range artifactIDs {
+		if artifact, ok := f.artifacts[id]; ok {
+			result[id] = artifact
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeArtifactStore) GetPatchChains(ctx context.Context, headIDs []uuid.UUID) (map[uuid.UUID][]*models.Artifact, error) {
+	result := make(map[uuid.UUID][]*models.Artifact)
+	for _, headID := range headIDs {
+		for _, id := range f.chains[headID] {
+			result[headID] = append(result[headID], f.artifacts[id])
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeArtifactStore) InsertPatchChain(ctx context.Context, headID uuid.UUID, memberIDs []uuid.UUID) error {
+	f.chains[headID] = append([]uuid.UUID{}, memberIDs...)
+	return nil
+}
+
+func (f *fakeArtifactStore) DeletePatch(ctx context.Context, artifactID uuid.UUID) error {
+	if artifact, ok := f.artifacts[artifactID]; !ok || !artifact.IsPatchSet() {
+		return fmt.Errorf("patch artifact %s not found", artifactID)
+	}
+	delete(f.artifacts, artifactID)
+	delete(f.chains, artifactID)
+	return nil
+}
+
+func (f *fakeArtifactStore) FindCompactedBase(ctx context.Context, patchID uuid.UUID) (*models.Artifact, error) {
+	for _, artifact := range f.artifacts {
+		if artifact.CompactedFromID != nil && *artifact.CompactedFromID == patchID {
+			return artifact, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeArtifactStore) GetCompactionCandidates(ctx context.Context, depthThreshold int) ([]*models.Artifact, error) {
+	var candidates []*models.Artifact
+	for _, artifact := range f.artifacts {
+		if artifact.Kind == models.KindPatchSet && artifact.Depth != nil && *artifact.Depth >= depthThreshold {
+			candidates = append(candidates, artifact)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return *candidates[i].Depth > *candidates[j].Depth })
+	return candidates, nil
+}
+
+// fakeTagStore is an in-memory tagStore
+type fakeTagStore struct {
+	tags  map[string]*models.Tag
+	moves []*models.TagMove
+}
+
+func newFakeTagStore() *fakeTagStore {
+	return &fakeTagStore{tags: make(map[string]*models.Tag)}
+}
+
+func (f *fakeTagStore) Create(ctx context.Context, tag *models.Tag) error {
+	stored := *tag
+	f.tags[tag.Username+"/"+tag.TagName] = &stored
+	return nil
+}
+
+func (f *fakeTagStore) GetByName(ctx context.Context, username, tagName string) (*models.Tag, error) {
+	tag, ok := f.tags[username+"/"+tagName]
+	if !ok {
+		// Like TagRepository.GetByName, which wraps the row scan error
+		return nil, fmt.Errorf("tag %s/%s: %w", username, tagName, pgx.ErrNoRows)
+	}
+	copied := *tag
+	return &copied, nil
+}
+
+func (f *fakeTagStore) Update(ctx context.Context, tag *models.Tag) error {
+	existing, ok := f.tags[tag.Username+"/"+tag.TagName]
+	if !ok {
+		return fmt.Errorf("tag not found: %s/%s", tag.Username, tag.TagName)
+	}
+	existing.TargetKind = tag.TargetKind
+	existing.TargetID = tag.TargetID
+	existing.TargetHash = tag.TargetHash
+	existing.Version++
+	tag.Version = existing.Version
+	return nil
+}
+
+func (f *fakeTagStore) CompareAndSwap(ctx context.Context, username, tagName string, expectedVersion int64, newTarget uuid.UUID, newTargetKind, newTargetHash, movedBy string) (bool, error) {
+	existing, ok := f.tags[username+"/"+tagName]
+	if !ok || existing.Version != expectedVersion {
+		return false, nil
+	}
+	existing.TargetKind = models.ArtifactKind(newTargetKind)
+	existing.TargetID = newTarget
+	existing.TargetHash = &newTargetHash
+	existing.Version++
+	return true, nil
+}
+
+func (f *fakeTagStore) ListByTarget(ctx context.Context, targetKind string, targetID uuid.UUID) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	for _, tag := range f.tags {
+		if string(tag.TargetKind) == targetKind && tag.TargetID == targetID {
+			copied := *tag
+			tags = append(tags, &copied)
+		}
+	}
+	return tags, nil
+}
+
+func (f *fakeTagStore) Delete(ctx context.Context, username, tagName string) error {
+	delete(f.tags, username+"/"+tagName)
+	return nil
+}
+
+func (f *fakeTagStore) ListByUsername(ctx context.Context, username string) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	for _, tag := range f.tags {
+		if tag.Username == username {
+			copied := *tag
+			tags = append(tags, &copied)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].TagName < tags[j].TagName })
+	return tags, nil
+}
+
+func (f *fakeTagStore) Exists(ctx context.Context, username, tagName string) (bool, error) {
+	_, ok := f.tags[username+"/"+tagName]
+	return ok, nil
+}
+
+func (f *fakeTagStore) GetHistory(ctx context.Context, username, tagName string, limit int) ([]*models.TagMove, error) {
+	// Newest first, like the repository
+	var history []*models.TagMove
+	for i := len(f.moves) - 1; i >= 0 && len(history) < limit; i-- {
+		if f.moves[i].Username == username && f.moves[i].TagName == tagName {
+			copied := *f.moves[i]
+			history = append(history, &copied)
+		}
+	}
+	return history, nil
+}
+
+func (f *fakeTagStore) ListHistory(ctx context.Context, username, tagName string, limit int, cursor string) (*repository.TagHistoryPage, error) {
+	// Newest first, like the repository; cursors aren't supported
+	history, err := f.GetHistory(ctx, username, tagName, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.TagHistoryPage{Moves: history}, nil
+}
+
+func (f *fakeTagStore) RecordMove(ctx context.Context, move *models.TagMove) error {
+	stored := *move
+	stored.ID = int64(len(f.moves) + 1)
+	f.moves = append(f.moves, &stored)
+	move.ID = stored.ID
+	return nil
+}
+
+// fakeAuditStore is an in-memory auditStore
+type fakeAuditStore struct {
+	entries []*models.AuditEntry
+}
+
+func (f *fakeAuditStore) Append(ctx context.Context, entry *models.AuditEntry) error {
+	stored := *entry
+	stored.ID = int64(len(f.entries) + 1)
+	f.entries = append(f.entries, &stored)
+	entry.ID = stored.ID
+	return nil
+}
+
+func (f *fakeAuditStore) List(ctx context.Context, opts repository.AuditListOptions) (*repository.AuditPage, error) {
+	// Newest first, like the repository; cursors aren't supported
+	page := &repository.AuditPage{Entries: []*models.AuditEntry{}}
+	for i := len(f.entries) - 1; i >= 0; i-- {
+		entry := f.entries[i]
+		if (opts.Actor != "" && entry.Actor != opts.Actor) ||
+			(opts.TagOwner != "" && (entry.TagOwner == nil || *entry.TagOwner != opts.TagOwner)) ||
+			(opts.TagName != "" && (entry.TagName == nil || *entry.TagName != opts.TagName)) ||
+			entry.CreatedAt.Before(opts.Since) {
+			continue
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	return page, nil
+}
+
+// fakeUserDataStore is an in-memory userDataStore over a fakeTagStore and runs
+type fakeUserDataStore struct {
+	tags *fakeTagStore
+	runs []*models.Run
+}
+
+func (f *fakeUserDataStore) PurgeUser(ctx context.Context, username string) (*repository.UserPurge, error) {
+	purge := &repository.UserPurge{Tags: []string{}, Runs: []uuid.UUID{}}
+
+	for _, run := range f.runs {
+		if run.SubmittedBy != nil && *run.SubmittedBy == username {
+			switch run.Status {
+			case models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+			default:
+				return nil, fmt.Errorf("%w: %s", repository.ErrUserHasActiveRuns, run.RunID)
+			}
+		}
+	}
+
+	for key, tag := range f.tags.tags {
+		if tag.Username == username {
+			purge.Tags = append(purge.Tags, tag.TagName)
+			delete(f.tags.tags, key)
+		}
+	}
+	sort.Strings(purge.Tags)
+
+	var moves []*models.TagMove
+	for _, move := range f.tags.moves {
+		if move.Username == username {
+			purge.TagMoves++
+			continue
+		}
+		moves = append(moves, move)
+	}
+	f.tags.moves = moves
+
+	var runs []*models.Run
+	for _, run := range f.runs {
+		if run.SubmittedBy != nil && *run.SubmittedBy == username {
+			purge.Runs = append(purge.Runs, run.RunID)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	f.runs = runs
+
+	return purge, nil
+}
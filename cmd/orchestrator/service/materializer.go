@@ -3,16 +3,22 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/lyzr/orchestrator/common/models"
 	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
 )
 
 // MaterializerService handles workflow materialization (base + patches)
 type MaterializerService struct {
 	log *logger.Logger
+
+	// Optional cache of materialized patch chains, see WithCache
+	cas   *CASService
+	redis *rediscommon.Client
 }
 
 // NewMaterializerService creates a new materializer service
@@ -50,6 +56,16 @@ func (s *MaterializerService) materializePatchSet(ctx context.Context, component
 		return s.unmarshalWorkflow(components.BaseContent)
 	}
 
+	// Identical chains materialize identically, so a cached result can be reused
+	var hash string
+	if s.cas != nil && components.BaseCASID != "" {
+		hash = chainHash(components)
+		if cached, ok := s.loadMaterialized(ctx, hash); ok {
+			s.log.Debug("using cached materialization", "chain_hash", hash, "patches", len(components.PatchChain))
+			return s.unmarshalWorkflow(cached)
+		}
+	}
+
 	// Start with base workflow
 	currentJSON := components.BaseContent
 
@@ -75,11 +91,17 @@ func (s *MaterializerService) materializePatchSet(ctx context.Context, component
 
 	s.log.Info("materialization complete", "patches_applied", len(components.PatchChain))
 
+	if hash != "" {
+		s.storeMaterialized(ctx, hash, currentJSON)
+	}
+
 	// Parse final result
 	return s.unmarshalWorkflow(currentJSON)
 }
 
 // applyPatch applies a JSON Patch to the workflow
+// All RFC 6902 operations replay here, including move, copy and test; test ops
+// already passed when the patch was saved, so they only fail on a corrupt chain.
 func (s *MaterializerService) applyPatch(workflowJSON []byte, patchJSON []byte) ([]byte, error) {
 	// Parse the patch operations
 	patch, err := jsonpatch.DecodePatch(patchJSON)
@@ -89,6 +111,9 @@ func (s *MaterializerService) applyPatch(workflowJSON []byte, patchJSON []byte)
 
 	// Apply the patch
 	modifiedJSON, err := patch.Apply(workflowJSON)
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return nil, fmt.Errorf("patch test operation no longer holds: %w", err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to apply patch operations: %w", err)
 	}
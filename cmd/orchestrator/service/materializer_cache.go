@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+// materializedCacheTTL bounds how long an unused chain's cache entry is kept
+// Entries never go stale: the key is a hash of the chain's CAS IDs.
+const materializedCacheTTL = 24 * time.Hour
+
+// materializedKey returns the Redis key pointing a patch chain at its materialized CAS blob
+func materializedKey(chainHash string) string {
+	return fmt.Sprintf("materialized:%s", chainHash)
+}
+
+// WithCache caches materialized patch chains in CAS
+// The result is stored as a CAS blob, indexed in Redis by the chain hash, so a
+// chain is only replayed once until compaction folds it into a new base. If
+// the blob was garbage collected, the chain is simply replayed again.
+func (s *MaterializerService) WithCache(cas *CASService, redis *rediscommon.Client) *MaterializerService {
+	s.cas = cas
+	s.redis = redis
+	return s
+}
+
+// chainHash identifies a patch chain by its base and ordered patch CAS IDs
+func chainHash(components *models.WorkflowComponents) string {
+	ids := make([]string, 0, len(components.PatchChain)+1)
+	ids = append(ids, components.BaseCASID)
+	for _, patch := range components.PatchChain {
+		ids = append(ids, patch.CASID)
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(strings.Join(ids, "\n"))))
+}
+
+// loadMaterialized returns the cached materialization of a chain, if any
+// Any failure is a miss: the chain is replayed instead.
+func (s *MaterializerService) loadMaterialized(ctx context.Context, hash string) ([]byte, bool) {
+	casID, err := s.redis.Get(ctx, materializedKey(hash))
+	if err != nil {
+		return nil, false
+	}
+
+	content, err := s.cas.GetContent(ctx, casID)
+	if err != nil {
+		s.log.Debug("cached materialization unavailable", "chain_hash", hash, "cas_id", casID, "error", err)
+		return nil, false
+	}
+	return content, true
+}
+
+// storeMaterialized caches a chain's materialization
+// Failures are logged; the result is returned to the caller either way.
+func (s *MaterializerService) storeMaterialized(ctx context.Context, hash string, content []byte) {
+	casID, err := s.cas.StoreContent(ctx, content, "application/json;type=dag")
+	if err == nil {
+		err = s.redis.SetWithExpiry(ctx, materializedKey(hash), casID, materializedCacheTTL)
+	}
+	if err != nil {
+		s.log.Warn("failed to cache materialized workflow", "chain_hash", hash, "error", err)
+	}
+}
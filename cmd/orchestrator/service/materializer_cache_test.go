@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+func TestMaterializerService_CachesPatchChains(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	cas := newTestCASService(newFakeCASStore(), "none", 0)
+	materializer := NewMaterializerService(log).WithCache(cas, rediscommon.NewClient(rdb, log))
+
+	components := func(patches ...string) *models.WorkflowComponents {
+		c := &models.WorkflowComponents{
+			Kind:        models.KindPatchSet,
+			BaseCASID:   "sha256:base",
+			BaseContent: []byte(`{"nodes": [{"id": "a"}], "edges": []}`),
+			PatchCount:  len(patches),
+		}
+		for i, patch := range patches {
+			c.PatchChain = append(c.PatchChain, models.PatchInfo{
+				Seq:        i + 1,
+				ArtifactID: uuid.New(),
+				CASID:      "sha256:" + patch,
+				Content:    []byte(`[{"op": "add", "path": "/nodes/-", "value": {"id": "` + patch + `"}}]`),
+			})
+		}
+		return c
+	}
+
+	first, err := materializer.Materialize(ctx, components("b", "c"))
+	require.NoError(t, err)
+	assert.Len(t, first["nodes"], 3)
+
+	// Same chain: served from cache, so patches that no longer apply are never replayed
+	cached := components("b", "c")
+	for i := range cached.PatchChain {
+		cached.PatchChain[i].Content = []byte(`[{"op": "remove", "path": "/missing"}]`)
+	}
+	second, err := materializer.Materialize(ctx, cached)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// A different chain misses the cache and is replayed
+	_, err = materializer.Materialize(ctx, components("c", "b"))
+	require.NoError(t, err)
+	_, err = materializer.Materialize(ctx, &models.WorkflowComponents{
+		Kind:        models.KindPatchSet,
+		BaseCASID:   "sha256:base",
+		BaseContent: []byte(`{"nodes": []}`),
+		PatchCount:  1,
+		PatchChain:  []models.PatchInfo{{Seq: 1, CASID: "sha256:bad", Content: []byte(`[{"op": "remove", "path": "/missing"}]`)}},
+	})
+	assert.Error(t, err)
+
+	t.Run("expired entry is a miss", func(t *testing.T) {
+		mr.FlushAll()
+		_, err := materializer.Materialize(ctx, cached)
+		assert.Error(t, err)
+	})
+}
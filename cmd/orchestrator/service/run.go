@@ -3,29 +3,49 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/metrics"
 	"github.com/lyzr/orchestrator/common/models"
 	"github.com/lyzr/orchestrator/common/repository"
 	"github.com/lyzr/orchestrator/common/bootstrap"
 	"github.com/lyzr/orchestrator/common/ratelimit"
 	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
+	"github.com/lyzr/orchestrator/common/worker"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // RunService handles business logic for workflow runs
 type RunService struct {
 	runRepo         *repository.RunRepository
+	statusStore     runStatusStore // runRepo; replaced in tests
+	runStore        runCreateStore // runRepo; replaced in tests
 	artifactRepo    *repository.ArtifactRepository
+	artifactStore   artifactStore // artifactRepo; replaced in tests
 	casService      *CASService
 	workflowSvc     *WorkflowServiceV2
 	materializerSvc *MaterializerService
 	runPatchService *RunPatchService
 	components      *bootstrap.Components
 	redis           *rediscommon.Client
+	results         clients.CASClient // Node results written by the coordinator
 	rateLimiter     *ratelimit.RateLimiter
+	audit           *AuditService
+	inspect         func(map[string]interface{}) ratelimit.WorkflowProfile // ratelimit.InspectWorkflow; replaced in tests
+}
+
+// runCreateStore is the subset of RunRepository used by CreateRun
+type runCreateStore interface {
+	Create(ctx context.Context, run *models.Run) error
 }
 
 // RunServiceOpts contains options for creating a RunService
@@ -38,29 +58,47 @@ type RunServiceOpts struct {
 	RunPatchService *RunPatchService
 	Components      *bootstrap.Components
 	Redis           *rediscommon.Client
+	ResultCAS       clients.CASClient // Optional; defaults to Redis CAS over Redis
 	RateLimiter     *ratelimit.RateLimiter
+	Audit           *AuditService // Optional
 }
 
 // NewRunService creates a new run service with options pattern
 func NewRunService(opts *RunServiceOpts) *RunService {
+	results := opts.ResultCAS
+	if results == nil && opts.Redis != nil && opts.Components != nil {
+		results = clients.NewRedisCASClient(opts.Redis.GetUnderlying(), opts.Components.Logger)
+	}
+
 	return &RunService{
 		runRepo:         opts.RunRepo,
+		statusStore:     opts.RunRepo,
+		runStore:        opts.RunRepo,
 		artifactRepo:    opts.ArtifactRepo,
+		artifactStore:   opts.ArtifactRepo,
 		casService:      opts.CASService,
 		workflowSvc:     opts.WorkflowSvc,
 		materializerSvc: opts.MaterializerSvc,
 		runPatchService: opts.RunPatchService,
 		components:      opts.Components,
 		redis:           opts.Redis,
+		results:         results,
 		rateLimiter:     opts.RateLimiter,
+		audit:           opts.Audit,
+		inspect:         ratelimit.InspectWorkflow,
 	}
 }
 
 // CreateRunRequest represents a request to create a workflow run
 type CreateRunRequest struct {
-	Tag      string                 `json:"tag"`
-	Username string                 `json:"username"`
-	Inputs   map[string]interface{} `json:"inputs"`
+	Tag            string                 `json:"tag"`
+	Username       string                 `json:"username"`
+	Inputs         map[string]interface{} `json:"inputs"`
+	Priority       string                 `json:"priority,omitempty"`        // high, normal (default) or low
+	MaxConcurrency int                    `json:"max_concurrency,omitempty"` // Tokens in flight at once; 0 uses the workflow's
+
+	// IdempotencyKey makes retries of the same submission return the same run
+	IdempotencyKey string `json:"-"`
 }
 
 // CreateRunResponse represents the response after creating a run
@@ -69,23 +107,56 @@ type CreateRunResponse struct {
 	ArtifactID uuid.UUID `json:"artifact_id"`
 	Status     string    `json:"status"`
 	Tag        string    `json:"tag"`
+	Replayed   bool      `json:"-"` // Returned for an idempotency key already used
 }
 
 // RateLimitError represents a rate limit exceeded error
+// Dimension says which limit was hit: the user's tier, the workflow tag or the org.
 type RateLimitError struct {
 	Tier              ratelimit.WorkflowTier
+	Dimension         ratelimit.Dimension
+	Target            string // Tag or org whose limit was hit
 	Limit             int64
 	CurrentCount      int64
 	RetryAfterSeconds int64
 }
 
 func (e *RateLimitError) Error() string {
+	switch e.Dimension {
+	case ratelimit.DimensionWorkflow:
+		return fmt.Sprintf("rate limit exceeded: workflow %s allows %d runs/minute, retry after %d seconds",
+			e.Target, e.Limit, e.RetryAfterSeconds)
+	case ratelimit.DimensionOrg:
+		return fmt.Sprintf("rate limit exceeded: org %s allows %d runs/minute, retry after %d seconds",
+			e.Target, e.Limit, e.RetryAfterSeconds)
+	}
 	return fmt.Sprintf("rate limit exceeded: %s tier allows %d runs/minute, retry after %d seconds",
 		e.Tier, e.Limit, e.RetryAfterSeconds)
 }
 
+// workflowOrg returns the org a workflow's metadata assigns it to, if any
+func workflowOrg(workflow map[string]interface{}) string {
+	metadata, _ := workflow["metadata"].(map[string]interface{})
+	org, _ := metadata["org"].(string)
+	return org
+}
+
 // CreateRun creates a new workflow run with materialized workflow
+// With an idempotency key, a repeated submission returns the original run
+// instead of creating another one (see createRunOnce).
 func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*CreateRunResponse, error) {
+	if req.IdempotencyKey != "" {
+		return s.createRunOnce(ctx, req, s.createRun)
+	}
+	return s.createRun(ctx, req)
+}
+
+// createRun materializes the workflow and creates and publishes a new run
+func (s *RunService) createRun(ctx context.Context, req *CreateRunRequest) (*CreateRunResponse, error) {
+	// Root span of the run's trace; the run request carries it to the runner
+	ctx, span := tracing.StartSpan(ctx, "run.create", "tag", req.Tag)
+	defer span.End()
+
 	s.components.Logger.Info("creating workflow run",
 		"tag", req.Tag,
 		"username", req.Username)
@@ -107,15 +178,37 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 		return nil, fmt.Errorf("failed to materialize workflow: %w", err)
 	}
 
+	// 2.1. Inline subworkflow nodes so the run executes one flat graph
+	materializedWorkflow, err = s.workflowSvc.ExpandSubworkflows(ctx, req.Username, req.Tag, materializedWorkflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand subworkflows: %w", err)
+	}
+
+	// 2.2. Serialize the materialized workflow; its hash is the version's CAS ID
+	workflowJSON, err := json.Marshal(materializedWorkflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+
+	// 2.3. Reject runs missing inputs the workflow declares, before anything is created
+	if err := validateRunInputs(materializedWorkflow, req.Inputs); err != nil {
+		return nil, err
+	}
+
 	// 2.5. Check rate limit based on workflow complexity (agent-aware)
-	profile := ratelimit.InspectWorkflow(materializedWorkflow)
+	profile := s.workflowProfile(ctx, s.casService.ComputeHash(workflowJSON), materializedWorkflow)
 	s.components.Logger.Info("workflow inspected for rate limiting",
 		"tier", profile.Tier,
 		"agent_count", profile.AgentCount,
 		"total_nodes", profile.TotalNodes)
 
-	// Check tiered rate limit (separate counters per tier)
-	result, err := s.rateLimiter.CheckTieredLimit(ctx, req.Username, profile.Tier)
+	// Check tiered rate limit (separate counters per tier), plus any tag and org limits
+	result, err := s.rateLimiter.CheckCompositeLimit(ctx, ratelimit.CompositeRequest{
+		Username:    req.Username,
+		Tier:        profile.Tier,
+		WorkflowTag: req.Tag,
+		Org:         workflowOrg(materializedWorkflow),
+	})
 	if err != nil {
 		s.components.Logger.Error("rate limit check failed", "error", err)
 		// On error, allow request (fail open for availability)
@@ -123,25 +216,23 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 		s.components.Logger.Warn("rate limit exceeded",
 			"username", req.Username,
 			"tier", profile.Tier,
+			"dimension", result.Dimension,
+			"target", result.Target,
 			"limit", result.Limit,
 			"current", result.CurrentCount,
 			"retry_after", result.RetryAfterSeconds)
 
 		return nil, &RateLimitError{
 			Tier:              profile.Tier,
+			Dimension:         result.Dimension,
+			Target:            result.Target,
 			Limit:             result.Limit,
 			CurrentCount:      result.CurrentCount,
 			RetryAfterSeconds: result.RetryAfterSeconds,
 		}
 	}
 
-	// 3. Store materialized workflow as artifact
-	workflowJSON, err := json.Marshal(materializedWorkflow)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal workflow: %w", err)
-	}
-
-	// 4. Store workflow in CAS
+	// 4. Store workflow in CAS (serialized in step 2.2)
 	casID, err := s.casService.StoreContent(ctx, workflowJSON, "application/json;type=workflow")
 	if err != nil {
 		return nil, fmt.Errorf("failed to store workflow in CAS: %w", err)
@@ -158,7 +249,7 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 		Meta:        make(map[string]interface{}), // Required field
 	}
 
-	if err := s.artifactRepo.Create(ctx, artifact); err != nil {
+	if err := s.artifactStore.Create(ctx, artifact); err != nil {
 		return nil, fmt.Errorf("failed to create artifact: %w", err)
 	}
 
@@ -166,11 +257,10 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 		"artifact_id", artifact.ArtifactID,
 		"cas_id", casID)
 
-	// 6. Get current tag positions for snapshot
-	// TODO: Implement GetAllTagPositions in WorkflowService
-	// For now, just record the requested tag
-	tagsSnapshot := map[string]string{
-		req.Tag: artifact.ArtifactID.String(),
+	// 6. Snapshot every tag position (subworkflows resolve other tags)
+	tagsSnapshot, err := s.workflowSvc.GetAllTagPositions(ctx, req.Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot tag positions: %w", err)
 	}
 
 	// 7. Create run entry
@@ -185,10 +275,16 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 		SubmittedAt:  time.Now(),
 	}
 
-	if err := s.runRepo.Create(ctx, run); err != nil {
+	if err := s.runStore.Create(ctx, run); err != nil {
 		return nil, fmt.Errorf("failed to create run: %w", err)
 	}
 
+	span.SetAttributes(attribute.String(tracing.AttrRunID, runID.String()))
+
+	s.audit.Record(ctx, req.Username, models.AuditActionRunCreate, runTarget(runID.String(), req.Username, req.Tag), map[string]interface{}{
+		"artifact_id": artifact.ArtifactID.String(),
+	})
+
 	s.components.Logger.Info("run created",
 		"run_id", runID,
 		"artifact_id", artifact.ArtifactID,
@@ -196,12 +292,14 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 
 	// 8. Publish to wf.run.requests stream
 	runRequest := map[string]interface{}{
-		"run_id":      runID.String(),
-		"artifact_id": artifact.ArtifactID.String(),
-		"tag":         req.Tag,
-		"username":    req.Username,
-		"inputs":      req.Inputs,
-		"created_at":  time.Now().Unix(),
+		"run_id":          runID.String(),
+		"artifact_id":     artifact.ArtifactID.String(),
+		"tag":             req.Tag,
+		"username":        req.Username,
+		"inputs":          req.Inputs,
+		"priority":        req.Priority,
+		"max_concurrency": req.MaxConcurrency,
+		"created_at":      time.Now().Unix(),
 	}
 
 	requestJSON, err := json.Marshal(runRequest)
@@ -209,6 +307,13 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 		return nil, fmt.Errorf("failed to marshal run request: %w", err)
 	}
 
+	// Keep the inputs next to the run state so the run can be exported as a fixture
+	if inputsJSON, err := json.Marshal(req.Inputs); err == nil {
+		if err := s.redis.SetWithExpiry(ctx, sdk.InputsKey(runID.String()), string(inputsJSON), sdk.RunStateTTL); err != nil {
+			s.components.Logger.Warn("failed to store run inputs", "run_id", runID, "error", err)
+		}
+	}
+
 	_, err = s.redis.AddToStream(ctx, "wf.run.requests", map[string]interface{}{
 		"request": string(requestJSON),
 	})
@@ -220,6 +325,8 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 		"run_id", runID,
 		"stream", "wf.run.requests")
 
+	metrics.RunsCreated.Inc(profile.Tier.String())
+
 	return &CreateRunResponse{
 		RunID:      runID,
 		ArtifactID: artifact.ArtifactID,
@@ -228,19 +335,291 @@ func (s *RunService) CreateRun(ctx context.Context, req *CreateRunRequest) (*Cre
 	}, nil
 }
 
+// ErrRunNotFound is returned for a run whose state is not in Redis
+// Unknown runs, and runs whose state has expired since they finished.
+var ErrRunNotFound = errors.New("run not found")
+
 // GetRun retrieves a run by ID
 func (s *RunService) GetRun(ctx context.Context, runID uuid.UUID) (*models.Run, error) {
 	return s.runRepo.GetByID(ctx, runID)
 }
 
+// Node cancellation errors
+var (
+	ErrRunNotActive       = errors.New("run is not active")
+	ErrNodeNotFound       = errors.New("node not found in run")
+	ErrNodeNotCancellable = errors.New("node is not in flight")
+)
+
+// CancelNode cancels a single in-flight node without cancelling the run
+// The node is marked cancelled and a synthetic completion is queued so the
+// coordinator balances the counter; sibling branches continue normally.
+func (s *RunService) CancelNode(ctx context.Context, runID uuid.UUID, nodeID, username string) error {
+	// 1. Run must exist and still be active
+	if _, err := s.activeRunStatus(ctx, runID); err != nil {
+		return err
+	}
+
+	// 2. Node must exist in the (possibly patched) IR
+	workflowIR, err := s.loadWorkflowIR(ctx, runID)
+	if err != nil {
+		return err
+	}
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+	if _, exists := nodes[nodeID]; !exists {
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, nodeID)
+	}
+
+	// 3. Atomically claim the node (only running/waiting nodes can be cancelled)
+	cancelled, previous, err := sdk.CancelNode(ctx, s.redis.GetUnderlying(), runID.String(), nodeID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		if previous == "" {
+			previous = "not started"
+		}
+		return fmt.Errorf("%w: node %s is %s", ErrNodeNotCancellable, nodeID, previous)
+	}
+
+	// 4. Queue synthetic completion so the coordinator consumes the node's token
+	err = worker.SignalCancellation(ctx, s.redis.GetUnderlying(), s.components.Logger, runID.String(), nodeID, map[string]interface{}{
+		"reason":          "cancelled_by_user",
+		"cancelled_by":    username,
+		"previous_status": previous,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to signal cancellation: %w", err)
+	}
+
+	s.audit.Record(ctx, username, models.AuditActionRunCancelNode, runTarget(runID.String(), "", ""), map[string]interface{}{
+		"node_id":         nodeID,
+		"previous_status": previous,
+	})
+
+	s.components.Logger.Info("node cancelled",
+		"run_id", runID,
+		"node_id", nodeID,
+		"previous_status", previous,
+		"cancelled_by", username)
+
+	return nil
+}
+
+// activeRunStatus returns the current status of a run that hasn't finished
+func (s *RunService) activeRunStatus(ctx context.Context, runID uuid.UUID) (models.RunStatus, error) {
+	status, err := s.GetRunStatus(ctx, runID)
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+		return status, fmt.Errorf("%w: run is %s", ErrRunNotActive, status)
+	}
+	return status, nil
+}
+
+// GetRunStatus returns a run's current status
+// The hot status in Redis wins over the DB status, which lags behind.
+func (s *RunService) GetRunStatus(ctx context.Context, runID uuid.UUID) (models.RunStatus, error) {
+	run, err := s.runRepo.GetByID(ctx, runID)
+	if err != nil {
+		return "", fmt.Errorf("run not found: %w", err)
+	}
+
+	status := run.Status
+	if hotStatus, err := s.redis.Get(ctx, fmt.Sprintf("run:status:%s", runID.String())); err == nil && hotStatus != "" {
+		status = models.RunStatus(hotStatus)
+	}
+	return status, nil
+}
+
+// Pause errors
+var (
+	ErrRunAlreadyPaused = errors.New("run is already paused")
+	ErrRunNotPaused     = errors.New("run is not paused")
+)
+
+// PauseRun stops a run from scheduling new nodes
+// Nodes already in flight finish normally; the coordinator buffers their
+// completion signals instead of routing them until the run is resumed.
+func (s *RunService) PauseRun(ctx context.Context, runID uuid.UUID, username string) error {
+	if _, err := s.activeRunStatus(ctx, runID); err != nil {
+		return err
+	}
+
+	paused, err := sdk.PauseRun(ctx, s.redis.GetUnderlying(), runID.String())
+	if err != nil {
+		return err
+	}
+	if !paused {
+		return ErrRunAlreadyPaused
+	}
+
+	if err := s.redis.SetWithExpiry(ctx, fmt.Sprintf("run:status:%s", runID.String()), string(models.StatusPaused), 24*time.Hour); err != nil {
+		s.components.Logger.Warn("failed to set paused run status", "run_id", runID, "error", err)
+	}
+
+	s.audit.Record(ctx, username, models.AuditActionRunPause, runTarget(runID.String(), "", ""), nil)
+
+	s.components.Logger.Info("run paused",
+		"run_id", runID,
+		"paused_by", username)
+
+	return nil
+}
+
+// ResumeRun resumes a paused run
+// Completion signals buffered while paused are replayed onto the completion
+// queue, so the tokens they would have emitted are emitted now.
+func (s *RunService) ResumeRun(ctx context.Context, runID uuid.UUID, username string) error {
+	if _, err := s.activeRunStatus(ctx, runID); err != nil {
+		return err
+	}
+
+	resumed, replayed, err := sdk.ResumeRun(ctx, s.redis.GetUnderlying(), runID.String())
+	if err != nil {
+		return err
+	}
+	if !resumed {
+		return ErrRunNotPaused
+	}
+
+	if err := s.redis.SetWithExpiry(ctx, fmt.Sprintf("run:status:%s", runID.String()), string(models.StatusRunning), 24*time.Hour); err != nil {
+		s.components.Logger.Warn("failed to set resumed run status", "run_id", runID, "error", err)
+	}
+
+	s.audit.Record(ctx, username, models.AuditActionRunResume, runTarget(runID.String(), "", ""), nil)
+
+	s.components.Logger.Info("run resumed",
+		"run_id", runID,
+		"replayed_signals", replayed,
+		"resumed_by", username)
+
+	return nil
+}
+
+// Retry errors
+var (
+	ErrRunNotRetryable  = errors.New("run is not retryable")
+	ErrNodeNotRetryable = errors.New("node cannot be retried")
+)
+
+// RetryRun re-runs a failed run from one node, keeping the run's ID and context
+// fromNode defaults to the node that failed. The coordinator resets that node
+// and everything downstream of it and re-emits its token; outputs of nodes
+// upstream are reused rather than recomputed. Returns the retried node.
+func (s *RunService) RetryRun(ctx context.Context, runID uuid.UUID, fromNode, username string) (string, error) {
+	// 1. Only failed runs can be retried
+	status, err := s.GetRunStatus(ctx, runID)
+	if err != nil {
+		return "", err
+	}
+	if status != models.StatusFailed {
+		return "", fmt.Errorf("%w: run is %s", ErrRunNotRetryable, status)
+	}
+
+	// 2. Resolve the node to retry from
+	workflowIR, err := s.loadWorkflowIR(ctx, runID)
+	if err != nil {
+		return "", err
+	}
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+
+	if fromNode == "" {
+		fromNode = s.findFailedNode(ctx, runID, nodes)
+		if fromNode == "" {
+			return "", fmt.Errorf("%w: no failed node to retry", ErrNodeNotFound)
+		}
+	}
+	if _, exists := nodes[fromNode]; !exists {
+		return "", fmt.Errorf("%w: %s", ErrNodeNotFound, fromNode)
+	}
+	if !retryableNodes(nodes)[fromNode] {
+		return "", fmt.Errorf("%w: %s is run by the coordinator", ErrNodeNotRetryable, fromNode)
+	}
+
+	// 3. Hand over to the coordinator through the completion queue
+	err = worker.SignalRetry(ctx, s.redis.GetUnderlying(), s.components.Logger, runID.String(), fromNode, map[string]interface{}{
+		"reason":     "retried_by_user",
+		"retried_by": username,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to signal retry: %w", err)
+	}
+
+	s.audit.Record(ctx, username, models.AuditActionRunRetry, runTarget(runID.String(), "", ""), map[string]interface{}{
+		"node_id": fromNode,
+	})
+
+	s.components.Logger.Info("run retry requested",
+		"run_id", runID,
+		"node_id", fromNode,
+		"retried_by", username)
+
+	return fromNode, nil
+}
+
+// retryableNodes returns the IR nodes that run from their own token
+// Branch, loop and map nodes run inside the coordinator, and map bodies only
+// run as part of their map, so none of them can be retried on their own.
+func retryableNodes(nodes map[string]interface{}) map[string]bool {
+	retryable := make(map[string]bool, len(nodes))
+	bodies := make(map[string]bool)
+	for id, raw := range nodes {
+		node, _ := raw.(map[string]interface{})
+		if mapConfig, ok := node["map"].(map[string]interface{}); ok {
+			if body, ok := mapConfig["body"].(string); ok {
+				bodies[body] = true
+			}
+		}
+		retryable[id] = node["branch"] == nil && node["loop"] == nil && node["map"] == nil
+	}
+	for body := range bodies {
+		retryable[body] = false
+	}
+	return retryable
+}
+
+// hasErrorHandlers reports whether a node routes its failure to on_error handlers
+// Such a failure is handled by the workflow and doesn't fail the run.
+func hasErrorHandlers(workflowIR map[string]interface{}, nodeID string) bool {
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+	node, _ := nodes[nodeID].(map[string]interface{})
+	handlers, _ := node["on_error"].([]interface{})
+	return len(handlers) > 0
+}
+
+// findFailedNode returns the failed node to retry a run from, or ""
+// Retryable nodes are checked in ID order.
+func (s *RunService) findFailedNode(ctx context.Context, runID uuid.UUID, nodes map[string]interface{}) string {
+	retryable := retryableNodes(nodes)
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		if retryable[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		status, err := s.redis.Get(ctx, sdk.NodeStatusKey(runID.String(), id))
+		if err == nil && status == sdk.NodeStatusFailed {
+			return id
+		}
+	}
+	return ""
+}
+
 // UpdateRunStatus updates the status of a run
 func (s *RunService) UpdateRunStatus(ctx context.Context, runID uuid.UUID, status models.RunStatus) error {
 	return s.runRepo.UpdateStatus(ctx, runID, status)
 }
 
-// ListUserRuns lists runs for a specific user
-func (s *RunService) ListUserRuns(ctx context.Context, username string, limit int) ([]*models.Run, error) {
-	return s.runRepo.ListByUser(ctx, username, limit)
+// ListUserRuns lists a page of runs for a specific user
+// Filtering, sorting and the cursor are described by repository.RunListOptions.
+func (s *RunService) ListUserRuns(ctx context.Context, username string, opts repository.RunListOptions) (*repository.RunPage, error) {
+	return s.runRepo.ListByUser(ctx, username, opts)
 }
 
 // ListRunsForWorkflow lists runs for a specific workflow tag
@@ -249,13 +628,18 @@ func (s *RunService) ListRunsForWorkflow(ctx context.Context, tag string, limit
 }
 
 // RunDetails represents comprehensive run information
+// Run.Status is the persisted status and is authoritative; DerivedStatus is
+// derived from the node executions on read (see DeriveRunStatus).
 type RunDetails struct {
 	Run             *models.Run                   `json:"run"`
+	DerivedStatus   *DerivedStatus                `json:"derived_status"`
 	BaseWorkflowIR  map[string]interface{}        `json:"base_workflow_ir"` // Workflow before any patches
 	WorkflowIR      map[string]interface{}        `json:"workflow_ir"`      // Workflow after all patches
 	NodeExecutions  map[string]*NodeExecution     `json:"node_executions"`
 	NodeOutputsRaw  map[string]interface{}        `json:"node_outputs_raw,omitempty"` // Raw node outputs from Redis context
 	Patches         []PatchInfo                   `json:"patches,omitempty"`
+	CriticalPathMs  int64                         `json:"critical_path_ms"`        // Longest chain of node durations (see criticalPath)
+	CriticalPath    []string                      `json:"critical_path,omitempty"` // Nodes on that chain, first to last
 }
 
 // NodeExecution represents execution details for a single node
@@ -266,8 +650,12 @@ type NodeExecution struct {
 	Output      map[string]interface{} `json:"output,omitempty"`
 	StartedAt   *time.Time             `json:"started_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	DurationMs  *int64                 `json:"duration_ms,omitempty"` // Wall clock from StartedAt to CompletedAt
 	Error       *string                `json:"error,omitempty"`
+	Handled     bool                   `json:"handled,omitempty"` // Failure was routed to on_error handlers
 	Metrics     *ExecutionMetrics      `json:"metrics,omitempty"`
+	Iterations  []*LoopIterationDetail `json:"iterations,omitempty"`  // Loop nodes: every iteration, oldest first
+	ExitReason  string                 `json:"exit_reason,omitempty"` // Loop nodes: why the loop last exited (break or timeout)
 }
 
 // ExecutionMetrics represents performance metrics for node execution
@@ -349,6 +737,9 @@ func parseMetrics(metricsData map[string]interface{}) *ExecutionMetrics {
 func (s *RunService) loadWorkflowIR(ctx context.Context, runID uuid.UUID) (map[string]interface{}, error) {
 	irKey := fmt.Sprintf("ir:%s", runID.String())
 	irJSON, err := s.redis.Get(ctx, irKey)
+	if errors.Is(err, rediscommon.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: no IR for run %s", ErrRunNotFound, runID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load IR from Redis: %w", err)
 	}
@@ -399,100 +790,125 @@ func (s *RunService) loadContextData(ctx context.Context, runID uuid.UUID) (map[
 	return contextData, nil
 }
 
-// bulkFetchCASData fetches CAS data in bulk for the given node outputs
-func (s *RunService) bulkFetchCASData(ctx context.Context, contextData map[string]string, nodes map[string]interface{}) (map[string]map[string]interface{}, error) {
-	// Collect all CAS IDs for bulk fetch
-	casRefs := make([]string, 0)
-	casRefToNodeID := make(map[string]string)
+// ErrRunOutputUnavailable is returned when node outputs a request needs can't be read
+// Their CAS entries expired, failed verification or don't hold a JSON object.
+var ErrRunOutputUnavailable = errors.New("run output unavailable")
+
+// CASFetchResult is the outcome of a bulk fetch of node outputs from CAS
+// Callers decide what a partial fetch means for them: run details show what
+// there is, while results and fixtures need every output they use.
+type CASFetchResult struct {
+	Data    map[string]map[string]interface{} // Parsed output by CAS ref
+	Missing []string                          // Refs with no CAS entry (expired or never written)
+	Invalid []string                          // Refs whose entry fails verification or isn't a JSON object
+}
 
-	for nodeID := range nodes {
-		outputKey := nodeID + ":output"
-		if outputRef, exists := contextData[outputKey]; exists {
-			casRefs = append(casRefs, outputRef)
-			casRefToNodeID[outputRef] = nodeID
-		}
-	}
+// Unavailable returns true if the output stored under casRef couldn't be read
+func (r *CASFetchResult) Unavailable(casRef string) bool {
+	_, found := r.Data[casRef]
+	return !found && (slices.Contains(r.Missing, casRef) || slices.Contains(r.Invalid, casRef))
+}
 
-	casDataMap := make(map[string]map[string]interface{})
+// fetchCASOutputs fetches and parses node outputs from the result CAS in bulk
+// Nodes with identical outputs share a content-addressed ref, fetched once.
+func (s *RunService) fetchCASOutputs(ctx context.Context, casRefs []string) (*CASFetchResult, error) {
+	result := &CASFetchResult{Data: make(map[string]map[string]interface{}, len(casRefs))}
 	if len(casRefs) == 0 {
-		return casDataMap, nil
+		return result, nil
 	}
 
-	// Build cas keys
-	casKeys := make([]string, len(casRefs))
-	for i, casRef := range casRefs {
-		casKeys[i] = fmt.Sprintf("cas:%s", casRef)
+	unique := make([]string, 0, len(casRefs))
+	seen := make(map[string]bool, len(casRefs))
+	for _, casRef := range casRefs {
+		if !seen[casRef] {
+			seen[casRef] = true
+			unique = append(unique, casRef)
+		}
 	}
 
-	// Bulk GET with pipeline
-	casResults, err := s.redis.GetMultiple(ctx, casKeys)
+	fetched, err := clients.GetCASBulk(ctx, s.results, unique)
 	if err != nil {
 		return nil, fmt.Errorf("failed to bulk fetch CAS data: %w", err)
 	}
 
-	// Parse all CAS results
-	for casKey, data := range casResults {
-		// Extract casRef from "cas:{casRef}"
-		casRef := casKey[4:] // Remove "cas:" prefix
-
-		var result map[string]interface{}
-		if err := json.Unmarshal([]byte(data), &result); err != nil {
+	result.Missing = fetched.Missing
+	for casRef, err := range fetched.Unreadable {
+		s.components.Logger.Warn("failed to read CAS data",
+			"cas_ref", casRef,
+			"error", err)
+		result.Invalid = append(result.Invalid, casRef)
+	}
+	for casRef, data := range fetched.Found {
+		var output map[string]interface{}
+		if err := json.Unmarshal(data, &output); err != nil {
 			s.components.Logger.Warn("failed to unmarshal CAS data",
 				"cas_ref", casRef,
 				"error", err)
+			result.Invalid = append(result.Invalid, casRef)
 			continue
 		}
-		casDataMap[casRef] = result
+		result.Data[casRef] = output
+	}
+	sort.Strings(result.Invalid)
+
+	if len(result.Missing) > 0 || len(result.Invalid) > 0 {
+		s.components.Logger.Warn("some CAS outputs unavailable",
+			"requested", len(unique),
+			"missing", len(result.Missing),
+			"invalid", len(result.Invalid))
+	}
+
+	return result, nil
+}
+
+// bulkFetchCASData fetches CAS data in bulk for the given node outputs
+func (s *RunService) bulkFetchCASData(ctx context.Context, contextData map[string]string, nodes map[string]interface{}) (*CASFetchResult, error) {
+	casRefs := make([]string, 0)
+	for nodeID := range nodes {
+		if outputRef, exists := contextData[nodeID+":output"]; exists {
+			casRefs = append(casRefs, outputRef)
+		}
 	}
 
-	return casDataMap, nil
+	return s.fetchCASOutputs(ctx, casRefs)
 }
 
 // bulkFetchAllCASFromContext fetches ALL CAS references from context data (not limited to IR nodes)
-func (s *RunService) bulkFetchAllCASFromContext(ctx context.Context, contextData map[string]string) (map[string]map[string]interface{}, error) {
+func (s *RunService) bulkFetchAllCASFromContext(ctx context.Context, contextData map[string]string) (*CASFetchResult, error) {
 	// Collect all CAS references from ALL context keys ending with :output
 	casRefs := make([]string, 0)
 
 	for key, value := range contextData {
 		// Check if this is an output key and the value looks like a CAS reference
-		if strings.HasSuffix(key, ":output") && strings.HasPrefix(value, "artifact://") {
+		if strings.HasSuffix(key, ":output") && isResultRef(value) {
 			casRefs = append(casRefs, value)
 		}
 	}
 
-	casDataMap := make(map[string]map[string]interface{})
-	if len(casRefs) == 0 {
-		return casDataMap, nil
-	}
-
-	// Build cas keys
-	casKeys := make([]string, len(casRefs))
-	for i, casRef := range casRefs {
-		casKeys[i] = fmt.Sprintf("cas:%s", casRef)
-	}
-
-	// Bulk GET with pipeline
-	casResults, err := s.redis.GetMultiple(ctx, casKeys)
-	if err != nil {
-		return nil, fmt.Errorf("failed to bulk fetch CAS data: %w", err)
-	}
+	return s.fetchCASOutputs(ctx, casRefs)
+}
 
-	// Parse all CAS results
-	for casKey, data := range casResults {
-		// Extract casRef from "cas:{casRef}"
-		casRef := strings.TrimPrefix(casKey, "cas:")
+// isResultRef returns true for a node result's CAS ref
+// Results are content-addressed; artifact:// refs are from before results went
+// through the CAS client, and are still readable from Redis.
+func isResultRef(value string) bool {
+	return strings.HasPrefix(value, "sha256:") || strings.HasPrefix(value, "artifact://")
+}
 
-		var result map[string]interface{}
-		if err := json.Unmarshal([]byte(data), &result); err != nil {
-			s.components.Logger.Warn("failed to unmarshal CAS data",
-				"cas_ref", casRef,
-				"error", err)
-			continue
+// requireNodeOutputs fails with ErrRunOutputUnavailable if any of the nodes' outputs couldn't be read
+// Nodes that recorded no output aren't checked.
+func requireNodeOutputs(fetched *CASFetchResult, contextData map[string]string, nodeIDs []string) error {
+	var unavailable []string
+	for _, nodeID := range nodeIDs {
+		if casRef, ok := contextData[nodeID+":output"]; ok && fetched.Unavailable(casRef) {
+			unavailable = append(unavailable, nodeID)
 		}
-		casDataMap[casRef] = result
 	}
-
-	return casDataMap, nil
+	if len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		return fmt.Errorf("%w: nodes %s", ErrRunOutputUnavailable, strings.Join(unavailable, ", "))
+	}
+	return nil
 }
 
 // buildNodeExecutions builds the node execution map from workflow IR and node_outputs_raw
@@ -510,7 +926,13 @@ func (s *RunService) buildNodeExecutions(
 		return nodeExecutions
 	}
 
-	for nodeID := range nodes {
+	// Start times recorded by the workers
+	startedAt, err := s.redis.GetAllHash(ctx, sdk.NodeStartedKey(run.RunID.String()))
+	if err != nil {
+		s.components.Logger.Warn("failed to load node start times", "run_id", run.RunID, "error", err)
+	}
+
+	for nodeID, rawNode := range nodes {
 		execution := &NodeExecution{
 			NodeID: nodeID,
 			Status: "not_executed", // Default to not_executed
@@ -522,6 +944,13 @@ func (s *RunService) buildNodeExecutions(
 			execution.Status = nodeStatus
 		}
 
+		// Loop nodes: whether the loop broke out or ran out of iterations
+		if node, ok := rawNode.(map[string]interface{}); ok && node["loop"] != nil {
+			if exitReason, err := s.redis.Get(ctx, sdk.LoopExitKey(run.RunID.String(), nodeID)); err == nil {
+				execution.ExitReason = exitReason
+			}
+		}
+
 		// Check if node has output in node_outputs_raw
 		if outputData, exists := nodeOutputsRaw[nodeID]; exists {
 			if output, ok := outputData.(map[string]interface{}); ok {
@@ -578,6 +1007,13 @@ func (s *RunService) buildNodeExecutions(
 			}
 		}
 
+		applyNodeTiming(execution, startedAt[nodeID])
+
+		// Failures routed to on_error handlers don't fail the run
+		if execution.Status == sdk.NodeStatusFailed || execution.Status == "error" {
+			execution.Handled = hasErrorHandlers(workflowIR, nodeID)
+		}
+
 		nodeExecutions[nodeID] = execution
 	}
 
@@ -591,6 +1027,7 @@ func (s *RunService) buildNodeOutputsRaw(
 	casDataMap map[string]map[string]interface{},
 ) map[string]interface{} {
 	nodeOutputsRaw := make(map[string]interface{})
+	handedOut := make(map[string]bool)
 
 	// Iterate through all context data
 	for key, value := range contextData {
@@ -600,6 +1037,12 @@ func (s *RunService) buildNodeOutputsRaw(
 
 			// Try to get the actual output data from CAS
 			if output, found := casDataMap[value]; found {
+				// Outputs are masked per node, in place, so nodes sharing a
+				// content-addressed ref each get their own copy
+				if handedOut[value] {
+					output = copyOutput(output)
+				}
+				handedOut[value] = true
 				nodeOutputsRaw[nodeID] = output
 			} else {
 				// If not in CAS, store the reference itself
@@ -622,6 +1065,48 @@ func (s *RunService) buildNodeOutputsRaw(
 	return nodeOutputsRaw
 }
 
+// copyOutput deep-copies a node output decoded from JSON
+func copyOutput(output map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return output
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return output
+	}
+	return copied
+}
+
+// maskNodeOutputs masks fields covered by each node's redaction rules
+// "drop" fields are masked too, in case an output was stored before the rule
+// was added. Outputs are modified in place; node executions share the same maps.
+func maskNodeOutputs(workflowIR map[string]interface{}, nodeOutputsRaw map[string]interface{}) {
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+	for nodeID, raw := range nodes {
+		node, _ := raw.(map[string]interface{})
+		config, _ := node["config"].(map[string]interface{})
+
+		rules := maskRules(config)
+		if len(rules) == 0 {
+			continue
+		}
+
+		if output, ok := nodeOutputsRaw[nodeID].(map[string]interface{}); ok {
+			sdk.Redact(output, rules)
+		}
+	}
+}
+
+// maskRules returns a node's redaction rules, all as masks
+func maskRules(config map[string]interface{}) []sdk.RedactionRule {
+	rules := sdk.RedactionRules(config, sdk.RedactActionMask)
+	for _, rule := range sdk.RedactionRules(config, sdk.RedactActionDrop) {
+		rules = append(rules, sdk.RedactionRule{Path: rule.Path, Action: sdk.RedactActionMask})
+	}
+	return rules
+}
+
 // loadRunPatches loads patches for the given run with operations
 func (s *RunService) loadRunPatches(ctx context.Context, runID uuid.UUID) ([]PatchInfo, error) {
 	patches := []PatchInfo{}
@@ -680,13 +1165,14 @@ func (s *RunService) GetRunDetails(ctx context.Context, runID uuid.UUID) (*RunDe
 	}
 
 	// 5. Bulk fetch ALL CAS data from context (including dynamically added nodes)
+	// Outputs that can't be read are shown as their refs (see buildNodeOutputsRaw)
 	casDataMap := make(map[string]map[string]interface{})
 	if len(contextData) > 0 {
-		var err error
-		casDataMap, err = s.bulkFetchAllCASFromContext(ctx, contextData)
+		fetched, err := s.bulkFetchAllCASFromContext(ctx, contextData)
 		if err != nil {
 			s.components.Logger.Warn("failed to bulk fetch CAS data", "error", err)
-			casDataMap = make(map[string]map[string]interface{}) // Continue with empty CAS data
+		} else {
+			casDataMap = fetched.Data
 		}
 	}
 
@@ -694,12 +1180,14 @@ func (s *RunService) GetRunDetails(ctx context.Context, runID uuid.UUID) (*RunDe
 	var nodeOutputsRaw map[string]interface{}
 	if len(contextData) > 0 {
 		nodeOutputsRaw = s.buildNodeOutputsRaw(ctx, contextData, casDataMap)
+		maskNodeOutputs(workflowIR, nodeOutputsRaw)
 	}
 
 	// 7. Build node executions using nodeOutputsRaw as source of truth for status
 	var nodeExecutions map[string]*NodeExecution
 	if _, ok := workflowIR["nodes"].(map[string]interface{}); ok {
 		nodeExecutions = s.buildNodeExecutions(ctx, run, workflowIR, nodeOutputsRaw)
+		s.attachLoopIterations(ctx, runID, workflowIR, nodeExecutions)
 	} else {
 		nodeExecutions = make(map[string]*NodeExecution)
 	}
@@ -711,56 +1199,26 @@ func (s *RunService) GetRunDetails(ctx context.Context, runID uuid.UUID) (*RunDe
 		patches = []PatchInfo{} // Continue with empty patches
 	}
 
-	// 9. Enrich run status based on actual node execution state
+	// 9. Derive the live status from node execution state, next to the persisted one
 	// This provides real-time status without constantly updating the DB
-	hasWaitingNode := false
-	hasFailedNode := false
-	hasCompletedNode := false
-	totalNodes := len(nodeExecutions)
-	completedCount := 0
-
-	for _, execution := range nodeExecutions {
-		switch execution.Status {
-		case "waiting_for_approval":
-			hasWaitingNode = true
-		case "failed", "error": // Treat error same as failed
-			hasFailedNode = true
-		case "completed":
-			hasCompletedNode = true
-			completedCount++
+	derivedStatus := DeriveRunStatus(nodeExecutions)
+	if derivedStatus.Status != models.StatusFailed {
+		if paused, _ := sdk.IsRunPaused(ctx, s.redis.GetUnderlying(), runID.String()); paused {
+			derivedStatus.Status = models.StatusPaused
 		}
 	}
 
-	// Determine display status based on node execution state
-	displayStatus := run.Status
-
-	// Priority order (most important first):
-	// 1. Any node failed → FAILED
-	// 2. Any node waiting for approval → WAITING_FOR_APPROVAL
-	// 3. Any node executed (completed/failed) → RUNNING
-	// 4. All nodes completed → COMPLETED
-	// 5. Otherwise → Keep DB status (QUEUED, etc.)
-
-	if hasFailedNode {
-		displayStatus = models.StatusFailed
-	} else if hasWaitingNode {
-		displayStatus = models.StatusWaitingForApproval
-	} else if completedCount == totalNodes && totalNodes > 0 {
-		displayStatus = models.StatusCompleted
-	} else if hasCompletedNode {
-		displayStatus = models.StatusRunning
-	}
-
-	// Create a copy of run with updated display status
-	runCopy := *run
-	runCopy.Status = displayStatus
+	criticalPathMs, criticalPathNodes := criticalPath(workflowIR, nodeExecutions)
 
 	return &RunDetails{
-		Run:             &runCopy,
+		Run:             run,
+		DerivedStatus:   derivedStatus,
 		BaseWorkflowIR:  baseWorkflowIR,
 		WorkflowIR:      workflowIR,
 		NodeExecutions:  nodeExecutions,
 		NodeOutputsRaw:  nodeOutputsRaw,
 		Patches:         patches,
+		CriticalPathMs:  criticalPathMs,
+		CriticalPath:    criticalPathNodes,
 	}, nil
 }
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// ExportRunFixture captures a run as a replayable test fixture
+// See sdk.RunFixture; the fixture replays through coordinator.Simulator.
+// Fails with ErrRunOutputUnavailable if a recorded node output can't be read.
+func (s *RunService) ExportRunFixture(ctx context.Context, runID uuid.UUID) (*sdk.RunFixture, error) {
+	// 1. Load workflow IR from Redis (final, with run patches applied)
+	workflowIR, err := s.loadWorkflowIR(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Load recorded node outputs, masked like every other API response
+	contextData, err := s.loadContextData(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched, err := s.bulkFetchAllCASFromContext(ctx, contextData)
+	if err != nil {
+		return nil, err
+	}
+	// Every recorded output becomes a mock, so a fixture missing one wouldn't replay the run
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+	nodeIDs := make([]string, 0, len(nodes))
+	for nodeID := range nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	if err := requireNodeOutputs(fetched, contextData, nodeIDs); err != nil {
+		return nil, err
+	}
+	nodeOutputsRaw := s.buildNodeOutputsRaw(ctx, contextData, fetched.Data)
+	maskNodeOutputs(workflowIR, nodeOutputsRaw)
+
+	// Round-trip through JSON to get the typed IR the simulator consumes
+	irJSON, err := json.Marshal(workflowIR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal IR: %w", err)
+	}
+	var ir sdk.IR
+	if err := json.Unmarshal(irJSON, &ir); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal IR: %w", err)
+	}
+
+	fixture := &sdk.RunFixture{
+		RunID:  runID.String(),
+		IR:     &ir,
+		Mocks:  make(map[string]map[string]interface{}),
+		Result: make(map[string]interface{}),
+	}
+
+	for nodeID := range ir.Nodes {
+		if output, ok := nodeOutputsRaw[nodeID].(map[string]interface{}); ok {
+			fixture.Mocks[nodeID] = output
+		}
+	}
+	for _, nodeID := range terminalNodeIDs(workflowIR) {
+		if output, ok := fixture.Mocks[nodeID]; ok {
+			fixture.Result[nodeID] = output
+		}
+	}
+
+	// 3. Inputs and variables are optional: older runs and runs without vars have none
+	if fixture.Inputs, err = s.loadRunInputs(ctx, runID); err != nil {
+		return nil, err
+	}
+	if fixture.Vars, err = s.loadRunVars(ctx, runID); err != nil {
+		return nil, err
+	}
+
+	return fixture, nil
+}
+
+// loadRunInputs returns the inputs stored at run creation, or nil if they have expired
+func (s *RunService) loadRunInputs(ctx context.Context, runID uuid.UUID) (map[string]interface{}, error) {
+	key := sdk.InputsKey(runID.String())
+	values, err := s.redis.GetMultiple(ctx, []string{key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run inputs: %w", err)
+	}
+	raw, ok := values[key]
+	if !ok {
+		return nil, nil
+	}
+
+	var inputs map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &inputs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run inputs: %w", err)
+	}
+	return inputs, nil
+}
+
+// loadRunVars returns the run variables' final values
+func (s *RunService) loadRunVars(ctx context.Context, runID uuid.UUID) (map[string]interface{}, error) {
+	raw, err := s.redis.GetAllHash(ctx, sdk.VarsKey(runID.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run vars: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	vars := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			return nil, fmt.Errorf("failed to decode var %s: %w", name, err)
+		}
+		vars[name] = decoded
+	}
+	return vars, nil
+}
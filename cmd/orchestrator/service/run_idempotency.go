@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyWindow is how long an idempotency key keeps returning its run
+const IdempotencyWindow = 24 * time.Hour
+
+// idempotencyPending marks a key whose run is still being created
+const idempotencyPending = "pending"
+
+// idempotencyPendingTTL bounds how long a claim can stay pending
+// Creating a run takes well under this; a claim left by a crashed orchestrator
+// frees the key for a retry after it rather than after the whole window.
+const idempotencyPendingTTL = time.Minute
+
+// ErrIdempotencyKeyInUse is returned while the first submission with the same
+// idempotency key is still creating its run
+var ErrIdempotencyKeyInUse = errors.New("a run for this idempotency key is still being created")
+
+// idempotencyKey returns the Redis key recording the run created for a key
+// Keys are scoped to the user and workflow tag, so clients only need to keep
+// them unique per workflow.
+func idempotencyKey(username, tag, key string) string {
+	return fmt.Sprintf("idempotency:run:%s:%s:%s", username, tag, key)
+}
+
+// createRunOnce creates a run at most once per idempotency key
+// The key is claimed with SETNX for idempotencyPendingTTL before the run is
+// created, then records the created run for IdempotencyWindow. A replay returns the recorded run with
+// Replayed set; a replay racing the first submission gets ErrIdempotencyKeyInUse.
+// If creating the run fails the claim is dropped so the client can retry.
+func (s *RunService) createRunOnce(ctx context.Context, req *CreateRunRequest, create func(context.Context, *CreateRunRequest) (*CreateRunResponse, error)) (*CreateRunResponse, error) {
+	key := idempotencyKey(req.Username, req.Tag, req.IdempotencyKey)
+
+	claimed, err := s.redis.SetNX(ctx, key, idempotencyPending, idempotencyPendingTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if !claimed {
+		return s.replayRun(ctx, key)
+	}
+
+	response, err := create(ctx, req)
+	if err != nil {
+		if delErr := s.redis.Delete(ctx, key); delErr != nil {
+			s.components.Logger.Warn("failed to release idempotency key",
+				"idempotency_key", req.IdempotencyKey,
+				"error", delErr)
+		}
+		return nil, err
+	}
+
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = s.redis.SetWithExpiry(ctx, key, string(data), IdempotencyWindow)
+	}
+	if err != nil {
+		// The run exists; a replay will see the key as in use until the claim expires
+		s.components.Logger.Error("failed to record run for idempotency key",
+			"idempotency_key", req.IdempotencyKey,
+			"run_id", response.RunID,
+			"error", err)
+	}
+
+	return response, nil
+}
+
+// replayRun returns the run recorded for an idempotency key
+func (s *RunService) replayRun(ctx context.Context, key string) (*CreateRunResponse, error) {
+	recorded, err := s.redis.Get(ctx, key)
+	if err != nil {
+		// Expired or released between the claim and now
+		return nil, ErrIdempotencyKeyInUse
+	}
+	if recorded == idempotencyPending {
+		return nil, ErrIdempotencyKeyInUse
+	}
+
+	var response CreateRunResponse
+	if err := json.Unmarshal([]byte(recorded), &response); err != nil {
+		return nil, fmt.Errorf("failed to decode run for idempotency key: %w", err)
+	}
+	response.Replayed = true
+
+	s.components.Logger.Info("returning run for repeated idempotency key",
+		"run_id", response.RunID,
+		"tag", response.Tag)
+	return &response, nil
+}
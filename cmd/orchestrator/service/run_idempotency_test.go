@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+// newIdempotencyTestService returns a run service on miniredis and a create
+// func counting the runs it creates
+func newIdempotencyTestService(t *testing.T) (*miniredis.Miniredis, *RunService, func(context.Context, *CreateRunRequest) (*CreateRunResponse, error), *int) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	var created int
+	create := func(ctx context.Context, req *CreateRunRequest) (*CreateRunResponse, error) {
+		created++
+		return &CreateRunResponse{
+			RunID:      uuid.New(),
+			ArtifactID: uuid.New(),
+			Status:     string(models.StatusQueued),
+			Tag:        req.Tag,
+		}, nil
+	}
+	return mr, svc, create, &created
+}
+
+func TestCreateRunOnce(t *testing.T) {
+	_, svc, create, created := newIdempotencyTestService(t)
+	ctx := context.Background()
+	req := &CreateRunRequest{Tag: "etl", Username: "alice", IdempotencyKey: "submit-1"}
+
+	// First submit creates the run
+	first, err := svc.createRunOnce(ctx, req, create)
+	require.NoError(t, err)
+	assert.False(t, first.Replayed)
+	assert.Equal(t, 1, *created)
+
+	// A retry with the same key returns the same run without creating one
+	replay, err := svc.createRunOnce(ctx, req, create)
+	require.NoError(t, err)
+	assert.True(t, replay.Replayed)
+	assert.Equal(t, first.RunID, replay.RunID)
+	assert.Equal(t, first.ArtifactID, replay.ArtifactID)
+	assert.Equal(t, "etl", replay.Tag)
+	assert.Equal(t, 1, *created)
+
+	// A different key is a different submission
+	other, err := svc.createRunOnce(ctx, &CreateRunRequest{Tag: "etl", Username: "alice", IdempotencyKey: "submit-2"}, create)
+	require.NoError(t, err)
+	assert.False(t, other.Replayed)
+	assert.NotEqual(t, first.RunID, other.RunID)
+	assert.Equal(t, 2, *created)
+
+	// Keys are scoped per user and tag
+	for _, scoped := range []*CreateRunRequest{
+		{Tag: "etl", Username: "bob", IdempotencyKey: "submit-1"},
+		{Tag: "report", Username: "alice", IdempotencyKey: "submit-1"},
+	} {
+		response, err := svc.createRunOnce(ctx, scoped, create)
+		require.NoError(t, err)
+		assert.False(t, response.Replayed)
+	}
+	assert.Equal(t, 4, *created)
+}
+
+func TestCreateRunOnce_Expires(t *testing.T) {
+	mr, svc, create, created := newIdempotencyTestService(t)
+	ctx := context.Background()
+	req := &CreateRunRequest{Tag: "etl", Username: "alice", IdempotencyKey: "submit-1"}
+
+	first, err := svc.createRunOnce(ctx, req, create)
+	require.NoError(t, err)
+
+	mr.FastForward(IdempotencyWindow)
+	again, err := svc.createRunOnce(ctx, req, create)
+	require.NoError(t, err)
+	assert.False(t, again.Replayed)
+	assert.NotEqual(t, first.RunID, again.RunID)
+	assert.Equal(t, 2, *created)
+}
+
+func TestCreateRunOnce_InProgress(t *testing.T) {
+	mr, svc, create, created := newIdempotencyTestService(t)
+	ctx := context.Background()
+	req := &CreateRunRequest{Tag: "etl", Username: "alice", IdempotencyKey: "submit-1"}
+	key := idempotencyKey("alice", "etl", "submit-1")
+
+	// The first submission claims the key only briefly while creating its run
+	inProgress := func(ctx context.Context, req *CreateRunRequest) (*CreateRunResponse, error) {
+		assert.Equal(t, idempotencyPendingTTL, mr.TTL(key))
+		_, err := svc.createRunOnce(ctx, req, create)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+		return create(ctx, req)
+	}
+	_, err := svc.createRunOnce(ctx, req, inProgress)
+	require.NoError(t, err)
+	assert.Equal(t, 1, *created)
+
+	// Recording the run extends the key to the whole window
+	assert.Equal(t, IdempotencyWindow, mr.TTL(key))
+}
+
+func TestCreateRunOnce_AbandonedClaimExpires(t *testing.T) {
+	mr, svc, create, created := newIdempotencyTestService(t)
+	ctx := context.Background()
+	req := &CreateRunRequest{Tag: "etl", Username: "alice", IdempotencyKey: "submit-1"}
+
+	// A submission crashed after claiming the key
+	claimed, err := svc.redis.SetNX(ctx, idempotencyKey("alice", "etl", "submit-1"), idempotencyPending, idempotencyPendingTTL)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	_, err = svc.createRunOnce(ctx, req, create)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+
+	// The retry succeeds once the claim expires, long before the window does
+	mr.FastForward(idempotencyPendingTTL)
+	response, err := svc.createRunOnce(ctx, req, create)
+	require.NoError(t, err)
+	assert.False(t, response.Replayed)
+	assert.Equal(t, 1, *created)
+}
+
+func TestCreateRunOnce_FailureReleasesKey(t *testing.T) {
+	mr, svc, create, created := newIdempotencyTestService(t)
+	ctx := context.Background()
+	req := &CreateRunRequest{Tag: "etl", Username: "alice", IdempotencyKey: "submit-1"}
+
+	failing := func(ctx context.Context, req *CreateRunRequest) (*CreateRunResponse, error) {
+		return nil, errors.New("materialize failed")
+	}
+	_, err := svc.createRunOnce(ctx, req, failing)
+	require.Error(t, err)
+	assert.False(t, mr.Exists(idempotencyKey("alice", "etl", "submit-1")))
+
+	// The retry creates the run
+	response, err := svc.createRunOnce(ctx, req, create)
+	require.NoError(t, err)
+	assert.False(t, response.Replayed)
+	assert.Equal(t, 1, *created)
+}
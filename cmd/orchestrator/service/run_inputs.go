@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lyzr/orchestrator/common/compiler"
+)
+
+// InputsMetadataKey is the workflow metadata key declaring a workflow's inputs
+// It lists one entry per input: {"name": "amount", "type": "number", "required": true}.
+// Types are the node config field types (string, number, integer, boolean,
+// object, array); an empty type accepts any value.
+const InputsMetadataKey = "inputs"
+
+var (
+	// ErrInvalidInputs is returned for run inputs that don't match the workflow's declaration
+	ErrInvalidInputs = errors.New("invalid run inputs")
+	// ErrInvalidInputSchema is returned for a workflow whose inputs declaration is malformed
+	ErrInvalidInputSchema = errors.New("invalid workflow inputs declaration")
+)
+
+// InputsError lists every problem with a run's inputs
+type InputsError struct {
+	Problems []string
+}
+
+func (e *InputsError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidInputs, strings.Join(e.Problems, "; "))
+}
+
+func (e *InputsError) Unwrap() error {
+	return ErrInvalidInputs
+}
+
+// workflowInputSchema returns the inputs a workflow declares, or nil if it declares none
+func workflowInputSchema(workflow map[string]interface{}) (compiler.ConfigSchema, error) {
+	metadata, _ := workflow["metadata"].(map[string]interface{})
+	raw, ok := metadata[InputsMetadataKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata.%s must be a list", ErrInvalidInputSchema, InputsMetadataKey)
+	}
+
+	schema := make(compiler.ConfigSchema, len(entries))
+	for i, entry := range entries {
+		fields, _ := entry.(map[string]interface{})
+		name, _ := fields["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%w: input %d has no name", ErrInvalidInputSchema, i)
+		}
+		fieldType, _ := fields["type"].(string)
+		if !compiler.ValidFieldType(fieldType) {
+			return nil, fmt.Errorf("%w: input %s has unknown type %q", ErrInvalidInputSchema, name, fieldType)
+		}
+		required, _ := fields["required"].(bool)
+		schema[name] = compiler.ConfigField{Type: fieldType, Required: required}
+	}
+	return schema, nil
+}
+
+// validateRunInputs checks a run's inputs against the inputs its workflow declares
+// Workflows that declare no inputs accept any. Inputs that aren't declared are
+// allowed and passed through unchecked.
+func validateRunInputs(workflow map[string]interface{}, inputs map[string]interface{}) error {
+	schema, err := workflowInputSchema(workflow)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	if problems := schema.Problems(inputs); len(problems) > 0 {
+		return &InputsError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+)
+
+func TestValidateRunInputs(t *testing.T) {
+	workflow := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"inputs": []interface{}{
+				map[string]interface{}{"name": "customer_id", "type": "string", "required": true},
+				map[string]interface{}{"name": "amount", "type": "number", "required": true},
+				map[string]interface{}{"name": "dry_run", "type": "boolean"},
+			},
+		},
+		"nodes": []interface{}{},
+		"edges": []interface{}{},
+	}
+
+	// A valid input set is accepted; undeclared inputs pass through
+	assert.NoError(t, validateRunInputs(workflow, map[string]interface{}{
+		"customer_id": "c-42",
+		"amount":      12.5,
+		"dry_run":     true,
+		"note":        "extra",
+	}))
+
+	// Missing and wrong-typed inputs are all listed
+	err := validateRunInputs(workflow, map[string]interface{}{"amount": "12.5", "dry_run": "yes"})
+	require.ErrorIs(t, err, ErrInvalidInputs)
+	var inputsErr *InputsError
+	require.True(t, errors.As(err, &inputsErr))
+	assert.Equal(t, []string{
+		"field amount must be a number, got string",
+		"missing required field customer_id",
+		"field dry_run must be a boolean, got string",
+	}, inputsErr.Problems)
+
+	// No inputs at all is rejected too
+	assert.ErrorIs(t, validateRunInputs(workflow, nil), ErrInvalidInputs)
+
+	// Workflows without a declaration accept any inputs
+	assert.NoError(t, validateRunInputs(map[string]interface{}{"nodes": []interface{}{}}, nil))
+
+	// A malformed declaration is the workflow's fault, not the run's
+	err = validateRunInputs(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"inputs": []interface{}{map[string]interface{}{"name": "amount", "type": "money"}},
+		},
+	}, map[string]interface{}{"amount": 1})
+	assert.ErrorIs(t, err, ErrInvalidInputSchema)
+}
+
+func TestRunService_CreateRunRejectsInvalidInputs(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	cas := newTestCASService(newFakeCASStore(), "none", 0)
+	materializer := NewMaterializerService(log)
+	workflows := NewWorkflowServiceV2(cas, NewArtifactService(newFakeArtifactStore(), log), NewTagService(newFakeTagStore(), log), materializer, log)
+	_, err := workflows.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: "alice",
+		TagName:  "main",
+		Workflow: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"name": "customer", "type": "object", "required": true},
+					map[string]interface{}{"name": "items", "type": "array"},
+					map[string]interface{}{"name": "retries", "type": "integer"},
+				},
+			},
+			"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "function"}},
+			"edges": []interface{}{},
+		},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	runs := NewRunService(&RunServiceOpts{
+		Components:      &bootstrap.Components{Logger: log},
+		CASService:      cas,
+		WorkflowSvc:     workflows,
+		MaterializerSvc: materializer,
+	})
+
+	// Decoded from a request body, so the values have encoding/json's types
+	_, err = runs.CreateRun(ctx, &CreateRunRequest{
+		Tag:      "main",
+		Username: "alice",
+		Inputs: map[string]interface{}{
+			"customer": []interface{}{"c-42"},
+			"items":    map[string]interface{}{"sku": "a"},
+			"retries":  1.5,
+		},
+	})
+	require.ErrorIs(t, err, ErrInvalidInputs)
+	var inputsErr *InputsError
+	require.True(t, errors.As(err, &inputsErr))
+	// Problems reach API clients as-is, so they name JSON types, never Go ones
+	assert.Equal(t, []string{
+		"field customer must be an object, got array",
+		"field items must be an array, got object",
+		"field retries must be an integer, got number 1.5",
+	}, inputsErr.Problems)
+}
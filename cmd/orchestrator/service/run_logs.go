@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// GetNodeLogs returns the log lines a node's workers recorded, oldest first
+// Only lines at level or above are returned ("" for all). A node's log holds
+// its last sdk.NodeLogMaxEntries lines and expires with the rest of the run's
+// state, so a node that never ran, or a run cleaned up, has an empty log.
+func (s *RunService) GetNodeLogs(ctx context.Context, runID uuid.UUID, nodeID, level string) ([]sdk.NodeLogEntry, error) {
+	return sdk.LoadNodeLogs(ctx, s.redis.GetUnderlying(), runID.String(), nodeID, level)
+}
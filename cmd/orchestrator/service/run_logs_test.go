@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+)
+
+func TestRunService_GetNodeLogs(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	runID := uuid.New()
+	token := &sdk.Token{ID: "tok-1", RunID: runID.String(), ToNode: "fetch"}
+	nodeLog := worker.NewNodeLogger(rdb, log, token)
+	nodeLog.Debug("using pre-resolved config from token")
+	nodeLog.Info("processing HTTP task", "token_id", "tok-1")
+	nodeLog.Warn("slow response", "duration_ms", 2500)
+	nodeLog.Error("HTTP request failed", "error", errors.New("connection refused"))
+
+	// Another node's logs are not included
+	worker.NewNodeLogger(rdb, log, &sdk.Token{RunID: runID.String(), ToNode: "notify"}).Error("unrelated")
+
+	logs, err := svc.GetNodeLogs(context.Background(), runID, "fetch", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 4)
+	assert.Equal(t, sdk.LogLevelDebug, logs[0].Level)
+	assert.Equal(t, "processing HTTP task", logs[1].Message)
+	assert.Equal(t, "tok-1", logs[1].Fields["token_id"])
+	assert.False(t, logs[1].Timestamp.IsZero())
+
+	logs, err = svc.GetNodeLogs(context.Background(), runID, "fetch", sdk.LogLevelWarn)
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	assert.Equal(t, "slow response", logs[0].Message)
+	assert.Equal(t, float64(2500), logs[0].Fields["duration_ms"])
+	assert.Equal(t, "HTTP request failed", logs[1].Message)
+	assert.Equal(t, "connection refused", logs[1].Fields["error"])
+
+	// Logs expire with the rest of the run's state
+	assert.Greater(t, mr.TTL(sdk.NodeLogKey(runID.String(), "fetch")), time.Duration(0))
+}
+
+func TestRunService_GetNodeLogsCapped(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	runID := uuid.New()
+	ctx := context.Background()
+	for i := 0; i < sdk.NodeLogMaxEntries+10; i++ {
+		require.NoError(t, sdk.AppendNodeLog(ctx, rdb, runID.String(), "fetch", &sdk.NodeLogEntry{
+			Level:   sdk.LogLevelInfo,
+			Message: fmt.Sprintf("line %d", i),
+		}))
+	}
+
+	logs, err := svc.GetNodeLogs(ctx, runID, "fetch", "")
+	require.NoError(t, err)
+	require.Len(t, logs, sdk.NodeLogMaxEntries)
+	assert.Equal(t, "line 10", logs[0].Message, "oldest lines are trimmed")
+}
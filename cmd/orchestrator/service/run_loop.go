@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// LoopIterationDetail is one iteration of a loop node in the run details
+type LoopIterationDetail struct {
+	Iteration         int64                  `json:"iteration"`
+	Output            map[string]interface{} `json:"output,omitempty"`
+	OutputUnavailable bool                   `json:"output_unavailable,omitempty"` // Output recorded but expired, or failed verification
+	Decision          string                 `json:"decision"`                     // continue, break or max_iterations
+	Reason            string                 `json:"reason,omitempty"`             // Why the loop continued or exited
+	CompletedAt       time.Time              `json:"completed_at"`
+}
+
+// attachLoopIterations adds each loop node's iteration history to its execution
+// Outputs are masked with the node's redaction rules, like its final output. An
+// iteration whose output can't be read is still listed, flagged unavailable.
+func (s *RunService) attachLoopIterations(ctx context.Context, runID uuid.UUID, workflowIR map[string]interface{}, nodeExecutions map[string]*NodeExecution) {
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+	workflowSDK := sdk.NewSDK(s.redis.GetUnderlying(), nil, s.components.Logger, "")
+
+	for nodeID, raw := range nodes {
+		node, _ := raw.(map[string]interface{})
+		execution, ok := nodeExecutions[nodeID]
+		if node["loop"] == nil || !ok {
+			continue
+		}
+
+		history, err := workflowSDK.LoadLoopHistory(ctx, runID.String(), nodeID)
+		if err != nil {
+			s.components.Logger.Warn("failed to load loop history", "run_id", runID, "node_id", nodeID, "error", err)
+			continue
+		}
+		if len(history) == 0 {
+			continue
+		}
+
+		outputs := map[string]map[string]interface{}{}
+		fetched, err := s.fetchCASOutputs(ctx, loopResultRefs(history))
+		if err != nil {
+			s.components.Logger.Warn("failed to load loop iteration outputs", "run_id", runID, "node_id", nodeID, "error", err)
+		} else {
+			outputs = fetched.Data
+		}
+
+		config, _ := node["config"].(map[string]interface{})
+		rules := maskRules(config)
+		execution.Iterations = make([]*LoopIterationDetail, 0, len(history))
+		for _, iteration := range history {
+			output := outputs[iteration.ResultRef]
+			if output != nil {
+				sdk.Redact(output, rules)
+			}
+			execution.Iterations = append(execution.Iterations, &LoopIterationDetail{
+				Iteration:         iteration.Iteration,
+				Output:            output,
+				OutputUnavailable: iteration.ResultRef != "" && output == nil,
+				Decision:          iteration.Decision,
+				Reason:            iteration.Reason,
+				CompletedAt:       iteration.CompletedAt,
+			})
+		}
+	}
+}
+
+// loopResultRefs returns the CAS refs of the iterations that recorded an output
+func loopResultRefs(history []*sdk.LoopIteration) []string {
+	casRefs := make([]string, 0, len(history))
+	for _, iteration := range history {
+		if iteration.ResultRef != "" {
+			casRefs = append(casRefs, iteration.ResultRef)
+		}
+	}
+	return casRefs
+}
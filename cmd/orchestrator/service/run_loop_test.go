@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+func TestRunService_AttachLoopIterations(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, log), log, "")
+
+	runID := uuid.New()
+	decisions := []string{sdk.LoopDecisionContinue, sdk.LoopDecisionContinue, sdk.LoopDecisionBreak}
+	refs := make([]string, len(decisions))
+	for i, decision := range decisions {
+		ref, err := workflowSDK.StoreOutput(ctx, map[string]interface{}{"attempt": i + 1, "token": "secret"})
+		require.NoError(t, err)
+		refs[i] = ref
+		require.NoError(t, workflowSDK.AppendLoopIteration(ctx, runID.String(), "retry", &sdk.LoopIteration{
+			Iteration: int64(i + 1),
+			ResultRef: ref,
+			Decision:  decision,
+		}))
+	}
+
+	workflowIR := map[string]interface{}{"nodes": map[string]interface{}{
+		"retry": map[string]interface{}{
+			"loop":   map[string]interface{}{"enabled": true},
+			"config": map[string]interface{}{"redact": []interface{}{map[string]interface{}{"path": "token"}}},
+		},
+		"done": map[string]interface{}{},
+	}}
+	nodeExecutions := map[string]*NodeExecution{"retry": {NodeID: "retry"}, "done": {NodeID: "done"}}
+
+	// The second iteration's output has expired
+	mr.Del("cas:" + refs[1])
+
+	svc.attachLoopIterations(ctx, runID, workflowIR, nodeExecutions)
+
+	iterations := nodeExecutions["retry"].Iterations
+	require.Len(t, iterations, 3)
+	for i, iteration := range iterations {
+		assert.EqualValues(t, i+1, iteration.Iteration)
+		assert.Equal(t, decisions[i], iteration.Decision)
+		if i == 1 {
+			assert.Nil(t, iteration.Output)
+			assert.True(t, iteration.OutputUnavailable)
+			continue
+		}
+		assert.False(t, iteration.OutputUnavailable)
+		assert.EqualValues(t, i+1, iteration.Output["attempt"])
+		assert.NotEqual(t, "secret", iteration.Output["token"], "outputs are masked")
+	}
+	assert.Empty(t, nodeExecutions["done"].Iterations)
+}
+
+func TestRunService_BuildNodeExecutions_LoopExitReason(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+	workflowSDK := sdk.NewSDK(rdb, nil, log, "")
+
+	run := &models.Run{RunID: uuid.New(), Status: models.StatusCompleted}
+	require.NoError(t, workflowSDK.RecordLoopExit(ctx, run.RunID.String(), "retry", sdk.LoopExitBreak))
+	require.NoError(t, workflowSDK.RecordLoopExit(ctx, run.RunID.String(), "poll", sdk.LoopExitTimeout))
+
+	workflowIR := map[string]interface{}{"nodes": map[string]interface{}{
+		"retry": map[string]interface{}{"loop": map[string]interface{}{"enabled": true}},
+		"poll":  map[string]interface{}{"loop": map[string]interface{}{"enabled": true}},
+		"done":  map[string]interface{}{},
+	}}
+	outputs := map[string]interface{}{
+		"retry": map[string]interface{}{"status": "success"},
+		"poll":  map[string]interface{}{"status": "success"},
+	}
+
+	executions := svc.buildNodeExecutions(ctx, run, workflowIR, outputs)
+	assert.Equal(t, sdk.LoopExitBreak, executions["retry"].ExitReason)
+	assert.Equal(t, sdk.LoopExitTimeout, executions["poll"].ExitReason)
+	assert.Empty(t, executions["done"].ExitReason)
+}
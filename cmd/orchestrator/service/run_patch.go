@@ -18,6 +18,7 @@ type RunPatchService struct {
 	casService   *CASService
 	artifactRepo *repository.ArtifactRepository
 	components   *bootstrap.Components
+	audit        *AuditService // Optional, see WithAudit
 }
 
 // NewRunPatchService creates a new run patch service
@@ -167,6 +168,13 @@ func (s *RunPatchService) CreateRunPatch(ctx context.Context, req *CreateRunPatc
 		return nil, fmt.Errorf("failed to create run patch: %w", err)
 	}
 
+	s.audit.Record(ctx, artifact.CreatedBy, models.AuditActionRunPatch, runTarget(req.RunID, "", ""), map[string]interface{}{
+		"artifact_id": artifact.ArtifactID.String(),
+		"cas_id":      casID,
+		"seq":         nextSeq,
+		"node_id":     req.NodeID,
+	})
+
 	s.components.Logger.Info("run patch created successfully",
 		"run_id", req.RunID,
 		"seq", nextSeq,
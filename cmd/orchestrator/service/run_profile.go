@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/ratelimit"
+)
+
+// workflowProfileTTL bounds how long an unused version's profile is kept
+// Profiles never go stale: the key is the content hash of the workflow.
+const workflowProfileTTL = 24 * time.Hour
+
+// workflowProfileKey returns the Redis key caching a workflow version's profile
+func workflowProfileKey(versionHash string) string {
+	return fmt.Sprintf("ratelimit:profile:%s", versionHash)
+}
+
+// workflowProfile returns the rate limit profile of a materialized workflow
+// Identical content yields an identical profile, so profiles are cached by the
+// workflow's version hash and hot workflows aren't walked again on every run.
+// Cache failures fall back to inspecting the workflow.
+func (s *RunService) workflowProfile(ctx context.Context, versionHash string, workflow map[string]interface{}) ratelimit.WorkflowProfile {
+	key := workflowProfileKey(versionHash)
+
+	if cached, err := s.redis.Get(ctx, key); err == nil {
+		var profile ratelimit.WorkflowProfile
+		if err := json.Unmarshal([]byte(cached), &profile); err == nil {
+			return profile
+		}
+	}
+
+	profile := s.inspect(workflow)
+
+	data, err := json.Marshal(profile)
+	if err == nil {
+		err = s.redis.SetWithExpiry(ctx, key, string(data), workflowProfileTTL)
+	}
+	if err != nil {
+		s.components.Logger.Warn("failed to cache workflow profile",
+			"version_hash", versionHash,
+			"error", err)
+	}
+
+	return profile
+}
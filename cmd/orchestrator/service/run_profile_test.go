@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/ratelimit"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+func TestRunService_WorkflowProfileCached(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+		CASService: &CASService{},
+	})
+	var inspections int
+	svc.inspect = func(workflow map[string]interface{}) ratelimit.WorkflowProfile {
+		inspections++
+		return ratelimit.InspectWorkflow(workflow)
+	}
+
+	workflow := map[string]interface{}{
+		"nodes": []interface{}{
+			map[string]interface{}{"id": "plan", "type": "agent"},
+			map[string]interface{}{"id": "fetch", "type": "http"},
+		},
+	}
+	workflowJSON, err := json.Marshal(workflow)
+	require.NoError(t, err)
+	versionHash := svc.casService.ComputeHash(workflowJSON)
+	ctx := context.Background()
+
+	first := svc.workflowProfile(ctx, versionHash, workflow)
+	assert.Equal(t, ratelimit.TierStandard, first.Tier)
+	assert.Equal(t, 1, first.AgentCount)
+	assert.Equal(t, 2, first.TotalNodes)
+	assert.Equal(t, 1, inspections)
+	assert.True(t, mr.Exists(workflowProfileKey(versionHash)))
+
+	// The second run of the same version is served from the cache
+	second := svc.workflowProfile(ctx, versionHash, workflow)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, inspections)
+
+	// Different content is a different version
+	workflow["nodes"] = append(workflow["nodes"].([]interface{}), map[string]interface{}{"id": "review", "type": "agent"})
+	workflowJSON, err = json.Marshal(workflow)
+	require.NoError(t, err)
+	third := svc.workflowProfile(ctx, svc.casService.ComputeHash(workflowJSON), workflow)
+	assert.Equal(t, 2, third.AgentCount)
+	assert.Equal(t, 2, inspections)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+func TestMaskNodeOutputs(t *testing.T) {
+	ir := sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"lookup": {ID: "lookup", Type: "http", Config: map[string]interface{}{
+				sdk.ConfigKeyRedact: []interface{}{
+					map[string]interface{}{"path": "body.email"},
+					map[string]interface{}{"path": "body.cards.*.number", "action": sdk.RedactActionMask},
+					map[string]interface{}{"path": "body.ssn", "action": sdk.RedactActionDrop},
+				},
+			}},
+			"notify": {ID: "notify", Type: "http"},
+		},
+	}
+
+	// Details read the IR as a generic map, the same way it's loaded from Redis
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	var workflowIR map[string]interface{}
+	require.NoError(t, json.Unmarshal(irJSON, &workflowIR))
+
+	lookup := map[string]interface{}{
+		"body": map[string]interface{}{
+			"name":  "Ada",
+			"email": "ada@example.com",
+			"ssn":   "123-45-6789",
+			"cards": []interface{}{
+				map[string]interface{}{"number": "4111111111111111", "brand": "visa"},
+			},
+		},
+	}
+	notify := map[string]interface{}{"email": "ops@example.com"}
+	outputs := map[string]interface{}{"lookup": lookup, "notify": notify}
+
+	maskNodeOutputs(workflowIR, outputs)
+
+	body := lookup["body"].(map[string]interface{})
+	assert.Equal(t, sdk.RedactedValue, body["email"])
+	assert.Equal(t, "Ada", body["name"])
+	card := body["cards"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, sdk.RedactedValue, card["number"])
+	assert.Equal(t, "visa", card["brand"])
+
+	// Drop fields stored before the rule existed are masked too; nodes without rules are untouched
+	assert.Equal(t, sdk.RedactedValue, body["ssn"])
+	assert.Equal(t, "ops@example.com", notify["email"])
+}
+
+func TestRunService_GetRunResult_MasksRedactedFields(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	runID := uuid.New()
+	ir := sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"lookup": {ID: "lookup", Type: "http", IsTerminal: true, Config: map[string]interface{}{
+				sdk.ConfigKeyRedact: []interface{}{map[string]interface{}{"path": "email", "action": sdk.RedactActionMask}},
+			}},
+		},
+		Metadata: map[string]interface{}{
+			sdk.MetadataResultMapping: map[string]interface{}{"contact": "$.lookup.email", "name": "$.lookup.name"},
+		},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	mr.Set("ir:"+runID.String(), string(irJSON))
+	mr.Set("cas:artifact://lookup", `{"name":"Ada","email":"ada@example.com"}`)
+	mr.HSet("context:"+runID.String(), "lookup:output", "artifact://lookup")
+
+	// Mappings can't be used to read masked fields back out
+	result, err := svc.GetRunResult(context.Background(), runID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"contact": sdk.RedactedValue, "name": "Ada"}, result.Result)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// ErrInvalidResultMapping is returned when a workflow's result mapping can't be applied
+var ErrInvalidResultMapping = errors.New("invalid result mapping")
+
+// RunResult is the final result of a run
+type RunResult struct {
+	RunID         uuid.UUID              `json:"run_id"`
+	TerminalNodes []string               `json:"terminal_nodes"`
+	Mapped        bool                   `json:"mapped"`
+	Result        map[string]interface{} `json:"result"`
+}
+
+// GetRunResult returns the run's terminal node outputs
+// Without a result mapping the result is the raw output of each terminal node,
+// keyed by node ID. When the workflow metadata declares result_mapping, each
+// entry maps a result field to an expression over those outputs:
+//
+//	"result_mapping": {
+//	  "summary": "$.summarize.text",
+//	  "score":   "outputs.grade.score * 100.0"
+//	}
+//
+// JSONPath expressions start with "$." followed by the node ID; anything else
+// is CEL with the terminal outputs bound to `outputs`. Masked fields (see
+// sdk.RedactionRules) are masked before the mapping sees them. Fails with
+// ErrRunOutputUnavailable if a terminal node's output can't be read.
+func (s *RunService) GetRunResult(ctx context.Context, runID uuid.UUID) (*RunResult, error) {
+	// 1. Load workflow IR from Redis
+	workflowIR, err := s.loadWorkflowIR(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Load terminal node outputs
+	contextData, err := s.loadContextData(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched, err := s.bulkFetchAllCASFromContext(ctx, contextData)
+	if err != nil {
+		return nil, err
+	}
+	terminals := terminalNodeIDs(workflowIR)
+	if err := requireNodeOutputs(fetched, contextData, terminals); err != nil {
+		return nil, err
+	}
+	nodeOutputsRaw := s.buildNodeOutputsRaw(ctx, contextData, fetched.Data)
+	maskNodeOutputs(workflowIR, nodeOutputsRaw)
+
+	outputs := make(map[string]interface{}, len(terminals))
+	for _, nodeID := range terminals {
+		if output, ok := nodeOutputsRaw[nodeID]; ok {
+			outputs[nodeID] = output
+		}
+	}
+
+	result := &RunResult{
+		RunID:         runID,
+		TerminalNodes: terminals,
+		Result:        outputs,
+	}
+
+	// 3. Shape the result if the workflow declares a mapping
+	metadata, _ := workflowIR["metadata"].(map[string]interface{})
+	mapping, ok := metadata[sdk.MetadataResultMapping]
+	if !ok {
+		return result, nil
+	}
+
+	shaped, err := applyResultMapping(mapping, outputs)
+	if err != nil {
+		return nil, err
+	}
+	result.Mapped = true
+	result.Result = shaped
+
+	return result, nil
+}
+
+// terminalNodeIDs returns the IR nodes flagged is_terminal, sorted
+func terminalNodeIDs(workflowIR map[string]interface{}) []string {
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+
+	terminals := []string{}
+	for nodeID, raw := range nodes {
+		node, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if terminal, _ := node["is_terminal"].(bool); terminal {
+			terminals = append(terminals, nodeID)
+		}
+	}
+	sort.Strings(terminals)
+	return terminals
+}
+
+// applyResultMapping evaluates each mapped field over the terminal outputs
+func applyResultMapping(mapping interface{}, outputs map[string]interface{}) (map[string]interface{}, error) {
+	fields, ok := mapping.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: expected an object of field expressions, got %T", ErrInvalidResultMapping, mapping)
+	}
+
+	var env *cel.Env
+	result := make(map[string]interface{}, len(fields))
+
+	for field, raw := range fields {
+		expr, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q: expression must be a string", ErrInvalidResultMapping, field)
+		}
+		expr = strings.TrimSpace(expr)
+
+		if strings.HasPrefix(expr, "$") {
+			value, err := evaluateResultPath(expr, outputs)
+			if err != nil {
+				return nil, fmt.Errorf("%w: field %q: %v", ErrInvalidResultMapping, field, err)
+			}
+			result[field] = value
+			continue
+		}
+
+		if env == nil {
+			var err error
+			env, err = cel.NewEnv(cel.Variable("outputs", cel.MapType(cel.StringType, cel.DynType)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+			}
+		}
+
+		value, err := evaluateResultCEL(env, expr, outputs)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %v", ErrInvalidResultMapping, field, err)
+		}
+		result[field] = value
+	}
+
+	return result, nil
+}
+
+// evaluateResultCEL runs a CEL expression and converts the result to plain JSON values
+func evaluateResultCEL(env *cel.Env, expr string, outputs map[string]interface{}) (interface{}, error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL expression: %w", issues.Err())
+	}
+
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	out, _, err := prg.Eval(map[string]interface{}{"outputs": outputs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate CEL expression: %w", err)
+	}
+
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("CEL result is not JSON-compatible: %w", err)
+	}
+	return native.(*structpb.Value).AsInterface(), nil
+}
+
+// evaluateResultPath resolves a JSONPath like $.node.items[0].name
+// Missing fields resolve to nil so optional outputs don't fail the whole result.
+func evaluateResultPath(expr string, outputs map[string]interface{}) (interface{}, error) {
+	path := strings.TrimPrefix(expr, "$")
+	if path == "" {
+		return outputs, nil
+	}
+	if !strings.HasPrefix(path, ".") {
+		return nil, fmt.Errorf("JSONPath must start with \"$.\": %s", expr)
+	}
+
+	var current interface{} = outputs
+	for _, segment := range strings.Split(path[1:], ".") {
+		name, indexes, err := splitPathSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath %s: %w", expr, err)
+		}
+
+		if name != "" {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, nil
+			}
+			current = obj[name]
+		}
+
+		for _, index := range indexes {
+			items, ok := current.([]interface{})
+			if !ok || index >= len(items) {
+				return nil, nil
+			}
+			current = items[index]
+		}
+	}
+
+	return current, nil
+}
+
+// splitPathSegment splits "items[0][1]" into "items" and [0, 1]
+func splitPathSegment(segment string) (string, []int, error) {
+	open := strings.Index(segment, "[")
+	if open < 0 {
+		if segment == "" {
+			return "", nil, fmt.Errorf("empty path segment")
+		}
+		return segment, nil, nil
+	}
+
+	name := segment[:open]
+	var indexes []int
+	for rest := segment[open:]; rest != ""; {
+		end := strings.Index(rest, "]")
+		if !strings.HasPrefix(rest, "[") || end < 0 {
+			return "", nil, fmt.Errorf("malformed index in %q", segment)
+		}
+		index, err := strconv.Atoi(rest[1:end])
+		if err != nil || index < 0 {
+			return "", nil, fmt.Errorf("invalid index in %q", segment)
+		}
+		indexes = append(indexes, index)
+		rest = rest[end+1:]
+	}
+	return name, indexes, nil
+}
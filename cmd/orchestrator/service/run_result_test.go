@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// seedRunResult stores a run IR and node outputs the way the runner leaves them
+func seedRunResult(t *testing.T, metadata map[string]interface{}) (*RunService, uuid.UUID, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	runID := uuid.New()
+	ir := sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"fetch":     {ID: "fetch", Type: "http", Dependents: []string{"summarize", "grade"}},
+			"summarize": {ID: "summarize", Type: "agent", IsTerminal: true},
+			"grade":     {ID: "grade", Type: "function", IsTerminal: true},
+		},
+		Metadata: metadata,
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	mr.Set("ir:"+runID.String(), string(irJSON))
+
+	outputs := map[string]map[string]interface{}{
+		"fetch":     {"status": 200, "body": "raw page"},
+		"summarize": {"text": "short summary", "tokens": 42, "topics": []interface{}{"go", "redis"}},
+		"grade":     {"score": 0.9, "passed": true},
+	}
+	for nodeID, output := range outputs {
+		data, err := json.Marshal(output)
+		require.NoError(t, err)
+		ref := "artifact://" + nodeID
+		mr.Set("cas:"+ref, string(data))
+		mr.HSet("context:"+runID.String(), nodeID+":output", ref)
+	}
+
+	return svc, runID, mr
+}
+
+func TestRunService_GetRunResult_Raw(t *testing.T) {
+	svc, runID, _ := seedRunResult(t, nil)
+
+	result, err := svc.GetRunResult(context.Background(), runID)
+	require.NoError(t, err)
+
+	assert.False(t, result.Mapped)
+	assert.Equal(t, []string{"grade", "summarize"}, result.TerminalNodes)
+	require.Len(t, result.Result, 2)
+	assert.Equal(t, "short summary", result.Result["summarize"].(map[string]interface{})["text"])
+	assert.Equal(t, true, result.Result["grade"].(map[string]interface{})["passed"])
+}
+
+func TestRunService_GetRunResult_Mapping(t *testing.T) {
+	svc, runID, _ := seedRunResult(t, map[string]interface{}{
+		sdk.MetadataResultMapping: map[string]interface{}{
+			"summary":     "$.summarize.text",
+			"first_topic": "$.summarize.topics[0]",
+			"missing":     "$.summarize.author",
+			"score":       "outputs.grade.score * 100.0",
+			"verdict":     `outputs.grade.passed ? "pass" : "fail"`,
+			"report":      `{"text": outputs.summarize.text, "tokens": outputs.summarize.tokens}`,
+		},
+	})
+
+	result, err := svc.GetRunResult(context.Background(), runID)
+	require.NoError(t, err)
+
+	assert.True(t, result.Mapped)
+	assert.Equal(t, map[string]interface{}{
+		"summary":     "short summary",
+		"first_topic": "go",
+		"missing":     nil,
+		"score":       90.0,
+		"verdict":     "pass",
+		"report":      map[string]interface{}{"text": "short summary", "tokens": 42.0},
+	}, result.Result)
+}
+
+func TestRunService_GetRunResult_InvalidMapping(t *testing.T) {
+	tests := map[string]interface{}{
+		"not an object":   []interface{}{"$.grade"},
+		"not a string":    map[string]interface{}{"score": 1},
+		"bad CEL":         map[string]interface{}{"score": "outputs.grade.score +"},
+		"bad JSONPath":    map[string]interface{}{"score": "$grade.score"},
+		"malformed index": map[string]interface{}{"topic": "$.summarize.topics[x]"},
+		"non-terminal":    map[string]interface{}{"body": "outputs.fetch.body"},
+	}
+
+	for name, mapping := range tests {
+		t.Run(name, func(t *testing.T) {
+			svc, runID, _ := seedRunResult(t, map[string]interface{}{sdk.MetadataResultMapping: mapping})
+
+			_, err := svc.GetRunResult(context.Background(), runID)
+			assert.ErrorIs(t, err, ErrInvalidResultMapping)
+		})
+	}
+}
+
+func TestRunService_GetRunResult_MissingRun(t *testing.T) {
+	svc, _, _ := seedRunResult(t, nil)
+
+	_, err := svc.GetRunResult(context.Background(), uuid.New())
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidResultMapping)
+}
+
+func TestRunService_FetchCASOutputs_PartialFailure(t *testing.T) {
+	svc, runID, mr := seedRunResult(t, nil)
+	ctx := context.Background()
+
+	// grade's output expired, fetch's was overwritten with garbage
+	mr.Del("cas:artifact://grade")
+	mr.Set("cas:artifact://fetch", "not json")
+
+	contextData, err := svc.loadContextData(ctx, runID)
+	require.NoError(t, err)
+	fetched, err := svc.bulkFetchAllCASFromContext(ctx, contextData)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"artifact://summarize"}, sortedKeys(fetched.Data))
+	assert.Equal(t, []string{"artifact://grade"}, fetched.Missing)
+	assert.Equal(t, []string{"artifact://fetch"}, fetched.Invalid)
+	assert.True(t, fetched.Unavailable("artifact://grade"))
+	assert.True(t, fetched.Unavailable("artifact://fetch"))
+	assert.False(t, fetched.Unavailable("artifact://summarize"))
+
+	// A result needing grade's output fails instead of returning its bare ref
+	_, err = svc.GetRunResult(ctx, runID)
+	assert.ErrorIs(t, err, ErrRunOutputUnavailable)
+	assert.ErrorContains(t, err, "nodes grade")
+
+	// So does a fixture, which mocks every node
+	_, err = svc.ExportRunFixture(ctx, runID)
+	assert.ErrorIs(t, err, ErrRunOutputUnavailable)
+	assert.ErrorContains(t, err, "nodes fetch, grade")
+}
+
+// memoryCAS is a result CAS that isn't Redis, like the S3 backend
+type memoryCAS struct {
+	blobs map[string][]byte
+}
+
+func (m *memoryCAS) Put(_ context.Context, data []byte, _ string) (string, error) {
+	id := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	m.blobs[id] = data
+	return id, nil
+}
+
+func (m *memoryCAS) Get(_ context.Context, casID string) (interface{}, error) {
+	data, ok := m.blobs[casID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", clients.ErrCASNotFound, casID)
+	}
+	return data, nil
+}
+
+func (m *memoryCAS) Store(ctx context.Context, data interface{}) (string, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return m.Put(ctx, jsonData, "application/json")
+}
+
+func TestRunService_FetchCASOutputs_ReadsResultCAS(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+
+	log := logger.New("error", "text")
+	results := &memoryCAS{blobs: map[string][]byte{}}
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+		ResultCAS:  results,
+	})
+
+	// Two nodes with the same output share its content-addressed ref
+	shared, err := results.Store(ctx, map[string]interface{}{"token": "secret", "ok": true})
+	require.NoError(t, err)
+	contextData := map[string]string{
+		"a:output": shared,
+		"b:output": shared,
+		"c:output": "sha256:expired",
+	}
+
+	fetched, err := svc.bulkFetchAllCASFromContext(ctx, contextData)
+	require.NoError(t, err)
+	assert.Equal(t, []string{shared}, sortedKeys(fetched.Data))
+	assert.Equal(t, []string{"sha256:expired"}, fetched.Missing)
+
+	// Each node gets its own copy, so one node's masking doesn't leak into the other
+	nodeOutputs := svc.buildNodeOutputsRaw(ctx, contextData, fetched.Data)
+	maskNodeOutputs(map[string]interface{}{"nodes": map[string]interface{}{
+		"a": map[string]interface{}{"config": map[string]interface{}{"redact": []interface{}{map[string]interface{}{"path": "token"}}}},
+		"b": map[string]interface{}{},
+	}}, nodeOutputs)
+	assert.NotEqual(t, "secret", nodeOutputs["a"].(map[string]interface{})["token"])
+	assert.Equal(t, "secret", nodeOutputs["b"].(map[string]interface{})["token"])
+}
+
+func TestRunService_FetchCASOutputs_VerifiesSignedResults(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+
+	log := logger.New("error", "text")
+	results := clients.NewRedisCASClient(rdb, log, clients.WithSigningKey("0123456789abcdef0123456789abcdef"))
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+		ResultCAS:  results,
+	})
+
+	signed, err := results.Store(ctx, map[string]interface{}{"ok": true})
+	require.NoError(t, err)
+	unsigned, err := clients.NewRedisCASClient(rdb, log).Store(ctx, map[string]interface{}{"ok": false})
+	require.NoError(t, err)
+
+	fetched, err := svc.fetchCASOutputs(ctx, []string{signed, unsigned})
+	require.NoError(t, err)
+	assert.Equal(t, []string{signed}, sortedKeys(fetched.Data))
+	assert.Equal(t, []string{unsigned}, fetched.Invalid)
+	assert.True(t, fetched.Unavailable(unsigned))
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// RunState is the raw coordinator state of a run, for debugging stuck runs
+// Internal: key layouts and values are the coordinator's own and may change
+// without notice. Nothing is redacted, so it is only served to admins.
+type RunState struct {
+	Internal             bool                              `json:"internal"` // Always true
+	RunID                string                            `json:"run_id"`
+	Counter              int                               `json:"counter"`                // Tokens in flight; the run completes at 0
+	AppliedOps           int64                             `json:"applied_ops"`            // Size of the idempotent counter operations set
+	NodeStatuses         map[string]string                 `json:"node_statuses"`          // Node ID → status key value; nodes not started are absent
+	Loops                map[string]map[string]string      `json:"loops"`                  // Node ID → loop iteration hash
+	PendingApprovals     map[string]map[string]interface{} `json:"pending_approvals"`      // Node ID → approval request still pending
+	PendingApprovalCount int64                             `json:"pending_approval_count"` // The run's pending approvals counter
+}
+
+// GetRunState assembles the coordinator's Redis state for a run
+// Returns ErrRunNotFound if the run's IR is gone (unknown or cleaned-up run).
+func (s *RunService) GetRunState(ctx context.Context, runID uuid.UUID) (*RunState, error) {
+	id := runID.String()
+
+	irJSON, err := s.redis.Get(ctx, fmt.Sprintf("ir:%s", id))
+	if errors.Is(err, rediscommon.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: no IR for run %s", ErrRunNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IR from Redis: %w", err)
+	}
+	var ir struct {
+		Nodes map[string]json.RawMessage `json:"nodes"`
+	}
+	if err := json.Unmarshal([]byte(irJSON), &ir); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal IR: %w", err)
+	}
+
+	rdb := s.redis.GetUnderlying()
+	counter, err := sdk.NewSDK(rdb, nil, s.components.Logger, "").GetCounter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &RunState{
+		Internal:         true,
+		RunID:            id,
+		Counter:          counter,
+		NodeStatuses:     make(map[string]string),
+		Loops:            make(map[string]map[string]string),
+		PendingApprovals: make(map[string]map[string]interface{}),
+	}
+
+	// Everything else in one round-trip
+	pipe := rdb.Pipeline()
+	applied := pipe.SCard(ctx, sdk.AppliedKey(id))
+	approvalCount := pipe.Get(ctx, fmt.Sprintf("run:%s:pending_approvals", id))
+	statuses := make(map[string]*redis.StringCmd, len(ir.Nodes))
+	loops := make(map[string]*redis.MapStringStringCmd, len(ir.Nodes))
+	approvals := make(map[string]*redis.StringCmd, len(ir.Nodes))
+	for nodeID := range ir.Nodes {
+		statuses[nodeID] = pipe.Get(ctx, sdk.NodeStatusKey(id, nodeID))
+		loops[nodeID] = pipe.HGetAll(ctx, fmt.Sprintf("loop:%s:%s", id, nodeID))
+		approvals[nodeID] = pipe.Get(ctx, fmt.Sprintf("hitl:approval:%s:%s", id, nodeID))
+	}
+	// redis.Nil only means some keys are absent
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to load run state: %w", err)
+	}
+
+	state.AppliedOps = applied.Val()
+	state.PendingApprovalCount, _ = approvalCount.Int64()
+	for nodeID := range ir.Nodes {
+		if status := statuses[nodeID].Val(); status != "" {
+			state.NodeStatuses[nodeID] = status
+		}
+		if loop := loops[nodeID].Val(); len(loop) > 0 {
+			state.Loops[nodeID] = loop
+		}
+		raw := approvals[nodeID].Val()
+		if raw == "" {
+			continue
+		}
+		var approval map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &approval); err != nil {
+			s.components.Logger.Warn("failed to parse approval", "run_id", id, "node_id", nodeID, "error", err)
+			continue
+		}
+		if approval["status"] == "pending" {
+			state.PendingApprovals[nodeID] = approval
+		}
+	}
+
+	return state, nil
+}
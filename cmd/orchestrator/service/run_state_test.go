@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+func TestRunService_GetRunState(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	// fetch → each (loop, on its third iteration) → approve (waiting) → notify
+	runID := uuid.New()
+	id := runID.String()
+	mr.Set("ir:"+id, `{"nodes": {"fetch": {}, "each": {}, "approve": {}, "notify": {}}}`)
+	mr.Set(sdk.CounterKey(id), "2")
+	for _, op := range []string{"emit:fetch", "consume:fetch", "emit:each->approve"} {
+		_, err := mr.SetAdd(sdk.AppliedKey(id), op)
+		require.NoError(t, err)
+	}
+	mr.Set(sdk.NodeStatusKey(id, "fetch"), sdk.NodeStatusCompleted)
+	mr.Set(sdk.NodeStatusKey(id, "each"), sdk.NodeStatusRunning)
+	mr.Set(sdk.NodeStatusKey(id, "approve"), sdk.NodeStatusWaitingForApproval)
+	mr.HSet("loop:"+id+":each", "current_iteration", "3", "max_iterations", "10")
+	mr.Set("hitl:approval:"+id+":approve", `{"node_id": "approve", "status": "pending", "message": "Ship it?"}`)
+	mr.Set("run:"+id+":pending_approvals", "1")
+
+	// Another run's state is not included
+	mr.Set(sdk.NodeStatusKey(uuid.NewString(), "fetch"), sdk.NodeStatusFailed)
+
+	state, err := svc.GetRunState(context.Background(), runID)
+	require.NoError(t, err)
+
+	assert.Equal(t, &RunState{
+		Internal:   true,
+		RunID:      id,
+		Counter:    2,
+		AppliedOps: 3,
+		NodeStatuses: map[string]string{
+			"fetch":   sdk.NodeStatusCompleted,
+			"each":    sdk.NodeStatusRunning,
+			"approve": sdk.NodeStatusWaitingForApproval,
+		},
+		Loops: map[string]map[string]string{
+			"each": {"current_iteration": "3", "max_iterations": "10"},
+		},
+		PendingApprovals: map[string]map[string]interface{}{
+			"approve": {"node_id": "approve", "status": "pending", "message": "Ship it?"},
+		},
+		PendingApprovalCount: 1,
+	}, state)
+
+	t.Run("decided approvals are not pending", func(t *testing.T) {
+		mr.Set("hitl:approval:"+id+":approve", `{"node_id": "approve", "status": "approved"}`)
+
+		state, err := svc.GetRunState(context.Background(), runID)
+		require.NoError(t, err)
+		assert.Empty(t, state.PendingApprovals)
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		_, err := svc.GetRunState(context.Background(), uuid.New())
+		assert.ErrorIs(t, err, ErrRunNotFound)
+	})
+}
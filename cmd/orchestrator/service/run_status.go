@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// DerivedStatus is a run's status as derived from its node executions
+// It is computed on every read and reflects the run's live state before the
+// workflow runner has written it back. Run.Status, the persisted status, stays
+// authoritative: it is the one run listings, filters and retries go by, and the
+// only one that records cancellation.
+type DerivedStatus struct {
+	Status    models.RunStatus `json:"status"`
+	Total     int              `json:"total"`
+	Completed int              `json:"completed"` // Includes failures handled by on_error handlers
+	Failed    int              `json:"failed"`
+	Waiting   int              `json:"waiting"` // Waiting for approval
+	Running   int              `json:"running"` // Dispatched, or waiting for a callback
+}
+
+// DeriveRunStatus derives a run's status from its node executions
+// Priority order (most important first):
+//  1. Any node failed → FAILED
+//  2. Any node waiting for approval → WAITING_FOR_APPROVAL
+//  3. All nodes completed → COMPLETED
+//  4. Any node completed or running → RUNNING
+//  5. Otherwise → QUEUED
+//
+// Failures routed to error handlers (NodeExecution.Handled) count as completed.
+func DeriveRunStatus(nodeExecutions map[string]*NodeExecution) *DerivedStatus {
+	derived := &DerivedStatus{Total: len(nodeExecutions)}
+
+	for _, execution := range nodeExecutions {
+		switch execution.Status {
+		case sdk.NodeStatusWaitingForApproval:
+			derived.Waiting++
+		case sdk.NodeStatusRunning, sdk.NodeStatusWaitingForCallback:
+			derived.Running++
+		case sdk.NodeStatusFailed, "error": // Treat error same as failed
+			if execution.Handled {
+				derived.Completed++
+			} else {
+				derived.Failed++
+			}
+		case sdk.NodeStatusCompleted:
+			derived.Completed++
+		}
+	}
+
+	switch {
+	case derived.Failed > 0:
+		derived.Status = models.StatusFailed
+	case derived.Waiting > 0:
+		derived.Status = models.StatusWaitingForApproval
+	case derived.Total > 0 && derived.Completed == derived.Total:
+		derived.Status = models.StatusCompleted
+	case derived.Completed > 0 || derived.Running > 0:
+		derived.Status = models.StatusRunning
+	default:
+		derived.Status = models.StatusQueued
+	}
+
+	return derived
+}
+
+// MaxRunStatusBatch caps how many runs a single bulk status lookup may ask for
+const MaxRunStatusBatch = 100
+
+// ErrTooManyRuns is returned for a bulk status lookup over MaxRunStatusBatch runs
+var ErrTooManyRuns = fmt.Errorf("at most %d runs can be requested at once", MaxRunStatusBatch)
+
+// runStatusStore is the subset of RunRepository used by GetRunStatuses
+type runStatusStore interface {
+	GetStatuses(ctx context.Context, runIDs []uuid.UUID) (map[uuid.UUID]models.RunStatus, error)
+}
+
+// RunStatusSummary is the compact status of a run for dashboards
+type RunStatusSummary struct {
+	Status         models.RunStatus `json:"status"`          // Persisted status
+	CompletedNodes int              `json:"completed_nodes"` // Nodes recorded as completed in Redis
+	TotalNodes     int              `json:"total_nodes"`     // Nodes in the run's IR; 0 once its state has expired
+}
+
+// GetRunStatuses returns the compact status of many runs, keyed by run ID
+// Persisted statuses come from a single query and node counts from two
+// pipelined Redis reads (IRs, then node statuses), so the cost doesn't grow in
+// round-trips with the number of runs. Runs that don't exist are omitted.
+func (s *RunService) GetRunStatuses(ctx context.Context, runIDs []uuid.UUID) (map[string]*RunStatusSummary, error) {
+	if len(runIDs) > MaxRunStatusBatch {
+		return nil, ErrTooManyRuns
+	}
+
+	statuses, err := s.statusStore.GetStatuses(ctx, runIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]*RunStatusSummary, len(statuses))
+	irKeys := make([]string, 0, len(statuses))
+	for runID, status := range statuses {
+		summaries[runID.String()] = &RunStatusSummary{Status: status}
+		irKeys = append(irKeys, fmt.Sprintf("ir:%s", runID))
+	}
+	if len(irKeys) == 0 {
+		return summaries, nil
+	}
+
+	irs, err := s.redis.GetMultiple(ctx, irKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run IRs: %w", err)
+	}
+
+	var statusKeys []string
+	statusKeyRuns := make(map[string]*RunStatusSummary)
+	for runID, summary := range summaries {
+		irJSON, ok := irs[fmt.Sprintf("ir:%s", runID)]
+		if !ok {
+			continue
+		}
+		var ir struct {
+			Nodes map[string]json.RawMessage `json:"nodes"`
+		}
+		if err := json.Unmarshal([]byte(irJSON), &ir); err != nil {
+			s.components.Logger.Warn("failed to parse run IR", "run_id", runID, "error", err)
+			continue
+		}
+		summary.TotalNodes = len(ir.Nodes)
+		for nodeID := range ir.Nodes {
+			key := sdk.NodeStatusKey(runID, nodeID)
+			statusKeys = append(statusKeys, key)
+			statusKeyRuns[key] = summary
+		}
+	}
+
+	nodeStatuses, err := s.redis.GetMultiple(ctx, statusKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load node statuses: %w", err)
+	}
+	for key, status := range nodeStatuses {
+		if status == sdk.NodeStatusCompleted {
+			statusKeyRuns[key].CompletedNodes++
+		}
+	}
+
+	return summaries, nil
+}
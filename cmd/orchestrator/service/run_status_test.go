@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// executions builds node executions from node ID → status
+func executions(statuses map[string]string) map[string]*NodeExecution {
+	nodeExecutions := make(map[string]*NodeExecution, len(statuses))
+	for nodeID, status := range statuses {
+		nodeExecutions[nodeID] = &NodeExecution{NodeID: nodeID, Status: status}
+	}
+	return nodeExecutions
+}
+
+func TestDeriveRunStatus_Priority(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses map[string]string
+		want     models.RunStatus
+	}{
+		{
+			name:     "failed_beats_waiting",
+			statuses: map[string]string{"A": "completed", "B": "failed", "C": "waiting_for_approval"},
+			want:     models.StatusFailed,
+		},
+		{
+			name:     "error_counts_as_failed",
+			statuses: map[string]string{"A": "completed", "B": "error"},
+			want:     models.StatusFailed,
+		},
+		{
+			name:     "waiting_beats_running",
+			statuses: map[string]string{"A": "completed", "B": "waiting_for_approval", "C": "running"},
+			want:     models.StatusWaitingForApproval,
+		},
+		{
+			name:     "all_completed",
+			statuses: map[string]string{"A": "completed", "B": "completed"},
+			want:     models.StatusCompleted,
+		},
+		{
+			name:     "partly_completed",
+			statuses: map[string]string{"A": "completed", "B": "not_executed"},
+			want:     models.StatusRunning,
+		},
+		{
+			name:     "running_only",
+			statuses: map[string]string{"A": "running", "B": "not_executed"},
+			want:     models.StatusRunning,
+		},
+		{
+			name:     "waiting_for_callback_is_running",
+			statuses: map[string]string{"A": "waiting_for_callback"},
+			want:     models.StatusRunning,
+		},
+		{
+			name:     "nothing_executed",
+			statuses: map[string]string{"A": "not_executed", "B": "not_executed"},
+			want:     models.StatusQueued,
+		},
+		{
+			name:     "no_nodes",
+			statuses: map[string]string{},
+			want:     models.StatusQueued,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeriveRunStatus(executions(tt.statuses)).Status)
+		})
+	}
+}
+
+func TestDeriveRunStatus_Counts(t *testing.T) {
+	derived := DeriveRunStatus(executions(map[string]string{
+		"A": "completed",
+		"B": "completed",
+		"C": "failed",
+		"D": "waiting_for_approval",
+		"E": "running",
+		"F": "not_executed",
+	}))
+
+	assert.Equal(t, &DerivedStatus{
+		Status:    models.StatusFailed,
+		Total:     6,
+		Completed: 2,
+		Failed:    1,
+		Waiting:   1,
+		Running:   1,
+	}, derived)
+}
+
+func TestDeriveRunStatus_HandledFailure(t *testing.T) {
+	nodeExecutions := executions(map[string]string{"A": "completed", "B": "failed", "handler": "completed"})
+	nodeExecutions["B"].Handled = true
+
+	derived := DeriveRunStatus(nodeExecutions)
+	assert.Equal(t, models.StatusCompleted, derived.Status)
+	assert.Equal(t, 3, derived.Completed)
+	assert.Equal(t, 0, derived.Failed)
+}
+
+// countingStatusStore serves run statuses and counts the queries issued
+type countingStatusStore struct {
+	statuses map[uuid.UUID]models.RunStatus
+	queries  int
+}
+
+func (f *countingStatusStore) GetStatuses(ctx context.Context, runIDs []uuid.UUID) (map[uuid.UUID]models.RunStatus, error) {
+	f.queries++
+	statuses := make(map[uuid.UUID]models.RunStatus)
+	for _, runID := range runIDs {
+		if status, ok := f.statuses[runID]; ok {
+			statuses[runID] = status
+		}
+	}
+	return statuses, nil
+}
+
+// roundTripHook counts Redis round-trips: single commands and whole pipelines
+type roundTripHook struct {
+	commands  int
+	pipelines int
+}
+
+func (h *roundTripHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *roundTripHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.commands++
+		return next(ctx, cmd)
+	}
+}
+
+func (h *roundTripHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.pipelines++
+		return next(ctx, cmds)
+	}
+}
+
+func TestRunService_GetRunStatuses_Batched(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+	store := &countingStatusStore{statuses: make(map[uuid.UUID]models.RunStatus)}
+	svc.statusStore = store
+
+	// 49 runs of fetch → score → report with fetch completed; the 50th doesn't exist
+	irJSON := `{"nodes": {"fetch": {}, "score": {}, "report": {}}}`
+	runIDs := make([]uuid.UUID, 50)
+	for i := range runIDs {
+		runIDs[i] = uuid.New()
+		if i == len(runIDs)-1 {
+			continue
+		}
+		runID := runIDs[i].String()
+		store.statuses[runIDs[i]] = models.StatusRunning
+		mr.Set("ir:"+runID, irJSON)
+		mr.Set(sdk.NodeStatusKey(runID, "fetch"), sdk.NodeStatusCompleted)
+		mr.Set(sdk.NodeStatusKey(runID, "score"), sdk.NodeStatusRunning)
+	}
+	// One run finished and one has no state left in Redis
+	store.statuses[runIDs[0]] = models.StatusCompleted
+	for _, node := range []string{"score", "report"} {
+		mr.Set(sdk.NodeStatusKey(runIDs[0].String(), node), sdk.NodeStatusCompleted)
+	}
+	store.statuses[runIDs[1]] = models.StatusFailed
+	mr.Del("ir:" + runIDs[1].String())
+
+	// Open the connection first so its handshake isn't counted
+	require.NoError(t, rdb.Ping(context.Background()).Err())
+	hook := &roundTripHook{}
+	rdb.AddHook(hook)
+
+	statuses, err := svc.GetRunStatuses(context.Background(), runIDs)
+	require.NoError(t, err)
+
+	// One query, two pipelines (IRs, node statuses) and no per-run commands
+	assert.Equal(t, 1, store.queries)
+	assert.Equal(t, 2, hook.pipelines)
+	assert.Equal(t, 0, hook.commands)
+
+	require.Len(t, statuses, 49)
+	assert.NotContains(t, statuses, runIDs[49].String())
+	assert.Equal(t, &RunStatusSummary{Status: models.StatusCompleted, CompletedNodes: 3, TotalNodes: 3}, statuses[runIDs[0].String()])
+	assert.Equal(t, &RunStatusSummary{Status: models.StatusFailed}, statuses[runIDs[1].String()])
+	for _, runID := range runIDs[2:49] {
+		assert.Equal(t, &RunStatusSummary{Status: models.StatusRunning, CompletedNodes: 1, TotalNodes: 3}, statuses[runID.String()], runID)
+	}
+}
+
+func TestRunService_GetRunStatuses_Cap(t *testing.T) {
+	svc := NewRunService(&RunServiceOpts{})
+	store := &countingStatusStore{}
+	svc.statusStore = store
+
+	_, err := svc.GetRunStatuses(context.Background(), make([]uuid.UUID, MaxRunStatusBatch+1))
+	assert.ErrorIs(t, err, ErrTooManyRuns)
+	assert.Equal(t, 0, store.queries, fmt.Sprintf("no query over %d runs", MaxRunStatusBatch))
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// inFlightNodeStatuses are node statuses with no completion time yet
+var inFlightNodeStatuses = map[string]bool{
+	sdk.NodeStatusRunning:            true,
+	sdk.NodeStatusWaitingForApproval: true,
+	sdk.NodeStatusWaitingForCallback: true,
+}
+
+// applyNodeTiming fills in a node's start, completion and wall-clock duration
+// The start time recorded by the worker when it picked the node up (startedAt,
+// see sdk.RecordNodeStarted) wins over the start_time in the node's metrics, so
+// nodes that wait (e.g. HITL approvals) are measured from when they started
+// waiting. Nodes still in flight keep a nil CompletedAt and duration.
+func applyNodeTiming(execution *NodeExecution, startedAt string) {
+	var start, end time.Time
+	if t, err := time.Parse(time.RFC3339Nano, startedAt); err == nil {
+		start = t
+	}
+	if execution.Metrics != nil {
+		if t, err := time.Parse(time.RFC3339Nano, execution.Metrics.StartTime); err == nil && start.IsZero() {
+			start = t
+		}
+		if t, err := time.Parse(time.RFC3339Nano, execution.Metrics.EndTime); err == nil {
+			end = t
+		}
+	}
+
+	if !start.IsZero() {
+		execution.StartedAt = &start
+	}
+	if inFlightNodeStatuses[execution.Status] || end.IsZero() {
+		return
+	}
+	execution.CompletedAt = &end
+
+	if !start.IsZero() {
+		durationMs := end.Sub(start).Milliseconds()
+		if durationMs < 0 {
+			durationMs = 0
+		}
+		execution.DurationMs = &durationMs
+	}
+}
+
+// criticalPath returns the longest chain of node durations through the IR
+// Each node's chain is its own duration plus the longest chain among its
+// dependencies; nodes without a duration count as zero. Dependency cycles
+// (loop back edges) are cut where they are found. Returns the total duration
+// and the node IDs along the path, first to last.
+func criticalPath(workflowIR map[string]interface{}, executions map[string]*NodeExecution) (int64, []string) {
+	nodes, _ := workflowIR["nodes"].(map[string]interface{})
+
+	type chain struct {
+		durationMs int64
+		previous   string
+	}
+	chains := make(map[string]chain, len(nodes))
+	visiting := make(map[string]bool)
+
+	var longest func(nodeID string) int64
+	longest = func(nodeID string) int64 {
+		if c, done := chains[nodeID]; done {
+			return c.durationMs
+		}
+		visiting[nodeID] = true
+		defer delete(visiting, nodeID)
+
+		var c chain
+		node, _ := nodes[nodeID].(map[string]interface{})
+		deps, _ := node["dependencies"].([]interface{})
+		for _, raw := range deps {
+			dep, ok := raw.(string)
+			if !ok || nodes[dep] == nil || visiting[dep] {
+				continue
+			}
+			if d := longest(dep); d > c.durationMs || c.previous == "" {
+				c.durationMs, c.previous = d, dep
+			}
+		}
+		if execution := executions[nodeID]; execution != nil && execution.DurationMs != nil {
+			c.durationMs += *execution.DurationMs
+		}
+		chains[nodeID] = c
+		return c.durationMs
+	}
+
+	// Sorted so ties resolve the same way on every call
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var total int64
+	var last string
+	for _, id := range ids {
+		if d := longest(id); d > total || last == "" {
+			total, last = d, id
+		}
+	}
+
+	var path []string
+	for id := last; id != ""; id = chains[id].previous {
+		path = append([]string{id}, path...)
+	}
+	return total, path
+}
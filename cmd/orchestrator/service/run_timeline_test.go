@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// timelineIR builds fetch → (score, review) → report as a generic IR map
+func timelineIR(t *testing.T) map[string]interface{} {
+	t.Helper()
+	ir := sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"fetch":  {ID: "fetch", Type: "http", Dependents: []string{"score", "review"}},
+			"score":  {ID: "score", Type: "http", Dependencies: []string{"fetch"}, Dependents: []string{"report"}},
+			"review": {ID: "review", Type: "hitl", Dependencies: []string{"fetch"}, Dependents: []string{"report"}},
+			"report": {ID: "report", Type: "http", Dependencies: []string{"score", "review"}, IsTerminal: true},
+		},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	var workflowIR map[string]interface{}
+	require.NoError(t, json.Unmarshal(irJSON, &workflowIR))
+	return workflowIR
+}
+
+func nodeOutput(start, end time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"status": "completed",
+		"metrics": map[string]interface{}{
+			"start_time":        start.Format(time.RFC3339Nano),
+			"end_time":          end.Format(time.RFC3339Nano),
+			"execution_time_ms": float64(end.Sub(start).Milliseconds()),
+		},
+	}
+}
+
+func TestRunService_BuildNodeExecutions_Timeline(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	ctx := context.Background()
+	run := &models.Run{RunID: uuid.New(), Status: models.StatusRunning}
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// fetch and score completed; review is still waiting for approval
+	require.NoError(t, sdk.RecordNodeStarted(ctx, rdb, run.RunID.String(), "review", base.Add(1500*time.Millisecond)))
+	mr.Set(sdk.NodeStatusKey(run.RunID.String(), "review"), sdk.NodeStatusWaitingForApproval)
+
+	outputs := map[string]interface{}{
+		"fetch": nodeOutput(base, base.Add(1500*time.Millisecond)),
+		"score": nodeOutput(base.Add(1600*time.Millisecond), base.Add(2100*time.Millisecond)),
+	}
+
+	executions := svc.buildNodeExecutions(ctx, run, timelineIR(t), outputs)
+
+	fetch := executions["fetch"]
+	require.NotNil(t, fetch.StartedAt)
+	require.NotNil(t, fetch.CompletedAt)
+	assert.True(t, base.Equal(*fetch.StartedAt))
+	assert.True(t, base.Add(1500*time.Millisecond).Equal(*fetch.CompletedAt))
+	require.NotNil(t, fetch.DurationMs)
+	assert.Equal(t, int64(1500), *fetch.DurationMs)
+
+	assert.Equal(t, int64(500), *executions["score"].DurationMs)
+
+	// Running nodes have a start but no completion or duration yet
+	review := executions["review"]
+	assert.Equal(t, sdk.NodeStatusWaitingForApproval, review.Status)
+	require.NotNil(t, review.StartedAt)
+	assert.True(t, base.Add(1500*time.Millisecond).Equal(*review.StartedAt))
+	assert.Nil(t, review.CompletedAt)
+	assert.Nil(t, review.DurationMs)
+
+	report := executions["report"]
+	assert.Nil(t, report.StartedAt)
+	assert.Nil(t, report.DurationMs)
+
+	total, path := criticalPath(timelineIR(t), executions)
+	assert.Equal(t, int64(2000), total)
+	assert.Equal(t, []string{"fetch", "score", "report"}, path)
+}
+
+func TestApplyNodeTiming_RecordedStartWins(t *testing.T) {
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// An approval node's metrics only cover handling the decision
+	execution := &NodeExecution{
+		Status:  "completed",
+		Metrics: parseMetrics(nodeOutput(base.Add(time.Minute), base.Add(time.Minute+20*time.Millisecond))["metrics"].(map[string]interface{})),
+	}
+	applyNodeTiming(execution, base.Format(time.RFC3339Nano))
+
+	assert.True(t, base.Equal(*execution.StartedAt))
+	assert.Equal(t, int64(60020), *execution.DurationMs)
+}
+
+func TestCriticalPath_PicksLongestChain(t *testing.T) {
+	durations := map[string]int64{"fetch": 100, "score": 300, "review": 50, "report": 20}
+	executions := make(map[string]*NodeExecution)
+	for id, ms := range durations {
+		ms := ms
+		executions[id] = &NodeExecution{NodeID: id, Status: "completed", DurationMs: &ms}
+	}
+
+	total, path := criticalPath(timelineIR(t), executions)
+	assert.Equal(t, int64(420), total)
+	assert.Equal(t, []string{"fetch", "score", "report"}, path)
+
+	// Dependency cycles (loop back edges) don't recurse forever
+	workflowIR := timelineIR(t)
+	fetch := workflowIR["nodes"].(map[string]interface{})["fetch"].(map[string]interface{})
+	fetch["dependencies"] = []interface{}{"report"}
+	total, _ = criticalPath(workflowIR, executions)
+	assert.Equal(t, int64(420), total)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+var (
+	ErrWebhookNotPending   = errors.New("webhook is not waiting for a callback")
+	ErrWebhookInvalidToken = errors.New("invalid webhook callback token")
+	ErrInvalidCallback     = errors.New("invalid webhook callback")
+)
+
+// CompleteWebhook accepts the result of an async webhook node
+// The callback token must match the one sent in the node's callback URL. The
+// result is handed to the webhook worker, which signals the node's completion.
+func (s *RunService) CompleteWebhook(ctx context.Context, runID uuid.UUID, nodeID, callbackToken string, callback *sdk.WebhookCallback) error {
+	// 1. The node must be suspended on a callback
+	data, err := s.redis.Get(ctx, sdk.WebhookPendingKey(runID.String(), nodeID))
+	if errors.Is(err, rediscommon.ErrKeyNotFound) {
+		return fmt.Errorf("%w: %s", ErrWebhookNotPending, nodeID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load pending webhook: %w", err)
+	}
+
+	var pending sdk.WebhookPending
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		return fmt.Errorf("failed to unmarshal pending webhook: %w", err)
+	}
+
+	// 2. Only the holder of the callback URL may complete it
+	if subtle.ConstantTimeCompare([]byte(callbackToken), []byte(pending.CallbackToken)) != 1 {
+		return ErrWebhookInvalidToken
+	}
+
+	if pending.Status != sdk.WebhookStatusPending {
+		return fmt.Errorf("%w: node %s is %s", ErrWebhookNotPending, nodeID, pending.Status)
+	}
+
+	// 3. Hand the result to the webhook worker
+	callback.RunID = runID.String()
+	callback.NodeID = nodeID
+	if callback.Status == "" {
+		callback.Status = sdk.WebhookStatusCompleted
+	}
+	if callback.Status != sdk.WebhookStatusCompleted && callback.Status != sdk.WebhookStatusFailed {
+		return fmt.Errorf("%w: status must be %s or %s", ErrInvalidCallback, sdk.WebhookStatusCompleted, sdk.WebhookStatusFailed)
+	}
+
+	callbackJSON, err := json.Marshal(callback)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook callback: %w", err)
+	}
+
+	if _, err := s.redis.AddToStream(ctx, sdk.WebhookCallbackStream, map[string]interface{}{
+		"callback": string(callbackJSON),
+		"run_id":   runID.String(),
+		"node_id":  nodeID,
+	}); err != nil {
+		return fmt.Errorf("failed to queue webhook callback: %w", err)
+	}
+
+	s.components.Logger.Info("webhook callback accepted",
+		"run_id", runID,
+		"node_id", nodeID,
+		"status", callback.Status)
+
+	return nil
+}
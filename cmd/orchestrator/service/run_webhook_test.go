@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+func TestCompleteWebhook(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	log := logger.New("error", "text")
+	svc := NewRunService(&RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+
+	runID := uuid.New()
+	pending, err := json.Marshal(sdk.WebhookPending{
+		RunID:         runID.String(),
+		NodeID:        "notify",
+		TokenID:       "token-1",
+		CallbackToken: "s3cret",
+		Status:        sdk.WebhookStatusPending,
+	})
+	require.NoError(t, err)
+	mr.Set(sdk.WebhookPendingKey(runID.String(), "notify"), string(pending))
+
+	result := &sdk.WebhookCallback{Result: map[string]interface{}{"ok": true}}
+
+	err = svc.CompleteWebhook(ctx, runID, "other", "s3cret", result)
+	assert.ErrorIs(t, err, ErrWebhookNotPending)
+
+	err = svc.CompleteWebhook(ctx, runID, "notify", "wrong", result)
+	assert.ErrorIs(t, err, ErrWebhookInvalidToken)
+
+	err = svc.CompleteWebhook(ctx, runID, "notify", "s3cret", &sdk.WebhookCallback{Status: "maybe"})
+	assert.ErrorIs(t, err, ErrInvalidCallback)
+
+	require.NoError(t, svc.CompleteWebhook(ctx, runID, "notify", "s3cret", result))
+
+	entries, err := rdb.XRange(ctx, sdk.WebhookCallbackStream, "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	var queued sdk.WebhookCallback
+	require.NoError(t, json.Unmarshal([]byte(entries[0].Values["callback"].(string)), &queued))
+	assert.Equal(t, sdk.WebhookCallback{
+		RunID:  runID.String(),
+		NodeID: "notify",
+		Status: sdk.WebhookStatusCompleted,
+		Result: map[string]interface{}{"ok": true},
+	}, queued)
+}
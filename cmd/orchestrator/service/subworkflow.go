@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NodeTypeSubworkflow is a node that runs another tagged workflow inline
+// Config: {"tag": "child-tag", "inputs_map": {"name": "$nodes.fetch.body", ...}}
+const NodeTypeSubworkflow = "subworkflow"
+
+// MaxSubworkflowDepth bounds how deeply subworkflows may nest
+const MaxSubworkflowDepth = 5
+
+// ErrInvalidSubworkflow is returned for unresolvable, recursive or too deeply nested subworkflows
+var ErrInvalidSubworkflow = errors.New("invalid subworkflow")
+
+// nodeRefPattern matches $nodes.<id>[.<field>...] references in node configs
+var nodeRefPattern = regexp.MustCompile(`\$nodes\.([A-Za-z0-9_.\-]+)`)
+
+// ExpandSubworkflows inlines every subworkflow node of a materialized workflow
+// Each referenced tag is resolved with GetWorkflowComponents and materialized,
+// then its nodes are added with IDs namespaced by the subworkflow node
+// ("parent.child"). Edges into the subworkflow node go to the child's entry
+// nodes and edges out of it leave from the child's terminal nodes. Nested
+// subworkflows are expanded first; recursive references and nesting beyond
+// MaxSubworkflowDepth are rejected. tag names the workflow being expanded.
+func (s *WorkflowServiceV2) ExpandSubworkflows(ctx context.Context, username, tag string, workflow map[string]interface{}) (map[string]interface{}, error) {
+	return s.expandSubworkflows(ctx, username, workflow, []string{tag})
+}
+
+// expandSubworkflows expands workflow, where stack holds the tags being expanded
+func (s *WorkflowServiceV2) expandSubworkflows(ctx context.Context, username string, workflow map[string]interface{}, stack []string) (map[string]interface{}, error) {
+	nodes, _ := workflow["nodes"].([]interface{})
+	edges, _ := workflow["edges"].([]interface{})
+
+	hasSubworkflow := false
+	for _, raw := range nodes {
+		if node, ok := raw.(map[string]interface{}); ok && node["type"] == NodeTypeSubworkflow {
+			hasSubworkflow = true
+			break
+		}
+	}
+	if !hasSubworkflow {
+		return workflow, nil
+	}
+
+	expandedNodes := make([]interface{}, 0, len(nodes))
+	entries := make(map[string][]string)   // subworkflow node → namespaced child entry nodes
+	terminals := make(map[string][]string) // subworkflow node → namespaced child terminal nodes
+	var childEdges []interface{}
+
+	for _, raw := range nodes {
+		node, ok := raw.(map[string]interface{})
+		if !ok || node["type"] != NodeTypeSubworkflow {
+			expandedNodes = append(expandedNodes, raw)
+			continue
+		}
+
+		nodeID, _ := node["id"].(string)
+		config, _ := node["config"].(map[string]interface{})
+		tag, _ := config["tag"].(string)
+		if nodeID == "" || tag == "" {
+			return nil, fmt.Errorf("%w: node %q requires config.tag", ErrInvalidSubworkflow, nodeID)
+		}
+
+		// Guard against A → B → A and unbounded nesting
+		for _, seen := range stack {
+			if seen == tag {
+				return nil, fmt.Errorf("%w: recursive reference %s", ErrInvalidSubworkflow, strings.Join(append(stack, tag), " -> "))
+			}
+		}
+		if len(stack) > MaxSubworkflowDepth {
+			return nil, fmt.Errorf("%w: nesting deeper than %d levels at %s", ErrInvalidSubworkflow, MaxSubworkflowDepth, strings.Join(append(stack, tag), " -> "))
+		}
+
+		child, err := s.materializeTag(ctx, username, tag)
+		if err != nil {
+			return nil, fmt.Errorf("%w: node %s: %v", ErrInvalidSubworkflow, nodeID, err)
+		}
+		child, err = s.expandSubworkflows(ctx, username, child, append(stack, tag))
+		if err != nil {
+			return nil, err
+		}
+
+		inlined, inlinedEdges, childEntries, childTerminals := namespaceWorkflow(nodeID, child)
+		if len(inlined) == 0 {
+			return nil, fmt.Errorf("%w: node %s: workflow %s has no nodes", ErrInvalidSubworkflow, nodeID, tag)
+		}
+
+		// Mapped inputs are handed to the child's entry nodes; $nodes references
+		// in them resolve against the parent at runtime like any config value
+		if inputsMap, ok := config["inputs_map"].(map[string]interface{}); ok && len(inputsMap) > 0 {
+			for _, raw := range inlined {
+				childNode := raw.(map[string]interface{})
+				if containsString(childEntries, childNode["id"].(string)) {
+					childConfig, _ := childNode["config"].(map[string]interface{})
+					if childConfig == nil {
+						childConfig = make(map[string]interface{})
+						childNode["config"] = childConfig
+					}
+					childConfig["inputs"] = inputsMap
+				}
+			}
+		}
+
+		expandedNodes = append(expandedNodes, inlined...)
+		childEdges = append(childEdges, inlinedEdges...)
+		entries[nodeID] = childEntries
+		terminals[nodeID] = childTerminals
+	}
+
+	// Rewire parent edges that touch a subworkflow node
+	expandedEdges := make([]interface{}, 0, len(edges)+len(childEdges))
+	for _, raw := range edges {
+		edge, ok := raw.(map[string]interface{})
+		if !ok {
+			expandedEdges = append(expandedEdges, raw)
+			continue
+		}
+
+		from, _ := edge["from"].(string)
+		to, _ := edge["to"].(string)
+
+		froms := []string{from}
+		if t, ok := terminals[from]; ok {
+			froms = t
+		}
+		tos := []string{to}
+		if e, ok := entries[to]; ok {
+			tos = e
+		}
+
+		for _, f := range froms {
+			for _, t := range tos {
+				rewired := copyMap(edge)
+				rewired["from"] = f
+				rewired["to"] = t
+				expandedEdges = append(expandedEdges, rewired)
+			}
+		}
+	}
+	expandedEdges = append(expandedEdges, childEdges...)
+
+	expanded := copyMap(workflow)
+	expanded["nodes"] = expandedNodes
+	expanded["edges"] = expandedEdges
+	return expanded, nil
+}
+
+// materializeTag resolves a tag to its current materialized workflow
+func (s *WorkflowServiceV2) materializeTag(ctx context.Context, username, tag string) (map[string]interface{}, error) {
+	components, err := s.GetWorkflowComponents(ctx, username, tag)
+	if err != nil {
+		return nil, err
+	}
+	return s.materializer.Materialize(ctx, components)
+}
+
+// namespaceWorkflow prefixes a child workflow's node IDs with parentID
+// Node references inside the child (edges, loop targets, $nodes expressions)
+// are rewritten to match. Returns the inlined nodes and edges plus the
+// namespaced entry (no incoming edge) and terminal (no outgoing edge) nodes.
+func namespaceWorkflow(parentID string, child map[string]interface{}) ([]interface{}, []interface{}, []string, []string) {
+	rawNodes, _ := child["nodes"].([]interface{})
+	rawEdges, _ := child["edges"].([]interface{})
+
+	prefix := parentID + "."
+	ids := make(map[string]bool)
+	for _, raw := range rawNodes {
+		if node, ok := raw.(map[string]interface{}); ok {
+			if id, ok := node["id"].(string); ok {
+				ids[id] = true
+			}
+		}
+	}
+	rename := func(id string) string {
+		if ids[id] {
+			return prefix + id
+		}
+		return id
+	}
+
+	hasIncoming := make(map[string]bool)
+	hasOutgoing := make(map[string]bool)
+	edges := make([]interface{}, 0, len(rawEdges))
+	for _, raw := range rawEdges {
+		edge, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		from, _ := edge["from"].(string)
+		to, _ := edge["to"].(string)
+		hasOutgoing[from] = true
+		hasIncoming[to] = true
+
+		renamed := copyMap(edge)
+		renamed["from"] = rename(from)
+		renamed["to"] = rename(to)
+		edges = append(edges, renamed)
+	}
+
+	nodes := make([]interface{}, 0, len(rawNodes))
+	var entries, terminals []string
+	for _, raw := range rawNodes {
+		node, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := node["id"].(string)
+
+		renamed := copyMap(node)
+		renamed["id"] = rename(id)
+		if config, ok := node["config"].(map[string]interface{}); ok {
+			renamed["config"] = rewriteNodeRefs(config, ids, prefix)
+		}
+		nodes = append(nodes, renamed)
+
+		if !hasIncoming[id] {
+			entries = append(entries, prefix+id)
+		}
+		if !hasOutgoing[id] {
+			terminals = append(terminals, prefix+id)
+		}
+	}
+
+	return nodes, edges, entries, terminals
+}
+
+// loopTargetKeys are loop config keys holding node IDs
+var loopTargetKeys = map[string]bool{
+	"loop_back_to": true,
+	"break_path":   true,
+	"timeout_path": true,
+}
+
+// rewriteNodeRefs namespaces node IDs referenced from a child node config
+func rewriteNodeRefs(config map[string]interface{}, ids map[string]bool, prefix string) map[string]interface{} {
+	rewritten := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		if loopTargetKeys[key] {
+			rewritten[key] = rewriteNodeIDs(value, ids, prefix)
+			continue
+		}
+		rewritten[key] = rewriteRefValue(value, ids, prefix)
+	}
+	return rewritten
+}
+
+// rewriteNodeIDs prefixes a node ID or list of node IDs
+func rewriteNodeIDs(value interface{}, ids map[string]bool, prefix string) interface{} {
+	switch v := value.(type) {
+	case string:
+		if ids[v] {
+			return prefix + v
+		}
+		return v
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = rewriteNodeIDs(item, ids, prefix)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// rewriteRefValue rewrites $nodes.<id> references found anywhere in value
+// The longest child ID matching the reference wins, so nested IDs such as
+// "inner.step" are rewritten as a whole.
+func rewriteRefValue(value interface{}, ids map[string]bool, prefix string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return nodeRefPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			path := strings.TrimPrefix(ref, "$nodes.")
+			for end := len(path); end > 0; end = strings.LastIndex(path[:end], ".") {
+				if ids[path[:end]] {
+					return "$nodes." + prefix + path
+				}
+			}
+			return ref
+		})
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = rewriteRefValue(item, ids, prefix)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = rewriteRefValue(item, ids, prefix)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// copyMap returns a shallow copy of m
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// containsString reports whether s is in list
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
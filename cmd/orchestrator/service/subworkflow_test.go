@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+)
+
+func newSubworkflowService(t *testing.T, workflows map[string]map[string]interface{}) *WorkflowServiceV2 {
+	t.Helper()
+	log := logger.New("error", "text")
+	cas := newTestCASService(newFakeCASStore(), "none", 0)
+	svc := NewWorkflowServiceV2(cas, NewArtifactService(newFakeArtifactStore(), log), NewTagService(newFakeTagStore(), log), NewMaterializerService(log), log)
+
+	for tag, workflow := range workflows {
+		_, err := svc.CreateWorkflow(context.Background(), &CreateWorkflowRequest{
+			Username:  "alice",
+			TagName:   tag,
+			Workflow:  workflow,
+			CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+	}
+	return svc
+}
+
+// expandTag materializes tag and inlines its subworkflows
+func expandTag(t *testing.T, svc *WorkflowServiceV2, tag string) (map[string]interface{}, error) {
+	t.Helper()
+	workflow, err := svc.materializeTag(context.Background(), "alice", tag)
+	require.NoError(t, err)
+	return svc.ExpandSubworkflows(context.Background(), "alice", tag, workflow)
+}
+
+func subworkflowNode(id, tag string) map[string]interface{} {
+	return map[string]interface{}{"id": id, "type": NodeTypeSubworkflow, "config": map[string]interface{}{"tag": tag}}
+}
+
+func TestExpandSubworkflows_TwoLevels(t *testing.T) {
+	sub := subworkflowNode("sub", "child")
+	sub["config"].(map[string]interface{})["inputs_map"] = map[string]interface{}{"order": "$nodes.fetch.body"}
+
+	svc := newSubworkflowService(t, map[string]map[string]interface{}{
+		"grandchild": {
+			"nodes": []interface{}{
+				map[string]interface{}{"id": "score", "type": "function"},
+				map[string]interface{}{"id": "report", "type": "transform", "config": map[string]interface{}{
+					"input": "$nodes.score.value",
+					"note":  "score was ${$nodes.score.value}",
+				}},
+			},
+			"edges": []interface{}{map[string]interface{}{"from": "score", "to": "report"}},
+		},
+		"child": {
+			"nodes": []interface{}{
+				map[string]interface{}{"id": "prepare", "type": "function"},
+				subworkflowNode("inner", "grandchild"),
+			},
+			"edges": []interface{}{map[string]interface{}{"from": "prepare", "to": "inner"}},
+		},
+		"parent": {
+			"nodes": []interface{}{
+				map[string]interface{}{"id": "fetch", "type": "http"},
+				sub,
+				map[string]interface{}{"id": "notify", "type": "function", "config": map[string]interface{}{"score": "$nodes.fetch.body"}},
+			},
+			"edges": []interface{}{
+				map[string]interface{}{"from": "fetch", "to": "sub"},
+				map[string]interface{}{"from": "sub", "to": "notify", "condition": "output.ok"},
+			},
+		},
+	})
+
+	expanded, err := expandTag(t, svc, "parent")
+	require.NoError(t, err)
+
+	nodes := make(map[string]map[string]interface{})
+	for _, raw := range expanded["nodes"].([]interface{}) {
+		node := raw.(map[string]interface{})
+		nodes[node["id"].(string)] = node
+	}
+	assert.ElementsMatch(t, []string{"fetch", "notify", "sub.prepare", "sub.inner.score", "sub.inner.report"}, keys(nodes))
+
+	var edges []string
+	for _, raw := range expanded["edges"].([]interface{}) {
+		edge := raw.(map[string]interface{})
+		edges = append(edges, edge["from"].(string)+"->"+edge["to"].(string))
+		if edge["to"] == "notify" {
+			assert.Equal(t, "output.ok", edge["condition"], "conditions on rewired edges are kept")
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"fetch->sub.prepare",
+		"sub.prepare->sub.inner.score",
+		"sub.inner.score->sub.inner.report",
+		"sub.inner.report->notify",
+	}, edges)
+
+	// Inputs land on the child's entry node; references inside the child follow the rename
+	assert.Equal(t, map[string]interface{}{"order": "$nodes.fetch.body"}, nodes["sub.prepare"]["config"].(map[string]interface{})["inputs"])
+	report := nodes["sub.inner.report"]["config"].(map[string]interface{})
+	assert.Equal(t, "$nodes.sub.inner.score.value", report["input"])
+	assert.Equal(t, "score was ${$nodes.sub.inner.score.value}", report["note"])
+	assert.Equal(t, "$nodes.fetch.body", nodes["notify"]["config"].(map[string]interface{})["score"])
+}
+
+func TestExpandSubworkflows_RejectsRecursion(t *testing.T) {
+	svc := newSubworkflowService(t, map[string]map[string]interface{}{
+		"a": {"nodes": []interface{}{subworkflowNode("to_b", "b")}, "edges": []interface{}{}},
+		"b": {"nodes": []interface{}{subworkflowNode("to_a", "a")}, "edges": []interface{}{}},
+	})
+
+	_, err := expandTag(t, svc, "a")
+	require.ErrorIs(t, err, ErrInvalidSubworkflow)
+	assert.Contains(t, err.Error(), "recursive reference a -> b -> a")
+}
+
+func TestExpandSubworkflows_RejectsMissingTag(t *testing.T) {
+	svc := newSubworkflowService(t, map[string]map[string]interface{}{
+		"parent": {"nodes": []interface{}{subworkflowNode("sub", "missing")}, "edges": []interface{}{}},
+	})
+
+	_, err := expandTag(t, svc, "parent")
+	assert.ErrorIs(t, err, ErrInvalidSubworkflow)
+}
+
+func keys(m map[string]map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -2,23 +2,53 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lyzr/orchestrator/common/models"
-	"github.com/lyzr/orchestrator/common/repository"
 	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/repository"
+)
+
+// tagStore is the subset of TagRepository used by TagService
+type tagStore interface {
+	Create(ctx context.Context, tag *models.Tag) error
+	GetByName(ctx context.Context, username, tagName string) (*models.Tag, error)
+	Update(ctx context.Context, tag *models.Tag) error
+	CompareAndSwap(ctx context.Context, username, tagName string, expectedVersion int64, newTarget uuid.UUID, newTargetKind, newTargetHash, movedBy string) (bool, error)
+	Delete(ctx context.Context, username, tagName string) error
+	ListByUsername(ctx context.Context, username string) ([]*models.Tag, error)
+	Exists(ctx context.Context, username, tagName string) (bool, error)
+	GetHistory(ctx context.Context, username, tagName string, limit int) ([]*models.TagMove, error)
+	ListHistory(ctx context.Context, username, tagName string, limit int, cursor string) (*repository.TagHistoryPage, error)
+	RecordMove(ctx context.Context, move *models.TagMove) error
+}
+
+// undoHistoryLimit bounds how many tag moves are replayed to build the undo/redo stacks
+const undoHistoryLimit = 1000
+
+var (
+	// ErrNothingToUndo is returned when the tag is at its first recorded target
+	ErrNothingToUndo = errors.New("nothing to undo")
+
+	// ErrNothingToRedo is returned when there is no undone move to reapply
+	ErrNothingToRedo = errors.New("nothing to redo")
+
+	// ErrTagExists is returned when creating a tag that already exists
+	ErrTagExists = errors.New("tag already exists")
 )
 
 // TagService handles tag operations
 type TagService struct {
-	repo *repository.TagRepository
-	log  *logger.Logger
+	repo  tagStore
+	audit *AuditService // Optional, see WithAudit
+	log   *logger.Logger
 }
 
 // NewTagService creates a new tag service
-func NewTagService(repo *repository.TagRepository, log *logger.Logger) *TagService {
+func NewTagService(repo tagStore, log *logger.Logger) *TagService {
 	return &TagService{
 		repo: repo,
 		log:  log,
@@ -34,7 +64,7 @@ func (s *TagService) CreateTag(ctx context.Context, username, tagName string, ta
 	}
 
 	if exists {
-		return fmt.Errorf("tag already exists: %s/%s", username, tagName)
+		return fmt.Errorf("%w: %s/%s", ErrTagExists, username, tagName)
 	}
 
 	tag := &models.Tag{
@@ -53,6 +83,8 @@ func (s *TagService) CreateTag(ctx context.Context, username, tagName string, ta
 		return fmt.Errorf("failed to create tag: %w", err)
 	}
 
+	s.recordMove(ctx, nil, tag, models.TagMoveActionMove)
+
 	s.log.Info("created tag",
 		"username", username,
 		"tag", tagName,
@@ -65,6 +97,12 @@ func (s *TagService) CreateTag(ctx context.Context, username, tagName string, ta
 
 // MoveTag moves a tag to a new target
 func (s *TagService) MoveTag(ctx context.Context, username, tagName string, targetKind models.ArtifactKind, targetID uuid.UUID, targetHash, movedBy string) error {
+	// Previous target, for the move history
+	previous, err := s.repo.GetByName(ctx, username, tagName)
+	if err != nil {
+		return fmt.Errorf("failed to move tag: %w", err)
+	}
+
 	tag := &models.Tag{
 		Username:   username,
 		TagName:    tagName,
@@ -79,6 +117,8 @@ func (s *TagService) MoveTag(ctx context.Context, username, tagName string, targ
 		return fmt.Errorf("failed to move tag: %w", err)
 	}
 
+	s.recordMove(ctx, previous, tag, models.TagMoveActionMove)
+
 	s.log.Info("moved tag",
 		"username", username,
 		"tag", tagName,
@@ -167,6 +207,8 @@ func (s *TagService) DeleteTag(ctx context.Context, username, tagName string) er
 		return fmt.Errorf("failed to delete tag: %w", err)
 	}
 
+	s.audit.Record(ctx, username, models.AuditActionTagDelete, tagTarget(username, tagName), nil)
+
 	s.log.Info("deleted tag", "username", username, "tag", tagName)
 	return nil
 }
@@ -181,13 +223,56 @@ func (s *TagService) GetHistory(ctx context.Context, username, tagName string, l
 	return history, nil
 }
 
+// ListHistory retrieves a page of the tag move history, newest first
+// Returns repository.ErrInvalidCursor for a malformed cursor.
+func (s *TagService) ListHistory(ctx context.Context, username, tagName string, limit int, cursor string) (*repository.TagHistoryPage, error) {
+	page, err := s.repo.ListHistory(ctx, username, tagName, limit, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tag history: %w", err)
+	}
+
+	return page, nil
+}
+
 // CompareAndSwap performs an optimistic lock update
 func (s *TagService) CompareAndSwap(ctx context.Context, username, tagName string, expectedVersion int64, newTarget uuid.UUID, newTargetKind models.ArtifactKind, newTargetHash, movedBy string) (bool, error) {
+	return s.compareAndSwap(ctx, username, tagName, expectedVersion, newTarget, newTargetKind, newTargetHash, movedBy, models.TagMoveActionMove)
+}
+
+// compareAndSwap performs the CAS update and records it in the move history
+func (s *TagService) compareAndSwap(ctx context.Context, username, tagName string, expectedVersion int64, newTarget uuid.UUID, newTargetKind models.ArtifactKind, newTargetHash, movedBy, action string) (bool, error) {
+	// The target at expectedVersion is the move's origin; if the tag already
+	// moved on, the CAS below fails anyway
+	previous, err := s.repo.GetByName(ctx, username, tagName)
+	if err != nil {
+		return false, fmt.Errorf("CAS operation failed: %w", err)
+	}
+	if previous.Version != expectedVersion {
+		s.log.Warn("CAS operation failed - version mismatch",
+			"username", username,
+			"tag", tagName,
+			"expected_version", expectedVersion,
+		)
+		return false, nil
+	}
+
 	success, err := s.repo.CompareAndSwap(ctx, username, tagName, expectedVersion, newTarget, string(newTargetKind), newTargetHash, movedBy)
 	if err != nil {
 		return false, fmt.Errorf("CAS operation failed: %w", err)
 	}
 
+	if success {
+		s.recordMove(ctx, previous, &models.Tag{
+			Username:   username,
+			TagName:    tagName,
+			TargetKind: newTargetKind,
+			TargetID:   newTarget,
+			TargetHash: &newTargetHash,
+			MovedBy:    &movedBy,
+			MovedAt:    time.Now(),
+		}, action)
+	}
+
 	if success {
 		s.log.Info("CAS operation succeeded",
 			"username", username,
@@ -205,3 +290,157 @@ func (s *TagService) CompareAndSwap(ctx context.Context, username, tagName strin
 
 	return success, nil
 }
+
+// UndoTag moves a tag back to the target it had before its last move
+// The undo/redo stacks are rebuilt from the move history; a regular move made
+// after an undo discards everything that could have been redone.
+func (s *TagService) UndoTag(ctx context.Context, username, tagName, movedBy string) (*models.TagMove, error) {
+	tag, undo, _, err := s.moveStacks(ctx, username, tagName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(undo) == 0 || undo[len(undo)-1].FromID == nil {
+		return nil, ErrNothingToUndo
+	}
+	last := undo[len(undo)-1]
+
+	targetKind := last.ToKind
+	if last.FromKind != nil {
+		targetKind = *last.FromKind
+	}
+
+	move, err := s.applyHistoryMove(ctx, tag, targetKind, *last.FromID, last.FromHash, movedBy, models.TagMoveActionUndo)
+	if err == nil {
+		s.audit.Record(ctx, movedBy, models.AuditActionTagUndo, tagTarget(username, tagName), map[string]interface{}{"to_id": move.ToID.String()})
+	}
+	return move, err
+}
+
+// RedoTag reapplies the most recently undone move of a tag
+func (s *TagService) RedoTag(ctx context.Context, username, tagName, movedBy string) (*models.TagMove, error) {
+	tag, _, redo, err := s.moveStacks(ctx, username, tagName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(redo) == 0 {
+		return nil, ErrNothingToRedo
+	}
+	last := redo[len(redo)-1]
+
+	move, err := s.applyHistoryMove(ctx, tag, last.ToKind, last.ToID, last.ExpectedHash, movedBy, models.TagMoveActionRedo)
+	if err == nil {
+		s.audit.Record(ctx, movedBy, models.AuditActionTagRedo, tagTarget(username, tagName), map[string]interface{}{"to_id": move.ToID.String()})
+	}
+	return move, err
+}
+
+// moveStacks replays a tag's move history into its undo and redo stacks
+// Each stack holds the regular moves that undo (resp. redo) would revert
+// (resp. reapply), most recent last.
+func (s *TagService) moveStacks(ctx context.Context, username, tagName string) (*models.Tag, []*models.TagMove, []*models.TagMove, error) {
+	// Read the tag first so a move recorded after this point fails the CAS
+	tag, err := s.GetTag(ctx, username, tagName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	history, err := s.GetHistory(ctx, username, tagName, undoHistoryLimit)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var undo, redo []*models.TagMove
+	// History is newest first; replay oldest first
+	for i := len(history) - 1; i >= 0; i-- {
+		move := history[i]
+		switch move.Action {
+		case models.TagMoveActionUndo:
+			if len(undo) > 0 {
+				redo = append(redo, undo[len(undo)-1])
+				undo = undo[:len(undo)-1]
+			}
+		case models.TagMoveActionRedo:
+			if len(redo) > 0 {
+				undo = append(undo, redo[len(redo)-1])
+				redo = redo[:len(redo)-1]
+			}
+		default:
+			undo = append(undo, move)
+			redo = nil
+		}
+	}
+
+	return tag, undo, redo, nil
+}
+
+// applyHistoryMove moves the tag with CAS at the version it was read at
+func (s *TagService) applyHistoryMove(ctx context.Context, tag *models.Tag, targetKind models.ArtifactKind, targetID uuid.UUID, targetHash *string, movedBy, action string) (*models.TagMove, error) {
+	hash := ""
+	if targetHash != nil {
+		hash = *targetHash
+	}
+
+	swapped, err := s.compareAndSwap(ctx, tag.Username, tag.TagName, tag.Version, targetID, targetKind, hash, movedBy, action)
+	if err != nil {
+		return nil, err
+	}
+	if !swapped {
+		return nil, ErrTagMoved
+	}
+
+	s.log.Info("applied tag history move",
+		"username", tag.Username,
+		"tag", tag.TagName,
+		"action", action,
+		"from", tag.TargetID,
+		"to", targetID,
+	)
+
+	fromKind := tag.TargetKind
+	fromID := tag.TargetID
+	return &models.TagMove{
+		Username:     tag.Username,
+		TagName:      tag.TagName,
+		FromKind:     &fromKind,
+		FromID:       &fromID,
+		FromHash:     tag.TargetHash,
+		ToKind:       targetKind,
+		ToID:         targetID,
+		ExpectedHash: &hash,
+		Action:       action,
+		MovedBy:      &movedBy,
+		MovedAt:      time.Now(),
+	}, nil
+}
+
+// recordMove appends a move to the tag history
+// The move itself already happened, so a failure is logged rather than returned.
+func (s *TagService) recordMove(ctx context.Context, previous, moved *models.Tag, action string) {
+	move := &models.TagMove{
+		Username:     moved.Username,
+		TagName:      moved.TagName,
+		ToKind:       moved.TargetKind,
+		ToID:         moved.TargetID,
+		ExpectedHash: moved.TargetHash,
+		Action:       action,
+		MovedBy:      moved.MovedBy,
+		MovedAt:      moved.MovedAt,
+	}
+	if previous != nil {
+		fromKind := previous.TargetKind
+		fromID := previous.TargetID
+		move.FromKind = &fromKind
+		move.FromID = &fromID
+		move.FromHash = previous.TargetHash
+	}
+
+	if err := s.repo.RecordMove(ctx, move); err != nil {
+		s.log.Error("failed to record tag move",
+			"username", moved.Username,
+			"tag", moved.TagName,
+			"action", action,
+			"error", err)
+	}
+}
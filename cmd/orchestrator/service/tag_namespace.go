@@ -11,6 +11,12 @@ const (
 	GlobalUsername = "_global_"
 )
 
+// CanAccessTag reports whether a user can read a tag owned by owner
+// Users can read their own tags and global tags, never another user's.
+func CanAccessTag(username, owner string) bool {
+	return owner == username || owner == GlobalUsername
+}
+
 // ValidateUserTagName validates a user-provided tag name
 // User tags should not contain invalid characters or reserved prefixes.
 //
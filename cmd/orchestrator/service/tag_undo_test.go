@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// undoFixture is a workflow service over in-memory stores
+type undoFixture struct {
+	ctx          context.Context
+	tags         *TagService
+	workflows    *WorkflowServiceV2
+	materializer *MaterializerService
+}
+
+func newUndoFixture(t *testing.T) *undoFixture {
+	log := logger.New("error", "text")
+	tags := NewTagService(newFakeTagStore(), log)
+	materializer := NewMaterializerService(log)
+
+	return &undoFixture{
+		ctx:  context.Background(),
+		tags: tags,
+		workflows: NewWorkflowServiceV2(
+			newTestCASService(newFakeCASStore(), "none", 0),
+			NewArtifactService(newFakeArtifactStore(), log),
+			tags,
+			materializer,
+			log,
+		),
+		materializer: materializer,
+	}
+}
+
+func (f *undoFixture) create(t *testing.T) uuid.UUID {
+	resp, err := f.workflows.CreateWorkflow(f.ctx, &CreateWorkflowRequest{
+		Username: "alice",
+		TagName:  "main",
+		Workflow: map[string]interface{}{
+			"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "function"}},
+			"edges": []interface{}{},
+		},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	return resp.ArtifactID
+}
+
+func (f *undoFixture) patch(t *testing.T, nodeID string) uuid.UUID {
+	resp, err := f.workflows.CreatePatch(f.ctx, &CreatePatchRequest{
+		Username: "alice",
+		TagName:  "main",
+		Operations: []map[string]interface{}{
+			{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": nodeID, "type": "function"}},
+		},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	return resp.ArtifactID
+}
+
+// head returns the tag target and the ids of its materialized nodes
+func (f *undoFixture) head(t *testing.T) (uuid.UUID, []string) {
+	tag, err := f.tags.GetTag(f.ctx, "alice", "main")
+	require.NoError(t, err)
+
+	components, err := f.workflows.GetWorkflowComponents(f.ctx, "alice", "main")
+	require.NoError(t, err)
+	workflow, err := f.materializer.Materialize(f.ctx, components)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, node := range workflow["nodes"].([]interface{}) {
+		ids = append(ids, node.(map[string]interface{})["id"].(string))
+	}
+	return tag.TargetID, ids
+}
+
+func TestTagService_UndoRedo(t *testing.T) {
+	f := newUndoFixture(t)
+	base := f.create(t)
+	p1 := f.patch(t, "b")
+	p2 := f.patch(t, "c")
+
+	// Undo twice walks back to the base version
+	move, err := f.tags.UndoTag(f.ctx, "alice", "main", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.TagMoveActionUndo, move.Action)
+	assert.Equal(t, p1, move.ToID)
+	assert.Equal(t, p2, *move.FromID)
+	target, nodes := f.head(t)
+	assert.Equal(t, p1, target)
+	assert.Equal(t, []string{"a", "b"}, nodes)
+
+	_, err = f.tags.UndoTag(f.ctx, "alice", "main", "alice")
+	require.NoError(t, err)
+	target, nodes = f.head(t)
+	assert.Equal(t, base, target)
+	assert.Equal(t, []string{"a"}, nodes)
+
+	tag, err := f.tags.GetTag(f.ctx, "alice", "main")
+	require.NoError(t, err)
+	assert.Equal(t, models.KindDAGVersion, tag.TargetKind)
+
+	// Nothing before the tag was created
+	_, err = f.tags.UndoTag(f.ctx, "alice", "main", "alice")
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+
+	// Redo replays both patches in order
+	move, err = f.tags.RedoTag(f.ctx, "alice", "main", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.TagMoveActionRedo, move.Action)
+	target, _ = f.head(t)
+	assert.Equal(t, p1, target)
+
+	_, err = f.tags.RedoTag(f.ctx, "alice", "main", "alice")
+	require.NoError(t, err)
+	target, nodes = f.head(t)
+	assert.Equal(t, p2, target)
+	assert.Equal(t, []string{"a", "b", "c"}, nodes)
+
+	_, err = f.tags.RedoTag(f.ctx, "alice", "main", "alice")
+	assert.ErrorIs(t, err, ErrNothingToRedo)
+
+	// Undo after redo goes back again
+	_, err = f.tags.UndoTag(f.ctx, "alice", "main", "alice")
+	require.NoError(t, err)
+	target, _ = f.head(t)
+	assert.Equal(t, p1, target)
+}
+
+func TestTagService_NewPatchInvalidatesRedo(t *testing.T) {
+	f := newUndoFixture(t)
+	f.create(t)
+	p1 := f.patch(t, "b")
+	f.patch(t, "c")
+
+	_, err := f.tags.UndoTag(f.ctx, "alice", "main", "alice")
+	require.NoError(t, err)
+
+	// A new patch on top of the undone state discards the redo stack
+	p3 := f.patch(t, "d")
+	target, nodes := f.head(t)
+	assert.Equal(t, p3, target)
+	assert.Equal(t, []string{"a", "b", "d"}, nodes)
+
+	_, err = f.tags.RedoTag(f.ctx, "alice", "main", "alice")
+	assert.ErrorIs(t, err, ErrNothingToRedo)
+
+	// Undo still works and skips the discarded patch
+	_, err = f.tags.UndoTag(f.ctx, "alice", "main", "alice")
+	require.NoError(t, err)
+	target, _ = f.head(t)
+	assert.Equal(t, p1, target)
+}
+
+func TestTagService_UndoWithoutHistory(t *testing.T) {
+	f := newUndoFixture(t)
+
+	_, err := f.tags.RedoTag(f.ctx, "alice", "main", "alice")
+	assert.Error(t, err)
+
+	f.create(t)
+	_, err = f.tags.UndoTag(f.ctx, "alice", "main", "alice")
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+	_, err = f.tags.RedoTag(f.ctx, "alice", "main", "alice")
+	assert.ErrorIs(t, err, ErrNothingToRedo)
+}
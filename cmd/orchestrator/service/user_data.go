@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/repository"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// userDataStore is the subset of UserDataRepository used by UserDataService
+type userDataStore interface {
+	PurgeUser(ctx context.Context, username string) (*repository.UserPurge, error)
+}
+
+// ErrGlobalUserPurge is returned for a purge of the global namespace
+var ErrGlobalUserPurge = errors.New("global workflows can't be purged")
+
+// UserDataService deletes all of a user's data, for account deletion
+type UserDataService struct {
+	repo  userDataStore
+	redis *rediscommon.Client
+	audit *AuditService
+	log   *logger.Logger
+}
+
+// NewUserDataService creates a new user data service
+func NewUserDataService(repo userDataStore, redis *rediscommon.Client, log *logger.Logger) *UserDataService {
+	return &UserDataService{
+		repo:  repo,
+		redis: redis,
+		log:   log,
+	}
+}
+
+// UserPurgeReport is what PurgeUser deleted
+type UserPurgeReport struct {
+	Username string `json:"username"`
+	repository.UserPurge
+	// Runs whose Redis state couldn't be deleted; it expires with the state's TTL
+	RunStateFailures []string `json:"run_state_failures,omitempty"`
+}
+
+// PurgeUser deletes a user's workflow tags and runs, then the runs' Redis state
+// The database rows go in one transaction first, which is refused with
+// repository.ErrUserHasActiveRuns while any run hasn't finished, so the Redis
+// state is only deleted once nothing writes it anymore. Redis can't join the
+// transaction, so its cleanup is best-effort: runs whose state couldn't be
+// deleted are reported, and their keys still expire on their own. actor is the
+// admin doing the purge.
+func (s *UserDataService) PurgeUser(ctx context.Context, actor, username string) (*UserPurgeReport, error) {
+	if username == GlobalUsername {
+		return nil, ErrGlobalUserPurge
+	}
+
+	purge, err := s.repo.PurgeUser(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge user data: %w", err)
+	}
+
+	report := &UserPurgeReport{Username: username, UserPurge: *purge}
+	for _, runID := range purge.Runs {
+		if err := sdk.PurgeRunState(ctx, s.redis, runID.String()); err != nil {
+			s.log.Warn("failed to delete purged run's state",
+				"username", username,
+				"run_id", runID,
+				"error", err)
+			report.RunStateFailures = append(report.RunStateFailures, runID.String())
+		}
+	}
+
+	s.audit.Record(ctx, actor, models.AuditActionUserPurge,
+		AuditTarget{Kind: models.AuditTargetUser, ID: username},
+		map[string]interface{}{
+			"tags":      purge.Tags,
+			"runs":      len(purge.Runs),
+			"tag_moves": purge.TagMoves,
+		})
+
+	s.log.Info("purged user data",
+		"username", username,
+		"actor", actor,
+		"tags", len(purge.Tags),
+		"runs", len(purge.Runs))
+	return report, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/repository"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+func TestUserDataService_PurgeUser(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	tags := newFakeTagStore()
+	store := &fakeUserDataStore{tags: tags}
+	auditStore := &fakeAuditStore{}
+	svc := NewUserDataService(store, rediscommon.NewClient(rdb, log), log).WithAudit(NewAuditService(auditStore, log))
+
+	// alice has three workflows and two runs, bob one of each
+	for _, tag := range []struct{ owner, name string }{
+		{"alice", "main"}, {"alice", "exp/quality"}, {"alice", "release"}, {"bob", "main"},
+	} {
+		require.NoError(t, tags.Create(ctx, &models.Tag{Username: tag.owner, TagName: tag.name}))
+		require.NoError(t, tags.RecordMove(ctx, &models.TagMove{Username: tag.owner, TagName: tag.name, Action: models.TagMoveActionMove}))
+	}
+	runKeys := func(runID string) []string {
+		return []string{"run:status:" + runID, "run:events:" + runID, "context:" + runID, sdk.NodeStatusKey(runID, "a"), sdk.RunKeysKey(runID)}
+	}
+	submit := func(owner string, status models.RunStatus) string {
+		run := &models.Run{RunID: uuid.New(), SubmittedBy: &owner, Status: status}
+		store.runs = append(store.runs, run)
+		runID := run.RunID.String()
+		for _, key := range runKeys(runID)[:4] {
+			require.NoError(t, mr.Set(key, "x"))
+		}
+		// The node status is found through the run's key set
+		require.NoError(t, sdk.TrackRunKeys(ctx, rdb, runID, sdk.NodeStatusKey(runID, "a")))
+		return runID
+	}
+	aliceRuns := []string{submit("alice", models.StatusCompleted), submit("alice", models.StatusRunning)}
+	bobRun := submit("bob", models.StatusRunning)
+
+	// Refused while one of alice's runs is still running, leaving everything in place
+	_, err := svc.PurgeUser(ctx, "admin", "alice")
+	assert.ErrorIs(t, err, repository.ErrUserHasActiveRuns)
+	require.Len(t, store.runs, 3)
+	for _, key := range runKeys(aliceRuns[1]) {
+		assert.True(t, mr.Exists(key), key)
+	}
+	assert.Empty(t, auditStore.entries)
+
+	store.runs[1].Status = models.StatusCancelled
+	report, err := svc.PurgeUser(ctx, "admin", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", report.Username)
+	assert.Equal(t, []string{"exp/quality", "main", "release"}, report.Tags)
+	assert.Len(t, report.Runs, 2)
+	assert.Equal(t, int64(3), report.TagMoves)
+	assert.Empty(t, report.RunStateFailures)
+
+	// All of alice's tags, history, runs and run state are gone
+	userTags, err := tags.ListByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, userTags)
+	history, err := tags.GetHistory(ctx, "alice", "main", 10)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	for _, runID := range aliceRuns {
+		for _, key := range runKeys(runID) {
+			assert.False(t, mr.Exists(key), key)
+		}
+	}
+
+	// bob's are untouched
+	_, err = tags.GetByName(ctx, "bob", "main")
+	assert.NoError(t, err)
+	require.Len(t, store.runs, 1)
+	assert.Equal(t, bobRun, store.runs[0].RunID.String())
+	for _, key := range runKeys(bobRun) {
+		assert.True(t, mr.Exists(key), key)
+	}
+
+	// The purge is audited under the admin who did it
+	require.Len(t, auditStore.entries, 1)
+	entry := auditStore.entries[0]
+	assert.Equal(t, "admin", entry.Actor)
+	assert.Equal(t, models.AuditActionUserPurge, entry.Action)
+	assert.Equal(t, models.AuditTargetUser, entry.TargetKind)
+	assert.Equal(t, "alice", entry.TargetID)
+
+	// The global namespace can't be purged
+	_, err = svc.PurgeUser(ctx, "admin", GlobalUsername)
+	assert.ErrorIs(t, err, ErrGlobalUserPurge)
+}
@@ -3,20 +3,30 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/lyzr/orchestrator/common/config"
 	"github.com/lyzr/orchestrator/common/models"
 	"github.com/lyzr/orchestrator/common/logger"
 )
 
+// ErrVersionNotFound is returned for a seq past the end of a workflow's history
+var ErrVersionNotFound = errors.New("workflow version not found")
+
 // WorkflowServiceV2 is a lightweight orchestrator for workflow operations
-// It composes CAS, Artifact, and Tag services
+// It composes CAS, Artifact, Tag, and Materializer services
 type WorkflowServiceV2 struct {
 	casService      *CASService
 	artifactService *ArtifactService
 	tagService      *TagService
+	materializer    *MaterializerService
+	limits          config.WorkflowLimitsConfig // Zero: unlimited
+	costWeights     map[string]int64            // Overrides of ratelimit.DefaultNodeCostWeights
+	audit           *AuditService               // Optional, see WithAudit
 	log             *logger.Logger
 }
 
@@ -25,12 +35,14 @@ func NewWorkflowServiceV2(
 	casService *CASService,
 	artifactService *ArtifactService,
 	tagService *TagService,
+	materializer *MaterializerService,
 	log *logger.Logger,
 ) *WorkflowServiceV2 {
 	return &WorkflowServiceV2{
 		casService:      casService,
 		artifactService: artifactService,
 		tagService:      tagService,
+		materializer:    materializer,
 		log:             log,
 	}
 }
@@ -59,49 +71,24 @@ type CreateWorkflowResponse struct {
 func (s *WorkflowServiceV2) CreateWorkflow(ctx context.Context, req *CreateWorkflowRequest) (*CreateWorkflowResponse, error) {
 	s.log.Info("creating workflow", "tag", req.TagName, "created_by", req.CreatedBy)
 
-	// 1. Validate and serialize workflow
-	workflowJSON, err := json.Marshal(req.Workflow)
-	if err != nil {
-		return nil, fmt.Errorf("invalid workflow JSON: %w", err)
-	}
-
-	// 2. Store in CAS (handles deduplication)
-	casID, err := s.casService.StoreContent(ctx, workflowJSON, "application/json;type=dag")
+	// 1-3. Validate and store the workflow as a DAG version
+	artifactID, casID, size, err := s.storeDAGVersion(ctx, req.Workflow, req.TagName, req.CreatedBy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to store workflow content: %w", err)
+		return nil, err
 	}
-
 	versionHash := casID // For DAG versions, version_hash = cas_id
 
-	// 3. Check if artifact already exists for this version
-	var artifactID uuid.UUID
-	existingArtifact, err := s.artifactService.GetByVersionHash(ctx, versionHash)
-	if err == nil {
-		// Artifact exists, reuse it
-		s.log.Info("artifact already exists", "artifact_id", existingArtifact.ArtifactID)
-		artifactID = existingArtifact.ArtifactID
-	} else {
-		// Create new artifact
-		nodesCount, edgesCount := CountWorkflowElements(req.Workflow)
-		artifactID, err = s.artifactService.CreateDAGVersion(
-			ctx,
-			casID,
-			versionHash,
-			req.TagName,
-			req.CreatedBy,
-			nodesCount,
-			edgesCount,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create artifact: %w", err)
-		}
-	}
-
 	// 4. Create or move tag
 	if err := s.tagService.CreateOrMoveTag(ctx, req.Username, req.TagName, "dag_version", artifactID, versionHash, req.CreatedBy); err != nil {
 		return nil, fmt.Errorf("failed to create/move tag: %w", err)
 	}
 
+	s.audit.Record(ctx, req.CreatedBy, models.AuditActionWorkflowCreate, artifactTarget(artifactID, req.Username, req.TagName), map[string]interface{}{
+		"cas_id": casID,
+		"nodes":  size.Nodes,
+		"edges":  size.Edges,
+	})
+
 	s.log.Info("workflow created successfully",
 		"artifact_id", artifactID,
 		"cas_id", casID,
@@ -109,20 +96,64 @@ func (s *WorkflowServiceV2) CreateWorkflow(ctx context.Context, req *CreateWorkf
 		"tag", req.TagName,
 	)
 
-	nodesCount, edgesCount := CountWorkflowElements(req.Workflow)
-
 	return &CreateWorkflowResponse{
 		ArtifactID:  artifactID,
 		CASID:       casID,
 		VersionHash: versionHash,
 		Username:    req.Username,
 		TagName:     req.TagName,
-		NodesCount:  nodesCount,
-		EdgesCount:  edgesCount,
+		NodesCount:  size.Nodes,
+		EdgesCount:  size.Edges,
 		CreatedAt:   time.Now(),
 	}, nil
 }
 
+// storeDAGVersion validates a workflow and stores it as a dag_version artifact
+// Returns the artifact, its CAS ID (also its version hash) and the workflow's
+// size. An existing artifact with the same content is reused.
+func (s *WorkflowServiceV2) storeDAGVersion(ctx context.Context, workflow map[string]interface{}, tagName, createdBy string) (uuid.UUID, string, WorkflowSize, error) {
+	// 1. Validate and serialize workflow
+	size := CountWorkflowElements(workflow)
+	if err := s.checkWorkflowSize(size); err != nil {
+		return uuid.Nil, "", size, err
+	}
+	workflowJSON, err := json.Marshal(workflow)
+	if err != nil {
+		return uuid.Nil, "", size, fmt.Errorf("invalid workflow JSON: %w", err)
+	}
+
+	// 2. Store in CAS (handles deduplication)
+	casID, err := s.casService.StoreContent(ctx, workflowJSON, "application/json;type=dag")
+	if err != nil {
+		return uuid.Nil, "", size, fmt.Errorf("failed to store workflow content: %w", err)
+	}
+
+	versionHash := casID // For DAG versions, version_hash = cas_id
+
+	// 3. Check if artifact already exists for this version
+	existingArtifact, err := s.artifactService.GetByVersionHash(ctx, versionHash)
+	if err == nil {
+		// Artifact exists, reuse it
+		s.log.Info("artifact already exists", "artifact_id", existingArtifact.ArtifactID)
+		return existingArtifact.ArtifactID, casID, size, nil
+	}
+
+	// Create new artifact
+	artifactID, err := s.artifactService.CreateDAGVersion(
+		ctx,
+		casID,
+		versionHash,
+		tagName,
+		createdBy,
+		size.Nodes,
+		size.Edges,
+	)
+	if err != nil {
+		return uuid.Nil, "", size, fmt.Errorf("failed to create artifact: %w", err)
+	}
+	return artifactID, casID, size, nil
+}
+
 // CreatePatchRequest represents the input for creating a patch
 type CreatePatchRequest struct {
 	Username    string                   `json:"username" validate:"required"`
@@ -130,6 +161,10 @@ type CreatePatchRequest struct {
 	Operations  []map[string]interface{} `json:"operations" validate:"required"`
 	Description string                   `json:"description"`
 	CreatedBy   string                   `json:"created_by"`
+
+	// ExpectedVersion is the tag version the operations were written against
+	// If the tag is at another version, the patch is rejected with ErrTagMoved.
+	ExpectedVersion *int64 `json:"expected_version,omitempty"`
 }
 
 // CreatePatchResponse represents the output after creating a patch
@@ -141,18 +176,33 @@ type CreatePatchResponse struct {
 	Username    string    `json:"username"`
 	TagName     string    `json:"tag_name"`
 	Description string    `json:"description"`
+	Version     int64     `json:"version"` // Tag version after the patch
 	CreatedAt   time.Time `json:"created_at"`
 }
 
 // CreatePatch creates a new patch artifact and updates the tag
+// The tag is only moved if it is still at the version the patch was built on:
+// of two patches racing on the same version, one fails with ErrTagMoved
+// instead of silently replacing the other. The loser's patch artifact is
+// deleted; its CAS blob stays until CAS GC finds nothing referring to it.
 func (s *WorkflowServiceV2) CreatePatch(ctx context.Context, req *CreatePatchRequest) (*CreatePatchResponse, error) {
 	s.log.Info("creating patch", "tag", req.TagName, "op_count", len(req.Operations), "created_by", req.CreatedBy)
 
+	if err := s.checkPatchSize(req.Operations); err != nil {
+		return nil, err
+	}
+
 	// 1. Resolve current tag to get current artifact
-	currentArtifact, err := s.resolveTagToArtifact(ctx, req.Username, req.TagName)
+	tag, currentArtifact, err := s.resolveTagToArtifact(ctx, req.Username, req.TagName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve tag: %w", err)
 	}
+	if req.ExpectedVersion != nil && *req.ExpectedVersion != tag.Version {
+		return nil, fmt.Errorf("%w: patch is based on version %d, tag is at version %d", ErrTagMoved, *req.ExpectedVersion, tag.Version)
+	}
+	if err := s.checkPatchedWorkflowSize(ctx, req.Username, req.TagName, req.Operations); err != nil {
+		return nil, err
+	}
 
 	// 2. Determine base version and previous patch set
 	var baseVersionID uuid.UUID
@@ -207,11 +257,27 @@ func (s *WorkflowServiceV2) CreatePatch(ctx context.Context, req *CreatePatchReq
 		return nil, fmt.Errorf("failed to create patch artifact: %w", err)
 	}
 
-	// 6. Move tag to new patch artifact
+	// 6. Move tag to new patch artifact, unless another move landed since step 1
 	// For patches, version_hash is the patch's cas_id
-	if err := s.tagService.CreateOrMoveTag(ctx, req.Username, req.TagName, "patch_set", patchArtifactID, casID, req.CreatedBy); err != nil {
+	swapped, err := s.tagService.CompareAndSwap(ctx, req.Username, req.TagName, tag.Version, patchArtifactID, models.KindPatchSet, casID, req.CreatedBy)
+	if err != nil {
 		return nil, fmt.Errorf("failed to move tag: %w", err)
 	}
+	if !swapped {
+		// Nothing saw the patch artifact, so it goes; its blob is left to CAS GC,
+		// which collects it once no artifact refers to it
+		if err := s.artifactService.DeletePatch(ctx, patchArtifactID); err != nil {
+			s.log.Warn("failed to delete patch of a lost tag move", "artifact_id", patchArtifactID, "error", err)
+		}
+		return nil, fmt.Errorf("%w: tag moved from version %d while the patch was saved", ErrTagMoved, tag.Version)
+	}
+
+	s.audit.Record(ctx, req.CreatedBy, models.AuditActionWorkflowPatch, artifactTarget(patchArtifactID, req.Username, req.TagName), map[string]interface{}{
+		"cas_id":      casID,
+		"depth":       newDepth,
+		"op_count":    opCount,
+		"description": req.Description,
+	})
 
 	s.log.Info("patch created successfully",
 		"artifact_id", patchArtifactID,
@@ -229,10 +295,107 @@ func (s *WorkflowServiceV2) CreatePatch(ctx context.Context, req *CreatePatchReq
 		Username:    req.Username,
 		TagName:     req.TagName,
 		Description: req.Description,
+		Version:     tag.Version + 1,
 		CreatedAt:   time.Now(),
 	}, nil
 }
 
+// RollbackWorkflowRequest represents the input for rolling a tag back to a previous version
+type RollbackWorkflowRequest struct {
+	Username  string `json:"username" validate:"required"`
+	TagName   string `json:"tag_name" validate:"required"`
+	Seq       int    `json:"seq"`
+	CreatedBy string `json:"created_by"`
+}
+
+// RollbackWorkflowResponse represents the output after a rollback
+type RollbackWorkflowResponse struct {
+	ArtifactID     uuid.UUID `json:"artifact_id"`
+	CASID          string    `json:"cas_id"`
+	DerivedFromSeq int       `json:"derived_from_seq"`
+	PreviousID     uuid.UUID `json:"previous_artifact_id"`
+	Username       string    `json:"username"`
+	TagName        string    `json:"tag_name"`
+	NodesCount     int       `json:"nodes_count"`
+	EdgesCount     int       `json:"edges_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// RollbackWorkflow moves a tag to a new dag_version holding the workflow as it was at seq
+// Like git revert, nothing is deleted: the old base and patch chain stay in
+// place and the rollback is a new version on top of the tag's history.
+func (s *WorkflowServiceV2) RollbackWorkflow(ctx context.Context, req *RollbackWorkflowRequest) (*RollbackWorkflowResponse, error) {
+	s.log.Info("rolling back workflow", "tag", req.TagName, "seq", req.Seq, "created_by", req.CreatedBy)
+
+	// 1. Load and materialize the workflow at the requested version
+	components, err := s.GetWorkflowComponentsAtVersion(ctx, req.Username, req.TagName, req.Seq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow at seq %d: %w", req.Seq, err)
+	}
+
+	workflow, err := s.materializer.Materialize(ctx, components)
+	if err != nil {
+		return nil, fmt.Errorf("failed to materialize workflow at seq %d: %w", req.Seq, err)
+	}
+
+	// 2. Store the materialized workflow in CAS
+	workflowJSON, err := json.Marshal(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize workflow: %w", err)
+	}
+
+	casID, err := s.casService.StoreContent(ctx, workflowJSON, "application/json;type=dag")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store workflow content: %w", err)
+	}
+
+	// 3. Create a new dag_version artifact (old chain is preserved)
+	size := CountWorkflowElements(workflow)
+	artifactID, err := s.artifactService.CreateRollbackVersion(
+		ctx,
+		casID,
+		req.TagName,
+		req.CreatedBy,
+		size.Nodes,
+		size.Edges,
+		components.ArtifactID,
+		req.Seq,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact: %w", err)
+	}
+
+	// 4. Move tag to the new version
+	if err := s.tagService.MoveTag(ctx, req.Username, req.TagName, models.KindDAGVersion, artifactID, casID, req.CreatedBy); err != nil {
+		return nil, fmt.Errorf("failed to move tag: %w", err)
+	}
+
+	s.audit.Record(ctx, req.CreatedBy, models.AuditActionWorkflowRollback, artifactTarget(artifactID, req.Username, req.TagName), map[string]interface{}{
+		"seq":                  req.Seq,
+		"previous_artifact_id": components.ArtifactID.String(),
+	})
+
+	s.log.Info("workflow rolled back successfully",
+		"artifact_id", artifactID,
+		"previous_artifact_id", components.ArtifactID,
+		"seq", req.Seq,
+		"username", req.Username,
+		"tag", req.TagName,
+	)
+
+	return &RollbackWorkflowResponse{
+		ArtifactID:     artifactID,
+		CASID:          casID,
+		DerivedFromSeq: req.Seq,
+		PreviousID:     components.ArtifactID,
+		Username:       req.Username,
+		TagName:        req.TagName,
+		NodesCount:     size.Nodes,
+		EdgesCount:     size.Edges,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
 // GetWorkflowByTag retrieves a workflow by tag name
 // NOTE: This function is incomplete and not currently used
 // TODO: Update to accept username parameter
@@ -282,23 +445,40 @@ func min(a, b int) int {
 	return b
 }
 
-// countWorkflowElements counts nodes and edges in a workflow
-func CountWorkflowElements(workflow map[string]interface{}) (int, int) {
-	nodesCount := 0
-	edgesCount := 0
+// WorkflowSize is a workflow's size as measured by CountWorkflowElements
+type WorkflowSize struct {
+	Nodes              int
+	Edges              int
+	Bytes              int    // Estimated size of the workflow as JSON
+	LargestConfigNode  string // Node with the largest config
+	LargestConfigBytes int    // Size of that config as JSON
+}
+
+// CountWorkflowElements counts nodes and edges in a workflow and estimates its size
+func CountWorkflowElements(workflow map[string]interface{}) WorkflowSize {
+	var size WorkflowSize
 
 	// Count nodes
 	if nodes, ok := workflow["nodes"].([]interface{}); ok {
-		nodesCount = len(nodes)
+		size.Nodes = len(nodes)
+		for _, node := range nodes {
+			nodeMap, _ := node.(map[string]interface{})
+			nodeID, _ := nodeMap["id"].(string)
+			size.measureConfig(nodeID, nodeMap)
+		}
 	} else if nodes, ok := workflow["nodes"].(map[string]interface{}); ok {
-		nodesCount = len(nodes)
+		size.Nodes = len(nodes)
+		for nodeID, node := range nodes {
+			nodeMap, _ := node.(map[string]interface{})
+			size.measureConfig(nodeID, nodeMap)
+		}
 	}
 
 	// Count edges/dependencies
 	if edges, ok := workflow["edges"].([]interface{}); ok {
-		edgesCount = len(edges)
+		size.Edges = len(edges)
 	} else if deps, ok := workflow["dependencies"].([]interface{}); ok {
-		edgesCount = len(deps)
+		size.Edges = len(deps)
 	}
 
 	// Count dependencies within nodes
@@ -306,13 +486,33 @@ func CountWorkflowElements(workflow map[string]interface{}) (int, int) {
 		for _, node := range nodesMap {
 			if nodeMap, ok := node.(map[string]interface{}); ok {
 				if deps, ok := nodeMap["dependencies"].([]interface{}); ok {
-					edgesCount += len(deps)
+					size.Edges += len(deps)
 				}
 			}
 		}
 	}
 
-	return nodesCount, edgesCount
+	if data, err := json.Marshal(workflow); err == nil {
+		size.Bytes = len(data)
+	}
+
+	return size
+}
+
+// measureConfig records a node's config size if it is the largest so far
+func (size *WorkflowSize) measureConfig(nodeID string, node map[string]interface{}) {
+	config, ok := node["config"]
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return
+	}
+	if len(data) > size.LargestConfigBytes {
+		size.LargestConfigNode = nodeID
+		size.LargestConfigBytes = len(data)
+	}
 }
 
 // GetWorkflowComponents fetches all components needed to reconstruct a workflow
@@ -325,12 +525,13 @@ func (s *WorkflowServiceV2) GetWorkflowComponents(ctx context.Context, username,
 	s.log.Info("fetching workflow components", "username", username, "tag", tagName)
 
 	// Query 1: Resolve tag to artifact
-	artifact, err := s.resolveTagToArtifact(ctx, username, tagName)
+	tag, artifact, err := s.resolveTagToArtifact(ctx, username, tagName)
 	if err != nil {
 		return nil, err
 	}
 
 	components := s.initializeComponents(username, tagName, artifact)
+	components.TagVersion = tag.Version
 
 	// Handle based on artifact kind
 	if artifact.IsDAGVersion() {
@@ -354,19 +555,36 @@ func (s *WorkflowServiceV2) GetWorkflowComponents(ctx context.Context, username,
 	return components, nil
 }
 
-// resolveTagToArtifact resolves a tag name to its artifact (Query 1)
-func (s *WorkflowServiceV2) resolveTagToArtifact(ctx context.Context, username, tagName string) (*models.Artifact, error) {
+// GetAllTagPositions returns where each of the user's tags currently points
+// Maps tag name → target artifact ID, read in one query over the user's tags
+// (ListByUsername: username = $1, not a name prefix that other users' tags could
+// also match). Recorded on runs so their environment can be reproduced.
+func (s *WorkflowServiceV2) GetAllTagPositions(ctx context.Context, username string) (map[string]string, error) {
+	tags, err := s.tagService.ListUserTags(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		positions[tag.TagName] = tag.TargetID.String()
+	}
+	return positions, nil
+}
+
+// resolveTagToArtifact resolves a tag name to the tag and its artifact (Query 1)
+func (s *WorkflowServiceV2) resolveTagToArtifact(ctx context.Context, username, tagName string) (*models.Tag, *models.Artifact, error) {
 	tag, err := s.tagService.GetTag(ctx, username, tagName)
 	if err != nil {
-		return nil, fmt.Errorf("tag not found: %w", err)
+		return nil, nil, fmt.Errorf("tag not found: %w", err)
 	}
 
 	artifact, err := s.artifactService.GetByID(ctx, tag.TargetID)
 	if err != nil {
-		return nil, fmt.Errorf("artifact not found: %w", err)
+		return nil, nil, fmt.Errorf("artifact not found: %w", err)
 	}
 
-	return artifact, nil
+	return tag, artifact, nil
 }
 
 // initializeComponents creates the base components structure
@@ -476,10 +694,14 @@ func (s *WorkflowServiceV2) loadPatchChain(ctx context.Context, patchArtifacts [
 
 	// Bulk fetch all patch contents in a single query
 	s.log.Info("bulk loading patch chain", "patch_count", len(casIDs))
-	contentsMap, err := s.casService.GetContentBulk(ctx, casIDs)
+	fetched, err := s.casService.GetContentBulk(ctx, casIDs)
 	if err != nil {
 		return fmt.Errorf("failed to bulk load patch contents: %w", err)
 	}
+	if err := fetched.Err(); err != nil {
+		return fmt.Errorf("failed to bulk load patch contents: %w", err)
+	}
+	contentsMap := fetched.Found
 
 	// Build patch chain with fetched contents
 	components.PatchChain = make([]models.PatchInfo, 0, len(patchArtifacts))
@@ -490,7 +712,7 @@ func (s *WorkflowServiceV2) loadPatchChain(ctx context.Context, patchArtifacts [
 		}
 
 		patchInfo := models.PatchInfo{
-			Seq:        i + 1, // 1-indexed
+			Seq:        i + 1, // 1-indexed; the chain is ordered by depth (see orderPatchChain)
 			ArtifactID: patchArt.ArtifactID,
 			CASID:      patchArt.CasID,
 			OpCount:    patchArt.OpCount,
@@ -524,18 +746,22 @@ func (s *WorkflowServiceV2) GetWorkflowComponentsAtVersion(ctx context.Context,
 	}
 
 	// Query 1: Resolve tag to artifact
-	artifact, err := s.resolveTagToArtifact(ctx, username, tagName)
+	tag, artifact, err := s.resolveTagToArtifact(ctx, username, tagName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s/%s: %v", ErrWorkflowNotFound, username, tagName, err)
+	}
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to resolve %s/%s: %w", username, tagName, err)
 	}
 
 	components := s.initializeComponents(username, tagName, artifact)
+	components.TagVersion = tag.Version
 
 	// Handle based on artifact kind
 	if artifact.IsDAGVersion() {
 		// DAG version has no patches, seq must be 0 or 1 (both return same thing)
 		if seq > 1 {
-			return nil, fmt.Errorf("dag_version only supports seq=0 or seq=1, requested seq=%d", seq)
+			return nil, fmt.Errorf("%w: dag_version only supports seq=0 or seq=1, requested seq=%d", ErrVersionNotFound, seq)
 		}
 		if err := s.loadDAGVersionComponents(ctx, artifact, components); err != nil {
 			return nil, err
@@ -610,7 +836,7 @@ func (s *WorkflowServiceV2) loadPatchSetComponentsAtVersion(ctx context.Context,
 
 	// Validate seq is within bounds
 	if seq > len(patchArtifacts) {
-		return fmt.Errorf("requested seq %d exceeds patch chain length %d", seq, len(patchArtifacts))
+		return fmt.Errorf("%w: requested seq %d exceeds patch chain length %d", ErrVersionNotFound, seq, len(patchArtifacts))
 	}
 
 	// Take only patches up to seq
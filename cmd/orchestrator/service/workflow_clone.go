@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+var (
+	// ErrTagNotAccessible is returned for another user's private tag
+	ErrTagNotAccessible = errors.New("tag is not accessible")
+
+	// ErrWorkflowNotFound is returned when a workflow tag doesn't exist
+	ErrWorkflowNotFound = errors.New("workflow not found")
+)
+
+// CloneWorkflowRequest represents the input for cloning a workflow into the caller's namespace
+type CloneWorkflowRequest struct {
+	Username   string `json:"username" validate:"required"` // Caller; the clone is created in their namespace
+	SourceUser string `json:"source_user"`                  // Owner of the source tag; defaults to Username
+	SourceTag  string `json:"source_tag" validate:"required"`
+	NewTag     string `json:"new_tag" validate:"required"`
+}
+
+// CloneWorkflowResponse represents the output after cloning a workflow
+type CloneWorkflowResponse struct {
+	ArtifactID       uuid.UUID `json:"artifact_id"`
+	CASID            string    `json:"cas_id"`
+	Username         string    `json:"username"`
+	TagName          string    `json:"tag_name"`
+	SourceUser       string    `json:"source_user"`
+	SourceTag        string    `json:"source_tag"`
+	SourceArtifactID uuid.UUID `json:"source_artifact_id"`
+	NodesCount       int       `json:"nodes_count"`
+	EdgesCount       int       `json:"edges_count"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// CloneWorkflow forks a workflow into a new tag in the caller's namespace
+// The source is materialized and stored as a dag_version (deduplicated by
+// content like any other): the content is copied, its patch chain and tag
+// history are not. Users can clone their own
+// and global tags; cloning another user's tag fails with ErrTagNotAccessible.
+// The new tag must not exist yet (ErrTagExists).
+func (s *WorkflowServiceV2) CloneWorkflow(ctx context.Context, req *CloneWorkflowRequest) (*CloneWorkflowResponse, error) {
+	sourceUser := req.SourceUser
+	if sourceUser == "" {
+		sourceUser = req.Username
+	}
+	s.log.Info("cloning workflow", "source_user", sourceUser, "source_tag", req.SourceTag, "username", req.Username, "new_tag", req.NewTag)
+
+	// Checked before the lookup, so private tags don't leak whether they exist
+	if !CanAccessTag(req.Username, sourceUser) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrTagNotAccessible, sourceUser, req.SourceTag)
+	}
+
+	// 1. Materialize the source workflow
+	components, err := s.GetWorkflowComponents(ctx, sourceUser, req.SourceTag)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s/%s: %v", ErrWorkflowNotFound, sourceUser, req.SourceTag, err)
+	}
+	workflow, err := s.materializer.Materialize(ctx, components)
+	if err != nil {
+		return nil, fmt.Errorf("failed to materialize workflow: %w", err)
+	}
+
+	// 2. Store it as a new base version
+	artifactID, casID, size, err := s.storeDAGVersion(ctx, workflow, req.NewTag, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Create the new tag (never moves an existing one)
+	if err := s.tagService.CreateTag(ctx, req.Username, req.NewTag, models.KindDAGVersion, artifactID, casID, req.Username); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, req.Username, models.AuditActionWorkflowClone, artifactTarget(artifactID, req.Username, req.NewTag), map[string]interface{}{
+		"source_user":        sourceUser,
+		"source_tag":         req.SourceTag,
+		"source_artifact_id": components.ArtifactID.String(),
+	})
+
+	s.log.Info("workflow cloned successfully",
+		"artifact_id", artifactID,
+		"source_artifact_id", components.ArtifactID,
+		"username", req.Username,
+		"tag", req.NewTag,
+	)
+
+	return &CloneWorkflowResponse{
+		ArtifactID:       artifactID,
+		CASID:            casID,
+		Username:         req.Username,
+		TagName:          req.NewTag,
+		SourceUser:       sourceUser,
+		SourceTag:        req.SourceTag,
+		SourceArtifactID: components.ArtifactID,
+		NodesCount:       size.Nodes,
+		EdgesCount:       size.Edges,
+		CreatedAt:        time.Now(),
+	}, nil
+}
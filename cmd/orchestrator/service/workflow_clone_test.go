@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+func TestWorkflowService_CloneWorkflow(t *testing.T) {
+	f := newUndoFixture(t)
+	ctx := f.ctx
+
+	// A global template with a patch on top
+	_, err := f.workflows.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: GlobalUsername,
+		TagName:  "template",
+		Workflow: map[string]interface{}{
+			"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "function"}},
+			"edges": []interface{}{},
+		},
+		CreatedBy: "admin",
+	})
+	require.NoError(t, err)
+	_, err = f.workflows.CreatePatch(ctx, &CreatePatchRequest{
+		Username: GlobalUsername,
+		TagName:  "template",
+		Operations: []map[string]interface{}{
+			{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "b", "type": "function"}},
+		},
+		CreatedBy: "admin",
+	})
+	require.NoError(t, err)
+
+	resp, err := f.workflows.CloneWorkflow(ctx, &CloneWorkflowRequest{
+		Username:   "alice",
+		SourceUser: GlobalUsername,
+		SourceTag:  "template",
+		NewTag:     "mine",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.NodesCount)
+
+	// The clone is a single base version with the materialized content
+	components, err := f.workflows.GetWorkflowComponents(ctx, "alice", "mine")
+	require.NoError(t, err)
+	assert.Equal(t, models.KindDAGVersion, components.Kind)
+	assert.Equal(t, resp.ArtifactID, components.ArtifactID)
+	workflow, err := f.materializer.Materialize(ctx, components)
+	require.NoError(t, err)
+	assert.Len(t, workflow["nodes"], 2)
+
+	// No history is copied
+	history, err := f.tags.GetHistory(ctx, "alice", "mine", 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	t.Run("existing tag", func(t *testing.T) {
+		_, err := f.workflows.CloneWorkflow(ctx, &CloneWorkflowRequest{
+			Username: "alice", SourceUser: GlobalUsername, SourceTag: "template", NewTag: "mine",
+		})
+		assert.ErrorIs(t, err, ErrTagExists)
+	})
+
+	t.Run("own tag", func(t *testing.T) {
+		_, err := f.workflows.CloneWorkflow(ctx, &CloneWorkflowRequest{
+			Username: "alice", SourceTag: "mine", NewTag: "mine-copy",
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("another user's private tag", func(t *testing.T) {
+		_, err := f.workflows.CloneWorkflow(ctx, &CloneWorkflowRequest{
+			Username: "bob", SourceUser: "alice", SourceTag: "mine", NewTag: "stolen",
+		})
+		assert.ErrorIs(t, err, ErrTagNotAccessible)
+
+		_, err = f.tags.GetTag(ctx, "bob", "stolen")
+		assert.Error(t, err)
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		_, err := f.workflows.CloneWorkflow(ctx, &CloneWorkflowRequest{
+			Username: "alice", SourceUser: GlobalUsername, SourceTag: "missing", NewTag: "other",
+		})
+		assert.ErrorIs(t, err, ErrWorkflowNotFound)
+	})
+}
+
+func TestCanAccessTag(t *testing.T) {
+	assert.True(t, CanAccessTag("alice", "alice"))
+	assert.True(t, CanAccessTag("alice", GlobalUsername))
+	assert.False(t, CanAccessTag("alice", "bob"))
+}
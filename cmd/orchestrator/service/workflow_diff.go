@@ -0,0 +1,190 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// WorkflowDiff is a structural diff between two materialized workflows
+// Nodes are matched by id and edges by (from, to, condition), so reordering
+// nodes or edges is not reported as a change.
+type WorkflowDiff struct {
+	NodesAdded    []map[string]interface{} `json:"nodes_added"`
+	NodesRemoved  []map[string]interface{} `json:"nodes_removed"`
+	NodesModified []NodeChange             `json:"nodes_modified"`
+	EdgesAdded    []map[string]interface{} `json:"edges_added"`
+	EdgesRemoved  []map[string]interface{} `json:"edges_removed"`
+}
+
+// NodeChange lists the field changes of a node present in both versions
+type NodeChange struct {
+	NodeID  string        `json:"node_id"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is a single changed node field
+// Config keys are reported individually as "config.<key>".
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// IsEmpty reports whether the two workflows are structurally identical
+func (d *WorkflowDiff) IsEmpty() bool {
+	return len(d.NodesAdded) == 0 && len(d.NodesRemoved) == 0 && len(d.NodesModified) == 0 &&
+		len(d.EdgesAdded) == 0 && len(d.EdgesRemoved) == 0
+}
+
+// DiffWorkflows computes the node- and edge-level diff from one materialized workflow to another
+func DiffWorkflows(from, to map[string]interface{}) *WorkflowDiff {
+	diff := &WorkflowDiff{
+		NodesAdded:    []map[string]interface{}{},
+		NodesRemoved:  []map[string]interface{}{},
+		NodesModified: []NodeChange{},
+		EdgesAdded:    []map[string]interface{}{},
+		EdgesRemoved:  []map[string]interface{}{},
+	}
+
+	// 1. Nodes, matched by id
+	fromNodes := indexByKey(workflowList(from, "nodes"), nodeKey)
+	toNodes := indexByKey(workflowList(to, "nodes"), nodeKey)
+
+	for _, id := range sortedKeys(toNodes) {
+		if _, exists := fromNodes[id]; !exists {
+			diff.NodesAdded = append(diff.NodesAdded, toNodes[id])
+		}
+	}
+	for _, id := range sortedKeys(fromNodes) {
+		newNode, exists := toNodes[id]
+		if !exists {
+			diff.NodesRemoved = append(diff.NodesRemoved, fromNodes[id])
+			continue
+		}
+		if changes := diffNode(fromNodes[id], newNode); len(changes) > 0 {
+			diff.NodesModified = append(diff.NodesModified, NodeChange{NodeID: id, Changes: changes})
+		}
+	}
+
+	// 2. Edges, matched by (from, to, condition)
+	fromEdges := indexByKey(workflowList(from, "edges"), edgeKey)
+	toEdges := indexByKey(workflowList(to, "edges"), edgeKey)
+
+	for _, key := range sortedKeys(toEdges) {
+		if _, exists := fromEdges[key]; !exists {
+			diff.EdgesAdded = append(diff.EdgesAdded, toEdges[key])
+		}
+	}
+	for _, key := range sortedKeys(fromEdges) {
+		if _, exists := toEdges[key]; !exists {
+			diff.EdgesRemoved = append(diff.EdgesRemoved, fromEdges[key])
+		}
+	}
+
+	return diff
+}
+
+// diffNode compares two versions of the same node field by field
+func diffNode(from, to map[string]interface{}) []FieldChange {
+	var changes []FieldChange
+
+	for _, field := range unionKeys(from, to) {
+		if field == "id" {
+			continue
+		}
+
+		oldValue, newValue := from[field], to[field]
+		if field == "config" {
+			oldConfig, oldOK := asMap(oldValue)
+			newConfig, newOK := asMap(newValue)
+			if oldOK && newOK {
+				for _, key := range unionKeys(oldConfig, newConfig) {
+					if !reflect.DeepEqual(oldConfig[key], newConfig[key]) {
+						changes = append(changes, FieldChange{
+							Field: "config." + key,
+							From:  oldConfig[key],
+							To:    newConfig[key],
+						})
+					}
+				}
+				continue
+			}
+		}
+
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: field, From: oldValue, To: newValue})
+		}
+	}
+
+	return changes
+}
+
+// workflowList returns the object entries of a top-level workflow array
+func workflowList(workflow map[string]interface{}, field string) []map[string]interface{} {
+	items, _ := workflow[field].([]interface{})
+	result := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// indexByKey indexes entries by an identity key, skipping entries without one
+func indexByKey(items []map[string]interface{}, key func(map[string]interface{}) string) map[string]map[string]interface{} {
+	index := make(map[string]map[string]interface{}, len(items))
+	for _, item := range items {
+		if k := key(item); k != "" {
+			index[k] = item
+		}
+	}
+	return index
+}
+
+func nodeKey(node map[string]interface{}) string {
+	id, _ := node["id"].(string)
+	return id
+}
+
+func edgeKey(edge map[string]interface{}) string {
+	from, _ := edge["from"].(string)
+	to, _ := edge["to"].(string)
+	if from == "" || to == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s\x00%s\x00%v", from, to, edge["condition"])
+}
+
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	if value == nil {
+		return map[string]interface{}{}, true
+	}
+	m, ok := value.(map[string]interface{})
+	return m, ok
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	keys := make([]string, 0, len(a)+len(b))
+	for _, m := range []map[string]interface{}{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustWorkflow(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var workflow map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &workflow))
+	return workflow
+}
+
+const diffBaseWorkflow = `{
+	"nodes": [
+		{"id": "fetch", "type": "http", "config": {"url": "https://a.example", "method": "GET"}},
+		{"id": "review", "type": "hitl", "config": {"prompt": "Approve?"}}
+	],
+	"edges": [
+		{"from": "fetch", "to": "review"}
+	]
+}`
+
+func TestDiffWorkflows_AddNode(t *testing.T) {
+	from := mustWorkflow(t, diffBaseWorkflow)
+	to := mustWorkflow(t, `{
+		"nodes": [
+			{"id": "review", "type": "hitl", "config": {"prompt": "Approve?"}},
+			{"id": "fetch", "type": "http", "config": {"method": "GET", "url": "https://a.example"}},
+			{"id": "notify", "type": "http", "config": {"url": "https://hooks.example"}}
+		],
+		"edges": [
+			{"from": "fetch", "to": "review"},
+			{"from": "review", "to": "notify"}
+		]
+	}`)
+
+	diff := DiffWorkflows(from, to)
+
+	require.Len(t, diff.NodesAdded, 1)
+	assert.Equal(t, "notify", diff.NodesAdded[0]["id"])
+	require.Len(t, diff.EdgesAdded, 1)
+	assert.Equal(t, "notify", diff.EdgesAdded[0]["to"])
+
+	// Reordered nodes and config keys are not changes
+	assert.Empty(t, diff.NodesRemoved)
+	assert.Empty(t, diff.NodesModified)
+	assert.Empty(t, diff.EdgesRemoved)
+}
+
+func TestDiffWorkflows_RemoveEdge(t *testing.T) {
+	from := mustWorkflow(t, `{
+		"nodes": [{"id": "a", "type": "function"}, {"id": "b", "type": "function"}, {"id": "c", "type": "function"}],
+		"edges": [{"from": "a", "to": "b"}, {"from": "a", "to": "c"}, {"from": "b", "to": "c", "condition": "$.ok"}]
+	}`)
+	to := mustWorkflow(t, `{
+		"nodes": [{"id": "c", "type": "function"}, {"id": "b", "type": "function"}, {"id": "a", "type": "function"}],
+		"edges": [{"from": "b", "to": "c", "condition": "$.ok"}, {"from": "a", "to": "b"}]
+	}`)
+
+	diff := DiffWorkflows(from, to)
+
+	require.Len(t, diff.EdgesRemoved, 1)
+	assert.Equal(t, "a", diff.EdgesRemoved[0]["from"])
+	assert.Equal(t, "c", diff.EdgesRemoved[0]["to"])
+	assert.Empty(t, diff.EdgesAdded)
+	assert.Empty(t, diff.NodesAdded)
+	assert.Empty(t, diff.NodesRemoved)
+	assert.Empty(t, diff.NodesModified)
+}
+
+func TestDiffWorkflows_ConfigChange(t *testing.T) {
+	from := mustWorkflow(t, diffBaseWorkflow)
+	to := mustWorkflow(t, `{
+		"nodes": [
+			{"id": "fetch", "type": "http", "config": {"url": "https://b.example", "method": "GET", "timeout": 30}},
+			{"id": "review", "type": "agent", "config": {"prompt": "Approve?"}}
+		],
+		"edges": [{"from": "fetch", "to": "review"}]
+	}`)
+
+	diff := DiffWorkflows(from, to)
+
+	require.Len(t, diff.NodesModified, 2)
+
+	fetch := diff.NodesModified[0]
+	assert.Equal(t, "fetch", fetch.NodeID)
+	assert.Equal(t, []FieldChange{
+		{Field: "config.timeout", From: nil, To: float64(30)},
+		{Field: "config.url", From: "https://a.example", To: "https://b.example"},
+	}, fetch.Changes)
+
+	review := diff.NodesModified[1]
+	assert.Equal(t, "review", review.NodeID)
+	assert.Equal(t, []FieldChange{{Field: "type", From: "hitl", To: "agent"}}, review.Changes)
+
+	assert.Empty(t, diff.NodesAdded)
+	assert.Empty(t, diff.EdgesAdded)
+	assert.False(t, diff.IsEmpty())
+	assert.True(t, DiffWorkflows(from, from).IsEmpty())
+}
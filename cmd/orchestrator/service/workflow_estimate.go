@@ -0,0 +1,41 @@
+package service
+
+import "github.com/lyzr/orchestrator/common/ratelimit"
+
+// WorkflowEstimate is what one run of a workflow is expected to cost, before running it
+type WorkflowEstimate struct {
+	Profile   ratelimit.WorkflowProfile `json:"profile"`
+	Cost      ratelimit.CostEstimate    `json:"cost"`
+	RateLimit TierLimit                 `json:"rate_limit"`
+}
+
+// TierLimit is the rate limit of a workflow tier
+type TierLimit struct {
+	Tier          ratelimit.WorkflowTier `json:"tier"`
+	Limit         int64                  `json:"limit"`
+	WindowSeconds int                    `json:"window_seconds"`
+	Description   string                 `json:"description"`
+}
+
+// WithCostWeights sets the cost units per node type used by EstimateWorkflow
+// Node types without a weight fall back to ratelimit.DefaultNodeCostWeights.
+func (s *WorkflowServiceV2) WithCostWeights(weights map[string]int64) *WorkflowServiceV2 {
+	s.costWeights = weights
+	return s
+}
+
+// EstimateWorkflow profiles a materialized workflow the way the run rate limiter does
+// and estimates the cost of one run from the configured node weights.
+func (s *WorkflowServiceV2) EstimateWorkflow(workflow map[string]interface{}) *WorkflowEstimate {
+	profile := ratelimit.InspectWorkflow(workflow)
+	return &WorkflowEstimate{
+		Profile: profile,
+		Cost:    ratelimit.EstimateCost(workflow, s.costWeights),
+		RateLimit: TierLimit{
+			Tier:          profile.Tier,
+			Limit:         ratelimit.GetLimitForTier(profile.Tier),
+			WindowSeconds: ratelimit.GetWindowForTier(profile.Tier),
+			Description:   ratelimit.GetDescription(profile.Tier),
+		},
+	}
+}
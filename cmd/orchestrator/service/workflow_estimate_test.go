@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/ratelimit"
+)
+
+func TestWorkflowService_EstimateWorkflow(t *testing.T) {
+	workflows := NewWorkflowServiceV2(nil, nil, nil, nil, logger.New("error", "text"))
+
+	nodes := func(types ...string) map[string]interface{} {
+		list := make([]interface{}, len(types))
+		for i, nodeType := range types {
+			list[i] = map[string]interface{}{"id": nodeType + string(rune('a'+i)), "type": nodeType}
+		}
+		return map[string]interface{}{"nodes": list, "edges": []interface{}{}}
+	}
+
+	functions := workflows.EstimateWorkflow(nodes("function", "function", "function", "http"))
+	agents := workflows.EstimateWorkflow(nodes("function", "agent", "agent", "agent"))
+
+	assert.Equal(t, ratelimit.TierSimple, functions.Profile.Tier)
+	assert.Equal(t, ratelimit.TierHeavy, agents.Profile.Tier)
+	assert.Equal(t, 3, agents.Profile.AgentCount)
+	assert.Equal(t, 4, agents.Profile.TotalNodes)
+
+	assert.Equal(t, int64(8), functions.Cost.TotalCost)
+	assert.Equal(t, int64(301), agents.Cost.TotalCost)
+	assert.Equal(t, &ratelimit.NodeTypeCost{Count: 3, Weight: 100, Cost: 300}, agents.Cost.ByType["agent"])
+
+	// The heavier tier has the tighter rate limit
+	assert.Equal(t, ratelimit.TierHeavy, agents.RateLimit.Tier)
+	assert.Less(t, agents.RateLimit.Limit, functions.RateLimit.Limit)
+
+	t.Run("configured weights", func(t *testing.T) {
+		workflows.WithCostWeights(map[string]int64{"agent": 10, "custom": 7})
+
+		estimate := workflows.EstimateWorkflow(nodes("agent", "custom", "function"))
+		assert.Equal(t, int64(18), estimate.Cost.TotalCost)
+	})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+// ErrWorkflowTooLarge is returned for workflows and patches over a configured size limit
+// The message names the limit, e.g. "workflow exceeds size limit: 1200 nodes (max_nodes 1000)".
+var ErrWorkflowTooLarge = errors.New("workflow exceeds size limit")
+
+// WithLimits sets the size limits enforced when saving workflows and patches
+func (s *WorkflowServiceV2) WithLimits(limits config.WorkflowLimitsConfig) *WorkflowServiceV2 {
+	s.limits = limits
+	return s
+}
+
+// CheckWorkflowSize returns ErrWorkflowTooLarge if workflow is over the size limits
+// Used for patched workflows, which CreatePatch only sees as operations.
+func (s *WorkflowServiceV2) CheckWorkflowSize(workflow map[string]interface{}) error {
+	return s.checkWorkflowSize(CountWorkflowElements(workflow))
+}
+
+// checkWorkflowSize checks a measured workflow against the limits
+func (s *WorkflowServiceV2) checkWorkflowSize(size WorkflowSize) error {
+	if s.limits.MaxNodes > 0 && size.Nodes > s.limits.MaxNodes {
+		return fmt.Errorf("%w: %d nodes (max_nodes %d)", ErrWorkflowTooLarge, size.Nodes, s.limits.MaxNodes)
+	}
+	if s.limits.MaxEdges > 0 && size.Edges > s.limits.MaxEdges {
+		return fmt.Errorf("%w: %d edges (max_edges %d)", ErrWorkflowTooLarge, size.Edges, s.limits.MaxEdges)
+	}
+	if s.limits.MaxWorkflowBytes > 0 && size.Bytes > s.limits.MaxWorkflowBytes {
+		return fmt.Errorf("%w: %d bytes (max_workflow_bytes %d)", ErrWorkflowTooLarge, size.Bytes, s.limits.MaxWorkflowBytes)
+	}
+	if s.limits.MaxNodeConfigBytes > 0 && size.LargestConfigBytes > s.limits.MaxNodeConfigBytes {
+		return fmt.Errorf("%w: config of node %s is %d bytes (max_node_config_bytes %d)",
+			ErrWorkflowTooLarge, size.LargestConfigNode, size.LargestConfigBytes, s.limits.MaxNodeConfigBytes)
+	}
+	return nil
+}
+
+// checkPatchedWorkflowSize checks the workflow a patch would produce against the limits
+// The tag's current workflow is materialized and the operations replayed on it
+// as they will be on every later read, so a patch can't grow a workflow past
+// the limits CreateWorkflow enforces.
+func (s *WorkflowServiceV2) checkPatchedWorkflowSize(ctx context.Context, username, tagName string, operations []map[string]interface{}) error {
+	if s.limits.MaxNodes == 0 && s.limits.MaxEdges == 0 && s.limits.MaxWorkflowBytes == 0 && s.limits.MaxNodeConfigBytes == 0 {
+		return nil
+	}
+
+	components, err := s.GetWorkflowComponents(ctx, username, tagName)
+	if err != nil {
+		return fmt.Errorf("failed to load workflow: %w", err)
+	}
+	current, err := s.materializer.Materialize(ctx, components)
+	if err != nil {
+		return fmt.Errorf("failed to materialize workflow: %w", err)
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to serialize workflow: %w", err)
+	}
+	patchJSON, err := json.Marshal(operations)
+	if err != nil {
+		return fmt.Errorf("failed to serialize patch operations: %w", err)
+	}
+	patchedJSON, err := s.materializer.applyPatch(currentJSON, patchJSON)
+	if err != nil {
+		return err
+	}
+	patched, err := s.materializer.unmarshalWorkflow(patchedJSON)
+	if err != nil {
+		return err
+	}
+	return s.checkWorkflowSize(CountWorkflowElements(patched))
+}
+
+// checkPatchSize checks patch operations against the limits
+// Operations adding a node or replacing a config are held to the config size limit.
+func (s *WorkflowServiceV2) checkPatchSize(operations []map[string]interface{}) error {
+	if s.limits.MaxPatchOperations > 0 && len(operations) > s.limits.MaxPatchOperations {
+		return fmt.Errorf("%w: %d patch operations (max_patch_operations %d)",
+			ErrWorkflowTooLarge, len(operations), s.limits.MaxPatchOperations)
+	}
+	if s.limits.MaxNodeConfigBytes == 0 {
+		return nil
+	}
+
+	for i, op := range operations {
+		path, _ := op["path"].(string)
+		value, ok := op["value"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		nodeConfig := value["config"] // Adds a node
+		if strings.HasSuffix(path, "/config") {
+			nodeConfig = value // Replaces a node's config
+		}
+		if nodeConfig == nil {
+			continue
+		}
+
+		data, err := json.Marshal(nodeConfig)
+		if err != nil {
+			continue
+		}
+		if len(data) > s.limits.MaxNodeConfigBytes {
+			return fmt.Errorf("%w: operation %d (%s) sets a %d byte config (max_node_config_bytes %d)",
+				ErrWorkflowTooLarge, i, path, len(data), s.limits.MaxNodeConfigBytes)
+		}
+	}
+	return nil
+}
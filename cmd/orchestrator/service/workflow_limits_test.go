@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/logger"
+)
+
+func newLimitedWorkflowService(limits config.WorkflowLimitsConfig) *WorkflowServiceV2 {
+	log := logger.New("error", "text")
+	return NewWorkflowServiceV2(
+		newTestCASService(newFakeCASStore(), "none", 0),
+		NewArtifactService(newFakeArtifactStore(), log),
+		NewTagService(newFakeTagStore(), log),
+		NewMaterializerService(log),
+		log,
+	).WithLimits(limits)
+}
+
+// chainWorkflow is n function nodes in a chain (n-1 edges), each with a config of configBytes as JSON
+func chainWorkflow(n, configBytes int) map[string]interface{} {
+	// {"v":"<padding>"}
+	config := map[string]interface{}{"v": strings.Repeat("x", configBytes-8)}
+	nodes := make([]interface{}, n)
+	edges := make([]interface{}, 0, n)
+	for i := range nodes {
+		nodes[i] = map[string]interface{}{"id": fmt.Sprintf("n%d", i), "type": "function", "config": config}
+		if i > 0 {
+			edges = append(edges, map[string]interface{}{"from": fmt.Sprintf("n%d", i-1), "to": fmt.Sprintf("n%d", i)})
+		}
+	}
+	return map[string]interface{}{"nodes": nodes, "edges": edges}
+}
+
+func TestWorkflowService_CreateWorkflow_Limits(t *testing.T) {
+	limits := config.WorkflowLimitsConfig{MaxNodes: 10, MaxEdges: 8, MaxNodeConfigBytes: 100}
+
+	tests := []struct {
+		name     string
+		workflow map[string]interface{}
+		wantErr  string
+	}{
+		{name: "at every limit", workflow: chainWorkflow(9, 100)},
+		{name: "nodes", workflow: chainWorkflow(11, 20), wantErr: "11 nodes (max_nodes 10)"},
+		{name: "edges", workflow: chainWorkflow(10, 20), wantErr: "9 edges (max_edges 8)"},
+		{name: "node config", workflow: chainWorkflow(2, 101), wantErr: "is 101 bytes (max_node_config_bytes 100)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newLimitedWorkflowService(limits)
+			resp, err := svc.CreateWorkflow(context.Background(), &CreateWorkflowRequest{
+				Username:  "alice",
+				TagName:   "main",
+				Workflow:  tt.workflow,
+				CreatedBy: "alice",
+			})
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, 9, resp.NodesCount)
+				return
+			}
+			require.ErrorIs(t, err, ErrWorkflowTooLarge)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("workflow bytes", func(t *testing.T) {
+		workflow := chainWorkflow(2, 20)
+		size := CountWorkflowElements(workflow).Bytes
+		for limit, wantErr := range map[int]bool{size: false, size - 1: true} {
+			svc := newLimitedWorkflowService(config.WorkflowLimitsConfig{MaxWorkflowBytes: limit})
+			_, err := svc.CreateWorkflow(context.Background(), &CreateWorkflowRequest{
+				Username: "alice", TagName: "main", Workflow: workflow, CreatedBy: "alice",
+			})
+			if !wantErr {
+				require.NoError(t, err)
+				continue
+			}
+			require.ErrorIs(t, err, ErrWorkflowTooLarge)
+			assert.ErrorContains(t, err, fmt.Sprintf("%d bytes (max_workflow_bytes %d)", size, limit))
+		}
+	})
+
+	t.Run("unlimited by default", func(t *testing.T) {
+		svc := newLimitedWorkflowService(config.WorkflowLimitsConfig{})
+		_, err := svc.CreateWorkflow(context.Background(), &CreateWorkflowRequest{
+			Username: "alice", TagName: "main", Workflow: chainWorkflow(50, 1000), CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestWorkflowService_CreatePatch_Limits(t *testing.T) {
+	ctx := context.Background()
+	svc := newLimitedWorkflowService(config.WorkflowLimitsConfig{MaxPatchOperations: 3, MaxNodeConfigBytes: 100})
+	_, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: "alice", TagName: "main", Workflow: chainWorkflow(1, 20), CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	addNode := func(configBytes int) map[string]interface{} {
+		return map[string]interface{}{"op": "add", "path": "/nodes/-", "value": chainWorkflow(1, configBytes)["nodes"].([]interface{})[0]}
+	}
+	patch := func(operations ...map[string]interface{}) error {
+		_, err := svc.CreatePatch(ctx, &CreatePatchRequest{
+			Username: "alice", TagName: "main", Operations: operations, CreatedBy: "alice",
+		})
+		return err
+	}
+
+	// At the limits
+	require.NoError(t, patch(addNode(100), addNode(100), addNode(100)))
+	require.NoError(t, patch(map[string]interface{}{
+		"op": "replace", "path": "/nodes/0/config", "value": map[string]interface{}{"v": strings.Repeat("x", 92)},
+	}))
+
+	err = patch(addNode(20), addNode(20), addNode(20), addNode(20))
+	require.ErrorIs(t, err, ErrWorkflowTooLarge)
+	assert.ErrorContains(t, err, "4 patch operations (max_patch_operations 3)")
+
+	err = patch(addNode(101))
+	require.ErrorIs(t, err, ErrWorkflowTooLarge)
+	assert.ErrorContains(t, err, "operation 0 (/nodes/-) sets a 101 byte config")
+
+	err = patch(map[string]interface{}{
+		"op": "replace", "path": "/nodes/0/config", "value": map[string]interface{}{"v": strings.Repeat("x", 93)},
+	})
+	require.ErrorIs(t, err, ErrWorkflowTooLarge)
+	assert.ErrorContains(t, err, "max_node_config_bytes 100")
+}
+
+func TestWorkflowService_CreatePatch_PatchedWorkflowLimits(t *testing.T) {
+	ctx := context.Background()
+	svc := newLimitedWorkflowService(config.WorkflowLimitsConfig{MaxNodes: 3, MaxEdges: 2})
+	_, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: "alice", TagName: "main", Workflow: chainWorkflow(2, 20), CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	patch := func(operations ...map[string]interface{}) error {
+		_, err := svc.CreatePatch(ctx, &CreatePatchRequest{
+			Username: "alice", TagName: "main", Operations: operations, CreatedBy: "alice",
+		})
+		return err
+	}
+	addNode := func(id string) map[string]interface{} {
+		return map[string]interface{}{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": id, "type": "function"}}
+	}
+	addEdge := func(from, to string) map[string]interface{} {
+		return map[string]interface{}{"op": "add", "path": "/edges/-", "value": map[string]interface{}{"from": from, "to": to}}
+	}
+
+	// Each patch is small, but counts against the workflow it lands on
+	require.NoError(t, patch(addNode("n2"), addEdge("n1", "n2")))
+
+	err = patch(addNode("n3"))
+	require.ErrorIs(t, err, ErrWorkflowTooLarge)
+	assert.ErrorContains(t, err, "4 nodes (max_nodes 3)")
+
+	err = patch(addEdge("n0", "n2"))
+	require.ErrorIs(t, err, ErrWorkflowTooLarge)
+	assert.ErrorContains(t, err, "3 edges (max_edges 2)")
+
+	// Removing a node makes room again
+	require.NoError(t, patch(map[string]interface{}{"op": "remove", "path": "/nodes/2"}, addNode("n3")))
+}
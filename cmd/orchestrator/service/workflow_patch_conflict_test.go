@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+)
+
+// racingTagStore runs beforeSwap just before the next compare-and-swap lands
+type racingTagStore struct {
+	*fakeTagStore
+	beforeSwap func()
+}
+
+func (r *racingTagStore) CompareAndSwap(ctx context.Context, username, tagName string, expectedVersion int64, newTarget uuid.UUID, newTargetKind, newTargetHash, movedBy string) (bool, error) {
+	if hook := r.beforeSwap; hook != nil {
+		r.beforeSwap = nil
+		hook()
+	}
+	return r.fakeTagStore.CompareAndSwap(ctx, username, tagName, expectedVersion, newTarget, newTargetKind, newTargetHash, movedBy)
+}
+
+func TestWorkflowService_CreatePatchConflict(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	store := &racingTagStore{fakeTagStore: newFakeTagStore()}
+	artifacts := newFakeArtifactStore()
+	materializer := NewMaterializerService(log)
+	workflows := NewWorkflowServiceV2(
+		newTestCASService(newFakeCASStore(), "none", 0),
+		NewArtifactService(artifacts, log),
+		NewTagService(store, log),
+		materializer,
+		log,
+	)
+
+	_, err := workflows.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: "alice",
+		TagName:  "main",
+		Workflow: map[string]interface{}{
+			"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "function"}},
+			"edges": []interface{}{},
+		},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	components, err := workflows.GetWorkflowComponents(ctx, "alice", "main")
+	require.NoError(t, err)
+	version := components.TagVersion
+
+	patch := func(nodeID string, expectedVersion int64) (*CreatePatchResponse, error) {
+		return workflows.CreatePatch(ctx, &CreatePatchRequest{
+			Username: "alice",
+			TagName:  "main",
+			Operations: []map[string]interface{}{
+				{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": nodeID, "type": "function"}},
+			},
+			CreatedBy:       "alice",
+			ExpectedVersion: &expectedVersion,
+		})
+	}
+
+	// Both patches are based on the same version; "c" lands while "b" is being saved
+	var racer *CreatePatchResponse
+	var racerErr error
+	store.beforeSwap = func() { racer, racerErr = patch("c", version) }
+
+	before := len(artifacts.artifacts)
+	_, err = patch("b", version)
+	assert.ErrorIs(t, err, ErrTagMoved)
+	require.NoError(t, racerErr)
+	assert.Equal(t, version+1, racer.Version)
+
+	// Only the winner's patch artifact is kept
+	assert.Len(t, artifacts.artifacts, before+1)
+	assert.Contains(t, artifacts.artifacts, racer.ArtifactID)
+	assert.Len(t, artifacts.chains, 1)
+
+	// The tag points at the winner, and the loser's node is not in the workflow
+	components, err = workflows.GetWorkflowComponents(ctx, "alice", "main")
+	require.NoError(t, err)
+	assert.Equal(t, racer.ArtifactID, components.ArtifactID)
+	assert.Equal(t, version+1, components.TagVersion)
+
+	workflow, err := materializer.Materialize(ctx, components)
+	require.NoError(t, err)
+	var ids []string
+	for _, node := range workflow["nodes"].([]interface{}) {
+		ids = append(ids, node.(map[string]interface{})["id"].(string))
+	}
+	assert.Equal(t, []string{"a", "c"}, ids)
+
+	t.Run("stale expected version", func(t *testing.T) {
+		_, err := patch("d", version)
+		assert.ErrorIs(t, err, ErrTagMoved)
+	})
+
+	t.Run("current expected version", func(t *testing.T) {
+		resp, err := patch("d", version+1)
+		require.NoError(t, err)
+		assert.Equal(t, version+2, resp.Version)
+	})
+}
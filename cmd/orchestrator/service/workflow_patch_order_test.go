@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+)
+
+func TestWorkflowService_PatchChainOrder(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	artifacts := newFakeArtifactStore()
+	tags := newFakeTagStore()
+	materializer := NewMaterializerService(log)
+	svc := NewWorkflowServiceV2(
+		newTestCASService(newFakeCASStore(), "none", 0),
+		NewArtifactService(artifacts, log),
+		NewTagService(tags, log),
+		materializer,
+		log,
+	)
+
+	_, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: "alice",
+		TagName:  "main",
+		Workflow: map[string]interface{}{
+			"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "function"}},
+			"edges": []interface{}{},
+		},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	for _, id := range []string{"b", "c", "d"} {
+		_, err := svc.CreatePatch(ctx, &CreatePatchRequest{
+			Username: "alice",
+			TagName:  "main",
+			Operations: []map[string]interface{}{
+				{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": id, "type": "function"}},
+			},
+			CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+	}
+
+	materialize := func() []string {
+		components, err := svc.GetWorkflowComponents(ctx, "alice", "main")
+		require.NoError(t, err)
+		for i, patch := range components.PatchChain {
+			assert.Equal(t, i+1, patch.Seq)
+			assert.Equal(t, i+1, patch.Depth)
+		}
+
+		workflow, err := materializer.Materialize(ctx, components)
+		require.NoError(t, err)
+		var ids []string
+		for _, node := range workflow["nodes"].([]interface{}) {
+			ids = append(ids, node.(map[string]interface{})["id"].(string))
+		}
+		return ids
+	}
+	want := []string{"a", "b", "c", "d"}
+	assert.Equal(t, want, materialize())
+
+	// Patches are applied by depth, whatever order the store returns them in
+	tag, err := tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	chain := artifacts.chains[tag.TargetID]
+	for _, order := range [][]int{{2, 1, 0}, {1, 2, 0}, {0, 2, 1}} {
+		artifacts.chains[tag.TargetID] = []uuid.UUID{chain[order[0]], chain[order[1]], chain[order[2]]}
+		assert.Equal(t, want, materialize(), "store order %v", order)
+	}
+
+	// A chain whose depths aren't 1..N is rejected
+	artifacts.chains[tag.TargetID] = []uuid.UUID{chain[0], chain[2]}
+	_, err = svc.GetWorkflowComponents(ctx, "alice", "main")
+	assert.ErrorIs(t, err, ErrPatchChainInconsistent)
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+func TestWorkflowService_RollbackToVersion(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	artifacts := newFakeArtifactStore()
+	tags := newFakeTagStore()
+	materializer := NewMaterializerService(log)
+	svc := NewWorkflowServiceV2(
+		newTestCASService(newFakeCASStore(), "none", 0),
+		NewArtifactService(artifacts, log),
+		NewTagService(tags, log),
+		materializer,
+		log,
+	)
+
+	_, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username: "alice",
+		TagName:  "main",
+		Workflow: map[string]interface{}{
+			"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "function"}},
+			"edges": []interface{}{},
+		},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	// Patch main three times
+	for _, id := range []string{"b", "c", "d"} {
+		_, err := svc.CreatePatch(ctx, &CreatePatchRequest{
+			Username: "alice",
+			TagName:  "main",
+			Operations: []map[string]interface{}{
+				{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": id, "type": "function"}},
+			},
+			CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+	}
+
+	headBefore, err := tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+
+	v1Components, err := svc.GetWorkflowComponentsAtVersion(ctx, "alice", "main", 1)
+	require.NoError(t, err)
+	v1, err := materializer.Materialize(ctx, v1Components)
+	require.NoError(t, err)
+
+	resp, err := svc.RollbackWorkflow(ctx, &RollbackWorkflowRequest{
+		Username:  "alice",
+		TagName:   "main",
+		Seq:       1,
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.DerivedFromSeq)
+	assert.Equal(t, headBefore.TargetID, resp.PreviousID)
+	assert.Equal(t, 2, resp.NodesCount)
+
+	// Tag now points at a new dag_version whose content matches version 1
+	tag, err := tags.GetByName(ctx, "alice", "main")
+	require.NoError(t, err)
+	assert.Equal(t, resp.ArtifactID, tag.TargetID)
+	assert.Equal(t, models.KindDAGVersion, tag.TargetKind)
+
+	components, err := svc.GetWorkflowComponents(ctx, "alice", "main")
+	require.NoError(t, err)
+	current, err := materializer.Materialize(ctx, components)
+	require.NoError(t, err)
+	assert.Equal(t, v1, current)
+
+	// History is preserved: the old head and its chain are untouched
+	chain, err := artifacts.GetPatchChain(ctx, headBefore.TargetID)
+	require.NoError(t, err)
+	assert.Len(t, chain, 3)
+	assert.Equal(t, 1, artifacts.artifacts[resp.ArtifactID].Meta["rollback_seq"])
+}
+
+func TestWorkflowService_RollbackNotFound(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	svc := NewWorkflowServiceV2(
+		newTestCASService(newFakeCASStore(), "none", 0),
+		NewArtifactService(newFakeArtifactStore(), log),
+		NewTagService(newFakeTagStore(), log),
+		NewMaterializerService(log),
+		log,
+	)
+
+	_, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username:  "alice",
+		TagName:   "main",
+		Workflow:  map[string]interface{}{"nodes": []interface{}{}, "edges": []interface{}{}},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	_, err = svc.CreatePatch(ctx, &CreatePatchRequest{
+		Username:   "alice",
+		TagName:    "main",
+		Operations: []map[string]interface{}{{"op": "add", "path": "/nodes/-", "value": map[string]interface{}{"id": "a"}}},
+		CreatedBy:  "alice",
+	})
+	require.NoError(t, err)
+
+	rollback := func(tagName string, seq int) error {
+		_, err := svc.RollbackWorkflow(ctx, &RollbackWorkflowRequest{Username: "alice", TagName: tagName, Seq: seq, CreatedBy: "alice"})
+		return err
+	}
+	assert.ErrorIs(t, rollback("missing", 0), ErrWorkflowNotFound)
+	assert.ErrorIs(t, rollback("main", 5), ErrVersionNotFound)
+}
+
+// unreachableTagStore fails every tag read with a storage error
+type unreachableTagStore struct {
+	*fakeTagStore
+	err error
+}
+
+func (s *unreachableTagStore) GetByName(ctx context.Context, username, tagName string) (*models.Tag, error) {
+	return nil, s.err
+}
+
+func TestWorkflowService_GetComponentsStoreError(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	storeErr := errors.New("connection reset")
+	svc := NewWorkflowServiceV2(
+		newTestCASService(newFakeCASStore(), "none", 0),
+		NewArtifactService(newFakeArtifactStore(), log),
+		NewTagService(&unreachableTagStore{fakeTagStore: newFakeTagStore(), err: storeErr}, log),
+		NewMaterializerService(log),
+		log,
+	)
+
+	// A failing store is not a missing workflow: it must not turn into a 404
+	_, err := svc.GetWorkflowComponentsAtVersion(ctx, "alice", "main", 0)
+	assert.ErrorIs(t, err, storeErr)
+	assert.NotErrorIs(t, err, ErrWorkflowNotFound)
+}
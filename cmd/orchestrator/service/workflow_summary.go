@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// WorkflowSummary is the resolved shape of a tag without its content
+type WorkflowSummary struct {
+	ArtifactID uuid.UUID           `json:"artifact_id"`
+	Kind       models.ArtifactKind `json:"kind"`
+	Depth      int                 `json:"depth"`
+	PatchCount int                 `json:"patch_count"`
+	NodesCount int                 `json:"nodes_count"`
+	EdgesCount int                 `json:"edges_count"`
+}
+
+// SummarizeTags resolves node/edge counts and patch depth for many tags at once
+// Lookups are batched (tag targets, patch chains, base versions, patch content)
+// so the number of queries doesn't grow with the number of tags. Patch sets are
+// not materialized: their counts are the base counts adjusted by each patch's
+// node and edge additions and removals. Summaries are keyed by target artifact.
+func (s *WorkflowServiceV2) SummarizeTags(ctx context.Context, tags []*models.Tag) (map[uuid.UUID]*WorkflowSummary, error) {
+	summaries := make(map[uuid.UUID]*WorkflowSummary, len(tags))
+	if len(tags) == 0 {
+		return summaries, nil
+	}
+
+	// 1. Tag targets
+	targets, err := s.artifactService.GetByIDs(ctx, uniqueIDs(tagTargets(tags)))
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Patch chains and base versions of patch set targets
+	var headIDs, baseIDs []uuid.UUID
+	for _, artifact := range targets {
+		if artifact.IsPatchSet() {
+			headIDs = append(headIDs, artifact.ArtifactID)
+			if artifact.BaseVersion != nil {
+				baseIDs = append(baseIDs, *artifact.BaseVersion)
+			}
+		}
+	}
+
+	chains, err := s.artifactService.GetPatchChains(ctx, headIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	bases, err := s.artifactService.GetByIDs(ctx, uniqueIDs(baseIDs))
+	if err != nil {
+		return nil, err
+	}
+	for id, artifact := range targets {
+		if artifact.IsDAGVersion() {
+			bases[id] = artifact
+		}
+	}
+
+	// 3. Content: every patch, plus bases that predate stored counts
+	var casIDs []string
+	for _, chain := range chains {
+		for _, patch := range chain {
+			casIDs = append(casIDs, patch.CasID)
+		}
+	}
+	for _, base := range bases {
+		if base.NodesCount == nil || base.EdgesCount == nil {
+			casIDs = append(casIDs, base.CasID)
+		}
+	}
+
+	fetched, err := s.casService.GetContentBulk(ctx, uniqueStrings(casIDs))
+	if err != nil {
+		return nil, err
+	}
+	if err := fetched.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load workflow contents: %w", err)
+	}
+	contents := fetched.Found
+
+	// 4. Counts
+	for id, artifact := range targets {
+		summary := &WorkflowSummary{ArtifactID: id, Kind: artifact.Kind}
+
+		base := artifact
+		if artifact.IsPatchSet() {
+			if artifact.BaseVersion == nil || bases[*artifact.BaseVersion] == nil {
+				return nil, fmt.Errorf("base version not found for patch set %s", id)
+			}
+			base = bases[*artifact.BaseVersion]
+			if artifact.Depth != nil {
+				summary.Depth = *artifact.Depth
+			}
+			summary.PatchCount = len(chains[id])
+		}
+
+		counts, err := baseCounts(base, contents)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, patch := range chains[id] {
+			content, ok := contents[patch.CasID]
+			if !ok {
+				return nil, fmt.Errorf("patch content not found: %s", patch.CasID)
+			}
+			if err := counts.applyPatch(content); err != nil {
+				return nil, fmt.Errorf("failed to count patch %s: %w", patch.ArtifactID, err)
+			}
+		}
+
+		summary.NodesCount = counts["nodes"]
+		summary.EdgesCount = counts["edges"]
+		summaries[id] = summary
+	}
+
+	s.log.Info("summarized workflow tags",
+		"tags", len(tags),
+		"patch_sets", len(headIDs),
+		"cas_blobs", len(contents),
+	)
+
+	return summaries, nil
+}
+
+// collectionCounts tracks the lengths of the top-level nodes and edges arrays
+type collectionCounts map[string]int
+
+// baseCounts returns a dag_version's counts, from the artifact row when present
+func baseCounts(base *models.Artifact, contents map[string][]byte) (collectionCounts, error) {
+	if base.NodesCount != nil && base.EdgesCount != nil {
+		return collectionCounts{"nodes": *base.NodesCount, "edges": *base.EdgesCount}, nil
+	}
+
+	content, ok := contents[base.CasID]
+	if !ok {
+		return nil, fmt.Errorf("base content not found: %s", base.CasID)
+	}
+
+	var workflow map[string]interface{}
+	if err := json.Unmarshal(content, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to parse base workflow %s: %w", base.ArtifactID, err)
+	}
+
+	counts := collectionCounts{}
+	for _, field := range []string{"nodes", "edges"} {
+		items, _ := workflow[field].([]interface{})
+		counts[field] = len(items)
+	}
+	return counts, nil
+}
+
+// applyPatch adjusts the counts for one patch's operations
+// Only operations on the arrays themselves or their direct elements change a
+// count; anything deeper (e.g. /nodes/2/config/url) leaves it as is.
+func (c collectionCounts) applyPatch(content []byte) error {
+	var operations []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		From  string      `json:"from"`
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(content, &operations); err != nil {
+		return fmt.Errorf("failed to parse patch operations: %w", err)
+	}
+
+	for _, op := range operations {
+		field, element := countedTarget(op.Path)
+		switch op.Op {
+		case "add", "copy":
+			if element {
+				c[field]++
+			} else if field != "" {
+				c.set(field, op.Value)
+			}
+		case "replace":
+			if field != "" && !element {
+				c.set(field, op.Value)
+			}
+		case "remove":
+			if element {
+				c[field]--
+			} else if field != "" {
+				c[field] = 0
+			}
+		case "move":
+			if fromField, fromElement := countedTarget(op.From); fromElement {
+				c[fromField]--
+			}
+			if element {
+				c[field]++
+			}
+		}
+	}
+
+	return nil
+}
+
+// set replaces a count with the length of a whole-array value
+func (c collectionCounts) set(field string, value interface{}) {
+	items, _ := value.([]interface{})
+	c[field] = len(items)
+}
+
+// countedTarget reports which counted array a JSON pointer addresses
+// "/nodes" → ("nodes", false); "/nodes/3" or "/nodes/-" → ("nodes", true);
+// anything else → ("", false).
+func countedTarget(path string) (string, bool) {
+	tokens := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if tokens[0] != "nodes" && tokens[0] != "edges" {
+		return "", false
+	}
+
+	switch len(tokens) {
+	case 1:
+		return tokens[0], false
+	case 2:
+		return tokens[0], true
+	default:
+		return "", false
+	}
+}
+
+func tagTargets(tags []*models.Tag) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(tags))
+	for _, tag := range tags {
+		ids = append(ids, tag.TargetID)
+	}
+	return ids
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
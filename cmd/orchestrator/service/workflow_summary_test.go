@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// countingArtifactStore counts single and batched artifact lookups
+type countingArtifactStore struct {
+	*fakeArtifactStore
+	calls map[string]int
+}
+
+func (s *countingArtifactStore) GetByID(ctx context.Context, artifactID uuid.UUID) (*models.Artifact, error) {
+	s.calls["GetByID"]++
+	return s.fakeArtifactStore.GetByID(ctx, artifactID)
+}
+
+func (s *countingArtifactStore) GetPatchChain(ctx context.Context, headID uuid.UUID) ([]*models.Artifact, error) {
+	s.calls["GetPatchChain"]++
+	return s.fakeArtifactStore.GetPatchChain(ctx, headID)
+}
+
+func (s *countingArtifactStore) GetByIDs(ctx context.Context, artifactIDs []uuid.UUID) (map[uuid.UUID]*models.Artifact, error) {
+	s.calls["GetByIDs"]++
+	return s.fakeArtifactStore.GetByIDs(ctx, artifactIDs)
+}
+
+func (s *countingArtifactStore) GetPatchChains(ctx context.Context, headIDs []uuid.UUID) (map[uuid.UUID][]*models.Artifact, error) {
+	s.calls["GetPatchChains"]++
+	return s.fakeArtifactStore.GetPatchChains(ctx, headIDs)
+}
+
+// countingCASStore counts single and batched content lookups
+type countingCASStore struct {
+	*fakeCASStore
+	calls map[string]int
+}
+
+func (s *countingCASStore) GetContentByID(ctx context.Context, casID string) (*models.CASBlob, error) {
+	s.calls["GetContentByID"]++
+	return s.fakeCASStore.GetContentByID(ctx, casID)
+}
+
+func (s *countingCASStore) GetContentBulk(ctx context.Context, casIDs []string) (map[string]*models.CASBlob, error) {
+	s.calls["GetContentBulk"]++
+	return s.fakeCASStore.GetContentBulk(ctx, casIDs)
+}
+
+func TestWorkflowService_SummarizeTags(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	artifacts := &countingArtifactStore{fakeArtifactStore: newFakeArtifactStore(), calls: map[string]int{}}
+	blobs := &countingCASStore{fakeCASStore: newFakeCASStore(), calls: map[string]int{}}
+	casService := newTestCASService(blobs, "none", 0)
+	tagService := NewTagService(newFakeTagStore(), log)
+	materializer := NewMaterializerService(log)
+	svc := NewWorkflowServiceV2(casService, NewArtifactService(artifacts, log), tagService, materializer, log)
+
+	node := func(id string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "type": "function", "config": map[string]interface{}{"n": 1}}
+	}
+	create := func(tagName string, nodeIDs ...string) {
+		nodes := []interface{}{}
+		for _, id := range nodeIDs {
+			nodes = append(nodes, node(id))
+		}
+		_, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+			Username: "alice",
+			TagName:  tagName,
+			Workflow: map[string]interface{}{
+				"nodes": nodes,
+				"edges": []interface{}{map[string]interface{}{"from": nodeIDs[0], "to": nodeIDs[1]}},
+			},
+			CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+	}
+	patch := func(tagName string, ops ...map[string]interface{}) {
+		_, err := svc.CreatePatch(ctx, &CreatePatchRequest{Username: "alice", TagName: tagName, Operations: ops, CreatedBy: "alice"})
+		require.NoError(t, err)
+	}
+
+	// main: plain dag_version
+	create("main", "a", "b")
+
+	// dev: three patches touching nodes, edges and nested config
+	create("dev", "a", "b")
+	patch("dev",
+		map[string]interface{}{"op": "add", "path": "/nodes/-", "value": node("c")},
+		map[string]interface{}{"op": "add", "path": "/edges/-", "value": map[string]interface{}{"from": "b", "to": "c"}},
+	)
+	patch("dev",
+		map[string]interface{}{"op": "replace", "path": "/nodes/0/config/n", "value": 2},
+		map[string]interface{}{"op": "add", "path": "/nodes/1", "value": node("d")},
+	)
+	patch("dev",
+		map[string]interface{}{"op": "remove", "path": "/edges/0"},
+		map[string]interface{}{"op": "remove", "path": "/nodes/0"},
+	)
+
+	// legacy: a dag_version stored before counts were recorded
+	legacyContent, err := json.Marshal(map[string]interface{}{
+		"nodes": []interface{}{node("x"), node("y"), node("z")},
+		"edges": []interface{}{},
+	})
+	require.NoError(t, err)
+	legacyCAS, err := casService.StoreContent(ctx, legacyContent, "application/json;type=dag")
+	require.NoError(t, err)
+	legacyID := uuid.New()
+	require.NoError(t, artifacts.Create(ctx, &models.Artifact{
+		ArtifactID: legacyID, Kind: models.KindDAGVersion, CasID: legacyCAS, CreatedBy: "alice", CreatedAt: time.Now(),
+	}))
+	require.NoError(t, tagService.CreateTag(ctx, "alice", "legacy", models.KindDAGVersion, legacyID, legacyCAS, "alice"))
+
+	tags := []*models.Tag{}
+	for _, name := range []string{"main", "dev", "legacy"} {
+		tag, err := tagService.GetTag(ctx, "alice", name)
+		require.NoError(t, err)
+		tags = append(tags, tag)
+	}
+
+	// Summaries come from a fixed number of batched lookups
+	artifacts.calls = map[string]int{}
+	blobs.calls = map[string]int{}
+
+	summaries, err := svc.SummarizeTags(ctx, tags)
+	require.NoError(t, err)
+	require.Len(t, summaries, 3)
+
+	assert.Equal(t, map[string]int{"GetByIDs": 2, "GetPatchChains": 1}, artifacts.calls)
+	assert.Equal(t, map[string]int{"GetContentBulk": 1}, blobs.calls)
+
+	// Counts match full materialization
+	expected := map[string][3]int{"main": {2, 1, 0}, "dev": {3, 1, 3}, "legacy": {3, 0, 0}}
+	for _, tag := range tags {
+		summary := summaries[tag.TargetID]
+		require.NotNil(t, summary, tag.TagName)
+		assert.Equal(t, tag.TargetKind, summary.Kind)
+
+		want := expected[tag.TagName]
+		assert.Equal(t, want[0], summary.NodesCount, tag.TagName)
+		assert.Equal(t, want[1], summary.EdgesCount, tag.TagName)
+		assert.Equal(t, want[2], summary.Depth, tag.TagName)
+		assert.Equal(t, want[2], summary.PatchCount, tag.TagName)
+
+		components, err := svc.GetWorkflowComponents(ctx, "alice", tag.TagName)
+		require.NoError(t, err)
+		workflow, err := materializer.Materialize(ctx, components)
+		require.NoError(t, err)
+		assert.Len(t, workflow["nodes"], summary.NodesCount, tag.TagName)
+		assert.Len(t, workflow["edges"], summary.EdgesCount, tag.TagName)
+	}
+
+	// No tags, no lookups
+	artifacts.calls = map[string]int{}
+	summaries, err = svc.SummarizeTags(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, summaries)
+	assert.Empty(t, artifacts.calls)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/ratelimit"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+)
+
+func TestWorkflowService_GetAllTagPositions(t *testing.T) {
+	ctx := context.Background()
+	svc := newSubworkflowService(t, map[string]map[string]interface{}{
+		"main":  {"nodes": []interface{}{map[string]interface{}{"id": "a", "type": "http"}}, "edges": []interface{}{}},
+		"child": {"nodes": []interface{}{map[string]interface{}{"id": "b", "type": "function"}}, "edges": []interface{}{}},
+	})
+
+	// Another user's tags stay out of the snapshot
+	_, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username:  "bob",
+		TagName:   "main",
+		Workflow:  map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"id": "z", "type": "http"}}},
+		CreatedBy: "bob",
+	})
+	require.NoError(t, err)
+
+	// Move child so the snapshot has to pick up its current position
+	moved, err := svc.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username:  "alice",
+		TagName:   "child",
+		Workflow:  map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"id": "c", "type": "function"}}},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	positions, err := svc.GetAllTagPositions(ctx, "alice")
+	require.NoError(t, err)
+
+	main, err := svc.tagService.GetTag(ctx, "alice", "main")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"main":  main.TargetID.String(),
+		"child": moved.ArtifactID.String(),
+	}, positions)
+}
+
+// fakeRunStore records the runs CreateRun creates
+type fakeRunStore struct {
+	created []*models.Run
+}
+
+func (f *fakeRunStore) Create(ctx context.Context, run *models.Run) error {
+	f.created = append(f.created, run)
+	return nil
+}
+
+func TestRunService_CreateRunSnapshotsTagPositions(t *testing.T) {
+	ctx := context.Background()
+	workflows := newSubworkflowService(t, map[string]map[string]interface{}{
+		"main":  {"nodes": []interface{}{subworkflowNode("call", "child")}, "edges": []interface{}{}},
+		"child": {"nodes": []interface{}{map[string]interface{}{"id": "b", "type": "function"}}, "edges": []interface{}{}},
+		"other": {"nodes": []interface{}{map[string]interface{}{"id": "o", "type": "http"}}, "edges": []interface{}{}},
+	})
+	// bob's main stays out of the snapshot, and child is snapshotted where it was moved to
+	_, err := workflows.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username:  "bob",
+		TagName:   "main",
+		Workflow:  map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"id": "z", "type": "http"}}},
+		CreatedBy: "bob",
+	})
+	require.NoError(t, err)
+	_, err = workflows.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Username:  "alice",
+		TagName:   "child",
+		Workflow:  map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"id": "c", "type": "function"}}},
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	log := logger.New("error", "text")
+	runs := NewRunService(&RunServiceOpts{
+		Components:      &bootstrap.Components{Logger: log},
+		Redis:           rediscommon.NewClient(rdb, log),
+		CASService:      workflows.casService,
+		WorkflowSvc:     workflows,
+		MaterializerSvc: workflows.materializer,
+		RateLimiter:     ratelimit.NewRateLimiter(rdb, log),
+	})
+	store := &fakeRunStore{}
+	runs.runStore = store
+	runs.artifactStore = newFakeArtifactStore()
+
+	resp, err := runs.CreateRun(ctx, &CreateRunRequest{Tag: "main", Username: "alice"})
+	require.NoError(t, err)
+
+	// Every one of alice's tags, where it points when the run is created
+	expected, err := workflows.GetAllTagPositions(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, expected, 3)
+	require.Len(t, store.created, 1)
+	run := store.created[0]
+	assert.Equal(t, resp.RunID, run.RunID)
+	assert.Equal(t, expected, run.TagsSnapshot)
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/templating"
+)
+
+var (
+	// ErrNotATemplate is returned for a global workflow not marked as a template
+	ErrNotATemplate = errors.New("workflow is not a template")
+
+	// ErrInvalidTemplateParameters is returned for missing or undeclared template parameters
+	ErrInvalidTemplateParameters = errors.New("invalid template parameters")
+)
+
+// TemplateParameter is a parameter declared in a template's metadata.parameters
+// Templates reference it as ${params.<name>}, in any node config or edge.
+type TemplateParameter struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
+
+// WorkflowTemplate is a global workflow marked as a template (metadata.template: true)
+type WorkflowTemplate struct {
+	TagName     string              `json:"tag_name"`
+	Description string              `json:"description,omitempty"`
+	ArtifactID  uuid.UUID           `json:"artifact_id"`
+	Parameters  []TemplateParameter `json:"parameters"`
+}
+
+// InstantiateTemplateRequest represents the input for creating a workflow from a template
+type InstantiateTemplateRequest struct {
+	Username   string                 `json:"username" validate:"required"` // The workflow is created in their namespace
+	Template   string                 `json:"template" validate:"required"` // Global template tag
+	TagName    string                 `json:"tag_name" validate:"required"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// InstantiateTemplateResponse represents the output after creating a workflow from a template
+type InstantiateTemplateResponse struct {
+	ArtifactID         uuid.UUID `json:"artifact_id"`
+	CASID              string    `json:"cas_id"`
+	Username           string    `json:"username"`
+	TagName            string    `json:"tag_name"`
+	Template           string    `json:"template"`
+	TemplateArtifactID uuid.UUID `json:"template_artifact_id"`
+	NodesCount         int       `json:"nodes_count"`
+	EdgesCount         int       `json:"edges_count"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// templateParameters returns the parameters a workflow declares as a template
+// Returns false if the workflow isn't marked as a template.
+func templateParameters(workflow map[string]interface{}) ([]TemplateParameter, bool) {
+	metadata, _ := workflow["metadata"].(map[string]interface{})
+	if isTemplate, _ := metadata["template"].(bool); !isTemplate {
+		return nil, false
+	}
+
+	declared, _ := metadata["parameters"].([]interface{})
+	params := make([]TemplateParameter, 0, len(declared))
+	for _, raw := range declared {
+		param, _ := raw.(map[string]interface{})
+		name, _ := param["name"].(string)
+		if name == "" {
+			continue
+		}
+		description, _ := param["description"].(string)
+		required, _ := param["required"].(bool)
+		params = append(params, TemplateParameter{
+			Name:        name,
+			Description: description,
+			Required:    required,
+			Default:     param["default"],
+		})
+	}
+	return params, true
+}
+
+// ListTemplates returns the catalog of global workflows marked as templates
+func (s *WorkflowServiceV2) ListTemplates(ctx context.Context) ([]*WorkflowTemplate, error) {
+	tags, err := s.tagService.ListGlobalTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]*WorkflowTemplate, 0)
+	for _, tag := range tags {
+		workflow, components, err := s.loadWorkflow(ctx, GlobalUsername, tag.TagName)
+		if err != nil {
+			s.log.Warn("failed to load global workflow, leaving it out of the template catalog", "tag", tag.TagName, "error", err)
+			continue
+		}
+		params, ok := templateParameters(workflow)
+		if !ok {
+			continue
+		}
+		metadata, _ := workflow["metadata"].(map[string]interface{})
+		description, _ := metadata["description"].(string)
+		templates = append(templates, &WorkflowTemplate{
+			TagName:     tag.TagName,
+			Description: description,
+			ArtifactID:  components.ArtifactID,
+			Parameters:  params,
+		})
+	}
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].TagName < templates[j].TagName })
+	return templates, nil
+}
+
+// InstantiateTemplate creates a workflow in the caller's namespace from a global template
+// Declared parameters fall back to their default; a required one without a
+// value, or a value for an undeclared one, is ErrInvalidTemplateParameters.
+// ${params.*} templates are substituted like node config templates at run
+// time (a lone template keeps the value's type); other templates are kept
+// for the runner. The new tag must not exist yet (ErrTagExists).
+func (s *WorkflowServiceV2) InstantiateTemplate(ctx context.Context, req *InstantiateTemplateRequest) (*InstantiateTemplateResponse, error) {
+	s.log.Info("creating workflow from template", "template", req.Template, "username", req.Username, "tag", req.TagName)
+
+	// 1. Load the template
+	template, components, err := s.loadWorkflow(ctx, GlobalUsername, req.Template)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s/%s: %v", ErrWorkflowNotFound, GlobalUsername, req.Template, err)
+	}
+	declared, ok := templateParameters(template)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotATemplate, req.Template)
+	}
+
+	// 2. Resolve the parameter values
+	params, err := templateParameterValues(declared, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Substitute them, dropping the template markers
+	metadata, _ := template["metadata"].(map[string]interface{})
+	delete(metadata, "template")
+	delete(metadata, "parameters")
+	metadata["from_template"] = req.Template
+
+	substituted, err := templating.SubstituteParams(template, params)
+	if err != nil {
+		if errors.Is(err, templating.ErrMissingPath) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplateParameters, err)
+		}
+		return nil, fmt.Errorf("failed to substitute template parameters: %w", err)
+	}
+	workflow := substituted.(map[string]interface{})
+
+	// 4. Store it as a new base version under a new tag
+	artifactID, casID, size, err := s.storeDAGVersion(ctx, workflow, req.TagName, req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tagService.CreateTag(ctx, req.Username, req.TagName, models.KindDAGVersion, artifactID, casID, req.Username); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, req.Username, models.AuditActionWorkflowTemplate, artifactTarget(artifactID, req.Username, req.TagName), map[string]interface{}{
+		"template":             req.Template,
+		"template_artifact_id": components.ArtifactID.String(),
+		"parameters":           params,
+	})
+
+	s.log.Info("workflow created from template",
+		"artifact_id", artifactID,
+		"template", req.Template,
+		"username", req.Username,
+		"tag", req.TagName,
+	)
+
+	return &InstantiateTemplateResponse{
+		ArtifactID:         artifactID,
+		CASID:              casID,
+		Username:           req.Username,
+		TagName:            req.TagName,
+		Template:           req.Template,
+		TemplateArtifactID: components.ArtifactID,
+		NodesCount:         size.Nodes,
+		EdgesCount:         size.Edges,
+		CreatedAt:          time.Now(),
+	}, nil
+}
+
+// templateParameterValues merges the given parameter values with the declared defaults
+func templateParameterValues(declared []TemplateParameter, given map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(declared))
+	known := make(map[string]bool, len(declared))
+	var missing []string
+	for _, param := range declared {
+		known[param.Name] = true
+		switch value, ok := given[param.Name]; {
+		case ok:
+			values[param.Name] = value
+		case param.Default != nil:
+			values[param.Name] = param.Default
+		case param.Required:
+			missing = append(missing, param.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: missing required parameters: %s", ErrInvalidTemplateParameters, strings.Join(missing, ", "))
+	}
+
+	var unknown []string
+	for name := range given {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: undeclared parameters: %s", ErrInvalidTemplateParameters, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// loadWorkflow materializes the workflow a tag points at
+func (s *WorkflowServiceV2) loadWorkflow(ctx context.Context, username, tagName string) (map[string]interface{}, *models.WorkflowComponents, error) {
+	components, err := s.GetWorkflowComponents(ctx, username, tagName)
+	if err != nil {
+		return nil, nil, err
+	}
+	workflow, err := s.materializer.Materialize(ctx, components)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to materialize workflow: %w", err)
+	}
+	return workflow, components, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTemplate saves a global "report" template with a required channel
+// and an optional limit
+func (f *undoFixture) createTemplate(t *testing.T) {
+	_, err := f.workflows.CreateWorkflow(f.ctx, &CreateWorkflowRequest{
+		Username: GlobalUsername,
+		TagName:  "report",
+		Workflow: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"template":    true,
+				"description": "Daily report",
+				"parameters": []interface{}{
+					map[string]interface{}{"name": "channel", "required": true},
+					map[string]interface{}{"name": "limit", "default": float64(10)},
+				},
+			},
+			"nodes": []interface{}{
+				map[string]interface{}{"id": "fetch", "type": "http", "config": map[string]interface{}{
+					"url":   "https://example.com/report?limit=${params.limit}",
+					"limit": "${params.limit}",
+				}},
+				map[string]interface{}{"id": "notify", "type": "slack", "config": map[string]interface{}{
+					"channel": "${params.channel}",
+					"text":    "Report: ${output.summary}",
+				}},
+			},
+			"edges": []interface{}{map[string]interface{}{"from": "fetch", "to": "notify"}},
+		},
+		CreatedBy: "admin",
+	})
+	require.NoError(t, err)
+}
+
+func TestWorkflowService_InstantiateTemplate(t *testing.T) {
+	f := newUndoFixture(t)
+	f.createTemplate(t)
+	f.create(t) // alice/main, not a template
+
+	templates, err := f.workflows.ListTemplates(f.ctx)
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, "report", templates[0].TagName)
+	assert.Equal(t, "Daily report", templates[0].Description)
+	assert.Equal(t, []TemplateParameter{
+		{Name: "channel", Required: true},
+		{Name: "limit", Default: float64(10)},
+	}, templates[0].Parameters)
+
+	resp, err := f.workflows.InstantiateTemplate(f.ctx, &InstantiateTemplateRequest{
+		Username:   "bob",
+		Template:   "report",
+		TagName:    "daily",
+		Parameters: map[string]interface{}{"channel": "#ops"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.NodesCount)
+	assert.Equal(t, templates[0].ArtifactID, resp.TemplateArtifactID)
+
+	workflow, err := f.workflows.GetWorkflowByTag(f.ctx, "bob", "daily")
+	require.NoError(t, err)
+	nodes := workflow["nodes"].([]interface{})
+	fetch := nodes[0].(map[string]interface{})["config"].(map[string]interface{})
+	notify := nodes[1].(map[string]interface{})["config"].(map[string]interface{})
+
+	// Defaults fill in, a lone template keeps its type, run-time templates stay
+	assert.Equal(t, "https://example.com/report?limit=10", fetch["url"])
+	assert.Equal(t, float64(10), fetch["limit"])
+	assert.Equal(t, "#ops", notify["channel"])
+	assert.Equal(t, "Report: ${output.summary}", notify["text"])
+
+	// The instance is an ordinary workflow
+	assert.Equal(t, map[string]interface{}{"description": "Daily report", "from_template": "report"}, workflow["metadata"])
+
+	t.Run("tag exists", func(t *testing.T) {
+		_, err := f.workflows.InstantiateTemplate(f.ctx, &InstantiateTemplateRequest{
+			Username:   "bob",
+			Template:   "report",
+			TagName:    "daily",
+			Parameters: map[string]interface{}{"channel": "#ops"},
+		})
+		assert.ErrorIs(t, err, ErrTagExists)
+	})
+}
+
+func TestWorkflowService_InstantiateTemplateRejected(t *testing.T) {
+	f := newUndoFixture(t)
+	f.createTemplate(t)
+	_, err := f.workflows.CreateWorkflow(f.ctx, &CreateWorkflowRequest{
+		Username:  GlobalUsername,
+		TagName:   "plain",
+		Workflow:  map[string]interface{}{"nodes": []interface{}{}, "edges": []interface{}{}},
+		CreatedBy: "admin",
+	})
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		template string
+		params   map[string]interface{}
+		want     error
+		message  string
+	}{
+		"missing required parameter": {"report", map[string]interface{}{"limit": 5}, ErrInvalidTemplateParameters, "missing required parameters: channel"},
+		"undeclared parameter":       {"report", map[string]interface{}{"channel": "#ops", "chanel": "#ops"}, ErrInvalidTemplateParameters, "undeclared parameters: chanel"},
+		"not a template":             {"plain", nil, ErrNotATemplate, "plain"},
+		"unknown template":           {"missing", nil, ErrWorkflowNotFound, "missing"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := f.workflows.InstantiateTemplate(f.ctx, &InstantiateTemplateRequest{
+				Username:   "bob",
+				Template:   tc.template,
+				TagName:    "daily",
+				Parameters: tc.params,
+			})
+			assert.ErrorIs(t, err, tc.want)
+			assert.ErrorContains(t, err, tc.message)
+
+			// Nothing is created
+			_, err = f.tags.GetTag(f.ctx, "bob", "daily")
+			assert.Error(t, err)
+		})
+	}
+}
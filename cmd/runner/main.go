@@ -6,12 +6,17 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/lyzr/orchestrator/cmd/runner/worker"
 	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
 	"github.com/lyzr/orchestrator/common/server"
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Runner doesn't need database, only queue and cache
 	components, err := bootstrap.Setup(ctx, "runner",
@@ -23,14 +28,55 @@ func main() {
 	}
 	defer components.Shutdown(ctx)
 
+	// Create Redis client
+	redisClient, err := rediscommon.NewUniversalClientFromEnv()
+	if err != nil {
+		components.Logger.Error("failed to create Redis client", "error", err)
+		os.Exit(1)
+	}
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		components.Logger.Error("failed to ping Redis", "error", err)
+		os.Exit(1)
+	}
+
+	// Load Lua script for apply_delta
+	luaScript, err := os.ReadFile("scripts/apply_delta.lua")
+	if err != nil {
+		components.Logger.Error("failed to load Lua script", "error", err)
+		os.Exit(1)
+	}
+
+	// Create CAS client
+	casClient, err := clients.NewCASClient(components.Config.CAS, redisClient, components.Logger)
+	if err != nil {
+		components.Logger.Error("failed to create CAS client", "error", err)
+		os.Exit(1)
+	}
+
+	// Create SDK
+	workflowSDK := sdk.NewSDK(redisClient, casClient, components.Logger, string(luaScript))
+
+	// Register handlers; add custom ones here
+	registry := worker.NewHandlerRegistry()
+	worker.RegisterBuiltins(registry)
+
+	// Execute function nodes
+	runnerWorker := worker.NewRunnerWorker(redisClient, workflowSDK, registry, components.Logger).
+		WithWorkers(components.Config.Service.Workers)
+	go func() {
+		if err := runnerWorker.Start(ctx); err != nil {
+			components.Logger.Error("runner worker failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
 	// Setup HTTP routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", server.HealthHandler())
+	mux.HandleFunc("/ready", components.Dependencies(redisClient).Handler())
+	mux.HandleFunc("/execute", worker.ExecuteHandler(runnerWorker))
 
-	// TODO: Add runner-specific routes
-	// mux.HandleFunc("/execute", handleExecute)
-
-	// Start HTTP server
+	// Start HTTP server (returns on SIGINT/SIGTERM)
 	srv := server.New(
 		components.Config.Service.Name,
 		components.Config.Service.Port,
@@ -40,11 +86,15 @@ func main() {
 
 	components.Logger.Info("runner service ready",
 		"port", components.Config.Service.Port,
-		"workers", os.Getenv("RUNNER_WORKERS"),
+		"workers", components.Config.Service.Workers,
+		"handlers", registry.Names(),
 	)
 
 	if err := srv.Start(); err != nil {
 		components.Logger.Error("server error", "error", err)
 		os.Exit(1)
 	}
+
+	// Stop taking tasks; unacknowledged ones are reclaimed by other runners
+	cancel()
 }
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+)
+
+// RegisterBuiltins registers the handlers every runner provides
+//   - passthrough: outputs its input unchanged
+//   - process_sales_data: totals the amount of each sale in input.sales
+//
+// Transform, filter and aggregate are node types of their own, executed by the
+// workflow-runner's data worker; no handler shadows them here.
+func RegisterBuiltins(registry Registry) {
+	registry.Register("passthrough", HandlerFunc(passthrough))
+	registry.Register("process_sales_data", HandlerFunc(processSalesData))
+}
+
+func passthrough(ctx context.Context, input *HandlerInput) (map[string]interface{}, error) {
+	return map[string]interface{}{"output": input.Payload}, nil
+}
+
+func processSalesData(ctx context.Context, input *HandlerInput) (map[string]interface{}, error) {
+	payload, _ := input.Payload.(map[string]interface{})
+	sales, ok := payload["sales"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("process_sales_data needs a sales list in its input")
+	}
+
+	var total float64
+	for _, sale := range sales {
+		amount, err := numberField(sale, "amount")
+		if err != nil {
+			return nil, err
+		}
+		total += amount
+	}
+
+	var average float64
+	if len(sales) > 0 {
+		average = total / float64(len(sales))
+	}
+	return map[string]interface{}{
+		"count":   len(sales),
+		"total":   total,
+		"average": average,
+	}, nil
+}
+
+// numberField returns a numeric field of a decoded JSON object, or the value
+// itself when field is empty
+func numberField(item interface{}, field string) (float64, error) {
+	value := item
+	if field != "" {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("expected an object with %q, got %T", field, item)
+		}
+		value = record[field]
+	}
+	switch number := value.(type) {
+	case float64:
+		return number, nil
+	case int:
+		return float64(number), nil
+	case int64:
+		return float64(number), nil
+	default:
+		return 0, fmt.Errorf("expected a number for %q, got %T", field, value)
+	}
+}
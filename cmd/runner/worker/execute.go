@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ExecuteRequest is the body of the /execute debug endpoint
+type ExecuteRequest struct {
+	Handler  string                 `json:"handler"`
+	NodeType string                 `json:"node_type,omitempty"` // Defaults to function
+	Config   map[string]interface{} `json:"config,omitempty"`
+	Input    interface{}            `json:"input,omitempty"`
+}
+
+// ExecuteHandler serves POST /execute: runs one handler synchronously
+// Meant for trying handlers out; nothing is signalled to a coordinator.
+func ExecuteHandler(w *RunnerWorker) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(rw, http.StatusMethodNotAllowed, map[string]interface{}{"error": "method not allowed"})
+			return
+		}
+
+		var req ExecuteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(rw, http.StatusBadRequest, map[string]interface{}{"error": "invalid request body"})
+			return
+		}
+		if req.NodeType == "" {
+			req.NodeType = "function"
+		}
+		if req.Config == nil {
+			req.Config = make(map[string]interface{})
+		}
+		if req.Handler != "" {
+			req.Config["handler"] = req.Handler
+		}
+
+		name, err := handlerName(req.NodeType, req.Config)
+		if err != nil {
+			writeJSON(rw, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+
+		output, err := w.Execute(r.Context(), name, &HandlerInput{
+			NodeType: req.NodeType,
+			Config:   req.Config,
+			Payload:  req.Input,
+		})
+		if errors.Is(err, ErrUnknownHandler) {
+			writeJSON(rw, http.StatusNotFound, map[string]interface{}{
+				"error":    err.Error(),
+				"handlers": w.registry.Names(),
+			})
+			return
+		}
+		if err != nil {
+			writeJSON(rw, http.StatusUnprocessableEntity, map[string]interface{}{
+				"handler": name,
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		writeJSON(rw, http.StatusOK, map[string]interface{}{
+			"handler": name,
+			"output":  output,
+		})
+	}
+}
+
+func writeJSON(rw http.ResponseWriter, status int, body interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(body)
+}
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/lyzr/orchestrator/common/worker"
+)
+
+// HandlerInput is what a handler gets to run one node
+type HandlerInput struct {
+	RunID    string
+	NodeID   string
+	NodeType string
+	Config   map[string]interface{} // Resolved by the coordinator
+	Payload  interface{}            // Output of the upstream node, nil for entry nodes
+	Vars     *worker.VarWrites      // Run variable writes, applied if the node completes; nil outside a run
+}
+
+// Handler executes function nodes
+// The returned map becomes the node's output.
+type Handler interface {
+	Handle(ctx context.Context, input *HandlerInput) (map[string]interface{}, error)
+}
+
+// HandlerFunc adapts a function to Handler
+type HandlerFunc func(ctx context.Context, input *HandlerInput) (map[string]interface{}, error)
+
+// Handle calls f
+func (f HandlerFunc) Handle(ctx context.Context, input *HandlerInput) (map[string]interface{}, error) {
+	return f(ctx, input)
+}
+
+// Registry looks handlers up by name
+// Nodes name their handler in config.handler; /execute requests for another
+// node type without one use the handler named after the type.
+type Registry interface {
+	Register(name string, handler Handler)
+	Lookup(name string) (Handler, bool)
+	Names() []string
+}
+
+// HandlerRegistry is an in-memory Registry safe for concurrent use
+type HandlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewHandlerRegistry creates an empty handler registry
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register adds a handler, replacing any handler of the same name
+func (r *HandlerRegistry) Register(name string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[name] = handler
+}
+
+// Lookup returns the handler registered under name
+func (r *HandlerRegistry) Lookup(name string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[name]
+	return handler, ok
+}
+
+// Names returns the registered handler names in order
+func (r *HandlerRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
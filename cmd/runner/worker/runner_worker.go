@@ -0,0 +1,264 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+)
+
+// streamNodeTypes maps the task streams the runner consumes to their node type
+// Transform, filter and aggregate nodes go to the workflow-runner's data worker.
+var streamNodeTypes = map[string]string{
+	"wf.tasks.function": "function",
+}
+
+// ErrUnknownHandler is returned for a handler name nothing is registered under
+var ErrUnknownHandler = errors.New("unknown handler")
+
+// RunnerWorker executes function nodes
+// Each node runs the registry handler its config names and the result is
+// signalled to the coordinator like any other worker's.
+type RunnerWorker struct {
+	redis         redis.UniversalClient
+	sdk           *sdk.SDK
+	logger        sdk.Logger
+	registry      Registry
+	consumerGroup string
+	consumerName  string
+	workers       int
+	block         time.Duration
+}
+
+// NewRunnerWorker creates a runner worker executing handlers from registry
+func NewRunnerWorker(redisClient redis.UniversalClient, workflowSDK *sdk.SDK, registry Registry, logger sdk.Logger) *RunnerWorker {
+	return &RunnerWorker{
+		redis:         redisClient,
+		sdk:           workflowSDK,
+		logger:        logger,
+		registry:      registry,
+		consumerGroup: "runner_workers",
+		consumerName:  fmt.Sprintf("runner_%s", uuid.New().String()[:8]),
+		workers:       redisWrapper.DefaultConsumerWorkers,
+		block:         redisWrapper.DefaultConsumerBlock,
+	}
+}
+
+// WithWorkers sets how many nodes of each stream run in parallel
+func (w *RunnerWorker) WithWorkers(workers int) *RunnerWorker {
+	if workers > 0 {
+		w.workers = workers
+	}
+	return w
+}
+
+// WithBlock sets how long a read waits for new tasks
+func (w *RunnerWorker) WithBlock(block time.Duration) *RunnerWorker {
+	w.block = block
+	return w
+}
+
+// Start consumes every runner stream until ctx is cancelled
+func (w *RunnerWorker) Start(ctx context.Context) error {
+	w.logger.Info("starting runner worker",
+		"consumer_group", w.consumerGroup,
+		"consumer_name", w.consumerName,
+		"workers", w.workers,
+		"handlers", w.registry.Names())
+
+	client := redisWrapper.NewClient(w.redis, w.logger)
+	for stream := range streamNodeTypes {
+		for _, priorityStream := range redisWrapper.PriorityStreams(stream) {
+			if err := client.CreateStreamGroup(ctx, priorityStream, w.consumerGroup); err != nil {
+				return fmt.Errorf("failed to create consumer group for %s: %w", priorityStream, err)
+			}
+		}
+	}
+
+	done := make(chan struct{}, len(streamNodeTypes))
+	for stream, nodeType := range streamNodeTypes {
+		stream, nodeType := stream, nodeType
+		handle := func(ctx context.Context, message redis.XMessage) error {
+			return w.handleMessage(ctx, stream, nodeType, message)
+		}
+
+		// Reclaim tasks left unacknowledged by crashed runners
+		for _, priorityStream := range redisWrapper.PriorityStreams(stream) {
+			go redisWrapper.NewReclaimer(client, priorityStream, w.consumerGroup, w.consumerName, handle).Run(ctx)
+		}
+
+		pool := redisWrapper.NewConsumerPool(client, stream, w.consumerGroup, w.consumerName, handle,
+			redisWrapper.WithConsumerWorkers(w.workers),
+			redisWrapper.WithConsumerBlock(w.block),
+			redisWrapper.WithPriorities(redisWrapper.DefaultPriorityFairness))
+		go func() {
+			pool.Run(ctx)
+			done <- struct{}{}
+		}()
+	}
+
+	for range streamNodeTypes {
+		<-done
+	}
+	w.logger.Info("runner worker stopping")
+	return nil
+}
+
+// Execute runs a handler once, outside of any run
+// Used by the /execute debug endpoint.
+func (w *RunnerWorker) Execute(ctx context.Context, handlerName string, input *HandlerInput) (map[string]interface{}, error) {
+	handler, ok := w.registry.Lookup(handlerName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownHandler, handlerName)
+	}
+	return handler.Handle(ctx, input)
+}
+
+// handlerName returns the handler a node runs
+// Function nodes must name one; the other types default to their built-in.
+func handlerName(nodeType string, config map[string]interface{}) (string, error) {
+	if name, ok := config["handler"].(string); ok && name != "" {
+		return name, nil
+	}
+	if nodeType == "function" {
+		return "", fmt.Errorf("function node config has no handler")
+	}
+	return nodeType, nil
+}
+
+// handleMessage executes the node a task token is for
+func (w *RunnerWorker) handleMessage(ctx context.Context, stream, nodeType string, message redis.XMessage) error {
+	tokenJSON, ok := message.Values["token"].(string)
+	if !ok {
+		return fmt.Errorf("message missing token field")
+	}
+
+	var token sdk.Token
+	if err := json.Unmarshal([]byte(tokenJSON), &token); err != nil {
+		return fmt.Errorf("failed to unmarshal token: %w", err)
+	}
+
+	// Also parse as map to get sent_at timestamp
+	var tokenMap map[string]interface{}
+	if err := json.Unmarshal([]byte(tokenJSON), &tokenMap); err != nil {
+		return fmt.Errorf("failed to unmarshal token map: %w", err)
+	}
+
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "node.execute",
+		tracing.AttrRunID, token.RunID,
+		tracing.AttrNodeID, token.ToNode,
+		tracing.AttrNodeType, nodeType,
+		tracing.AttrStream, stream)
+	defer span.End()
+
+	// Node logs are tagged with the run and node, and readable through the run's logs endpoint
+	nodeLog := worker.NewNodeLogger(w.redis, w.logger, &token)
+
+	config := token.Config
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+
+	startTime := time.Now()
+	if err := sdk.RecordNodeStarted(ctx, w.redis, token.RunID, token.ToNode, startTime); err != nil {
+		w.logger.Warn("failed to record node start", "run_id", token.RunID, "node_id", token.ToNode, "error", err)
+	}
+
+	name, err := handlerName(nodeType, config)
+	if err != nil {
+		nodeLog.Error("invalid runner config", "node_type", nodeType, "error", err)
+		return w.signalFailure(ctx, &token, "HandlerConfigError", err)
+	}
+
+	nodeLog.Info("processing runner task",
+		"node_type", nodeType,
+		"handler", name,
+		"token_id", token.ID)
+
+	var payload interface{}
+	if token.PayloadRef != "" {
+		payload, err = w.sdk.LoadPayload(ctx, token.PayloadRef)
+		if err != nil {
+			nodeLog.Error("failed to load input", "error", err)
+			return w.signalFailure(ctx, &token, "PayloadLoadError", fmt.Errorf("failed to load input: %w", err))
+		}
+	}
+
+	vars := worker.NewVarWrites()
+	result, err := w.Execute(ctx, name, &HandlerInput{
+		RunID:    token.RunID,
+		NodeID:   token.ToNode,
+		NodeType: nodeType,
+		Config:   config,
+		Payload:  payload,
+		Vars:     vars,
+	})
+	endTime := time.Now()
+	if err != nil {
+		errorType := "HandlerError"
+		if errors.Is(err, ErrUnknownHandler) {
+			errorType = "UnknownHandler"
+		}
+		nodeLog.Error("runner handler failed",
+			"handler", name,
+			"error", err)
+		return w.signalFailure(ctx, &token, errorType, err)
+	}
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+
+	// Timing, as the other workers report it
+	var queueTimeMs int64
+	sentAtStr, _ := tokenMap["sent_at"].(string)
+	if sentTime, err := time.Parse(time.RFC3339Nano, sentAtStr); err == nil {
+		queueTimeMs = startTime.Sub(sentTime).Milliseconds()
+	}
+	executionTimeMs := endTime.Sub(startTime).Milliseconds()
+	result["metrics"] = map[string]interface{}{
+		"sent_at":           sentAtStr,
+		"start_time":        startTime.Format(time.RFC3339Nano),
+		"end_time":          endTime.Format(time.RFC3339Nano),
+		"queue_time_ms":     queueTimeMs,
+		"execution_time_ms": executionTimeMs,
+		"total_duration_ms": queueTimeMs + executionTimeMs,
+	}
+
+	nodeLog.Info("runner handler completed", "handler", name, "execution_time_ms", executionTimeMs)
+
+	return worker.SignalCompletion(ctx, w.redis, w.logger, &worker.CompletionOpts{
+		Token:      &token,
+		Status:     "completed",
+		ResultData: result,
+		Metadata: map[string]interface{}{
+			"handler":     name,
+			"duration_ms": executionTimeMs,
+		},
+		Vars: vars,
+	})
+}
+
+// signalFailure fails the node with the handler's error
+func (w *RunnerWorker) signalFailure(ctx context.Context, token *sdk.Token, errorType string, err error) error {
+	return worker.SignalCompletion(ctx, w.redis, w.logger, &worker.CompletionOpts{
+		Token:  token,
+		Status: "failed",
+		ResultData: map[string]interface{}{
+			"status": "failed",
+			"error":  err.Error(),
+		},
+		Metadata: map[string]interface{}{
+			"error_type":    errorType,
+			"error_message": err.Error(),
+		},
+	})
+}
@@ -0,0 +1,254 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/coordinator"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (noopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// TestRunnerExecutesFunctionNode runs fetch → process, where process is a
+// function node: the coordinator routes it to the runner, which runs the
+// process_sales_data handler on fetch's output and completes the run.
+func TestRunnerExecutesFunctionNode(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := coordinator.NewCoordinator(&coordinator.CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	registry := NewHandlerRegistry()
+	RegisterBuiltins(registry)
+	runner := NewRunnerWorker(rdb, workflowSDK, registry, logger).
+		WithWorkers(2).
+		WithBlock(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+	go runner.Start(ctx)
+
+	runID := "run_function_test"
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/sales"}},
+			{ID: "process", Type: "function", Config: map[string]interface{}{"handler": "process_sales_data"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "fetch", To: "process"},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+		Token:  &sdk.Token{ID: runID + "-fetch", RunID: runID, ToNode: "fetch"},
+		Status: "completed",
+		ResultData: map[string]interface{}{
+			"sales": []interface{}{
+				map[string]interface{}{"amount": 10},
+				map[string]interface{}{"amount": 20},
+				map[string]interface{}{"amount": 60},
+			},
+		},
+	}))
+
+	// The runner completes process and the run drains
+	require.Eventually(t, func() bool {
+		status, _ := mr.Get(sdk.NodeStatusKey(runID, "process"))
+		counter, err := workflowSDK.GetCounter(ctx, runID)
+		return status == sdk.NodeStatusCompleted && err == nil && counter == 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	ref := mr.HGet("context:"+runID, "process:output")
+	require.NotEmpty(t, ref)
+	output, err := workflowSDK.LoadPayload(ctx, ref)
+	require.NoError(t, err)
+	result := output.(map[string]interface{})
+	assert.Equal(t, float64(3), result["count"])
+	assert.Equal(t, float64(90), result["total"])
+	assert.Equal(t, float64(30), result["average"])
+	assert.Contains(t, result, "metrics")
+
+	// Every task was acknowledged
+	pending, err := rdb.XPending(ctx, "wf.tasks.function", "runner_workers").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestRunnerFailsUnknownHandler(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, "")
+	runner := NewRunnerWorker(rdb, workflowSDK, NewHandlerRegistry(), logger)
+
+	ctx := context.Background()
+	token := sdk.Token{ID: "job-1", RunID: "run_1", ToNode: "store", Config: map[string]interface{}{"handler": "store_in_database"}}
+	tokenJSON, err := json.Marshal(token)
+	require.NoError(t, err)
+
+	require.NoError(t, runner.handleMessage(ctx, "wf.tasks.function", "function", redis.XMessage{
+		ID:     "1-0",
+		Values: map[string]interface{}{"token": string(tokenJSON)},
+	}))
+
+	signals := rdb.LRange(ctx, "completion_signals", 0, -1).Val()
+	require.Len(t, signals, 1)
+	var signal map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(signals[0]), &signal))
+	assert.Equal(t, "failed", signal["status"])
+	metadata := signal["metadata"].(map[string]interface{})
+	assert.Equal(t, "UnknownHandler", metadata["error_type"])
+	assert.Contains(t, metadata["error_message"], "store_in_database")
+
+	// The failure is in the node's logs
+	logs, err := sdk.LoadNodeLogs(ctx, rdb, "run_1", "store", sdk.LogLevelError)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "runner handler failed", logs[0].Message)
+	assert.Equal(t, "store_in_database", logs[0].Fields["handler"])
+}
+
+func TestRunnerSignalsHandlerVarWrites(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, "")
+	registry := NewHandlerRegistry()
+	registry.Register("count", HandlerFunc(func(ctx context.Context, input *HandlerInput) (map[string]interface{}, error) {
+		input.Vars.Incr("processed", 2)
+		input.Vars.Set("last", input.NodeID)
+		return map[string]interface{}{}, nil
+	}))
+	runner := NewRunnerWorker(rdb, workflowSDK, registry, logger)
+
+	ctx := context.Background()
+	token := sdk.Token{ID: "job-1", RunID: "run_1", ToNode: "tally", Config: map[string]interface{}{"handler": "count"}}
+	tokenJSON, err := json.Marshal(token)
+	require.NoError(t, err)
+	require.NoError(t, runner.handleMessage(ctx, "wf.tasks.function", "function", redis.XMessage{
+		ID:     "1-0",
+		Values: map[string]interface{}{"token": string(tokenJSON)},
+	}))
+
+	// The coordinator applies them when it handles the completion
+	signals := rdb.LRange(ctx, "completion_signals", 0, -1).Val()
+	require.Len(t, signals, 1)
+	var signal coordinator.CompletionSignal
+	require.NoError(t, json.Unmarshal([]byte(signals[0]), &signal))
+	assert.Equal(t, "completed", signal.Status)
+	assert.Equal(t, map[string]int64{"processed": 2}, signal.VarIncrements)
+	assert.Equal(t, map[string]interface{}{"last": "tally"}, signal.Vars)
+
+	// Outside a run, writes are dropped
+	_, err = runner.Execute(ctx, "count", &HandlerInput{NodeID: "tally"})
+	assert.NoError(t, err)
+}
+
+func TestExecuteHandler(t *testing.T) {
+	registry := NewHandlerRegistry()
+	RegisterBuiltins(registry)
+	runner := NewRunnerWorker(nil, nil, registry, noopLogger{})
+	handler := ExecuteHandler(runner)
+
+	execute := func(body string) (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/execute", strings.NewReader(body)))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return rec.Code, response
+	}
+
+	code, response := execute(`{"handler": "process_sales_data", "input": {"sales": [{"amount": 5}, {"amount": 15}]}}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "process_sales_data", response["handler"])
+	assert.Equal(t, float64(20), response["output"].(map[string]interface{})["total"])
+
+	// Data operators run in the workflow-runner, not as runner handlers
+	code, _ = execute(`{"node_type": "transform", "config": {"mapping": {"b": "$.a"}}, "input": {"a": 1}}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, response = execute(`{"handler": "store_in_database"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Contains(t, response["handlers"], "process_sales_data")
+
+	code, _ = execute(`{"input": {}}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, response = execute(`{"handler": "process_sales_data", "input": {}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Contains(t, response["error"], "sales")
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/execute", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestBuiltins(t *testing.T) {
+	registry := NewHandlerRegistry()
+	RegisterBuiltins(registry)
+	ctx := context.Background()
+
+	run := func(name string, config map[string]interface{}, payload interface{}) (map[string]interface{}, error) {
+		handler, ok := registry.Lookup(name)
+		require.True(t, ok, name)
+		return handler.Handle(ctx, &HandlerInput{NodeType: name, Config: config, Payload: payload})
+	}
+
+	out, err := run("process_sales_data", nil, map[string]interface{}{"sales": []interface{}{
+		map[string]interface{}{"amount": float64(10)},
+		map[string]interface{}{"amount": float64(5)},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, float64(15), out["total"])
+	assert.Equal(t, 7.5, out["average"])
+
+	out, err = run("passthrough", nil, "x")
+	require.NoError(t, err)
+	assert.Equal(t, "x", out["output"])
+
+	assert.Equal(t, []string{"passthrough", "process_sales_data"}, registry.Names())
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lyzr/orchestrator/cmd/webhook-worker/worker"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Bootstrap service components
+	components, err := bootstrap.Setup(ctx, "webhook-worker", bootstrap.WithoutDB())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to setup service: %v\n", err)
+		os.Exit(1)
+	}
+	defer components.Shutdown(ctx)
+
+	components.Logger.Info("webhook-worker starting")
+
+	// Create Redis client
+	redisClient, err := rediscommon.NewUniversalClientFromEnv()
+	if err != nil {
+		components.Logger.Error("failed to create Redis client", "error", err)
+		os.Exit(1)
+	}
+
+	// Ping Redis
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		components.Logger.Error("failed to ping Redis", "error", err)
+		os.Exit(1)
+	}
+	components.Logger.Info("connected to Redis")
+
+	// Load Lua script for apply_delta
+	luaScript, err := os.ReadFile("scripts/apply_delta.lua")
+	if err != nil {
+		components.Logger.Error("failed to load Lua script", "error", err)
+		os.Exit(1)
+	}
+
+	// Create CAS client
+	casClient, err := clients.NewCASClient(components.Config.CAS, redisClient, components.Logger)
+	if err != nil {
+		components.Logger.Error("failed to create CAS client", "error", err)
+		os.Exit(1)
+	}
+
+	// Create SDK
+	workflowSDK := sdk.NewSDK(redisClient, casClient, components.Logger, string(luaScript))
+
+	// Callback URLs for async webhooks must be reachable by the external system
+	callbackBaseURL := os.Getenv("WEBHOOK_CALLBACK_BASE_URL")
+	if callbackBaseURL == "" {
+		callbackBaseURL = "http://localhost:8081"
+	}
+
+	// Create webhook worker
+	webhookWorker := worker.NewWebhookWorker(redisClient, workflowSDK, components.Logger, callbackBaseURL)
+
+	// Start worker in goroutine
+	errChan := make(chan error, 1)
+	go func() {
+		if err := webhookWorker.Start(ctx); err != nil && err != context.Canceled {
+			errChan <- fmt.Errorf("webhook worker error: %w", err)
+		}
+	}()
+
+	components.Logger.Info("webhook-worker started successfully")
+
+	// Wait for shutdown signal or error
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-errChan:
+		components.Logger.Error("worker failed", "error", err)
+		os.Exit(1)
+	case sig := <-sigChan:
+		components.Logger.Info("received shutdown signal", "signal", sig)
+		cancel()
+	}
+
+	components.Logger.Info("webhook-worker shutting down gracefully")
+}
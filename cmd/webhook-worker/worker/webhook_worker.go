@@ -0,0 +1,722 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/cmd/http-worker/security"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+)
+
+// Webhook delivery modes
+const (
+	ModeSync  = "sync"  // Response body becomes the node output
+	ModeAsync = "async" // Node waits for POST /api/v1/runs/:id/webhook/:node_id
+)
+
+// urlValidator blocks requests to internal addresses (SSRF protection)
+type urlValidator interface {
+	Validate(rawURL string) error
+}
+
+// WebhookWorker delivers webhook nodes to external URLs
+// It handles two streams:
+// 1. wf.tasks.webhook - Node tokens (POST input; sync completes, async suspends)
+// 2. wf.tasks.webhook.callbacks - Async results accepted by the orchestrator
+// Async webhooks whose callback doesn't arrive by their deadline are failed.
+type WebhookWorker struct {
+	redis                 *redisWrapper.Client
+	sdk                   *sdk.SDK
+	logger                sdk.Logger
+	taskStream            string
+	callbackStream        string
+	taskConsumerGroup     string
+	callbackConsumerGroup string
+	consumerName          string
+	callbackBaseURL       string
+	httpClient            *http.Client
+	urlValidator          urlValidator
+	deadlineInterval      time.Duration // How often overdue callbacks are checked
+}
+
+// NewWebhookWorker creates a new webhook worker
+// callbackBaseURL is the externally reachable orchestrator URL used to build
+// callback URLs for async webhooks.
+func NewWebhookWorker(redisClient redis.UniversalClient, workflowSDK *sdk.SDK, logger sdk.Logger, callbackBaseURL string) *WebhookWorker {
+	return &WebhookWorker{
+		redis:                 redisWrapper.NewClient(redisClient, logger),
+		sdk:                   workflowSDK,
+		logger:                logger,
+		taskStream:            sdk.WebhookTaskStream,
+		callbackStream:        sdk.WebhookCallbackStream,
+		taskConsumerGroup:     "webhook_workers",
+		callbackConsumerGroup: "webhook_callback_workers",
+		consumerName:          fmt.Sprintf("webhook_worker_%s", uuid.New().String()[:8]),
+		callbackBaseURL:       strings.TrimRight(callbackBaseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		urlValidator:     security.NewURLValidator(),
+		deadlineInterval: 30 * time.Second,
+	}
+}
+
+// Start begins processing webhook tasks and callbacks
+func (w *WebhookWorker) Start(ctx context.Context) error {
+	w.logger.Info("starting webhook worker",
+		"task_stream", w.taskStream,
+		"callback_stream", w.callbackStream,
+		"consumer_name", w.consumerName)
+
+	for _, stream := range redisWrapper.PriorityStreams(w.taskStream) {
+		if err := w.redis.CreateStreamGroup(ctx, stream, w.taskConsumerGroup); err != nil {
+			return fmt.Errorf("failed to create task consumer group: %w", err)
+		}
+	}
+	if err := w.redis.CreateStreamGroup(ctx, w.callbackStream, w.callbackConsumerGroup); err != nil {
+		return fmt.Errorf("failed to create callback consumer group: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errChan := make(chan error, 2)
+	go func() {
+		// Tasks are delivered most urgent priority first
+		redisWrapper.NewConsumerPool(w.redis, w.taskStream, w.taskConsumerGroup, w.consumerName, w.handleTask,
+			redisWrapper.WithPriorities(redisWrapper.DefaultPriorityFairness)).Run(ctx)
+		errChan <- nil
+	}()
+	go func() {
+		errChan <- w.consume(ctx, w.callbackStream, w.callbackConsumerGroup, w.handleCallback)
+	}()
+
+	// Reclaim messages left unacknowledged by crashed workers. Reprocessing is
+	// safe: async tasks are deduplicated by the SETNX on the pending key, and
+	// callbacks only apply while the webhook is still pending.
+	for _, stream := range redisWrapper.PriorityStreams(w.taskStream) {
+		go redisWrapper.NewReclaimer(w.redis, stream, w.taskConsumerGroup, w.consumerName, w.handleTask).Run(ctx)
+	}
+	go redisWrapper.NewReclaimer(w.redis, w.callbackStream, w.callbackConsumerGroup, w.consumerName, w.handleCallback).Run(ctx)
+	go w.watchDeadlines(ctx)
+
+	select {
+	case <-ctx.Done():
+		w.logger.Info("webhook worker stopping")
+		return nil
+	case err := <-errChan:
+		w.logger.Error("webhook worker goroutine failed", "error", err)
+		cancel()
+		return err
+	}
+}
+
+// consume reads a stream until ctx is cancelled, acknowledging every message
+// Consumed and acked messages are counted by the Redis client.
+func (w *WebhookWorker) consume(ctx context.Context, stream, group string, handle func(context.Context, redis.XMessage) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		streams, err := w.redis.ReadFromStreamGroup(ctx, group, w.consumerName, stream, 1, 5*time.Second)
+		if err != nil {
+			w.logger.Error("failed to read stream", "stream", stream, "error", err)
+			time.Sleep(1 * time.Second) // Back off on error
+			continue
+		}
+
+		for _, s := range streams {
+			for _, message := range s.Messages {
+				if err := handle(ctx, message); err != nil {
+					w.logger.Error("failed to handle message", "stream", stream, "message_id", message.ID, "error", err)
+				}
+
+				if err := w.redis.AckStreamMessage(ctx, stream, group, message.ID); err != nil {
+					w.logger.Error("failed to ACK message", "stream", stream, "message_id", message.ID, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// handleTask delivers a webhook node
+// Sync webhooks signal completion with the response; async webhooks record a
+// pending callback and leave the node waiting.
+func (w *WebhookWorker) handleTask(ctx context.Context, message redis.XMessage) error {
+	tokenJSON, ok := message.Values["token"].(string)
+	if !ok {
+		return fmt.Errorf("message missing token field")
+	}
+
+	var token sdk.Token
+	if err := json.Unmarshal([]byte(tokenJSON), &token); err != nil {
+		return fmt.Errorf("failed to unmarshal token: %w", err)
+	}
+
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "node.execute",
+		tracing.AttrRunID, token.RunID,
+		tracing.AttrNodeID, token.ToNode,
+		tracing.AttrNodeType, "webhook",
+		tracing.AttrStream, w.taskStream)
+	defer span.End()
+
+	// Node logs are tagged with the run and node, and readable through the run's logs endpoint
+	nodeLog := w.nodeLogger(&token)
+	nodeLog.Info("processing webhook task", "token_id", token.ID)
+
+	// The IR carries the retry policy (and the config if the token has none)
+	node, err := w.loadNode(ctx, token.RunID, token.ToNode)
+	if err != nil {
+		return err
+	}
+
+	config := token.Config
+	if config == nil {
+		if config, err = w.nodeConfig(ctx, node); err != nil {
+			return err
+		}
+	}
+
+	mode, _ := config["mode"].(string)
+	if mode == "" {
+		mode = ModeSync
+	}
+
+	startTime := time.Now()
+	if err := sdk.RecordNodeStarted(ctx, w.redis.GetUnderlying(), token.RunID, token.ToNode, startTime); err != nil {
+		w.logger.Warn("failed to record node start", "run_id", token.RunID, "node_id", token.ToNode, "error", err)
+	}
+	var result map[string]interface{}
+	switch mode {
+	case ModeSync:
+		result, err = w.deliver(ctx, &token, node.Retry, config, "")
+	case ModeAsync:
+		return w.deliverAsync(ctx, &token, node.Retry, config)
+	default:
+		err = fmt.Errorf("invalid webhook mode %q (expected %s or %s)", mode, ModeSync, ModeAsync)
+	}
+	endTime := time.Now()
+
+	if err != nil {
+		return w.signalFailure(ctx, &token, err)
+	}
+
+	result["metrics"] = map[string]interface{}{
+		"start_time":        startTime.Format(time.RFC3339Nano),
+		"end_time":          endTime.Format(time.RFC3339Nano),
+		"execution_time_ms": endTime.Sub(startTime).Milliseconds(),
+	}
+
+	return worker.SignalCompletion(ctx, w.redis.GetUnderlying(), w.logger, &worker.CompletionOpts{
+		Token:      &token,
+		Status:     "completed",
+		ResultData: result,
+		Metadata: map[string]interface{}{
+			"status_code": result["status_code"],
+			"attempts":    result["attempts"],
+		},
+	})
+}
+
+// deliverAsync posts an async webhook and suspends the node until its callback
+func (w *WebhookWorker) deliverAsync(ctx context.Context, token *sdk.Token, retry *sdk.RetryPolicy, config map[string]interface{}) error {
+	callbackToken, err := newCallbackToken()
+	if err != nil {
+		return err
+	}
+
+	urlStr, _ := config["url"].(string)
+	now := time.Now()
+	deadline := now.Add(callbackTimeout(config))
+	pending := sdk.WebhookPending{
+		RunID:         token.RunID,
+		NodeID:        token.ToNode,
+		TokenID:       token.ID,
+		CallbackToken: callbackToken,
+		URL:           urlStr,
+		Status:        sdk.WebhookStatusPending,
+		CreatedAt:     now.Unix(),
+		DeadlineAt:    deadline.Unix(),
+	}
+	pendingJSON, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pending webhook: %w", err)
+	}
+
+	// SETNX: a redelivered token must not post the webhook twice
+	pendingKey := sdk.WebhookPendingKey(token.RunID, token.ToNode)
+	created, err := w.redis.SetNX(ctx, pendingKey, string(pendingJSON), sdk.WebhookPendingTTL)
+	if err != nil {
+		return fmt.Errorf("failed to record pending webhook: %w", err)
+	}
+	nodeLog := w.nodeLogger(token)
+	if !created {
+		nodeLog.Warn("webhook already pending, skipping")
+		return nil
+	}
+	if err := sdk.TrackRunKeys(ctx, w.redis.GetUnderlying(), token.RunID, pendingKey); err != nil {
+		nodeLog.Error("failed to track pending webhook", "error", err)
+	}
+	if err := sdk.TrackWebhookDeadline(ctx, w.redis.GetUnderlying(), pendingKey, deadline); err != nil {
+		nodeLog.Error("failed to track webhook deadline", "error", err)
+	}
+
+	// Mark waiting before posting so a fast callback's completion is not overwritten
+	// (no-op if the node was cancelled)
+	if _, err := sdk.SetNodeStatus(ctx, w.redis.GetUnderlying(), token.RunID, token.ToNode, sdk.NodeStatusWaitingForCallback); err != nil {
+		nodeLog.Error("failed to set node status", "error", err)
+	}
+
+	callbackURL := fmt.Sprintf("%s/api/v1/runs/%s/webhook/%s?token=%s",
+		w.callbackBaseURL, url.PathEscape(token.RunID), url.PathEscape(token.ToNode), callbackToken)
+
+	if _, err := w.deliver(ctx, token, retry, config, callbackURL); err != nil {
+		// Nothing will call back: drop the pending record and fail the node
+		if delErr := w.redis.Delete(ctx, pendingKey); delErr != nil {
+			nodeLog.Error("failed to delete pending webhook", "error", delErr)
+		}
+		if delErr := sdk.ForgetWebhookDeadline(ctx, w.redis.GetUnderlying(), pendingKey); delErr != nil {
+			nodeLog.Error("failed to forget webhook deadline", "error", delErr)
+		}
+		return w.signalFailure(ctx, token, err)
+	}
+
+	nodeLog.Info("webhook delivered, waiting for callback")
+
+	return nil
+}
+
+// handleCallback completes an async webhook node with the delivered result
+func (w *WebhookWorker) handleCallback(ctx context.Context, message redis.XMessage) error {
+	callbackJSON, ok := message.Values["callback"].(string)
+	if !ok {
+		return fmt.Errorf("message missing callback field")
+	}
+
+	var callback sdk.WebhookCallback
+	if err := json.Unmarshal([]byte(callbackJSON), &callback); err != nil {
+		return fmt.Errorf("failed to unmarshal callback: %w", err)
+	}
+	if callback.RunID == "" || callback.NodeID == "" {
+		return fmt.Errorf("callback missing run_id or node_id")
+	}
+
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "webhook.callback",
+		tracing.AttrRunID, callback.RunID,
+		tracing.AttrNodeID, callback.NodeID,
+		tracing.AttrNodeType, "webhook",
+		tracing.AttrStream, w.callbackStream)
+	defer span.End()
+
+	pendingKey := sdk.WebhookPendingKey(callback.RunID, callback.NodeID)
+	data, err := w.redis.Get(ctx, pendingKey)
+	if err != nil {
+		return fmt.Errorf("failed to load pending webhook: %w", err)
+	}
+
+	var pending sdk.WebhookPending
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		return fmt.Errorf("failed to unmarshal pending webhook: %w", err)
+	}
+
+	token := sdk.Token{
+		ID:     pending.TokenID,
+		RunID:  callback.RunID,
+		ToNode: callback.NodeID,
+	}
+	nodeLog := w.nodeLogger(&token)
+
+	// Idempotency check: only the first callback completes the node
+	if pending.Status != sdk.WebhookStatusPending {
+		nodeLog.Warn("webhook callback already processed", "previous_status", pending.Status)
+		return nil
+	}
+
+	status := sdk.WebhookStatusCompleted
+	if callback.Status == sdk.WebhookStatusFailed {
+		status = sdk.WebhookStatusFailed
+		if err := w.signalFailure(ctx, &token, fmt.Errorf("webhook callback reported failure: %s", callback.Error)); err != nil {
+			return err
+		}
+	} else {
+		result := callback.Result
+		if result == nil {
+			result = make(map[string]interface{})
+		}
+		err = worker.SignalCompletion(ctx, w.redis.GetUnderlying(), w.logger, &worker.CompletionOpts{
+			Token:      &token,
+			Status:     "completed",
+			ResultData: result,
+			Metadata: map[string]interface{}{
+				"callback": true,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to signal completion: %w", err)
+		}
+	}
+
+	// Mark processed AFTER the completion signal so a failed signal can be retried
+	pending.Status = status
+	pending.ProcessedAt = time.Now().Unix()
+	if updated, err := json.Marshal(pending); err != nil {
+		nodeLog.Error("failed to marshal pending webhook", "error", err)
+	} else if err := w.redis.Set(ctx, pendingKey, string(updated), sdk.WebhookPendingTTL); err != nil {
+		nodeLog.Error("failed to update pending webhook", "error", err)
+	}
+	if err := sdk.ForgetWebhookDeadline(ctx, w.redis.GetUnderlying(), pendingKey); err != nil {
+		nodeLog.Error("failed to forget webhook deadline", "error", err)
+	}
+
+	nodeStatus := sdk.NodeStatusCompleted
+	if status == sdk.WebhookStatusFailed {
+		nodeStatus = sdk.NodeStatusFailed
+	}
+	if _, err := sdk.SetNodeStatus(ctx, w.redis.GetUnderlying(), callback.RunID, callback.NodeID, nodeStatus); err != nil {
+		nodeLog.Error("failed to update node status", "error", err)
+	}
+
+	nodeLog.Info("webhook callback processed", "status", status)
+
+	return nil
+}
+
+// watchDeadlines fails async webhooks whose callback is overdue until ctx is cancelled
+func (w *WebhookWorker) watchDeadlines(ctx context.Context) {
+	ticker := time.NewTicker(w.deadlineInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.expireCallbacks(ctx, time.Now()); err != nil {
+				w.logger.Error("failed to check webhook deadlines", "error", err)
+			}
+		}
+	}
+}
+
+// expireCallbacks queues a failed callback for every webhook still pending past its deadline
+// The failure goes through the callback stream, so it is applied exactly like a
+// failure reported by the external system, and a callback that races it is
+// ignored by the same idempotency check. Paused runs are checked again after resume.
+func (w *WebhookWorker) expireCallbacks(ctx context.Context, now time.Time) error {
+	keys, err := sdk.ExpiredWebhooks(ctx, w.redis.GetUnderlying(), now)
+	if err != nil {
+		return err
+	}
+
+	for _, pendingKey := range keys {
+		data, err := w.redis.Get(ctx, pendingKey)
+		if errors.Is(err, redisWrapper.ErrKeyNotFound) {
+			w.forgetDeadline(ctx, pendingKey)
+			continue
+		}
+		if err != nil {
+			w.logger.Error("failed to load pending webhook", "key", pendingKey, "error", err)
+			continue
+		}
+
+		var pending sdk.WebhookPending
+		if err := json.Unmarshal([]byte(data), &pending); err != nil {
+			w.logger.Error("failed to unmarshal pending webhook", "key", pendingKey, "error", err)
+			w.forgetDeadline(ctx, pendingKey)
+			continue
+		}
+		if pending.Status != sdk.WebhookStatusPending {
+			w.forgetDeadline(ctx, pendingKey)
+			continue
+		}
+		if paused, err := sdk.IsRunPaused(ctx, w.redis.GetUnderlying(), pending.RunID); err != nil || paused {
+			continue
+		}
+
+		deadline := time.Unix(pending.DeadlineAt, 0).UTC().Format(time.RFC3339)
+		callbackJSON, err := json.Marshal(sdk.WebhookCallback{
+			RunID:  pending.RunID,
+			NodeID: pending.NodeID,
+			Status: sdk.WebhookStatusFailed,
+			Error:  fmt.Sprintf("no callback received by the deadline (%s)", deadline),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook callback: %w", err)
+		}
+		if _, err := w.redis.AddToStream(ctx, w.callbackStream, map[string]interface{}{
+			"callback": string(callbackJSON),
+			"run_id":   pending.RunID,
+			"node_id":  pending.NodeID,
+		}); err != nil {
+			w.logger.Error("failed to queue webhook timeout", "key", pendingKey, "error", err)
+			continue
+		}
+
+		token := sdk.Token{ID: pending.TokenID, RunID: pending.RunID, ToNode: pending.NodeID}
+		w.nodeLogger(&token).Warn("webhook callback timed out", "deadline", deadline)
+		w.forgetDeadline(ctx, pendingKey)
+	}
+	return nil
+}
+
+// forgetDeadline stops tracking a pending webhook's deadline
+func (w *WebhookWorker) forgetDeadline(ctx context.Context, pendingKey string) {
+	if err := sdk.ForgetWebhookDeadline(ctx, w.redis.GetUnderlying(), pendingKey); err != nil {
+		w.logger.Error("failed to forget webhook deadline", "key", pendingKey, "error", err)
+	}
+}
+
+// callbackTimeout returns how long an async webhook waits for its callback
+// Set by the node's callback_timeout_ms, capped to how long the pending record is kept.
+func callbackTimeout(config map[string]interface{}) time.Duration {
+	timeout := sdk.DefaultWebhookCallbackTimeout
+	if ms, ok := config["callback_timeout_ms"].(float64); ok && ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	if timeout > sdk.WebhookPendingTTL {
+		timeout = sdk.WebhookPendingTTL
+	}
+	return timeout
+}
+
+// deliver posts the node input to the webhook URL, retrying per the node's policy
+// Network errors, 429 and 5xx responses are retried; other non-2xx fail at once.
+func (w *WebhookWorker) deliver(ctx context.Context, token *sdk.Token, retry *sdk.RetryPolicy, config map[string]interface{}, callbackURL string) (map[string]interface{}, error) {
+	urlStr, ok := config["url"].(string)
+	if !ok || urlStr == "" {
+		return nil, fmt.Errorf("missing or invalid url in config")
+	}
+
+	nodeLog := w.nodeLogger(token)
+	if err := w.urlValidator.Validate(urlStr); err != nil {
+		nodeLog.Warn("URL validation failed (security protection)",
+			"url", urlStr,
+			"error", err)
+		return nil, fmt.Errorf("URL blocked for security: %w", err)
+	}
+
+	method, _ := config["method"].(string)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	input, err := w.loadInput(ctx, token, config)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := map[string]interface{}{
+		"run_id":  token.RunID,
+		"node_id": token.ToNode,
+		"input":   input,
+	}
+	if callbackURL != "" {
+		envelope["callback_url"] = callbackURL
+	}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal webhook body: %w", err)
+	}
+
+	attempts := retry.Attempts()
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			delay := retry.Backoff(attempt - 1)
+			nodeLog.Warn("retrying webhook",
+				"attempt", attempt,
+				"delay", delay,
+				"error", lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		result, retryable, err := w.post(ctx, method, urlStr, body, config)
+		if err == nil {
+			result["attempts"] = attempt
+			return result, nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// post sends a single webhook request
+// Returns whether a failed request is worth retrying.
+func (w *WebhookWorker) post(ctx context.Context, method, urlStr string, body []byte, config map[string]interface{}) (map[string]interface{}, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "workflow-runner/1.0")
+	if headers, ok := config["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			if s, ok := value.(string); ok {
+				req.Header.Set(name, s)
+			}
+		}
+	}
+
+	start := time.Now()
+	resp, err := w.httpClient.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return nil, true, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retryable, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	// Parse response as JSON if possible
+	var responseData interface{}
+	if err := json.Unmarshal(respBody, &responseData); err != nil {
+		responseData = string(respBody)
+	}
+
+	w.logger.Info("webhook delivered",
+		"url", urlStr,
+		"method", method,
+		"status_code", resp.StatusCode,
+		"duration_ms", duration.Milliseconds())
+
+	return map[string]interface{}{
+		"status":      "success",
+		"status_code": resp.StatusCode,
+		"body":        responseData,
+		"duration_ms": duration.Milliseconds(),
+		"url":         urlStr,
+		"method":      method,
+	}, false, nil
+}
+
+// loadInput returns the payload to post
+// An explicit config payload wins; otherwise the upstream output referenced by the token.
+func (w *WebhookWorker) loadInput(ctx context.Context, token *sdk.Token, config map[string]interface{}) (interface{}, error) {
+	if payload, ok := config["payload"]; ok {
+		return payload, nil
+	}
+	if token.PayloadRef == "" {
+		return map[string]interface{}{}, nil
+	}
+
+	data, err := w.sdk.CASClient.Get(ctx, token.PayloadRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load node input: %w", err)
+	}
+
+	raw, _ := data.([]byte)
+	var input interface{}
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return string(raw), nil
+	}
+	return input, nil
+}
+
+// loadNode loads the webhook node from the run's IR
+func (w *WebhookWorker) loadNode(ctx context.Context, runID, nodeID string) (*sdk.Node, error) {
+	irJSON, err := w.redis.Get(ctx, fmt.Sprintf("ir:%s", runID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IR: %w", err)
+	}
+
+	var ir sdk.IR
+	if err := json.Unmarshal([]byte(irJSON), &ir); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal IR: %w", err)
+	}
+
+	node, exists := ir.Nodes[nodeID]
+	if !exists {
+		return nil, fmt.Errorf("node not found: %s", nodeID)
+	}
+	return node, nil
+}
+
+// nodeConfig returns the node config (inline first, then CAS)
+func (w *WebhookWorker) nodeConfig(ctx context.Context, node *sdk.Node) (map[string]interface{}, error) {
+	if len(node.Config) > 0 {
+		return node.Config, nil
+	}
+	if node.ConfigRef == "" {
+		return make(map[string]interface{}), nil
+	}
+
+	data, err := w.sdk.CASClient.Get(ctx, node.ConfigRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config from CAS: %w", err)
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(data.([]byte), &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return config, nil
+}
+
+// signalFailure fails the node with the delivery error
+func (w *WebhookWorker) signalFailure(ctx context.Context, token *sdk.Token, err error) error {
+	w.nodeLogger(token).Error("webhook failed", "error", err)
+
+	return worker.SignalCompletion(ctx, w.redis.GetUnderlying(), w.logger, &worker.CompletionOpts{
+		Token:  token,
+		Status: "failed",
+		ResultData: map[string]interface{}{
+			"status": "failed",
+			"error":  err.Error(),
+		},
+		Metadata: map[string]interface{}{
+			"error_type":    "WebhookError",
+			"error_message": err.Error(),
+		},
+	})
+}
+
+// nodeLogger returns a logger that also records lines in the node's logs
+func (w *WebhookWorker) nodeLogger(token *sdk.Token) *worker.NodeLogger {
+	return worker.NewNodeLogger(w.redis.GetUnderlying(), w.logger, token)
+}
+
+// newCallbackToken returns a random secret for an async webhook's callback URL
+func newCallbackToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate callback token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
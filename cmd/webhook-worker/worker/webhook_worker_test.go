@@ -0,0 +1,260 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// allowAllURLs skips SSRF validation so tests can target httptest servers
+type allowAllURLs struct{}
+
+func (allowAllURLs) Validate(string) error { return nil }
+
+type webhookFixture struct {
+	redis  *miniredis.Miniredis
+	rdb    redis.UniversalClient
+	worker *WebhookWorker
+	runID  string
+}
+
+func newWebhookFixture(t *testing.T, retry *sdk.RetryPolicy) *webhookFixture {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	log := logger.New("error", "text")
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, log), log, "")
+	w := NewWebhookWorker(rdb, workflowSDK, log, "http://orchestrator.test/")
+	w.urlValidator = allowAllURLs{}
+
+	f := &webhookFixture{redis: mr, rdb: rdb, worker: w, runID: "run-1"}
+	ir := sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"notify": {ID: "notify", Type: "webhook", IsTerminal: true, Retry: retry},
+		},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	mr.Set("ir:"+f.runID, string(irJSON))
+	return f
+}
+
+// task builds a stream message for the webhook node, with upstream output as its input
+func (f *webhookFixture) task(t *testing.T, config map[string]interface{}) redis.XMessage {
+	payloadRef, err := f.worker.sdk.CASClient.Put(context.Background(), []byte(`{"order_id":42}`), "application/json")
+	require.NoError(t, err)
+
+	tokenJSON, err := json.Marshal(sdk.Token{
+		ID:         "token-1",
+		RunID:      f.runID,
+		FromNode:   "fetch",
+		ToNode:     "notify",
+		PayloadRef: payloadRef,
+		Config:     config,
+	})
+	require.NoError(t, err)
+	return redis.XMessage{ID: "1-0", Values: map[string]interface{}{"token": string(tokenJSON)}}
+}
+
+// signals pops every completion signal the worker sent
+func (f *webhookFixture) signals(t *testing.T) []map[string]interface{} {
+	var signals []map[string]interface{}
+	for {
+		raw, err := f.rdb.LPop(context.Background(), "completion_signals").Result()
+		if err == redis.Nil {
+			return signals
+		}
+		require.NoError(t, err)
+		var signal map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(raw), &signal))
+		signals = append(signals, signal)
+	}
+}
+
+func (f *webhookFixture) nodeStatus() string {
+	status, _ := f.redis.Get(sdk.NodeStatusKey(f.runID, "notify"))
+	return status
+}
+
+func TestWebhookWorker_SyncRetriesAndCompletes(t *testing.T) {
+	f := newWebhookFixture(t, &sdk.RetryPolicy{MaxAttempts: 3, BackoffMS: 1})
+
+	var calls atomic.Int32
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		w.Write([]byte(`{"delivered":true}`))
+	}))
+	defer server.Close()
+
+	err := f.worker.handleTask(context.Background(), f.task(t, map[string]interface{}{
+		"url":     server.URL,
+		"headers": map[string]interface{}{"X-Api-Key": "secret"},
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, map[string]interface{}{"order_id": float64(42)}, received["input"])
+	assert.NotContains(t, received, "callback_url")
+
+	signals := f.signals(t)
+	require.Len(t, signals, 1)
+	assert.Equal(t, "completed", signals[0]["status"])
+	result := signals[0]["result_data"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"delivered": true}, result["body"])
+	assert.Equal(t, float64(2), result["attempts"])
+
+	// The retry is in the node's logs
+	logs, err := sdk.LoadNodeLogs(context.Background(), f.rdb, f.runID, "notify", sdk.LogLevelWarn)
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "retrying webhook", logs[0].Message)
+}
+
+func TestWebhookWorker_SyncFailsWithoutRetryingClientErrors(t *testing.T) {
+	f := newWebhookFixture(t, &sdk.RetryPolicy{MaxAttempts: 3, BackoffMS: 1})
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	require.NoError(t, f.worker.handleTask(context.Background(), f.task(t, map[string]interface{}{"url": server.URL})))
+
+	assert.Equal(t, int32(1), calls.Load())
+	signals := f.signals(t)
+	require.Len(t, signals, 1)
+	assert.Equal(t, "failed", signals[0]["status"])
+	assert.Contains(t, signals[0]["metadata"].(map[string]interface{})["error_message"], "status 400")
+}
+
+func TestWebhookWorker_AsyncWaitsForCallback(t *testing.T) {
+	f := newWebhookFixture(t, nil)
+	ctx := context.Background()
+
+	var callbackURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		callbackURL, _ = body["callback_url"].(string)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	task := f.task(t, map[string]interface{}{"url": server.URL, "mode": ModeAsync})
+	require.NoError(t, f.worker.handleTask(ctx, task))
+
+	// Suspended: no completion yet, node waits on the callback
+	assert.Empty(t, f.signals(t))
+	assert.Equal(t, sdk.NodeStatusWaitingForCallback, f.nodeStatus())
+
+	parsed, err := url.Parse(callbackURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/runs/run-1/webhook/notify", parsed.Path)
+
+	raw, err := f.redis.Get(sdk.WebhookPendingKey(f.runID, "notify"))
+	require.NoError(t, err)
+	var pending sdk.WebhookPending
+	require.NoError(t, json.Unmarshal([]byte(raw), &pending))
+	assert.Equal(t, sdk.WebhookStatusPending, pending.Status)
+	assert.Equal(t, parsed.Query().Get("token"), pending.CallbackToken)
+
+	// Redelivery of the same token does not post twice
+	require.NoError(t, f.worker.handleTask(ctx, task))
+	raw2, _ := f.redis.Get(sdk.WebhookPendingKey(f.runID, "notify"))
+	assert.Equal(t, raw, raw2)
+
+	// The callback (queued by the orchestrator) completes the node exactly once
+	callbackJSON, err := json.Marshal(sdk.WebhookCallback{
+		RunID:  f.runID,
+		NodeID: "notify",
+		Status: sdk.WebhookStatusCompleted,
+		Result: map[string]interface{}{"approved_by": "billing"},
+	})
+	require.NoError(t, err)
+	callback := redis.XMessage{ID: "2-0", Values: map[string]interface{}{"callback": string(callbackJSON)}}
+	require.NoError(t, f.worker.handleCallback(ctx, callback))
+	require.NoError(t, f.worker.handleCallback(ctx, callback))
+
+	signals := f.signals(t)
+	require.Len(t, signals, 1)
+	assert.Equal(t, "completed", signals[0]["status"])
+	assert.Equal(t, "token-1", signals[0]["job_id"])
+	assert.Equal(t, map[string]interface{}{"approved_by": "billing"}, signals[0]["result_data"])
+	assert.Equal(t, sdk.NodeStatusCompleted, f.nodeStatus())
+}
+
+func TestWebhookWorker_AsyncFailsWithoutCallback(t *testing.T) {
+	f := newWebhookFixture(t, nil)
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	task := f.task(t, map[string]interface{}{"url": server.URL, "mode": ModeAsync, "callback_timeout_ms": float64(60000)})
+	require.NoError(t, f.worker.handleTask(ctx, task))
+	assert.Equal(t, sdk.NodeStatusWaitingForCallback, f.nodeStatus())
+
+	raw, err := f.redis.Get(sdk.WebhookPendingKey(f.runID, "notify"))
+	require.NoError(t, err)
+	var pending sdk.WebhookPending
+	require.NoError(t, json.Unmarshal([]byte(raw), &pending))
+	assert.InDelta(t, start.Add(time.Minute).Unix(), pending.DeadlineAt, 1)
+
+	callbacks := func() []redis.XMessage {
+		messages, err := f.rdb.XRange(ctx, sdk.WebhookCallbackStream, "-", "+").Result()
+		require.NoError(t, err)
+		return messages
+	}
+
+	// Not due yet
+	require.NoError(t, f.worker.expireCallbacks(ctx, start.Add(30*time.Second)))
+	assert.Empty(t, callbacks())
+
+	// Overdue: a failed callback is queued once
+	require.NoError(t, f.worker.expireCallbacks(ctx, start.Add(2*time.Minute)))
+	require.NoError(t, f.worker.expireCallbacks(ctx, start.Add(3*time.Minute)))
+	queued := callbacks()
+	require.Len(t, queued, 1)
+
+	require.NoError(t, f.worker.handleCallback(ctx, queued[0]))
+	signals := f.signals(t)
+	require.Len(t, signals, 1)
+	assert.Equal(t, "failed", signals[0]["status"])
+	assert.Contains(t, signals[0]["metadata"].(map[string]interface{})["error_message"], "no callback received")
+	assert.Equal(t, sdk.NodeStatusFailed, f.nodeStatus())
+}
+
+func TestCallbackTimeout(t *testing.T) {
+	assert.Equal(t, sdk.DefaultWebhookCallbackTimeout, callbackTimeout(map[string]interface{}{}))
+	assert.Equal(t, 90*time.Second, callbackTimeout(map[string]interface{}{"callback_timeout_ms": float64(90000)}))
+	assert.Equal(t, sdk.WebhookPendingTTL, callbackTimeout(map[string]interface{}{"callback_timeout_ms": float64(48 * time.Hour / time.Millisecond)}))
+}
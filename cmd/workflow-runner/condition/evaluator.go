@@ -1,12 +1,17 @@
 package condition
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
 	"sync"
 
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types/ref"
 	"github.com/lyzr/orchestrator/common/sdk"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // Evaluator evaluates conditions using CEL (Common Expression Language)
@@ -23,24 +28,237 @@ func NewEvaluator() *Evaluator {
 }
 
 // Evaluate evaluates a condition and returns the result
-func (e *Evaluator) Evaluate(condition *sdk.Condition, output interface{}, context map[string]interface{}) (bool, error) {
+// Expressions can reference `output` (current node output), `ctx` (previous node
+// outputs), and `vars` (run-level variables, see sdk.SetVar). A nil vars map is
+// treated as empty.
+func (e *Evaluator) Evaluate(condition *sdk.Condition, output interface{}, context map[string]interface{}, vars map[string]interface{}) (bool, error) {
 	if condition == nil {
 		return false, fmt.Errorf("nil condition")
 	}
 
 	switch condition.Type {
 	case "cel":
-		return e.evaluateCEL(condition.Expression, output, context)
+		return e.evaluateCEL(condition.Expression, newActivation(output, context, vars, nil))
 	default:
 		return false, fmt.Errorf("unsupported condition type: %s", condition.Type)
 	}
 }
 
-// evaluateCEL evaluates a CEL expression
-func (e *Evaluator) evaluateCEL(expr string, output, context interface{}) (bool, error) {
-	// Convert JSONPath-style $.field to CEL output.field for compatibility
-	// This allows workflows to use $.approved instead of output.approved
-	normalizedExpr := strings.ReplaceAll(expr, "$.", "output.")
+// Evaluation is the outcome of a condition together with the values it read
+// Used to explain routing decisions, e.g. "output.score was 50 (needed >= 80)".
+type Evaluation struct {
+	Expression string                 `json:"expression"`
+	Result     bool                   `json:"result"`
+	Values     map[string]interface{} `json:"values,omitempty"` // Referenced path → value at evaluation time
+	Error      string                 `json:"error,omitempty"`
+}
+
+// EvaluateWithDetails evaluates a condition and records the referenced values
+// Evaluation errors are returned and also recorded on the Evaluation. nodes
+// is bound as in EvaluateBranchCondition.
+func (e *Evaluator) EvaluateWithDetails(condition *sdk.Condition, output interface{}, context map[string]interface{}, vars map[string]interface{}, nodes map[string]interface{}) (*Evaluation, error) {
+	return e.evaluateWithDetails(condition, newActivation(output, context, vars, nodes))
+}
+
+// EvaluateBranchCondition evaluates a branch rule's condition with details
+// The condition also sees `nodes`, the outputs of the nodes it references by
+// ID, e.g. "nodes.a.output.score > nodes.b.output.score" (see ConditionNodes).
+func (e *Evaluator) EvaluateBranchCondition(condition *sdk.Condition, output interface{}, context map[string]interface{}, vars map[string]interface{}, nodes map[string]interface{}) (*Evaluation, error) {
+	return e.evaluateWithDetails(condition, newActivation(output, context, vars, nodes))
+}
+
+// EvaluateLoopCondition evaluates a loop node's condition with details
+// The condition also sees `iteration` (1 for the first), `history`, the
+// outputs of earlier iterations, e.g. "size(history) < 3", and `nodes` as in
+// EvaluateBranchCondition.
+func (e *Evaluator) EvaluateLoopCondition(condition *sdk.Condition, output interface{}, context map[string]interface{}, vars map[string]interface{}, nodes map[string]interface{}, iteration int64, history []interface{}) (*Evaluation, error) {
+	activation := newActivation(output, context, vars, nodes)
+	activation["iteration"] = iteration
+	if history != nil {
+		activation["history"] = history
+	}
+	return e.evaluateWithDetails(condition, activation)
+}
+
+// ConditionNodes builds the `nodes` variable for a condition
+// Only the nodes the expression references are loaded, each as {"output": ...}.
+// A referenced node without an output yet (still pending, skipped, or not in
+// the workflow) is an error naming it, rather than a CEL "no such key" later.
+func ConditionNodes(condition *sdk.Condition, load func(nodeID string) (interface{}, error)) (map[string]interface{}, error) {
+	if condition == nil {
+		return nil, nil
+	}
+	return loadNodes("condition", []string{condition.Expression}, load)
+}
+
+// ExpressionNodes builds the `nodes` variable for map selectors and data operators
+// As ConditionNodes, for the nodes referenced by any of exprs.
+func ExpressionNodes(exprs []string, load func(nodeID string) (interface{}, error)) (map[string]interface{}, error) {
+	return loadNodes("expression", exprs, load)
+}
+
+// loadNodes loads the outputs of the nodes exprs reference, each once
+func loadNodes(what string, exprs []string, load func(nodeID string) (interface{}, error)) (map[string]interface{}, error) {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, expr := range exprs {
+		for _, ref := range sdk.ConditionNodeRefs(expr) {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	nodes := make(map[string]interface{}, len(refs))
+	for _, nodeID := range refs {
+		output, err := load(nodeID)
+		if errors.Is(err, sdk.ErrNodeOutputNotFound) {
+			return nil, fmt.Errorf("%s references node %s, which has not completed", what, nodeID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load output of node %s: %w", nodeID, err)
+		}
+		nodes[nodeID] = map[string]interface{}{"output": output}
+	}
+	return nodes, nil
+}
+
+func (e *Evaluator) evaluateWithDetails(condition *sdk.Condition, activation map[string]interface{}) (*Evaluation, error) {
+	if condition == nil {
+		return nil, fmt.Errorf("nil condition")
+	}
+
+	evaluation := &Evaluation{
+		Expression: condition.Expression,
+		Values:     referencedValues(normalizeExpression(condition.Expression), activation),
+	}
+
+	var result bool
+	var err error
+	switch condition.Type {
+	case "cel":
+		result, err = e.evaluateCEL(condition.Expression, activation)
+	default:
+		err = fmt.Errorf("unsupported condition type: %s", condition.Type)
+	}
+	if err != nil {
+		evaluation.Error = err.Error()
+		return evaluation, err
+	}
+	evaluation.Result = result
+
+	return evaluation, nil
+}
+
+// newActivation binds the variables expressions can reference
+// Variables that aren't given (nil vars, iteration and history outside loop
+// conditions, nodes unless an expression references them) are empty.
+func newActivation(output interface{}, context map[string]interface{}, vars map[string]interface{}, nodes map[string]interface{}) map[string]interface{} {
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	if nodes == nil {
+		nodes = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"output":    output,
+		"ctx":       context,
+		"vars":      vars,
+		"iteration": int64(0),
+		"history":   []interface{}{},
+		"nodes":     nodes,
+	}
+}
+
+// referencePattern matches dotted variable paths such as output.score or vars.retries
+var referencePattern = regexp.MustCompile(`\b(?:output|ctx|vars|nodes)(?:\.[A-Za-z_][A-Za-z0-9_]*)+`)
+
+// referencedValues resolves every variable path in expr against the activation
+// Paths that don't resolve are recorded as nil so the caller can see the miss.
+func referencedValues(expr string, activation map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, path := range referencePattern.FindAllString(expr, -1) {
+		if _, seen := values[path]; seen {
+			continue
+		}
+		segments := strings.Split(path, ".")
+		var current interface{} = activation
+		for _, segment := range segments {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				current = nil
+				break
+			}
+			current = m[segment]
+		}
+		values[path] = current
+	}
+	return values
+}
+
+// normalizeExpression converts JSONPath-style $.field to CEL output.field
+func normalizeExpression(expr string) string {
+	return sdk.NormalizeConditionExpression(expr)
+}
+
+// Select evaluates a CEL collection selector and returns its elements
+// Used by map nodes to fan out, e.g. "output.items" or "$.items". The
+// expression sees the same variables as conditions, `nodes` loaded by
+// ExpressionNodes, and must return a list.
+func (e *Evaluator) Select(expr string, output interface{}, context map[string]interface{}, vars map[string]interface{}, nodes map[string]interface{}) ([]interface{}, error) {
+	out, err := e.evalCEL(expr, newActivation(output, context, vars, nodes))
+	if err != nil {
+		return nil, err
+	}
+
+	native, err := out.ConvertToNative(reflect.TypeOf([]interface{}{}))
+	if err != nil {
+		return nil, fmt.Errorf("CEL selector did not return a list, got %s", out.Type())
+	}
+
+	return native.([]interface{}), nil
+}
+
+// Value evaluates a CEL expression and returns its result as a JSON value
+// Used by transform nodes to compute fields, e.g. "output.name" or
+// "$.price * 2". Numbers come back as float64, as decoded JSON has them.
+// nodes is bound as in Select.
+func (e *Evaluator) Value(expr string, output interface{}, context map[string]interface{}, vars map[string]interface{}, nodes map[string]interface{}) (interface{}, error) {
+	out, err := e.evalCEL(expr, newActivation(output, context, vars, nodes))
+	if err != nil {
+		return nil, err
+	}
+
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("CEL expression did not return a JSON value, got %s", out.Type())
+	}
+
+	return native.(*structpb.Value).AsInterface(), nil
+}
+
+// evaluateCEL evaluates a boolean CEL expression
+func (e *Evaluator) evaluateCEL(expr string, activation map[string]interface{}) (bool, error) {
+	out, err := e.evalCEL(expr, activation)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("CEL expression did not return boolean, got %T", out.Value())
+	}
+
+	return result, nil
+}
+
+// evalCEL compiles (with caching) and runs a CEL expression
+func (e *Evaluator) evalCEL(expr string, activation map[string]interface{}) (ref.Val, error) {
+	normalizedExpr := normalizeExpression(expr)
 
 	// Check cache first
 	e.mu.RLock()
@@ -52,7 +270,7 @@ func (e *Evaluator) evaluateCEL(expr string, output, context interface{}) (bool,
 		var err error
 		prg, err = e.compileCEL(normalizedExpr)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 
 		e.mu.Lock()
@@ -61,32 +279,20 @@ func (e *Evaluator) evaluateCEL(expr string, output, context interface{}) (bool,
 	}
 
 	// Evaluate
-	out, _, err := prg.Eval(map[string]interface{}{
-		"output": output,
-		"ctx":    context,
-	})
-
+	out, _, err := prg.Eval(activation)
 	if err != nil {
-		return false, fmt.Errorf("CEL evaluation error: %w", err)
-	}
-
-	result, ok := out.Value().(bool)
-	if !ok {
-		return false, fmt.Errorf("CEL expression did not return boolean, got %T", out.Value())
+		return nil, fmt.Errorf("CEL evaluation error: %w", err)
 	}
 
-	return result, nil
+	return out, nil
 }
 
 // compileCEL compiles a CEL expression
 func (e *Evaluator) compileCEL(expr string) (cel.Program, error) {
-	// Create CEL environment with variables
-	env, err := cel.NewEnv(
-		cel.Variable("output", cel.DynType),
-		cel.Variable("ctx", cel.DynType),
-	)
+	// Same environment the compiler validates conditions against
+	env, err := sdk.NewConditionEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create CEL env: %w", err)
+		return nil, err
 	}
 
 	// Compile expression
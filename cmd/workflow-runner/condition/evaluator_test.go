@@ -0,0 +1,79 @@
+package condition
+
+import (
+	"testing"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVarsInConditions evaluates conditions over run variables
+// How nodes write them is tested in the coordinator (TestParallelVarIncrementsReadDownstream).
+func TestVarsInConditions(t *testing.T) {
+	evaluator := NewEvaluator()
+	output := map[string]interface{}{"ok": true}
+	vars := map[string]interface{}{"processed": float64(150), "status": "done"}
+
+	met, err := evaluator.Evaluate(&sdk.Condition{
+		Type:       "cel",
+		Expression: "vars.processed == 150 && vars.status == 'done' && $.ok",
+	}, output, nil, vars)
+	require.NoError(t, err)
+	assert.True(t, met)
+
+	met, err = evaluator.Evaluate(&sdk.Condition{
+		Type:       "cel",
+		Expression: "vars.processed > 150",
+	}, output, nil, vars)
+	require.NoError(t, err)
+	assert.False(t, met)
+
+	// Missing vars are absent from the map, not an error with has()
+	met, err = evaluator.Evaluate(&sdk.Condition{
+		Type:       "cel",
+		Expression: "!has(vars.unset)",
+	}, output, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, met)
+}
+
+// TestSelect evaluates map node selectors against an upstream output
+func TestSelect(t *testing.T) {
+	evaluator := NewEvaluator()
+	output := map[string]interface{}{
+		"items": []interface{}{"a", map[string]interface{}{"id": float64(2)}, float64(3)},
+		"count": float64(3),
+	}
+
+	items, err := evaluator.Select("$.items", output, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", map[string]interface{}{"id": float64(2)}, float64(3)}, items)
+
+	items, err = evaluator.Select("output.items.filter(i, type(i) == double)", output, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{float64(3)}, items)
+
+	_, err = evaluator.Select("output.count", output, nil, nil, nil)
+	assert.ErrorContains(t, err, "did not return a list")
+}
+
+func TestValue(t *testing.T) {
+	evaluator := NewEvaluator()
+	output := map[string]interface{}{"name": "Ada", "price": float64(4), "qty": float64(3)}
+
+	value, err := evaluator.Value("$.name", output, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", value)
+
+	value, err = evaluator.Value("output.price * output.qty", output, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, float64(12), value)
+
+	value, err = evaluator.Value(`{"tags": [output.name, vars.region]}`, output, nil, map[string]interface{}{"region": "eu"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"tags": []interface{}{"Ada", "eu"}}, value)
+
+	_, err = evaluator.Value("output.missing", output, nil, nil, nil)
+	assert.Error(t, err)
+}
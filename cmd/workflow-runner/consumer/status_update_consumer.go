@@ -7,8 +7,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/config"
 	"github.com/lyzr/orchestrator/common/models"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
 	"github.com/lyzr/orchestrator/common/repository"
+	"github.com/lyzr/orchestrator/common/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -22,12 +25,14 @@ type Logger interface {
 
 // StatusUpdateConsumer consumes status updates from Redis stream and updates database
 type StatusUpdateConsumer struct {
-	redis         *redis.Client
+	redis         redis.UniversalClient
 	runRepo       *repository.RunRepository
 	logger        Logger
 	stream        string
 	consumerGroup string
 	consumerName  string
+	workers       int
+	consumer      config.ConsumerConfig
 }
 
 // StatusUpdate represents a status update message
@@ -38,7 +43,7 @@ type StatusUpdate struct {
 }
 
 // NewStatusUpdateConsumer creates a new status update consumer
-func NewStatusUpdateConsumer(redis *redis.Client, runRepo *repository.RunRepository, logger Logger) *StatusUpdateConsumer {
+func NewStatusUpdateConsumer(redis redis.UniversalClient, runRepo *repository.RunRepository, logger Logger) *StatusUpdateConsumer {
 	return &StatusUpdateConsumer{
 		redis:         redis,
 		runRepo:       runRepo,
@@ -46,9 +51,24 @@ func NewStatusUpdateConsumer(redis *redis.Client, runRepo *repository.RunReposit
 		stream:        "run.status.updates",
 		consumerGroup: "status_updaters",
 		consumerName:  fmt.Sprintf("status_updater_%d", time.Now().Unix()),
+		workers:       redisWrapper.DefaultConsumerWorkers,
 	}
 }
 
+// WithWorkers sets how many status updates are applied in parallel
+// Updates are applied in no particular order, so with more than one worker a
+// run's RUNNING update can land after its COMPLETED one when the stream backs up.
+func (c *StatusUpdateConsumer) WithWorkers(workers int) *StatusUpdateConsumer {
+	c.workers = workers
+	return c
+}
+
+// WithConsumerConfig sets the read block and error backoff for status updates
+func (c *StatusUpdateConsumer) WithConsumerConfig(cfg config.ConsumerConfig) *StatusUpdateConsumer {
+	c.consumer = cfg
+	return c
+}
+
 // Start begins consuming status updates
 func (c *StatusUpdateConsumer) Start(ctx context.Context) error {
 	c.logger.Info("starting status update consumer",
@@ -62,55 +82,16 @@ func (c *StatusUpdateConsumer) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
-	// Process messages in a loop
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("status update consumer stopping")
-			return nil
-		default:
-			if err := c.processNextMessage(ctx); err != nil {
-				c.logger.Error("failed to process message", "error", err)
-				time.Sleep(1 * time.Second) // Back off on error
-			}
-		}
-	}
-}
-
-// processNextMessage reads and processes one message from the stream
-func (c *StatusUpdateConsumer) processNextMessage(ctx context.Context) error {
-	// Read message from stream (XREADGROUP)
-	streams, err := c.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
-		Group:    c.consumerGroup,
-		Consumer: c.consumerName,
-		Streams:  []string{c.stream, ">"},
-		Count:    1,
-		Block:    5 * time.Second,
-	}).Result()
-
-	if err == redis.Nil {
-		// No messages, continue
-		return nil
-	}
-	if err != nil {
-		return fmt.Errorf("XREADGROUP error: %w", err)
-	}
+	// Reclaim updates left unacknowledged by crashed consumers
+	client := redisWrapper.NewClient(c.redis, c.logger)
+	go redisWrapper.NewReclaimer(client, c.stream, c.consumerGroup, c.consumerName, c.handleMessage).Run(ctx)
 
-	// Process each message
-	for _, stream := range streams {
-		for _, message := range stream.Messages {
-			if err := c.handleMessage(ctx, message); err != nil {
-				c.logger.Error("failed to handle message", "message_id", message.ID, "error", err)
-				// Continue to next message even if this one fails
-			}
-
-			// Acknowledge message
-			if err := c.redis.XAck(ctx, c.stream, c.consumerGroup, message.ID).Err(); err != nil {
-				c.logger.Error("failed to ACK message", "message_id", message.ID, "error", err)
-			}
-		}
-	}
+	// Up to c.workers updates are applied in parallel
+	redisWrapper.NewConsumerPool(client, c.stream, c.consumerGroup, c.consumerName, c.handleMessage,
+		redisWrapper.WithConsumerWorkers(c.workers),
+		redisWrapper.WithConsumerConfig(c.consumer)).Run(ctx)
 
+	c.logger.Info("status update consumer stopping")
 	return nil
 }
 
@@ -127,6 +108,12 @@ func (c *StatusUpdateConsumer) handleMessage(ctx context.Context, message redis.
 		return fmt.Errorf("failed to unmarshal status update: %w", err)
 	}
 
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "run.status_update",
+		tracing.AttrRunID, statusUpdate.RunID,
+		tracing.AttrStream, c.stream)
+	defer span.End()
+
 	c.logger.Info("processing status update",
 		"run_id", statusUpdate.RunID,
 		"status", statusUpdate.Status)
@@ -0,0 +1,61 @@
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// handleCancelledNode processes a synthetic cancellation signal for one node
+// The node's token is consumed so the counter stays balanced, but its
+// dependents are not triggered. Other branches keep running, and the run
+// completes normally once the counter reaches zero.
+func (c *Coordinator) handleCancelledNode(ctx context.Context, signal *CompletionSignal, ir *sdk.IR) {
+	c.logger.Info("node cancelled",
+		"run_id", signal.RunID,
+		"node_id", signal.NodeID,
+		"metadata", signal.Metadata)
+
+	// 1. Store cancelled output so the node shows up in run details
+	cancelledOutput := map[string]interface{}{
+		"status":       sdk.NodeStatusCancelled,
+		"node_id":      signal.NodeID,
+		"cancelled_at": time.Now().Format(time.RFC3339Nano),
+	}
+	for k, v := range signal.Metadata {
+		cancelledOutput[k] = v
+	}
+
+	if resultRef, err := c.sdk.StoreOutput(ctx, cancelledOutput); err == nil {
+		c.sdk.StoreContext(ctx, signal.RunID, signal.NodeID, resultRef)
+	}
+
+	// 2. Consume the node's token (same op key as a normal completion, so idempotent)
+	if err := c.sdk.Consume(ctx, signal.RunID, signal.NodeID); err != nil {
+		c.logger.Error("failed to consume token for cancelled node",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+		return
+	}
+
+	counter, _ := c.sdk.GetCounter(ctx, signal.RunID)
+
+	// 3. Publish node_cancelled event
+	if ir.Metadata != nil {
+		if username, ok := ir.Metadata["username"].(string); ok {
+			c.lifecycle.EventPublisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
+				"type":      "node_cancelled",
+				"run_id":    signal.RunID,
+				"node_id":   signal.NodeID,
+				"counter":   counter,
+				"timestamp": time.Now().Unix(),
+			})
+		}
+	}
+
+	// 4. No routing - the cancelled branch stops here.
+	// The remaining branches may already be done, so check for run completion.
+	c.lifecycle.CompletionChecker.CheckCompletion(ctx, signal.RunID)
+}
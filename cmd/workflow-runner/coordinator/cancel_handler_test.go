@@ -0,0 +1,126 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (noopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// TestCancelNodeInParallelWorkflow cancels one branch of A→(B,C) while the
+// sibling branch completes normally, and checks the counter stays balanced.
+func TestCancelNodeInParallelWorkflow(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	// A → (B, C), both branches terminal
+	runID := "run_cancel_test"
+	ir := &sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"A": {ID: "A", Type: "http", Dependents: []string{"B", "C"}},
+			"B": {ID: "B", Type: "http", Dependencies: []string{"A"}, IsTerminal: true},
+			"C": {ID: "C", Type: "http", Dependencies: []string{"A"}, IsTerminal: true},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	complete := func(nodeID string) {
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+			Token:      &sdk.Token{ID: runID + "-" + nodeID, RunID: runID, ToNode: nodeID},
+			Status:     "completed",
+			ResultData: map[string]interface{}{"node": nodeID},
+		}))
+	}
+	nodeStatus := func(nodeID string) string {
+		return rdb.Get(ctx, sdk.NodeStatusKey(runID, nodeID)).Val()
+	}
+	counter := func() int {
+		value, _ := workflowSDK.GetCounter(ctx, runID)
+		return value
+	}
+
+	// 1. A completes and fans out to B and C
+	complete("A")
+	require.Eventually(t, func() bool {
+		return nodeStatus("B") == sdk.NodeStatusRunning && nodeStatus("C") == sdk.NodeStatusRunning && counter() == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// 2. Cancel B while it is in flight
+	cancelled, previous, err := sdk.CancelNode(ctx, rdb, runID, "B")
+	require.NoError(t, err)
+	require.True(t, cancelled)
+	assert.Equal(t, sdk.NodeStatusRunning, previous)
+	require.NoError(t, worker.SignalCancellation(ctx, rdb, logger, runID, "B", map[string]interface{}{"reason": "test"}))
+
+	require.Eventually(t, func() bool { return counter() == 1 }, 5*time.Second, 20*time.Millisecond)
+
+	// Cancelling again is rejected
+	cancelled, previous, err = sdk.CancelNode(ctx, rdb, runID, "B")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.Equal(t, sdk.NodeStatusCancelled, previous)
+
+	// 3. B's worker finishes late - the result is dropped
+	complete("B")
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, counter())
+	assert.Equal(t, sdk.NodeStatusCancelled, nodeStatus("B"))
+
+	// 4. Sibling C completes normally and the run finishes
+	complete("C")
+	require.Eventually(t, func() bool {
+		return rdb.Get(ctx, "run:status:"+runID).Val() == "COMPLETED"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 0, counter())
+	assert.Equal(t, sdk.NodeStatusCompleted, nodeStatus("C"))
+
+	// B's recorded output reflects the cancellation
+	output, err := workflowSDK.LoadNodeOutput(ctx, runID, "B")
+	require.NoError(t, err)
+	assert.Equal(t, sdk.NodeStatusCancelled, output.(map[string]interface{})["status"])
+
+	// Terminal nodes cannot be cancelled
+	cancelled, previous, err = sdk.CancelNode(ctx, rdb, runID, "C")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.Equal(t, sdk.NodeStatusCompleted, previous)
+}
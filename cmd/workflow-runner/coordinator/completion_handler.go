@@ -2,16 +2,38 @@ package coordinator
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/operators"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // handleCompletion processes a completion signal and routes to next nodes
 func (c *Coordinator) handleCompletion(ctx context.Context, signal *CompletionSignal) {
+	// Completions arriving after the timeout detector gave up on the token are
+	// dropped (checked first so buffered completions of paused runs stop their
+	// token counting as in flight)
+	if c.completedAfterTimeout(ctx, signal) {
+		return
+	}
+
+	// Paused runs route nothing; the signal is replayed on resume
+	if c.holdIfPaused(ctx, signal) {
+		return
+	}
+
+	// Route as a child of the worker's node execution span
+	ctx = tracing.ContextWithTraceParent(ctx, signal.TraceParent)
+	ctx, span := tracing.StartSpan(ctx, "coordinator.route",
+		tracing.AttrRunID, signal.RunID,
+		tracing.AttrNodeID, signal.NodeID)
+	defer span.End()
+
 	c.logger.Info("handling completion",
 		"run_id", signal.RunID,
 		"node_id", signal.NodeID,
@@ -26,6 +48,9 @@ func (c *Coordinator) handleCompletion(ctx context.Context, signal *CompletionSi
 		return
 	}
 
+	// The job is no longer in flight; its concurrency slot goes to a waiting token
+	c.releaseSlot(ctx, signal, ir)
+
 	node, exists := ir.Nodes[signal.NodeID]
 	if !exists {
 		c.logger.Error("node not found in IR",
@@ -33,13 +58,60 @@ func (c *Coordinator) handleCompletion(ctx context.Context, signal *CompletionSi
 			"node_id", signal.NodeID)
 		return
 	}
+	span.SetAttributes(attribute.String(tracing.AttrNodeType, node.Type))
+
+	// Retries re-emit the node's token rather than completing it
+	if signal.Status == sdk.SignalStatusRetry {
+		c.handleRetryNode(ctx, signal, node, ir)
+		return
+	}
+
+	// Timed-out tokens are dispatched again or failed
+	if signal.Status == sdk.SignalStatusTimeout {
+		c.handleTimedOutNode(ctx, signal, node, ir)
+		return
+	}
+
+	// Map bodies run once per element; their results are joined by the map node
+	if mapNode := mapNodeForBody(ir, signal.NodeID); mapNode != nil && signal.Status != sdk.NodeStatusCancelled {
+		c.handleMapItemCompletion(ctx, signal, mapNode, ir)
+		return
+	}
+
+	// 2. Handle cancelled and failed execution
+	if signal.Status == sdk.NodeStatusCancelled {
+		c.handleCancelledNode(ctx, signal, ir)
+		return
+	}
+
+	// Record the final node status; this fails if the node was cancelled while
+	// running, in which case the late result is dropped (its token is already consumed)
+	finalStatus := sdk.NodeStatusCompleted
+	if signal.Status == "failed" {
+		finalStatus = sdk.NodeStatusFailed
+	}
+	recorded, err := sdk.SetNodeStatus(ctx, c.redis, signal.RunID, signal.NodeID, finalStatus)
+	if err != nil {
+		c.logger.Warn("failed to record node status",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+	} else if !recorded {
+		c.logger.Info("ignoring completion for cancelled node",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"status", signal.Status)
+		return
+	}
 
-	// 2. Handle failed execution
 	if signal.Status == "failed" {
 		c.handleFailedNode(ctx, signal, ir)
 		return
 	}
 
+	// The node's run variable writes must be visible to the conditions routed below
+	c.applyVarWrites(ctx, signal)
+
 	// 3. Check if this was an agent node that might have created patches
 	if node.Type == "agent" {
 		c.logger.Info("agent node completed, checking for run patches",
@@ -106,37 +178,10 @@ func (c *Coordinator) handleCompletion(ctx context.Context, signal *CompletionSi
 		}
 	}
 
-	// 4. Consume token (apply -1 to counter)
-	if err := c.sdk.Consume(ctx, signal.RunID, signal.NodeID); err != nil {
-		c.logger.Error("failed to consume token",
-			"run_id", signal.RunID,
-			"node_id", signal.NodeID,
-			"error", err)
-		return
-	}
-
-	// Get counter after consumption for event
-	counter, _ := c.sdk.GetCounter(ctx, signal.RunID)
-
-	// 5. Store result data in CAS and create reference
+	// 4. Store result data in CAS and create reference
 	resultRef := c.storeResultInCAS(ctx, signal)
 
-	// Publish node_completed event
-	if ir.Metadata != nil {
-		if username, ok := ir.Metadata["username"].(string); ok {
-			c.lifecycle.EventPublisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
-				"type":       "node_completed",
-				"run_id":     signal.RunID,
-				"node_id":    signal.NodeID,
-				"status":     signal.Status,
-				"counter":    counter,
-				"result_ref": resultRef,
-				"timestamp":  time.Now().Unix(),
-			})
-		}
-	}
-
-	// 6. Reload IR to get latest version with patches (if any)
+	// 5. Reload IR to get latest version with patches (if any)
 	ir, err = c.loadIR(ctx, signal.RunID)
 	if err != nil {
 		c.logger.Error("failed to reload IR",
@@ -160,7 +205,7 @@ func (c *Coordinator) handleCompletion(ctx context.Context, signal *CompletionSi
 		"is_terminal", node.IsTerminal,
 		"dependents_count", len(node.Dependents))
 
-	// 7. Determine next nodes (handles branches, loops, etc.)
+	// 6. Determine next nodes (handles branches, loops, etc.)
 	c.logger.Info("about to determine next nodes",
 		"run_id", signal.RunID,
 		"node_id", signal.NodeID,
@@ -178,25 +223,64 @@ func (c *Coordinator) handleCompletion(ctx context.Context, signal *CompletionSi
 		ResultRef: resultRef, // Use the CAS ref we just created
 		Metadata:  signal.Metadata,
 	}, node, ir)
+	if errors.Is(err, operators.ErrNoBranchMatched) {
+		// The node fails instead of completing; its token is left unconsumed
+		// like any failed node's, so the run can't complete through it
+		c.failUnmatchedBranch(ctx, signal, resultRef, err, ir)
+		return
+	}
+
 	if err != nil {
 		c.logger.Error("failed to determine next nodes",
 			"run_id", signal.RunID,
 			"node_id", signal.NodeID,
 			"error", err)
+	} else {
+		c.logger.Info("determined next nodes from branch/loop logic",
+			"run_id", signal.RunID,
+			"from_node", signal.NodeID,
+			"next_nodes", nextNodes,
+			"count", len(nextNodes))
+
+		// 7. Emit to appropriate streams and update counter
+		c.routeToNextNodes(ctx, signal, nextNodes, resultRef, ir)
+	}
+
+	// 8. Consume token (apply -1 to counter), only after the next nodes are
+	// counted, so the counter can't read zero while the run continues
+	if err := c.sdk.Consume(ctx, signal.RunID, signal.NodeID); err != nil {
+		c.logger.Error("failed to consume token",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
 		return
 	}
 
-	c.logger.Info("determined next nodes from branch/loop logic",
-		"run_id", signal.RunID,
-		"from_node", signal.NodeID,
-		"next_nodes", nextNodes,
-		"count", len(nextNodes))
+	// Get counter after consumption for event
+	counter, _ := c.sdk.GetCounter(ctx, signal.RunID)
+
+	// Publish node_completed event
+	if ir.Metadata != nil {
+		if username, ok := ir.Metadata["username"].(string); ok {
+			c.lifecycle.EventPublisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
+				"type":       "node_completed",
+				"run_id":     signal.RunID,
+				"node_id":    signal.NodeID,
+				"status":     signal.Status,
+				"counter":    counter,
+				"result_ref": resultRef,
+				"timestamp":  time.Now().Unix(),
+			})
+		}
+	}
 
-	// 8. Emit to appropriate streams and update counter
-	c.routeToNextNodes(ctx, signal, nextNodes, resultRef, ir)
+	if err != nil {
+		return
+	}
 
-	// 9. Terminal node check
-	if node.IsTerminal {
+	// 9. Terminal node check; a path that routes nowhere (e.g. a filter that
+	// dropped its token) may also have been the last one running
+	if node.IsTerminal || len(nextNodes) == 0 {
 		c.logger.Debug("terminal node completed, checking for run completion",
 			"run_id", signal.RunID,
 			"node_id", signal.NodeID)
@@ -204,38 +288,64 @@ func (c *Coordinator) handleCompletion(ctx context.Context, signal *CompletionSi
 	}
 }
 
+// claimCompletion reports whether a signal read from the queue should be handled
+// Only the first signal with a given completionKey is handled. Signals the
+// coordinator creates itself don't pass through here.
+func (c *Coordinator) claimCompletion(ctx context.Context, signal *CompletionSignal) bool {
+	claimed, err := c.sdk.ClaimCompletion(ctx, signal.RunID, completionKey(signal))
+	if err != nil {
+		// Handle it anyway: counter operations are still idempotent per node
+		c.logger.Warn("failed to claim completion signal",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"job_id", signal.JobID,
+			"error", err)
+		return true
+	}
+	if !claimed {
+		c.logger.Info("ignoring duplicate completion signal",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"job_id", signal.JobID,
+			"status", signal.Status)
+	}
+	return claimed
+}
+
+// completionKey returns the idempotency key of a completion signal
+// Signals are keyed by job ID, or run, node and attempt for senders that don't
+// set one. Timeout signals come from the timeout detector rather than the
+// worker, so they are keyed apart from the job's own completion.
+func completionKey(signal *CompletionSignal) string {
+	key := signal.JobID
+	if key == "" {
+		key = fmt.Sprintf("%s:%s:%d", signal.RunID, signal.NodeID, metadataInt(signal.Metadata, "attempt"))
+	}
+	if signal.Status == sdk.SignalStatusTimeout {
+		key += ":timeout"
+	}
+	return key
+}
+
 // storeResultInCAS stores the result data in CAS and returns the result reference
 // Handles both new ResultData field and legacy ResultRef field for backward compatibility
+// Results go through the CAS client, so they land on the configured backend.
 func (c *Coordinator) storeResultInCAS(ctx context.Context, signal *CompletionSignal) string {
 	var resultRef string
 
 	if signal.ResultData != nil {
-		// Generate CAS key
-		resultID := fmt.Sprintf("artifact://%s-%s-%d", signal.RunID, signal.NodeID, time.Now().UnixNano())
-		casKey := fmt.Sprintf("cas:%s", resultID)
-
-		// Store result data in CAS
-		resultJSON, err := json.Marshal(signal.ResultData)
+		ref, err := c.sdk.StoreOutput(ctx, signal.ResultData)
 		if err != nil {
-			c.logger.Error("failed to marshal result data",
+			c.logger.Error("failed to store result in CAS",
 				"run_id", signal.RunID,
 				"node_id", signal.NodeID,
 				"error", err)
 		} else {
-			if err := c.redisWrapper.Set(ctx, casKey, string(resultJSON), 0); err != nil {
-				c.logger.Error("failed to store result in CAS",
-					"run_id", signal.RunID,
-					"node_id", signal.NodeID,
-					"cas_key", casKey,
-					"error", err)
-			} else {
-				resultRef = resultID
-				c.logger.Info("stored result in CAS",
-					"run_id", signal.RunID,
-					"node_id", signal.NodeID,
-					"result_ref", resultRef,
-					"cas_key", casKey)
-			}
+			resultRef = ref
+			c.logger.Info("stored result in CAS",
+				"run_id", signal.RunID,
+				"node_id", signal.NodeID,
+				"result_ref", resultRef)
 		}
 	} else if signal.ResultRef != "" {
 		// Backward compatibility: use provided ResultRef
@@ -258,3 +368,62 @@ func (c *Coordinator) storeResultInCAS(ctx context.Context, signal *CompletionSi
 
 	return resultRef
 }
+
+// dropRedactedFields removes fields that must never be persisted from the result data
+// Called as soon as a signal is read, before it is buffered for a paused run or
+// stored, so a dropped field is never written anywhere. The rules come from the
+// node's config in the run's IR; a signal whose IR can't be loaded is left as is.
+func (c *Coordinator) dropRedactedFields(ctx context.Context, signal *CompletionSignal) {
+	if signal.ResultData == nil {
+		return
+	}
+	ir, err := c.loadIR(ctx, signal.RunID)
+	if err != nil {
+		c.logger.Warn("failed to load IR for redaction",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+		return
+	}
+	node, ok := ir.Nodes[signal.NodeID]
+	if !ok {
+		return
+	}
+
+	rules := sdk.RedactionRules(node.Config, sdk.RedactActionDrop)
+	if len(rules) == 0 {
+		return
+	}
+
+	if dropped := sdk.Redact(signal.ResultData, rules); dropped > 0 {
+		c.logger.Debug("dropped redacted fields from result",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"fields", dropped)
+	}
+}
+
+// applyVarWrites applies the run variable writes a completed node signalled
+// Each write is atomic on its own, and duplicate signals never get here, so
+// increments from parallel nodes add up. A write that fails is logged: the
+// node has completed, and routing goes on without it.
+func (c *Coordinator) applyVarWrites(ctx context.Context, signal *CompletionSignal) {
+	for name, value := range signal.Vars {
+		if err := c.sdk.SetVar(ctx, signal.RunID, name, value); err != nil {
+			c.logger.Error("failed to set run var",
+				"run_id", signal.RunID,
+				"node_id", signal.NodeID,
+				"name", name,
+				"error", err)
+		}
+	}
+	for name, delta := range signal.VarIncrements {
+		if _, err := c.sdk.IncrVar(ctx, signal.RunID, name, delta); err != nil {
+			c.logger.Error("failed to increment run var",
+				"run_id", signal.RunID,
+				"node_id", signal.NodeID,
+				"name", name,
+				"error", err)
+		}
+	}
+}
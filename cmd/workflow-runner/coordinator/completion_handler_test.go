@@ -0,0 +1,314 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startBranchRun starts a coordinator on a run of check → high (score > 80),
+// plus extra edges from check
+func startBranchRun(t *testing.T, runID string, edges ...compiler.WorkflowEdge) (*redis.Client, *miniredis.Miniredis, *sdk.SDK) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go coord.Start(ctx)
+
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "check", Type: "http", Config: map[string]interface{}{"url": "https://example.com/score"}},
+			{ID: "high", Type: "http", Config: map[string]interface{}{"url": "https://example.com/high"}},
+			{ID: "fallback", Type: "http", Config: map[string]interface{}{"url": "https://example.com/fallback"}},
+		},
+		Edges: append([]compiler.WorkflowEdge{
+			{From: "check", To: "high", Condition: "output.score > 80"},
+		}, edges...),
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	// check completes with a score no rule matches
+	require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+		Token:      &sdk.Token{ID: runID + "-check", RunID: runID, ToNode: "check"},
+		Status:     "completed",
+		ResultData: map[string]interface{}{"score": float64(10)},
+	}))
+
+	return rdb, mr, workflowSDK
+}
+
+// dispatchedNodes returns the nodes tokens were emitted to
+func dispatchedNodes(t *testing.T, rdb *redis.Client) []string {
+	var nodes []string
+	for _, msg := range rdb.XRange(context.Background(), "wf.tasks.http", "-", "+").Val() {
+		var token sdk.Token
+		require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+		nodes = append(nodes, token.ToNode)
+	}
+	return nodes
+}
+
+func TestBranchNoMatchRoutesDefault(t *testing.T) {
+	runID := "run_branch_default_test"
+	rdb, mr, workflowSDK := startBranchRun(t, runID, compiler.WorkflowEdge{From: "check", To: "fallback"})
+
+	require.Eventually(t, func() bool {
+		return len(dispatchedNodes(t, rdb)) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{"fallback"}, dispatchedNodes(t, rdb))
+
+	status, _ := mr.Get(sdk.NodeStatusKey(runID, "check"))
+	assert.Equal(t, sdk.NodeStatusCompleted, status)
+
+	// check's token was consumed and fallback's emitted
+	counter, err := workflowSDK.GetCounter(context.Background(), runID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+	assert.False(t, mr.Exists("run:status:"+runID))
+}
+
+func TestBranchNoMatchWithoutDefaultFailsNode(t *testing.T) {
+	runID := "run_branch_no_match_test"
+	rdb, mr, workflowSDK := startBranchRun(t, runID)
+	ctx := context.Background()
+
+	require.Eventually(t, func() bool {
+		return rdb.Get(ctx, "run:status:"+runID).Val() == "FAILED"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	status, _ := mr.Get(sdk.NodeStatusKey(runID, "check"))
+	assert.Equal(t, sdk.NodeStatusFailed, status)
+	assert.Empty(t, dispatchedNodes(t, rdb))
+
+	// The failure explains itself
+	var failure struct {
+		ErrorType string                 `json:"error_type"`
+		Error     map[string]interface{} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(rdb.HGet(ctx, "context:"+runID, "check:failure:output").Val()), &failure))
+	assert.Equal(t, "NoBranchMatchedError", failure.ErrorType)
+	assert.Equal(t, "no branch matched: no branch rule matched (output.score > 80 was false (output.score = 10))", failure.Error["error_message"])
+
+	// The token is left counted like any failed node's: the run can't be
+	// completed through a branch that went nowhere
+	counter, err := workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+}
+
+func TestDuplicateCompletionHandledOnce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	runID := "run_duplicate_completion_test"
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/fetch"}},
+			{ID: "left", Type: "http", Config: map[string]interface{}{"url": "https://example.com/left"}},
+			{ID: "right", Type: "http", Config: map[string]interface{}{"url": "https://example.com/right"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "fetch", To: "left"},
+			{From: "fetch", To: "right"},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	// The worker signals fetch's completion twice, as a retry after a lost reply would
+	for i := 0; i < 2; i++ {
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+			Token:      &sdk.Token{ID: runID + "-fetch", RunID: runID, ToNode: "fetch"},
+			Status:     "completed",
+			ResultData: map[string]interface{}{"ok": true},
+		}))
+	}
+	go coord.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		return rdb.LLen(ctx, "completion_signals").Val() == 0 && len(dispatchedNodes(t, rdb)) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+
+	// fetch's token was consumed once and its dependents emitted once
+	assert.ElementsMatch(t, []string{"left", "right"}, dispatchedNodes(t, rdb))
+	counter, err := workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, counter)
+}
+
+// TestRoutingThroughAbsorberNeverZeroesCounter completes fetch → route (branch) → next
+// and checks the counter never reads zero while the run is still going
+func TestRoutingThroughAbsorberNeverZeroesCounter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	completions := rdb.Subscribe(ctx, sdk.CompletionEventsChannel)
+	t.Cleanup(func() { completions.Close() })
+	_, err = completions.Receive(ctx)
+	require.NoError(t, err)
+
+	go coord.Start(ctx)
+
+	runID := "run_absorber_counter_test"
+	ir := &sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"fetch": {ID: "fetch", Type: "http", Dependents: []string{"route"}},
+			"route": {
+				ID:           "route",
+				Type:         "conditional",
+				Dependencies: []string{"fetch"},
+				Dependents:   []string{"next"},
+				Branch: &sdk.BranchConfig{
+					Enabled: true,
+					Type:    "conditional",
+					Default: []string{"next"},
+				},
+			},
+			"next": {ID: "next", Type: "http", Dependencies: []string{"route"}, IsTerminal: true},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+		Token:      &sdk.Token{ID: runID + "-fetch", RunID: runID, ToNode: "fetch"},
+		Status:     "completed",
+		ResultData: map[string]interface{}{"ok": true},
+	}))
+
+	require.Eventually(t, func() bool {
+		return rdb.SIsMember(ctx, sdk.AppliedKey(runID), "consume:"+runID+":fetch").Val()
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{"next"}, dispatchedNodes(t, rdb))
+
+	// next's slot was counted before fetch's was released
+	counter, err := workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+	select {
+	case msg := <-completions.Channel():
+		t.Fatalf("counter reached zero mid-run: %s", msg.Payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestParallelVarIncrementsReadDownstream(t *testing.T) {
+	run := startDataTestRun(t, "run_vars_test", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "east", Type: "http", Config: map[string]interface{}{"url": "https://example.com/east"}},
+			{ID: "west", Type: "http", Config: map[string]interface{}{"url": "https://example.com/west"}},
+			{ID: "join", Type: "aggregate", Config: map[string]interface{}{"strategy": "collect"}},
+			{ID: "all", Type: "http", Config: map[string]interface{}{"url": "https://example.com/all"}},
+			{ID: "some", Type: "http", Config: map[string]interface{}{"url": "https://example.com/some"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "east", To: "join"},
+			{From: "west", To: "join"},
+			{From: "join", To: "all", Condition: "vars.processed == 2 && vars.region == 'eu'"},
+			{From: "join", To: "some", Condition: "vars.processed < 2"},
+		},
+	}, 2)
+
+	// Both workers finish at once, each counting itself
+	var wg sync.WaitGroup
+	for _, nodeID := range []string{"east", "west"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vars := worker.NewVarWrites()
+			vars.Incr("processed", 1)
+			vars.Set("region", "eu")
+			assert.NoError(t, worker.SignalCompletion(run.ctx, run.rdb, noopLogger{}, &worker.CompletionOpts{
+				Token:      &sdk.Token{ID: run.runID + "-" + nodeID, RunID: run.runID, ToNode: nodeID},
+				Status:     "completed",
+				ResultData: map[string]interface{}{"node": nodeID},
+				Vars:       vars,
+			}))
+		}()
+	}
+	wg.Wait()
+
+	run.waitForPayload("all")
+	assert.Empty(t, run.payloadsFor("some"))
+
+	processed, err := run.sdk.GetVar(run.ctx, run.runID, "processed")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, processed)
+}
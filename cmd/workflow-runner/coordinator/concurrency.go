@@ -0,0 +1,58 @@
+package coordinator
+
+import (
+	"context"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// releaseSlot hands the concurrency slot of a finished job to the run's next ready token
+// Runs without a concurrency limit hold no slots. Ready tokens of nodes
+// cancelled while they waited are dropped, passing the slot on again.
+func (c *Coordinator) releaseSlot(ctx context.Context, signal *CompletionSignal, ir *sdk.IR) {
+	if sdk.MaxConcurrency(ir) <= 0 || signal.JobID == "" {
+		return
+	}
+
+	jobID := signal.JobID
+	for {
+		ready, err := sdk.ReleaseSlot(ctx, c.redis, signal.RunID, jobID)
+		if err != nil {
+			c.logger.Error("failed to release concurrency slot",
+				"run_id", signal.RunID,
+				"job_id", jobID,
+				"error", err)
+			return
+		}
+		if ready == nil {
+			return
+		}
+		jobID = ready.JobID
+
+		if ready.Inflight != nil {
+			status, err := sdk.GetNodeStatus(ctx, c.redis, signal.RunID, ready.Inflight.NodeID)
+			if err == nil && status == sdk.NodeStatusCancelled {
+				c.logger.Info("dropping ready token of cancelled node",
+					"run_id", signal.RunID,
+					"node_id", ready.Inflight.NodeID,
+					"job_id", ready.JobID)
+				continue
+			}
+		}
+
+		if err := c.dispatchReady(ctx, ready, ir); err != nil {
+			c.logger.Error("failed to dispatch ready token",
+				"run_id", signal.RunID,
+				"job_id", ready.JobID,
+				"stream", ready.Stream,
+				"error", err)
+			continue
+		}
+
+		c.logger.Debug("dispatched ready token",
+			"run_id", signal.RunID,
+			"job_id", ready.JobID,
+			"stream", ready.Stream)
+		return
+	}
+}
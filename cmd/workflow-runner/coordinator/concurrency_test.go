@@ -0,0 +1,114 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dispatchedTokens returns the tokens emitted to the HTTP stream, oldest first
+func dispatchedTokens(t *testing.T, rdb *redis.Client) []sdk.Token {
+	var tokens []sdk.Token
+	for _, msg := range rdb.XRange(context.Background(), "wf.tasks.http", "-", "+").Val() {
+		var token sdk.Token
+		require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func TestMaxConcurrencyCapsInflightTokens(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go coord.Start(ctx)
+
+	// start fans out to 10 nodes, at most 3 of which may run at once
+	runID := "run_max_concurrency_test"
+	schema := &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "start", Type: "http", Config: map[string]interface{}{"url": "https://example.com/start"}},
+		},
+		Metadata: map[string]interface{}{"username": "alice", sdk.MaxConcurrencyKey: 3},
+	}
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("item%d", i)
+		schema.Nodes = append(schema.Nodes, compiler.WorkflowNode{
+			ID: id, Type: "http", Config: map[string]interface{}{"url": "https://example.com/" + id},
+		})
+		schema.Edges = append(schema.Edges, compiler.WorkflowEdge{From: "start", To: id})
+	}
+	ir, err := compiler.CompileWorkflowSchema(schema, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	complete := func(token sdk.Token) {
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+			Token:      &token,
+			Status:     "completed",
+			ResultData: map[string]interface{}{"node": token.ToNode},
+		}))
+	}
+	complete(sdk.Token{ID: runID + "-start", RunID: runID, ToNode: "start"})
+
+	// Workers finish the oldest token in flight, one at a time
+	completed := 0
+	for completed < 10 {
+		want := completed + 3
+		if want > 10 {
+			want = 10
+		}
+		require.Eventually(t, func() bool {
+			return len(dispatchedTokens(t, rdb)) == want
+		}, 5*time.Second, 10*time.Millisecond, "after %d completions", completed)
+		if completed == 0 {
+			// The other 7 stay held until a slot is released
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		tokens := dispatchedTokens(t, rdb)
+		assert.LessOrEqual(t, len(tokens)-completed, 3, "tokens in flight")
+		complete(tokens[completed])
+		completed++
+	}
+
+	require.Eventually(t, func() bool {
+		counter, err := workflowSDK.GetCounter(ctx, runID)
+		return err == nil && counter == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, dispatchedTokens(t, rdb), 10)
+	assert.Zero(t, rdb.LLen(ctx, sdk.ReadyQueueKey(runID)).Val())
+	assert.Equal(t, "0", rdb.Get(ctx, sdk.ConcurrencySlotsKey(runID)).Val())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/condition"
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/operators"
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/resolver"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/routing"
 	"github.com/lyzr/orchestrator/common/sdk"
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/workflow_lifecycle"
 	"github.com/lyzr/orchestrator/common/clients"
@@ -18,23 +19,28 @@ import (
 
 // CompletionSignal represents a worker's completion notification
 type CompletionSignal struct {
-	Version    string                 `json:"version"`              // Protocol version (1.0)
-	JobID      string                 `json:"job_id"`               // Unique job ID
-	RunID      string                 `json:"run_id"`               // Workflow run ID
-	NodeID     string                 `json:"node_id"`              // Node that completed
-	Status     string                 `json:"status"`               // completed|failed
-	ResultData map[string]interface{} `json:"result_data,omitempty"` // Actual result data (coordinator stores in CAS)
-	ResultRef  string                 `json:"result_ref,omitempty"` // CAS reference (deprecated, for backward compat)
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Version     string                 `json:"version"`               // Protocol version (1.0)
+	JobID       string                 `json:"job_id"`                // Unique job ID
+	RunID       string                 `json:"run_id"`                // Workflow run ID
+	NodeID      string                 `json:"node_id"`               // Node that completed
+	Status      string                 `json:"status"`                // completed|failed
+	ResultData  map[string]interface{} `json:"result_data,omitempty"` // Actual result data (coordinator stores in CAS)
+	ResultRef   string                 `json:"result_ref,omitempty"`  // CAS reference (deprecated, for backward compat)
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	TraceParent string                 `json:"traceparent,omitempty"` // Worker's span (W3C traceparent)
+
+	// Run variable writes of a completed node (see worker.VarWrites)
+	Vars          map[string]interface{} `json:"vars,omitempty"`           // Set with sdk.SetVar
+	VarIncrements map[string]int64       `json:"var_increments,omitempty"` // Added with sdk.IncrVar
 }
 
 // Coordinator handles choreography for workflow execution
 type Coordinator struct {
-	redis               *redis.Client // Raw client for BLPOP and other blocking ops
+	redis               redis.UniversalClient // Raw client for BLPOP and other blocking ops
 	redisWrapper        *redisWrapper.Client // Wrapped client for common ops
 	sdk                 *sdk.SDK
 	logger              Logger
-	router              *StreamRouter
+	routes              *routing.Registry
 	evaluator           *condition.Evaluator
 	resolver            *resolver.Resolver
 	orchestratorClient  *clients.OrchestratorClient
@@ -69,12 +75,16 @@ type Logger interface {
 
 // CoordinatorOpts contains options for creating a coordinator
 type CoordinatorOpts struct {
-	Redis               *redis.Client
+	Redis               redis.UniversalClient
+	RedisOpTimeout      time.Duration // Per-operation Redis timeout (0 keeps the default)
 	SDK                 *sdk.SDK
 	Logger              Logger
 	OrchestratorBaseURL string
 	CASClient           clients.CASClient
 	RateLimiter         *ratelimit.RateLimiter
+	StrictTemplates     bool          // Templates referencing missing values fail the node
+	RunStateRetention   time.Duration // How long a finished run's Redis state is kept (0: sdk.RunStateTTL)
+	Routes              *routing.Registry // Node type → worker stream (nil: built-in mappings)
 }
 
 // NewCoordinator creates a new coordinator instance
@@ -83,37 +93,48 @@ func NewCoordinator(opts *CoordinatorOpts) *Coordinator {
 	evaluator := condition.NewEvaluator()
 
 	// Wrap Redis client for better abstractions and instrumentation
-	redisClient := redisWrapper.NewClient(opts.Redis, opts.Logger)
+	var redisOpts []redisWrapper.ClientOption
+	if opts.RedisOpTimeout > 0 {
+		redisOpts = append(redisOpts, redisWrapper.WithOperationTimeout(opts.RedisOpTimeout))
+	}
+	redisClient := redisWrapper.NewClient(opts.Redis, opts.Logger, redisOpts...)
 
 	// Create workflow lifecycle modules with wrapped Redis client
 	eventPublisher := workflow_lifecycle.NewEventPublisher(redisClient, opts.Logger)
-	statusManager := workflow_lifecycle.NewStatusManager(redisClient, opts.Logger)
+	statusManager := workflow_lifecycle.NewStatusManager(redisClient, opts.Logger).WithRetention(opts.RunStateRetention)
 	completionChecker := workflow_lifecycle.NewCompletionChecker(redisClient, opts.SDK, opts.Logger, eventPublisher, statusManager)
 
-	// Create control flow router (still uses raw Redis for complex operations like XREADGROUP)
-	controlFlowRouter := operators.NewControlFlowRouter(opts.Redis, opts.SDK, evaluator, opts.Logger)
+	routes := opts.Routes
+	if routes == nil {
+		routes = routing.NewRegistry()
+	}
 
-	return &Coordinator{
+	c := &Coordinator{
 		redis:               opts.Redis, // Keep raw for BLPOP
 		redisWrapper:        redisClient, // Use wrapper for common ops
 		sdk:                 opts.SDK,
 		logger:              opts.Logger,
-		router:              NewStreamRouter(),
+		routes:              routes,
 		evaluator:           evaluator,
-		resolver:            resolver.NewResolver(opts.SDK, opts.Logger),
+		resolver:            resolver.NewResolver(opts.SDK, opts.Logger).WithStrict(opts.StrictTemplates),
 		orchestratorClient:  orchestratorClient,
 		orchestratorBaseURL: opts.OrchestratorBaseURL,
 		casClient:           opts.CASClient,
 		rateLimiter:         opts.RateLimiter,
-		operators: &OperatorOpts{
-			ControlFlowRouter: controlFlowRouter,
-		},
 		lifecycle: &LifecycleHandlerOpts{
 			CompletionChecker: completionChecker,
 			EventPublisher:    eventPublisher,
 			StatusManager:     statusManager,
 		},
 	}
+
+	// Create control flow router (still uses raw Redis for complex operations like XREADGROUP)
+	// Skip decisions are reported back to the coordinator for the run event log
+	c.operators = &OperatorOpts{
+		ControlFlowRouter: operators.NewControlFlowRouter(opts.Redis, opts.SDK, evaluator, operators.SkipRecorderFunc(c.recordSkip), opts.Logger),
+	}
+
+	return c
 }
 
 // Start begins the coordinator main loop
@@ -149,6 +170,15 @@ func (c *Coordinator) Start(ctx context.Context) error {
 				continue
 			}
 
+			// Fields that must never be stored are dropped first, so they don't
+			// even reach the buffer of a paused run
+			c.dropRedactedFields(ctx, &signal)
+
+			// Duplicated signals (e.g. a worker retrying its completion) are dropped
+			if !c.claimCompletion(ctx, &signal) {
+				continue
+			}
+
 			// Handle completion in goroutine for parallel processing
 			go c.handleCompletion(ctx, &signal)
 		}
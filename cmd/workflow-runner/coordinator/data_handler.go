@@ -0,0 +1,330 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/condition"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/operators"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/routing"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+)
+
+// DataWorker executes transform, filter and aggregate nodes
+// The coordinator publishes their tokens to routing.DataStream like any worker
+// node's, so they are tracked for the timeout detector and held by concurrency
+// limits too. A token is acknowledged only once its completion is signalled,
+// and tokens left pending by a crashed worker are reclaimed; a redelivered
+// token signals the same job ID again, which the coordinator ignores.
+type DataWorker struct {
+	coord         *Coordinator
+	consumerGroup string
+	consumerName  string
+	workers       int
+	block         time.Duration
+}
+
+// NewDataWorker creates a data worker executing nodes for coord's runs
+func NewDataWorker(coord *Coordinator) *DataWorker {
+	return &DataWorker{
+		coord:         coord,
+		consumerGroup: "data_workers",
+		consumerName:  fmt.Sprintf("data_%s", uuid.New().String()[:8]),
+		workers:       redisWrapper.DefaultConsumerWorkers,
+		block:         redisWrapper.DefaultConsumerBlock,
+	}
+}
+
+// WithWorkers sets how many nodes run in parallel
+func (w *DataWorker) WithWorkers(workers int) *DataWorker {
+	if workers > 0 {
+		w.workers = workers
+	}
+	return w
+}
+
+// WithBlock sets how long a read waits for new tokens
+func (w *DataWorker) WithBlock(block time.Duration) *DataWorker {
+	w.block = block
+	return w
+}
+
+// Start consumes the data stream until ctx is cancelled
+func (w *DataWorker) Start(ctx context.Context) error {
+	client := w.coord.redisWrapper
+	for _, stream := range redisWrapper.PriorityStreams(routing.DataStream) {
+		if err := client.CreateStreamGroup(ctx, stream, w.consumerGroup); err != nil {
+			return fmt.Errorf("failed to create consumer group for %s: %w", stream, err)
+		}
+		// Reclaim tokens left unacknowledged by crashed workers
+		go redisWrapper.NewReclaimer(client, stream, w.consumerGroup, w.consumerName, w.handleMessage).Run(ctx)
+	}
+
+	w.coord.logger.Info("starting data worker",
+		"stream", routing.DataStream,
+		"consumer_group", w.consumerGroup,
+		"consumer_name", w.consumerName,
+		"workers", w.workers)
+
+	redisWrapper.NewConsumerPool(client, routing.DataStream, w.consumerGroup, w.consumerName, w.handleMessage,
+		redisWrapper.WithConsumerWorkers(w.workers),
+		redisWrapper.WithConsumerBlock(w.block),
+		redisWrapper.WithPriorities(redisWrapper.DefaultPriorityFairness)).Run(ctx)
+
+	w.coord.logger.Info("data worker stopping")
+	return nil
+}
+
+// handleMessage executes the data operator node a token is for
+// Its result goes through the completion queue, which stores it in CAS and
+// routes it. A filter whose condition is false completes without routing anywhere.
+func (w *DataWorker) handleMessage(ctx context.Context, message redis.XMessage) error {
+	c := w.coord
+	tokenJSON, ok := message.Values["token"].(string)
+	if !ok {
+		return fmt.Errorf("message missing token field")
+	}
+	var token sdk.Token
+	if err := json.Unmarshal([]byte(tokenJSON), &token); err != nil {
+		return fmt.Errorf("failed to unmarshal token: %w", err)
+	}
+	var sent struct {
+		SentAt string `json:"sent_at"`
+	}
+	_ = json.Unmarshal([]byte(tokenJSON), &sent)
+
+	ir, err := c.loadIR(ctx, token.RunID)
+	if err != nil {
+		return w.signalFailure(ctx, &token, fmt.Errorf("failed to load IR: %w", err))
+	}
+	node, ok := ir.Nodes[token.ToNode]
+	if !ok {
+		return w.signalFailure(ctx, &token, fmt.Errorf("node %s not found in IR", token.ToNode))
+	}
+
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "node.execute",
+		tracing.AttrRunID, token.RunID,
+		tracing.AttrNodeID, token.ToNode,
+		tracing.AttrNodeType, node.Type,
+		tracing.AttrStream, routing.DataStream)
+	defer span.End()
+
+	startTime := time.Now()
+	if err := sdk.RecordNodeStarted(ctx, c.redis, token.RunID, token.ToNode, startTime); err != nil {
+		c.logger.Warn("failed to record node start", "run_id", token.RunID, "node_id", token.ToNode, "error", err)
+	}
+
+	input, err := c.loadDataInput(ctx, token.RunID, node, token.PayloadRef, token.Config)
+	if err != nil {
+		return w.signalFailure(ctx, &token, err)
+	}
+
+	resultData := map[string]interface{}{}
+	metadata := map[string]interface{}{"operator": node.Type}
+	dataOperator := operators.NewDataOperator(c.evaluator)
+	switch node.Type {
+	case operators.DataOperatorTransform:
+		resultData, err = dataOperator.Transform(input)
+	case operators.DataOperatorAggregate:
+		resultData, err = dataOperator.Aggregate(input)
+	case operators.DataOperatorFilter:
+		var passed bool
+		var reason string
+		passed, reason, err = dataOperator.Filter(input)
+		if err == nil {
+			// Passing tokens carry the input on unchanged
+			if output, ok := input.Output.(map[string]interface{}); ok {
+				for k, v := range output {
+					resultData[k] = v
+				}
+			} else {
+				resultData["output"] = input.Output
+			}
+			metadata[operators.MetadataFilterRejected] = !passed
+			metadata[operators.MetadataFilterReason] = reason
+		}
+	default:
+		err = fmt.Errorf("node type %q is not a data operator", node.Type)
+	}
+	if err != nil {
+		return w.signalFailure(ctx, &token, err)
+	}
+
+	endTime := time.Now()
+	var queueTimeMs int64
+	if sentTime, err := time.Parse(time.RFC3339Nano, sent.SentAt); err == nil {
+		queueTimeMs = startTime.Sub(sentTime).Milliseconds()
+	}
+	executionTimeMs := endTime.Sub(startTime).Milliseconds()
+	resultData["metrics"] = map[string]interface{}{
+		"sent_at":           sent.SentAt,
+		"start_time":        startTime.Format(time.RFC3339Nano),
+		"end_time":          endTime.Format(time.RFC3339Nano),
+		"queue_time_ms":     queueTimeMs,
+		"execution_time_ms": executionTimeMs,
+		"total_duration_ms": queueTimeMs + executionTimeMs,
+	}
+
+	c.logger.Info("data operator node executed",
+		"run_id", token.RunID,
+		"node_id", token.ToNode,
+		"node_type", node.Type,
+		"from_node", token.FromNode)
+
+	return worker.SignalCompletion(ctx, c.redis, c.logger, &worker.CompletionOpts{
+		Token:      &token,
+		Status:     "completed",
+		ResultData: resultData,
+		Metadata:   metadata,
+	})
+}
+
+// signalFailure fails a data operator node (and with it the run)
+func (w *DataWorker) signalFailure(ctx context.Context, token *sdk.Token, err error) error {
+	w.coord.logger.Error("data operator node failed",
+		"run_id", token.RunID,
+		"node_id", token.ToNode,
+		"error", err)
+
+	return worker.SignalCompletion(ctx, w.coord.redis, w.coord.logger, &worker.CompletionOpts{
+		Token:  token,
+		Status: "failed",
+		ResultData: map[string]interface{}{
+			"status": "failed",
+			"error":  err.Error(),
+		},
+		Metadata: map[string]interface{}{
+			"error_type":    "DataOperatorError",
+			"error_message": err.Error(),
+		},
+	})
+}
+
+// loadDataInput loads what a data operator node works on
+// Aggregates get the output of every dependency; the others the token's payload.
+func (c *Coordinator) loadDataInput(ctx context.Context, runID string, node *sdk.Node, payloadRef string, config map[string]interface{}) (*operators.DataInput, error) {
+	input := &operators.DataInput{Config: config}
+	if input.Config == nil {
+		input.Config = map[string]interface{}{}
+	}
+
+	if node.Type == operators.DataOperatorAggregate {
+		for _, dep := range node.Dependencies {
+			output, err := c.sdk.LoadNodeOutput(ctx, runID, dep)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load output of %s: %w", dep, err)
+			}
+			input.Upstream = append(input.Upstream, operators.UpstreamOutput{NodeID: dep, Output: output})
+		}
+	} else if payloadRef != "" {
+		output, err := c.sdk.LoadPayload(ctx, payloadRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load input: %w", err)
+		}
+		input.Output = output
+	}
+
+	context, err := c.sdk.LoadContext(ctx, runID)
+	if err != nil {
+		c.logger.Warn("failed to load context for data operator", "run_id", runID, "error", err)
+		context = make(map[string]interface{})
+	}
+	vars, err := c.sdk.GetVars(ctx, runID)
+	if err != nil {
+		c.logger.Warn("failed to load vars for data operator", "run_id", runID, "error", err)
+		vars = make(map[string]interface{})
+	}
+	input.Context = context
+	input.Vars = vars
+
+	nodes, err := condition.ExpressionNodes(operators.DataExpressions(node.Type, input.Config), func(nodeID string) (interface{}, error) {
+		return c.sdk.LoadNodeOutput(ctx, runID, nodeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	input.Nodes = nodes
+
+	return input, nil
+}
+
+// joinReady reports whether fromNode's arrival should dispatch nodeID
+// Aggregates with several upstream nodes are dispatched once, when the last of
+// them arrives (see joinArrival); every other node on each arrival.
+func (c *Coordinator) joinReady(ctx context.Context, runID, fromNode, nodeID string, node *sdk.Node) bool {
+	if node.Type != operators.DataOperatorAggregate || len(node.Dependencies) <= 1 {
+		return true
+	}
+	ready, err := c.joinArrival(ctx, runID, nodeID, fromNode, len(node.Dependencies))
+	if err != nil {
+		go c.failDataNode(ctx, runID, nodeID, fmt.Errorf("failed to record join arrival: %w", err))
+		return false
+	}
+	return ready
+}
+
+// joinArrival records that fromNode reached a join node
+// Returns true for the arrival that completes the join, which then dispatches
+// the node. Earlier arrivals give back the counter slot emitted for them.
+// Upstream nodes that never run (e.g. on a branch not taken) leave the join waiting.
+func (c *Coordinator) joinArrival(ctx context.Context, runID, nodeID, fromNode string, expected int) (bool, error) {
+	key := fmt.Sprintf("join:%s:%s", runID, nodeID)
+
+	// Added and counted atomically, so exactly one arrival sees the join complete
+	var scard *redis.IntCmd
+	_, err := c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, key, fromNode)
+		scard = pipe.SCard(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	arrived := scard.Val()
+
+	if arrived < int64(expected) {
+		c.logger.Info("join waiting for upstream nodes",
+			"run_id", runID,
+			"node_id", nodeID,
+			"from_node", fromNode,
+			"arrived", arrived,
+			"expected", expected)
+		return false, c.sdk.ConsumeJob(ctx, runID, nodeID, "join:"+fromNode)
+	}
+
+	// Reset so a loop can join again
+	c.redisWrapper.Delete(ctx, key)
+	return true, nil
+}
+
+// failDataNode fails a data operator node the coordinator couldn't dispatch
+func (c *Coordinator) failDataNode(ctx context.Context, runID, nodeID string, err error) {
+	c.logger.Error("data operator node failed",
+		"run_id", runID,
+		"node_id", nodeID,
+		"error", err)
+
+	c.handleCompletion(ctx, &CompletionSignal{
+		Version: "1.0",
+		JobID:   fmt.Sprintf("%s-%s-data", runID, nodeID),
+		RunID:   runID,
+		NodeID:  nodeID,
+		Status:  "failed",
+		ResultData: map[string]interface{}{
+			"status": "failed",
+			"error":  err.Error(),
+		},
+		Metadata: map[string]interface{}{
+			"error_type":    "DataOperatorError",
+			"error_message": err.Error(),
+		},
+	})
+}
@@ -0,0 +1,323 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/operators"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/routing"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/workflow_lifecycle"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dataTestRun is a run of a compiled workflow on a running coordinator
+type dataTestRun struct {
+	t     *testing.T
+	ctx   context.Context
+	mr    *miniredis.Miniredis
+	rdb   *redis.Client
+	sdk   *sdk.SDK
+	coord *Coordinator
+	runID string
+}
+
+// startDataTestRun starts a run (see newDataTestRun) and a data worker
+func startDataTestRun(t *testing.T, runID string, schema *compiler.WorkflowSchema, entries int, opts ...func(*CoordinatorOpts)) *dataTestRun {
+	run := newDataTestRun(t, runID, schema, entries, opts...)
+	run.startDataWorker()
+	return run
+}
+
+// newDataTestRun compiles schema and starts a run with entries in flight
+// opts customize the coordinator. No data worker runs yet.
+func newDataTestRun(t *testing.T, runID string, schema *compiler.WorkflowSchema, entries int, opts ...func(*CoordinatorOpts)) *dataTestRun {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coordOpts := &CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	}
+	for _, opt := range opts {
+		opt(coordOpts)
+	}
+	coord := NewCoordinator(coordOpts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go coord.Start(ctx)
+
+	schema.Metadata = map[string]interface{}{"username": "alice"}
+	ir, err := compiler.CompileWorkflowSchema(schema, casClient)
+	require.NoError(t, err)
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, entries))
+
+	return &dataTestRun{t: t, ctx: ctx, mr: mr, rdb: rdb, sdk: workflowSDK, coord: coord, runID: runID}
+}
+
+// startDataWorker starts a data worker until the test ends
+func (r *dataTestRun) startDataWorker() {
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.t.Cleanup(cancel)
+	go NewDataWorker(r.coord).WithBlock(50 * time.Millisecond).Start(ctx)
+}
+
+// complete signals that an http entry node finished with result
+func (r *dataTestRun) complete(nodeID string, result map[string]interface{}) {
+	require.NoError(r.t, worker.SignalCompletion(r.ctx, r.rdb, noopLogger{}, &worker.CompletionOpts{
+		Token:      &sdk.Token{ID: r.runID + "-" + nodeID, RunID: r.runID, ToNode: nodeID},
+		Status:     "completed",
+		ResultData: result,
+	}))
+}
+
+// payloadsFor returns the payloads of the http tokens sent to nodeID
+func (r *dataTestRun) payloadsFor(nodeID string) []map[string]interface{} {
+	var payloads []map[string]interface{}
+	for _, msg := range r.rdb.XRange(r.ctx, "wf.tasks.http", "-", "+").Val() {
+		var token sdk.Token
+		require.NoError(r.t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+		if token.ToNode != nodeID {
+			continue
+		}
+		payload, err := r.sdk.LoadPayload(r.ctx, token.PayloadRef)
+		require.NoError(r.t, err)
+		payloads = append(payloads, payload.(map[string]interface{}))
+	}
+	return payloads
+}
+
+// waitForPayload waits for the one http token sent to nodeID
+func (r *dataTestRun) waitForPayload(nodeID string) map[string]interface{} {
+	require.Eventually(r.t, func() bool {
+		return len(r.payloadsFor(nodeID)) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	return r.payloadsFor(nodeID)[0]
+}
+
+// waitForStatus waits for a node to reach status
+func (r *dataTestRun) waitForStatus(nodeID, status string) {
+	require.Eventually(r.t, func() bool {
+		got, _ := r.mr.Get(sdk.NodeStatusKey(r.runID, nodeID))
+		return got == status
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func (r *dataTestRun) counter() int {
+	counter, err := r.sdk.GetCounter(r.ctx, r.runID)
+	require.NoError(r.t, err)
+	return counter
+}
+
+func TestTransformRenamesField(t *testing.T) {
+	run := startDataTestRun(t, "run_transform_test", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/user"}},
+			{ID: "rename", Type: "transform", Config: map[string]interface{}{
+				"mapping": map[string]interface{}{
+					"full_name": "$.name",
+					"id":        "output.id",
+				},
+			}},
+			{ID: "store", Type: "http", Config: map[string]interface{}{"url": "https://example.com/store"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "fetch", To: "rename"},
+			{From: "rename", To: "store"},
+		},
+	}, 1)
+
+	run.complete("fetch", map[string]interface{}{"name": "Ada", "id": 7})
+
+	payload := run.waitForPayload("store")
+	assert.Equal(t, "Ada", payload["full_name"])
+	assert.Equal(t, float64(7), payload["id"])
+	assert.NotContains(t, payload, "name")
+	assert.Contains(t, payload, "metrics")
+
+	run.waitForStatus("rename", sdk.NodeStatusCompleted)
+	assert.Equal(t, 1, run.counter(), "only store is in flight")
+
+	// The token was acknowledged once its completion was signalled
+	pending, err := run.rdb.XPending(run.ctx, routing.DataStream, "data_workers").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+// TestDataNodeWaitsForWorker checks a data node dispatched while no data worker
+// runs is kept on the stream and executed once one starts
+func TestDataNodeWaitsForWorker(t *testing.T) {
+	run := newDataTestRun(t, "run_data_durable", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/user"}},
+			{ID: "shape", Type: "transform", Config: map[string]interface{}{"mapping": map[string]interface{}{"b": "$.a"}}},
+			{ID: "store", Type: "http", Config: map[string]interface{}{"url": "https://example.com/store"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "fetch", To: "shape"},
+			{From: "shape", To: "store"},
+		},
+	}, 1)
+
+	run.complete("fetch", map[string]interface{}{"a": 1})
+	require.Eventually(t, func() bool {
+		return run.rdb.XLen(run.ctx, routing.DataStream).Val() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	run.waitForStatus("shape", sdk.NodeStatusRunning)
+
+	run.startDataWorker()
+	assert.Equal(t, float64(1), run.waitForPayload("store")["b"])
+	run.waitForStatus("shape", sdk.NodeStatusCompleted)
+}
+
+func TestFilter(t *testing.T) {
+	schema := func() *compiler.WorkflowSchema {
+		return &compiler.WorkflowSchema{
+			Nodes: []compiler.WorkflowNode{
+				{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/order"}},
+				{ID: "large", Type: "filter", Config: map[string]interface{}{"condition": "$.amount > 100"}},
+				{ID: "notify", Type: "http", Config: map[string]interface{}{"url": "https://example.com/notify"}},
+			},
+			Edges: []compiler.WorkflowEdge{
+				{From: "fetch", To: "large"},
+				{From: "large", To: "notify"},
+			},
+		}
+	}
+
+	t.Run("drops", func(t *testing.T) {
+		run := startDataTestRun(t, "run_filter_drop", schema(), 1)
+		run.complete("fetch", map[string]interface{}{"amount": 50})
+
+		run.waitForStatus("large", sdk.NodeStatusCompleted)
+		require.Eventually(t, func() bool { return run.counter() == 0 }, 5*time.Second, 20*time.Millisecond)
+		assert.Empty(t, run.payloadsFor("notify"))
+
+		// The dropped dependent is in the run's skip log
+		var entries []redis.XMessage
+		require.Eventually(t, func() bool {
+			entries = run.rdb.XRange(run.ctx, workflow_lifecycle.RunEventLogKey(run.runID), "-", "+").Val()
+			return len(entries) > 0
+		}, 5*time.Second, 20*time.Millisecond)
+		var logged map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(entries[0].Values["event"].(string)), &logged))
+		assert.Equal(t, "notify", logged["node_id"])
+		assert.Equal(t, "large", logged["from_node"])
+		assert.Equal(t, operators.SkipReasonFilterRejected, logged["reason"])
+		assert.Contains(t, logged["message"], "output.amount = 50")
+	})
+
+	t.Run("passes input on", func(t *testing.T) {
+		run := startDataTestRun(t, "run_filter_pass", schema(), 1)
+		run.complete("fetch", map[string]interface{}{"amount": 500, "customer": "acme"})
+
+		payload := run.waitForPayload("notify")
+		assert.Equal(t, float64(500), payload["amount"])
+		assert.Equal(t, "acme", payload["customer"])
+	})
+
+	t.Run("reads other nodes", func(t *testing.T) {
+		run := startDataTestRun(t, "run_filter_nodes", &compiler.WorkflowSchema{
+			Nodes: []compiler.WorkflowNode{
+				{ID: "limits", Type: "http", Config: map[string]interface{}{"url": "https://example.com/limits"}},
+				{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/order"}},
+				{ID: "large", Type: "filter", Config: map[string]interface{}{"condition": "$.amount > nodes.limits.output.threshold"}},
+				{ID: "notify", Type: "http", Config: map[string]interface{}{"url": "https://example.com/notify"}},
+			},
+			Edges: []compiler.WorkflowEdge{
+				{From: "limits", To: "fetch"},
+				{From: "fetch", To: "large"},
+				{From: "large", To: "notify"},
+			},
+		}, 1)
+		run.complete("limits", map[string]interface{}{"threshold": 400})
+		run.waitForPayload("fetch")
+		run.complete("fetch", map[string]interface{}{"amount": 500})
+
+		payload := run.waitForPayload("notify")
+		assert.Equal(t, float64(500), payload["amount"])
+	})
+}
+
+func TestMapSelectorReadsNodes(t *testing.T) {
+	run := newDataTestRun(t, "run_map_nodes", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "catalog", Type: "http", Config: map[string]interface{}{"url": "https://example.com/catalog"}},
+			{ID: "each", Type: "map", Config: map[string]interface{}{"over": "nodes.catalog.output.items", "body": "process"}},
+			{ID: "process", Type: "http", Config: map[string]interface{}{"url": "https://example.com/process"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "catalog", To: "each"},
+		},
+	}, 1)
+	ir, err := run.coord.loadIR(run.ctx, run.runID)
+	require.NoError(t, err)
+	payloadRef, err := run.sdk.StoreOutput(run.ctx, map[string]interface{}{"items": []interface{}{"ignored"}})
+	require.NoError(t, err)
+
+	// Not completed yet, so the selector can't read it
+	_, err = run.coord.selectMapItems(run.ctx, run.runID, payloadRef, ir.Nodes["each"])
+	assert.ErrorContains(t, err, "expression references node catalog, which has not completed")
+
+	outputRef, err := run.sdk.StoreOutput(run.ctx, map[string]interface{}{"items": []interface{}{"a", "b"}})
+	require.NoError(t, err)
+	require.NoError(t, run.sdk.StoreContext(run.ctx, run.runID, "catalog", outputRef))
+
+	items, err := run.coord.selectMapItems(run.ctx, run.runID, payloadRef, ir.Nodes["each"])
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, items)
+}
+
+func TestAggregateSumsInputs(t *testing.T) {
+	run := startDataTestRun(t, "run_aggregate_test", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "east", Type: "http", Config: map[string]interface{}{"url": "https://example.com/east"}},
+			{ID: "west", Type: "http", Config: map[string]interface{}{"url": "https://example.com/west"}},
+			{ID: "total", Type: "aggregate", Config: map[string]interface{}{"strategy": "sum", "value": "$.sales"}},
+			{ID: "report", Type: "http", Config: map[string]interface{}{"url": "https://example.com/report"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "east", To: "total"},
+			{From: "west", To: "total"},
+			{From: "total", To: "report"},
+		},
+	}, 2)
+
+	// The first arrival waits for the second and gives back its slot
+	run.complete("east", map[string]interface{}{"sales": 10})
+	require.Eventually(t, func() bool { return run.counter() == 1 }, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, run.payloadsFor("report"))
+
+	run.complete("west", map[string]interface{}{"sales": 32.5})
+
+	payload := run.waitForPayload("report")
+	assert.Equal(t, 42.5, payload["sum"])
+	assert.Equal(t, float64(2), payload["count"])
+
+	run.waitForStatus("total", sdk.NodeStatusCompleted)
+	assert.Equal(t, 1, run.counter(), "only report is in flight")
+	assert.False(t, run.mr.Exists("join:run_aggregate_test:total"))
+}
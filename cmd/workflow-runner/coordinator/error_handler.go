@@ -0,0 +1,67 @@
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// routeToErrorHandlers hands a failed node's failure to its on_error handlers
+// The handlers receive the failure as their payload ({failed_node, error and,
+// if the worker sent any, result_data}) and the run carries on through them
+// instead of failing. The handlers' tokens are emitted before the failed node's
+// token is consumed, so the counter cannot reach zero in between. Returns false
+// if the node has no error handlers or the failure could not be routed.
+func (c *Coordinator) routeToErrorHandlers(ctx context.Context, signal *CompletionSignal, ir *sdk.IR) bool {
+	node, exists := ir.Nodes[signal.NodeID]
+	if !exists || len(node.OnError) == 0 {
+		return false
+	}
+
+	payload := map[string]interface{}{
+		"failed_node": signal.NodeID,
+		"error":       signal.Metadata,
+	}
+	if signal.ResultData != nil {
+		payload["result_data"] = signal.ResultData
+	}
+
+	payloadRef, err := c.sdk.StoreOutput(ctx, payload)
+	if err != nil {
+		c.logger.Error("failed to store failure payload for error handlers",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+		return false
+	}
+
+	c.logger.Info("routing failure to error handlers",
+		"run_id", signal.RunID,
+		"node_id", signal.NodeID,
+		"error_handlers", node.OnError)
+
+	c.routeToNextNodes(ctx, signal, node.OnError, payloadRef, ir)
+
+	if err := c.sdk.Consume(ctx, signal.RunID, signal.NodeID); err != nil {
+		c.logger.Error("failed to consume token of failed node",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+	}
+
+	if ir.Metadata != nil {
+		if username, ok := ir.Metadata["username"].(string); ok {
+			c.lifecycle.EventPublisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
+				"type":           "node_failed",
+				"run_id":         signal.RunID,
+				"node_id":        signal.NodeID,
+				"error":          signal.Metadata,
+				"error_handlers": node.OnError,
+				"timestamp":      time.Now().Unix(),
+			})
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,120 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFailureRoutedToErrorHandler fails B in A → B with an on_error edge
+// B → handler, and checks handler receives B's failure and the run completes.
+func TestFailureRoutedToErrorHandler(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	runID := "run_error_handler_test"
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "A", Type: "http", Config: map[string]interface{}{"url": "https://example.com/a"}},
+			{ID: "B", Type: "http", Config: map[string]interface{}{"url": "https://example.com/b"}},
+			{ID: "error_handler", Type: "http", Config: map[string]interface{}{"url": "https://example.com/alert"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "A", To: "B"},
+			{From: "B", To: "error_handler", OnError: true},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"error_handler"}, ir.Nodes["B"].OnError)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	signal := func(token *sdk.Token, status string, result map[string]interface{}) {
+		opts := &worker.CompletionOpts{Token: token, Status: status, ResultData: result}
+		if status == "failed" {
+			opts.Metadata = map[string]interface{}{"error_type": "HTTPError", "error_message": "boom"}
+		}
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, opts))
+	}
+	tokensFor := func(node string, n int) []*sdk.Token {
+		var tokens []*sdk.Token
+		require.Eventually(t, func() bool {
+			tokens = nil
+			for _, msg := range rdb.XRange(ctx, "wf.tasks.http", "-", "+").Val() {
+				var token sdk.Token
+				require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+				if token.ToNode == node {
+					tokens = append(tokens, &token)
+				}
+			}
+			return len(tokens) == n
+		}, 5*time.Second, 20*time.Millisecond)
+		return tokens
+	}
+
+	// 1. A completes and B fails
+	signal(&sdk.Token{ID: runID + "-A", RunID: runID, ToNode: "A"}, "completed", map[string]interface{}{"a": float64(1)})
+	b := tokensFor("B", 1)[0]
+	signal(b, "failed", nil)
+
+	// 2. The handler gets B's failure as its payload instead of the run failing
+	handler := tokensFor("error_handler", 1)[0]
+	assert.Equal(t, "B", handler.FromNode)
+	payload, err := workflowSDK.LoadPayload(ctx, handler.PayloadRef)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"failed_node": "B",
+		"error":       map[string]interface{}{"error_type": "HTTPError", "error_message": "boom"},
+	}, payload)
+
+	status, _ := mr.Get(sdk.NodeStatusKey(runID, "B"))
+	assert.Equal(t, sdk.NodeStatusFailed, status)
+	assert.NotEqual(t, "FAILED", rdb.Get(ctx, "run:status:"+runID).Val())
+
+	counter, err := workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+
+	// 3. The handler completes the run
+	signal(handler, "completed", map[string]interface{}{"alerted": true})
+	require.Eventually(t, func() bool {
+		return rdb.Get(ctx, "run:status:"+runID).Val() == "COMPLETED"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	counter, err = workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, counter)
+}
@@ -19,7 +19,8 @@ func getMapKeys(m map[string]interface{}) []string {
 }
 
 // handleFailedNode processes a failed node execution
-// Stores failure data in CAS, publishes events, and updates run status
+// Stores failure data in CAS, then either routes the failure to the node's
+// error handlers or publishes failure events and fails the run
 func (c *Coordinator) handleFailedNode(ctx context.Context, signal *CompletionSignal, ir *sdk.IR) {
 	c.logger.Error("node execution failed",
 		"run_id", signal.RunID,
@@ -30,20 +31,14 @@ func (c *Coordinator) handleFailedNode(ctx context.Context, signal *CompletionSi
 	// Store result_data in CAS even on failure (for metrics)
 	var failureResultRef string
 	if signal.ResultData != nil {
-		resultID := fmt.Sprintf("artifact://%s-%s-%d", signal.RunID, signal.NodeID, time.Now().UnixNano())
-		casKey := fmt.Sprintf("cas:%s", resultID)
-
-		resultJSON, err := json.Marshal(signal.ResultData)
-		if err == nil {
-			if err := c.redisWrapper.Set(ctx, casKey, string(resultJSON), 0); err == nil {
-				failureResultRef = resultID
-				// Store at :output so RunService can find it
-				c.sdk.StoreContext(ctx, signal.RunID, signal.NodeID, failureResultRef)
-				c.logger.Info("stored failure result in CAS",
-					"run_id", signal.RunID,
-					"node_id", signal.NodeID,
-					"result_ref", failureResultRef)
-			}
+		if resultRef, err := c.sdk.StoreOutput(ctx, signal.ResultData); err == nil {
+			failureResultRef = resultRef
+			// Store at :output so RunService can find it
+			c.sdk.StoreContext(ctx, signal.RunID, signal.NodeID, failureResultRef)
+			c.logger.Info("stored failure result in CAS",
+				"run_id", signal.RunID,
+				"node_id", signal.NodeID,
+				"result_ref", failureResultRef)
 		}
 	}
 
@@ -75,6 +70,11 @@ func (c *Coordinator) handleFailedNode(ctx context.Context, signal *CompletionSi
 		}
 	}
 
+	// Error handlers take over the failure; the run carries on through them
+	if c.routeToErrorHandlers(ctx, signal, ir) {
+		return
+	}
+
 	// Publish node_failed event
 	c.logger.Info("attempting to publish node_failed event",
 		"run_id", signal.RunID,
@@ -133,3 +133,34 @@ func (c *Coordinator) handleFailedNode(ctx context.Context, signal *CompletionSi
 
 	// TODO: Handle failure (DLQ, retry, etc.)
 }
+
+// failUnmatchedBranch fails a branch node none of whose rules matched
+// Without a default path the node has nowhere to route, so it fails like any
+// other node: its error handlers take over, or the run fails. Its output is
+// kept for inspection.
+func (c *Coordinator) failUnmatchedBranch(ctx context.Context, signal *CompletionSignal, resultRef string, err error, ir *sdk.IR) {
+	c.logger.Error("no branch matched, failing node",
+		"run_id", signal.RunID,
+		"node_id", signal.NodeID,
+		"error", err)
+
+	if _, setErr := sdk.SetNodeStatus(ctx, c.redis, signal.RunID, signal.NodeID, sdk.NodeStatusFailed); setErr != nil {
+		c.logger.Warn("failed to record node status",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", setErr)
+	}
+
+	c.handleFailedNode(ctx, &CompletionSignal{
+		Version:   signal.Version,
+		JobID:     signal.JobID,
+		RunID:     signal.RunID,
+		NodeID:    signal.NodeID,
+		Status:    "failed",
+		ResultRef: resultRef,
+		Metadata: map[string]interface{}{
+			"error_type":    "NoBranchMatchedError",
+			"error_message": err.Error(),
+		},
+	}, ir)
+}
@@ -0,0 +1,110 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// rebuildIR recompiles a run's IR after it expired from Redis
+// Runs that outlive RunStateTTL would otherwise fail on their next completion.
+// The IR is compiled again from the run's artifact with its patches applied,
+// the runtime metadata recorded when the run started is restored, and the
+// result is cached again with a fresh TTL. The rest of the run's state gets
+// the same TTL, so it doesn't expire from under the rebuilt IR.
+func (c *Coordinator) rebuildIR(ctx context.Context, runID string) (*sdk.IR, error) {
+	c.logger.Warn("IR missing from Redis, rebuilding from artifact", "run_id", runID)
+
+	source, err := c.irSource(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild expired IR: %w", err)
+	}
+	ctx = clients.WithUserID(ctx, source.Username)
+
+	artifact, err := c.orchestratorClient.GetArtifact(ctx, source.ArtifactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild expired IR: %w", err)
+	}
+
+	// Patches applied to the run are part of its IR too
+	workflow, err := c.orchestratorClient.MaterializeWorkflowForRun(ctx, artifact.Content, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild expired IR: %w", err)
+	}
+
+	workflowJSON, err := json.Marshal(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow: %w", err)
+	}
+	var schema compiler.WorkflowSchema
+	if err := json.Unmarshal(workflowJSON, &schema); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow to schema: %w", err)
+	}
+
+	ir, err := compiler.CompileWorkflowSchema(&schema, c.casClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild expired IR: %w", err)
+	}
+
+	if ir.Metadata == nil {
+		ir.Metadata = make(map[string]interface{})
+	}
+	for key, value := range source.Metadata {
+		ir.Metadata[key] = value
+	}
+	if _, ok := ir.Metadata["username"]; !ok && source.Username != "" {
+		ir.Metadata["username"] = source.Username
+	}
+
+	irJSON, err := json.Marshal(ir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal IR: %w", err)
+	}
+	if err := c.redisWrapper.Set(ctx, fmt.Sprintf("ir:%s", runID), string(irJSON), sdk.RunStateTTL); err != nil {
+		// The rebuilt IR still serves this completion; the next one rebuilds again
+		c.logger.Error("failed to cache rebuilt IR", "run_id", runID, "error", err)
+	} else if err := sdk.ExpireRunState(ctx, c.redisWrapper, runID, sdk.RunStateTTL); err != nil {
+		c.logger.Error("failed to renew run state", "run_id", runID, "error", err)
+	}
+
+	c.logger.Info("rebuilt expired IR",
+		"run_id", runID,
+		"artifact_id", source.ArtifactID,
+		"nodes", len(ir.Nodes))
+
+	return ir, nil
+}
+
+// irSource returns what a run's IR was compiled from
+// Runs started before IR sources were recorded (or whose record expired too)
+// fall back to the run's base artifact and submitter from the orchestrator;
+// their runtime metadata other than the username is lost. Finished runs are
+// not rebuilt: their IR was expired on purpose, and late signals are dropped.
+func (c *Coordinator) irSource(ctx context.Context, runID string) (*sdk.IRSource, error) {
+	source, err := sdk.LoadIRSource(ctx, c.redis, runID)
+	if err != nil {
+		return nil, err
+	}
+	if source != nil {
+		return source, nil
+	}
+
+	run, err := c.orchestratorClient.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	switch models.RunStatus(run.Status) {
+	case models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+		return nil, fmt.Errorf("run %s already finished (%s)", runID, run.Status)
+	}
+	if run.BaseRef == "" {
+		return nil, fmt.Errorf("run %s has no base artifact", runID)
+	}
+
+	return &sdk.IRSource{ArtifactID: run.BaseRef, Username: run.SubmittedBy}, nil
+}
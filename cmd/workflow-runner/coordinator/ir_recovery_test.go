@@ -0,0 +1,131 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiredIRRebuiltFromArtifact(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	// fetch → enrich → notify, frozen in artifact art-1
+	workflow := map[string]interface{}{
+		"nodes": []interface{}{
+			map[string]interface{}{"id": "fetch", "type": "http", "config": map[string]interface{}{"url": "https://example.com/fetch"}},
+			map[string]interface{}{"id": "enrich", "type": "http", "config": map[string]interface{}{"url": "https://example.com/enrich"}},
+			map[string]interface{}{"id": "notify", "type": "http", "config": map[string]interface{}{"url": "https://example.com/notify"}},
+		},
+		"edges": []interface{}{
+			map[string]interface{}{"from": "fetch", "to": "enrich"},
+			map[string]interface{}{"from": "enrich", "to": "notify"},
+		},
+	}
+
+	var artifactFetches atomic.Int32
+	orchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/artifacts/art-1":
+			artifactFetches.Add(1)
+			assert.Equal(t, "alice", r.Header.Get("X-User-ID"))
+			json.NewEncoder(w).Encode(map[string]interface{}{"artifact_id": "art-1", "kind": "dag_version", "content": workflow})
+		case "/api/v1/runs/run_ir_expiry_test/patches":
+			json.NewEncoder(w).Encode(map[string]interface{}{"patches": []interface{}{}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(orchestrator.Close)
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:               rdb,
+		SDK:                 workflowSDK,
+		Logger:              logger,
+		CASClient:           casClient,
+		OrchestratorBaseURL: orchestrator.URL,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go coord.Start(ctx)
+
+	// Started as the executor would: IR plus the record it was compiled from
+	runID := "run_ir_expiry_test"
+	workflowJSON, err := json.Marshal(workflow)
+	require.NoError(t, err)
+	var schema compiler.WorkflowSchema
+	require.NoError(t, json.Unmarshal(workflowJSON, &schema))
+	ir, err := compiler.CompileWorkflowSchema(&schema, casClient)
+	require.NoError(t, err)
+	ir.Metadata = map[string]interface{}{"username": "alice", "tag": "main"}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, sdk.RunStateTTL).Err())
+	require.NoError(t, sdk.StoreIRSource(ctx, rdb, runID, &sdk.IRSource{
+		ArtifactID: "art-1",
+		Username:   "alice",
+		Metadata:   ir.Metadata,
+	}))
+	require.NoError(t, rdb.Set(ctx, sdk.InputsKey(runID), `{"customer":"ada"}`, sdk.RunStateTTL).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	complete := func(nodeID string) {
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+			Token:      &sdk.Token{ID: runID + "-" + nodeID, RunID: runID, ToNode: nodeID},
+			Status:     "completed",
+			ResultData: map[string]interface{}{"node": nodeID},
+		}))
+	}
+
+	complete("fetch")
+	require.Eventually(t, func() bool {
+		return len(dispatchedNodes(t, rdb)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Zero(t, artifactFetches.Load(), "the cached IR is used while it lasts")
+
+	// The IR expires mid-run; routing rebuilds it from the artifact
+	mr.FastForward(20 * time.Hour)
+	mr.Del("ir:" + runID)
+	complete("enrich")
+	require.Eventually(t, func() bool {
+		return len(dispatchedNodes(t, rdb)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"enrich", "notify"}, dispatchedNodes(t, rdb))
+	assert.EqualValues(t, 1, artifactFetches.Load())
+
+	// Re-cached with a fresh TTL and the run's runtime metadata
+	require.True(t, mr.Exists("ir:"+runID))
+	assert.Equal(t, sdk.RunStateTTL, mr.TTL("ir:"+runID))
+	// The rest of the run's state lives as long as the rebuilt IR
+	assert.Equal(t, sdk.RunStateTTL, mr.TTL(sdk.InputsKey(runID)))
+	assert.Equal(t, sdk.RunStateTTL, mr.TTL("context:"+runID))
+	assert.Equal(t, sdk.RunStateTTL, mr.TTL(sdk.CounterKey(runID)))
+	assert.Equal(t, sdk.RunStateTTL, mr.TTL(sdk.AppliedKey(runID)))
+	var rebuilt sdk.IR
+	require.NoError(t, json.Unmarshal([]byte(rdb.Get(ctx, "ir:"+runID).Val()), &rebuilt))
+	assert.Equal(t, "main", rebuilt.Metadata["tag"])
+	assert.Equal(t, "alice", rebuilt.Metadata["username"])
+}
@@ -0,0 +1,334 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/condition"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// mapStateKey returns the hash tracking a map node's in-flight elements
+// Fields: total, done, job:<job_id> → element index, result:<index> → result ref
+func mapStateKey(runID, mapNodeID string) string {
+	return fmt.Sprintf("map:%s:%s", runID, mapNodeID)
+}
+
+// mapNodeForBody returns the map node whose body is nodeID, or nil
+func mapNodeForBody(ir *sdk.IR, nodeID string) *sdk.Node {
+	node, exists := ir.Nodes[nodeID]
+	if !exists {
+		return nil
+	}
+	for _, dep := range node.Dependencies {
+		if mapNode, ok := ir.Nodes[dep]; ok && mapNode.IsMap() && mapNode.Map.Body == nodeID {
+			return mapNode
+		}
+	}
+	return nil
+}
+
+// startMapNode fans a map node out over the collection its selector returns
+// One token per element is published to the body node, with the element as its
+// payload. The counter gets +1 for the map node itself and +1 per element; the
+// map node's slot is only consumed once every element has completed and the
+// results are joined, so the run cannot be seen as complete mid fan-out.
+func (c *Coordinator) startMapNode(ctx context.Context, runID, fromNode, mapNodeID, payloadRef string, mapNode *sdk.Node, ir *sdk.IR) {
+	c.logger.Info("starting map node",
+		"run_id", runID,
+		"from_node", fromNode,
+		"map_node", mapNodeID,
+		"over", mapNode.Map.Over,
+		"body", mapNode.Map.Body)
+
+	// Hold the map node's slot for the whole fan-out
+	if err := c.sdk.Emit(ctx, runID, fromNode, []string{mapNodeID}, payloadRef); err != nil {
+		c.logger.Error("failed to emit counter update for map node",
+			"run_id", runID,
+			"map_node", mapNodeID,
+			"error", err)
+		return
+	}
+	if _, err := sdk.SetNodeStatus(ctx, c.redis, runID, mapNodeID, sdk.NodeStatusRunning); err != nil {
+		c.logger.Warn("failed to mark map node running",
+			"run_id", runID,
+			"map_node", mapNodeID,
+			"error", err)
+	}
+
+	body, exists := ir.Nodes[mapNode.Map.Body]
+	if !exists || !c.routes.HasWorker(body.Type) {
+		c.failMapNode(ctx, runID, mapNodeID, map[string]interface{}{
+			"error_type":    "MapBodyError",
+			"error_message": fmt.Sprintf("no worker available for map body %q", mapNode.Map.Body),
+		})
+		return
+	}
+
+	items, err := c.selectMapItems(ctx, runID, payloadRef, mapNode)
+	if err != nil {
+		c.failMapNode(ctx, runID, mapNodeID, map[string]interface{}{
+			"error_type":    "MapSelectorError",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	if len(items) == 0 {
+		c.completeMapNode(ctx, runID, mapNodeID, []interface{}{})
+		return
+	}
+
+	stateKey := mapStateKey(runID, mapNodeID)
+	if err := c.redisWrapper.Delete(ctx, stateKey); err != nil {
+		c.logger.Warn("failed to clear map state",
+			"run_id", runID,
+			"map_node", mapNodeID,
+			"error", err)
+	}
+	if err := c.redisWrapper.SetHash(ctx, stateKey, "total", strconv.Itoa(len(items))); err != nil {
+		c.failMapNode(ctx, runID, mapNodeID, map[string]interface{}{
+			"error_type":    "MapStateError",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	// One counter slot per element, applied before any element can complete
+	bodies := make([]string, len(items))
+	for i := range bodies {
+		bodies[i] = body.ID
+	}
+	if err := c.sdk.Emit(ctx, runID, mapNodeID, bodies, payloadRef); err != nil {
+		c.logger.Error("failed to emit counter update for map elements",
+			"run_id", runID,
+			"map_node", mapNodeID,
+			"count", len(items),
+			"error", err)
+		return
+	}
+
+	// Templates see the map node's input as ${output}
+	resolvedConfig, err := c.loadAndResolveConfig(ctx, runID, body.ID, body, payloadRef)
+	if err != nil {
+		c.failMapNode(ctx, runID, mapNodeID, map[string]interface{}{
+			"error_type":    "ConfigResolutionError",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	stream := c.routes.StreamFor(body.Type)
+
+	for i, item := range items {
+		itemRef, err := c.sdk.StoreOutput(ctx, item)
+		if err != nil {
+			c.failMapNode(ctx, runID, mapNodeID, map[string]interface{}{
+				"error_type":    "MapElementError",
+				"error_message": fmt.Sprintf("failed to store element %d: %v", i, err),
+			})
+			return
+		}
+
+		jobID := fmt.Sprintf("%s-%s-%d-%d", runID, body.ID, i, time.Now().UnixNano())
+		if err := c.redisWrapper.SetHash(ctx, stateKey, "job:"+jobID, strconv.Itoa(i)); err != nil {
+			c.failMapNode(ctx, runID, mapNodeID, map[string]interface{}{
+				"error_type":    "MapStateError",
+				"error_message": err.Error(),
+			})
+			return
+		}
+
+		if err := c.publishTokenWithID(ctx, jobID, stream, runID, mapNodeID, body.ID, itemRef, resolvedConfig, ir); err != nil {
+			c.failMapNode(ctx, runID, mapNodeID, map[string]interface{}{
+				"error_type":    "MapElementError",
+				"error_message": fmt.Sprintf("failed to publish element %d: %v", i, err),
+			})
+			return
+		}
+	}
+
+	c.logger.Info("map node fanned out",
+		"run_id", runID,
+		"map_node", mapNodeID,
+		"body", body.ID,
+		"count", len(items))
+}
+
+// selectMapItems evaluates the map selector against the upstream output
+func (c *Coordinator) selectMapItems(ctx context.Context, runID, payloadRef string, mapNode *sdk.Node) ([]interface{}, error) {
+	output, err := c.sdk.LoadPayload(ctx, payloadRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upstream output: %w", err)
+	}
+
+	context, err := c.sdk.LoadContext(ctx, runID)
+	if err != nil {
+		c.logger.Warn("failed to load context for map selector",
+			"run_id", runID,
+			"error", err)
+		context = make(map[string]interface{})
+	}
+
+	vars, err := c.sdk.GetVars(ctx, runID)
+	if err != nil {
+		c.logger.Warn("failed to load vars for map selector",
+			"run_id", runID,
+			"error", err)
+		vars = make(map[string]interface{})
+	}
+
+	nodes, err := condition.ExpressionNodes([]string{mapNode.Map.Over}, func(nodeID string) (interface{}, error) {
+		return c.sdk.LoadNodeOutput(ctx, runID, nodeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := c.evaluator.Select(mapNode.Map.Over, output, context, vars, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate map selector %q: %w", mapNode.Map.Over, err)
+	}
+	return items, nil
+}
+
+// handleMapItemCompletion records one element's result and joins when all are done
+// Redelivered completions and completions arriving after the map failed are ignored.
+func (c *Coordinator) handleMapItemCompletion(ctx context.Context, signal *CompletionSignal, mapNode *sdk.Node, ir *sdk.IR) {
+	stateKey := mapStateKey(signal.RunID, mapNode.ID)
+
+	index, err := c.redisWrapper.GetHash(ctx, stateKey, "job:"+signal.JobID)
+	if err != nil {
+		c.logger.Warn("ignoring completion for unknown map element",
+			"run_id", signal.RunID,
+			"map_node", mapNode.ID,
+			"node_id", signal.NodeID,
+			"job_id", signal.JobID)
+		return
+	}
+
+	if signal.Status == "failed" {
+		sdk.SetNodeStatus(ctx, c.redis, signal.RunID, signal.NodeID, sdk.NodeStatusFailed)
+
+		metadata := make(map[string]interface{}, len(signal.Metadata)+1)
+		for k, v := range signal.Metadata {
+			metadata[k] = v
+		}
+		metadata["map_index"] = index
+		c.failMapNode(ctx, signal.RunID, mapNode.ID, metadata)
+		return
+	}
+
+	resultRef := c.storeResultInCAS(ctx, signal)
+
+	// Record the element result once
+	recorded, err := c.redis.HSetNX(ctx, stateKey, "result:"+index, resultRef).Result()
+	if err != nil {
+		c.logger.Error("failed to record map element result",
+			"run_id", signal.RunID,
+			"map_node", mapNode.ID,
+			"index", index,
+			"error", err)
+		return
+	}
+	if !recorded {
+		c.logger.Info("ignoring duplicate map element completion",
+			"run_id", signal.RunID,
+			"map_node", mapNode.ID,
+			"index", index)
+		return
+	}
+
+	if err := c.sdk.ConsumeJob(ctx, signal.RunID, signal.NodeID, signal.JobID); err != nil {
+		c.logger.Error("failed to consume map element token",
+			"run_id", signal.RunID,
+			"map_node", mapNode.ID,
+			"job_id", signal.JobID,
+			"error", err)
+		return
+	}
+
+	done, err := c.redisWrapper.IncrementHash(ctx, stateKey, "done", 1)
+	if err != nil {
+		c.logger.Error("failed to count map element",
+			"run_id", signal.RunID,
+			"map_node", mapNode.ID,
+			"error", err)
+		return
+	}
+
+	state, err := c.redisWrapper.GetAllHash(ctx, stateKey)
+	if err != nil {
+		c.logger.Error("failed to load map state",
+			"run_id", signal.RunID,
+			"map_node", mapNode.ID,
+			"error", err)
+		return
+	}
+	total, _ := strconv.Atoi(state["total"])
+
+	c.logger.Info("map element completed",
+		"run_id", signal.RunID,
+		"map_node", mapNode.ID,
+		"index", index,
+		"done", done,
+		"total", total)
+
+	// Only the completion that brings done to total joins
+	if int(done) != total {
+		return
+	}
+
+	results := make([]interface{}, total)
+	for i := range results {
+		ref := state["result:"+strconv.Itoa(i)]
+		if ref == "" {
+			continue
+		}
+		output, err := c.sdk.LoadPayload(ctx, ref)
+		if err != nil {
+			c.logger.Warn("failed to load map element result",
+				"run_id", signal.RunID,
+				"map_node", mapNode.ID,
+				"index", i,
+				"error", err)
+			continue
+		}
+		results[i] = output
+	}
+
+	c.redisWrapper.Delete(ctx, stateKey)
+	c.completeMapNode(ctx, signal.RunID, mapNode.ID, results)
+}
+
+// completeMapNode completes the map node with its joined results
+// Runs through handleCompletion, which consumes the map node's slot and routes
+// the results (in element order) to its dependents.
+func (c *Coordinator) completeMapNode(ctx context.Context, runID, mapNodeID string, results []interface{}) {
+	c.handleCompletion(ctx, &CompletionSignal{
+		Version: "1.0",
+		JobID:   fmt.Sprintf("%s-%s-map", runID, mapNodeID),
+		RunID:   runID,
+		NodeID:  mapNodeID,
+		Status:  "completed",
+		ResultData: map[string]interface{}{
+			"results": results,
+			"count":   len(results),
+		},
+	})
+}
+
+// failMapNode fails the map node (and with it the run)
+// The fan-out state is dropped so completions of the remaining elements are ignored.
+func (c *Coordinator) failMapNode(ctx context.Context, runID, mapNodeID string, metadata map[string]interface{}) {
+	c.redisWrapper.Delete(ctx, mapStateKey(runID, mapNodeID))
+	c.handleCompletion(ctx, &CompletionSignal{
+		Version:  "1.0",
+		JobID:    fmt.Sprintf("%s-%s-map", runID, mapNodeID),
+		RunID:    runID,
+		NodeID:   mapNodeID,
+		Status:   "failed",
+		Metadata: metadata,
+	})
+}
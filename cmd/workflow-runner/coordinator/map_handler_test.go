@@ -0,0 +1,147 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMapNodeFansOutAndJoins runs fetch → each(map over output.items, body
+// process) → summarize with a 3-element collection: process runs three times in
+// parallel, the results join in element order, and the counter drains to zero.
+func TestMapNodeFansOutAndJoins(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	runID := "run_map_test"
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/items"}},
+			{ID: "each", Type: "map", Config: map[string]interface{}{"over": "output.items", "body": "process"}},
+			{ID: "process", Type: "http", Config: map[string]interface{}{"url": "https://example.com/process"}},
+			{ID: "summarize", Type: "http", Config: map[string]interface{}{"url": "https://example.com/summary"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "fetch", To: "each"},
+			{From: "each", To: "summarize"},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	complete := func(token *sdk.Token, result map[string]interface{}) {
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+			Token:      token,
+			Status:     "completed",
+			ResultData: result,
+		}))
+	}
+	tokensFor := func(node string, n int) []*sdk.Token {
+		var tokens []*sdk.Token
+		require.Eventually(t, func() bool {
+			tokens = nil
+			for _, msg := range rdb.XRange(ctx, "wf.tasks.http", "-", "+").Val() {
+				var token sdk.Token
+				require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+				if token.ToNode == node {
+					tokens = append(tokens, &token)
+				}
+			}
+			return len(tokens) == n
+		}, 5*time.Second, 20*time.Millisecond)
+		return tokens
+	}
+
+	complete(&sdk.Token{ID: runID + "-fetch", RunID: runID, ToNode: "fetch"}, map[string]interface{}{
+		"items": []interface{}{"a", "b", "c"},
+	})
+
+	// 1. One body token per element, each carrying its element as payload
+	tokens := tokensFor("process", 3)
+	var payloads []interface{}
+	for _, token := range tokens {
+		assert.Equal(t, "each", token.FromNode)
+		payload, err := workflowSDK.LoadPayload(ctx, token.PayloadRef)
+		require.NoError(t, err)
+		payloads = append(payloads, payload)
+	}
+	assert.ElementsMatch(t, []interface{}{"a", "b", "c"}, payloads)
+
+	// The map node and its three elements are all in flight
+	counter, err := workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, counter)
+
+	// 2. Complete out of order; nothing downstream starts until the last one
+	for i := len(tokens) - 1; i >= 0; i-- {
+		payload, _ := workflowSDK.LoadPayload(ctx, tokens[i].PayloadRef)
+		complete(tokens[i], map[string]interface{}{"processed": payload})
+		if i > 0 {
+			time.Sleep(50 * time.Millisecond)
+			assert.Empty(t, tokensFor("summarize", 0))
+		}
+	}
+
+	// 3. The join feeds summarize with the results in element order
+	summarize := tokensFor("summarize", 1)[0]
+	assert.Equal(t, "each", summarize.FromNode)
+	joined, err := workflowSDK.LoadPayload(ctx, summarize.PayloadRef)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"count": float64(3),
+		"results": []interface{}{
+			map[string]interface{}{"processed": "a"},
+			map[string]interface{}{"processed": "b"},
+			map[string]interface{}{"processed": "c"},
+		},
+	}, joined)
+
+	status, _ := mr.Get(sdk.NodeStatusKey(runID, "each"))
+	assert.Equal(t, sdk.NodeStatusCompleted, status)
+	assert.False(t, mr.Exists(mapStateKey(runID, "each")))
+
+	counter, err = workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+
+	// 4. The terminal node drains the counter
+	complete(summarize, map[string]interface{}{"ok": true})
+	require.Eventually(t, func() bool {
+		counter, err := workflowSDK.GetCounter(ctx, runID)
+		return err == nil && counter == 0
+	}, 5*time.Second, 20*time.Millisecond)
+}
@@ -0,0 +1,94 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetricsAfterRun routes A → B and checks the run shows up on /metrics
+func TestMetricsAfterRun(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	runID := "run_metrics_test"
+	ir := &sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"A": {ID: "A", Type: "http", Dependents: []string{"B"}},
+			"B": {ID: "B", Type: "http", Dependencies: []string{"A"}, IsTerminal: true},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	routedBefore := metrics.CoordinatorRoutingDuration.Count()
+	casPutsBefore := metrics.CASOperationDuration.Count("put")
+
+	require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+		Token:      &sdk.Token{ID: runID + "-A", RunID: runID, ToNode: "A"},
+		Status:     "completed",
+		ResultData: map[string]interface{}{"node": "A"},
+	}))
+
+	// B's token reaches the http stream once A is routed
+	require.Eventually(t, func() bool {
+		return rdb.XLen(ctx, "wf.tasks.http").Val() > 0
+	}, 5*time.Second, 20*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return metrics.CoordinatorRoutingDuration.Count() > routedBefore
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Greater(t, metrics.CASOperationDuration.Count("put"), casPutsBefore)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	// Labelled metrics appear once updated; metrics without labels from the start
+	for _, series := range []string{
+		"coordinator_routing_duration_seconds_count ",
+		`cas_operation_duration_seconds_count{operation="put"} `,
+		`redis_commands_total{command="get"} `,
+		"# TYPE stream_messages_consumed_total counter",
+		"# TYPE hitl_pending_approvals gauge",
+		"# TYPE run_stalls_detected_total counter",
+	} {
+		assert.Contains(t, string(body), series)
+	}
+}
@@ -2,24 +2,39 @@ package coordinator
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/operators"
+	"github.com/lyzr/orchestrator/common/metrics"
 	"github.com/lyzr/orchestrator/common/sdk"
 )
 
 // routeToNextNodes processes and routes execution to next nodes
 // Handles both absorber nodes (branch/loop) and worker nodes (http, agent, etc.)
+// Call it before consuming the completed node's token: absorbers are handled
+// inline, and their downstream nodes are counted before that slot is released.
 func (c *Coordinator) routeToNextNodes(ctx context.Context, signal *CompletionSignal, nextNodes []string, resultRef string, ir *sdk.IR) {
 	if len(nextNodes) == 0 {
 		return
 	}
+	defer metrics.CoordinatorRoutingDuration.ObserveSince(time.Now())
+
+	// Apply counter update (+N) for worker nodes before dispatching any of them,
+	// so a fast node can't consume its token before its slot is counted
+	workerNodes := countedNodes(ir, nextNodes)
+	if len(workerNodes) > 0 {
+		if err := c.sdk.Emit(ctx, signal.RunID, signal.NodeID, workerNodes, resultRef); err != nil {
+			c.logger.Error("failed to emit counter update",
+				"run_id", signal.RunID,
+				"node_id", signal.NodeID,
+				"next_nodes_count", len(workerNodes),
+				"error", err)
+		}
+	}
 
 	// Track which nodes are absorbers (handled inline) vs. workers (published to streams)
 	absorberNodes := []string{}
-	workerNodes := []string{}
 
 	for _, nextNodeID := range nextNodes {
 		nextNode, exists := ir.Nodes[nextNodeID]
@@ -30,6 +45,12 @@ func (c *Coordinator) routeToNextNodes(ctx context.Context, signal *CompletionSi
 			continue
 		}
 
+		// Map nodes fan out inline and account for their own counter slot
+		if nextNode.IsMap() {
+			c.startMapNode(ctx, signal.RunID, signal.NodeID, nextNodeID, resultRef, nextNode, ir)
+			continue
+		}
+
 		// Check if this is an absorber node (branch or loop) - handle inline
 		// Absorber logic is encapsulated in Node.IsAbsorber()
 		if nextNode.IsAbsorber() {
@@ -42,12 +63,11 @@ func (c *Coordinator) routeToNextNodes(ctx context.Context, signal *CompletionSi
 			absorberNodes = append(absorberNodes, nextNodeID)
 
 			// Handle absorber node inline - immediately trigger downstream nodes
-			go c.handleAbsorberNode(ctx, signal.RunID, signal.NodeID, nextNodeID, resultRef, nextNode, ir)
+			c.handleAbsorberNode(ctx, signal.RunID, signal.NodeID, nextNodeID, resultRef, nextNode, ir)
 			continue
 		}
 
 		// Regular worker node - publish to stream
-		workerNodes = append(workerNodes, nextNodeID)
 		c.processWorkerNode(ctx, signal, nextNodeID, nextNode, resultRef, ir)
 	}
 
@@ -55,32 +75,33 @@ func (c *Coordinator) routeToNextNodes(ctx context.Context, signal *CompletionSi
 		"run_id", signal.RunID,
 		"absorber_nodes", absorberNodes,
 		"worker_nodes", workerNodes)
+}
 
-	// Apply counter update (+N) only for worker nodes
-	// Absorber nodes will handle their own counter updates when they complete
-	if len(workerNodes) > 0 {
-		if err := c.sdk.Emit(ctx, signal.RunID, signal.NodeID, workerNodes, resultRef); err != nil {
-			c.logger.Error("failed to emit counter update",
-				"run_id", signal.RunID,
-				"node_id", signal.NodeID,
-				"next_nodes_count", len(workerNodes),
-				"error", err)
+// countedNodes returns the next nodes that hold a counter slot until they complete
+// Map nodes emit their own slot when they start (see startMapNode). Absorbers
+// hold none: they route inline while the node before them still holds its slot.
+func countedNodes(ir *sdk.IR, nodeIDs []string) []string {
+	counted := make([]string, 0, len(nodeIDs))
+	for _, id := range nodeIDs {
+		node, ok := ir.Nodes[id]
+		if !ok || node.IsMap() || node.IsAbsorber() {
+			continue
 		}
+		counted = append(counted, id)
 	}
+	return counted
 }
 
 // processWorkerNode handles a regular worker node (http, agent, etc.)
 // Loads config, resolves variables, and publishes token to worker stream
 func (c *Coordinator) processWorkerNode(ctx context.Context, signal *CompletionSignal, nextNodeID string, nextNode *sdk.Node, resultRef string, ir *sdk.IR) {
-	// Check if we have a worker for this node type
-	supportedTypes := map[string]bool{
-		"http":  true,
-		"agent": true,
-		"hitl":  true,
-		// Add other types as workers are implemented
+	// Joins are dispatched once, by the last upstream node to arrive
+	if !c.joinReady(ctx, signal.RunID, signal.NodeID, nextNodeID, nextNode) {
+		return
 	}
 
-	if !supportedTypes[nextNode.Type] {
+	// Check if we have a worker for this node type
+	if !c.routes.HasWorker(nextNode.Type) {
 		c.logger.Warn("no worker available for node type, skipping to next nodes",
 			"run_id", signal.RunID,
 			"node_id", nextNodeID,
@@ -92,14 +113,14 @@ func (c *Coordinator) processWorkerNode(ctx context.Context, signal *CompletionS
 	}
 
 	// Load and resolve config
-	resolvedConfig := c.loadAndResolveConfig(ctx, signal.RunID, nextNodeID, nextNode)
-	if resolvedConfig == nil && nextNode.ConfigRef != "" {
-		// Config loading failed for a node that requires config
+	resolvedConfig, err := c.loadAndResolveConfig(ctx, signal.RunID, nextNodeID, nextNode, resultRef)
+	if err != nil {
+		go c.failNodeConfig(ctx, signal.RunID, nextNodeID, err)
 		return
 	}
 
 	// Get appropriate stream for node type
-	stream := c.router.GetStreamForNodeType(nextNode.Type)
+	stream := c.routes.StreamFor(nextNode.Type)
 
 	// Publish token to stream with resolved config and IR
 	if err := c.publishToken(ctx, stream, signal.RunID, signal.NodeID, nextNodeID, resultRef, resolvedConfig, ir); err != nil {
@@ -118,7 +139,8 @@ func (c *Coordinator) processWorkerNode(ctx context.Context, signal *CompletionS
 		"stream", stream)
 }
 
-// handleSkippedNode immediately completes a node that has no worker available
+// handleSkippedNode immediately completes a node that has no worker available,
+// i.e. no stream is registered for its type in c.routes
 // This prevents the workflow from hanging when agents add unsupported node types
 func (c *Coordinator) handleSkippedNode(ctx context.Context, runID, fromNode, skippedNodeID string, skippedNode *sdk.Node, payloadRef string, ir *sdk.IR) {
 	c.logger.Warn("handling skipped node (no worker available)",
@@ -127,6 +149,16 @@ func (c *Coordinator) handleSkippedNode(ctx context.Context, runID, fromNode, sk
 		"skipped_node", skippedNodeID,
 		"node_type", skippedNode.Type)
 
+	c.recordSkip(ctx, runID, &operators.SkipDecision{
+		NodeID:   skippedNodeID,
+		FromNode: fromNode,
+		Reason:   operators.SkipReasonNoWorkerAvailable,
+		Message:  fmt.Sprintf("no worker available for node type %q", skippedNode.Type),
+		Details: map[string]interface{}{
+			"node_type": skippedNode.Type,
+		},
+	})
+
 	// Create a warning result
 	skippedOutput := map[string]interface{}{
 		"status":  "skipped",
@@ -140,13 +172,8 @@ func (c *Coordinator) handleSkippedNode(ctx context.Context, runID, fromNode, sk
 	}
 
 	// Store in CAS so it appears in node executions
-	resultID := fmt.Sprintf("artifact://%s-%s-%d", runID, skippedNodeID, time.Now().UnixNano())
-	casKey := fmt.Sprintf("cas:%s", resultID)
-	skippedJSON, err := json.Marshal(skippedOutput)
-	if err == nil {
-		if err := c.redisWrapper.Set(ctx, casKey, string(skippedJSON), 0); err == nil {
-			c.sdk.StoreContext(ctx, runID, skippedNodeID, resultID)
-		}
+	if resultRef, err := c.sdk.StoreOutput(ctx, skippedOutput); err == nil {
+		c.sdk.StoreContext(ctx, runID, skippedNodeID, resultRef)
 	}
 
 	// Create synthetic completion signal
@@ -159,7 +186,7 @@ func (c *Coordinator) handleSkippedNode(ctx context.Context, runID, fromNode, sk
 		ResultData: skippedOutput,
 		Metadata: map[string]interface{}{
 			"skipped": true,
-			"reason":  "no_worker_available",
+			"reason":  operators.SkipReasonNoWorkerAvailable,
 		},
 	}
 
@@ -168,6 +195,7 @@ func (c *Coordinator) handleSkippedNode(ctx context.Context, runID, fromNode, sk
 }
 
 // handleAbsorberNode handles branch/loop nodes inline (no worker needed)
+// Runs synchronously, while the node that routed here still holds its counter slot.
 func (c *Coordinator) handleAbsorberNode(ctx context.Context, runID, fromNode, absorberNodeID, payloadRef string, absorberNode *sdk.Node, ir *sdk.IR) {
 	startTime := time.Now()
 	c.logger.Info("handling absorber node inline",
@@ -195,18 +223,13 @@ func (c *Coordinator) handleAbsorberNode(ctx context.Context, runID, fromNode, a
 	}
 
 	// Store absorber output in CAS (so it appears in node executions)
-	absorberResultID := fmt.Sprintf("artifact://%s-%s-%d", runID, absorberNodeID, time.Now().UnixNano())
-	absorberCASKey := fmt.Sprintf("cas:%s", absorberResultID)
-	absorberJSON, err := json.Marshal(absorberOutput)
-	if err == nil {
-		if err := c.redisWrapper.Set(ctx, absorberCASKey, string(absorberJSON), 0); err == nil {
-			// Store reference in context
-			c.sdk.StoreContext(ctx, runID, absorberNodeID, absorberResultID)
-			c.logger.Debug("stored absorber output in CAS",
-				"run_id", runID,
-				"absorber_node", absorberNodeID,
-				"result_ref", absorberResultID)
-		}
+	if absorberResultRef, err := c.sdk.StoreOutput(ctx, absorberOutput); err == nil {
+		// Store reference in context
+		c.sdk.StoreContext(ctx, runID, absorberNodeID, absorberResultRef)
+		c.logger.Debug("stored absorber output in CAS",
+			"run_id", runID,
+			"absorber_node", absorberNodeID,
+			"result_ref", absorberResultRef)
 	}
 
 	// Create a synthetic completion signal for the absorber node
@@ -239,6 +262,15 @@ func (c *Coordinator) handleAbsorberNode(ctx context.Context, runID, fromNode, a
 
 	// Emit tokens to next nodes (recursively handles nested absorbers)
 	if len(nextNodes) > 0 {
+		// Update counter for worker nodes emitted by absorber, before any is dispatched
+		if err := c.sdk.Emit(ctx, runID, absorberNodeID, countedNodes(ir, nextNodes), payloadRef); err != nil {
+			c.logger.Error("failed to emit counter update from absorber",
+				"run_id", runID,
+				"absorber_node", absorberNodeID,
+				"next_nodes_count", len(nextNodes),
+				"error", err)
+		}
+
 		for _, nextNodeID := range nextNodes {
 			nextNode, exists := ir.Nodes[nextNodeID]
 			if !exists {
@@ -248,24 +280,29 @@ func (c *Coordinator) handleAbsorberNode(ctx context.Context, runID, fromNode, a
 				continue
 			}
 
+			// Map nodes fan out inline and account for their own counter slot
+			if nextNode.IsMap() {
+				c.startMapNode(ctx, runID, absorberNodeID, nextNodeID, payloadRef, nextNode, ir)
+				continue
+			}
+
 			// Check if next node is also an absorber - recurse
 			if nextNode.IsAbsorber() {
 				c.logger.Info("next node is also an absorber - recursing",
 					"run_id", runID,
 					"absorber_node", absorberNodeID,
 					"next_absorber", nextNodeID)
-				go c.handleAbsorberNode(ctx, runID, absorberNodeID, nextNodeID, payloadRef, nextNode, ir)
+				c.handleAbsorberNode(ctx, runID, absorberNodeID, nextNodeID, payloadRef, nextNode, ir)
 				continue
 			}
 
-			// Check if we have a worker for this node type
-			supportedTypes := map[string]bool{
-				"http":  true,
-				"agent": true,
-				"hitl":  true,
+			// Joins are dispatched once, by the last upstream node to arrive
+			if !c.joinReady(ctx, runID, absorberNodeID, nextNodeID, nextNode) {
+				continue
 			}
 
-			if !supportedTypes[nextNode.Type] {
+			// Check if we have a worker for this node type
+			if !c.routes.HasWorker(nextNode.Type) {
 				c.logger.Warn("no worker for node type from absorber, skipping",
 					"run_id", runID,
 					"absorber_node", absorberNodeID,
@@ -278,14 +315,14 @@ func (c *Coordinator) handleAbsorberNode(ctx context.Context, runID, fromNode, a
 			}
 
 			// Load and resolve config for worker node
-			resolvedConfig := c.loadAndResolveConfig(ctx, runID, nextNodeID, nextNode)
-			if resolvedConfig == nil && nextNode.ConfigRef != "" {
-				// Config loading failed for a node that requires config
+			resolvedConfig, err := c.loadAndResolveConfig(ctx, runID, nextNodeID, nextNode, payloadRef)
+			if err != nil {
+				go c.failNodeConfig(ctx, runID, nextNodeID, err)
 				continue
 			}
 
 			// Publish to worker stream
-			stream := c.router.GetStreamForNodeType(nextNode.Type)
+			stream := c.routes.StreamFor(nextNode.Type)
 			if err := c.publishToken(ctx, stream, runID, absorberNodeID, nextNodeID, payloadRef, resolvedConfig, ir); err != nil {
 				c.logger.Error("failed to publish token from absorber",
 					"run_id", runID,
@@ -301,15 +338,6 @@ func (c *Coordinator) handleAbsorberNode(ctx context.Context, runID, fromNode, a
 				"to_node", nextNodeID,
 				"stream", stream)
 		}
-
-		// Update counter for worker nodes emitted by absorber
-		if err := c.sdk.Emit(ctx, runID, absorberNodeID, nextNodes, payloadRef); err != nil {
-			c.logger.Error("failed to emit counter update from absorber",
-				"run_id", runID,
-				"absorber_node", absorberNodeID,
-				"next_nodes_count", len(nextNodes),
-				"error", err)
-		}
 	}
 
 	c.logger.Info("absorber node completed inline",
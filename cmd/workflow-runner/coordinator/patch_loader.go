@@ -3,18 +3,24 @@ package coordinator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/lyzr/orchestrator/common/compiler"
 	"github.com/lyzr/orchestrator/common/ratelimit"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
 	"github.com/lyzr/orchestrator/common/sdk"
 	"github.com/lyzr/orchestrator/common/clients"
 )
 
 // loadIR loads the latest IR from Redis (no caching for patch support)
+// An IR that expired while the run was still going is rebuilt (see rebuildIR).
 func (c *Coordinator) loadIR(ctx context.Context, runID string) (*sdk.IR, error) {
 	key := fmt.Sprintf("ir:%s", runID)
 	data, err := c.redisWrapper.Get(ctx, key)
+	if errors.Is(err, redisWrapper.ErrKeyNotFound) {
+		return c.rebuildIR(ctx, runID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get IR from Redis: %w", err)
 	}
@@ -206,7 +212,7 @@ func (c *Coordinator) reloadIRIfPatched(ctx context.Context, runID string, curre
 		"ir_size_bytes", len(patchedIRJSON))
 
 	irKey := fmt.Sprintf("ir:%s", runID)
-	if err := c.redisWrapper.Set(ctx, irKey, string(patchedIRJSON), 0); err != nil {
+	if err := c.redisWrapper.Set(ctx, irKey, string(patchedIRJSON), sdk.RunStateTTL); err != nil {
 		c.logger.Error("ERROR: failed to store patched IR in Redis",
 			"run_id", runID,
 			"error", err)
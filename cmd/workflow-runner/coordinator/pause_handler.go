@@ -0,0 +1,48 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// holdIfPaused buffers a completion signal while its run is paused
+// Nothing is routed for a paused run; the signal is replayed onto the
+// completion queue when the run is resumed (see sdk.ResumeRun), so no
+// completed work is lost; its claim is released so the replay is handled.
+// Returns true if the signal was held.
+func (c *Coordinator) holdIfPaused(ctx context.Context, signal *CompletionSignal) bool {
+	signalJSON, err := json.Marshal(signal)
+	if err != nil {
+		c.logger.Error("failed to marshal completion signal",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+		return false
+	}
+
+	held, err := sdk.BufferSignalIfPaused(ctx, c.redis, signal.RunID, signalJSON)
+	if err != nil {
+		// Fail open: routing a signal beats stalling the run
+		c.logger.Warn("failed to check if run is paused",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+		return false
+	}
+	if held {
+		c.logger.Info("run paused, holding completion signal",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"job_id", signal.JobID)
+
+		if err := c.sdk.ReleaseCompletion(ctx, signal.RunID, completionKey(signal)); err != nil {
+			c.logger.Warn("failed to release held completion signal",
+				"run_id", signal.RunID,
+				"node_id", signal.NodeID,
+				"error", err)
+		}
+	}
+	return held
+}
@@ -0,0 +1,126 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPausedRunHoldsSignalsUntilResumed runs A → B → C, pauses it while B is
+// in flight, completes B and checks C isn't routed until the run is resumed.
+func TestPausedRunHoldsSignalsUntilResumed(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	runID := "run_pause_test"
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "A", Type: "http", Config: map[string]interface{}{"url": "https://example.com/a"}},
+			{ID: "B", Type: "http", Config: map[string]interface{}{"url": "https://example.com/b"}},
+			{ID: "C", Type: "http", Config: map[string]interface{}{"url": "https://example.com/c"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "A", To: "B"},
+			{From: "B", To: "C"},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	// The flag and the buffer are used together by Lua scripts
+	assert.Equal(t, rediscommon.KeySlot(sdk.RunPausedKey(runID)), rediscommon.KeySlot(sdk.PausedSignalsKey(runID)))
+
+	tokensFor := func(nodeID string) int {
+		count := 0
+		for _, msg := range rdb.XRange(ctx, "wf.tasks.http", "-", "+").Val() {
+			var token sdk.Token
+			require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+			if token.ToNode == nodeID {
+				count++
+			}
+		}
+		return count
+	}
+	complete := func(nodeID string) {
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+			Token:      &sdk.Token{ID: runID + "-" + nodeID, RunID: runID, ToNode: nodeID},
+			Status:     "completed",
+			ResultData: map[string]interface{}{"ok": true},
+		}))
+	}
+
+	// 1. A completes while the run is active, and B is dispatched
+	complete("A")
+	require.Eventually(t, func() bool {
+		return tokensFor("B") == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// 2. The run is paused while B is in flight; B's completion is held, not routed
+	paused, err := sdk.PauseRun(ctx, rdb, runID)
+	require.NoError(t, err)
+	require.True(t, paused)
+
+	complete("B")
+	require.Eventually(t, func() bool {
+		return rdb.LLen(ctx, sdk.PausedSignalsKey(runID)).Val() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, tokensFor("C"))
+
+	counter, err := workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter, "B's token is still outstanding")
+
+	// 3. Resume replays the held signal and C is routed
+	resumed, replayed, err := sdk.ResumeRun(ctx, rdb, runID)
+	require.NoError(t, err)
+	assert.True(t, resumed)
+	assert.Equal(t, 1, replayed)
+
+	require.Eventually(t, func() bool {
+		return tokensFor("C") == 1
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 1, tokensFor("B"))
+	assert.False(t, mr.Exists(sdk.RunPausedKey(runID)))
+	assert.False(t, mr.Exists(sdk.PausedSignalsKey(runID)))
+
+	// Resuming twice is rejected
+	resumed, _, err = sdk.ResumeRun(ctx, rdb, runID)
+	require.NoError(t, err)
+	assert.False(t, resumed)
+}
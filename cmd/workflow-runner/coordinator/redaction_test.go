@@ -0,0 +1,143 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDropRedactedFieldsBeforeStoring checks "drop" fields never reach CAS
+// while "mask" fields are stored for downstream nodes.
+func TestDropRedactedFieldsBeforeStoring(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	runID := "run_redaction_test"
+	ir := &sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"lookup": {
+				ID:         "lookup",
+				Type:       "http",
+				IsTerminal: true,
+				Config: map[string]interface{}{
+					"url": "https://example.com/customers",
+					sdk.ConfigKeyRedact: []interface{}{
+						map[string]interface{}{"path": "body.ssn", "action": sdk.RedactActionDrop},
+						map[string]interface{}{"path": "body.contacts.*.phone", "action": sdk.RedactActionDrop},
+						map[string]interface{}{"path": "body.email", "action": sdk.RedactActionMask},
+					},
+				},
+			},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+		Token:  &sdk.Token{ID: runID + "-lookup", RunID: runID, ToNode: "lookup"},
+		Status: "completed",
+		ResultData: map[string]interface{}{
+			"status_code": 200,
+			"body": map[string]interface{}{
+				"name":  "Ada",
+				"ssn":   "123-45-6789",
+				"email": "ada@example.com",
+				"contacts": []interface{}{
+					map[string]interface{}{"kind": "home", "phone": "555-0100"},
+					map[string]interface{}{"kind": "work", "phone": "555-0199"},
+				},
+			},
+		},
+	}))
+
+	var ref string
+	require.Eventually(t, func() bool {
+		ref = rdb.HGet(ctx, "context:"+runID, "lookup:output").Val()
+		return ref != ""
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Results go through the CAS client, content-addressed
+	assert.True(t, strings.HasPrefix(ref, "sha256:"), ref)
+	stored, err := rdb.Get(ctx, "cas:"+ref).Result()
+	require.NoError(t, err)
+	assert.NotContains(t, stored, "123-45-6789")
+	assert.NotContains(t, stored, "555-01")
+
+	var output map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stored), &output))
+	body := output["body"].(map[string]interface{})
+	assert.NotContains(t, body, "ssn")
+	assert.Equal(t, "Ada", body["name"])
+	// Masking happens when outputs are returned by the API, not in storage
+	assert.Equal(t, "ada@example.com", body["email"])
+	for _, contact := range body["contacts"].([]interface{}) {
+		assert.NotContains(t, contact.(map[string]interface{}), "phone")
+		assert.Contains(t, contact.(map[string]interface{}), "kind")
+	}
+}
+
+// TestDropRedactedFieldsBeforeHoldingPausedSignal checks "drop" fields don't
+// reach the buffer a paused run holds its completion signals in either
+func TestDropRedactedFieldsBeforeHoldingPausedSignal(t *testing.T) {
+	run := newDataTestRun(t, "run_redaction_paused_test", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "lookup", Type: "http", Config: map[string]interface{}{
+				"url": "https://example.com/customers",
+				sdk.ConfigKeyRedact: []interface{}{
+					map[string]interface{}{"path": "ssn", "action": sdk.RedactActionDrop},
+				},
+			}},
+			{ID: "notify", Type: "http", Config: map[string]interface{}{"url": "https://example.com/notify"}},
+		},
+		Edges: []compiler.WorkflowEdge{{From: "lookup", To: "notify"}},
+	}, 1)
+
+	paused, err := sdk.PauseRun(run.ctx, run.rdb, run.runID)
+	require.NoError(t, err)
+	require.True(t, paused)
+
+	run.complete("lookup", map[string]interface{}{"name": "Ada", "ssn": "123-45-6789"})
+	require.Eventually(t, func() bool {
+		return run.rdb.LLen(run.ctx, sdk.PausedSignalsKey(run.runID)).Val() == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	held := run.rdb.LIndex(run.ctx, sdk.PausedSignalsKey(run.runID), 0).Val()
+	assert.NotContains(t, held, "123-45-6789")
+	var signal CompletionSignal
+	require.NoError(t, json.Unmarshal([]byte(held), &signal))
+	assert.Equal(t, map[string]interface{}{"name": "Ada"}, signal.ResultData)
+}
@@ -0,0 +1,119 @@
+package coordinator
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// downstreamNodes returns every node reachable from nodeID, excluding nodeID
+func downstreamNodes(ir *sdk.IR, nodeID string) []string {
+	seen := map[string]bool{nodeID: true}
+	queue := []string{nodeID}
+	var downstream []string
+
+	for len(queue) > 0 {
+		current := ir.Nodes[queue[0]]
+		queue = queue[1:]
+		if current == nil {
+			continue
+		}
+
+		next := append([]string{}, current.Dependents...)
+		if current.IsMap() {
+			next = append(next, current.Map.Body)
+		}
+		for _, id := range next {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			downstream = append(downstream, id)
+			queue = append(queue, id)
+		}
+	}
+
+	sort.Strings(downstream)
+	return downstream
+}
+
+// handleRetryNode runs a node again within its run (see worker.SignalRetry)
+// The node and everything downstream of it are reset; upstream outputs stay in
+// the run context and are reused, so only the re-activated subgraph executes.
+// The node's token is re-emitted with the output of its first upstream node
+// that has one, and routing continues from there as for a fresh token.
+func (c *Coordinator) handleRetryNode(ctx context.Context, signal *CompletionSignal, node *sdk.Node, ir *sdk.IR) {
+	if !c.routes.HasWorker(node.Type) || mapNodeForBody(ir, node.ID) != nil {
+		c.logger.Error("cannot retry node without its own worker token",
+			"run_id", signal.RunID,
+			"node_id", node.ID,
+			"node_type", node.Type)
+		return
+	}
+
+	downstream := downstreamNodes(ir, node.ID)
+	c.logger.Info("retrying node",
+		"run_id", signal.RunID,
+		"node_id", node.ID,
+		"downstream", downstream,
+		"metadata", signal.Metadata)
+
+	// 1. Reset the re-activated subgraph and settle its counter slots
+	if err := c.sdk.ReactivateNodes(ctx, signal.RunID, node.ID, downstream); err != nil {
+		c.logger.Error("failed to reactivate nodes",
+			"run_id", signal.RunID,
+			"node_id", node.ID,
+			"error", err)
+		return
+	}
+
+	// 2. Reuse the upstream output the node originally ran with
+	fromNode, payloadRef := "", ""
+	for _, dep := range node.Dependencies {
+		ref, err := c.sdk.LoadOutputRef(ctx, signal.RunID, dep)
+		if err != nil {
+			c.logger.Warn("failed to load upstream output for retry",
+				"run_id", signal.RunID,
+				"node_id", node.ID,
+				"dependency", dep,
+				"error", err)
+			continue
+		}
+		if ref != "" {
+			fromNode, payloadRef = dep, ref
+			break
+		}
+	}
+
+	c.lifecycle.StatusManager.UpdateRunStatus(ctx, signal.RunID, "RUNNING")
+
+	// 3. Re-emit the node's token
+	resolvedConfig, err := c.loadAndResolveConfig(ctx, signal.RunID, node.ID, node, payloadRef)
+	if err != nil {
+		c.failNodeConfig(ctx, signal.RunID, node.ID, err)
+		return
+	}
+	stream := c.routes.StreamFor(node.Type)
+	if err := c.publishToken(ctx, stream, signal.RunID, fromNode, node.ID, payloadRef, resolvedConfig, ir); err != nil {
+		c.logger.Error("failed to publish retry token",
+			"run_id", signal.RunID,
+			"node_id", node.ID,
+			"stream", stream,
+			"error", err)
+		return
+	}
+
+	if ir.Metadata != nil {
+		if username, ok := ir.Metadata["username"].(string); ok {
+			c.lifecycle.EventPublisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
+				"type":      "node_retried",
+				"run_id":    signal.RunID,
+				"node_id":   node.ID,
+				"from_node": fromNode,
+				"timestamp": time.Now().Unix(),
+			})
+		}
+	}
+}
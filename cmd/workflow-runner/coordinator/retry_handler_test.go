@@ -0,0 +1,190 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// retryRun is a run of A → B → C driven by hand, for retry tests
+type retryRun struct {
+	t     *testing.T
+	ctx   context.Context
+	rdb   *redis.Client
+	sdk   *sdk.SDK
+	runID string
+}
+
+// startRetryRun starts a coordinator on a run of A → B → C
+func startRetryRun(t *testing.T, runID string) *retryRun {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go coord.Start(ctx)
+
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "A", Type: "http", Config: map[string]interface{}{"url": "https://example.com/a"}},
+			{ID: "B", Type: "http", Config: map[string]interface{}{"url": "https://example.com/b"}},
+			{ID: "C", Type: "http", Config: map[string]interface{}{"url": "https://example.com/c"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "A", To: "B"},
+			{From: "B", To: "C"},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	return &retryRun{t: t, ctx: ctx, rdb: rdb, sdk: workflowSDK, runID: runID}
+}
+
+// signal sends a node's completion; failures carry an error
+func (r *retryRun) signal(token *sdk.Token, status string, result map[string]interface{}) {
+	opts := &worker.CompletionOpts{Token: token, Status: status, ResultData: result}
+	if status == "failed" {
+		opts.Metadata = map[string]interface{}{"error_type": "HTTPError", "error_message": "boom"}
+	}
+	require.NoError(r.t, worker.SignalCompletion(r.ctx, r.rdb, noopLogger{}, opts))
+}
+
+// tokensFor waits until n tokens were dispatched to node and returns them
+func (r *retryRun) tokensFor(node string, n int) []*sdk.Token {
+	var tokens []*sdk.Token
+	require.Eventually(r.t, func() bool {
+		tokens = nil
+		for _, msg := range r.rdb.XRange(r.ctx, "wf.tasks.http", "-", "+").Val() {
+			var token sdk.Token
+			require.NoError(r.t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+			if token.ToNode == node {
+				tokens = append(tokens, &token)
+			}
+		}
+		return len(tokens) == n
+	}, 5*time.Second, 20*time.Millisecond)
+	return tokens
+}
+
+// status returns the run's hot status
+func (r *retryRun) status() string {
+	status, _ := r.rdb.Get(r.ctx, "run:status:"+r.runID).Result()
+	return status
+}
+
+// counter returns the run's token counter
+func (r *retryRun) counter() int {
+	counter, err := r.sdk.GetCounter(r.ctx, r.runID)
+	require.NoError(r.t, err)
+	return counter
+}
+
+// TestRetryFailedNode fails B in A → B → C, retries the run from B and checks
+// it completes while reusing A's original output.
+func TestRetryFailedNode(t *testing.T) {
+	runID := "run_retry_test"
+	run := startRetryRun(t, runID)
+	ctx, rdb, workflowSDK := run.ctx, run.rdb, run.sdk
+	logger := noopLogger{}
+	signal, tokensFor, runStatus := run.signal, run.tokensFor, run.status
+
+	// 1. A completes, B fails and the run fails with B's token outstanding
+	signal(&sdk.Token{ID: runID + "-A", RunID: runID, ToNode: "A"}, "completed", map[string]interface{}{"a": float64(1)})
+	b := tokensFor("B", 1)[0]
+	signal(b, "failed", nil)
+	require.Eventually(t, func() bool { return runStatus() == "FAILED" }, 5*time.Second, 20*time.Millisecond)
+
+	failure, err := rdb.HGet(ctx, "context:"+runID, "B:failure:output").Result()
+	require.NoError(t, err)
+	assert.Contains(t, failure, `"status":"failed"`)
+
+	aRef, err := workflowSDK.LoadOutputRef(ctx, runID, "A")
+	require.NoError(t, err)
+
+	// 2. Retry from B re-emits its token with A's stored output
+	require.NoError(t, worker.SignalRetry(ctx, rdb, logger, runID, "B", nil))
+	retried := tokensFor("B", 2)[1]
+	assert.Equal(t, "A", retried.FromNode)
+	assert.Equal(t, aRef, retried.PayloadRef)
+	assert.False(t, rdb.HExists(ctx, "context:"+runID, "B:failure:output").Val(), "failure context is cleared")
+	assert.Equal(t, "RUNNING", runStatus())
+
+	counter, err := workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+
+	// 3. The retried subgraph runs to completion
+	signal(retried, "completed", map[string]interface{}{"b": float64(2)})
+	c := tokensFor("C", 1)[0]
+	assert.Equal(t, "B", c.FromNode)
+	signal(c, "completed", map[string]interface{}{"c": float64(3)})
+
+	require.Eventually(t, func() bool { return runStatus() == "COMPLETED" }, 5*time.Second, 20*time.Millisecond)
+	counter, err = workflowSDK.GetCounter(ctx, runID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, counter)
+
+	// A was not re-run and its output is intact
+	assert.Len(t, tokensFor("A", 0), 0)
+	aAfter, err := workflowSDK.LoadOutputRef(ctx, runID, "A")
+	require.NoError(t, err)
+	assert.Equal(t, aRef, aAfter)
+	aOutput, err := workflowSDK.LoadNodeOutput(ctx, runID, "A")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, aOutput)
+}
+
+// TestRetryFromUpstreamNode fails B in A → B → C and retries the run from A,
+// which completed: B's slot from the failed attempt must not be left counted.
+func TestRetryFromUpstreamNode(t *testing.T) {
+	run := startRetryRun(t, "run_retry_upstream_test")
+
+	run.signal(&sdk.Token{ID: run.runID + "-A", RunID: run.runID, ToNode: "A"}, "completed", map[string]interface{}{"a": float64(1)})
+	run.signal(run.tokensFor("B", 1)[0], "failed", nil)
+	require.Eventually(t, func() bool { return run.status() == "FAILED" }, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 1, run.counter(), "B's failed token is still counted")
+
+	// Retrying A restores A's slot and releases B's: one token, A's, is counted
+	require.NoError(t, worker.SignalRetry(run.ctx, run.rdb, noopLogger{}, run.runID, "A", nil))
+	a := run.tokensFor("A", 1)[0]
+	assert.Equal(t, 1, run.counter())
+	assert.Equal(t, "RUNNING", run.status())
+
+	// The whole subgraph runs again and the counter reaches zero at the end
+	run.signal(a, "completed", map[string]interface{}{"a": float64(2)})
+	run.signal(run.tokensFor("B", 2)[1], "completed", map[string]interface{}{"b": float64(2)})
+	run.signal(run.tokensFor("C", 1)[0], "completed", map[string]interface{}{"c": float64(3)})
+	require.Eventually(t, func() bool { return run.status() == "COMPLETED" }, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 0, run.counter())
+}
@@ -0,0 +1,254 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultSimulationTimeout bounds how long a replay may wait for the run to finish
+const DefaultSimulationTimeout = 10 * time.Second
+
+// Simulator replays a run fixture through the coordinator without workers
+// Instead of dispatching tokens to workers it answers each one with the node's
+// recorded output from the fixture, so routing (branches, joins, loops) runs
+// through the real coordinator code and can be asserted in a unit test.
+// Give it a throwaway Redis (e.g. miniredis): the replay writes run state there.
+type Simulator struct {
+	coordinator *Coordinator
+	redis       redis.UniversalClient
+	sdk         *sdk.SDK
+	timeout     time.Duration
+}
+
+// SimulatorOpts contains options for creating a simulator
+type SimulatorOpts struct {
+	Redis   redis.UniversalClient
+	SDK     *sdk.SDK
+	Logger  Logger
+	Timeout time.Duration // 0 uses DefaultSimulationTimeout
+}
+
+// SimulationResult is the outcome of a replay
+type SimulationResult struct {
+	RunID    string                 `json:"run_id"`
+	Status   string                 `json:"status"`   // run status recorded by the coordinator
+	Executed []string               `json:"executed"` // nodes answered with a mock, in order
+	Result   map[string]interface{} `json:"result"`   // terminal node outputs
+}
+
+// NewSimulator creates a simulator backed by a coordinator on the given Redis
+func NewSimulator(opts *SimulatorOpts) *Simulator {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSimulationTimeout
+	}
+
+	return &Simulator{
+		coordinator: NewCoordinator(&CoordinatorOpts{
+			Redis:     opts.Redis,
+			SDK:       opts.SDK,
+			Logger:    opts.Logger,
+			CASClient: opts.SDK.CASClient,
+		}),
+		redis:   opts.Redis,
+		sdk:     opts.SDK,
+		timeout: timeout,
+	}
+}
+
+// Run replays the fixture until the run's token counter drains
+func (s *Simulator) Run(ctx context.Context, fixture *sdk.RunFixture) (*SimulationResult, error) {
+	if fixture.IR == nil || len(fixture.IR.Nodes) == 0 {
+		return nil, fmt.Errorf("fixture has no IR")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	runID := fixture.RunID
+	entryNodes, err := s.start(ctx, fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SimulationResult{RunID: runID, Result: make(map[string]interface{})}
+	cursors := make(map[string]string)
+	pending := entryNodes
+
+	for {
+		for _, nodeID := range pending {
+			output, ok := fixture.Mocks[nodeID]
+			if !ok {
+				return nil, fmt.Errorf("no recorded output for node %s", nodeID)
+			}
+
+			result.Executed = append(result.Executed, nodeID)
+			signal := &CompletionSignal{
+				Version:    "1.0",
+				JobID:      fmt.Sprintf("sim-%s-%d", nodeID, len(result.Executed)),
+				RunID:      runID,
+				NodeID:     nodeID,
+				Status:     "completed",
+				ResultData: copyOutput(output),
+			}
+			s.coordinator.dropRedactedFields(ctx, signal) // As Start does for worker signals
+			s.coordinator.handleCompletion(ctx, signal)
+		}
+
+		pending, err = s.dispatched(ctx, runID, cursors)
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 {
+			continue
+		}
+
+		counter, err := s.sdk.GetCounter(ctx, runID)
+		if err != nil {
+			return nil, err
+		}
+		if counter <= 0 {
+			break
+		}
+
+		// Nodes without a worker are passed through asynchronously; give them a moment
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("simulation stalled with %d tokens outstanding: %w", counter, ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	status, err := s.redis.Get(ctx, fmt.Sprintf("run:status:%s", runID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to load run status: %w", err)
+	}
+	result.Status = status
+
+	for nodeID, node := range fixture.IR.Nodes {
+		if !node.IsTerminal {
+			continue
+		}
+		if output, err := s.sdk.LoadNodeOutput(ctx, runID, nodeID); err == nil {
+			result.Result[nodeID] = output
+		}
+	}
+
+	return result, nil
+}
+
+// start stores the run state the run request consumer would and returns the entry nodes
+func (s *Simulator) start(ctx context.Context, fixture *sdk.RunFixture) ([]string, error) {
+	irJSON, err := json.Marshal(fixture.IR)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal IR: %w", err)
+	}
+	if err := s.redis.Set(ctx, fmt.Sprintf("ir:%s", fixture.RunID), irJSON, 0).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store IR: %w", err)
+	}
+
+	for name, value := range fixture.Vars {
+		if err := s.sdk.SetVar(ctx, fixture.RunID, name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	entryNodes := []string{}
+	for nodeID, node := range fixture.IR.Nodes {
+		if len(node.Dependencies) == 0 {
+			entryNodes = append(entryNodes, nodeID)
+		}
+	}
+	if len(entryNodes) == 0 {
+		return nil, fmt.Errorf("workflow has no entry nodes")
+	}
+	sort.Strings(entryNodes)
+
+	if err := s.sdk.InitializeCounter(ctx, fixture.RunID, len(entryNodes)); err != nil {
+		return nil, err
+	}
+	return entryNodes, nil
+}
+
+// dispatched returns the nodes the coordinator has sent tokens for since the last call
+// Tokens are ordered by stream entry ID, i.e. the order they were published in.
+func (s *Simulator) dispatched(ctx context.Context, runID string, cursors map[string]string) ([]string, error) {
+	type dispatch struct {
+		id     string
+		nodeID string
+	}
+	var found []dispatch
+
+	for _, stream := range s.coordinator.routes.Streams() {
+		start := "-"
+		if cursor, ok := cursors[stream]; ok {
+			start = "(" + cursor
+		}
+
+		messages, err := s.redis.XRange(ctx, stream, start, "+").Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", stream, err)
+		}
+
+		for _, message := range messages {
+			cursors[stream] = message.ID
+
+			raw, _ := message.Values["token"].(string)
+			var token sdk.Token
+			if err := json.Unmarshal([]byte(raw), &token); err != nil || token.RunID != runID {
+				continue
+			}
+			found = append(found, dispatch{id: message.ID, nodeID: token.ToNode})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return compareStreamIDs(found[i].id, found[j].id) < 0
+	})
+
+	nodes := make([]string, len(found))
+	for i, d := range found {
+		nodes[i] = d.nodeID
+	}
+	return nodes, nil
+}
+
+// compareStreamIDs orders "<ms>-<seq>" stream entry IDs
+func compareStreamIDs(a, b string) int {
+	var aMs, aSeq, bMs, bSeq int64
+	fmt.Sscanf(a, "%d-%d", &aMs, &aSeq)
+	fmt.Sscanf(b, "%d-%d", &bMs, &bSeq)
+
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq < bSeq:
+		return -1
+	case aSeq > bSeq:
+		return 1
+	}
+	return 0
+}
+
+// copyOutput gives each replayed completion its own copy of the mock
+// The coordinator drops redacted fields from result data in place.
+func copyOutput(output map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return output
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return output
+	}
+	return copied
+}
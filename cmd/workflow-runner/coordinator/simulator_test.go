@@ -0,0 +1,144 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/cmd/orchestrator/service"
+	"github.com/lyzr/orchestrator/common/bootstrap"
+	"github.com/lyzr/orchestrator/common/clients"
+	commonlogger "github.com/lyzr/orchestrator/common/logger"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSimulatorSDK builds an SDK over a fresh miniredis
+func newSimulatorSDK(t *testing.T) (*redis.Client, *sdk.SDK) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	return rdb, sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, string(luaScript))
+}
+
+// TestExportedFixtureReplaysBranchRun runs a branch workflow through the
+// coordinator, exports it as a fixture and replays it in the simulator
+func TestExportedFixtureReplaysBranchRun(t *testing.T) {
+	rdb, workflowSDK := newSimulatorSDK(t)
+	logger := noopLogger{}
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: workflowSDK.CASClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	runID := uuid.New().String()
+	ir := &sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"check": {
+				ID:         "check",
+				Type:       "http",
+				Dependents: []string{"approve", "reject"},
+				Branch: &sdk.BranchConfig{
+					Enabled: true,
+					Type:    "conditional",
+					Rules: []sdk.BranchRule{
+						{Condition: &sdk.Condition{Type: "cel", Expression: "output.score >= 80"}, NextNodes: []string{"approve"}},
+					},
+					Default: []string{"reject"},
+				},
+			},
+			"approve": {ID: "approve", Type: "http", Dependencies: []string{"check"}, IsTerminal: true},
+			"reject":  {ID: "reject", Type: "http", Dependencies: []string{"check"}, IsTerminal: true},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, rdb.Set(ctx, sdk.InputsKey(runID), `{"customer":"ada"}`, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	// The "production" run: check scores 91, so the branch picks approve
+	complete := func(nodeID string, output map[string]interface{}) {
+		require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+			Token:      &sdk.Token{ID: runID + "-" + nodeID, RunID: runID, ToNode: nodeID},
+			Status:     "completed",
+			ResultData: output,
+		}))
+	}
+	complete("check", map[string]interface{}{"score": 91})
+	require.Eventually(t, func() bool {
+		return rdb.XLen(ctx, "wf.tasks.http").Val() > 0
+	}, 5*time.Second, 20*time.Millisecond)
+	complete("approve", map[string]interface{}{"decision": "approved", "by": "rules"})
+	require.Eventually(t, func() bool {
+		return rdb.Get(ctx, "run:status:"+runID).Val() == "COMPLETED"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// Export the run the way GET /api/v1/runs/:id/fixture does
+	log := commonlogger.New("error", "text")
+	runService := service.NewRunService(&service.RunServiceOpts{
+		Components: &bootstrap.Components{Logger: log},
+		Redis:      rediscommon.NewClient(rdb, log),
+	})
+	exported, err := runService.ExportRunFixture(ctx, uuid.MustParse(runID))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"customer": "ada"}, exported.Inputs)
+	assert.Len(t, exported.Mocks, 2)
+	assert.NotContains(t, exported.Mocks, "reject")
+
+	// Fixtures are saved to disk and loaded by the test that replays them
+	data, err := json.Marshal(exported)
+	require.NoError(t, err)
+	var fixture sdk.RunFixture
+	require.NoError(t, json.Unmarshal(data, &fixture))
+
+	simRedis, simSDK := newSimulatorSDK(t)
+	simulator := NewSimulator(&SimulatorOpts{Redis: simRedis, SDK: simSDK, Logger: logger})
+	result, err := simulator.Run(context.Background(), &fixture)
+	require.NoError(t, err)
+
+	assert.Equal(t, "COMPLETED", result.Status)
+	assert.Equal(t, []string{"check", "approve"}, result.Executed)
+	assert.Equal(t, fixture.Result, result.Result)
+	assert.Equal(t, "approved", result.Result["approve"].(map[string]interface{})["decision"])
+}
+
+func TestSimulatorRequiresMockForDispatchedNode(t *testing.T) {
+	simRedis, simSDK := newSimulatorSDK(t)
+	simulator := NewSimulator(&SimulatorOpts{Redis: simRedis, SDK: simSDK, Logger: noopLogger{}})
+
+	_, err := simulator.Run(context.Background(), &sdk.RunFixture{
+		RunID: "run_missing_mock",
+		IR: &sdk.IR{
+			Version: "1.0",
+			Nodes: map[string]*sdk.Node{
+				"A": {ID: "A", Type: "http", Dependents: []string{"B"}},
+				"B": {ID: "B", Type: "http", Dependencies: []string{"A"}, IsTerminal: true},
+			},
+		},
+		Mocks: map[string]map[string]interface{}{"A": {"ok": true}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no recorded output for node B")
+}
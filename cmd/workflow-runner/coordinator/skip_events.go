@@ -0,0 +1,42 @@
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/operators"
+)
+
+// recordSkip publishes a node_skipped event to the run event log and fanout
+// The username for fanout comes from the run's IR; the durable log is written regardless.
+func (c *Coordinator) recordSkip(ctx context.Context, runID string, skip *operators.SkipDecision) {
+	c.logger.Info("node skipped",
+		"run_id", runID,
+		"node_id", skip.NodeID,
+		"from_node", skip.FromNode,
+		"reason", skip.Reason,
+		"message", skip.Message)
+
+	username := ""
+	if ir, err := c.loadIR(ctx, runID); err == nil && ir.Metadata != nil {
+		username, _ = ir.Metadata["username"].(string)
+	}
+
+	event := map[string]interface{}{
+		"type":      "node_skipped",
+		"run_id":    runID,
+		"node_id":   skip.NodeID,
+		"from_node": skip.FromNode,
+		"reason":    skip.Reason,
+		"message":   skip.Message,
+		"timestamp": time.Now().Unix(),
+	}
+	if skip.Condition != nil {
+		event["condition"] = skip.Condition
+	}
+	if len(skip.Details) > 0 {
+		event["details"] = skip.Details
+	}
+
+	c.lifecycle.EventPublisher.PublishRunEvent(ctx, username, runID, event)
+}
@@ -0,0 +1,105 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/operators"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/workflow_lifecycle"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSkippedNodeEvents runs A → B where B has no worker, and checks the skip
+// is written to the run event log and fanned out with its reason.
+func TestSkippedNodeEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub := rdb.Subscribe(ctx, "workflow:events:alice")
+	defer sub.Close()
+	_, err = sub.Receive(ctx)
+	require.NoError(t, err)
+
+	go coord.Start(ctx)
+
+	runID := "run_skip_events_test"
+	ir := &sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"A": {ID: "A", Type: "http", Dependents: []string{"B"}},
+			"B": {ID: "B", Type: "classifier", Dependencies: []string{"A"}, IsTerminal: true},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+		Token:      &sdk.Token{ID: runID + "-A", RunID: runID, ToNode: "A"},
+		Status:     "completed",
+		ResultData: map[string]interface{}{"node": "A"},
+	}))
+
+	// 1. Durable log
+	logKey := workflow_lifecycle.RunEventLogKey(runID)
+	var entries []redis.XMessage
+	require.Eventually(t, func() bool {
+		entries = rdb.XRange(ctx, logKey, "-", "+").Val()
+		return len(entries) > 0
+	}, 5*time.Second, 20*time.Millisecond)
+
+	assert.Equal(t, "node_skipped", entries[0].Values["type"])
+	var logged map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(entries[0].Values["event"].(string)), &logged))
+	assert.Equal(t, "B", logged["node_id"])
+	assert.Equal(t, "A", logged["from_node"])
+	assert.Equal(t, operators.SkipReasonNoWorkerAvailable, logged["reason"])
+	assert.Equal(t, `no worker available for node type "classifier"`, logged["message"])
+	assert.Equal(t, "classifier", logged["details"].(map[string]interface{})["node_type"])
+	assert.Greater(t, mr.TTL(logKey), time.Duration(0))
+
+	// 2. Fanout carries the same event
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-sub.Channel():
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(msg.Payload), &event))
+			if event["type"] != "node_skipped" {
+				continue
+			}
+			assert.Equal(t, logged, event)
+			return
+		case <-deadline:
+			t.Fatal("node_skipped event was not published")
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// completedAfterTimeout reports whether a completion belongs to a timed-out token
+// Completions stop their token being tracked as in flight. A token that is no
+// longer tracked and was claimed by the timeout detector has already been
+// dispatched again or failed, so its late completion must not route.
+func (c *Coordinator) completedAfterTimeout(ctx context.Context, signal *CompletionSignal) bool {
+	if signal.Status == sdk.SignalStatusRetry || signal.Status == sdk.SignalStatusTimeout || signal.JobID == "" {
+		return false
+	}
+
+	cleared, err := sdk.ClearInflight(ctx, c.redis, signal.JobID)
+	if err != nil {
+		c.logger.Warn("failed to clear in-flight token",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"job_id", signal.JobID,
+			"error", err)
+		return false
+	}
+	if cleared {
+		return false
+	}
+
+	timedOut, err := sdk.IsJobTimedOut(ctx, c.redis, signal.RunID, signal.JobID)
+	if err != nil || !timedOut {
+		return false
+	}
+
+	c.logger.Info("ignoring completion of timed out token",
+		"run_id", signal.RunID,
+		"node_id", signal.NodeID,
+		"job_id", signal.JobID,
+		"status", signal.Status)
+	return true
+}
+
+// handleTimedOutNode dispatches a timed-out node again or fails it
+// The node is dispatched again while its retry policy allows more attempts
+// (see sdk.RetryPolicy.Attempts); after that it fails like any other failed
+// node. The token's counter slot is still held, so neither path touches the
+// counter here.
+func (c *Coordinator) handleTimedOutNode(ctx context.Context, signal *CompletionSignal, node *sdk.Node, ir *sdk.IR) {
+	attempt := metadataInt(signal.Metadata, "attempt")
+	if attempt < 1 {
+		attempt = 1
+	}
+	timeoutMs := metadataInt(signal.Metadata, "timeout_ms")
+
+	// Cancelled, completed or failed meanwhile: nothing is in flight any more
+	status, err := sdk.GetNodeStatus(ctx, c.redis, signal.RunID, node.ID)
+	if err != nil {
+		c.logger.Warn("failed to get node status for timeout",
+			"run_id", signal.RunID,
+			"node_id", node.ID,
+			"error", err)
+	} else if status != sdk.NodeStatusRunning && status != sdk.NodeStatusWaitingForApproval && status != sdk.NodeStatusWaitingForCallback {
+		c.logger.Info("ignoring timeout for node no longer in flight",
+			"run_id", signal.RunID,
+			"node_id", node.ID,
+			"status", status)
+		return
+	}
+
+	c.logger.Warn("node timed out",
+		"run_id", signal.RunID,
+		"node_id", node.ID,
+		"job_id", signal.JobID,
+		"attempt", attempt,
+		"max_attempts", node.Retry.Attempts(),
+		"timeout_ms", timeoutMs)
+
+	if attempt < node.Retry.Attempts() {
+		err := c.redispatchTimedOut(ctx, signal, node, ir, attempt+1)
+		if err == nil {
+			return
+		}
+		c.logger.Error("failed to dispatch timed out node again",
+			"run_id", signal.RunID,
+			"node_id", node.ID,
+			"error", err)
+	}
+
+	failSignal := &CompletionSignal{
+		Version: signal.Version,
+		JobID:   signal.JobID,
+		RunID:   signal.RunID,
+		NodeID:  node.ID,
+		Status:  "failed",
+		Metadata: map[string]interface{}{
+			"error_type":    "NodeTimeout",
+			"error_message": fmt.Sprintf("node did not complete within %s", time.Duration(timeoutMs)*time.Millisecond),
+			"timeout_ms":    timeoutMs,
+			"attempts":      attempt,
+			"retryable":     true,
+		},
+	}
+
+	if mapNode := mapNodeForBody(ir, node.ID); mapNode != nil {
+		c.handleMapItemCompletion(ctx, failSignal, mapNode, ir)
+		return
+	}
+
+	recorded, err := sdk.SetNodeStatus(ctx, c.redis, signal.RunID, node.ID, sdk.NodeStatusFailed)
+	if err == nil && !recorded {
+		c.logger.Info("ignoring timeout for cancelled node",
+			"run_id", signal.RunID,
+			"node_id", node.ID)
+		return
+	}
+	c.handleFailedNode(ctx, failSignal, ir)
+}
+
+// redispatchTimedOut publishes a timed-out node's token again under a new job ID
+// Map elements keep their element index so the join still finds their result.
+func (c *Coordinator) redispatchTimedOut(ctx context.Context, signal *CompletionSignal, node *sdk.Node, ir *sdk.IR, attempt int) error {
+	fromNode, _ := signal.Metadata["from_node"].(string)
+	payloadRef, _ := signal.Metadata["payload_ref"].(string)
+	jobID := fmt.Sprintf("%s-%s-%d", signal.RunID, node.ID, time.Now().UnixNano())
+
+	if mapNode := mapNodeForBody(ir, node.ID); mapNode != nil {
+		stateKey := mapStateKey(signal.RunID, mapNode.ID)
+		index, err := c.redisWrapper.GetHash(ctx, stateKey, "job:"+signal.JobID)
+		if err != nil {
+			return fmt.Errorf("map element of job %s not found: %w", signal.JobID, err)
+		}
+		if err := c.redisWrapper.SetHash(ctx, stateKey, "job:"+jobID, index); err != nil {
+			return err
+		}
+	}
+
+	resolvedConfig, err := c.loadAndResolveConfig(ctx, signal.RunID, node.ID, node, payloadRef)
+	if err != nil {
+		return err
+	}
+	stream := c.routes.StreamFor(node.Type)
+	if err := c.publishTokenAttempt(ctx, attempt, jobID, stream, signal.RunID, fromNode, node.ID, payloadRef, resolvedConfig, ir); err != nil {
+		return err
+	}
+
+	c.logger.Info("dispatched timed out node again",
+		"run_id", signal.RunID,
+		"node_id", node.ID,
+		"job_id", jobID,
+		"attempt", attempt)
+	return nil
+}
+
+// metadataInt reads a number from signal metadata (JSON numbers decode as float64)
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return 0
+}
@@ -6,30 +6,21 @@ import (
 	"fmt"
 	"time"
 
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
 	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
 )
 
 // loadAndResolveConfig loads node config (inline or from CAS) and resolves variables
-// Returns the resolved config map, or nil if config loading/resolution fails
-func (c *Coordinator) loadAndResolveConfig(ctx context.Context, runID, nodeID string, node *sdk.Node) map[string]interface{} {
-	// Load node config (inline or CAS)
-	c.logger.Info("loading node config",
-		"run_id", runID,
-		"node_id", nodeID,
-		"node_type", node.Type,
-		"has_inline_config", len(node.Config) > 0,
-		"has_config_ref", node.ConfigRef != "",
-		"inline_config", node.Config,
-		"config_ref", node.ConfigRef)
-
+// payloadRef is the upstream output ${output...} templates read. Returns an error
+// if the config can't be loaded or, with strict templates, references a missing
+// value; the node must not run then (see failNodeConfig).
+func (c *Coordinator) loadAndResolveConfig(ctx context.Context, runID, nodeID string, node *sdk.Node, payloadRef string) (map[string]interface{}, error) {
+	// Inline config saves a CAS round-trip; only large configs are externalized
 	var config map[string]interface{}
-	c.logger.Info("config is ehre",
-		"config", node.Config)
 	if len(node.Config) > 0 {
 		config = node.Config
-		c.logger.Info("using inline config", "config", config)
 	} else if node.ConfigRef != "" {
-		c.logger.Info("loading config from CAS", "config_ref", node.ConfigRef)
 		configData, err := c.sdk.LoadConfig(ctx, node.ConfigRef)
 		if err != nil {
 			c.logger.Error("failed to load config from CAS",
@@ -37,61 +28,92 @@ func (c *Coordinator) loadAndResolveConfig(ctx context.Context, runID, nodeID st
 				"node_id", nodeID,
 				"config_ref", node.ConfigRef,
 				"error", err)
-			return nil
+			return nil, fmt.Errorf("failed to load config %s: %w", node.ConfigRef, err)
 		}
-		// Convert to map
-		if configMap, ok := configData.(map[string]interface{}); ok {
-			config = configMap
-			c.logger.Info("loaded config from CAS", "config", config)
-		} else {
+		configMap, ok := configData.(map[string]interface{})
+		if !ok {
 			c.logger.Error("config is not a map",
 				"run_id", runID,
 				"node_id", nodeID)
-			return nil
+			return nil, fmt.Errorf("config %s is not an object", node.ConfigRef)
 		}
+		config = configMap
 	} else {
-		c.logger.Warn("node has no config (neither inline nor CAS ref)",
+		c.logger.Debug("node has no config (neither inline nor CAS ref)",
 			"run_id", runID,
 			"node_id", nodeID)
+		return nil, nil
 	}
 
-	// Resolve variables in config (e.g., $nodes.node_id)
-	c.logger.Info("about to resolve config",
-		"run_id", runID,
-		"node_id", nodeID,
-		"config_is_nil", config == nil,
-		"config", config)
-
-	var resolvedConfig map[string]interface{}
-	if config != nil {
-		var err error
-		resolvedConfig, err = c.resolver.ResolveConfig(ctx, runID, config)
-		if err != nil {
-			c.logger.Error("failed to resolve config variables",
-				"run_id", runID,
-				"node_id", nodeID,
-				"error", err)
-			// Continue with unresolved config as fallback
-			resolvedConfig = config
-		} else {
-			c.logger.Info("resolved config variables successfully",
-				"run_id", runID,
-				"node_id", nodeID,
-				"resolvedConfig", resolvedConfig)
-		}
-	} else {
-		c.logger.Warn("config is nil, cannot resolve - resolvedConfig will be nil",
+	// Resolve templates in config (e.g. ${output.id}, ${nodes.fetch.output.url})
+	resolvedConfig, err := c.resolver.ResolveConfigFor(ctx, runID, payloadRef, config)
+	if err != nil {
+		c.logger.Error("failed to resolve config variables",
 			"run_id", runID,
-			"node_id", nodeID)
+			"node_id", nodeID,
+			"error", err)
+		return nil, err
 	}
 
-	return resolvedConfig
+	c.logger.Debug("resolved config variables",
+		"run_id", runID,
+		"node_id", nodeID,
+		"config", resolvedConfig)
+	return resolvedConfig, nil
+}
+
+// failNodeConfig fails a node whose config could not be loaded or resolved
+func (c *Coordinator) failNodeConfig(ctx context.Context, runID, nodeID string, err error) {
+	c.handleCompletion(ctx, &CompletionSignal{
+		Version: "1.0",
+		JobID:   fmt.Sprintf("%s-%s-config", runID, nodeID),
+		RunID:   runID,
+		NodeID:  nodeID,
+		Status:  "failed",
+		ResultData: map[string]interface{}{
+			"status": "failed",
+			"error":  err.Error(),
+		},
+		Metadata: map[string]interface{}{
+			"error_type":    "ConfigResolutionError",
+			"error_message": err.Error(),
+		},
+	})
 }
 
 // publishToken publishes a token to a Redis stream with resolved config
 func (c *Coordinator) publishToken(ctx context.Context, stream, runID, fromNode, toNode, payloadRef string, resolvedConfig map[string]interface{}, ir *sdk.IR) error {
 	// Generate unique job ID for this token
 	jobID := fmt.Sprintf("%s-%s-%d", runID, toNode, time.Now().UnixNano())
+	return c.publishTokenWithID(ctx, jobID, stream, runID, fromNode, toNode, payloadRef, resolvedConfig, ir)
+}
+
+// publishTokenWithID publishes a token under a caller-chosen job ID
+// Workers echo the job ID in their completion signal, which lets the caller
+// tell apart several executions of the same node (see startMapNode).
+func (c *Coordinator) publishTokenWithID(ctx context.Context, jobID, stream, runID, fromNode, toNode, payloadRef string, resolvedConfig map[string]interface{}, ir *sdk.IR) error {
+	return c.publishTokenAttempt(ctx, 1, jobID, stream, runID, fromNode, toNode, payloadRef, resolvedConfig, ir)
+}
+
+// publishTokenAttempt publishes a token and tracks it for the timeout detector
+// attempt counts dispatches of the same node execution, starting at 1; it is
+// raised when a timed-out token is dispatched again (see handleTimedOutNode).
+func (c *Coordinator) publishTokenAttempt(ctx context.Context, attempt int, jobID, stream, runID, fromNode, toNode, payloadRef string, resolvedConfig map[string]interface{}, ir *sdk.IR) error {
+	// Tokens of prioritized runs go to the stream's priority variant
+	priority, _ := ir.Metadata["priority"].(string)
+	stream = redisWrapper.PriorityStream(stream, priority)
+
+	// The token carries this span to the worker (see redis.Client.AddToStream)
+	nodeType := ""
+	if node, ok := ir.Nodes[toNode]; ok {
+		nodeType = node.Type
+	}
+	ctx, span := tracing.StartSpan(ctx, "token.publish",
+		tracing.AttrRunID, runID,
+		tracing.AttrNodeID, toNode,
+		tracing.AttrNodeType, nodeType,
+		tracing.AttrStream, stream)
+	defer span.End()
 
 	// Debug log the resolvedConfig
 	c.logger.Info("publishToken called",
@@ -110,6 +132,9 @@ func (c *Coordinator) publishToken(ctx context.Context, stream, runID, fromNode,
 		"created_at":  sentAt.Format(time.RFC3339),
 		"sent_at":     sentAt.Format(time.RFC3339Nano), // High precision timestamp for metrics
 	}
+	if priority != "" {
+		token["priority"] = priority
+	}
 
 	// Include resolved config if available
 	if resolvedConfig != nil {
@@ -167,14 +192,52 @@ func (c *Coordinator) publishToken(ctx context.Context, stream, runID, fromNode,
 		return fmt.Errorf("failed to marshal token: %w", err)
 	}
 
-	_, err = c.redisWrapper.AddToStream(ctx, stream, map[string]interface{}{
-		"token":   string(tokenJSON),
-		"run_id":  runID,
-		"to_node": toNode,
-	})
+	// Mark node in flight before publishing so it can be cancelled individually
+	// (set first so a fast worker's completion cannot be overwritten)
+	if _, err := sdk.SetNodeStatus(ctx, c.redis, runID, toNode, sdk.NodeStatusRunning); err != nil {
+		c.logger.Warn("failed to mark node running",
+			"run_id", runID,
+			"node_id", toNode,
+			"error", err)
+	}
 
-	if err != nil {
-		return fmt.Errorf("failed to add to stream: %w", err)
+	ready := &sdk.ReadyToken{
+		JobID:  jobID,
+		Stream: stream,
+		Values: map[string]interface{}{
+			"token":   string(tokenJSON),
+			"run_id":  runID,
+			"to_node": toNode,
+		},
+		Inflight: &sdk.InflightToken{
+			JobID:      jobID,
+			RunID:      runID,
+			NodeID:     toNode,
+			FromNode:   fromNode,
+			PayloadRef: payloadRef,
+			SentAt:     sentAt.Format(time.RFC3339Nano),
+			Attempt:    attempt,
+		},
+	}
+
+	// Runs at their concurrency limit hold the token until a slot is released
+	if limit := sdk.MaxConcurrency(ir); limit > 0 {
+		acquired, err := sdk.AcquireSlot(ctx, c.redis, runID, limit, ready)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			c.logger.Info("run at concurrency limit, holding token",
+				"run_id", runID,
+				"job_id", jobID,
+				"to_node", toNode,
+				"max_concurrency", limit)
+			return nil
+		}
+	}
+
+	if err := c.dispatchReady(ctx, ready, ir); err != nil {
+		return err
 	}
 
 	c.logger.Debug("published token with job_id",
@@ -185,3 +248,46 @@ func (c *Coordinator) publishToken(ctx context.Context, stream, runID, fromNode,
 
 	return nil
 }
+
+// dispatchReady adds a token to its worker stream
+// Tokens with an in-flight record are tracked for the timeout detector from
+// now on, and their node_started event is published.
+func (c *Coordinator) dispatchReady(ctx context.Context, ready *sdk.ReadyToken, ir *sdk.IR) error {
+	if _, err := c.redisWrapper.AddToStream(ctx, ready.Stream, ready.Values); err != nil {
+		return fmt.Errorf("failed to add to stream: %w", err)
+	}
+
+	inflight := ready.Inflight
+	if inflight == nil {
+		return nil
+	}
+
+	// Track the token until its completion arrives (see supervisor.TimeoutDetector);
+	// a token held by the concurrency limit only starts its timeout now
+	inflight.SentAt = time.Now().UTC().Format(time.RFC3339Nano)
+	if err := sdk.TrackInflight(ctx, c.redis, inflight); err != nil {
+		c.logger.Warn("failed to track in-flight token",
+			"run_id", inflight.RunID,
+			"node_id", inflight.NodeID,
+			"job_id", inflight.JobID,
+			"error", err)
+	}
+
+	// Publish node_started event, so live UIs show the node running until it completes
+	if username, ok := ir.Metadata["username"].(string); ok {
+		nodeType := ""
+		if node, ok := ir.Nodes[inflight.NodeID]; ok {
+			nodeType = node.Type
+		}
+		c.lifecycle.EventPublisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
+			"type":      "node_started",
+			"run_id":    inflight.RunID,
+			"node_id":   inflight.NodeID,
+			"node_type": nodeType,
+			"job_id":    inflight.JobID,
+			"attempt":   inflight.Attempt,
+			"timestamp": time.Now().Unix(),
+		})
+	}
+	return nil
+}
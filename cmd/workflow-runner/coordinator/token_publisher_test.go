@@ -0,0 +1,173 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/routing"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConfigTemplates runs fetch → notify, where notify's config templates read
+// fetch's output (see startDataTestRun)
+func TestConfigTemplates(t *testing.T) {
+	schema := func() *compiler.WorkflowSchema {
+		return &compiler.WorkflowSchema{
+			Nodes: []compiler.WorkflowNode{
+				{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/user"}},
+				{ID: "notify", Type: "http", Config: map[string]interface{}{
+					"url":  "https://example.com/users/${output.id}",
+					"body": map[string]interface{}{"email": "${output.email}"},
+				}},
+			},
+			Edges: []compiler.WorkflowEdge{
+				{From: "fetch", To: "notify"},
+			},
+		}
+	}
+	strict := func(opts *CoordinatorOpts) { opts.StrictTemplates = true }
+	notifyConfig := func(run *dataTestRun) map[string]interface{} {
+		var config map[string]interface{}
+		require.Eventually(t, func() bool {
+			for _, msg := range run.rdb.XRange(run.ctx, "wf.tasks.http", "-", "+").Val() {
+				var token sdk.Token
+				require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+				if token.ToNode == "notify" {
+					config = token.Config
+					return true
+				}
+			}
+			return false
+		}, 5*time.Second, 20*time.Millisecond)
+		return config
+	}
+
+	t.Run("resolved against the upstream output", func(t *testing.T) {
+		run := startDataTestRun(t, "run_templates_resolved", schema(), 1, strict)
+		run.complete("fetch", map[string]interface{}{"id": 7, "email": "ada@example.com"})
+
+		config := notifyConfig(run)
+		assert.Equal(t, "https://example.com/users/7", config["url"])
+		assert.Equal(t, map[string]interface{}{"email": "ada@example.com"}, config["body"])
+	})
+
+	t.Run("strict fails the node on a missing path", func(t *testing.T) {
+		run := startDataTestRun(t, "run_templates_strict", schema(), 1, strict)
+		run.complete("fetch", map[string]interface{}{"id": 7})
+
+		run.waitForStatus("notify", sdk.NodeStatusFailed)
+		assert.Empty(t, run.payloadsFor("notify"))
+	})
+
+	t.Run("lenient keeps the template", func(t *testing.T) {
+		run := startDataTestRun(t, "run_templates_lenient", schema(), 1)
+		run.complete("fetch", map[string]interface{}{"id": 7})
+
+		config := notifyConfig(run)
+		assert.Equal(t, map[string]interface{}{"email": "${output.email}"}, config["body"])
+	})
+}
+
+// TestNodeStartedEvent checks that routing a token to a worker stream is
+// announced to the run owner's fanout channel
+func TestNodeStartedEvent(t *testing.T) {
+	run := startDataTestRun(t, "run_node_started", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/user"}},
+			{ID: "notify", Type: "http", Config: map[string]interface{}{"url": "https://example.com/notify"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "fetch", To: "notify"},
+		},
+	}, 1)
+
+	sub := run.rdb.Subscribe(run.ctx, "workflow:events:alice")
+	t.Cleanup(func() { sub.Close() })
+	_, err := sub.Receive(run.ctx)
+	require.NoError(t, err)
+
+	run.complete("fetch", map[string]interface{}{"id": 7})
+
+	var token sdk.Token
+	require.Eventually(t, func() bool {
+		msgs := run.rdb.XRange(run.ctx, "wf.tasks.http", "-", "+").Val()
+		if len(msgs) == 0 {
+			return false
+		}
+		return json.Unmarshal([]byte(msgs[0].Values["token"].(string)), &token) == nil
+	}, 5*time.Second, 20*time.Millisecond)
+
+	event := waitForEvent(t, sub, "node_started")
+	assert.Equal(t, run.runID, event["run_id"])
+	assert.Equal(t, "notify", event["node_id"])
+	assert.Equal(t, "http", event["node_type"])
+	assert.Equal(t, token.ID, event["job_id"])
+	assert.EqualValues(t, 1, event["attempt"])
+	assert.NotZero(t, event["timestamp"])
+}
+
+// TestCustomStreamRouting remaps a worker type's stream and registers a worker
+// for a data operator, and checks their tokens are published there
+func TestCustomStreamRouting(t *testing.T) {
+	routes := func(opts *CoordinatorOpts) {
+		opts.Routes = routing.NewRegistry().
+			Register("webhook", "wf.tasks.webhook.v2").
+			Register("transform", "wf.tasks.transform")
+	}
+	run := startDataTestRun(t, "run_custom_routes", &compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/data"}},
+			{ID: "notify", Type: "webhook", Config: map[string]interface{}{"url": "https://example.com/hook"}},
+			{ID: "shape", Type: "transform", Config: map[string]interface{}{"mapping": map[string]interface{}{"b": "$.a"}}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "fetch", To: "notify"},
+			{From: "fetch", To: "shape"},
+		},
+	}, 1, routes)
+
+	run.complete("fetch", map[string]interface{}{"a": 1})
+
+	for stream, nodeID := range map[string]string{"wf.tasks.webhook.v2": "notify", "wf.tasks.transform": "shape"} {
+		require.Eventually(t, func() bool {
+			for _, msg := range run.rdb.XRange(run.ctx, stream, "-", "+").Val() {
+				var token sdk.Token
+				require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+				if token.ToNode == nodeID {
+					return true
+				}
+			}
+			return false
+		}, 5*time.Second, 20*time.Millisecond, "no token for %s on %s", nodeID, stream)
+	}
+	assert.Zero(t, run.rdb.XLen(run.ctx, "wf.tasks.webhook").Val())
+
+	// Routed to another worker, the transform never reaches the data worker
+	assert.Zero(t, run.rdb.XLen(run.ctx, routing.DataStream).Val())
+	status, _ := run.mr.Get(sdk.NodeStatusKey(run.runID, "shape"))
+	assert.NotEqual(t, sdk.NodeStatusCompleted, status)
+}
+
+// waitForEvent returns the first event of type eventType published on sub
+func waitForEvent(t *testing.T, sub *redis.PubSub, eventType string) map[string]interface{} {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-sub.Channel():
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(msg.Payload), &event))
+			if event["type"] == eventType {
+				return event
+			}
+		case <-deadline:
+			t.Fatalf("%s event was not published", eventType)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTraceContinuesThroughCoordinator checks a worker's node span is the
+// parent of the routing span, whose token publish reaches the next worker
+func TestTraceContinuesThroughCoordinator(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	recorder, restore := tracing.Record()
+	defer restore()
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := NewCoordinator(&CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go coord.Start(ctx)
+
+	runID := "run_tracing_test"
+	ir := &sdk.IR{
+		Version: "1.0",
+		Nodes: map[string]*sdk.Node{
+			"A": {ID: "A", Type: "http", Dependents: []string{"B"}},
+			"B": {ID: "B", Type: "http", Dependencies: []string{"A"}, IsTerminal: true},
+		},
+	}
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	// Worker executing A signals completion from inside its span
+	nodeCtx, execute := tracing.StartSpan(ctx, "node.execute", tracing.AttrRunID, runID, tracing.AttrNodeID, "A")
+	require.NoError(t, worker.SignalCompletion(nodeCtx, rdb, logger, &worker.CompletionOpts{
+		Token:      &sdk.Token{ID: runID + "-A", RunID: runID, ToNode: "A"},
+		Status:     "completed",
+		ResultData: map[string]interface{}{"ok": true},
+	}))
+	execute.End()
+
+	require.Eventually(t, func() bool {
+		return len(recorder.SpansNamed("coordinator.route")) == 1
+	}, 5*time.Second, 20*time.Millisecond)
+
+	route := recorder.SpansNamed("coordinator.route")[0]
+	assert.Equal(t, execute.SpanContext().SpanID(), route.Parent.SpanID())
+	assert.True(t, route.Parent.IsRemote())
+	assert.Equal(t, "http", tracing.Attribute(route, tracing.AttrNodeType))
+
+	publishes := recorder.SpansNamed("token.publish")
+	require.Len(t, publishes, 1)
+	publish := publishes[0]
+	assert.Equal(t, route.SpanContext, publish.Parent)
+	assert.Equal(t, execute.SpanContext().TraceID(), publish.SpanContext.TraceID())
+	assert.Equal(t, "B", tracing.Attribute(publish, tracing.AttrNodeID))
+	assert.Equal(t, "wf.tasks.http", tracing.Attribute(publish, tracing.AttrStream))
+
+	// B's token carries the publish span to its worker
+	messages, err := rdb.XRange(ctx, "wf.tasks.http", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	publishCtx := trace.ContextWithSpanContext(ctx, publish.SpanContext)
+	assert.Equal(t, tracing.TraceParent(publishCtx), messages[0].Values[tracing.TraceParentField])
+}
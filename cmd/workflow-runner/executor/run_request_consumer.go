@@ -7,35 +7,44 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/routing"
 	"github.com/lyzr/orchestrator/common/compiler"
 	"github.com/lyzr/orchestrator/common/sdk"
 	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/config"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
 // RunRequestConsumer listens to wf.run.requests stream and starts workflow execution
 type RunRequestConsumer struct {
-	redis              *redis.Client
+	redis              redis.UniversalClient
 	sdk                *sdk.SDK
 	logger             sdk.Logger
 	stream             string
 	consumerGroup      string
 	consumerName       string
+	workers            int
+	consumer           config.ConsumerConfig
+	routes             *routing.Registry
 	orchestratorClient *clients.OrchestratorClient
 }
 
 // RunRequest represents a workflow execution request
 type RunRequest struct {
-	RunID      string                 `json:"run_id"`
-	ArtifactID string                 `json:"artifact_id"`
-	Tag        string                 `json:"tag"`
-	Username   string                 `json:"username"`
-	Inputs     map[string]interface{} `json:"inputs"`
-	CreatedAt  int64                  `json:"created_at"`
+	RunID          string                 `json:"run_id"`
+	ArtifactID     string                 `json:"artifact_id"`
+	Tag            string                 `json:"tag"`
+	Username       string                 `json:"username"`
+	Inputs         map[string]interface{} `json:"inputs"`
+	Priority       string                 `json:"priority,omitempty"`        // See redis.PriorityStream
+	MaxConcurrency int                    `json:"max_concurrency,omitempty"` // Overrides the workflow's; see sdk.MaxConcurrency
+	CreatedAt      int64                  `json:"created_at"`
 }
 
 // NewRunRequestConsumer creates a new run request consumer
-func NewRunRequestConsumer(redisClient *redis.Client, workflowSDK *sdk.SDK, logger sdk.Logger, orchestratorURL string) *RunRequestConsumer {
+func NewRunRequestConsumer(redisClient redis.UniversalClient, workflowSDK *sdk.SDK, logger sdk.Logger, orchestratorURL string) *RunRequestConsumer {
 	return &RunRequestConsumer{
 		redis:              redisClient,
 		sdk:                workflowSDK,
@@ -43,10 +52,30 @@ func NewRunRequestConsumer(redisClient *redis.Client, workflowSDK *sdk.SDK, logg
 		stream:             "wf.run.requests",
 		consumerGroup:      "run_executors",
 		consumerName:       fmt.Sprintf("executor_%s", uuid.New().String()[:8]),
+		workers:            redisWrapper.DefaultConsumerWorkers,
+		routes:             routing.NewRegistry(),
 		orchestratorClient: clients.NewOrchestratorClient(orchestratorURL, logger),
 	}
 }
 
+// WithWorkers sets how many run requests are processed in parallel
+func (c *RunRequestConsumer) WithWorkers(workers int) *RunRequestConsumer {
+	c.workers = workers
+	return c
+}
+
+// WithConsumerConfig sets the read block and error backoff for run requests
+func (c *RunRequestConsumer) WithConsumerConfig(cfg config.ConsumerConfig) *RunRequestConsumer {
+	c.consumer = cfg
+	return c
+}
+
+// WithRoutes sets the node type → stream mapping for entry tokens
+func (c *RunRequestConsumer) WithRoutes(routes *routing.Registry) *RunRequestConsumer {
+	c.routes = routes
+	return c
+}
+
 // Start begins processing run requests
 func (c *RunRequestConsumer) Start(ctx context.Context) error {
 	c.logger.Info("starting run request consumer",
@@ -60,55 +89,17 @@ func (c *RunRequestConsumer) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
-	// Process messages in a loop
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("run request consumer stopping")
-			return nil
-		default:
-			if err := c.processNextMessage(ctx); err != nil {
-				c.logger.Error("failed to process message", "error", err)
-				time.Sleep(1 * time.Second) // Back off on error
-			}
-		}
-	}
-}
-
-// processNextMessage reads and processes one message from the stream
-func (c *RunRequestConsumer) processNextMessage(ctx context.Context) error {
-	// Read message from stream (XREADGROUP)
-	streams, err := c.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
-		Group:    c.consumerGroup,
-		Consumer: c.consumerName,
-		Streams:  []string{c.stream, ">"},
-		Count:    1,
-		Block:    5 * time.Second,
-	}).Result()
-
-	if err == redis.Nil {
-		// No messages, continue
-		return nil
-	}
-	if err != nil {
-		return fmt.Errorf("XREADGROUP error: %w", err)
-	}
-
-	// Process each message
-	for _, stream := range streams {
-		for _, message := range stream.Messages {
-			if err := c.handleMessage(ctx, message); err != nil {
-				c.logger.Error("failed to handle message", "message_id", message.ID, "error", err)
-				// Continue to next message even if this one fails
-			}
+	// Reclaim requests left unacknowledged by crashed executors
+	// (handleMessage's SETNX idempotency key keeps a run from starting twice)
+	client := redisWrapper.NewClient(c.redis, c.logger)
+	go redisWrapper.NewReclaimer(client, c.stream, c.consumerGroup, c.consumerName, c.handleMessage).Run(ctx)
 
-			// Acknowledge message
-			if err := c.redis.XAck(ctx, c.stream, c.consumerGroup, message.ID).Err(); err != nil {
-				c.logger.Error("failed to ACK message", "message_id", message.ID, "error", err)
-			}
-		}
-	}
+	// Requests are independent, so up to c.workers runs are started in parallel
+	redisWrapper.NewConsumerPool(client, c.stream, c.consumerGroup, c.consumerName, c.handleMessage,
+		redisWrapper.WithConsumerWorkers(c.workers),
+		redisWrapper.WithConsumerConfig(c.consumer)).Run(ctx)
 
+	c.logger.Info("run request consumer stopping")
 	return nil
 }
 
@@ -125,6 +116,13 @@ func (c *RunRequestConsumer) handleMessage(ctx context.Context, message redis.XM
 		return fmt.Errorf("failed to unmarshal run request: %w", err)
 	}
 
+	// Continue the trace started by the orchestrator when it created the run
+	ctx = tracing.ExtractValues(ctx, message.Values)
+	ctx, span := tracing.StartSpan(ctx, "run.start",
+		tracing.AttrRunID, runRequest.RunID,
+		tracing.AttrStream, c.stream)
+	defer span.End()
+
 	c.logger.Info("processing run request",
 		"run_id", runRequest.RunID,
 		"artifact_id", runRequest.ArtifactID,
@@ -160,12 +158,24 @@ func (c *RunRequestConsumer) handleMessage(ctx context.Context, message redis.XM
 		return fmt.Errorf("failed to compile workflow: %w", err)
 	}
 	c.logger.Info("compiled", ir)
+	for _, warning := range ir.Warnings {
+		c.logger.Warn("workflow compiled with warning",
+			"run_id", runRequest.RunID,
+			"warning", warning)
+	}
 	// Store username in IR metadata for event publishing
 	if ir.Metadata == nil {
 		ir.Metadata = make(map[string]interface{})
 	}
 	ir.Metadata["username"] = runRequest.Username
 	ir.Metadata["tag"] = runRequest.Tag
+	// The coordinator routes the run's later tokens by it too
+	if runRequest.Priority != "" {
+		ir.Metadata["priority"] = runRequest.Priority
+	}
+	if runRequest.MaxConcurrency > 0 {
+		ir.Metadata[sdk.MaxConcurrencyKey] = runRequest.MaxConcurrency
+	}
 
 	c.logger.Info("compiled workflow to IR",
 		"run_id", runRequest.RunID,
@@ -178,10 +188,21 @@ func (c *RunRequestConsumer) handleMessage(ctx context.Context, message redis.XM
 	}
 
 	irKey := fmt.Sprintf("ir:%s", runRequest.RunID)
-	if err := c.redis.Set(ctx, irKey, irJSON, 24*time.Hour).Err(); err != nil {
+	if err := c.redis.Set(ctx, irKey, irJSON, sdk.RunStateTTL).Err(); err != nil {
 		return fmt.Errorf("failed to store IR: %w", err)
 	}
 
+	// Record where the IR came from, so the coordinator can rebuild it if it expires mid-run
+	if err := sdk.StoreIRSource(ctx, c.redis, runRequest.RunID, &sdk.IRSource{
+		ArtifactID: runRequest.ArtifactID,
+		Username:   runRequest.Username,
+		Metadata:   ir.Metadata,
+	}); err != nil {
+		c.logger.Warn("failed to store IR source",
+			"run_id", runRequest.RunID,
+			"error", err)
+	}
+
 	// Find entry nodes (nodes with no dependencies)
 	entryNodes := c.findEntryNodes(ir)
 	if len(entryNodes) == 0 {
@@ -242,6 +263,7 @@ func (c *RunRequestConsumer) handleMessage(ctx context.Context, message redis.XM
 			RunID:    runRequest.RunID,
 			FromNode: "",
 			ToNode:   nodeID,
+			Priority: runRequest.Priority,
 			Metadata: metadata,
 		}
 
@@ -252,12 +274,34 @@ func (c *RunRequestConsumer) handleMessage(ctx context.Context, message redis.XM
 		}
 
 		// Route to appropriate stream based on node type
-		stream := c.getStreamForNodeType(node.Type)
+		stream := redisWrapper.PriorityStream(c.getStreamForNodeType(node.Type), runRequest.Priority)
+		values := map[string]interface{}{
+			"token": string(tokenJSON),
+		}
+		tracing.InjectValues(ctx, values)
+
+		// Entry tokens past the run's concurrency limit wait for a slot
+		if limit := sdk.MaxConcurrency(ir); limit > 0 {
+			acquired, err := sdk.AcquireSlot(ctx, c.redis, runRequest.RunID, limit, &sdk.ReadyToken{
+				JobID:  token.ID,
+				Stream: stream,
+				Values: values,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to emit initial token: %w", err)
+			}
+			if !acquired {
+				c.logger.Info("run at concurrency limit, holding initial token",
+					"run_id", runRequest.RunID,
+					"node_id", nodeID,
+					"max_concurrency", limit)
+				continue
+			}
+		}
+
 		err = c.redis.XAdd(ctx, &redis.XAddArgs{
 			Stream: stream,
-			Values: map[string]interface{}{
-				"token": string(tokenJSON),
-			},
+			Values: values,
 		}).Err()
 
 		if err != nil {
@@ -290,6 +334,7 @@ func (c *RunRequestConsumer) handleMessage(ctx context.Context, message redis.XM
 }
 
 // fetchWorkflowFromArtifact fetches frozen workflow from artifact by ID
+// Runs queued against the same artifact are served from the client's cache.
 // Requires: ctx with UserID set via WithUserID()
 func (c *RunRequestConsumer) fetchWorkflowFromArtifact(ctx context.Context, artifactID string) (*compiler.WorkflowSchema, error) {
 	// Use orchestrator client to fetch artifact
@@ -330,19 +375,12 @@ func (c *RunRequestConsumer) findEntryNodes(ir *sdk.IR) []string {
 }
 
 // getStreamForNodeType returns the appropriate stream for a node type
+// Entry nodes of types without a registered worker go to the function runner.
 func (c *RunRequestConsumer) getStreamForNodeType(nodeType string) string {
-	switch nodeType {
-	case "agent":
-		return "wf.tasks.agent"
-	case "http":
-		return "wf.tasks.http"
-	case "hitl":
-		return "wf.tasks.hitl"
-	case "function":
-		return "wf.tasks.function"
-	default:
-		return "wf.tasks.function"
+	if stream, ok := c.routes.Lookup(nodeType); ok {
+		return stream
 	}
+	return c.routes.StreamFor("function")
 }
 
 // publishWorkflowEvent publishes an event to Redis PubSub for fanout service
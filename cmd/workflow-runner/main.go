@@ -11,11 +11,13 @@ import (
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/consumer"
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/coordinator"
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/executor"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/routing"
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/supervisor"
 	"github.com/lyzr/orchestrator/common/bootstrap"
 	"github.com/lyzr/orchestrator/common/clients"
 	"github.com/lyzr/orchestrator/common/ratelimit"
 	"github.com/lyzr/orchestrator/common/sdk"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -47,7 +49,7 @@ func main() {
 	errChan := startComponents(ctx, workflowComponents, components)
 
 	components.Logger.Info("workflow-runner started successfully",
-		"components", []string{"coordinator", "run_request_consumer", "status_update_consumer"},
+		"components", []string{"coordinator", "data_worker", "run_request_consumer", "status_update_consumer", "timeout_detector"},
 		"note", "workers (http, hitl) now run as separate services")
 
 	// Wait for shutdown signal or error
@@ -58,7 +60,8 @@ func main() {
 
 // dependencies holds all external dependencies needed by workflow components
 type dependencies struct {
-	redisClient     *redis.Client
+	redisClient     redis.UniversalClient
+	redisConfig     *rediscommon.Config
 	casClient       clients.CASClient
 	workflowSDK     *sdk.SDK
 	orchestratorURL string
@@ -67,15 +70,24 @@ type dependencies struct {
 
 // workflowComponents holds all workflow-runner components
 type workflowComponents struct {
-	coordinator    *coordinator.Coordinator
-	runConsumer    *executor.RunRequestConsumer
-	statusConsumer *consumer.StatusUpdateConsumer
+	coordinator          *coordinator.Coordinator
+	dataWorker           *coordinator.DataWorker
+	runConsumer          *executor.RunRequestConsumer
+	statusConsumer       *consumer.StatusUpdateConsumer
+	timeoutDetector      *supervisor.TimeoutDetector
+	completionSupervisor *supervisor.CompletionSupervisor
+	stallDetector        *supervisor.StallDetector
 }
 
 // initializeDependencies sets up Redis, CAS client, and SDK
 func initializeDependencies(ctx context.Context, components *bootstrap.Components) (*dependencies, error) {
 	// Create Redis client
-	redisClient, err := createRedisClient(components)
+	redisConfig, err := rediscommon.ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis config: %w", err)
+	}
+
+	redisClient, err := rediscommon.NewUniversalClient(redisConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Redis client: %w", err)
 	}
@@ -92,20 +104,25 @@ func initializeDependencies(ctx context.Context, components *bootstrap.Component
 		return nil, fmt.Errorf("failed to load Lua script: %w", err)
 	}
 
-	// Create Redis-based CAS client for storing execution results
-	casClient := clients.NewRedisCASClient(redisClient, components.Logger)
+	// Create CAS client for storing execution results (backend from CAS_BACKEND)
+	casClient, err := clients.NewCASClient(components.Config.CAS, redisClient, components.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CAS client: %w", err)
+	}
 
 	// Create SDK
 	workflowSDK := sdk.NewSDK(redisClient, casClient, components.Logger, string(luaScript))
 
 	// Create rate limiter for dynamic agent checks
-	rateLimiter := ratelimit.NewRateLimiter(redisClient, components.Logger)
+	rateLimiter := ratelimit.NewRateLimiter(redisClient, components.Logger).
+		WithAlgorithm(ratelimit.Algorithm(components.Config.RateLimit.Algorithm))
 
 	// Get orchestrator URL
 	orchestratorURL := getEnv("ORCHESTRATOR_URL", "http://localhost:8081")
 
 	return &dependencies{
 		redisClient:     redisClient,
+		redisConfig:     redisConfig,
 		casClient:       casClient,
 		workflowSDK:     workflowSDK,
 		orchestratorURL: orchestratorURL,
@@ -115,31 +132,49 @@ func initializeDependencies(ctx context.Context, components *bootstrap.Component
 
 // createWorkflowComponents initializes all workflow-runner components
 func createWorkflowComponents(deps *dependencies, components *bootstrap.Components) *workflowComponents {
-	// TODO: Create and start supervisors when needed
-	// For MVP integration tests, we only need the coordinator
-	_ = supervisor.NewCompletionSupervisor // Avoid unused import error
-	_ = supervisor.NewTimeoutDetector
-
 	// Create run repository for status updates
 	runRepo := repository.NewRunRepository(components.DB)
 
+	// Node type → worker stream: built-ins plus NODE_TYPE_STREAMS
+	routes := routing.NewRegistry().RegisterAll(components.Config.Routing.Streams)
+
+	coord := coordinator.NewCoordinator(&coordinator.CoordinatorOpts{
+		Redis:               deps.redisClient,
+		RedisOpTimeout:      deps.redisConfig.OperationTimeout,
+		SDK:                 deps.workflowSDK,
+		Logger:              components.Logger,
+		OrchestratorBaseURL: deps.orchestratorURL,
+		CASClient:           deps.casClient,
+		RateLimiter:         deps.rateLimiter,
+		StrictTemplates:     components.Config.Templates.Strict,
+		RunStateRetention:   components.Config.RunState.Retention,
+		Routes:              routes,
+	})
+
 	return &workflowComponents{
-		coordinator: coordinator.NewCoordinator(&coordinator.CoordinatorOpts{
-			Redis:               deps.redisClient,
-			SDK:                 deps.workflowSDK,
-			Logger:              components.Logger,
-			OrchestratorBaseURL: deps.orchestratorURL,
-			CASClient:           deps.casClient,
-			RateLimiter:         deps.rateLimiter,
-		}),
-		runConsumer:    executor.NewRunRequestConsumer(deps.redisClient, deps.workflowSDK, components.Logger, deps.orchestratorURL),
-		statusConsumer: consumer.NewStatusUpdateConsumer(deps.redisClient, runRepo, components.Logger),
+		coordinator: coord,
+		// Transform, filter and aggregate nodes, off the coordinator's loop
+		dataWorker: coordinator.NewDataWorker(coord).
+			WithWorkers(components.Config.Service.Workers),
+		runConsumer: executor.NewRunRequestConsumer(deps.redisClient, deps.workflowSDK, components.Logger, deps.orchestratorURL).
+			WithWorkers(components.Config.Service.Workers).
+			WithConsumerConfig(components.Config.Consumer).
+			WithRoutes(routes),
+		// Status updates stay serial: a run's updates must be applied in order
+		statusConsumer: consumer.NewStatusUpdateConsumer(deps.redisClient, runRepo, components.Logger).
+			WithConsumerConfig(components.Config.Consumer),
+		timeoutDetector: supervisor.NewTimeoutDetector(deps.redisClient, components.Logger),
+		// Persists COMPLETED once a run's counter reaches zero
+		completionSupervisor: supervisor.NewCompletionSupervisor(deps.redisClient, runRepo, components.Logger),
+		stallDetector: supervisor.NewStallDetector(deps.redisClient, runRepo, components.Logger).
+			WithThreshold(components.Config.RunState.StallThreshold).
+			WithStatusUpdate(components.Config.RunState.MarkStalled),
 	}
 }
 
 // startComponents starts all workflow components in goroutines
 func startComponents(ctx context.Context, wc *workflowComponents, components *bootstrap.Components) chan error {
-	errChan := make(chan error, 3) // Reduced to 3 (coordinator, run consumer, status consumer)
+	errChan := make(chan error, 7) // coordinator, data worker, run consumer, status consumer, timeout detector, completion supervisor, stall detector
 
 	// Start coordinator
 	go func() {
@@ -149,6 +184,14 @@ func startComponents(ctx context.Context, wc *workflowComponents, components *bo
 		}
 	}()
 
+	// Start data worker
+	go func() {
+		components.Logger.Info("starting data worker")
+		if err := wc.dataWorker.Start(ctx); err != nil && err != context.Canceled {
+			errChan <- fmt.Errorf("data worker error: %w", err)
+		}
+	}()
+
 	// HTTP worker now runs as separate service (cmd/http-worker)
 	// Start with: make start-http-worker
 
@@ -171,6 +214,30 @@ func startComponents(ctx context.Context, wc *workflowComponents, components *bo
 		}
 	}()
 
+	// Start timeout detector
+	go func() {
+		components.Logger.Info("starting timeout detector")
+		if err := wc.timeoutDetector.Start(ctx); err != nil && err != context.Canceled {
+			errChan <- fmt.Errorf("timeout detector error: %w", err)
+		}
+	}()
+
+	// Start completion supervisor
+	go func() {
+		components.Logger.Info("starting completion supervisor")
+		if err := wc.completionSupervisor.Start(ctx); err != nil && err != context.Canceled {
+			errChan <- fmt.Errorf("completion supervisor error: %w", err)
+		}
+	}()
+
+	// Start stall detector
+	go func() {
+		components.Logger.Info("starting stall detector")
+		if err := wc.stallDetector.Start(ctx); err != nil && err != context.Canceled {
+			errChan <- fmt.Errorf("stall detector error: %w", err)
+		}
+	}()
+
 	return errChan
 }
 
@@ -189,23 +256,6 @@ func waitForShutdown(ctx context.Context, cancel context.CancelFunc, errChan cha
 	}
 }
 
-// createRedisClient creates a Redis client from config
-func createRedisClient(components *bootstrap.Components) (*redis.Client, error) {
-	// Get Redis config from environment or use defaults
-	redisHost := getEnv("REDIS_HOST", "localhost")
-	redisPort := getEnv("REDIS_PORT", "6379")
-	redisPassword := getEnv("REDIS_PASSWORD", "")
-	redisDB := 0 // Use database 0
-
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
-		Password: redisPassword,
-		DB:       redisDB,
-	})
-
-	return client, nil
-}
-
 // getEnv gets an environment variable or returns a default
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
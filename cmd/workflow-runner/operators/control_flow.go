@@ -2,7 +2,11 @@ package operators
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/lyzr/orchestrator/cmd/workflow-runner/condition"
 	"github.com/lyzr/orchestrator/common/sdk"
@@ -33,16 +37,23 @@ type CompletionSignal struct {
 type ControlFlowRouter struct {
 	loopOperator   *LoopOperator
 	branchOperator *BranchOperator
+	skips          SkipRecorder
 }
 
 // NewControlFlowRouter creates a new control flow router
-func NewControlFlowRouter(redis *redis.Client, workflowSDK *sdk.SDK, evaluator *condition.Evaluator, logger Logger) *ControlFlowRouter {
+// skips receives every routing decision that leaves a node unrun; it may be nil.
+func NewControlFlowRouter(redis redis.UniversalClient, workflowSDK *sdk.SDK, evaluator *condition.Evaluator, skips SkipRecorder, logger Logger) *ControlFlowRouter {
 	// Wrap Redis client for better abstractions
 	redisWrapper := redisWrapper.NewClient(redis, logger)
 
+	if skips == nil {
+		skips = noopSkipRecorder{}
+	}
+
 	return &ControlFlowRouter{
-		loopOperator:   NewLoopOperator(redisWrapper, workflowSDK, evaluator, logger),
-		branchOperator: NewBranchOperator(workflowSDK, evaluator, logger),
+		loopOperator:   NewLoopOperator(redisWrapper, workflowSDK, evaluator, skips, logger),
+		branchOperator: NewBranchOperator(workflowSDK, evaluator, skips, logger),
+		skips:          skips,
 	}
 }
 
@@ -58,7 +69,21 @@ func (r *ControlFlowRouter) DetermineNextNodes(ctx context.Context, signal *Comp
 		return r.branchOperator.HandleBranch(ctx, signal, node)
 	}
 
-	// 3. Default: static dependents
+	// 3. Filters that rejected their input drop the token
+	if filterRejected(signal, node) {
+		reason, _ := signal.Metadata[MetadataFilterReason].(string)
+		for _, nodeID := range node.Dependents {
+			r.skips.RecordSkip(ctx, signal.RunID, &SkipDecision{
+				NodeID:   nodeID,
+				FromNode: signal.NodeID,
+				Reason:   SkipReasonFilterRejected,
+				Message:  "filter dropped the token: " + reason,
+			})
+		}
+		return nil, nil
+	}
+
+	// 4. Default: static dependents
 	return node.Dependents, nil
 }
 
@@ -67,20 +92,25 @@ type LoopOperator struct {
 	redis     *redisWrapper.Client
 	sdk       *sdk.SDK
 	evaluator *condition.Evaluator
+	skips     SkipRecorder
 	logger    Logger
 }
 
 // NewLoopOperator creates a new loop operator
-func NewLoopOperator(redis *redisWrapper.Client, workflowSDK *sdk.SDK, evaluator *condition.Evaluator, logger Logger) *LoopOperator {
+func NewLoopOperator(redis *redisWrapper.Client, workflowSDK *sdk.SDK, evaluator *condition.Evaluator, skips SkipRecorder, logger Logger) *LoopOperator {
 	return &LoopOperator{
 		redis:     redis,
 		sdk:       workflowSDK,
 		evaluator: evaluator,
+		skips:     skips,
 		logger:    logger,
 	}
 }
 
 // HandleLoop determines next nodes for loop configuration
+// Every iteration is appended to the loop's history (sdk.LoopHistoryKey) with
+// the decision taken, and the condition can read earlier iterations' outputs
+// as history.
 func (o *LoopOperator) HandleLoop(ctx context.Context, signal *CompletionSignal, node *sdk.Node) ([]string, error) {
 	loopKey := fmt.Sprintf("loop:%s:%s", signal.RunID, signal.NodeID)
 
@@ -89,6 +119,12 @@ func (o *LoopOperator) HandleLoop(ctx context.Context, signal *CompletionSignal,
 	if err != nil {
 		return nil, fmt.Errorf("failed to increment loop iteration: %w", err)
 	}
+	if iteration == 1 {
+		// Entering the loop created its state
+		if err := sdk.TrackRunKeys(ctx, o.redis.GetUnderlying(), signal.RunID, loopKey); err != nil {
+			o.logger.Warn("failed to track loop state", "run_id", signal.RunID, "node_id", signal.NodeID, "error", err)
+		}
+	}
 
 	o.logger.Debug("loop iteration",
 		"run_id", signal.RunID,
@@ -104,6 +140,18 @@ func (o *LoopOperator) HandleLoop(ctx context.Context, signal *CompletionSignal,
 			"iterations", iteration)
 		// Cleanup loop state
 		o.redis.Delete(ctx, loopKey)
+		message := fmt.Sprintf("loop stopped after %d of %d iterations", iteration, node.Loop.MaxIterations)
+		o.recordIteration(ctx, signal, iteration, sdk.LoopDecisionMaxIterations, message)
+		o.skips.RecordSkip(ctx, signal.RunID, &SkipDecision{
+			NodeID:   node.Loop.LoopBackTo,
+			FromNode: signal.NodeID,
+			Reason:   SkipReasonLoopMaxIterations,
+			Message:  message,
+			Details: map[string]interface{}{
+				"iteration":      iteration,
+				"max_iterations": node.Loop.MaxIterations,
+			},
+		})
 		// Exit to timeout_path
 		return node.Loop.TimeoutPath, nil
 	}
@@ -119,6 +167,7 @@ func (o *LoopOperator) HandleLoop(ctx context.Context, signal *CompletionSignal,
 				"error", err)
 			// On error, break loop
 			o.redis.Delete(ctx, loopKey)
+			o.recordIteration(ctx, signal, iteration, sdk.LoopDecisionBreak, "failed to load output: "+err.Error())
 			return node.Loop.BreakPath, nil
 		}
 
@@ -131,8 +180,24 @@ func (o *LoopOperator) HandleLoop(ctx context.Context, signal *CompletionSignal,
 			context = make(map[string]interface{})
 		}
 
-		// Evaluate condition
-		conditionMet, err := o.evaluator.Evaluate(node.Loop.Condition, output, context)
+		// Load run variables
+		vars, err := o.sdk.GetVars(ctx, signal.RunID)
+		if err != nil {
+			o.logger.Warn("failed to load vars for loop condition",
+				"run_id", signal.RunID,
+				"error", err)
+			vars = make(map[string]interface{})
+		}
+
+		// Evaluate condition, failing it if a node it references has no output yet
+		var evaluation *condition.Evaluation
+		nodes, err := loadConditionNodes(ctx, o.sdk, signal.RunID, node.Loop.Condition)
+		if err != nil {
+			evaluation = &condition.Evaluation{Expression: node.Loop.Condition.Expression, Error: err.Error()}
+		} else {
+			evaluation, err = o.evaluator.EvaluateLoopCondition(node.Loop.Condition, output, context, vars, nodes,
+				iteration, o.loadHistory(ctx, signal, node))
+		}
 		if err != nil {
 			o.logger.Error("loop condition evaluation failed",
 				"run_id", signal.RunID,
@@ -141,8 +206,11 @@ func (o *LoopOperator) HandleLoop(ctx context.Context, signal *CompletionSignal,
 				"error", err)
 			// On error, break loop
 			o.redis.Delete(ctx, loopKey)
+			o.recordIteration(ctx, signal, iteration, sdk.LoopDecisionBreak, describeEvaluation(evaluation))
+			o.recordLoopExit(ctx, signal, node, iteration, evaluation)
 			return node.Loop.BreakPath, nil
 		}
+		conditionMet := evaluation.Result
 
 		o.logger.Debug("loop condition evaluated",
 			"run_id", signal.RunID,
@@ -151,30 +219,143 @@ func (o *LoopOperator) HandleLoop(ctx context.Context, signal *CompletionSignal,
 
 		if conditionMet {
 			// Continue looping
+			o.recordIteration(ctx, signal, iteration, sdk.LoopDecisionContinue, describeEvaluation(evaluation))
 			return []string{node.Loop.LoopBackTo}, nil
 		}
 
 		// Condition not met, break loop
 		o.redis.Delete(ctx, loopKey)
+		o.recordIteration(ctx, signal, iteration, sdk.LoopDecisionBreak, describeEvaluation(evaluation))
+		o.recordLoopExit(ctx, signal, node, iteration, evaluation)
 		return node.Loop.BreakPath, nil
 	}
 
 	// No condition, continue looping (will eventually hit max iterations)
+	o.recordIteration(ctx, signal, iteration, sdk.LoopDecisionContinue, "")
 	return []string{node.Loop.LoopBackTo}, nil
 }
 
+// loadConditionNodes loads the outputs of the nodes a condition references through `nodes`
+func loadConditionNodes(ctx context.Context, workflowSDK *sdk.SDK, runID string, cond *sdk.Condition) (map[string]interface{}, error) {
+	return condition.ConditionNodes(cond, func(nodeID string) (interface{}, error) {
+		return workflowSDK.LoadNodeOutput(ctx, runID, nodeID)
+	})
+}
+
+// historyPattern matches conditions reading the history variable
+var historyPattern = regexp.MustCompile(`\bhistory\b`)
+
+// loadHistory returns the outputs of the iterations before this one, oldest first
+// Only iterations since the loop was last entered count, and they are only
+// loaded when the condition reads history.
+func (o *LoopOperator) loadHistory(ctx context.Context, signal *CompletionSignal, node *sdk.Node) []interface{} {
+	if !historyPattern.MatchString(node.Loop.Condition.Expression) {
+		return nil
+	}
+
+	history, err := o.sdk.LoadLoopHistory(ctx, signal.RunID, signal.NodeID)
+	if err != nil {
+		o.logger.Warn("failed to load loop history for loop condition",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"error", err)
+	}
+
+	outputs := make([]interface{}, 0, len(history))
+	for _, previous := range history {
+		if previous.Decision != sdk.LoopDecisionContinue {
+			// An earlier pass of the loop ended here
+			outputs = outputs[:0]
+			continue
+		}
+		output, err := o.sdk.LoadPayload(ctx, previous.ResultRef)
+		if err != nil {
+			o.logger.Warn("failed to load output of earlier loop iteration",
+				"run_id", signal.RunID,
+				"node_id", signal.NodeID,
+				"iteration", previous.Iteration,
+				"error", err)
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
+
+// recordIteration appends an iteration and the decision taken to the loop's history
+func (o *LoopOperator) recordIteration(ctx context.Context, signal *CompletionSignal, iteration int64, decision, reason string) {
+	err := o.sdk.AppendLoopIteration(ctx, signal.RunID, signal.NodeID, &sdk.LoopIteration{
+		Iteration:   iteration,
+		ResultRef:   signal.ResultRef,
+		Decision:    decision,
+		Reason:      reason,
+		CompletedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		o.logger.Warn("failed to record loop iteration",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"iteration", iteration,
+			"error", err)
+	}
+
+	o.recordExitReason(ctx, signal, iteration, decision)
+}
+
+// recordExitReason keeps why the loop last exited for the node's status
+// The first iteration clears a reason left by an earlier pass through the loop.
+func (o *LoopOperator) recordExitReason(ctx context.Context, signal *CompletionSignal, iteration int64, decision string) {
+	var err error
+	switch decision {
+	case sdk.LoopDecisionBreak:
+		err = o.sdk.RecordLoopExit(ctx, signal.RunID, signal.NodeID, sdk.LoopExitBreak)
+	case sdk.LoopDecisionMaxIterations:
+		err = o.sdk.RecordLoopExit(ctx, signal.RunID, signal.NodeID, sdk.LoopExitTimeout)
+	default:
+		if iteration == 1 {
+			err = o.sdk.ClearLoopExit(ctx, signal.RunID, signal.NodeID)
+		}
+	}
+	if err != nil {
+		o.logger.Warn("failed to record loop exit reason",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"decision", decision,
+			"error", err)
+	}
+}
+
+// recordLoopExit records that the loop body was skipped because the condition didn't hold
+func (o *LoopOperator) recordLoopExit(ctx context.Context, signal *CompletionSignal, node *sdk.Node, iteration int64, evaluation *condition.Evaluation) {
+	o.skips.RecordSkip(ctx, signal.RunID, &SkipDecision{
+		NodeID:    node.Loop.LoopBackTo,
+		FromNode:  signal.NodeID,
+		Reason:    SkipReasonLoopConditionFalse,
+		Message:   "loop exited: " + describeEvaluation(evaluation),
+		Condition: evaluation,
+		Details: map[string]interface{}{
+			"iteration": iteration,
+		},
+	})
+}
+
+// ErrNoBranchMatched is returned when no branch rule matches and there is no default path
+// The branch node fails rather than routing nowhere.
+var ErrNoBranchMatched = errors.New("no branch matched")
+
 // BranchOperator handles conditional branch evaluation
 type BranchOperator struct {
 	sdk       *sdk.SDK
 	evaluator *condition.Evaluator
+	skips     SkipRecorder
 	logger    Logger
 }
 
 // NewBranchOperator creates a new branch operator
-func NewBranchOperator(workflowSDK *sdk.SDK, evaluator *condition.Evaluator, logger Logger) *BranchOperator {
+func NewBranchOperator(workflowSDK *sdk.SDK, evaluator *condition.Evaluator, skips SkipRecorder, logger Logger) *BranchOperator {
 	return &BranchOperator{
 		sdk:       workflowSDK,
 		evaluator: evaluator,
+		skips:     skips,
 		logger:    logger,
 	}
 }
@@ -189,7 +370,7 @@ func (o *BranchOperator) HandleBranch(ctx context.Context, signal *CompletionSig
 			"node_id", signal.NodeID,
 			"error", err)
 		// On error, use default path
-		return node.Branch.Default, nil
+		return o.routeDefault(ctx, signal, node, nil, fmt.Sprintf("branch output could not be loaded: %v", err))
 	}
 
 	// Load context
@@ -201,7 +382,17 @@ func (o *BranchOperator) HandleBranch(ctx context.Context, signal *CompletionSig
 		context = make(map[string]interface{})
 	}
 
+	// Load run variables
+	vars, err := o.sdk.GetVars(ctx, signal.RunID)
+	if err != nil {
+		o.logger.Warn("failed to load vars for branch condition",
+			"run_id", signal.RunID,
+			"error", err)
+		vars = make(map[string]interface{})
+	}
+
 	// Evaluate rules in order
+	evaluations := make([]*condition.Evaluation, len(node.Branch.Rules))
 	for i, rule := range node.Branch.Rules {
 		if rule.Condition == nil {
 			o.logger.Warn("branch rule has nil condition, skipping",
@@ -211,7 +402,14 @@ func (o *BranchOperator) HandleBranch(ctx context.Context, signal *CompletionSig
 			continue
 		}
 
-		conditionMet, err := o.evaluator.Evaluate(rule.Condition, output, context)
+		var evaluation *condition.Evaluation
+		nodes, err := loadConditionNodes(ctx, o.sdk, signal.RunID, rule.Condition)
+		if err != nil {
+			evaluation = &condition.Evaluation{Expression: rule.Condition.Expression, Error: err.Error()}
+		} else {
+			evaluation, err = o.evaluator.EvaluateBranchCondition(rule.Condition, output, context, vars, nodes)
+		}
+		evaluations[i] = evaluation
 		if err != nil {
 			o.logger.Warn("branch rule evaluation failed",
 				"run_id", signal.RunID,
@@ -221,6 +419,7 @@ func (o *BranchOperator) HandleBranch(ctx context.Context, signal *CompletionSig
 				"error", err)
 			continue
 		}
+		conditionMet := evaluation.Result
 
 		o.logger.Debug("branch rule evaluated",
 			"run_id", signal.RunID,
@@ -234,14 +433,88 @@ func (o *BranchOperator) HandleBranch(ctx context.Context, signal *CompletionSig
 				"node_id", signal.NodeID,
 				"rule_index", i,
 				"next_nodes", rule.NextNodes)
+			o.recordBranchSkips(ctx, signal, node, rule.NextNodes, evaluations,
+				fmt.Sprintf("rule %d matched: %s", i, describeEvaluation(evaluation)))
 			return rule.NextNodes, nil
 		}
 	}
 
 	// No rule matched, use default
+	return o.routeDefault(ctx, signal, node, evaluations, "no branch rule matched")
+}
+
+// routeDefault routes to the default path when no rule was taken
+// Without a default path (no unconditional edges), a branch with rules has
+// nowhere to go: ErrNoBranchMatched.
+func (o *BranchOperator) routeDefault(ctx context.Context, signal *CompletionSignal, node *sdk.Node, evaluations []*condition.Evaluation, decision string) ([]string, error) {
+	o.recordBranchSkips(ctx, signal, node, node.Branch.Default, evaluations, decision)
+
+	if len(node.Branch.Default) == 0 && len(node.Branch.Rules) > 0 {
+		o.logger.Warn("no branch rule matched and no default path",
+			"run_id", signal.RunID,
+			"node_id", signal.NodeID,
+			"reason", decision)
+		return nil, fmt.Errorf("%w: %s", ErrNoBranchMatched, describeNoMatch(evaluations, decision))
+	}
+
 	o.logger.Debug("no branch rule matched, using default",
 		"run_id", signal.RunID,
 		"node_id", signal.NodeID,
 		"default", node.Branch.Default)
 	return node.Branch.Default, nil
 }
+
+// describeNoMatch explains why no branch rule was taken
+func describeNoMatch(evaluations []*condition.Evaluation, decision string) string {
+	var failed []string
+	for _, evaluation := range evaluations {
+		if evaluation != nil {
+			failed = append(failed, describeEvaluation(evaluation))
+		}
+	}
+	if len(failed) == 0 {
+		return decision
+	}
+	return fmt.Sprintf("%s (%s)", decision, strings.Join(failed, "; "))
+}
+
+// recordBranchSkips records every branch target that is not in taken
+// A target skipped by a rule that was evaluated carries that rule's condition;
+// otherwise (later rules, default path) the decision that won is explained.
+func (o *BranchOperator) recordBranchSkips(ctx context.Context, signal *CompletionSignal, node *sdk.Node, taken []string, evaluations []*condition.Evaluation, decision string) {
+	skipped := make(map[string]bool)
+	for _, nodeID := range taken {
+		skipped[nodeID] = true // Mark taken targets as handled
+	}
+
+	record := func(nodeID string, evaluation *condition.Evaluation, message string) {
+		if nodeID == "" || skipped[nodeID] {
+			return
+		}
+		skipped[nodeID] = true
+		o.skips.RecordSkip(ctx, signal.RunID, &SkipDecision{
+			NodeID:    nodeID,
+			FromNode:  signal.NodeID,
+			Reason:    SkipReasonBranchNotTaken,
+			Message:   message,
+			Condition: evaluation,
+		})
+	}
+
+	for i, rule := range node.Branch.Rules {
+		var evaluation *condition.Evaluation
+		if i < len(evaluations) {
+			evaluation = evaluations[i]
+		}
+		for _, nodeID := range rule.NextNodes {
+			if evaluation != nil && !evaluation.Result {
+				record(nodeID, evaluation, "branch not taken: "+describeEvaluation(evaluation))
+			} else {
+				record(nodeID, nil, "branch not taken: "+decision)
+			}
+		}
+	}
+	for _, nodeID := range node.Branch.Default {
+		record(nodeID, nil, "default branch not taken: "+decision)
+	}
+}
@@ -0,0 +1,430 @@
+package operators
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/condition"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (noopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// skipCollector records skip decisions for assertions
+type skipCollector struct {
+	mu    sync.Mutex
+	skips []*SkipDecision
+}
+
+func (c *skipCollector) RecordSkip(ctx context.Context, runID string, skip *SkipDecision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skips = append(c.skips, skip)
+}
+
+func (c *skipCollector) byNode() map[string]*SkipDecision {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make(map[string]*SkipDecision, len(c.skips))
+	for _, skip := range c.skips {
+		result[skip.NodeID] = skip
+	}
+	return result
+}
+
+// newTestRouter builds a router over miniredis and stores output as the signal's result
+func newTestRouter(t *testing.T, output map[string]interface{}) (*ControlFlowRouter, *skipCollector, *CompletionSignal) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, "")
+	resultRef, err := workflowSDK.StoreOutput(context.Background(), output)
+	require.NoError(t, err)
+
+	skips := &skipCollector{}
+	router := NewControlFlowRouter(rdb, workflowSDK, condition.NewEvaluator(), skips, logger)
+
+	return router, skips, &CompletionSignal{RunID: "run_skip_test", NodeID: "check", ResultRef: resultRef}
+}
+
+func celCondition(expr string) *sdk.Condition {
+	return &sdk.Condition{Type: "cel", Expression: expr}
+}
+
+func TestBranchRecordsSkippedTargets(t *testing.T) {
+	ctx := context.Background()
+	router, skips, signal := newTestRouter(t, map[string]interface{}{"score": 50})
+
+	node := &sdk.Node{
+		ID: "check",
+		Branch: &sdk.BranchConfig{
+			Enabled: true,
+			Rules: []sdk.BranchRule{
+				{Condition: celCondition("output.score >= 80"), NextNodes: []string{"approve"}},
+				{Condition: celCondition("$.score >= 40"), NextNodes: []string{"review"}},
+				{Condition: celCondition("output.score >= 0"), NextNodes: []string{"archive"}},
+			},
+			Default: []string{"reject"},
+		},
+	}
+
+	next, err := router.DetermineNextNodes(ctx, signal, node, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"review"}, next)
+
+	recorded := skips.byNode()
+	require.Len(t, recorded, 3)
+	assert.NotContains(t, recorded, "review")
+
+	// The failed rule explains itself with the evaluated value
+	approve := recorded["approve"]
+	require.NotNil(t, approve)
+	assert.Equal(t, SkipReasonBranchNotTaken, approve.Reason)
+	assert.Equal(t, "check", approve.FromNode)
+	require.NotNil(t, approve.Condition)
+	assert.Equal(t, "output.score >= 80", approve.Condition.Expression)
+	assert.False(t, approve.Condition.Result)
+	assert.EqualValues(t, 50, approve.Condition.Values["output.score"])
+	assert.Equal(t, "branch not taken: output.score >= 80 was false (output.score = 50)", approve.Message)
+
+	// Rules after the match and the default point at the rule that won
+	for _, nodeID := range []string{"archive", "reject"} {
+		skip := recorded[nodeID]
+		require.NotNil(t, skip, nodeID)
+		assert.Equal(t, SkipReasonBranchNotTaken, skip.Reason)
+		assert.Nil(t, skip.Condition)
+		assert.Contains(t, skip.Message, "rule 1 matched: $.score >= 40 was true (output.score = 50)")
+	}
+}
+
+func TestBranchDefaultRecordsFailedRules(t *testing.T) {
+	ctx := context.Background()
+	router, skips, signal := newTestRouter(t, map[string]interface{}{"approved": false})
+
+	node := &sdk.Node{
+		ID: "check",
+		Branch: &sdk.BranchConfig{
+			Enabled: true,
+			Rules: []sdk.BranchRule{
+				{Condition: celCondition("output.approved == true"), NextNodes: []string{"ship"}},
+			},
+			Default: []string{"fix"},
+		},
+	}
+
+	next, err := router.DetermineNextNodes(ctx, signal, node, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fix"}, next)
+
+	recorded := skips.byNode()
+	require.Len(t, recorded, 1)
+	require.NotNil(t, recorded["ship"].Condition)
+	assert.Equal(t, false, recorded["ship"].Condition.Values["output.approved"])
+}
+
+func TestBranchNoMatchWithoutDefault(t *testing.T) {
+	ctx := context.Background()
+	router, skips, signal := newTestRouter(t, map[string]interface{}{"score": 10})
+
+	node := &sdk.Node{
+		ID: "check",
+		Branch: &sdk.BranchConfig{
+			Enabled: true,
+			Rules: []sdk.BranchRule{
+				{Condition: celCondition("output.score > 80"), NextNodes: []string{"high"}},
+				{Condition: celCondition("output.score > 40"), NextNodes: []string{"medium"}},
+			},
+		},
+	}
+
+	next, err := router.DetermineNextNodes(ctx, signal, node, nil)
+	require.ErrorIs(t, err, ErrNoBranchMatched)
+	assert.Empty(t, next)
+	assert.Equal(t, "no branch matched: no branch rule matched (output.score > 80 was false (output.score = 10); output.score > 40 was false (output.score = 10))", err.Error())
+
+	// Both targets are still recorded as not taken
+	assert.Len(t, skips.byNode(), 2)
+
+	// A branch node without rules (no out-edges) just ends its path
+	next, err = router.DetermineNextNodes(ctx, signal, &sdk.Node{ID: "check", Branch: &sdk.BranchConfig{Enabled: true}}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, next)
+}
+
+func TestBranchComparesUpstreamOutputs(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, "")
+	runID := "run_upstream_test"
+
+	// score_a and score_b completed before check, whose own output is unused
+	for nodeID, output := range map[string]interface{}{
+		"score_a": map[string]interface{}{"score": 72},
+		"score_b": map[string]interface{}{"score": 65},
+	} {
+		ref, err := workflowSDK.StoreOutput(ctx, output)
+		require.NoError(t, err)
+		require.NoError(t, workflowSDK.StoreContext(ctx, runID, nodeID, ref))
+	}
+	resultRef, err := workflowSDK.StoreOutput(ctx, map[string]interface{}{"ok": true})
+	require.NoError(t, err)
+
+	skips := &skipCollector{}
+	router := NewControlFlowRouter(rdb, workflowSDK, condition.NewEvaluator(), skips, logger)
+	signal := &CompletionSignal{RunID: runID, NodeID: "check", ResultRef: resultRef}
+
+	branch := func(expr string) *sdk.Node {
+		return &sdk.Node{
+			ID: "check",
+			Branch: &sdk.BranchConfig{
+				Enabled: true,
+				Rules:   []sdk.BranchRule{{Condition: celCondition(expr), NextNodes: []string{"pick_a"}}},
+				Default: []string{"pick_b"},
+			},
+		}
+	}
+
+	next, err := router.DetermineNextNodes(ctx, signal, branch("nodes.score_a.output.score > nodes.score_b.output.score"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pick_a"}, next)
+
+	next, err = router.DetermineNextNodes(ctx, signal, branch(`nodes["score_a"].output.score < nodes.score_b.output.score`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pick_b"}, next)
+
+	recorded := skips.byNode()["pick_a"]
+	require.NotNil(t, recorded)
+	require.NotNil(t, recorded.Condition)
+	assert.EqualValues(t, 65, recorded.Condition.Values["nodes.score_b.output.score"])
+
+	// A reference to a node without an output yet fails the rule with a clear error
+	skips.skips = nil
+	next, err = router.DetermineNextNodes(ctx, signal, branch("nodes.score_c.output.score > 50"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pick_b"}, next)
+	recorded = skips.byNode()["pick_a"]
+	require.NotNil(t, recorded)
+	require.NotNil(t, recorded.Condition)
+	assert.Equal(t, "condition references node score_c, which has not completed", recorded.Condition.Error)
+}
+
+func TestBranchFromFunctionNode(t *testing.T) {
+	ctx := context.Background()
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	// A function node (not type: conditional) with two conditional out-edges
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "check", Type: "function", Config: map[string]interface{}{"name": "score"}},
+			{ID: "high", Type: "function", Config: map[string]interface{}{"name": "high_path"}},
+			{ID: "low", Type: "function", Config: map[string]interface{}{"name": "low_path"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "check", To: "high", Condition: "output.score > 80"},
+			{From: "check", To: "low", Condition: "output.score <= 80"},
+		},
+	}, clients.NewRedisCASClient(rdb, noopLogger{}))
+	require.NoError(t, err)
+
+	for score, want := range map[int]string{95: "high", 30: "low"} {
+		router, skips, signal := newTestRouter(t, map[string]interface{}{"score": score})
+
+		next, err := router.DetermineNextNodes(ctx, signal, ir.Nodes["check"], ir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{want}, next, "score %d", score)
+
+		// The other branch is recorded as not taken
+		recorded := skips.byNode()
+		require.Len(t, recorded, 1)
+		assert.NotContains(t, recorded, want)
+	}
+}
+
+func TestLoopRecordsExitReasons(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("condition false", func(t *testing.T) {
+		router, skips, signal := newTestRouter(t, map[string]interface{}{"retries": 3})
+		node := &sdk.Node{
+			ID: "check",
+			Loop: &sdk.LoopConfig{
+				Enabled:       true,
+				Condition:     celCondition("output.retries < 3"),
+				MaxIterations: 10,
+				LoopBackTo:    "attempt",
+				BreakPath:     []string{"done"},
+			},
+		}
+
+		next, err := router.DetermineNextNodes(ctx, signal, node, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"done"}, next)
+
+		skip := skips.byNode()["attempt"]
+		require.NotNil(t, skip)
+		assert.Equal(t, SkipReasonLoopConditionFalse, skip.Reason)
+		require.NotNil(t, skip.Condition)
+		assert.EqualValues(t, 3, skip.Condition.Values["output.retries"])
+		assert.Equal(t, "loop exited: output.retries < 3 was false (output.retries = 3)", skip.Message)
+		assert.EqualValues(t, 1, skip.Details["iteration"])
+	})
+
+	t.Run("max iterations", func(t *testing.T) {
+		router, skips, signal := newTestRouter(t, map[string]interface{}{"retries": 0})
+		node := &sdk.Node{
+			ID: "check",
+			Loop: &sdk.LoopConfig{
+				Enabled:       true,
+				Condition:     celCondition("output.retries < 3"),
+				MaxIterations: 2,
+				LoopBackTo:    "attempt",
+				TimeoutPath:   []string{"give_up"},
+			},
+		}
+
+		next, err := router.DetermineNextNodes(ctx, signal, node, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"attempt"}, next)
+		assert.Empty(t, skips.byNode())
+
+		next, err = router.DetermineNextNodes(ctx, signal, node, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"give_up"}, next)
+
+		skip := skips.byNode()["attempt"]
+		require.NotNil(t, skip)
+		assert.Equal(t, SkipReasonLoopMaxIterations, skip.Reason)
+		assert.Equal(t, "loop stopped after 2 of 2 iterations", skip.Message)
+		assert.EqualValues(t, 2, skip.Details["iteration"])
+		assert.Equal(t, 2, skip.Details["max_iterations"])
+	})
+}
+
+func TestLoopKeepsIterationHistory(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, "")
+	router := NewControlFlowRouter(rdb, workflowSDK, condition.NewEvaluator(), nil, logger)
+	node := &sdk.Node{
+		ID: "attempt",
+		Loop: &sdk.LoopConfig{
+			Enabled: true,
+			// Earlier iterations are visible to the condition
+			Condition:     celCondition("output.status != 'success' && size(history) == iteration - 1 && history.all(h, h.status == 'error')"),
+			MaxIterations: 10,
+			LoopBackTo:    "attempt",
+			BreakPath:     []string{"done"},
+		},
+	}
+
+	statuses := []string{"error", "error", "success"}
+	refs := make([]string, len(statuses))
+	for i, status := range statuses {
+		var err error
+		refs[i], err = workflowSDK.StoreOutput(ctx, map[string]interface{}{"status": status, "attempt": i + 1})
+		require.NoError(t, err)
+
+		next, err := router.DetermineNextNodes(ctx, &CompletionSignal{RunID: "run_history_test", NodeID: "attempt", ResultRef: refs[i]}, node, nil)
+		require.NoError(t, err)
+		if i < len(statuses)-1 {
+			assert.Equal(t, []string{"attempt"}, next, "iteration %d", i+1)
+		} else {
+			assert.Equal(t, []string{"done"}, next)
+		}
+	}
+
+	history, err := workflowSDK.LoadLoopHistory(ctx, "run_history_test", "attempt")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	for i, iteration := range history {
+		assert.EqualValues(t, i+1, iteration.Iteration)
+		assert.Equal(t, refs[i], iteration.ResultRef)
+
+		output, err := workflowSDK.LoadPayload(ctx, iteration.ResultRef)
+		require.NoError(t, err)
+		assert.Equal(t, statuses[i], output.(map[string]interface{})["status"])
+	}
+	assert.Equal(t, sdk.LoopDecisionContinue, history[0].Decision)
+	assert.Equal(t, sdk.LoopDecisionContinue, history[1].Decision)
+	assert.Equal(t, sdk.LoopDecisionBreak, history[2].Decision)
+	assert.Contains(t, history[2].Reason, "output.status = success")
+
+	// The loop state is reset on exit; the history is kept
+	assert.False(t, mr.Exists("loop:run_history_test:attempt"))
+}
+
+func TestLoopRecordsExitReasonForStatus(t *testing.T) {
+	ctx := context.Background()
+
+	// runLoop feeds outputs to a retry loop until it exits, returning the exit key's value
+	runLoop := func(t *testing.T, statuses []string, maxIterations int) ([]string, string) {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+
+		logger := noopLogger{}
+		workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, "")
+		router := NewControlFlowRouter(rdb, workflowSDK, condition.NewEvaluator(), nil, logger)
+		node := &sdk.Node{
+			ID: "attempt",
+			Loop: &sdk.LoopConfig{
+				Enabled:       true,
+				Condition:     celCondition("output.status != 'success'"),
+				MaxIterations: maxIterations,
+				LoopBackTo:    "attempt",
+				BreakPath:     []string{"done"},
+				TimeoutPath:   []string{"give_up"},
+			},
+		}
+
+		var next []string
+		for i, status := range statuses {
+			// Still looping: no exit reason yet
+			assert.False(t, mr.Exists(sdk.LoopExitKey("run_exit_test", "attempt")), "iteration %d", i+1)
+
+			ref, err := workflowSDK.StoreOutput(ctx, map[string]interface{}{"status": status})
+			require.NoError(t, err)
+			next, err = router.DetermineNextNodes(ctx, &CompletionSignal{RunID: "run_exit_test", NodeID: "attempt", ResultRef: ref}, node, nil)
+			require.NoError(t, err)
+		}
+
+		exitReason, err := mr.Get(sdk.LoopExitKey("run_exit_test", "attempt"))
+		require.NoError(t, err)
+		return next, exitReason
+	}
+
+	t.Run("breaks on success", func(t *testing.T) {
+		next, exitReason := runLoop(t, []string{"error", "success"}, 5)
+		assert.Equal(t, []string{"done"}, next)
+		assert.Equal(t, sdk.LoopExitBreak, exitReason)
+	})
+
+	t.Run("exhausts iterations", func(t *testing.T) {
+		next, exitReason := runLoop(t, []string{"error", "error", "error"}, 3)
+		assert.Equal(t, []string{"give_up"}, next)
+		assert.Equal(t, sdk.LoopExitTimeout, exitReason)
+	})
+}
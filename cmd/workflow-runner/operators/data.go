@@ -0,0 +1,192 @@
+package operators
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/condition"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// Data operator node types, executed inline by the coordinator
+const (
+	DataOperatorTransform = "transform"
+	DataOperatorFilter    = "filter"
+	DataOperatorAggregate = "aggregate"
+)
+
+// MetadataFilterRejected marks the completion of a filter whose condition was false
+// MetadataFilterReason explains it; both are read when routing (see DetermineNextNodes).
+const (
+	MetadataFilterRejected = "filter_rejected"
+	MetadataFilterReason   = "filter_reason"
+)
+
+// IsDataOperator returns true for node types the coordinator executes inline
+func IsDataOperator(nodeType string) bool {
+	switch nodeType {
+	case DataOperatorTransform, DataOperatorFilter, DataOperatorAggregate:
+		return true
+	default:
+		return false
+	}
+}
+
+// UpstreamOutput is the output of one node an aggregate depends on
+type UpstreamOutput struct {
+	NodeID string
+	Output interface{}
+}
+
+// DataInput is what a data operator node works on
+// Expressions see Output as `output` (or `$.`), Context as `ctx`, Vars as `vars`
+// and Nodes as `nodes`, as in branch conditions.
+type DataInput struct {
+	Config   map[string]interface{}
+	Output   interface{}            // Output of the node the token came from
+	Upstream []UpstreamOutput       // Aggregate only: outputs of every dependency, in IR order
+	Context  map[string]interface{} // Previous node outputs
+	Vars     map[string]interface{} // Run variables
+	Nodes    map[string]interface{} // Outputs of the nodes the expressions reference (see DataExpressions)
+}
+
+// DataExpressions returns the CEL expressions in a data operator node's config
+// Used to load the nodes they reference through `nodes` before running the node.
+func DataExpressions(nodeType string, config map[string]interface{}) []string {
+	var exprs []string
+	switch nodeType {
+	case DataOperatorTransform:
+		mapping, _ := config["mapping"].(map[string]interface{})
+		for _, field := range sortedKeys(mapping) {
+			if expr, ok := mapping[field].(string); ok {
+				exprs = append(exprs, expr)
+			}
+		}
+	case DataOperatorFilter:
+		if expr, ok := config["condition"].(string); ok {
+			exprs = append(exprs, expr)
+		}
+	case DataOperatorAggregate:
+		if expr, ok := config["value"].(string); ok {
+			exprs = append(exprs, expr)
+		}
+	}
+	return exprs
+}
+
+// DataOperator executes transform, filter and aggregate nodes
+type DataOperator struct {
+	evaluator *condition.Evaluator
+}
+
+// NewDataOperator creates a data operator evaluating expressions with evaluator
+func NewDataOperator(evaluator *condition.Evaluator) *DataOperator {
+	return &DataOperator{evaluator: evaluator}
+}
+
+// Transform builds a new object from config.mapping
+// Every mapping entry names an output field and the CEL or JSONPath expression
+// computing it, e.g. {"full_name": "$.name", "total": "output.price * output.qty"}.
+func (o *DataOperator) Transform(input *DataInput) (map[string]interface{}, error) {
+	mapping, ok := input.Config["mapping"].(map[string]interface{})
+	if !ok || len(mapping) == 0 {
+		return nil, fmt.Errorf("transform needs config.mapping")
+	}
+
+	result := make(map[string]interface{}, len(mapping))
+	for _, field := range sortedKeys(mapping) {
+		expr, ok := mapping[field].(string)
+		if !ok {
+			return nil, fmt.Errorf("mapping for %q must be an expression string", field)
+		}
+		value, err := o.evaluator.Value(expr, input.Output, input.Context, input.Vars, input.Nodes)
+		if err != nil {
+			return nil, fmt.Errorf("mapping for %q: %w", field, err)
+		}
+		result[field] = value
+	}
+	return result, nil
+}
+
+// Filter evaluates config.condition against the input
+// Returns whether the token passes, with the evaluation described for the
+// run's skip log. A token that doesn't pass is dropped: the filter's
+// dependents are not run.
+func (o *DataOperator) Filter(input *DataInput) (bool, string, error) {
+	expr, _ := input.Config["condition"].(string)
+	if expr == "" {
+		return false, "", fmt.Errorf("filter needs config.condition")
+	}
+	evaluation, err := o.evaluator.EvaluateWithDetails(&sdk.Condition{Type: "cel", Expression: expr},
+		input.Output, input.Context, input.Vars, input.Nodes)
+	if err != nil {
+		return false, "", err
+	}
+	return evaluation.Result, describeEvaluation(evaluation), nil
+}
+
+// Aggregate combines the outputs of the node's upstream nodes (config.strategy)
+//   - collect (default): every output by node ID
+//   - sum: adds config.value, an expression evaluated against each output
+//   - merge: merges the object outputs; later dependencies win on conflicts
+func (o *DataOperator) Aggregate(input *DataInput) (map[string]interface{}, error) {
+	strategy, _ := input.Config["strategy"].(string)
+	switch strategy {
+	case "", "collect":
+		inputs := make(map[string]interface{}, len(input.Upstream))
+		for _, upstream := range input.Upstream {
+			inputs[upstream.NodeID] = upstream.Output
+		}
+		return map[string]interface{}{"inputs": inputs, "count": len(input.Upstream)}, nil
+	case "sum":
+		expr, _ := input.Config["value"].(string)
+		if expr == "" {
+			return nil, fmt.Errorf("sum needs config.value")
+		}
+		var sum float64
+		for _, upstream := range input.Upstream {
+			value, err := o.evaluator.Value(expr, upstream.Output, input.Context, input.Vars, input.Nodes)
+			if err != nil {
+				return nil, fmt.Errorf("value of %s: %w", upstream.NodeID, err)
+			}
+			number, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("value of %s is not a number, got %T", upstream.NodeID, value)
+			}
+			sum += number
+		}
+		return map[string]interface{}{"sum": sum, "count": len(input.Upstream)}, nil
+	case "merge":
+		merged := make(map[string]interface{})
+		for _, upstream := range input.Upstream {
+			record, ok := upstream.Output.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("merge needs object outputs, %s returned %T", upstream.NodeID, upstream.Output)
+			}
+			for k, v := range record {
+				merged[k] = v
+			}
+		}
+		return merged, nil
+	default:
+		return nil, fmt.Errorf("unknown aggregate strategy %q (expected collect, sum or merge)", strategy)
+	}
+}
+
+// filterRejected returns whether a completion is of a filter that dropped its token
+func filterRejected(signal *CompletionSignal, node *sdk.Node) bool {
+	if node.Type != DataOperatorFilter || signal.Metadata == nil {
+		return false
+	}
+	rejected, _ := signal.Metadata[MetadataFilterRejected].(bool)
+	return rejected
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
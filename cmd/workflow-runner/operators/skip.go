@@ -0,0 +1,75 @@
+package operators
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/condition"
+)
+
+// Skip reasons recorded when a node does not run
+const (
+	SkipReasonBranchNotTaken     = "branch_not_taken"
+	SkipReasonFilterRejected     = "filter_rejected"
+	SkipReasonLoopConditionFalse = "loop_condition_false"
+	SkipReasonLoopMaxIterations  = "loop_max_iterations"
+	SkipReasonNoWorkerAvailable  = "no_worker_available"
+)
+
+// SkipDecision explains why a node was not routed to
+type SkipDecision struct {
+	NodeID    string                 `json:"node_id"`   // Node that did not run
+	FromNode  string                 `json:"from_node"` // Node whose routing decided it
+	Reason    string                 `json:"reason"`
+	Message   string                 `json:"message"`
+	Condition *condition.Evaluation  `json:"condition,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// SkipRecorder receives skip decisions made while routing
+type SkipRecorder interface {
+	RecordSkip(ctx context.Context, runID string, skip *SkipDecision)
+}
+
+// SkipRecorderFunc adapts a function to a SkipRecorder
+type SkipRecorderFunc func(ctx context.Context, runID string, skip *SkipDecision)
+
+// RecordSkip calls f
+func (f SkipRecorderFunc) RecordSkip(ctx context.Context, runID string, skip *SkipDecision) {
+	f(ctx, runID, skip)
+}
+
+// noopSkipRecorder drops skip decisions
+type noopSkipRecorder struct{}
+
+func (noopSkipRecorder) RecordSkip(ctx context.Context, runID string, skip *SkipDecision) {}
+
+// describeEvaluation renders an evaluation for humans
+// e.g. "output.score >= 80 was false (output.score = 50)"
+func describeEvaluation(evaluation *condition.Evaluation) string {
+	if evaluation == nil {
+		return ""
+	}
+	if evaluation.Error != "" {
+		return fmt.Sprintf("%s could not be evaluated: %s", evaluation.Expression, evaluation.Error)
+	}
+
+	description := fmt.Sprintf("%s was %t", evaluation.Expression, evaluation.Result)
+	if len(evaluation.Values) == 0 {
+		return description
+	}
+
+	paths := make([]string, 0, len(evaluation.Values))
+	for path := range evaluation.Values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	values := make([]string, 0, len(paths))
+	for _, path := range paths {
+		values = append(values, fmt.Sprintf("%s = %v", path, evaluation.Values[path]))
+	}
+	return fmt.Sprintf("%s (%s)", description, strings.Join(values, ", "))
+}
@@ -3,18 +3,23 @@ package resolver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/lyzr/orchestrator/common/sdk"
-	"github.com/tidwall/gjson"
+	"github.com/lyzr/orchestrator/common/templating"
 )
 
+// ErrMissingPath is returned for a template referencing a value that doesn't exist
+// Only strict resolvers return it; lenient ones leave the template in place.
+var ErrMissingPath = templating.ErrMissingPath
+
 // Resolver handles variable substitution in node configs
 type Resolver struct {
 	sdk    *sdk.SDK
 	logger sdk.Logger
+	strict bool
 }
 
 // NewResolver creates a new expression resolver
@@ -25,16 +30,50 @@ func NewResolver(workflowSDK *sdk.SDK, logger sdk.Logger) *Resolver {
 	}
 }
 
+// WithStrict makes templates referencing missing values an error
+// Lenient resolvers (the default) log a warning and keep such templates as written.
+func (r *Resolver) WithStrict(strict bool) *Resolver {
+	r.strict = strict
+	return r
+}
+
+// scope is what the templates of one config resolve against
+// Each source is loaded on first use and then reused.
+type scope struct {
+	runID      string
+	payloadRef string
+
+	output       interface{}
+	outputLoaded bool
+	inputs       map[string]interface{}
+	inputsLoaded bool
+	vars         map[string]interface{}
+}
+
 // ResolveConfig resolves all variable expressions in a config map
-// Supports:
-// - $nodes.node_id - entire node output
-// - $nodes.node_id.field - specific field access
-// - ${$nodes.node_id.field} - string interpolation
+// Equivalent to ResolveConfigFor without an upstream output.
 func (r *Resolver) ResolveConfig(ctx context.Context, runID string, config map[string]interface{}) (map[string]interface{}, error) {
+	return r.ResolveConfigFor(ctx, runID, "", config)
+}
+
+// ResolveConfigFor resolves the templates in a config map, at any depth
+// payloadRef is the output the node receives from upstream. Templates:
+//   - ${output.field} - field of the upstream output (${output} for all of it)
+//   - ${nodes.node_id.output.field} - field of any completed node's output
+//   - ${run.inputs.field} - field of the run's inputs; ${run.id} is the run ID
+//   - ${vars.name} - run variable (see sdk.SetVar)
+//   - $${...} - a literal ${...}
+//
+// Paths use gjson syntax, so list elements are numbered (items.0.name). A string
+// that is a single template becomes the referenced value, keeping its type;
+// templates inside longer strings are interpolated (objects as JSON). The older
+// $nodes.node_id.field form, bare or as ${$nodes.node_id.field}, still works.
+func (r *Resolver) ResolveConfigFor(ctx context.Context, runID, payloadRef string, config map[string]interface{}) (map[string]interface{}, error) {
+	s := &scope{runID: runID, payloadRef: payloadRef}
 	resolved := make(map[string]interface{})
 
 	for key, value := range config {
-		resolvedValue, err := r.resolveValue(ctx, runID, value)
+		resolvedValue, err := r.resolveValue(ctx, s, value)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve config key %s: %w", key, err)
 		}
@@ -45,14 +84,14 @@ func (r *Resolver) ResolveConfig(ctx context.Context, runID string, config map[s
 }
 
 // resolveValue recursively resolves a value (string, map, array, etc.)
-func (r *Resolver) resolveValue(ctx context.Context, runID string, value interface{}) (interface{}, error) {
+func (r *Resolver) resolveValue(ctx context.Context, s *scope, value interface{}) (interface{}, error) {
 	switch v := value.(type) {
 	case string:
-		return r.resolveString(ctx, runID, v)
+		return r.resolveString(ctx, s, v)
 	case map[string]interface{}:
-		return r.resolveMap(ctx, runID, v)
+		return r.resolveMap(ctx, s, v)
 	case []interface{}:
-		return r.resolveArray(ctx, runID, v)
+		return r.resolveArray(ctx, s, v)
 	default:
 		// Primitives (int, bool, etc.) pass through
 		return value, nil
@@ -60,15 +99,16 @@ func (r *Resolver) resolveValue(ctx context.Context, runID string, value interfa
 }
 
 // resolveString handles string expressions
-func (r *Resolver) resolveString(ctx context.Context, runID, str string) (interface{}, error) {
+func (r *Resolver) resolveString(ctx context.Context, s *scope, str string) (interface{}, error) {
 	// Case 1: Full node reference: "$nodes.node_id" or "$nodes.node_id.field"
 	if strings.HasPrefix(str, "$nodes.") {
-		return r.resolveNodeReference(ctx, runID, str)
+		value, err := r.resolveNodeReference(ctx, s.runID, str)
+		return r.lenient(str, value, err)
 	}
 
-	// Case 2: String interpolation: "text ${$nodes.node_id} more text"
+	// Case 2: Templates: "${output.id}" or "text ${run.inputs.name} more text"
 	if strings.Contains(str, "${") {
-		return r.resolveInterpolation(ctx, runID, str)
+		return r.resolveInterpolation(ctx, s, str)
 	}
 
 	// Case 3: Plain string, no substitution needed
@@ -76,10 +116,10 @@ func (r *Resolver) resolveString(ctx context.Context, runID, str string) (interf
 }
 
 // resolveMap recursively resolves all values in a map
-func (r *Resolver) resolveMap(ctx context.Context, runID string, m map[string]interface{}) (map[string]interface{}, error) {
+func (r *Resolver) resolveMap(ctx context.Context, s *scope, m map[string]interface{}) (map[string]interface{}, error) {
 	resolved := make(map[string]interface{})
 	for key, value := range m {
-		resolvedValue, err := r.resolveValue(ctx, runID, value)
+		resolvedValue, err := r.resolveValue(ctx, s, value)
 		if err != nil {
 			return nil, err
 		}
@@ -89,10 +129,10 @@ func (r *Resolver) resolveMap(ctx context.Context, runID string, m map[string]in
 }
 
 // resolveArray recursively resolves all items in an array
-func (r *Resolver) resolveArray(ctx context.Context, runID string, arr []interface{}) ([]interface{}, error) {
+func (r *Resolver) resolveArray(ctx context.Context, s *scope, arr []interface{}) ([]interface{}, error) {
 	resolved := make([]interface{}, len(arr))
 	for i, value := range arr {
-		resolvedValue, err := r.resolveValue(ctx, runID, value)
+		resolvedValue, err := r.resolveValue(ctx, s, value)
 		if err != nil {
 			return nil, err
 		}
@@ -107,77 +147,196 @@ func (r *Resolver) resolveNodeReference(ctx context.Context, runID, expr string)
 	expr = strings.TrimPrefix(expr, "$nodes.")
 
 	// Split into node_id and path
-	parts := strings.SplitN(expr, ".", 2)
-	nodeID := parts[0]
+	nodeID, fieldPath, _ := strings.Cut(expr, ".")
 
 	// Load node output
-	output, err := r.sdk.LoadNodeOutput(ctx, runID, nodeID)
-	if err != nil {
-		r.logger.Error("failed to load node output", "node_id", nodeID, "error", err)
-		return nil, fmt.Errorf("node output not found: %s", nodeID)
-	}
+	output, err := r.loadNodeOutput(ctx, runID, nodeID)
 
-	// If no field path, return entire output
-	if len(parts) == 1 {
-		return output, nil
+	// Inlined subworkflow nodes have namespaced IDs ("parent.child"), so fall
+	// back to longer dotted prefixes when the first segment has no output
+	for id, path := nodeID, fieldPath; errors.Is(err, ErrMissingPath) && path != ""; {
+		segment, rest, _ := strings.Cut(path, ".")
+		id, path = id+"."+segment, rest
+		if nested, nestedErr := r.loadNodeOutput(ctx, runID, id); nestedErr == nil {
+			nodeID, fieldPath, output, err = id, path, nested, nil
+		}
 	}
-
-	// Extract specific field using gjson
-	fieldPath := parts[1]
-	outputJSON, err := json.Marshal(output)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal node output: %w", err)
+		return nil, err
 	}
 
-	result := gjson.GetBytes(outputJSON, fieldPath)
-	if !result.Exists() {
-		return nil, fmt.Errorf("field not found: %s in node %s", fieldPath, nodeID)
-	}
-
-	return result.Value(), nil
+	return templating.LookupPath(output, fieldPath, "node "+nodeID)
 }
 
-// resolveInterpolation handles string interpolation "${$nodes.node_id.field}"
-func (r *Resolver) resolveInterpolation(ctx context.Context, runID, str string) (string, error) {
-	// Pattern: ${$nodes.node_id.field.path}
-	pattern := regexp.MustCompile(`\$\{([^}]+)\}`)
-
-	result := str
-	matches := pattern.FindAllStringSubmatch(str, -1)
+// resolveInterpolation resolves the templates in a string
+// A string that is exactly one template resolves to the referenced value itself.
+func (r *Resolver) resolveInterpolation(ctx context.Context, s *scope, str string) (interface{}, error) {
+	matches := templating.Pattern.FindAllStringSubmatchIndex(str, -1)
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(str) && !strings.HasPrefix(str, "$$") {
+		value, err := r.resolveTemplate(ctx, s, str[matches[0][2]:matches[0][3]])
+		return r.lenient(str, value, err)
+	}
 
+	var result strings.Builder
+	last := 0
 	for _, match := range matches {
-		if len(match) < 2 {
+		placeholder := str[match[0]:match[1]] // Full match: ${...} or $${...}
+		expr := str[match[2]:match[3]]        // Inner expression: output.field
+		result.WriteString(str[last:match[0]])
+		last = match[1]
+
+		// Escaped: $${...} is the literal ${...}
+		if strings.HasPrefix(placeholder, "$$") {
+			result.WriteString(placeholder[1:])
 			continue
 		}
 
-		placeholder := match[0] // Full match: ${...}
-		expr := match[1]        // Inner expression: $nodes.node_id.field
-
-		// Resolve the expression
-		value, err := r.resolveString(ctx, runID, expr)
-		if err != nil {
+		value, err := r.resolveTemplate(ctx, s, expr)
+		if value, err = r.lenient(placeholder, value, err); err != nil {
 			return "", fmt.Errorf("failed to resolve interpolation %s: %w", placeholder, err)
 		}
 
 		// Convert value to string
-		var valueStr string
 		switch v := value.(type) {
 		case string:
-			valueStr = v
+			result.WriteString(v)
 		case []byte:
-			valueStr = string(v)
+			result.Write(v)
 		default:
 			// For complex types, marshal to JSON
 			jsonBytes, err := json.Marshal(v)
 			if err != nil {
 				return "", fmt.Errorf("failed to marshal interpolated value: %w", err)
 			}
-			valueStr = string(jsonBytes)
+			result.Write(jsonBytes)
+		}
+	}
+	result.WriteString(str[last:])
+
+	return result.String(), nil
+}
+
+// resolveTemplate resolves the expression inside ${...}
+func (r *Resolver) resolveTemplate(ctx context.Context, s *scope, expr string) (interface{}, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "$nodes.") {
+		return r.resolveNodeReference(ctx, s.runID, expr)
+	}
+
+	root, path, _ := strings.Cut(expr, ".")
+	switch root {
+	case "output":
+		output, err := r.upstreamOutput(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		return templating.LookupPath(output, path, "upstream output")
+	case "nodes":
+		return r.resolveNodeTemplate(ctx, s.runID, path)
+	case "run":
+		field, rest, _ := strings.Cut(path, ".")
+		switch field {
+		case "id":
+			if rest == "" {
+				return s.runID, nil
+			}
+		case "inputs":
+			inputs, err := r.runInputs(ctx, s)
+			if err != nil {
+				return nil, err
+			}
+			return templating.LookupPath(inputs, rest, "run inputs")
+		}
+		return nil, fmt.Errorf("%w: run.%s (expected run.id or run.inputs)", ErrMissingPath, path)
+	case "vars":
+		if s.vars == nil {
+			vars, err := r.sdk.GetVars(ctx, s.runID)
+			if err != nil {
+				return nil, err
+			}
+			s.vars = vars
+		}
+		if path == "" {
+			return s.vars, nil
+		}
+		return templating.LookupPath(s.vars, path, "run vars")
+	default:
+		return nil, fmt.Errorf("%w: unknown template root %q (expected output, nodes, run or vars)", ErrMissingPath, root)
+	}
+}
+
+// resolveNodeTemplate resolves "node_id.output.field" of ${nodes.node_id.output.field}
+// Node IDs may contain dots (inlined subworkflows), so the ID is everything
+// before the first "output" segment.
+func (r *Resolver) resolveNodeTemplate(ctx context.Context, runID, path string) (interface{}, error) {
+	segments := strings.Split(path, ".")
+	for i := 1; i < len(segments); i++ {
+		if segments[i] != "output" {
+			continue
 		}
+		nodeID := strings.Join(segments[:i], ".")
+		output, err := r.loadNodeOutput(ctx, runID, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return templating.LookupPath(output, strings.Join(segments[i+1:], "."), "node "+nodeID)
+	}
+	return nil, fmt.Errorf("%w: nodes.%s (expected nodes.<node_id>.output)", ErrMissingPath, path)
+}
 
-		// Replace placeholder with value
-		result = strings.Replace(result, placeholder, valueStr, 1)
+// upstreamOutput loads the output the node receives from upstream
+func (r *Resolver) upstreamOutput(ctx context.Context, s *scope) (interface{}, error) {
+	if !s.outputLoaded {
+		if s.payloadRef == "" {
+			return nil, fmt.Errorf("%w: the node has no upstream output", ErrMissingPath)
+		}
+		output, err := r.sdk.LoadPayload(ctx, s.payloadRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load upstream output: %w", err)
+		}
+		s.output, s.outputLoaded = output, true
 	}
+	return s.output, nil
+}
 
-	return result, nil
+// runInputs loads the inputs the run was created with
+func (r *Resolver) runInputs(ctx context.Context, s *scope) (map[string]interface{}, error) {
+	if !s.inputsLoaded {
+		inputs, err := r.sdk.LoadInputs(ctx, s.runID)
+		if err != nil {
+			return nil, err
+		}
+		s.inputs, s.inputsLoaded = inputs, true
+	}
+	return s.inputs, nil
+}
+
+// loadNodeOutput loads a node's output, or ErrMissingPath if it has none yet
+func (r *Resolver) loadNodeOutput(ctx context.Context, runID, nodeID string) (interface{}, error) {
+	ref, err := r.sdk.LoadOutputRef(ctx, runID, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("%w: node output not found: %s", ErrMissingPath, nodeID)
+	}
+
+	output, err := r.sdk.LoadNodeOutput(ctx, runID, nodeID)
+	if err != nil {
+		r.logger.Error("failed to load node output", "node_id", nodeID, "error", err)
+		return nil, err
+	}
+	return output, nil
+}
+
+// lenient keeps a template that references a missing value as written, unless strict
+// Other errors (e.g. Redis being unreachable) are returned either way.
+func (r *Resolver) lenient(template string, value interface{}, err error) (interface{}, error) {
+	if err == nil || r.strict || !errors.Is(err, ErrMissingPath) {
+		return value, err
+	}
+	r.logger.Warn("template references a missing value, keeping it unresolved",
+		"template", template,
+		"error", err)
+	return template, nil
 }
@@ -0,0 +1,144 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (noopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+const testRunID = "run_resolver_test"
+
+// newTestResolver returns a resolver over a run where fetch has completed
+// Returns the ref of the upstream output the resolved node receives.
+func newTestResolver(t *testing.T) (*Resolver, string) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	ctx := context.Background()
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, "")
+
+	fetchRef, err := workflowSDK.StoreOutput(ctx, map[string]interface{}{
+		"url":   "https://example.com/report",
+		"items": []interface{}{map[string]interface{}{"name": "first"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, workflowSDK.StoreContext(ctx, testRunID, "fetch", fetchRef))
+
+	payloadRef, err := workflowSDK.StoreOutput(ctx, map[string]interface{}{
+		"id":    float64(42),
+		"owner": map[string]interface{}{"email": "ada@example.com"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, rdb.Set(ctx, sdk.InputsKey(testRunID), `{"customer": "acme", "limit": 5}`, 0).Err())
+	require.NoError(t, workflowSDK.SetVar(ctx, testRunID, "region", "eu"))
+
+	return NewResolver(workflowSDK, logger), payloadRef
+}
+
+func TestResolveConfigFor_Substitutes(t *testing.T) {
+	r, payloadRef := newTestResolver(t)
+
+	resolved, err := r.ResolveConfigFor(context.Background(), testRunID, payloadRef, map[string]interface{}{
+		"id":       "${output.id}",
+		"owner":    "${output.owner}",
+		"url":      "${nodes.fetch.output.url}?customer=${run.inputs.customer}",
+		"first":    "${nodes.fetch.output.items.0.name}",
+		"subject":  "Run ${run.id} for ${output.owner}",
+		"retries":  3,
+		"legacy":   "$nodes.fetch.url",
+		"embedded": "see ${$nodes.fetch.items.0.name}",
+		"headers": map[string]interface{}{
+			"X-Region": "${vars.region}",
+			"X-Limit":  "${ run.inputs.limit }",
+		},
+		"recipients": []interface{}{"${output.owner.email}", "ops@example.com"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		// A single template keeps the referenced value's type
+		"id":       float64(42),
+		"owner":    map[string]interface{}{"email": "ada@example.com"},
+		"url":      "https://example.com/report?customer=acme",
+		"first":    "first",
+		"subject":  `Run run_resolver_test for {"email":"ada@example.com"}`,
+		"retries":  3,
+		"legacy":   "https://example.com/report",
+		"embedded": "see first",
+		"headers": map[string]interface{}{
+			"X-Region": "eu",
+			"X-Limit":  float64(5),
+		},
+		"recipients": []interface{}{"ada@example.com", "ops@example.com"},
+	}, resolved)
+}
+
+func TestResolveConfigFor_MissingPath(t *testing.T) {
+	configs := map[string]string{
+		"upstream field": "${output.missing}",
+		"node not run":   "${nodes.report.output.url}",
+		"node field":     "prefix ${nodes.fetch.output.missing}",
+		"no output part": "${nodes.fetch.url}",
+		"input":          "${run.inputs.missing}",
+		"var":            "${vars.missing}",
+		"unknown root":   "${inputs.customer}",
+		"legacy":         "$nodes.fetch.missing",
+	}
+
+	for name, template := range configs {
+		t.Run(name, func(t *testing.T) {
+			r, payloadRef := newTestResolver(t)
+			config := map[string]interface{}{"nested": []interface{}{map[string]interface{}{"value": template}}}
+
+			// Strict: an error naming the template
+			_, err := r.WithStrict(true).ResolveConfigFor(context.Background(), testRunID, payloadRef, config)
+			require.ErrorIs(t, err, ErrMissingPath)
+			assert.ErrorContains(t, err, "nested")
+
+			// Lenient: the template is kept as written, never emptied
+			resolved, err := r.WithStrict(false).ResolveConfigFor(context.Background(), testRunID, payloadRef, config)
+			require.NoError(t, err)
+			assert.Equal(t, config, resolved)
+		})
+	}
+}
+
+func TestResolveConfigFor_OutputWithoutUpstream(t *testing.T) {
+	r, _ := newTestResolver(t)
+
+	_, err := r.WithStrict(true).ResolveConfigFor(context.Background(), testRunID, "", map[string]interface{}{
+		"id": "${output.id}",
+	})
+	assert.ErrorIs(t, err, ErrMissingPath)
+}
+
+func TestResolveConfigFor_Escaping(t *testing.T) {
+	r, payloadRef := newTestResolver(t)
+
+	resolved, err := r.WithStrict(true).ResolveConfigFor(context.Background(), testRunID, payloadRef, map[string]interface{}{
+		"literal": "$${output.id}",
+		"mixed":   "id ${output.id}, template $${output.id}",
+		"script":  "echo $${HOME} $HOME",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "${output.id}", resolved["literal"])
+	assert.Equal(t, "id 42, template ${output.id}", resolved["mixed"])
+	assert.Equal(t, "echo ${HOME} $HOME", resolved["script"])
+}
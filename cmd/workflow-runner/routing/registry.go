@@ -0,0 +1,88 @@
+// Package routing maps node types to the Redis streams their workers consume
+package routing
+
+import "sort"
+
+// DefaultStream is returned for node types no worker is registered for
+const DefaultStream = "wf.tasks.default"
+
+// DataStream carries data operator tokens to the workflow-runner's data worker
+const DataStream = "wf.tasks.data"
+
+// builtinStreams are the node types served by the bundled workers
+// Data operators (transform, filter, aggregate) are executed by the data worker
+// running alongside the coordinator (see coordinator.DataWorker).
+var builtinStreams = map[string]string{
+	"agent":     "wf.tasks.agent",
+	"http":      "wf.tasks.http",
+	"hitl":      "wf.tasks.hitl",
+	"webhook":   "wf.tasks.webhook",
+	"function":  "wf.tasks.function",
+	"transform": DataStream,
+	"filter":    DataStream,
+	"aggregate": DataStream,
+}
+
+// Registry maps node types to worker streams
+// Register mappings at startup, before tokens are routed; lookups are not
+// synchronized with Register.
+type Registry struct {
+	streams map[string]string
+}
+
+// NewRegistry creates a registry holding the built-in mappings
+func NewRegistry() *Registry {
+	streams := make(map[string]string, len(builtinStreams))
+	for nodeType, stream := range builtinStreams {
+		streams[nodeType] = stream
+	}
+	return &Registry{streams: streams}
+}
+
+// Register routes nodeType to stream, replacing any existing mapping
+func (r *Registry) Register(nodeType, stream string) *Registry {
+	r.streams[nodeType] = stream
+	return r
+}
+
+// RegisterAll registers each type → stream mapping (see Register)
+func (r *Registry) RegisterAll(mappings map[string]string) *Registry {
+	for nodeType, stream := range mappings {
+		r.Register(nodeType, stream)
+	}
+	return r
+}
+
+// Lookup returns the stream for nodeType and whether one is registered
+func (r *Registry) Lookup(nodeType string) (string, bool) {
+	stream, ok := r.streams[nodeType]
+	return stream, ok
+}
+
+// HasWorker returns true if tokens for nodeType are consumed by a worker
+func (r *Registry) HasWorker(nodeType string) bool {
+	_, ok := r.streams[nodeType]
+	return ok
+}
+
+// StreamFor returns the stream for nodeType, or DefaultStream if none is registered
+func (r *Registry) StreamFor(nodeType string) string {
+	if stream, ok := r.streams[nodeType]; ok {
+		return stream
+	}
+	return DefaultStream
+}
+
+// Streams returns the registered stream names, sorted
+func (r *Registry) Streams() []string {
+	seen := make(map[string]bool, len(r.streams))
+	streams := make([]string, 0, len(r.streams))
+	for _, stream := range r.streams {
+		if !seen[stream] {
+			seen[stream] = true
+			streams = append(streams, stream)
+		}
+	}
+	sort.Strings(streams)
+	return streams
+}
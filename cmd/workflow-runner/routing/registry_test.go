@@ -0,0 +1,26 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	routes := NewRegistry().Register("ocr", "wf.tasks.ocr").Register("http", "wf.tasks.http.v2")
+
+	assert.Equal(t, "wf.tasks.agent", routes.StreamFor("agent"))
+	assert.Equal(t, "wf.tasks.ocr", routes.StreamFor("ocr"))
+	assert.Equal(t, "wf.tasks.http.v2", routes.StreamFor("http"), "registered mappings override built-ins")
+
+	assert.True(t, routes.HasWorker("ocr"))
+	assert.False(t, routes.HasWorker("classifier"))
+	assert.Equal(t, DefaultStream, routes.StreamFor("classifier"))
+
+	assert.Equal(t, DataStream, routes.StreamFor("transform"))
+	assert.Equal(t, "wf.tasks.agg", NewRegistry().Register("aggregate", "wf.tasks.agg").StreamFor("aggregate"),
+		"a registered worker replaces the data worker")
+
+	assert.Contains(t, routes.Streams(), "wf.tasks.ocr")
+	assert.NotContains(t, routes.Streams(), "wf.tasks.http")
+}
@@ -2,18 +2,36 @@ package supervisor
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/workflow_lifecycle"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
 	"github.com/redis/go-redis/v9"
 )
 
-// CompletionSupervisor handles final completion verification and database updates
+// RunStatusStore persists run statuses (see repository.RunRepository)
+type RunStatusStore interface {
+	UpdateStatus(ctx context.Context, runID uuid.UUID, status models.RunStatus) error
+	GetStatuses(ctx context.Context, runIDs []uuid.UUID) (map[uuid.UUID]models.RunStatus, error)
+}
+
+// CompletionSupervisor finalizes runs whose token counter reaches zero
+// apply_delta.lua publishes the run ID to completion_events when the counter
+// hits zero. The supervisor verifies the run is really done, persists its
+// COMPLETED status and publishes workflow_completed, so the terminal status
+// doesn't depend on being derived when the run is read. Every replica gets the
+// event; a finalization claim (see sdk.ClaimFinalization) lets one act on it.
 type CompletionSupervisor struct {
-	redis  *redis.Client
-	db     *sql.DB
-	logger Logger
+	redis     redis.UniversalClient
+	runs      RunStatusStore
+	publisher *workflow_lifecycle.EventPublisher
+	logger    Logger
+	settle    time.Duration
 }
 
 // Logger interface for logging
@@ -25,21 +43,32 @@ type Logger interface {
 }
 
 // NewCompletionSupervisor creates a new completion supervisor
-func NewCompletionSupervisor(redis *redis.Client, db *sql.DB, logger Logger) *CompletionSupervisor {
+func NewCompletionSupervisor(redis redis.UniversalClient, runs RunStatusStore, logger Logger) *CompletionSupervisor {
 	return &CompletionSupervisor{
-		redis:  redis,
-		db:     db,
-		logger: logger,
+		redis:     redis,
+		runs:      runs,
+		publisher: workflow_lifecycle.NewEventPublisher(rediscommon.NewClient(redis, logger), logger),
+		logger:    logger,
+		settle:    500 * time.Millisecond,
 	}
 }
 
+// WithSettleDelay sets how long to wait before verifying a completion event
+// The coordinator consumes a node's token before emitting its successors', so
+// the counter touches zero briefly between nodes; an event is only acted on if
+// the counter is still zero after the delay.
+func (s *CompletionSupervisor) WithSettleDelay(delay time.Duration) *CompletionSupervisor {
+	s.settle = delay
+	return s
+}
+
 // Start begins the completion supervisor
 // It listens for completion events published by the Lua script when counter hits 0
 func (s *CompletionSupervisor) Start(ctx context.Context) error {
-	s.logger.Info("completion supervisor starting", "channel", "completion_events")
+	s.logger.Info("completion supervisor starting", "channel", sdk.CompletionEventsChannel)
 
 	// Subscribe to completion_events channel
-	pubsub := s.redis.Subscribe(ctx, "completion_events")
+	pubsub := s.redis.Subscribe(ctx, sdk.CompletionEventsChannel)
 	defer pubsub.Close()
 
 	// Wait for subscription confirmation
@@ -71,10 +100,16 @@ func (s *CompletionSupervisor) Start(ctx context.Context) error {
 
 // handleCompletionEvent verifies completion and marks run as completed
 func (s *CompletionSupervisor) handleCompletionEvent(ctx context.Context, runID string) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(s.settle):
+	}
+
 	s.logger.Info("verifying completion", "run_id", runID)
 
 	// 1. Double-check counter is still 0
-	counterKey := fmt.Sprintf("counter:%s", runID)
+	counterKey := sdk.CounterKey(runID)
 	counter, err := s.redis.Get(ctx, counterKey).Int()
 	if err != nil && err != redis.Nil {
 		s.logger.Error("failed to get counter", "run_id", runID, "error", err)
@@ -88,7 +123,20 @@ func (s *CompletionSupervisor) handleCompletionEvent(ctx context.Context, runID
 		return
 	}
 
-	// 2. Check for pending approvals (HITL)
+	// 2. Runs that failed or were cancelled keep their status
+	status, err := s.redis.Get(ctx, fmt.Sprintf("run:status:%s", runID)).Result()
+	if err != nil && err != redis.Nil {
+		s.logger.Error("failed to get run status", "run_id", runID, "error", err)
+		return
+	}
+	if status == string(models.StatusFailed) || status == string(models.StatusCancelled) {
+		s.logger.Info("run already finished, skipping completion",
+			"run_id", runID,
+			"status", status)
+		return
+	}
+
+	// 3. Check for pending approvals (HITL)
 	pendingApprovalsKey := fmt.Sprintf("pending_approvals:%s", runID)
 	pendingApprovals, err := s.redis.SCard(ctx, pendingApprovalsKey).Result()
 	if err != nil && err != redis.Nil {
@@ -105,96 +153,81 @@ func (s *CompletionSupervisor) handleCompletionEvent(ctx context.Context, runID
 		return
 	}
 
-	// 3. Check for pending tokens (join pattern)
-	pendingTokensPattern := fmt.Sprintf("pending_tokens:%s:*", runID)
-	pendingTokenKeys, err := s.redis.Keys(ctx, pendingTokensPattern).Result()
-	if err != nil && err != redis.Nil {
-		s.logger.Error("failed to check pending tokens",
-			"run_id", runID,
-			"error", err)
+	// 4. All checks passed; only one replica finalizes the run
+	claimed, err := sdk.ClaimFinalization(ctx, s.redis, runID)
+	if err != nil {
+		s.logger.Error("failed to claim run finalization", "run_id", runID, "error", err)
 		return
 	}
-
-	if len(pendingTokenKeys) > 0 {
-		s.logger.Info("pending tokens exist, not complete",
-			"run_id", runID,
-			"pending_keys", len(pendingTokenKeys))
+	if !claimed {
+		s.logger.Info("run already finalized, skipping completion", "run_id", runID)
 		return
 	}
 
-	// 4. All checks passed, mark as completed
 	s.logger.Info("all checks passed, marking as completed", "run_id", runID)
 
 	if err := s.markCompleted(ctx, runID); err != nil {
 		s.logger.Error("failed to mark as completed",
 			"run_id", runID,
 			"error", err)
+		// Let the next completion event try again
+		if err := sdk.ReleaseFinalization(ctx, s.redis, runID); err != nil {
+			s.logger.Warn("failed to release run finalization", "run_id", runID, "error", err)
+		}
 		return
 	}
 
-	// 5. Cleanup Redis keys
-	if err := s.cleanup(ctx, runID); err != nil {
-		s.logger.Error("failed to cleanup Redis",
-			"run_id", runID,
-			"error", err)
-		// Don't return, completion is already recorded
+	// 5. Tell the run's owner; run state is cleaned up after its retention
+	// period (see workflow_lifecycle.StatusManager)
+	s.publishCompleted(ctx, runID)
+	if err := sdk.ForgetProgress(ctx, s.redis, runID); err != nil {
+		s.logger.Warn("failed to forget completed run", "run_id", runID, "error", err)
 	}
 
 	s.logger.Info("workflow completed successfully", "run_id", runID)
 }
 
-// markCompleted updates the database to mark the run as completed
+// markCompleted persists the run's COMPLETED status
 func (s *CompletionSupervisor) markCompleted(ctx context.Context, runID string) error {
-	query := `
-		UPDATE run
-		SET
-			status = 'COMPLETED',
-			ended_at = $1,
-			last_event_at = $1
-		WHERE run_id = $2
-	`
-
-	_, err := s.db.ExecContext(ctx, query, time.Now().UTC(), runID)
+	id, err := uuid.Parse(runID)
 	if err != nil {
-		return fmt.Errorf("failed to update run status: %w", err)
+		return fmt.Errorf("invalid run_id: %w", err)
 	}
 
+	if err := s.runs.UpdateStatus(ctx, id, models.StatusCompleted); err != nil {
+		return fmt.Errorf("failed to update run status: %w", err)
+	}
 	return nil
 }
 
-// cleanup removes Redis keys for completed run
-func (s *CompletionSupervisor) cleanup(ctx context.Context, runID string) error {
-	// Keys to clean up
-	keys := []string{
-		fmt.Sprintf("counter:%s", runID),
-		fmt.Sprintf("applied:%s", runID),
-		fmt.Sprintf("context:%s", runID),
-		fmt.Sprintf("ir:%s", runID),
-	}
-
-	// Use pipeline for efficiency
-	pipe := s.redis.Pipeline()
-	for _, key := range keys {
-		pipe.Del(ctx, key)
+// publishCompleted publishes workflow_completed to the run owner's event channel
+// The owner is read from the run's IR; runs without one publish nothing.
+func (s *CompletionSupervisor) publishCompleted(ctx context.Context, runID string) {
+	data, err := s.redis.Get(ctx, fmt.Sprintf("ir:%s", runID)).Result()
+	if err != nil {
+		s.logger.Warn("failed to load IR for completion event",
+			"run_id", runID,
+			"error", err)
+		return
 	}
 
-	// Also delete any loop state keys
-	loopPattern := fmt.Sprintf("loop:%s:*", runID)
-	loopKeys, err := s.redis.Keys(ctx, loopPattern).Result()
-	if err == nil {
-		for _, key := range loopKeys {
-			pipe.Del(ctx, key)
-		}
+	var ir sdk.IR
+	if err := json.Unmarshal([]byte(data), &ir); err != nil {
+		s.logger.Warn("failed to unmarshal IR for completion event",
+			"run_id", runID,
+			"error", err)
+		return
 	}
 
-	_, err = pipe.Exec(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to delete Redis keys: %w", err)
+	username, _ := ir.Metadata["username"].(string)
+	if username == "" {
+		return
 	}
 
-	s.logger.Debug("cleaned up Redis keys",
-		"run_id", runID,
-		"keys_deleted", len(keys)+len(loopKeys))
-
-	return nil
+	s.publisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
+		"type":      "workflow_completed",
+		"run_id":    runID,
+		"counter":   0,
+		"timestamp": time.Now().Unix(),
+	})
 }
@@ -0,0 +1,175 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunStore records the statuses persisted for each run
+type fakeRunStore struct {
+	mu       sync.Mutex
+	statuses map[uuid.UUID]models.RunStatus
+	updates  int
+}
+
+func (f *fakeRunStore) UpdateStatus(ctx context.Context, runID uuid.UUID, status models.RunStatus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[runID] = status
+	f.updates++
+	return nil
+}
+
+func (f *fakeRunStore) updateCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.updates
+}
+
+func (f *fakeRunStore) GetStatuses(ctx context.Context, runIDs []uuid.UUID) (map[uuid.UUID]models.RunStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	statuses := make(map[uuid.UUID]models.RunStatus, len(runIDs))
+	for _, runID := range runIDs {
+		if status, ok := f.statuses[runID]; ok {
+			statuses[runID] = status
+		}
+	}
+	return statuses, nil
+}
+
+func (f *fakeRunStore) status(runID uuid.UUID) models.RunStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.statuses[runID]
+}
+
+func TestCompletionSupervisorPersistsCompletedStatus(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, string(luaScript))
+	store := &fakeRunStore{statuses: make(map[uuid.UUID]models.RunStatus)}
+	completions := NewCompletionSupervisor(rdb, store, logger).WithSettleDelay(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	runID := uuid.New()
+	irJSON, err := json.Marshal(&sdk.IR{Metadata: map[string]interface{}{"username": "alice"}})
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID.String(), irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID.String(), 1))
+
+	events := rdb.Subscribe(ctx, "workflow:events:alice")
+	t.Cleanup(func() { events.Close() })
+	_, err = events.Receive(ctx)
+	require.NoError(t, err)
+
+	go completions.Start(ctx)
+	require.Eventually(t, func() bool {
+		return rdb.PubSubNumSub(ctx, sdk.CompletionEventsChannel).Val()[sdk.CompletionEventsChannel] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Consuming the last token takes the counter to zero
+	require.NoError(t, workflowSDK.Consume(ctx, runID.String(), "last"))
+
+	require.Eventually(t, func() bool {
+		return store.status(runID) == models.StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	msg, err := events.ReceiveMessage(ctx)
+	require.NoError(t, err)
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(msg.Payload), &event))
+	assert.Equal(t, "workflow_completed", event["type"])
+	assert.Equal(t, runID.String(), event["run_id"])
+}
+
+func TestCompletionSupervisorIgnoresTransientZero(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	logger := noopLogger{}
+	store := &fakeRunStore{statuses: make(map[uuid.UUID]models.RunStatus)}
+	completions := NewCompletionSupervisor(rdb, store, logger).WithSettleDelay(0)
+	ctx := context.Background()
+
+	// The next node's token was emitted after the completion event
+	runID := uuid.New()
+	require.NoError(t, rdb.Set(ctx, sdk.CounterKey(runID.String()), 1, 0).Err())
+	completions.handleCompletionEvent(ctx, runID.String())
+	assert.Empty(t, store.status(runID))
+
+	// Failed runs keep their status
+	require.NoError(t, rdb.Set(ctx, sdk.CounterKey(runID.String()), 0, 0).Err())
+	require.NoError(t, rdb.Set(ctx, "run:status:"+runID.String(), "FAILED", 0).Err())
+	completions.handleCompletionEvent(ctx, runID.String())
+	assert.Empty(t, store.status(runID))
+}
+
+func TestCompletionSupervisorFinalizesOnce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	logger := noopLogger{}
+	store := &fakeRunStore{statuses: make(map[uuid.UUID]models.RunStatus)}
+	ctx := context.Background()
+
+	runID := uuid.New()
+	irJSON, err := json.Marshal(&sdk.IR{Metadata: map[string]interface{}{"username": "alice"}})
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID.String(), irJSON, 0).Err())
+	require.NoError(t, rdb.Set(ctx, sdk.CounterKey(runID.String()), 0, 0).Err())
+
+	events := rdb.Subscribe(ctx, "workflow:events:alice")
+	t.Cleanup(func() { events.Close() })
+	_, err = events.Receive(ctx)
+	require.NoError(t, err)
+
+	// Every replica receives the completion event
+	replicas := []*CompletionSupervisor{
+		NewCompletionSupervisor(rdb, store, logger).WithSettleDelay(0),
+		NewCompletionSupervisor(rdb, store, logger).WithSettleDelay(0),
+	}
+	var wg sync.WaitGroup
+	for _, replica := range replicas {
+		wg.Add(1)
+		go func(replica *CompletionSupervisor) {
+			defer wg.Done()
+			replica.handleCompletionEvent(ctx, runID.String())
+		}(replica)
+	}
+	wg.Wait()
+
+	assert.Equal(t, models.StatusCompleted, store.status(runID))
+	assert.Equal(t, 1, store.updateCount())
+
+	_, err = events.ReceiveMessage(ctx)
+	require.NoError(t, err)
+	select {
+	case msg := <-events.Channel():
+		t.Fatalf("workflow_completed published twice: %s", msg.Payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
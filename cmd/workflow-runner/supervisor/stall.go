@@ -0,0 +1,289 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/workflow_lifecycle"
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/lyzr/orchestrator/common/models"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+)
+
+// StallDetector flags runs that stopped making progress
+// Every applied counter operation records when the run last progressed (see
+// sdk.RecordProgress). A run with tokens still in flight and no counter change
+// for longer than the threshold is flagged once per stall: run_stalled is
+// published to its event log and counted in the run stall metrics. Paused runs
+// and runs waiting for an approval or an async webhook's callback are expected
+// to sit idle and aren't flagged. Runs that already failed, were cancelled or
+// completed stop being tracked, whatever their counter says.
+//
+// Each scan also keeps the Redis state of live runs from expiring: runs that
+// progress, and runs waiting on purpose, get RunStateTTL back once half of it
+// has run out. Stalled runs don't, so their state expires RunStateTTL after they
+// stopped.
+type StallDetector struct {
+	redis         redis.UniversalClient
+	state         *rediscommon.Client
+	runs          RunStatusStore
+	publisher     *workflow_lifecycle.EventPublisher
+	logger        Logger
+	checkInterval time.Duration
+	threshold     time.Duration
+	markStatus    bool
+}
+
+// NewStallDetector creates a new stall detector
+func NewStallDetector(redis redis.UniversalClient, runs RunStatusStore, logger Logger) *StallDetector {
+	return &StallDetector{
+		redis:         redis,
+		state:         rediscommon.NewClient(redis, logger),
+		runs:          runs,
+		publisher:     workflow_lifecycle.NewEventPublisher(rediscommon.NewClient(redis, logger), logger),
+		logger:        logger,
+		checkInterval: time.Minute,
+		threshold:     30 * time.Minute,
+	}
+}
+
+// WithCheckInterval sets how often runs are scanned
+func (d *StallDetector) WithCheckInterval(interval time.Duration) *StallDetector {
+	d.checkInterval = interval
+	return d
+}
+
+// WithThreshold sets how long a run may go without progress before it is stalled
+// Keep it above the longest node timeout, or slow nodes are reported as stalls.
+func (d *StallDetector) WithThreshold(threshold time.Duration) *StallDetector {
+	d.threshold = threshold
+	return d
+}
+
+// WithStatusUpdate makes flagged runs also persist the STALLED status
+// The status is kept until the run finishes, even if it progresses again.
+func (d *StallDetector) WithStatusUpdate(enabled bool) *StallDetector {
+	d.markStatus = enabled
+	return d
+}
+
+// Start begins the stall detector
+func (d *StallDetector) Start(ctx context.Context) error {
+	d.logger.Info("stall detector starting",
+		"check_interval", d.checkInterval,
+		"threshold", d.threshold)
+
+	ticker := time.NewTicker(d.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.logger.Info("stall detector shutting down")
+			return ctx.Err()
+		case <-ticker.C:
+			if err := d.checkStalledRuns(ctx, time.Now()); err != nil {
+				d.logger.Error("failed to check stalled runs", "error", err)
+			}
+		}
+	}
+}
+
+// checkStalledRuns flags every run idle past the threshold
+// Runs whose counter is zero or gone have finished and are no longer tracked.
+// The stalled runs gauge is set to the runs found stalled by this scan.
+func (d *StallDetector) checkStalledRuns(ctx context.Context, now time.Time) error {
+	idle, err := sdk.IdleRuns(ctx, d.redis, now.Add(-d.threshold))
+	if err != nil {
+		return err
+	}
+	tracked, err := sdk.TrackedRuns(ctx, d.redis)
+	if err != nil {
+		return err
+	}
+	for _, runID := range tracked {
+		if _, isIdle := idle[runID]; !isIdle {
+			d.keepAlive(ctx, runID)
+		}
+	}
+
+	stalled := 0
+	for runID, lastProgress := range idle {
+		counter, err := d.redis.Get(ctx, sdk.CounterKey(runID)).Int()
+		if err != nil && err != redis.Nil {
+			d.logger.Warn("failed to get counter of idle run", "run_id", runID, "error", err)
+			continue
+		}
+		finished := counter <= 0
+		if !finished {
+			if finished, err = d.runFinished(ctx, runID); err != nil {
+				d.logger.Warn("failed to get status of idle run", "run_id", runID, "error", err)
+				continue
+			}
+		}
+		if finished {
+			if err := sdk.ForgetProgress(ctx, d.redis, runID); err != nil {
+				d.logger.Warn("failed to forget finished run", "run_id", runID, "error", err)
+			}
+			continue
+		}
+
+		waiting, err := d.waitingOnPurpose(ctx, runID)
+		if err != nil {
+			d.logger.Warn("failed to check idle run", "run_id", runID, "error", err)
+			continue
+		}
+		if waiting {
+			d.keepAlive(ctx, runID)
+			continue
+		}
+
+		stalled++
+		flagged, err := sdk.FlagStalled(ctx, d.redis, runID, lastProgress)
+		if err != nil {
+			d.logger.Warn("failed to flag stalled run", "run_id", runID, "error", err)
+			continue
+		}
+		if flagged {
+			d.reportStall(ctx, runID, counter, lastProgress, now)
+		}
+	}
+
+	metrics.RunsStalled.Set(float64(stalled))
+	return nil
+}
+
+// runFinished returns true for runs in a terminal status
+// A failed or cancelled run keeps its in-flight tokens, so its counter alone
+// doesn't tell. The hot status in Redis is checked first, then the database
+// once the hot status has expired.
+func (d *StallDetector) runFinished(ctx context.Context, runID string) (bool, error) {
+	status, err := d.redis.Get(ctx, fmt.Sprintf("run:status:%s", runID)).Result()
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("failed to get run status: %w", err)
+	}
+	if status != "" {
+		return isTerminalStatus(models.RunStatus(status)), nil
+	}
+
+	id, err := uuid.Parse(runID)
+	if err != nil {
+		return false, fmt.Errorf("invalid run_id: %w", err)
+	}
+	statuses, err := d.runs.GetStatuses(ctx, []uuid.UUID{id})
+	if err != nil {
+		return false, fmt.Errorf("failed to get run status: %w", err)
+	}
+	persisted, ok := statuses[id]
+	return ok && isTerminalStatus(persisted), nil
+}
+
+// waitingOnPurpose returns true for runs that are idle by design
+// Paused runs and runs with a pending approval wait on a person, not a worker;
+// runs with a pending async webhook wait on its callback.
+func (d *StallDetector) waitingOnPurpose(ctx context.Context, runID string) (bool, error) {
+	paused, err := sdk.IsRunPaused(ctx, d.redis, runID)
+	if err != nil || paused {
+		return paused, err
+	}
+
+	approvals, err := d.redis.Get(ctx, fmt.Sprintf("run:%s:pending_approvals", runID)).Int()
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("failed to get pending approvals: %w", err)
+	}
+	if approvals > 0 {
+		return true, nil
+	}
+
+	return sdk.HasPendingWebhook(ctx, d.redis, runID)
+}
+
+// keepAlive renews a live run's Redis state once half of its TTL has run out
+// The IR's TTL stands for the whole state: counter operations renew the counter
+// and applied set on their own, but nothing else while a run waits.
+func (d *StallDetector) keepAlive(ctx context.Context, runID string) {
+	ttl, err := d.redis.PTTL(ctx, fmt.Sprintf("ir:%s", runID)).Result()
+	if err != nil {
+		d.logger.Warn("failed to get run state TTL", "run_id", runID, "error", err)
+		return
+	}
+	// Missing or persistent IRs have a negative TTL
+	if ttl <= 0 || ttl > sdk.RunStateTTL/2 {
+		return
+	}
+
+	if err := sdk.ExpireRunState(ctx, d.state, runID, sdk.RunStateTTL); err != nil {
+		d.logger.Warn("failed to renew run state", "run_id", runID, "error", err)
+		return
+	}
+	d.logger.Debug("renewed run state", "run_id", runID, "ttl", sdk.RunStateTTL)
+}
+
+// isTerminalStatus returns true for statuses a run never leaves on its own
+func isTerminalStatus(status models.RunStatus) bool {
+	switch status {
+	case models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// reportStall publishes run_stalled and, if enabled, persists the STALLED status
+func (d *StallDetector) reportStall(ctx context.Context, runID string, counter int, lastProgress, now time.Time) {
+	idleFor := now.Sub(lastProgress)
+	d.logger.Warn("run stalled",
+		"run_id", runID,
+		"counter", counter,
+		"idle_for", idleFor)
+	metrics.RunStallsDetected.Inc()
+
+	d.publisher.PublishRunEvent(ctx, d.runOwner(ctx, runID), runID, map[string]interface{}{
+		"type":          "run_stalled",
+		"run_id":        runID,
+		"counter":       counter,
+		"last_progress": lastProgress.UTC().Format(time.RFC3339Nano),
+		"idle_ms":       idleFor.Milliseconds(),
+		"timestamp":     now.Unix(),
+	})
+
+	if !d.markStatus {
+		return
+	}
+	// The run may have finished since the scan read its status
+	finished, err := d.runFinished(ctx, runID)
+	if err != nil {
+		d.logger.Warn("failed to get run status, not marking stalled", "run_id", runID, "error", err)
+		return
+	}
+	if finished {
+		return
+	}
+	id, err := uuid.Parse(runID)
+	if err != nil {
+		d.logger.Warn("invalid run_id, not marking stalled", "run_id", runID, "error", err)
+		return
+	}
+	if err := d.runs.UpdateStatus(ctx, id, models.StatusStalled); err != nil {
+		d.logger.Error("failed to mark run stalled", "run_id", runID, "error", err)
+	}
+}
+
+// runOwner returns the username the run's IR records, or "" if it can't be read
+func (d *StallDetector) runOwner(ctx context.Context, runID string) string {
+	data, err := d.redis.Get(ctx, fmt.Sprintf("ir:%s", runID)).Result()
+	if err != nil {
+		return ""
+	}
+
+	var ir sdk.IR
+	if err := json.Unmarshal([]byte(data), &ir); err != nil {
+		return ""
+	}
+	username, _ := ir.Metadata["username"].(string)
+	return username
+}
@@ -0,0 +1,193 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/workflow_lifecycle"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/lyzr/orchestrator/common/models"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStallDetectorFlagsRunWithoutProgress(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, string(luaScript))
+	store := &fakeRunStore{statuses: make(map[uuid.UUID]models.RunStatus)}
+	threshold := 10 * time.Minute
+	detector := NewStallDetector(rdb, store, logger).WithThreshold(threshold).WithStatusUpdate(true)
+	ctx := context.Background()
+
+	// stuck fans out to two nodes, one completes, and the other never does
+	stuck := uuid.NewString()
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, stuck, 1))
+	require.NoError(t, workflowSDK.Emit(ctx, stuck, "fetch", []string{"left", "right"}, ""))
+	require.NoError(t, workflowSDK.Consume(ctx, stuck, "fetch"))
+	require.NoError(t, workflowSDK.Consume(ctx, stuck, "left"))
+	lastProgress := time.Now()
+
+	// paused waits on a person; done finished
+	paused := uuid.NewString()
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, paused, 1))
+	_, err = sdk.PauseRun(ctx, rdb, paused)
+	require.NoError(t, err)
+	done := uuid.NewString()
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, done, 1))
+	require.NoError(t, workflowSDK.Consume(ctx, done, "only"))
+
+	// Within the threshold nothing is flagged
+	stallsBefore := metrics.RunStallsDetected.Value()
+	require.NoError(t, detector.checkStalledRuns(ctx, lastProgress.Add(threshold-time.Minute)))
+	assert.False(t, mr.Exists(sdk.RunStalledKey(stuck)))
+	assert.Zero(t, metrics.RunsStalled.Value())
+
+	// Past it, only the stuck run is flagged, and finished runs stop being tracked
+	scanAt := lastProgress.Add(threshold + time.Minute)
+	require.NoError(t, detector.checkStalledRuns(ctx, scanAt))
+	assert.True(t, mr.Exists(sdk.RunStalledKey(stuck)))
+	assert.False(t, mr.Exists(sdk.RunStalledKey(paused)))
+	assert.Equal(t, models.StatusStalled, store.status(uuid.MustParse(stuck)))
+	assert.Empty(t, store.status(uuid.MustParse(paused)))
+	assert.Equal(t, float64(1), metrics.RunsStalled.Value())
+	assert.Equal(t, float64(1), metrics.RunStallsDetected.Value()-stallsBefore)
+	assert.ErrorIs(t, rdb.ZScore(ctx, sdk.RunProgressKey, done).Err(), redis.Nil)
+
+	events := rdb.XRange(ctx, workflow_lifecycle.RunEventLogKey(stuck), "-", "+").Val()
+	require.Len(t, events, 1)
+	var event map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(events[0].Values["event"].(string)), &event))
+	assert.Equal(t, "run_stalled", event["type"])
+	assert.EqualValues(t, 1, event["counter"])
+
+	// A stall is reported once
+	require.NoError(t, detector.checkStalledRuns(ctx, scanAt.Add(time.Minute)))
+	assert.Equal(t, float64(1), metrics.RunStallsDetected.Value()-stallsBefore)
+	assert.Len(t, rdb.XRange(ctx, workflow_lifecycle.RunEventLogKey(stuck), "-", "+").Val(), 1)
+
+	// Progress clears the flag
+	require.NoError(t, workflowSDK.Consume(ctx, stuck, "right"))
+	assert.False(t, mr.Exists(sdk.RunStalledKey(stuck)))
+}
+
+func TestStallDetectorSkipsFinishedRunsAndWebhookWaits(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	logger := noopLogger{}
+	store := &fakeRunStore{statuses: make(map[uuid.UUID]models.RunStatus)}
+	threshold := 10 * time.Minute
+	detector := NewStallDetector(rdb, store, logger).WithThreshold(threshold).WithStatusUpdate(true)
+	ctx := context.Background()
+	lastProgress := time.Now()
+
+	// Every run still has a token in flight
+	track := func() string {
+		runID := uuid.NewString()
+		require.NoError(t, rdb.Set(ctx, sdk.CounterKey(runID), 1, 0).Err())
+		require.NoError(t, sdk.RecordProgress(ctx, rdb, runID, lastProgress))
+		return runID
+	}
+
+	// failed says so in Redis; cancelled's hot status expired, leaving the database
+	failed := track()
+	require.NoError(t, rdb.Set(ctx, "run:status:"+failed, string(models.StatusFailed), 0).Err())
+	cancelled := track()
+	store.statuses[uuid.MustParse(cancelled)] = models.StatusCancelled
+
+	// callback waits on an async webhook; called back's webhook already answered
+	callback := track()
+	pending, err := json.Marshal(sdk.WebhookPending{RunID: callback, NodeID: "notify", Status: sdk.WebhookStatusPending})
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, sdk.WebhookPendingKey(callback, "notify"), pending, 0).Err())
+	require.NoError(t, sdk.TrackRunKeys(ctx, rdb, callback, sdk.WebhookPendingKey(callback, "notify")))
+	calledBack := track()
+	processed, err := json.Marshal(sdk.WebhookPending{RunID: calledBack, NodeID: "notify", Status: sdk.WebhookStatusCompleted})
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, sdk.WebhookPendingKey(calledBack, "notify"), processed, 0).Err())
+	require.NoError(t, sdk.TrackRunKeys(ctx, rdb, calledBack, sdk.WebhookPendingKey(calledBack, "notify")))
+
+	require.NoError(t, detector.checkStalledRuns(ctx, lastProgress.Add(threshold+time.Minute)))
+
+	// Finished runs are neither flagged nor overwritten, and stop being tracked
+	for _, runID := range []string{failed, cancelled} {
+		assert.False(t, mr.Exists(sdk.RunStalledKey(runID)), runID)
+		assert.ErrorIs(t, rdb.ZScore(ctx, sdk.RunProgressKey, runID).Err(), redis.Nil)
+	}
+	assert.Equal(t, models.StatusCancelled, store.status(uuid.MustParse(cancelled)))
+	assert.Empty(t, store.status(uuid.MustParse(failed)))
+
+	// A pending callback is a wait by design; an answered one isn't
+	assert.False(t, mr.Exists(sdk.RunStalledKey(callback)))
+	assert.NoError(t, rdb.ZScore(ctx, sdk.RunProgressKey, callback).Err())
+	assert.True(t, mr.Exists(sdk.RunStalledKey(calledBack)))
+	assert.Equal(t, models.StatusStalled, store.status(uuid.MustParse(calledBack)))
+}
+
+func TestStallDetectorKeepsLiveRunStateAlive(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	workflowSDK := sdk.NewSDK(rdb, clients.NewRedisCASClient(rdb, logger), logger, string(luaScript))
+	store := &fakeRunStore{statuses: make(map[uuid.UUID]models.RunStatus)}
+	threshold := 10 * time.Minute
+	detector := NewStallDetector(rdb, store, logger).WithThreshold(threshold)
+	ctx := context.Background()
+
+	// Three runs, 20 hours in: one still progressing, one paused, one stalled
+	start := func() string {
+		runID := uuid.NewString()
+		require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 2))
+		require.NoError(t, rdb.Set(ctx, "ir:"+runID, "{}", sdk.RunStateTTL).Err())
+		require.NoError(t, rdb.HSet(ctx, "context:"+runID, "fetch:output", "artifact://fetch").Err())
+		require.NoError(t, rdb.Expire(ctx, "context:"+runID, sdk.RunStateTTL).Err())
+		return runID
+	}
+	progressing, paused, stalled := start(), start(), start()
+	_, err = sdk.PauseRun(ctx, rdb, paused)
+	require.NoError(t, err)
+	mr.FastForward(20 * time.Hour)
+	for _, runID := range []string{paused, stalled} {
+		require.NoError(t, sdk.RecordProgress(ctx, rdb, runID, time.Now().Add(-20*time.Hour)))
+	}
+
+	// Counter operations renew the counter and applied set on their own
+	require.NoError(t, workflowSDK.Consume(ctx, progressing, "fetch"))
+	assert.Equal(t, sdk.RunStateTTL, mr.TTL(sdk.CounterKey(progressing)))
+	assert.Equal(t, sdk.RunStateTTL, mr.TTL(sdk.AppliedKey(progressing)))
+
+	require.NoError(t, detector.checkStalledRuns(ctx, time.Now()))
+
+	// The scan renews the rest of the live runs' state, the stalled run's runs out
+	for _, runID := range []string{progressing, paused} {
+		assert.Equal(t, sdk.RunStateTTL, mr.TTL("ir:"+runID), runID)
+		assert.Equal(t, sdk.RunStateTTL, mr.TTL("context:"+runID), runID)
+		assert.Equal(t, sdk.RunStateTTL, mr.TTL(sdk.CounterKey(runID)), runID)
+	}
+	assert.True(t, mr.Exists(sdk.RunStalledKey(stalled)))
+	mr.FastForward(5 * time.Hour)
+	assert.False(t, mr.Exists("ir:"+stalled))
+	assert.False(t, mr.Exists(sdk.CounterKey(stalled)))
+	assert.True(t, mr.Exists(sdk.CounterKey(paused)))
+}
@@ -2,30 +2,34 @@ package supervisor
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
 	"github.com/redis/go-redis/v9"
 )
 
-// TimeoutDetector monitors for hanging workflows and marks them as failed
+// TimeoutDetector times out nodes whose tokens stall in flight
+// The coordinator tracks every token it dispatches together with the token's
+// sent_at (see sdk.TrackInflight). A token still in flight past its node's
+// timeout_ms, or the default timeout, is claimed and handed back to the
+// coordinator with a timeout signal, which dispatches it again or fails the node.
 type TimeoutDetector struct {
-	redis         *redis.Client
-	db            *sql.DB
+	redis         redis.UniversalClient
 	logger        Logger
 	checkInterval time.Duration
 	timeout       time.Duration
 }
 
 // NewTimeoutDetector creates a new timeout detector
-func NewTimeoutDetector(redis *redis.Client, db *sql.DB, logger Logger) *TimeoutDetector {
+func NewTimeoutDetector(redis redis.UniversalClient, logger Logger) *TimeoutDetector {
 	return &TimeoutDetector{
 		redis:         redis,
-		db:            db,
 		logger:        logger,
 		checkInterval: 30 * time.Second, // Check every 30 seconds
-		timeout:       5 * time.Minute,  // Consider hung after 5 minutes of inactivity
+		timeout:       5 * time.Minute,  // Default for nodes without timeout_ms
 	}
 }
 
@@ -35,7 +39,8 @@ func (t *TimeoutDetector) WithCheckInterval(interval time.Duration) *TimeoutDete
 	return t
 }
 
-// WithTimeout sets the inactivity timeout
+// WithTimeout sets the default node timeout
+// Nodes waiting for an approval or a callback only time out with an explicit timeout_ms.
 func (t *TimeoutDetector) WithTimeout(timeout time.Duration) *TimeoutDetector {
 	t.timeout = timeout
 	return t
@@ -56,171 +61,167 @@ func (t *TimeoutDetector) Start(ctx context.Context) error {
 			t.logger.Info("timeout detector shutting down")
 			return ctx.Err()
 		case <-ticker.C:
-			if err := t.checkHangingWorkflows(ctx); err != nil {
-				t.logger.Error("failed to check hanging workflows", "error", err)
+			if err := t.checkStalledNodes(ctx, time.Now()); err != nil {
+				t.logger.Error("failed to check stalled nodes", "error", err)
 			}
 		}
 	}
 }
 
-// checkHangingWorkflows finds and marks hanging workflows as failed
-func (t *TimeoutDetector) checkHangingWorkflows(ctx context.Context) error {
-	// Query for runs that are RUNNING but have no activity for > timeout
-	query := `
-		SELECT run_id, last_event_at
-		FROM run
-		WHERE status = 'RUNNING'
-		  AND last_event_at < $1
-		LIMIT 100
-	`
-
-	cutoff := time.Now().UTC().Add(-t.timeout)
-	rows, err := t.db.QueryContext(ctx, query, cutoff)
+// checkStalledNodes signals a timeout for every in-flight token past its deadline
+// Tokens of runs whose state is gone, or of nodes that are no longer in flight,
+// are dropped from tracking.
+func (t *TimeoutDetector) checkStalledNodes(ctx context.Context, now time.Time) error {
+	tokens, err := sdk.ListInflight(ctx, t.redis)
 	if err != nil {
-		return fmt.Errorf("failed to query hanging workflows: %w", err)
+		return err
 	}
-	defer rows.Close()
 
-	var hangingCount int
-	for rows.Next() {
-		var runID string
-		var lastEventAt time.Time
+	irs := make(map[string]*sdk.IR)
+	var timedOutCount int
+	for _, token := range tokens {
+		ir, loaded := irs[token.RunID]
+		if !loaded {
+			ir, err = t.loadIR(ctx, token.RunID)
+			if err != nil {
+				t.logger.Warn("failed to load IR for in-flight token",
+					"run_id", token.RunID,
+					"error", err)
+				continue
+			}
+			irs[token.RunID] = ir
+		}
 
-		if err := rows.Scan(&runID, &lastEventAt); err != nil {
-			t.logger.Error("failed to scan row", "error", err)
+		var node *sdk.Node
+		if ir != nil {
+			node = ir.Nodes[token.NodeID]
+		}
+		if node == nil {
+			t.untrack(ctx, token, "run state gone")
 			continue
 		}
 
-		t.logger.Warn("detected hanging workflow",
-			"run_id", runID,
-			"last_event_at", lastEventAt,
-			"inactive_duration", time.Since(lastEventAt))
-
-		// Check if counter is stuck (non-zero)
-		counter, err := t.getCounter(ctx, runID)
+		timeout, inFlight, err := t.timeoutFor(ctx, token, node)
 		if err != nil {
-			t.logger.Error("failed to get counter",
-				"run_id", runID,
+			t.logger.Warn("failed to get node status for in-flight token",
+				"run_id", token.RunID,
+				"node_id", token.NodeID,
 				"error", err)
 			continue
 		}
+		if !inFlight {
+			t.untrack(ctx, token, "node no longer in flight")
+			continue
+		}
+		if timeout <= 0 {
+			continue
+		}
+
+		sentAt, err := time.Parse(time.RFC3339Nano, token.SentAt)
+		if err != nil {
+			t.logger.Warn("in-flight token has no valid sent_at",
+				"run_id", token.RunID,
+				"job_id", token.JobID,
+				"sent_at", token.SentAt)
+			continue
+		}
+		if now.Sub(sentAt) < timeout {
+			continue
+		}
 
-		t.logger.Debug("hanging workflow counter check",
-			"run_id", runID,
-			"counter", counter)
+		// Paused runs keep their tokens; they are checked again after resume
+		if paused, err := sdk.IsRunPaused(ctx, t.redis, token.RunID); err != nil || paused {
+			continue
+		}
 
-		// If counter is 0 but workflow is still RUNNING, something is wrong
-		// If counter is > 0, workflow is stuck
-		if err := t.markFailed(ctx, runID, fmt.Sprintf("timeout: no activity for %s, counter=%d", t.timeout, counter)); err != nil {
-			t.logger.Error("failed to mark as failed",
-				"run_id", runID,
+		claimed, err := sdk.ClaimTimedOut(ctx, t.redis, token)
+		if err != nil {
+			t.logger.Error("failed to claim timed out token",
+				"run_id", token.RunID,
+				"job_id", token.JobID,
 				"error", err)
 			continue
 		}
+		if !claimed {
+			continue // Completed meanwhile
+		}
 
-		hangingCount++
+		t.logger.Warn("detected stalled node",
+			"run_id", token.RunID,
+			"node_id", token.NodeID,
+			"job_id", token.JobID,
+			"attempt", token.Attempt,
+			"in_flight", now.Sub(sentAt),
+			"timeout", timeout)
+
+		if err := worker.SignalTimeout(ctx, t.redis, t.logger, token, timeout); err != nil {
+			t.logger.Error("failed to signal timeout",
+				"run_id", token.RunID,
+				"node_id", token.NodeID,
+				"error", err)
+			continue
+		}
+		timedOutCount++
 	}
 
-	if hangingCount > 0 {
-		t.logger.Info("marked hanging workflows as failed", "count", hangingCount)
+	if timedOutCount > 0 {
+		t.logger.Info("timed out stalled nodes", "count", timedOutCount)
 	}
 
 	return nil
 }
 
-// getCounter gets the counter value from Redis
-func (t *TimeoutDetector) getCounter(ctx context.Context, runID string) (int, error) {
-	key := fmt.Sprintf("counter:%s", runID)
-	val, err := t.redis.Get(ctx, key).Int()
-	if err == redis.Nil {
-		return 0, nil
-	}
+// timeoutFor returns how long a token may stay in flight
+// Returns inFlight=false if the node already completed, failed or was cancelled,
+// and a zero timeout for waiting nodes without an explicit timeout_ms.
+func (t *TimeoutDetector) timeoutFor(ctx context.Context, token *sdk.InflightToken, node *sdk.Node) (time.Duration, bool, error) {
+	status, err := sdk.GetNodeStatus(ctx, t.redis, token.RunID, token.NodeID)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
-	return val, nil
-}
 
-// markFailed marks a workflow as failed in the database
-func (t *TimeoutDetector) markFailed(ctx context.Context, runID, reason string) error {
-	query := `
-		UPDATE run
-		SET
-			status = 'FAILED',
-			ended_at = $1,
-			last_event_at = $1
-		WHERE run_id = $2
-		  AND status = 'RUNNING'
-	`
-
-	result, err := t.db.ExecContext(ctx, query, time.Now().UTC(), runID)
-	if err != nil {
-		return fmt.Errorf("failed to update run status: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rowsAffected == 0 {
-		t.logger.Warn("run already completed or failed",
-			"run_id", runID)
-		return nil
+	explicit := time.Duration(node.TimeoutMS) * time.Millisecond
+	switch status {
+	case sdk.NodeStatusRunning:
+		if explicit > 0 {
+			return explicit, true, nil
+		}
+		return t.timeout, true, nil
+	case sdk.NodeStatusWaitingForApproval, sdk.NodeStatusWaitingForCallback:
+		return explicit, true, nil
+	default:
+		return 0, false, nil
 	}
-
-	// Log event
-	t.logger.Info("marked workflow as failed",
-		"run_id", runID,
-		"reason", reason)
-
-	// TODO: Write to event_log table for audit trail
-
-	// Cleanup Redis keys
-	t.cleanupFailedRun(ctx, runID)
-
-	return nil
 }
 
-// cleanupFailedRun removes Redis keys for failed run
-func (t *TimeoutDetector) cleanupFailedRun(ctx context.Context, runID string) {
-	keys := []string{
-		fmt.Sprintf("counter:%s", runID),
-		fmt.Sprintf("applied:%s", runID),
-		fmt.Sprintf("context:%s", runID),
-		fmt.Sprintf("ir:%s", runID),
-	}
-
-	pipe := t.redis.Pipeline()
-	for _, key := range keys {
-		pipe.Del(ctx, key)
+// loadIR loads a run's IR, or nil if the run state is gone
+func (t *TimeoutDetector) loadIR(ctx context.Context, runID string) (*sdk.IR, error) {
+	data, err := t.redis.Get(ctx, fmt.Sprintf("ir:%s", runID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
 	}
-
-	// Also delete any loop state keys
-	loopPattern := fmt.Sprintf("loop:%s:*", runID)
-	loopKeys, err := t.redis.Keys(ctx, loopPattern).Result()
-	if err == nil {
-		for _, key := range loopKeys {
-			pipe.Del(ctx, key)
-		}
+	if err != nil {
+		return nil, err
 	}
 
-	// Also delete any pending token keys
-	pendingPattern := fmt.Sprintf("pending_tokens:%s:*", runID)
-	pendingKeys, err := t.redis.Keys(ctx, pendingPattern).Result()
-	if err == nil {
-		for _, key := range pendingKeys {
-			pipe.Del(ctx, key)
-		}
+	var ir sdk.IR
+	if err := json.Unmarshal(data, &ir); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal IR: %w", err)
 	}
+	return &ir, nil
+}
 
-	_, err = pipe.Exec(ctx)
-	if err != nil {
-		t.logger.Error("failed to cleanup Redis keys",
-			"run_id", runID,
+// untrack drops a token that can no longer time out
+func (t *TimeoutDetector) untrack(ctx context.Context, token *sdk.InflightToken, reason string) {
+	if _, err := sdk.ClearInflight(ctx, t.redis, token.JobID); err != nil {
+		t.logger.Warn("failed to clear in-flight token",
+			"run_id", token.RunID,
+			"job_id", token.JobID,
 			"error", err)
-	} else {
-		t.logger.Debug("cleaned up Redis keys for failed run",
-			"run_id", runID)
+		return
 	}
+	t.logger.Debug("dropped in-flight token",
+		"run_id", token.RunID,
+		"job_id", token.JobID,
+		"reason", reason)
 }
@@ -0,0 +1,175 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/cmd/workflow-runner/coordinator"
+	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/compiler"
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (noopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// timeoutFixture runs a coordinator over A → B → C where B has a 100ms timeout
+type timeoutFixture struct {
+	ctx      context.Context
+	rdb      *redis.Client
+	detector *TimeoutDetector
+	runID    string
+}
+
+func newTimeoutFixture(t *testing.T, retry *sdk.RetryPolicy) *timeoutFixture {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	luaScript, err := os.ReadFile("../../../scripts/apply_delta.lua")
+	require.NoError(t, err)
+
+	logger := noopLogger{}
+	casClient := clients.NewRedisCASClient(rdb, logger)
+	workflowSDK := sdk.NewSDK(rdb, casClient, logger, string(luaScript))
+	coord := coordinator.NewCoordinator(&coordinator.CoordinatorOpts{
+		Redis:     rdb,
+		SDK:       workflowSDK,
+		Logger:    logger,
+		CASClient: casClient,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go coord.Start(ctx)
+
+	runID := "run_timeout_test"
+	ir, err := compiler.CompileWorkflowSchema(&compiler.WorkflowSchema{
+		Nodes: []compiler.WorkflowNode{
+			{ID: "A", Type: "http", Config: map[string]interface{}{"url": "https://example.com/a"}},
+			{ID: "B", Type: "http", Config: map[string]interface{}{"url": "https://example.com/b"}, TimeoutMS: 100, Retry: retry},
+			{ID: "C", Type: "http", Config: map[string]interface{}{"url": "https://example.com/c"}},
+		},
+		Edges: []compiler.WorkflowEdge{
+			{From: "A", To: "B"},
+			{From: "B", To: "C"},
+		},
+		Metadata: map[string]interface{}{"username": "alice"},
+	}, casClient)
+	require.NoError(t, err)
+
+	irJSON, err := json.Marshal(ir)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, "ir:"+runID, irJSON, 0).Err())
+	require.NoError(t, workflowSDK.InitializeCounter(ctx, runID, 1))
+
+	require.NoError(t, worker.SignalCompletion(ctx, rdb, logger, &worker.CompletionOpts{
+		Token:      &sdk.Token{ID: runID + "-A", RunID: runID, ToNode: "A"},
+		Status:     "completed",
+		ResultData: map[string]interface{}{"a": float64(1)},
+	}))
+
+	return &timeoutFixture{
+		ctx:      ctx,
+		rdb:      rdb,
+		detector: NewTimeoutDetector(rdb, logger),
+		runID:    runID,
+	}
+}
+
+func (f *timeoutFixture) tokensFor(t *testing.T, node string, n int) []*sdk.Token {
+	t.Helper()
+	var tokens []*sdk.Token
+	require.Eventually(t, func() bool {
+		tokens = nil
+		for _, msg := range f.rdb.XRange(f.ctx, "wf.tasks.http", "-", "+").Val() {
+			var token sdk.Token
+			require.NoError(t, json.Unmarshal([]byte(msg.Values["token"].(string)), &token))
+			if token.ToNode == node {
+				tokens = append(tokens, &token)
+			}
+		}
+		return len(tokens) == n
+	}, 5*time.Second, 20*time.Millisecond)
+	return tokens
+}
+
+func (f *timeoutFixture) runStatus() string {
+	status, _ := f.rdb.Get(f.ctx, "run:status:"+f.runID).Result()
+	return status
+}
+
+// TestTimeoutDetectorFailsStalledNode leaves B without a completion and checks
+// the detector fails it once its timeout_ms has passed.
+func TestTimeoutDetectorFailsStalledNode(t *testing.T) {
+	f := newTimeoutFixture(t, nil)
+	b := f.tokensFor(t, "B", 1)[0]
+
+	// Within the timeout nothing happens
+	require.NoError(t, f.detector.checkStalledNodes(f.ctx, time.Now()))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, sdk.NodeStatusRunning, f.rdb.Get(f.ctx, sdk.NodeStatusKey(f.runID, "B")).Val())
+
+	require.NoError(t, f.detector.checkStalledNodes(f.ctx, time.Now().Add(time.Second)))
+	require.Eventually(t, func() bool { return f.runStatus() == "FAILED" }, 5*time.Second, 20*time.Millisecond)
+
+	assert.Equal(t, sdk.NodeStatusFailed, f.rdb.Get(f.ctx, sdk.NodeStatusKey(f.runID, "B")).Val())
+	failure, err := f.rdb.HGet(f.ctx, "context:"+f.runID, "B:failure:output").Result()
+	require.NoError(t, err)
+	assert.Contains(t, failure, `"error_type":"NodeTimeout"`)
+	assert.False(t, f.rdb.HExists(f.ctx, sdk.InflightTokensKey, b.ID).Val(), "timed out token is no longer tracked")
+
+	// B's late completion is dropped rather than routed to C
+	require.NoError(t, worker.SignalCompletion(f.ctx, f.rdb, noopLogger{}, &worker.CompletionOpts{
+		Token:      b,
+		Status:     "completed",
+		ResultData: map[string]interface{}{"b": float64(2)},
+	}))
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, f.tokensFor(t, "C", 0))
+	assert.Equal(t, sdk.NodeStatusFailed, f.rdb.Get(f.ctx, sdk.NodeStatusKey(f.runID, "B")).Val())
+}
+
+// TestTimeoutDetectorRetriesPerPolicy gives B two attempts: the first timeout
+// dispatches it again, and the retried token completing lets the run continue.
+func TestTimeoutDetectorRetriesPerPolicy(t *testing.T) {
+	f := newTimeoutFixture(t, &sdk.RetryPolicy{MaxAttempts: 2})
+	first := f.tokensFor(t, "B", 1)[0]
+
+	require.NoError(t, f.detector.checkStalledNodes(f.ctx, time.Now().Add(time.Second)))
+	retried := f.tokensFor(t, "B", 2)[1]
+	assert.NotEqual(t, first.ID, retried.ID)
+	assert.Equal(t, first.PayloadRef, retried.PayloadRef)
+	assert.Equal(t, "A", retried.FromNode)
+	assert.NotEqual(t, "FAILED", f.runStatus())
+
+	// The first attempt's late completion no longer counts
+	require.NoError(t, worker.SignalCompletion(f.ctx, f.rdb, noopLogger{}, &worker.CompletionOpts{
+		Token:      first,
+		Status:     "completed",
+		ResultData: map[string]interface{}{"b": "late"},
+	}))
+	require.NoError(t, worker.SignalCompletion(f.ctx, f.rdb, noopLogger{}, &worker.CompletionOpts{
+		Token:      retried,
+		Status:     "completed",
+		ResultData: map[string]interface{}{"b": float64(2)},
+	}))
+	c := f.tokensFor(t, "C", 1)[0]
+	assert.Equal(t, "B", c.FromNode)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, f.tokensFor(t, "C", 1), 1)
+}
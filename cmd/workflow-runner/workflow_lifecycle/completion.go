@@ -57,43 +57,15 @@ func (c *CompletionChecker) CheckCompletion(ctx context.Context, runID string) {
 		c.logger.Info("workflow completed",
 			"run_id", runID)
 
-		// Load IR to get username for event publishing
-		ir, err := c.loadIR(ctx, runID)
-		if err == nil && ir.Metadata != nil {
-			if username, ok := ir.Metadata["username"].(string); ok {
-				// Publish workflow_completed event
-				c.publisher.PublishWorkflowEvent(ctx, username, map[string]interface{}{
-					"type":      "workflow_completed",
-					"run_id":    runID,
-					"counter":   0,
-					"timestamp": time.Now().Unix(),
-				})
-			}
-		}
-
-		// Update run status (both Redis hot path and DB cold path)
+		// Update run status (both Redis hot path and DB cold path); the
+		// completion supervisor publishes workflow_completed once it has
+		// verified the counter stayed at zero
 		c.statusMgr.UpdateRunStatus(ctx, runID, "COMPLETED")
 
 		// TODO: Cleanup Redis keys
 	}
 }
 
-// loadIR loads the latest IR from Redis
-func (c *CompletionChecker) loadIR(ctx context.Context, runID string) (*sdk.IR, error) {
-	key := fmt.Sprintf("ir:%s", runID)
-	data, err := c.redis.Get(ctx, key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get IR from Redis: %w", err)
-	}
-
-	var ir sdk.IR
-	if err := json.Unmarshal([]byte(data), &ir); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal IR: %w", err)
-	}
-
-	return &ir, nil
-}
-
 // EventPublisher publishes workflow events to Redis PubSub
 type EventPublisher struct {
 	redis  *redisWrapper.Client
@@ -129,3 +101,38 @@ func (p *EventPublisher) PublishWorkflowEvent(ctx context.Context, username stri
 		"channel", channel,
 		"type", event["type"])
 }
+
+// runEventLogTTL bounds how long a run's event log is kept after its last event
+const runEventLogTTL = 7 * 24 * time.Hour
+
+// runEventLogMaxLen caps a run's event log; it has no consumer groups, so MAXLEN is safe
+const runEventLogMaxLen = 10000
+
+// RunEventLogKey returns the Redis stream holding a run's durable event log
+func RunEventLogKey(runID string) string {
+	return fmt.Sprintf("run:events:%s", runID)
+}
+
+// PublishRunEvent appends an event to the run's durable event log and fans it out
+// Unlike PubSub, the log can be replayed by clients that connect after the fact.
+func (p *EventPublisher) PublishRunEvent(ctx context.Context, username, runID string, event map[string]interface{}) {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		p.logger.Error("failed to marshal run event", "run_id", runID, "error", err)
+		return
+	}
+
+	key := RunEventLogKey(runID)
+	if _, err := p.redis.AddToStream(ctx, key, map[string]interface{}{
+		"type":  event["type"],
+		"event": string(eventJSON),
+	}, redisWrapper.WithMaxLen(runEventLogMaxLen)); err != nil {
+		p.logger.Error("failed to append run event", "run_id", runID, "error", err)
+	} else if err := p.redis.GetUnderlying().Expire(ctx, key, runEventLogTTL).Err(); err != nil {
+		p.logger.Warn("failed to set run event log expiry", "run_id", runID, "error", err)
+	}
+
+	if username != "" {
+		p.PublishWorkflowEvent(ctx, username, event)
+	}
+}
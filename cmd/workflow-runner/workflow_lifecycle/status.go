@@ -7,12 +7,14 @@ import (
 	"time"
 
 	redisWrapper "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
 )
 
 // StatusManager handles run status updates (both Redis hot path and DB cold path)
 type StatusManager struct {
-	redis  *redisWrapper.Client
-	logger Logger
+	redis     *redisWrapper.Client
+	logger    Logger
+	retention time.Duration // How long a finished run's state is kept; see WithRetention
 }
 
 // NewStatusManager creates a new status manager
@@ -23,6 +25,14 @@ func NewStatusManager(redis *redisWrapper.Client, logger Logger) *StatusManager
 	}
 }
 
+// WithRetention sets how long a finished run's coordinator state is kept in Redis
+// Run details, results and retries need it for a while after the run ends.
+// Zero keeps it for sdk.RunStateTTL.
+func (m *StatusManager) WithRetention(retention time.Duration) *StatusManager {
+	m.retention = retention
+	return m
+}
+
 // UpdateRunStatus updates run status in both Redis (hot path) and queues for DB update (cold path)
 // Uses pipelining to batch both operations into a single network round-trip
 func (m *StatusManager) UpdateRunStatus(ctx context.Context, runID, status string) {
@@ -65,4 +75,48 @@ func (m *StatusManager) UpdateRunStatus(ctx context.Context, runID, status strin
 	m.logger.Info("updated run status (Redis + queued for DB)",
 		"run_id", runID,
 		"status", status)
+
+	m.scheduleCleanup(ctx, runID, status)
+	m.forgetProgress(ctx, runID, status)
+}
+
+// forgetProgress stops the stall detector tracking a run that has finished
+// A failed or cancelled run keeps tokens in flight, so it would otherwise sit
+// in the progress set and be reported as stalled.
+func (m *StatusManager) forgetProgress(ctx context.Context, runID, status string) {
+	switch status {
+	case "COMPLETED", "FAILED", "CANCELLED":
+	default:
+		return
+	}
+	if err := sdk.ForgetProgress(ctx, m.redis.GetUnderlying(), runID); err != nil {
+		m.logger.Warn("failed to forget finished run's progress",
+			"run_id", runID,
+			"status", status,
+			"error", err)
+	}
+}
+
+// scheduleCleanup sets a run's state to expire once the retention is over
+// A failed run that is retried is active again and gets the full TTL back.
+func (m *StatusManager) scheduleCleanup(ctx context.Context, runID, status string) {
+	var ttl time.Duration
+	switch status {
+	case "COMPLETED", "FAILED", "CANCELLED":
+		ttl = m.retention
+	case "RUNNING":
+		ttl = sdk.RunStateTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	if err := sdk.ExpireRunState(ctx, m.redis, runID, ttl); err != nil {
+		m.logger.Error("failed to schedule run state cleanup",
+			"run_id", runID,
+			"status", status,
+			"error", err)
+		return
+	}
+	m.logger.Debug("scheduled run state cleanup", "run_id", runID, "status", status, "ttl", ttl)
 }
@@ -0,0 +1,108 @@
+package workflow_lifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/logger"
+	redisWrapper "github.com/lyzr/orchestrator/common/redis"
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// trackedRunKeys are run-1's keys named after a node, found through its key set
+var trackedRunKeys = []string{
+	"run:run-1:node:fetch:status",
+	"loop:run-1:retry",
+	"loop:run-1:retry:history",
+	"webhook:pending:run-1:notify",
+	"logs:run-1:fetch",
+}
+
+// runStateKeys are a run's coordinator state keys, as created while it runs
+var runStateKeys = append([]string{
+	"ir:run-1",
+	"context:run-1",
+	"inputs:run-1",
+	"counter:{run-1}",
+	"applied:{run-1}",
+	"run:{run-1}:paused",
+	"run:run-1:keys",
+}, trackedRunKeys...)
+
+// newStatusTestManager returns a status manager over miniredis seeded with
+// run-1's state and another run's IR
+func newStatusTestManager(t *testing.T, retention time.Duration) (*miniredis.Miniredis, *StatusManager) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	for _, key := range append(runStateKeys, "ir:run-2") {
+		if key == sdk.RunKeysKey("run-1") {
+			continue
+		}
+		require.NoError(t, mr.Set(key, "x"))
+		mr.SetTTL(key, sdk.RunStateTTL)
+	}
+	require.NoError(t, sdk.TrackRunKeys(context.Background(), rdb, "run-1", trackedRunKeys...))
+
+	log := logger.New("error", "text")
+	return mr, NewStatusManager(redisWrapper.NewClient(rdb, log), log).WithRetention(retention)
+}
+
+func TestStatusManager_FinishedRunStateExpiresAfterRetention(t *testing.T) {
+	for _, status := range []string{"COMPLETED", "FAILED", "CANCELLED"} {
+		t.Run(status, func(t *testing.T) {
+			mr, m := newStatusTestManager(t, time.Hour)
+			_, err := mr.ZAdd(sdk.RunProgressKey, 1, "run-1")
+			require.NoError(t, err)
+
+			m.UpdateRunStatus(context.Background(), "run-1", status)
+
+			// The stall detector stops tracking it
+			assert.False(t, mr.Exists(sdk.RunProgressKey))
+
+			// Still readable within the retention window
+			mr.FastForward(30 * time.Minute)
+			for _, key := range runStateKeys {
+				assert.True(t, mr.Exists(key), key)
+			}
+			assert.True(t, mr.Exists("run:status:run-1"))
+
+			// Gone once it passes; other runs and the hot status are untouched
+			mr.FastForward(31 * time.Minute)
+			for _, key := range runStateKeys {
+				assert.False(t, mr.Exists(key), key)
+			}
+			assert.True(t, mr.Exists("ir:run-2"))
+			assert.True(t, mr.Exists("run:status:run-1"))
+		})
+	}
+}
+
+func TestStatusManager_RetriedRunStateGetsFullTTL(t *testing.T) {
+	mr, m := newStatusTestManager(t, time.Hour)
+	ctx := context.Background()
+
+	m.UpdateRunStatus(ctx, "run-1", "FAILED")
+	assert.Equal(t, time.Hour, mr.TTL("ir:run-1"))
+
+	m.UpdateRunStatus(ctx, "run-1", "RUNNING")
+	for _, key := range runStateKeys {
+		assert.Equal(t, sdk.RunStateTTL, mr.TTL(key), key)
+	}
+}
+
+func TestStatusManager_NoRetentionKeepsRunStateTTL(t *testing.T) {
+	mr, m := newStatusTestManager(t, 0)
+
+	m.UpdateRunStatus(context.Background(), "run-1", "COMPLETED")
+	for _, key := range runStateKeys {
+		assert.Equal(t, sdk.RunStateTTL, mr.TTL(key), key)
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// SecretEnv is the environment variable holding the shared token signing secret
+// The orchestrator (issuer) and fanout (verifier) must use the same value.
+const SecretEnv = "AUTH_TOKEN_SECRET"
+
+// DefaultTokenTTL is the lifetime of issued tokens
+const DefaultTokenTTL = 15 * time.Minute
+
+var (
+	// ErrInvalidToken is returned for malformed tokens or bad signatures
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned for tokens past their expiry
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Claims is the signed token payload
+// Subject is the same username the orchestrator reads from X-User-ID.
+type Claims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenManager issues and verifies HMAC-SHA256 signed user tokens
+// Token format: base64url(claims JSON) + "." + base64url(signature)
+type TokenManager struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewTokenManager creates a token manager with the given signing secret
+func NewTokenManager(secret []byte) (*TokenManager, error) {
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("token secret must be at least 16 bytes")
+	}
+	return &TokenManager{
+		secret: secret,
+		now:    time.Now,
+	}, nil
+}
+
+// NewTokenManagerFromEnv creates a token manager using AUTH_TOKEN_SECRET
+func NewTokenManagerFromEnv() (*TokenManager, error) {
+	secret := os.Getenv(SecretEnv)
+	if secret == "" {
+		return nil, fmt.Errorf("%s is not set", SecretEnv)
+	}
+	return NewTokenManager([]byte(secret))
+}
+
+// Issue creates a token for username valid for ttl
+func (m *TokenManager) Issue(username string, ttl time.Duration) (string, error) {
+	if username == "" {
+		return "", fmt.Errorf("username is required")
+	}
+
+	payload, err := json.Marshal(Claims{
+		Subject:   username,
+		ExpiresAt: m.now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + m.sign(encoded), nil
+}
+
+// Verify checks the token signature and expiry and returns the username
+func (m *TokenManager) Verify(token string) (string, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || encoded == "" || signature == "" {
+		return "", ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(m.sign(encoded))) {
+		return "", ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+
+	if m.now().Unix() >= claims.ExpiresAt {
+		return "", ErrExpiredToken
+	}
+
+	return claims.Subject, nil
+}
+
+// sign computes the base64url HMAC of the encoded claims
+func (m *TokenManager) sign(encoded string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TokenFromHeader extracts a bearer token from an Authorization header value
+// Returns empty string if the header is missing or not a bearer token.
+func TokenFromHeader(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
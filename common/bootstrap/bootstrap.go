@@ -15,7 +15,6 @@ import (
 // Setup initializes all service components
 // This is the main entry point for all services
 func Setup(ctx context.Context, serviceName string, opts ...Option) (*Components, error) {
-	fmt.Println("%s", opts)
 	// Apply options
 	options := defaultOptions()
 	for _, opt := range opts {
@@ -117,19 +116,19 @@ func Setup(ctx context.Context, serviceName string, opts ...Option) (*Components
 	}
 
 	// 6. Initialize telemetry (if not skipped)
-	if !options.skipTelemetry && components.Config.Telemetry.EnablePprof {
+	telemetryConfig := components.Config.Telemetry
+	if !options.skipTelemetry && (telemetryConfig.EnablePprof || telemetryConfig.EnableMetrics) {
 		components.Logger.Info("initializing telemetry")
-		components.Telemetry = telemetry.New(
-			components.Config.Telemetry.PprofPort,
-			components.Config.Telemetry.MetricsPort,
-			components.Logger,
-		)
+		components.Telemetry = telemetry.New(telemetryConfig, components.Logger)
 
 		if err := components.Telemetry.Start(ctx); err != nil {
 			components.Logger.Warn("failed to start telemetry", "error", err)
 			// Don't fail startup if telemetry fails
 		}
 	}
+	if !options.skipTelemetry && telemetryConfig.EnableTracing {
+		components.addCleanup(telemetry.InitTracing(ctx, serviceName, telemetryConfig, components.Logger))
+	}
 
 	components.Logger.Info("service initialization complete",
 		"service", serviceName,
@@ -7,9 +7,11 @@ import (
 	"github.com/lyzr/orchestrator/common/cache"
 	"github.com/lyzr/orchestrator/common/config"
 	"github.com/lyzr/orchestrator/common/db"
+	"github.com/lyzr/orchestrator/common/health"
 	"github.com/lyzr/orchestrator/common/logger"
 	"github.com/lyzr/orchestrator/common/queue"
 	"github.com/lyzr/orchestrator/common/telemetry"
+	"github.com/redis/go-redis/v9"
 )
 
 // Components holds all initialized service dependencies
@@ -63,6 +65,20 @@ func (c *Components) Health(ctx context.Context) error {
 	return nil
 }
 
+// Dependencies returns a readiness checker probing the components' external dependencies
+// The database is probed when it was set up and Redis when a client is given
+// (services create their own). The queue and cache are in memory and always up.
+func (c *Components) Dependencies(redisClient redis.UniversalClient) *health.DependencyChecker {
+	checker := health.NewDependencyChecker()
+	if c.DB != nil {
+		checker.Add("database", c.DB.Health)
+	}
+	if redisClient != nil {
+		checker.Add("redis", health.RedisProbe(redisClient))
+	}
+	return checker
+}
+
 // addCleanup registers a cleanup function
 func (c *Components) addCleanup(fn func() error) {
 	c.cleanupFuncs = append(c.cleanupFuncs, fn)
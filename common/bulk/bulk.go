@@ -0,0 +1,60 @@
+// Package bulk splits large fetches into bounded chunks run a few at a time
+// Redis pipelines, CAS reads and Postgres ANY() queries all share it, so a huge
+// fetch neither becomes one giant request nor opens a request per key at once.
+package bulk
+
+import (
+	"context"
+	"sync"
+)
+
+// Chunks splits keys into consecutive chunks of at most size keys
+func Chunks(keys []string, size int) [][]string {
+	if size <= 0 {
+		size = max(len(keys), 1) // Unbounded: one chunk
+	}
+	chunks := make([][]string, 0, (len(keys)+size-1)/size)
+	for start := 0; start < len(keys); start += size {
+		chunks = append(chunks, keys[start:min(start+size, len(keys))])
+	}
+	return chunks
+}
+
+// ForEach calls fetch for every chunk, at most concurrency at once
+// fetch gets the chunk's index, so it can store its result in a slice sized to
+// len(chunks) without locking. The first error cancels the context of the
+// fetches still running and is returned once they have all stopped. A single
+// chunk is fetched on the caller's goroutine.
+func ForEach(ctx context.Context, chunks [][]string, concurrency int, fetch func(ctx context.Context, i int, chunk []string) error) error {
+	if len(chunks) == 1 {
+		return fetch(ctx, 0, chunks[0])
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	slots := make(chan struct{}, max(concurrency, 1))
+	for i, chunk := range chunks {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := fetch(ctx, i, chunk); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,64 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunks(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, Chunks(keys, 2))
+	assert.Equal(t, [][]string{keys}, Chunks(keys, 10))
+	assert.Equal(t, [][]string{keys}, Chunks(keys, 0))
+	assert.Empty(t, Chunks(nil, 2))
+}
+
+func TestForEach(t *testing.T) {
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = string(rune('a' + i%26))
+	}
+	chunks := Chunks(keys, 10)
+
+	var (
+		mu          sync.Mutex
+		inflight    int
+		maxInflight int
+	)
+	sizes := make([]int, len(chunks))
+	err := ForEach(context.Background(), chunks, 3, func(ctx context.Context, i int, chunk []string) error {
+		mu.Lock()
+		inflight++
+		maxInflight = max(maxInflight, inflight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inflight--
+			mu.Unlock()
+		}()
+
+		sizes[i] = len(chunk)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 10, 10, 10, 10, 10, 10, 10, 10, 10}, sizes)
+	assert.LessOrEqual(t, maxInflight, 3)
+}
+
+func TestForEachFirstErrorCancels(t *testing.T) {
+	failure := errors.New("query failed")
+	chunks := Chunks([]string{"a", "b", "c", "d"}, 1)
+
+	err := ForEach(context.Background(), chunks, len(chunks), func(ctx context.Context, i int, chunk []string) error {
+		if i == 0 {
+			return failure
+		}
+		<-ctx.Done() // The others only return once cancelled
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, failure)
+}
@@ -0,0 +1,64 @@
+package clients
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultArtifactCacheSize is how many artifacts an OrchestratorClient keeps in memory
+const defaultArtifactCacheSize = 256
+
+// artifactCache is a fixed-size LRU of artifacts keyed by artifact ID
+// Artifacts are immutable (their content is addressed by cas_id), so a cached
+// entry never goes stale; the size bound only caps memory.
+type artifactCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // Most recently used at the front
+	entries map[string]*list.Element
+}
+
+type artifactCacheEntry struct {
+	id       string
+	artifact *ArtifactResponse
+}
+
+func newArtifactCache(size int) *artifactCache {
+	return &artifactCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns a cached artifact and marks it recently used
+func (c *artifactCache) get(id string) (*ArtifactResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*artifactCacheEntry).artifact, true
+}
+
+// add caches an artifact, evicting the least recently used one when full
+func (c *artifactCache) add(id string, artifact *ArtifactResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[id]; ok {
+		elem.Value.(*artifactCacheEntry).artifact = artifact
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(&artifactCacheEntry{id: id, artifact: artifact})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*artifactCacheEntry).id)
+	}
+}
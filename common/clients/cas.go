@@ -5,7 +5,11 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/lyzr/orchestrator/common/compression"
+	"github.com/lyzr/orchestrator/common/metrics"
 	redisWrapper "github.com/lyzr/orchestrator/common/redis"
 	"github.com/redis/go-redis/v9"
 )
@@ -18,51 +22,88 @@ type CASClient interface {
 	Store(ctx context.Context, data interface{}) (string, error)
 }
 
+// CASOption customizes a CAS client
+type CASOption func(*casOptions)
+
+type casOptions struct {
+	compression          string
+	compressionThreshold int
+	httpClient           *http.Client
+	signer               BlobSigner
+}
+
+func newCASOptions(opts []CASOption) casOptions {
+	options := casOptions{compression: compression.None}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithCompression compresses blobs larger than threshold bytes with codec ("none", "gzip" or "zstd")
+// CAS IDs are computed over the uncompressed content, so dedup is unaffected
+// and readers decode blobs whatever their own setting.
+func WithCompression(codec string, threshold int) CASOption {
+	return func(o *casOptions) {
+		o.compression = codec
+		o.compressionThreshold = threshold
+	}
+}
+
 // RedisCASClient stores CAS blobs in Redis (for workflow execution results)
 // This is used by workflow-runner for temporary storage of execution results
 type RedisCASClient struct {
-	redis  *redisWrapper.Client
-	logger Logger
+	redis   *redisWrapper.Client
+	logger  Logger
+	options casOptions
 }
 
 // NewRedisCASClient creates a new Redis-based CAS client
-func NewRedisCASClient(redis *redis.Client, logger Logger) *RedisCASClient {
+func NewRedisCASClient(redis redis.UniversalClient, logger Logger, opts ...CASOption) *RedisCASClient {
 	return &RedisCASClient{
-		redis:  redisWrapper.NewClient(redis, logger),
-		logger: logger,
+		redis:   redisWrapper.NewClient(redis, logger),
+		logger:  logger,
+		options: newCASOptions(opts),
 	}
 }
 
 // Put stores data in Redis and returns the CAS ID (SHA256 hash)
 func (c *RedisCASClient) Put(ctx context.Context, data []byte, contentType string) (string, error) {
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "put")
+
 	// Generate SHA256 hash as CAS ID
 	hash := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
-	casKey := fmt.Sprintf("cas:%s", hash)
 
-	// Store in Redis with no expiry (adjust based on needs)
-	err := c.redis.SetWithExpiry(ctx, casKey, string(data), 0)
+	stored, encoding, err := compression.Encode(data, c.options.compression, c.options.compressionThreshold)
 	if err != nil {
-		c.logger.Error("failed to store in CAS", "cas_id", hash, "error", err)
-		return "", fmt.Errorf("failed to store in CAS: %w", err)
+		return "", fmt.Errorf("failed to encode CAS entry: %w", err)
 	}
 
-	c.logger.Debug("stored in CAS", "cas_id", hash, "size", len(data))
+	// Store in Redis with no expiry (adjust based on needs)
+	if err := c.setBlob(ctx, hash, stored, encoding, c.options.sign(hash, data), 0); err != nil {
+		return "", err
+	}
 	return hash, nil
 }
 
 // Get retrieves data from Redis by CAS ID
 func (c *RedisCASClient) Get(ctx context.Context, casID string) (interface{}, error) {
-	casKey := fmt.Sprintf("cas:%s", casID)
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "get")
 
-	data, err := c.redis.Get(ctx, casKey)
+	stored, encoding, signature, err := c.getBlob(ctx, casID)
 	if err != nil {
-		// Wrapper already logs errors
-		c.logger.Warn("CAS entry not found", "cas_id", casID)
-		return nil, fmt.Errorf("CAS entry not found: %s", casID)
+		return nil, err
 	}
 
-	c.logger.Debug("retrieved from CAS", "cas_id", casID, "size", len(data))
-	return []byte(data), nil
+	data, err := decodeBlob(casID, stored, encoding)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.options.verify(casID, data, signature); err != nil {
+		c.logger.Error("CAS entry failed verification", "cas_id", casID, "error", err)
+		return nil, err
+	}
+	return data, nil
 }
 
 // Store marshals data to JSON and stores it
@@ -73,3 +114,77 @@ func (c *RedisCASClient) Store(ctx context.Context, data interface{}) (string, e
 	}
 	return c.Put(ctx, jsonData, "application/json")
 }
+
+// setBlob writes a blob with its encoding and signature under its CAS key (expiry 0 keeps it forever)
+// The encoding and signature are written first, so a stored blob is never
+// missing them. Identity blobs have no encoding key; one left by an earlier
+// write of the same content with another codec is deleted.
+func (c *RedisCASClient) setBlob(ctx context.Context, casID string, data []byte, encoding, signature string, expiry time.Duration) error {
+	meta := c.redis.NewPipeline()
+	if signature != "" {
+		meta.SetWithExpiry(ctx, casSignatureKey(casID), signature, expiry)
+	}
+	if encoding != compression.Identity {
+		meta.SetWithExpiry(ctx, casEncodingKey(casID), encoding, expiry)
+	} else {
+		meta.Delete(ctx, casEncodingKey(casID))
+	}
+	if err := meta.Exec(ctx); err != nil {
+		c.logger.Error("failed to store CAS metadata", "cas_id", casID, "error", err)
+		return fmt.Errorf("failed to store in CAS: %w", err)
+	}
+	if err := c.redis.SetWithExpiry(ctx, casBlobKey(casID), string(data), expiry); err != nil {
+		c.logger.Error("failed to store in CAS", "cas_id", casID, "error", err)
+		return fmt.Errorf("failed to store in CAS: %w", err)
+	}
+
+	c.logger.Debug("stored in CAS", "cas_id", casID, "stored_bytes", len(data), "encoding", encoding)
+	return nil
+}
+
+// getBlob reads a blob by CAS ID with its encoding, and its signature if blobs are signed
+// A missing signature is returned as "", for verify to reject; a missing
+// encoding as "", which decodes as identity.
+func (c *RedisCASClient) getBlob(ctx context.Context, casID string) ([]byte, string, string, error) {
+	keys := []string{casBlobKey(casID), casEncodingKey(casID)}
+	if c.options.signing() && contentAddressed(casID) {
+		keys = append(keys, casSignatureKey(casID))
+	}
+
+	fetched, err := c.redis.GetBulk(ctx, keys)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to get CAS entry %s: %w", casID, err)
+	}
+	data, ok := fetched.Found[casBlobKey(casID)]
+	if !ok {
+		c.logger.Warn("CAS entry not found", "cas_id", casID)
+		return nil, "", "", fmt.Errorf("%w: %s", ErrCASNotFound, casID)
+	}
+
+	c.logger.Debug("retrieved from CAS", "cas_id", casID, "stored_bytes", len(data))
+	return []byte(data), fetched.Found[casEncodingKey(casID)], fetched.Found[casSignatureKey(casID)], nil
+}
+
+// casBlobKey returns the key holding a blob
+func casBlobKey(casID string) string {
+	return fmt.Sprintf("cas:%s", casID)
+}
+
+// casSignatureKey returns the key holding a blob's signature
+func casSignatureKey(casID string) string {
+	return fmt.Sprintf("cas:%s:sig", casID)
+}
+
+// casEncodingKey returns the key holding a compressed blob's encoding
+func casEncodingKey(casID string) string {
+	return fmt.Sprintf("cas:%s:enc", casID)
+}
+
+// decodeBlob decompresses a blob read from Redis with the encoding stored beside it
+func decodeBlob(casID string, stored []byte, encoding string) ([]byte, error) {
+	data, err := compression.Decode(stored, encoding)
+	if err != nil {
+		return nil, decodeError(casID, err)
+	}
+	return data, nil
+}
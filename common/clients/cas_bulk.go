@@ -0,0 +1,180 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/lyzr/orchestrator/common/bulk"
+)
+
+// ErrCASNotFound is returned for a CAS ID with no stored blob
+var ErrCASNotFound = errors.New("CAS entry not found")
+
+// casBulkConcurrency caps how many blobs GetCASBulk reads at once one by one
+const casBulkConcurrency = 8
+
+// CASBulkResult is the outcome of a bulk CAS read, like GetCASBulk
+type CASBulkResult struct {
+	Found      map[string][]byte // Content of each blob read, by CAS ID
+	Missing    []string          // IDs with no stored blob, in request order
+	Unreadable map[string]error  // IDs whose blob couldn't be decoded or verified
+}
+
+// NewCASBulkResult creates an empty result for size blobs
+func NewCASBulkResult(size int) *CASBulkResult {
+	return &CASBulkResult{
+		Found:      make(map[string][]byte, size),
+		Missing:    []string{},
+		Unreadable: make(map[string]error),
+	}
+}
+
+// Err returns nil if every blob was read, or an error naming one that wasn't
+// For callers that need every blob, like a patch chain: a missing blob wraps
+// ErrCASNotFound, an unreadable one its decoding or verification error.
+func (r *CASBulkResult) Err() error {
+	if len(r.Missing) > 0 {
+		return fmt.Errorf("%w: %d missing, e.g. %s", ErrCASNotFound, len(r.Missing), r.Missing[0])
+	}
+	if len(r.Unreadable) > 0 {
+		casID := slices.Min(slices.Collect(maps.Keys(r.Unreadable))) // Deterministic pick
+		return fmt.Errorf("CAS entry %s unreadable (%d in all): %w", casID, len(r.Unreadable), r.Unreadable[casID])
+	}
+	return nil
+}
+
+// casBulkGetter is implemented by CAS clients that can read many blobs at once
+type casBulkGetter interface {
+	getBulk(ctx context.Context, casIDs []string) (*CASBulkResult, error)
+}
+
+// GetCASBulk reads many blobs from a CAS client
+// Clients backed by Redis read them in bounded pipelines; others read them one
+// by one, a few at a time. A blob that is missing or fails verification is
+// reported in the result; an error means the storage itself couldn't be read.
+func GetCASBulk(ctx context.Context, client CASClient, casIDs []string) (*CASBulkResult, error) {
+	if len(casIDs) == 0 {
+		return NewCASBulkResult(0), nil
+	}
+	if bulk, ok := client.(casBulkGetter); ok {
+		return bulk.getBulk(ctx, casIDs)
+	}
+	return getEach(ctx, casIDs, func(ctx context.Context, casID string) ([]byte, error) {
+		data, err := client.Get(ctx, casID)
+		if err != nil {
+			return nil, err
+		}
+		if content, ok := data.([]byte); ok {
+			return content, nil
+		}
+		return json.Marshal(data)
+	})
+}
+
+// getEach reads blobs with get, at most casBulkConcurrency at once
+// Not-found and integrity errors are sorted into the result; the first other
+// error cancels the reads still running and fails the fetch.
+func getEach(ctx context.Context, casIDs []string, get func(ctx context.Context, casID string) ([]byte, error)) (*CASBulkResult, error) {
+	contents := make([][]byte, len(casIDs))
+	errs := make([]error, len(casIDs))
+	err := bulk.ForEach(ctx, bulk.Chunks(casIDs, 1), casBulkConcurrency, func(ctx context.Context, i int, chunk []string) error {
+		contents[i], errs[i] = get(ctx, chunk[0])
+		if err := errs[i]; err != nil && !errors.Is(err, ErrCASNotFound) && !errors.Is(err, ErrCASIntegrity) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to bulk get CAS entries: %w", err)
+	}
+
+	result := NewCASBulkResult(len(casIDs))
+	for i, casID := range casIDs {
+		switch err := errs[i]; {
+		case err == nil:
+			result.Found[casID] = contents[i]
+		case errors.Is(err, ErrCASNotFound):
+			result.Missing = append(result.Missing, casID)
+		default:
+			result.Unreadable[casID] = err
+		}
+	}
+	return result, nil
+}
+
+// getBulk reads blobs and their signatures in bounded pipelines
+func (c *RedisCASClient) getBulk(ctx context.Context, casIDs []string) (*CASBulkResult, error) {
+	keys := make([]string, 0, len(casIDs))
+	for _, casID := range casIDs {
+		keys = append(keys, casBlobKey(casID), casEncodingKey(casID))
+		if c.options.signing() && contentAddressed(casID) {
+			keys = append(keys, casSignatureKey(casID))
+		}
+	}
+
+	fetched, err := c.redis.GetBulk(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bulk get CAS entries: %w", err)
+	}
+
+	result := NewCASBulkResult(len(casIDs))
+	for _, casID := range casIDs {
+		stored, ok := fetched.Found[casBlobKey(casID)]
+		if !ok {
+			result.Missing = append(result.Missing, casID)
+			continue
+		}
+		data, err := decodeBlob(casID, []byte(stored), fetched.Found[casEncodingKey(casID)])
+		if err == nil {
+			err = c.options.verify(casID, data, fetched.Found[casSignatureKey(casID)])
+		}
+		if err != nil {
+			c.logger.Error("CAS entry failed verification", "cas_id", casID, "error", err)
+			result.Unreadable[casID] = err
+			continue
+		}
+		result.Found[casID] = data
+	}
+	return result, nil
+}
+
+// getBulk reads blobs from the cache, and the ones it misses or can't verify from S3
+func (c *TieredCASClient) getBulk(ctx context.Context, casIDs []string) (*CASBulkResult, error) {
+	cached, err := c.cache.getBulk(ctx, casIDs)
+	if err != nil {
+		c.logger.Warn("failed to read CAS cache, reading from S3", "error", err)
+		return getEach(ctx, casIDs, c.getFromStore)
+	}
+
+	uncached := append([]string{}, cached.Missing...)
+	for _, casID := range casIDs {
+		if _, ok := cached.Unreadable[casID]; ok {
+			uncached = append(uncached, casID)
+		}
+	}
+	if len(uncached) == 0 {
+		return cached, nil
+	}
+
+	stored, err := getEach(ctx, uncached, c.getFromStore)
+	if err != nil {
+		return nil, err
+	}
+	result := NewCASBulkResult(len(casIDs))
+	for _, casID := range casIDs {
+		if data, ok := cached.Found[casID]; ok {
+			result.Found[casID] = data
+		} else if data, ok := stored.Found[casID]; ok {
+			result.Found[casID] = data
+		} else if err, ok := stored.Unreadable[casID]; ok {
+			result.Unreadable[casID] = err
+		} else {
+			result.Missing = append(result.Missing, casID)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCASBulk(t *testing.T) {
+	_, server := newFakeS3(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	newClients := func(key string) map[string]CASClient {
+		signing := WithSigningKey(key)
+		return map[string]CASClient{
+			"redis":  NewRedisCASClient(rdb, noopLogger{}, signing),
+			"s3":     newTestS3Client(t, server.URL, signing),
+			"tiered": NewTieredCASClient(NewRedisCASClient(rdb, noopLogger{}, signing), newTestS3Client(t, server.URL, signing), time.Hour, noopLogger{}),
+		}
+	}
+	forgers := newClients("another-key")
+	for name, client := range newClients(testSigningKey) {
+		t.Run(name, func(t *testing.T) {
+			good, err := client.Put(ctx, []byte(`{"ok":true}`), "application/json")
+			require.NoError(t, err)
+			// Signed with another key, so it fails verification
+			forged, err := forgers[name].Put(ctx, []byte(`{"forged":"`+name+`"}`), "application/json")
+			require.NoError(t, err)
+
+			result, err := GetCASBulk(ctx, client, []string{good, "sha256:missing", forged})
+			require.NoError(t, err)
+			assert.Equal(t, map[string][]byte{good: []byte(`{"ok":true}`)}, result.Found)
+			assert.Equal(t, []string{"sha256:missing"}, result.Missing)
+			require.Len(t, result.Unreadable, 1)
+			assert.ErrorIs(t, result.Unreadable[forged], ErrCASIntegrity)
+		})
+	}
+}
+
+func TestGetCASBulkTieredReadsEvictedBlobsFromS3(t *testing.T) {
+	s3, server := newFakeS3(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	client := NewTieredCASClient(NewRedisCASClient(rdb, noopLogger{}), newTestS3Client(t, server.URL), time.Hour, noopLogger{})
+	hot, err := client.Put(ctx, []byte(`"hot"`), "application/json")
+	require.NoError(t, err)
+	cold, err := client.Put(ctx, []byte(`"cold"`), "application/json")
+	require.NoError(t, err)
+	mr.Del("cas:" + cold)
+
+	result, err := GetCASBulk(ctx, client, []string{hot, cold})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{hot: []byte(`"hot"`), cold: []byte(`"cold"`)}, result.Found)
+	assert.Empty(t, result.Missing)
+
+	// Only the evicted blob came from S3, and it is cached again
+	_, gets := s3.counts()
+	assert.Equal(t, 1, gets)
+	assert.True(t, mr.Exists("cas:"+cold))
+}
+
+func TestGetCASBulkFailsOnStorageErrors(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	mr.Close()
+	_, err := GetCASBulk(context.Background(), NewRedisCASClient(rdb, noopLogger{}), []string{"sha256:any"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/compression"
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/metrics"
+)
+
+// S3CASClient stores CAS blobs as objects in an S3-compatible bucket
+// Objects are keyed by content hash (<prefix>sha256/<hex>) and CAS IDs have
+// the same "sha256:<hex>" form as RedisCASClient and CASService, so a blob has
+// one ID whichever backend holds it. Requests are signed with AWS Signature
+// Version 4, which S3 and MinIO both accept.
+type S3CASClient struct {
+	cfg        config.S3Config
+	endpoint   *url.URL
+	httpClient *http.Client
+	logger     Logger
+	options    casOptions
+	now        func() time.Time
+}
+
+// WithS3HTTPClient sets the HTTP client used for S3 requests
+func WithS3HTTPClient(httpClient *http.Client) CASOption {
+	return func(o *casOptions) {
+		o.httpClient = httpClient
+	}
+}
+
+// NewS3CASClient creates an S3-backed CAS client
+func NewS3CASClient(cfg config.S3Config, logger Logger, opts ...CASOption) (*S3CASClient, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %q", cfg.Endpoint)
+	}
+
+	options := newCASOptions(opts)
+	httpClient := options.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &S3CASClient{
+		cfg:        cfg,
+		endpoint:   endpoint,
+		httpClient: httpClient,
+		logger:     logger,
+		options:    options,
+		now:        time.Now,
+	}, nil
+}
+
+// Put stores data under its content hash and returns the CAS ID
+// Content already in the bucket is not uploaded again. Compressed objects keep
+// their content type and record the codec as their Content-Encoding.
+func (c *S3CASClient) Put(ctx context.Context, data []byte, contentType string) (string, error) {
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "put")
+
+	hash := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	stored, encoding, err := compression.Encode(data, c.options.compression, c.options.compressionThreshold)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode CAS entry: %w", err)
+	}
+
+	if err := c.putObject(ctx, hash, stored, encoding, contentType, c.options.sign(hash, data)); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// Get retrieves data by CAS ID
+func (c *S3CASClient) Get(ctx context.Context, casID string) (interface{}, error) {
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "get")
+
+	stored, encoding, signature, err := c.getObject(ctx, casID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := compression.Decode(stored, encoding)
+	if err != nil {
+		return nil, decodeError(casID, err)
+	}
+	if err := c.options.verify(casID, data, signature); err != nil {
+		c.logger.Error("CAS entry failed verification", "cas_id", casID, "error", err)
+		return nil, err
+	}
+	return data, nil
+}
+
+// Store marshals data to JSON and stores it
+func (c *S3CASClient) Store(ctx context.Context, data interface{}) (string, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal data: %w", err)
+	}
+	return c.Put(ctx, jsonData, "application/json")
+}
+
+// putObject uploads stored bytes for hash unless the object already exists
+// A non-empty signature is recorded as the object's user metadata.
+func (c *S3CASClient) putObject(ctx context.Context, hash string, stored []byte, encoding, contentType, signature string) error {
+	key := c.objectKey(hash)
+
+	resp, err := c.do(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to store in CAS: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		c.logger.Debug("CAS object already exists", "cas_id", hash)
+		return nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to store in CAS: %w", s3Error(http.MethodHead, key, resp))
+	}
+
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if encoding != compression.Identity {
+		header.Set("Content-Encoding", encoding)
+	}
+	if signature != "" {
+		header.Set(casSignatureHeader, signature)
+	}
+
+	resp, err = c.do(ctx, http.MethodPut, key, stored, header)
+	if err != nil {
+		return fmt.Errorf("failed to store in CAS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error("failed to store in CAS", "cas_id", hash, "status", resp.StatusCode)
+		return fmt.Errorf("failed to store in CAS: %w", s3Error(http.MethodPut, key, resp))
+	}
+
+	c.logger.Debug("stored in CAS", "cas_id", hash, "stored_bytes", len(stored), "encoding", encoding, "backend", "s3")
+	return nil
+}
+
+// getObject downloads the stored bytes for casID along with their encoding and signature
+func (c *S3CASClient) getObject(ctx context.Context, casID string) ([]byte, string, string, error) {
+	key := c.objectKey(casID)
+
+	// Asking for gzip explicitly stops net/http from transparently decoding
+	// the body, so the codec recorded on the object is what we see
+	header := http.Header{}
+	header.Set("Accept-Encoding", compression.Gzip)
+
+	resp, err := c.do(ctx, http.MethodGet, key, nil, header)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to get CAS entry %s: %w", casID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		c.logger.Warn("CAS entry not found", "cas_id", casID)
+		return nil, "", "", fmt.Errorf("%w: %s", ErrCASNotFound, casID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", fmt.Errorf("failed to get CAS entry %s: %w", casID, s3Error(http.MethodGet, key, resp))
+	}
+
+	stored, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read CAS entry %s: %w", casID, err)
+	}
+
+	encoding := resp.Header.Get("Content-Encoding")
+	c.logger.Debug("retrieved from CAS", "cas_id", casID, "stored_bytes", len(stored), "encoding", encoding, "backend", "s3")
+	return stored, encoding, resp.Header.Get(casSignatureHeader), nil
+}
+
+// objectKey maps a CAS ID ("sha256:<hex>") to an object key ("<prefix>sha256/<hex>")
+func (c *S3CASClient) objectKey(casID string) string {
+	return c.cfg.Prefix + strings.Replace(casID, ":", "/", 1)
+}
+
+// do sends a signed request for an object
+func (c *S3CASClient) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, error) {
+	u := *c.endpoint
+	if c.cfg.UsePathStyle {
+		u.Path = "/" + c.cfg.Bucket + "/" + key
+	} else {
+		u.Host = c.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(body))
+
+	c.sign(req, body)
+	return c.httpClient.Do(req)
+}
+
+// sign adds AWS Signature Version 4 headers; requests stay anonymous without credentials
+func (c *S3CASClient) sign(req *http.Request, body []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hashHex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.cfg.AccessKeyID == "" {
+		return
+	}
+
+	// User metadata (x-amz-meta-*) must be signed too; it sorts after x-amz-date
+	headerNames := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	var metaNames []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-meta-") {
+			metaNames = append(metaNames, lower)
+		}
+	}
+	sort.Strings(metaNames)
+	for _, name := range metaNames {
+		headerNames = append(headerNames, name)
+		canonicalHeaders += name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n"
+	}
+
+	signedHeaders := strings.Join(headerNames, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := deriveSigningKey(c.cfg.SecretAccessKey, date, c.cfg.Region, "s3")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// deriveSigningKey computes the SigV4 signing key for a day, region and service
+func deriveSigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// s3Error describes an unexpected S3 response
+func s3Error(method, key string, resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 %s %s returned %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(detail)))
+}
@@ -0,0 +1,282 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/lyzr/orchestrator/common/compression"
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Debug(string, ...interface{}) {}
+
+type fakeObject struct {
+	data            []byte
+	contentType     string
+	contentEncoding string
+	signature       string
+}
+
+// fakeS3 is a path-style, in-memory S3 that checks request signing headers
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	puts    int
+	gets    int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	s3 := &fakeS3{objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		s3.mu.Lock()
+		defer s3.mu.Unlock()
+		obj, ok := s3.objects[r.URL.Path]
+		switch r.Method {
+		case http.MethodPut:
+			s3.puts++
+			s3.objects[r.URL.Path] = fakeObject{
+				data:            body,
+				contentType:     r.Header.Get("Content-Type"),
+				contentEncoding: r.Header.Get("Content-Encoding"),
+				signature:       r.Header.Get(casSignatureHeader),
+			}
+		case http.MethodHead, http.MethodGet:
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if r.Method == http.MethodGet {
+				s3.gets++
+				w.Header().Set("Content-Type", obj.contentType)
+				if obj.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", obj.contentEncoding)
+				}
+				if obj.signature != "" {
+					w.Header().Set(casSignatureHeader, obj.signature)
+				}
+				w.Write(obj.data)
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return s3, server
+}
+
+func (s *fakeS3) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.puts, s.gets
+}
+
+func newTestS3Client(t *testing.T, endpoint string, opts ...CASOption) *S3CASClient {
+	client, err := NewS3CASClient(config.S3Config{
+		Endpoint:        endpoint,
+		Region:          "us-east-1",
+		Bucket:          "artifacts",
+		Prefix:          "cas/",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		UsePathStyle:    true,
+	}, noopLogger{}, opts...)
+	require.NoError(t, err)
+	return client
+}
+
+func TestS3CASClientPutGetDedup(t *testing.T) {
+	s3, server := newFakeS3(t)
+	client := newTestS3Client(t, server.URL)
+	ctx := context.Background()
+
+	data := []byte(`{"status":200}`)
+	id, err := client.Put(ctx, data, "application/json")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(data)), id)
+
+	// Same hash as the Redis client, so IDs are portable between backends
+	mr := miniredis.RunT(t)
+	redisID, err := NewRedisCASClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}), noopLogger{}).Put(ctx, data, "application/json")
+	require.NoError(t, err)
+	assert.Equal(t, redisID, id)
+
+	obj := s3.objects["/artifacts/cas/sha256/"+strings.TrimPrefix(id, "sha256:")]
+	assert.Equal(t, data, obj.data)
+	assert.Equal(t, "application/json", obj.contentType)
+
+	again, err := client.Put(ctx, data, "application/json")
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+	puts, _ := s3.counts()
+	assert.Equal(t, 1, puts, "identical content must not be uploaded twice")
+
+	got, err := client.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	_, err = client.Get(ctx, "sha256:missing")
+	assert.EqualError(t, err, "CAS entry not found: sha256:missing")
+}
+
+func TestTieredCASClientFallsBackToS3(t *testing.T) {
+	s3, server := newFakeS3(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	client := NewTieredCASClient(NewRedisCASClient(rdb, noopLogger{}), newTestS3Client(t, server.URL), time.Hour, noopLogger{})
+	ctx := context.Background()
+
+	id, err := client.Store(ctx, map[string]interface{}{"ok": true})
+	require.NoError(t, err)
+	assert.True(t, mr.Exists("cas:"+id))
+	assert.Equal(t, time.Hour, mr.TTL("cas:"+id))
+
+	// Hot read is served by Redis
+	got, err := client.Get(ctx, id)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(got.([]byte)))
+	_, gets := s3.counts()
+	assert.Equal(t, 0, gets)
+
+	// Evicted entries come back from S3 and are cached again
+	mr.Del("cas:" + id)
+	got, err = client.Get(ctx, id)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(got.([]byte)))
+	_, gets = s3.counts()
+	assert.Equal(t, 1, gets)
+	assert.True(t, mr.Exists("cas:"+id))
+
+	_, err = client.Get(ctx, "sha256:missing")
+	assert.EqualError(t, err, "CAS entry not found: sha256:missing")
+}
+
+func TestDeriveSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation
+	key := deriveSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	assert.Equal(t, "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d", hex.EncodeToString(key))
+}
+
+func TestNewCASClientSelectsBackend(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	s3cfg := config.S3Config{Endpoint: "http://localhost:9000", Bucket: "artifacts"}
+	for backend, want := range map[string]interface{}{
+		"":       &RedisCASClient{},
+		"redis":  &RedisCASClient{},
+		"s3":     &S3CASClient{},
+		"tiered": &TieredCASClient{},
+	} {
+		client, err := NewCASClient(config.CASConfig{Backend: backend, CacheTTL: time.Hour, S3: s3cfg}, rdb, noopLogger{})
+		require.NoError(t, err, backend)
+		assert.IsType(t, want, client, backend)
+	}
+
+	_, err := NewCASClient(config.CASConfig{Backend: "gcs"}, rdb, noopLogger{})
+	assert.Error(t, err)
+}
+
+func TestCASClientsCompressLargeBlobs(t *testing.T) {
+	for _, codec := range []string{compression.Gzip, compression.Zstd} {
+		t.Run(codec, func(t *testing.T) {
+			s3, server := newFakeS3(t)
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer rdb.Close()
+			ctx := context.Background()
+
+			compress := WithCompression(codec, 1024)
+			large := []byte(strings.Repeat(`{"node":"a","status":"completed"}`, 500))
+			small := []byte(`{"tiny":true}`)
+
+			// Redis: large blobs are stored compressed with their encoding beside them, small ones raw
+			redisClient := NewRedisCASClient(rdb, noopLogger{}, compress)
+			largeID, err := redisClient.Put(ctx, large, "application/json")
+			require.NoError(t, err)
+			smallID, err := redisClient.Put(ctx, small, "application/json")
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(large)), largeID)
+
+			storedLarge, _ := mr.Get("cas:" + largeID)
+			assert.Less(t, len(storedLarge), len(large))
+			encoding, _ := mr.Get("cas:" + largeID + ":enc")
+			assert.Equal(t, codec, encoding)
+			storedSmall, _ := mr.Get("cas:" + smallID)
+			assert.Equal(t, string(small), storedSmall)
+			assert.False(t, mr.Exists("cas:"+smallID+":enc"))
+
+			for id, want := range map[string][]byte{largeID: large, smallID: small} {
+				got, err := redisClient.Get(ctx, id)
+				require.NoError(t, err)
+				assert.Equal(t, want, got)
+			}
+
+			// Bytes stored without an encoding are returned as they are, even compressed ones
+			plain := NewRedisCASClient(rdb, noopLogger{})
+			compressed, _, err := compression.Encode(large, codec, 0)
+			require.NoError(t, err)
+			rawID, err := plain.Put(ctx, compressed, "application/octet-stream")
+			require.NoError(t, err)
+			got, err := redisClient.Get(ctx, rawID)
+			require.NoError(t, err)
+			assert.Equal(t, compressed, got)
+
+			// Storing the same content uncompressed drops its encoding
+			_, err = plain.Put(ctx, large, "application/json")
+			require.NoError(t, err)
+			assert.False(t, mr.Exists("cas:"+largeID+":enc"))
+			got, err = redisClient.Get(ctx, largeID)
+			require.NoError(t, err)
+			assert.Equal(t, large, got)
+
+			// S3: the codec is recorded as Content-Encoding alongside the content type
+			s3Client := newTestS3Client(t, server.URL, compress)
+			id, err := s3Client.Put(ctx, large, "application/json")
+			require.NoError(t, err)
+			assert.Equal(t, largeID, id)
+
+			obj := s3.objects["/artifacts/cas/sha256/"+strings.TrimPrefix(id, "sha256:")]
+			assert.Equal(t, "application/json", obj.contentType)
+			assert.Equal(t, codec, obj.contentEncoding)
+			assert.Less(t, len(obj.data), len(large))
+
+			got, err = s3Client.Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, large, got)
+
+			// A plain reader decodes compressed objects too
+			got, err = newTestS3Client(t, server.URL).Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, large, got)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package clients
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestRedisCASClientSigning(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	client := NewRedisCASClient(rdb, noopLogger{}, WithSigningKey(testSigningKey))
+	content := []byte(`{"nodes":[{"id":"a","type":"function"}]}`)
+
+	// Signed content round-trips
+	id, err := client.Put(ctx, content, "application/json")
+	require.NoError(t, err)
+	assert.True(t, mr.Exists("cas:"+id+":sig"))
+	got, err := client.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	// Tampered content is rejected, even with its signature in place
+	require.NoError(t, mr.Set("cas:"+id, `{"nodes":[{"id":"evil","type":"http"}]}`))
+	_, err = client.Get(ctx, id)
+	assert.ErrorIs(t, err, ErrCASIntegrity)
+
+	// So is content re-signed with another key
+	forged := NewRedisCASClient(rdb, noopLogger{}, WithSigningKey(strings.Repeat("x", 32)))
+	otherID, err := forged.Put(ctx, []byte(`{"forged":true}`), "application/json")
+	require.NoError(t, err)
+	_, err = client.Get(ctx, otherID)
+	assert.ErrorIs(t, err, ErrCASIntegrity)
+
+	// And unsigned content
+	unsignedID, err := NewRedisCASClient(rdb, noopLogger{}).Put(ctx, []byte(`{"unsigned":true}`), "application/json")
+	require.NoError(t, err)
+	_, err = client.Get(ctx, unsignedID)
+	assert.ErrorIs(t, err, ErrCASIntegrity)
+
+	// Without a key, content that doesn't hash to its ID is still rejected
+	_, err = NewRedisCASClient(rdb, noopLogger{}).Get(ctx, id)
+	assert.ErrorIs(t, err, ErrCASIntegrity)
+}
+
+func TestS3CASClientSigning(t *testing.T) {
+	s3, server := newFakeS3(t)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	signing := WithSigningKey(testSigningKey)
+	client := newTestS3Client(t, server.URL, signing)
+	content := []byte(`{"nodes":[{"id":"a","type":"function"}]}`)
+
+	id, err := client.Put(ctx, content, "application/json")
+	require.NoError(t, err)
+	key := "/artifacts/cas/sha256/" + strings.TrimPrefix(id, "sha256:")
+	assert.NotEmpty(t, s3.objects[key].signature)
+
+	got, err := client.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	// Tampered objects are rejected
+	tampered := s3.objects[key]
+	tampered.data = []byte(`{"nodes":[{"id":"evil","type":"http"}]}`)
+	s3.objects[key] = tampered
+	_, err = client.Get(ctx, id)
+	assert.ErrorIs(t, err, ErrCASIntegrity)
+
+	// The tiered client falls back to S3 when its cached copy is tampered with
+	tiered := NewTieredCASClient(NewRedisCASClient(rdb, noopLogger{}, signing), client, time.Hour, noopLogger{})
+	otherID, err := tiered.Put(ctx, []byte(`{"tiered":true}`), "application/json")
+	require.NoError(t, err)
+	require.NoError(t, mr.Set("cas:"+otherID, `{"tiered":false}`))
+	got, err = tiered.Get(ctx, otherID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"tiered":true}`), got)
+}
@@ -0,0 +1,130 @@
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/compression"
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// TieredCASClient keeps hot blobs in Redis and durable copies in S3
+// Put writes S3 first so a blob is never only in the cache; Get reads Redis
+// and falls back to S3 on a miss, repopulating the cache for later reads.
+// Blobs are compressed with the S3 client's settings and cached as stored.
+type TieredCASClient struct {
+	cache    *RedisCASClient
+	store    *S3CASClient
+	cacheTTL time.Duration
+	logger   Logger
+}
+
+// NewTieredCASClient creates a CAS client that caches S3 blobs in Redis for cacheTTL
+func NewTieredCASClient(cache *RedisCASClient, store *S3CASClient, cacheTTL time.Duration, logger Logger) *TieredCASClient {
+	return &TieredCASClient{
+		cache:    cache,
+		store:    store,
+		cacheTTL: cacheTTL,
+		logger:   logger,
+	}
+}
+
+// Put stores data in S3 and caches it in Redis
+func (c *TieredCASClient) Put(ctx context.Context, data []byte, contentType string) (string, error) {
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "put")
+
+	hash := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	stored, encoding, err := compression.Encode(data, c.store.options.compression, c.store.options.compressionThreshold)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode CAS entry: %w", err)
+	}
+
+	signature := c.store.options.sign(hash, data)
+	if err := c.store.putObject(ctx, hash, stored, encoding, contentType, signature); err != nil {
+		return "", err
+	}
+
+	// The cache is best-effort: S3 already holds the blob
+	if err := c.cache.setBlob(ctx, hash, stored, encoding, signature, c.cacheTTL); err != nil {
+		c.logger.Warn("failed to cache CAS entry", "cas_id", hash, "error", err)
+	}
+	return hash, nil
+}
+
+// Get retrieves data from Redis, falling back to S3 on a miss
+// A cached blob that fails verification is read from S3 instead, which also
+// replaces the cached copy.
+func (c *TieredCASClient) Get(ctx context.Context, casID string) (interface{}, error) {
+	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "get")
+
+	if stored, encoding, signature, err := c.cache.getBlob(ctx, casID); err == nil {
+		data, err := decodeBlob(casID, stored, encoding)
+		if err == nil {
+			err = c.cache.options.verify(casID, data, signature)
+		}
+		if err == nil {
+			return data, nil
+		}
+		c.logger.Warn("cached CAS entry failed verification, reading from S3", "cas_id", casID, "error", err)
+	}
+
+	return c.getFromStore(ctx, casID)
+}
+
+// getFromStore reads and verifies a blob from S3, then caches it
+func (c *TieredCASClient) getFromStore(ctx context.Context, casID string) ([]byte, error) {
+	stored, encoding, signature, err := c.store.getObject(ctx, casID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := compression.Decode(stored, encoding)
+	if err != nil {
+		return nil, decodeError(casID, err)
+	}
+	if err := c.store.options.verify(casID, data, signature); err != nil {
+		c.logger.Error("CAS entry failed verification", "cas_id", casID, "error", err)
+		return nil, err
+	}
+
+	if err := c.cache.setBlob(ctx, casID, stored, encoding, signature, c.cacheTTL); err != nil {
+		c.logger.Warn("failed to cache CAS entry", "cas_id", casID, "error", err)
+	}
+	return data, nil
+}
+
+// Store marshals data to JSON and stores it
+func (c *TieredCASClient) Store(ctx context.Context, data interface{}) (string, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal data: %w", err)
+	}
+	return c.Put(ctx, jsonData, "application/json")
+}
+
+// NewCASClient creates the CAS client selected by cfg.Backend
+// Every service that writes or reads node results must use the same backend.
+func NewCASClient(cfg config.CASConfig, redisClient redis.UniversalClient, logger Logger) (CASClient, error) {
+	compress := WithCompression(cfg.Compression, cfg.CompressionThreshold)
+	signing := WithSigningKey(cfg.SigningKey)
+
+	switch cfg.Backend {
+	case "", "redis":
+		return NewRedisCASClient(redisClient, logger, compress, signing), nil
+	case "s3":
+		return NewS3CASClient(cfg.S3, logger, compress, signing)
+	case "tiered":
+		store, err := NewS3CASClient(cfg.S3, logger, compress, signing)
+		if err != nil {
+			return nil, err
+		}
+		return NewTieredCASClient(NewRedisCASClient(redisClient, logger, compress, signing), store, cfg.CacheTTL, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown CAS backend: %s", cfg.Backend)
+	}
+}
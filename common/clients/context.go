@@ -9,9 +9,11 @@ const (
 	// UserIDKey is the context key for user ID (for X-User-ID header)
 	UserIDKey contextKey = "user-id"
 
+	// RequestIDKey is the context key for the request ID (X-Request-ID header)
+	RequestIDKey contextKey = "request-id"
+
 	// Future context keys can be added here:
 	// OrgIDKey     contextKey = "org-id"
-	// RequestIDKey contextKey = "request-id"
 	// TraceIDKey   contextKey = "trace-id"
 )
 
@@ -27,3 +29,15 @@ func GetUserID(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(UserIDKey).(string)
 	return userID, ok && userID != ""
 }
+
+// WithRequestID adds a request ID to the context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, requestID)
+}
+
+// GetRequestID retrieves the request ID from context
+// Returns the request ID and true if found, empty string and false otherwise
+func GetRequestID(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(RequestIDKey).(string)
+	return requestID, ok && requestID != ""
+}
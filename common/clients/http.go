@@ -38,6 +38,11 @@ func (c *HTTPClient) DoRequest(ctx context.Context, method, url string, body io.
 		return nil, err
 	}
 
+	// Request bodies are JSON
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
 	// Extract user ID from context and set X-User-ID header
 	if userID, ok := GetUserID(ctx); ok {
 		req.Header.Set("X-User-ID", userID)
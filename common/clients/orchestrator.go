@@ -1,6 +1,7 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -12,9 +13,10 @@ import (
 // OrchestratorClient handles communication with the orchestrator API
 // It uses context to pass authentication and other metadata
 type OrchestratorClient struct {
-	baseURL string
-	http    *HTTPClient
-	logger  Logger
+	baseURL   string
+	http      *HTTPClient
+	logger    Logger
+	artifacts *artifactCache
 }
 
 // NewOrchestratorClient creates a new orchestrator client
@@ -24,9 +26,10 @@ func NewOrchestratorClient(baseURL string, logger Logger) *OrchestratorClient {
 	}
 
 	return &OrchestratorClient{
-		baseURL: baseURL,
-		http:    NewHTTPClient(httpClient, logger),
-		logger:  logger,
+		baseURL:   baseURL,
+		http:      NewHTTPClient(httpClient, logger),
+		logger:    logger,
+		artifacts: newArtifactCache(defaultArtifactCacheSize),
 	}
 }
 
@@ -277,9 +280,20 @@ type ArtifactResponse struct {
 	Content    map[string]interface{} `json:"content"`
 }
 
+// maxArtifactBatch is how many artifacts GetArtifacts asks for per request
+// Matches the orchestrator's cap on POST /api/v1/artifacts/batch.
+const maxArtifactBatch = 100
+
 // GetArtifact fetches an artifact by ID from the orchestrator
+// Artifacts are immutable, so they're served from an in-process LRU cache after
+// the first fetch. The returned artifact is shared with the cache: don't modify it.
 // Requires: ctx with UserID set via WithUserID()
 func (c *OrchestratorClient) GetArtifact(ctx context.Context, artifactID string) (*ArtifactResponse, error) {
+	if artifact, ok := c.artifacts.get(artifactID); ok {
+		c.logger.Debug("artifact cache hit", "artifact_id", artifactID)
+		return artifact, nil
+	}
+
 	url := fmt.Sprintf("%s/api/v1/artifacts/%s", c.baseURL, artifactID)
 	resp, err := c.http.DoRequest(ctx, "GET", url, nil)
 	if err != nil {
@@ -301,5 +315,76 @@ func (c *OrchestratorClient) GetArtifact(ctx context.Context, artifactID string)
 		"artifact_id", artifact.ArtifactID,
 		"kind", artifact.Kind)
 
+	c.artifacts.add(artifactID, &artifact)
 	return &artifact, nil
 }
+
+// GetArtifacts fetches several artifacts by ID, keyed by artifact ID
+// Cached artifacts are served from memory and the rest are fetched in one
+// request per maxArtifactBatch IDs. Artifacts the orchestrator doesn't know are
+// omitted. Like GetArtifact, the returned artifacts are shared with the cache.
+// Requires: ctx with UserID set via WithUserID()
+func (c *OrchestratorClient) GetArtifacts(ctx context.Context, artifactIDs []string) (map[string]*ArtifactResponse, error) {
+	artifacts := make(map[string]*ArtifactResponse, len(artifactIDs))
+
+	var missing []string
+	seen := make(map[string]bool, len(artifactIDs))
+	for _, id := range artifactIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if artifact, ok := c.artifacts.get(id); ok {
+			artifacts[id] = artifact
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	for start := 0; start < len(missing); start += maxArtifactBatch {
+		batch := missing[start:min(start+maxArtifactBatch, len(missing))]
+
+		fetched, err := c.fetchArtifactBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for id, artifact := range fetched {
+			c.artifacts.add(id, artifact)
+			artifacts[id] = artifact
+		}
+	}
+
+	c.logger.Info("fetched artifacts",
+		"requested", len(artifactIDs),
+		"fetched", len(missing),
+		"found", len(artifacts))
+
+	return artifacts, nil
+}
+
+// fetchArtifactBatch fetches up to maxArtifactBatch artifacts in one request
+func (c *OrchestratorClient) fetchArtifactBatch(ctx context.Context, artifactIDs []string) (map[string]*ArtifactResponse, error) {
+	body, err := json.Marshal(map[string]interface{}{"artifact_ids": artifactIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal artifacts request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/v1/artifacts/batch", c.baseURL)
+	resp, err := c.http.DoRequest(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch artifacts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("artifacts request failed: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var artifacts map[string]*ArtifactResponse
+	if err := json.NewDecoder(resp.Body).Decode(&artifacts); err != nil {
+		return nil, fmt.Errorf("failed to decode artifacts response: %w", err)
+	}
+
+	return artifacts, nil
+}
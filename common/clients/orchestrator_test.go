@@ -0,0 +1,155 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrchestrator serves the artifact endpoints and counts requests to them
+type fakeOrchestrator struct {
+	mu        sync.Mutex
+	artifacts map[string]*ArtifactResponse
+	gets      int
+	batches   [][]string
+}
+
+func newFakeOrchestrator(t *testing.T, ids ...string) (*fakeOrchestrator, *OrchestratorClient) {
+	f := &fakeOrchestrator{artifacts: make(map[string]*ArtifactResponse)}
+	for _, id := range ids {
+		f.artifacts[id] = &ArtifactResponse{
+			ArtifactID: id,
+			Kind:       "dag_version",
+			Content:    map[string]interface{}{"nodes": []interface{}{map[string]interface{}{"id": id}}},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/artifacts/batch":
+			var req struct {
+				ArtifactIDs []string `json:"artifact_ids"`
+			}
+			if r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&req) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.batches = append(f.batches, req.ArtifactIDs)
+
+			found := make(map[string]*ArtifactResponse)
+			for _, id := range req.ArtifactIDs {
+				if artifact, ok := f.artifacts[id]; ok {
+					found[id] = artifact
+				}
+			}
+			json.NewEncoder(w).Encode(found)
+
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/artifacts/"):
+			f.gets++
+			artifact, ok := f.artifacts[strings.TrimPrefix(r.URL.Path, "/api/v1/artifacts/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(artifact)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return f, NewOrchestratorClient(server.URL, noopLogger{})
+}
+
+func TestGetArtifact_CachesRepeatedFetches(t *testing.T) {
+	ctx := WithUserID(context.Background(), "alice")
+	f, client := newFakeOrchestrator(t, "a1", "a2")
+
+	for i := 0; i < 3; i++ {
+		artifact, err := client.GetArtifact(ctx, "a1")
+		require.NoError(t, err)
+		assert.Equal(t, "a1", artifact.ArtifactID)
+	}
+	assert.Equal(t, 1, f.gets)
+
+	_, err := client.GetArtifact(ctx, "a2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.gets)
+
+	// Failed fetches aren't cached
+	for i := 0; i < 2; i++ {
+		_, err := client.GetArtifact(ctx, "missing")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 4, f.gets)
+}
+
+func TestGetArtifacts_SingleRequest(t *testing.T) {
+	ctx := WithUserID(context.Background(), "alice")
+	f, client := newFakeOrchestrator(t, "a1", "a2", "a3")
+
+	artifacts, err := client.GetArtifacts(ctx, []string{"a1", "a2", "a3", "a1", "missing"})
+	require.NoError(t, err)
+
+	require.Len(t, artifacts, 3)
+	for _, id := range []string{"a1", "a2", "a3"} {
+		assert.Equal(t, id, artifacts[id].ArtifactID)
+		assert.NotNil(t, artifacts[id].Content)
+	}
+	assert.Equal(t, [][]string{{"a1", "a2", "a3", "missing"}}, f.batches)
+
+	// The batch filled the cache: neither call goes back to the orchestrator
+	_, err = client.GetArtifact(ctx, "a2")
+	require.NoError(t, err)
+	artifacts, err = client.GetArtifacts(ctx, []string{"a1", "a3"})
+	require.NoError(t, err)
+	assert.Len(t, artifacts, 2)
+	assert.Zero(t, f.gets)
+	assert.Len(t, f.batches, 1)
+}
+
+func TestGetArtifacts_SplitsLargeBatches(t *testing.T) {
+	ids := make([]string, maxArtifactBatch+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("a%d", i)
+	}
+	f, client := newFakeOrchestrator(t, ids...)
+
+	artifacts, err := client.GetArtifacts(WithUserID(context.Background(), "alice"), ids)
+	require.NoError(t, err)
+	assert.Len(t, artifacts, len(ids))
+	require.Len(t, f.batches, 2)
+	assert.Len(t, f.batches[0], maxArtifactBatch)
+	assert.Len(t, f.batches[1], 1)
+}
+
+func TestArtifactCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newArtifactCache(2)
+	cache.add("a1", &ArtifactResponse{ArtifactID: "a1"})
+	cache.add("a2", &ArtifactResponse{ArtifactID: "a2"})
+
+	// Using a1 makes a2 the oldest
+	_, ok := cache.get("a1")
+	require.True(t, ok)
+	cache.add("a3", &ArtifactResponse{ArtifactID: "a3"})
+
+	_, ok = cache.get("a2")
+	assert.False(t, ok)
+	for _, id := range []string{"a1", "a3"} {
+		artifact, ok := cache.get(id)
+		require.True(t, ok, id)
+		assert.Equal(t, id, artifact.ArtifactID)
+	}
+}
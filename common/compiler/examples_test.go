@@ -0,0 +1,53 @@
+package compiler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCompileWorkflowSchema_ShippedExamples compiles every example workflow the
+// repo ships, so a schema change can't silently break them
+func TestCompileWorkflowSchema_ShippedExamples(t *testing.T) {
+	examples := map[string]*WorkflowSchema{}
+
+	files, err := filepath.Glob("../schema/examples/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no examples found in common/schema/examples")
+	}
+	for _, file := range files {
+		var schema WorkflowSchema
+		readJSON(t, file, &schema)
+		examples["common/schema/examples/"+filepath.Base(file)] = &schema
+	}
+
+	// A catalog of named workflows
+	var catalog map[string]*WorkflowSchema
+	readJSON(t, "../../test_data/workflow_examples.json", &catalog)
+	for name, schema := range catalog {
+		examples["test_data/workflow_examples.json#"+name] = schema
+	}
+
+	for name, schema := range examples {
+		t.Run(name, func(t *testing.T) {
+			if _, err := CompileWorkflowSchema(schema, NewMockCASClient()); err != nil {
+				t.Errorf("example doesn't compile: %v", err)
+			}
+		})
+	}
+}
+
+func readJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
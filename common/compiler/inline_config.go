@@ -0,0 +1,26 @@
+package compiler
+
+import "github.com/lyzr/orchestrator/common/sdk"
+
+// DefaultInlineConfigMaxBytes is the largest node config, as JSON, kept inline in the IR
+const DefaultInlineConfigMaxBytes = 4 * 1024
+
+// inlineConfigMaxBytes is the inline config threshold in use
+var inlineConfigMaxBytes = DefaultInlineConfigMaxBytes
+
+// SetInlineConfigMaxBytes sets the largest node config kept inline in the IR
+// Larger configs are stored in CAS and referenced by ConfigRef. Zero externalizes
+// every config. Set it at startup, before workflows are compiled.
+func SetInlineConfigMaxBytes(n int) {
+	inlineConfigMaxBytes = n
+}
+
+// externalizeConfig returns true if a node config should be stored in CAS
+// Configs with redaction rules always stay inline: results are redacted with
+// the IR alone, without loading configs back from CAS.
+func externalizeConfig(config map[string]interface{}, configJSON []byte) bool {
+	if _, ok := config[sdk.ConfigKeyRedact]; ok {
+		return false
+	}
+	return len(configJSON) > inlineConfigMaxBytes
+}
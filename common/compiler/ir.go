@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/lyzr/orchestrator/common/sdk"
 	"github.com/lyzr/orchestrator/common/clients"
+	"github.com/lyzr/orchestrator/common/graph"
 )
 
 // Node type constants
@@ -22,6 +25,9 @@ const (
 	NodeTypeTransform   = "transform"
 	NodeTypeAggregate   = "aggregate"
 	NodeTypeFilter      = "filter"
+	NodeTypeWebhook     = "webhook"
+	NodeTypeSubworkflow = "subworkflow"
+	NodeTypeMap         = "map"
 )
 
 // Condition type constants
@@ -39,6 +45,7 @@ var validExecutableTypes = map[string]bool{
 	NodeTypeTransform: true,
 	NodeTypeAggregate: true,
 	NodeTypeFilter:    true,
+	NodeTypeWebhook:   true,
 }
 
 // ============================================================================
@@ -63,7 +70,7 @@ type WorkflowSchema struct {
 // Schema: common/schema/workflow.schema.json#/definitions/Node
 type WorkflowNode struct {
 	ID        string                 `json:"id"`
-	Type      string                 `json:"type"` // function, http, agent, conditional, loop, parallel, transform, aggregate, filter
+	Type      string                 `json:"type"` // function, http, agent, webhook, conditional, loop, parallel, transform, aggregate, filter
 	Config    map[string]interface{} `json:"config,omitempty"`
 	TimeoutMS int                    `json:"timeout_ms,omitempty"`
 	Retry     *RetryPolicy           `json:"retry,omitempty"`
@@ -75,15 +82,13 @@ type WorkflowEdge struct {
 	From      string `json:"from"`
 	To        string `json:"to"`
 	Condition string `json:"condition,omitempty"` // Optional condition for edge traversal
+	OnError   bool   `json:"on_error,omitempty"`  // Traversed only when From fails (error handler)
 }
 
 // RetryPolicy from workflow.schema.json
 // Schema: common/schema/workflow.schema.json#/definitions/RetryPolicy
-type RetryPolicy struct {
-	MaxAttempts       int     `json:"max_attempts,omitempty"`
-	BackoffMS         int     `json:"backoff_ms,omitempty"`
-	BackoffMultiplier float64 `json:"backoff_multiplier,omitempty"`
-}
+// Carried through to the IR unchanged so workers can apply it.
+type RetryPolicy = sdk.RetryPolicy
 
 // DSL represents the source workflow definition (legacy, for backward compatibility)
 type DSL struct {
@@ -120,6 +125,10 @@ func CompileWorkflowSchema(schema *WorkflowSchema, casClient clients.CASClient)
 	edgesFromNode := make(map[string][]WorkflowEdge) // All edges from each node
 
 	for _, edge := range schema.Edges {
+		// Error edges route failures and play no part in branching
+		if edge.OnError {
+			continue
+		}
 		edgesFromNode[edge.From] = append(edgesFromNode[edge.From], edge)
 		if edge.Condition != "" {
 			conditionalEdges[edge.From] = append(conditionalEdges[edge.From], edge)
@@ -128,6 +137,10 @@ func CompileWorkflowSchema(schema *WorkflowSchema, casClient clients.CASClient)
 
 	// 1. Convert nodes with type mapping
 	for _, wfNode := range schema.Nodes {
+		// Reject configs the node's worker can't run, rather than failing at runtime
+		if err := validateNodeConfig(&wfNode); err != nil {
+			return nil, err
+		}
 		node, err := convertWorkflowNode(&wfNode, conditionalEdges, edgesFromNode, casClient)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert node %s: %w", wfNode.ID, err)
@@ -147,6 +160,16 @@ func CompileWorkflowSchema(schema *WorkflowSchema, casClient clients.CASClient)
 			return nil, fmt.Errorf("edge references non-existent node: %s", edge.To)
 		}
 
+		// Error edges route the failure of from_node to its error handlers
+		if edge.OnError {
+			if edge.Condition != "" {
+				return nil, fmt.Errorf("edge %s -> %s: on_error edges cannot have a condition", edge.From, edge.To)
+			}
+			fromNode.OnError = append(fromNode.OnError, edge.To)
+			toNode.Dependencies = append(toNode.Dependencies, edge.From)
+			continue
+		}
+
 		// Skip if this is handled by branch config
 		if edge.Condition == "" || fromNode.Branch == nil {
 			// Add to dependents of from_node (only for unconditional edges or non-branch nodes)
@@ -161,9 +184,20 @@ func CompileWorkflowSchema(schema *WorkflowSchema, casClient clients.CASClient)
 		}
 	}
 
-	// 3. Set wait_for_all flag for join nodes
+	// 2.5. Attach map bodies to their map nodes
+	if err := wireMapBodies(ir); err != nil {
+		return nil, err
+	}
+
+	// 3. Set wait_for_all flag for join nodes (error edges never join)
+	errorSources := make(map[string]int)
 	for _, node := range ir.Nodes {
-		if len(node.Dependencies) > 1 {
+		for _, handler := range node.OnError {
+			errorSources[handler]++
+		}
+	}
+	for _, node := range ir.Nodes {
+		if len(node.Dependencies)-errorSources[node.ID] > 1 {
 			node.WaitForAll = true
 		}
 	}
@@ -185,24 +219,26 @@ func convertWorkflowNode(wfNode *WorkflowNode, conditionalEdges map[string][]Wor
 		ID:           wfNode.ID,
 		Dependencies: []string{},
 		Dependents:   []string{},
+		Retry:        wfNode.Retry,
+		TimeoutMS:    wfNode.TimeoutMS,
 	}
 
-	// Store config in CAS and inline for MVP
+	// Small configs stay inline; larger ones are stored in CAS and loaded on dispatch
 	if len(wfNode.Config) > 0 {
-		// Always include inline config for MVP (fallback if CAS unavailable)
-		node.Config = wfNode.Config
-
-		// Also try to store in CAS for future optimization
 		configJSON, err := json.Marshal(wfNode.Config)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal config: %w", err)
 		}
-		// Use background context for compiler operations
-		casID, err := casClient.Put(context.Background(), configJSON, "application/json;type=node_config")
-		if err == nil {
-			node.ConfigRef = casID
+		node.Config = wfNode.Config
+		if externalizeConfig(wfNode.Config, configJSON) {
+			// Use background context for compiler operations
+			casID, err := casClient.Put(context.Background(), configJSON, "application/json;type=node_config")
+			if err == nil {
+				node.ConfigRef = casID
+				node.Config = nil
+			}
+			// If CAS fails, the config stays inline
 		}
-		// If CAS fails, we still have inline config
 	}
 
 	// Type mapping: workflow.schema.json type → IR type + additional config
@@ -213,39 +249,14 @@ func convertWorkflowNode(wfNode *WorkflowNode, conditionalEdges map[string][]Wor
 	case NodeTypeConditional:
 		// Map to task with branch config (routing happens through branch rules)
 		node.Type = NodeTypeTask
-		// Pass ALL edges so default path gets populated with unconditional edges
-		allEdges := edgesFromNode[wfNode.ID]
-		branchConfig, err := createBranchConfig(wfNode, allEdges)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create branch config: %w", err)
-		}
-		node.Branch = branchConfig
-
-		// Populate Dependents from branch config (for UI and validation)
-		for _, rule := range branchConfig.Rules {
-			node.Dependents = append(node.Dependents, rule.NextNodes...)
+		if err := attachBranchConfig(node, wfNode, edgesFromNode[wfNode.ID]); err != nil {
+			return nil, err
 		}
-		node.Dependents = append(node.Dependents, branchConfig.Default...)
 
 	case NodeTypeHITL:
 		// HITL node - preserve type for specialized routing
+		// Conditional edges get branch config below, like any other node
 		node.Type = NodeTypeHITL
-		// If HITL has conditional edges, create branch config automatically
-		// Pass ALL edges (not just conditional ones) so default path gets populated
-		if _, hasConditionalEdges := conditionalEdges[wfNode.ID]; hasConditionalEdges {
-			allEdges := edgesFromNode[wfNode.ID]
-			branchConfig, err := createBranchConfig(wfNode, allEdges)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create branch config for HITL: %w", err)
-			}
-			node.Branch = branchConfig
-
-			// Populate Dependents from branch config (for UI and validation)
-			for _, rule := range branchConfig.Rules {
-				node.Dependents = append(node.Dependents, rule.NextNodes...)
-			}
-			node.Dependents = append(node.Dependents, branchConfig.Default...)
-		}
 
 	case NodeTypeLoop:
 		// Map to task with loop config (routing happens through loop logic)
@@ -261,8 +272,21 @@ func convertWorkflowNode(wfNode *WorkflowNode, conditionalEdges map[string][]Wor
 		// Just mark as task
 		node.Type = NodeTypeTask
 
+	case NodeTypeMap:
+		// Map keeps its type; the coordinator fans out to the body node at runtime
+		node.Type = NodeTypeMap
+		mapConfig, err := createMapConfig(wfNode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create map config: %w", err)
+		}
+		node.Map = mapConfig
+
+	case NodeTypeSubworkflow:
+		// Subworkflows are inlined by the orchestrator when the run is created
+		return nil, fmt.Errorf("subworkflow node %s must be expanded before compiling", wfNode.ID)
+
 	default:
-		// All other types (function, http, agent, transform, aggregate, filter, etc.)
+		// All other types (function, http, agent, webhook, transform, aggregate, filter, etc.)
 		// are preserved as-is for specialized routing by the coordinator
 		// This allows for extensibility without modifying the compiler
 		if !isValidExecutableType(wfNode.Type) {
@@ -271,16 +295,42 @@ func convertWorkflowNode(wfNode *WorkflowNode, conditionalEdges map[string][]Wor
 		node.Type = wfNode.Type
 	}
 
+	// A condition on an edge always makes it conditional: any node with
+	// conditional edges routes through branch rules, whatever its type.
+	// Loop and map nodes route through their own config.
+	if _, hasConditionalEdges := conditionalEdges[wfNode.ID]; hasConditionalEdges && node.Branch == nil && node.Loop == nil && node.Map == nil {
+		if err := attachBranchConfig(node, wfNode, edgesFromNode[wfNode.ID]); err != nil {
+			return nil, err
+		}
+	}
+
 	return node, nil
 }
 
+// attachBranchConfig routes a node's edges through branch rules
+// Takes ALL the node's edges so unconditional ones make up the default path.
+func attachBranchConfig(node *sdk.Node, wfNode *WorkflowNode, edges []WorkflowEdge) error {
+	branchConfig, err := createBranchConfig(wfNode, edges)
+	if err != nil {
+		return fmt.Errorf("failed to create branch config: %w", err)
+	}
+	node.Branch = branchConfig
+
+	// Populate Dependents from branch config (for UI and validation)
+	for _, rule := range branchConfig.Rules {
+		node.Dependents = append(node.Dependents, rule.NextNodes...)
+	}
+	node.Dependents = append(node.Dependents, branchConfig.Default...)
+	return nil
+}
+
 // isValidExecutableType checks if a node type is a valid executable type
 // Executable types are those that can be routed to specific streams for execution
 func isValidExecutableType(nodeType string) bool {
 	return validExecutableTypes[nodeType]
 }
 
-// createBranchConfig creates branch config from conditional node
+// createBranchConfig creates branch config from a node's conditional edges
 func createBranchConfig(wfNode *WorkflowNode, edges []WorkflowEdge) (*sdk.BranchConfig, error) {
 	branchConfig := &sdk.BranchConfig{
 		Enabled: true,
@@ -288,12 +338,16 @@ func createBranchConfig(wfNode *WorkflowNode, edges []WorkflowEdge) (*sdk.Branch
 		Rules:   []sdk.BranchRule{},
 	}
 
-	// Group edges by condition
+	// Group edges by condition, keeping the edges' order: the first matching rule wins
 	conditionMap := make(map[string][]string)
+	var conditions []string
 	var defaultNodes []string
 
 	for _, edge := range edges {
 		if edge.Condition != "" {
+			if _, seen := conditionMap[edge.Condition]; !seen {
+				conditions = append(conditions, edge.Condition)
+			}
 			conditionMap[edge.Condition] = append(conditionMap[edge.Condition], edge.To)
 		} else {
 			defaultNodes = append(defaultNodes, edge.To)
@@ -301,10 +355,10 @@ func createBranchConfig(wfNode *WorkflowNode, edges []WorkflowEdge) (*sdk.Branch
 	}
 
 	// Create rules for each unique condition
-	for condExpr, nextNodes := range conditionMap {
+	for _, condExpr := range conditions {
 		rule := sdk.BranchRule{
 			Condition: createCELCondition(condExpr),
-			NextNodes: nextNodes,
+			NextNodes: conditionMap[condExpr],
 		}
 		branchConfig.Rules = append(branchConfig.Rules, rule)
 	}
@@ -346,6 +400,129 @@ func createLoopConfig(wfNode *WorkflowNode) (*sdk.LoopConfig, error) {
 	return loopConfig, nil
 }
 
+// createMapConfig creates map config from map node
+func createMapConfig(wfNode *WorkflowNode) (*sdk.MapConfig, error) {
+	over, ok := wfNode.Config["over"].(string)
+	if !ok || over == "" {
+		return nil, fmt.Errorf("map node missing over in config")
+	}
+
+	body, ok := wfNode.Config["body"].(string)
+	if !ok || body == "" {
+		return nil, fmt.Errorf("map node missing body in config")
+	}
+
+	return &sdk.MapConfig{
+		Over: over,
+		Body: body,
+	}, nil
+}
+
+// wireMapBodies makes each map node the only dependency of its body node
+// The body is not connected by edges: the coordinator routes to it once per
+// element and joins the results into the map node's output, so it must be an
+// executable node that belongs to a single map.
+func wireMapBodies(ir *sdk.IR) error {
+	nodeIDs := make([]string, 0, len(ir.Nodes))
+	for id := range ir.Nodes {
+		nodeIDs = append(nodeIDs, id)
+	}
+	sort.Strings(nodeIDs)
+
+	bodies := make(map[string]string) // body → map node
+	for _, id := range nodeIDs {
+		node := ir.Nodes[id]
+		if !node.IsMap() {
+			continue
+		}
+
+		if len(node.Dependencies) == 0 {
+			return fmt.Errorf("map node %s needs an upstream node to select its collection from", id)
+		}
+
+		body, exists := ir.Nodes[node.Map.Body]
+		if !exists {
+			return fmt.Errorf("map node %s: body references non-existent node: %s", id, node.Map.Body)
+		}
+		if !body.IsExecutableType() {
+			return fmt.Errorf("map node %s: body %s must be an executable node, got %s", id, body.ID, body.Type)
+		}
+		if other, used := bodies[body.ID]; used {
+			return fmt.Errorf("node %s is the body of both map %s and map %s", body.ID, other, id)
+		}
+		if len(body.Dependencies) > 0 || len(body.Dependents) > 0 || body.Branch != nil {
+			return fmt.Errorf("map node %s: body %s must not have edges (it runs once per element)", id, body.ID)
+		}
+
+		bodies[body.ID] = id
+		body.Dependencies = []string{id}
+	}
+
+	return nil
+}
+
+// validateConditions compiles every CEL branch rule, loop condition and map selector
+// An expression that doesn't parse or type-check, or reads a node that isn't in
+// the workflow, would otherwise only fail (or silently mis-route) when the run
+// reaches it.
+func validateConditions(ir *sdk.IR) error {
+	env, err := sdk.NewConditionEnv()
+	if err != nil {
+		return err
+	}
+
+	checkRefs := func(nodeID, what, expr string) error {
+		for _, ref := range sdk.ConditionNodeRefs(expr) {
+			if _, exists := ir.Nodes[ref]; !exists {
+				return fmt.Errorf("node %s: %s %q references non-existent node: %s", nodeID, what, expr, ref)
+			}
+		}
+		return nil
+	}
+	check := func(nodeID string, condition *sdk.Condition) error {
+		if condition == nil || condition.Type != ConditionTypeCEL {
+			return nil
+		}
+		if _, err := sdk.CheckCondition(env, condition.Expression); err != nil {
+			return fmt.Errorf("node %s: invalid condition %q: %w", nodeID, condition.Expression, err)
+		}
+		return checkRefs(nodeID, "condition", condition.Expression)
+	}
+
+	// Sorted so the first invalid node reported is stable
+	nodeIDs := make([]string, 0, len(ir.Nodes))
+	for id := range ir.Nodes {
+		nodeIDs = append(nodeIDs, id)
+	}
+	sort.Strings(nodeIDs)
+
+	for _, id := range nodeIDs {
+		node := ir.Nodes[id]
+		if node.Branch != nil && node.Branch.Enabled {
+			for _, rule := range node.Branch.Rules {
+				if err := check(id, rule.Condition); err != nil {
+					return err
+				}
+			}
+		}
+		if node.Loop != nil && node.Loop.Enabled {
+			if err := check(id, node.Loop.Condition); err != nil {
+				return err
+			}
+		}
+		if node.IsMap() {
+			if _, err := sdk.CheckSelector(env, node.Map.Over); err != nil {
+				return fmt.Errorf("node %s: invalid map selector %q: %w", id, node.Map.Over, err)
+			}
+			if err := checkRefs(id, "map selector", node.Map.Over); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // createCELCondition creates a CEL condition from an expression string
 func createCELCondition(expression string) *sdk.Condition {
 	return &sdk.Condition{
@@ -426,10 +603,19 @@ func Compile(dsl *DSL) (*sdk.IR, error) {
 }
 
 // computeTerminalNodes marks nodes with no outgoing edges as terminal
+// Map bodies have no edges but hand their results back to the map node.
 func computeTerminalNodes(ir *sdk.IR) {
 	for _, node := range ir.Nodes {
 		node.IsTerminal = isTerminal(node)
 	}
+	for _, node := range ir.Nodes {
+		if !node.IsMap() {
+			continue
+		}
+		if body, ok := ir.Nodes[node.Map.Body]; ok {
+			body.IsTerminal = false
+		}
+	}
 }
 
 // isTerminal checks if a node is terminal (has no outgoing edges)
@@ -540,7 +726,34 @@ func validate(ir *sdk.IR) error {
 		}
 	}
 
-	// 5. Check for cycles (without loop config)
+	// 4.5. Validate error handlers
+	for _, node := range ir.Nodes {
+		for _, handler := range node.OnError {
+			if _, exists := ir.Nodes[handler]; !exists {
+				return fmt.Errorf("node %s: on_error references non-existent node: %s", node.ID, handler)
+			}
+			if handler == node.ID {
+				return fmt.Errorf("node %s: on_error cannot route to itself", node.ID)
+			}
+		}
+	}
+
+	// 5. Validate loop exit paths lead out of the loop body
+	if err := validateLoopExits(ir); err != nil {
+		return err
+	}
+
+	// 6. Compile branch and loop conditions with the runtime CEL environment
+	if err := validateConditions(ir); err != nil {
+		return err
+	}
+
+	// 7. Every node must be reachable from an entry node
+	if err := validateReachability(ir); err != nil {
+		return err
+	}
+
+	// 8. Check for cycles (without loop config)
 	// Simple DFS-based cycle detection
 	visited := make(map[string]bool)
 	recStack := make(map[string]bool)
@@ -582,6 +795,103 @@ func validate(ir *sdk.IR) error {
 	return nil
 }
 
+// validateLoopExits rejects break_path/timeout_path targets that re-enter the loop body
+// The loop body is every node on a path from loop_back_to to the loop node. An exit
+// target is invalid if it is in the body or can reach the body through ordinary edges.
+// Paths that pass through another loop-enabled node are allowed (intentional nested loop).
+func validateLoopExits(ir *sdk.IR) error {
+	g := graph.FromIR(ir)
+
+	isLoop := func(id string) bool {
+		node, exists := ir.Nodes[id]
+		return exists && node.Loop != nil && node.Loop.Enabled
+	}
+
+	for _, node := range ir.Nodes {
+		if !isLoop(node.ID) {
+			continue
+		}
+
+		body := g.Between(node.Loop.LoopBackTo, node.ID)
+
+		exits := []struct {
+			name    string
+			targets []string
+		}{
+			{"break_path", node.Loop.BreakPath},
+			{"timeout_path", node.Loop.TimeoutPath},
+		}
+
+		for _, exit := range exits {
+			for _, target := range exit.targets {
+				if _, exists := ir.Nodes[target]; !exists {
+					return fmt.Errorf("node %s: loop %s references non-existent node: %s",
+						node.ID, exit.name, target)
+				}
+
+				if body[target] {
+					return fmt.Errorf("node %s: loop %s target %s is inside the loop body (would create an unintended cycle)",
+						node.ID, exit.name, target)
+				}
+
+				reachable := g.ReachableUntil(target, func(id string) bool {
+					return isLoop(id) && !body[id]
+				})
+				for _, id := range g.Nodes() {
+					if reachable[id] && body[id] {
+						return fmt.Errorf("node %s: loop %s target %s re-enters the loop body via %s (would create an unintended cycle)",
+							node.ID, exit.name, target, id)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateReachability rejects nodes no entry node can reach
+// Reachability follows dependents, branch next_nodes, loop paths and error
+// edges. A node that has dependencies but is never routed to (e.g. orphaned by a
+// patch that removed a branch rule) would otherwise leave the run hanging.
+// Workflows that set the unreachable_nodes metadata to "warn" compile with
+// ir.Warnings instead.
+func validateReachability(ir *sdk.IR) error {
+	g := graph.FromIR(ir)
+	for id, node := range ir.Nodes {
+		if node.Loop != nil && node.Loop.Enabled && node.Loop.LoopBackTo != "" {
+			g.AddEdge(id, node.Loop.LoopBackTo)
+		}
+	}
+
+	reachable := make(map[string]bool)
+	for _, entry := range GetEntryNodes(ir) {
+		for id := range g.Reachable(entry.ID) {
+			reachable[id] = true
+		}
+	}
+
+	var unreachable []string
+	for id := range ir.Nodes {
+		if !reachable[id] {
+			unreachable = append(unreachable, id)
+		}
+	}
+	if len(unreachable) == 0 {
+		return nil
+	}
+	sort.Strings(unreachable)
+
+	if ir.WarnsOnUnreachableNodes() {
+		for _, id := range unreachable {
+			ir.Warnings = append(ir.Warnings, fmt.Sprintf("node %s is not reachable from any entry node", id))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("nodes not reachable from any entry node: %s", strings.Join(unreachable, ", "))
+}
+
 // GetEntryNodes returns nodes with no dependencies (entry points)
 func GetEntryNodes(ir *sdk.IR) []*sdk.Node {
 	var entries []*sdk.Node
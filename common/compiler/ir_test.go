@@ -3,14 +3,16 @@ package compiler
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
-	_ "github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/sdk"
 )
 
 // MockCASClient for testing
 type MockCASClient struct {
 	storage map[string][]byte
+	puts    int
 }
 
 func NewMockCASClient() *MockCASClient {
@@ -30,6 +32,7 @@ func (m *MockCASClient) Get(ctx context.Context, ref string) (interface{}, error
 }
 
 func (m *MockCASClient) Put(ctx context.Context, data []byte, mediaType string) (string, error) {
+	m.puts++
 	ref := "cas://test-" + string(data[:10])
 	m.storage[ref] = data
 	return ref, nil
@@ -45,7 +48,7 @@ func TestCompileWorkflowSchema_SimpleSequential(t *testing.T) {
 	schema := &WorkflowSchema{
 		Nodes: []WorkflowNode{
 			{ID: "A", Type: "function", Config: map[string]interface{}{"name": "taskA"}},
-			{ID: "B", Type: "transform", Config: map[string]interface{}{"type": "uppercase"}},
+			{ID: "B", Type: "transform", Config: map[string]interface{}{"mapping": map[string]interface{}{"name": "$.name"}}},
 			{ID: "C", Type: "http", Config: map[string]interface{}{"url": "http://example.com"}},
 		},
 		Edges: []WorkflowEdge{
@@ -193,6 +196,57 @@ func TestCompileWorkflowSchema_ConditionalBranch(t *testing.T) {
 	}
 }
 
+// TestCompileWorkflowSchema_ConditionalEdgesFromFunction tests branch routing for a non-conditional node
+func TestCompileWorkflowSchema_ConditionalEdgesFromFunction(t *testing.T) {
+	schema := &WorkflowSchema{
+		Nodes: []WorkflowNode{
+			{ID: "score", Type: "function", Config: map[string]interface{}{"name": "score"}},
+			{ID: "high", Type: "function", Config: map[string]interface{}{"name": "high_path"}},
+			{ID: "low", Type: "function", Config: map[string]interface{}{"name": "low_path"}},
+			{ID: "audit", Type: "function", Config: map[string]interface{}{"name": "audit"}},
+		},
+		Edges: []WorkflowEdge{
+			{From: "score", To: "high", Condition: "output.score > 80"},
+			{From: "score", To: "low", Condition: "output.score <= 80"},
+			{From: "score", To: "audit"},
+		},
+	}
+
+	ir, err := CompileWorkflowSchema(schema, NewMockCASClient())
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed: %v", err)
+	}
+
+	// Keeps its type, routes through branch rules in edge order
+	node := ir.Nodes["score"]
+	if node.Type != "function" {
+		t.Errorf("Node 'score': expected type 'function', got '%s'", node.Type)
+	}
+	if node.Branch == nil || !node.Branch.Enabled {
+		t.Fatalf("Node 'score' should have enabled branch config")
+	}
+	if len(node.Branch.Rules) != 2 {
+		t.Fatalf("Expected 2 branch rules, got %d", len(node.Branch.Rules))
+	}
+	for i, want := range []struct{ expr, next string }{
+		{"output.score > 80", "high"},
+		{"output.score <= 80", "low"},
+	} {
+		rule := node.Branch.Rules[i]
+		if rule.Condition.Expression != want.expr || len(rule.NextNodes) != 1 || rule.NextNodes[0] != want.next {
+			t.Errorf("Rule %d: expected %s -> [%s], got %s -> %v", i, want.expr, want.next, rule.Condition.Expression, rule.NextNodes)
+		}
+	}
+
+	// Unconditional edges make up the default path
+	if len(node.Branch.Default) != 1 || node.Branch.Default[0] != "audit" {
+		t.Errorf("Expected default path [audit], got %v", node.Branch.Default)
+	}
+	if len(node.Dependents) != 3 {
+		t.Errorf("Expected dependents [high low audit], got %v", node.Dependents)
+	}
+}
+
 // TestCompileWorkflowSchema_Loop tests loop configuration
 func TestCompileWorkflowSchema_Loop(t *testing.T) {
 	schema := &WorkflowSchema{
@@ -265,19 +319,20 @@ func TestCompileWorkflowSchema_TypeMapping(t *testing.T) {
 	tests := []struct {
 		inputType    string
 		expectedType string
+		config       map[string]interface{}
 	}{
-		{"function", "function"},   // Executable types are preserved
-		{"http", "http"},
-		{"transform", "transform"},
-		{"aggregate", "aggregate"},
-		{"filter", "filter"},
-		{"parallel", "task"},       // Control flow type mapped to task
+		{"function", "function", nil}, // Executable types are preserved
+		{"http", "http", map[string]interface{}{"url": "https://example.com"}},
+		{"transform", "transform", map[string]interface{}{"mapping": map[string]interface{}{"id": "$.id"}}},
+		{"aggregate", "aggregate", nil},
+		{"filter", "filter", map[string]interface{}{"condition": "output.ok"}},
+		{"parallel", "task", nil}, // Control flow type mapped to task
 	}
 
 	for _, tt := range tests {
 		schema := &WorkflowSchema{
 			Nodes: []WorkflowNode{
-				{ID: "test", Type: tt.inputType, Config: map[string]interface{}{}},
+				{ID: "test", Type: tt.inputType, Config: tt.config},
 			},
 			Edges: []WorkflowEdge{},
 		}
@@ -346,3 +401,579 @@ func TestCompileWorkflowSchema_Validation(t *testing.T) {
 		})
 	}
 }
+
+// TestCompileWorkflowSchema_LoopExitPaths tests that loop exits cannot re-enter the loop body
+func TestCompileWorkflowSchema_LoopExitPaths(t *testing.T) {
+	loopNode := func(breakPath ...interface{}) WorkflowNode {
+		return WorkflowNode{
+			ID:   "check",
+			Type: "loop",
+			Config: map[string]interface{}{
+				"max_iterations": 3.0,
+				"loop_back_to":   "work",
+				"condition":      "output.done == false",
+				"break_path":     breakPath,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		schema   *WorkflowSchema
+		errorMsg string
+	}{
+		{
+			name: "break_path_leaves_loop",
+			schema: &WorkflowSchema{
+				Nodes: []WorkflowNode{
+					{ID: "start", Type: "function"},
+					{ID: "work", Type: "function"},
+					loopNode("done"),
+					{ID: "done", Type: "function"},
+				},
+				Edges: []WorkflowEdge{
+					{From: "start", To: "work"},
+					{From: "work", To: "check"},
+				},
+			},
+		},
+		{
+			name: "break_path_targets_loop_body",
+			schema: &WorkflowSchema{
+				Nodes: []WorkflowNode{
+					{ID: "start", Type: "function"},
+					{ID: "work", Type: "function"},
+					loopNode("work", "done"),
+					{ID: "done", Type: "function"},
+				},
+				Edges: []WorkflowEdge{
+					{From: "start", To: "work"},
+					{From: "work", To: "check"},
+				},
+			},
+			errorMsg: "break_path target work is inside the loop body",
+		},
+		{
+			name: "break_path_reenters_loop_body",
+			schema: &WorkflowSchema{
+				Nodes: []WorkflowNode{
+					{ID: "start", Type: "function"},
+					{ID: "work", Type: "function"},
+					loopNode("cleanup", "done"),
+					{ID: "cleanup", Type: "function"},
+					{ID: "done", Type: "function"},
+				},
+				Edges: []WorkflowEdge{
+					{From: "start", To: "work"},
+					{From: "work", To: "check"},
+					{From: "cleanup", To: "work"},
+				},
+			},
+			errorMsg: "break_path target cleanup re-enters the loop body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWorkflowSchema(tt.schema, NewMockCASClient())
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Expected error containing '%s', got nil", tt.errorMsg)
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("Expected error containing '%s', got: %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+// TestCompileWorkflowSchema_WebhookRetry tests webhook nodes keep their type and retry policy
+func TestCompileWorkflowSchema_WebhookRetry(t *testing.T) {
+	retry := &RetryPolicy{MaxAttempts: 5, BackoffMS: 200, BackoffMultiplier: 1.5}
+	schema := &WorkflowSchema{
+		Nodes: []WorkflowNode{
+			{ID: "A", Type: "function"},
+			{ID: "B", Type: "webhook", Config: map[string]interface{}{"url": "https://example.com/hook", "mode": "async"}, Retry: retry},
+		},
+		Edges: []WorkflowEdge{{From: "A", To: "B"}},
+	}
+
+	ir, err := CompileWorkflowSchema(schema, NewMockCASClient())
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed: %v", err)
+	}
+
+	nodeB := ir.Nodes["B"]
+	if nodeB.Type != NodeTypeWebhook {
+		t.Errorf("Node B: expected type 'webhook', got '%s'", nodeB.Type)
+	}
+	if !nodeB.IsExecutableType() {
+		t.Errorf("Node B: webhook should be executable")
+	}
+	if nodeB.Retry == nil || *nodeB.Retry != *retry {
+		t.Errorf("Node B: expected retry %+v, got %+v", retry, nodeB.Retry)
+	}
+	if ir.Nodes["A"].Retry != nil {
+		t.Errorf("Node A: expected no retry policy, got %+v", ir.Nodes["A"].Retry)
+	}
+}
+
+// TestCompileWorkflowSchema_ConditionValidation tests CEL conditions are compiled at compile time
+func TestCompileWorkflowSchema_ConditionValidation(t *testing.T) {
+	branching := func(condition string) *WorkflowSchema {
+		return &WorkflowSchema{
+			Nodes: []WorkflowNode{
+				{ID: "check", Type: "conditional"},
+				{ID: "high", Type: "function"},
+				{ID: "low", Type: "function"},
+			},
+			Edges: []WorkflowEdge{
+				{From: "check", To: "high", Condition: condition},
+				{From: "check", To: "low"},
+			},
+		}
+	}
+	looping := func(condition string) *WorkflowSchema {
+		return &WorkflowSchema{
+			Nodes: []WorkflowNode{
+				{ID: "retry", Type: "loop", Config: map[string]interface{}{
+					"max_iterations": float64(3),
+					"loop_back_to":   "retry",
+					"condition":      condition,
+					"break_path":     []interface{}{"done"},
+				}},
+				{ID: "done", Type: "function"},
+			},
+			Edges: []WorkflowEdge{{From: "retry", To: "done"}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		schema   *WorkflowSchema
+		errorMsg string
+	}{
+		{name: "valid_branch", schema: branching("output.score > 80 && vars.enabled == true")},
+		{name: "valid_jsonpath_shorthand", schema: branching("$.approved == true")},
+		{name: "valid_loop", schema: looping("output.status != 'success'")},
+		{name: "valid_node_reference", schema: branching("nodes.high.output.score > nodes['low'].output.score")},
+		{
+			name:     "unknown_node_reference",
+			schema:   branching("nodes.missing.output.score > 80"),
+			errorMsg: `node check: condition "nodes.missing.output.score > 80" references non-existent node: missing`,
+		},
+		{
+			name:     "malformed_branch",
+			schema:   branching("output.score >"),
+			errorMsg: `node check: invalid condition "output.score >": `,
+		},
+		{
+			name:     "undeclared_variable",
+			schema:   branching("result.score > 80"),
+			errorMsg: `node check: invalid condition "result.score > 80": `,
+		},
+		{
+			name:     "non_boolean_result",
+			schema:   branching("1 + 2"),
+			errorMsg: "expression must return bool",
+		},
+		{
+			name:     "malformed_loop",
+			schema:   looping("output.status ==="),
+			errorMsg: `node retry: invalid condition "output.status ===": `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWorkflowSchema(tt.schema, NewMockCASClient())
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Fatalf("Expected error containing '%s', got: %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+// TestValidate_Reachability tests nodes no entry node can reach are rejected
+func TestValidate_Reachability(t *testing.T) {
+	// start branches to approved (rule) or manual (default); orphan still
+	// depends on start but its branch rule was removed by a patch
+	newIR := func(metadata map[string]interface{}) *sdk.IR {
+		return &sdk.IR{
+			Version: "1.0",
+			Nodes: map[string]*sdk.Node{
+				"start": {ID: "start", Type: "task", Dependents: []string{"approved", "manual"}, Branch: &sdk.BranchConfig{
+					Enabled: true,
+					Type:    "conditional",
+					Rules: []sdk.BranchRule{{
+						Condition: &sdk.Condition{Type: "cel", Expression: "output.ok == true"},
+						NextNodes: []string{"approved"},
+					}},
+					Default: []string{"manual"},
+				}},
+				"approved": {ID: "approved", Type: "function", Dependencies: []string{"start"}, IsTerminal: true},
+				"manual":   {ID: "manual", Type: "function", Dependencies: []string{"start"}, IsTerminal: true},
+				"orphan":   {ID: "orphan", Type: "function", Dependencies: []string{"start"}, IsTerminal: true},
+			},
+			Metadata: metadata,
+		}
+	}
+
+	ir := newIR(nil)
+	err := validate(ir)
+	if err == nil || err.Error() != "nodes not reachable from any entry node: orphan" {
+		t.Fatalf("Expected unreachable orphan error, got: %v", err)
+	}
+
+	// Warn mode compiles and records the finding instead
+	ir = newIR(map[string]interface{}{sdk.MetadataUnreachableNodes: "warn"})
+	if err := validate(ir); err != nil {
+		t.Fatalf("Expected no error in warn mode, got: %v", err)
+	}
+	if len(ir.Warnings) != 1 || !strings.Contains(ir.Warnings[0], "node orphan") {
+		t.Errorf("Expected one warning for orphan, got %v", ir.Warnings)
+	}
+
+	// A node only reachable through the branch default is fine
+	ir = newIR(nil)
+	delete(ir.Nodes, "orphan")
+	if err := validate(ir); err != nil {
+		t.Errorf("Expected default-only node to be reachable, got: %v", err)
+	}
+	if len(ir.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", ir.Warnings)
+	}
+}
+
+// TestCompileWorkflowSchema_DisconnectedNode tests a node cut off from every entry is rejected
+func TestCompileWorkflowSchema_DisconnectedNode(t *testing.T) {
+	// island loops on itself, so it has a dependency but nothing routes to it
+	schema := &WorkflowSchema{
+		Nodes: []WorkflowNode{
+			{ID: "A", Type: "function"},
+			{ID: "B", Type: "function"},
+			{ID: "island", Type: "loop", Config: map[string]interface{}{
+				"max_iterations": float64(3),
+				"loop_back_to":   "island",
+				"condition":      "output.done == false",
+			}},
+		},
+		Edges: []WorkflowEdge{
+			{From: "A", To: "B"},
+			{From: "island", To: "island"},
+		},
+	}
+
+	_, err := CompileWorkflowSchema(schema, NewMockCASClient())
+	if err == nil || !strings.Contains(err.Error(), "not reachable from any entry node: island") {
+		t.Fatalf("Expected disconnected node error, got: %v", err)
+	}
+}
+
+// TestCompileWorkflowSchema_MapNode tests map nodes wire their body and validate their config
+func TestCompileWorkflowSchema_MapNode(t *testing.T) {
+	mapping := func(config map[string]interface{}, extraEdges ...WorkflowEdge) *WorkflowSchema {
+		return &WorkflowSchema{
+			Nodes: []WorkflowNode{
+				{ID: "fetch", Type: "http", Config: map[string]interface{}{"url": "https://example.com/items"}},
+				{ID: "each", Type: "map", Config: config},
+				{ID: "process", Type: "http", Config: map[string]interface{}{"url": "https://example.com/process"}},
+				{ID: "summarize", Type: "agent"},
+			},
+			Edges: append([]WorkflowEdge{
+				{From: "fetch", To: "each"},
+				{From: "each", To: "summarize"},
+			}, extraEdges...),
+		}
+	}
+	valid := map[string]interface{}{"over": "output.items", "body": "process"}
+
+	ir, err := CompileWorkflowSchema(mapping(valid), NewMockCASClient())
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed: %v", err)
+	}
+
+	each := ir.Nodes["each"]
+	if each.Type != NodeTypeMap || !each.IsMap() {
+		t.Fatalf("Node each: expected map node, got type %s", each.Type)
+	}
+	if each.Map.Over != "output.items" || each.Map.Body != "process" {
+		t.Errorf("Node each: unexpected map config %+v", each.Map)
+	}
+	if len(each.Dependents) != 1 || each.Dependents[0] != "summarize" {
+		t.Errorf("Node each: expected dependents [summarize], got %v", each.Dependents)
+	}
+
+	process := ir.Nodes["process"]
+	if len(process.Dependencies) != 1 || process.Dependencies[0] != "each" {
+		t.Errorf("Node process: expected dependencies [each], got %v", process.Dependencies)
+	}
+	if process.IsTerminal {
+		t.Errorf("Node process: map body should not be terminal")
+	}
+	for _, entry := range GetEntryNodes(ir) {
+		if entry.ID != "fetch" {
+			t.Errorf("Unexpected entry node %s", entry.ID)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		schema   *WorkflowSchema
+		errorMsg string
+	}{
+		{
+			name:     "missing_body",
+			schema:   mapping(map[string]interface{}{"over": "output.items"}),
+			errorMsg: "node each: config invalid: missing required field body",
+		},
+		{
+			name:     "unknown_body",
+			schema:   mapping(map[string]interface{}{"over": "output.items", "body": "nope"}),
+			errorMsg: "body references non-existent node: nope",
+		},
+		{
+			name:     "body_with_edges",
+			schema:   mapping(valid, WorkflowEdge{From: "process", To: "summarize"}),
+			errorMsg: "body process must not have edges",
+		},
+		{
+			name:     "selector_not_a_list",
+			schema:   mapping(map[string]interface{}{"over": "1 + 2", "body": "process"}),
+			errorMsg: "expression must return a list",
+		},
+		{
+			name:     "malformed_selector",
+			schema:   mapping(map[string]interface{}{"over": "output.items[", "body": "process"}),
+			errorMsg: `node each: invalid map selector "output.items[": `,
+		},
+		{
+			name:     "selector_unknown_node",
+			schema:   mapping(map[string]interface{}{"over": "nodes.nope.output.items", "body": "process"}),
+			errorMsg: `node each: map selector "nodes.nope.output.items" references non-existent node: nope`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWorkflowSchema(tt.schema, NewMockCASClient())
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Fatalf("Expected error containing '%s', got: %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+// TestCompileWorkflowSchema_ErrorHandler tests on_error edges wire error handlers apart from normal routing
+func TestCompileWorkflowSchema_ErrorHandler(t *testing.T) {
+	handled := func(extraEdges ...WorkflowEdge) *WorkflowSchema {
+		return &WorkflowSchema{
+			Nodes: []WorkflowNode{
+				{ID: "A", Type: "http", Config: map[string]interface{}{"url": "https://example.com/a"}},
+				{ID: "B", Type: "http", Config: map[string]interface{}{"url": "https://example.com/b"}},
+				{ID: "C", Type: "function"},
+				{ID: "handler", Type: "function"},
+			},
+			Edges: append([]WorkflowEdge{
+				{From: "A", To: "B"},
+				{From: "B", To: "C"},
+				{From: "B", To: "handler", OnError: true},
+			}, extraEdges...),
+		}
+	}
+
+	ir, err := CompileWorkflowSchema(handled(), NewMockCASClient())
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed: %v", err)
+	}
+
+	b := ir.Nodes["B"]
+	if len(b.OnError) != 1 || b.OnError[0] != "handler" {
+		t.Errorf("Node B: expected on_error [handler], got %v", b.OnError)
+	}
+	if len(b.Dependents) != 1 || b.Dependents[0] != "C" {
+		t.Errorf("Node B: expected dependents [C], got %v", b.Dependents)
+	}
+
+	handler := ir.Nodes["handler"]
+	if len(handler.Dependencies) != 1 || handler.Dependencies[0] != "B" {
+		t.Errorf("Node handler: expected dependencies [B], got %v", handler.Dependencies)
+	}
+	if !handler.IsTerminal {
+		t.Errorf("Node handler: expected terminal node")
+	}
+
+	tests := []struct {
+		name     string
+		schema   *WorkflowSchema
+		errorMsg string
+	}{
+		{
+			name:     "conditional_error_edge",
+			schema:   handled(WorkflowEdge{From: "A", To: "handler", OnError: true, Condition: "output.ok == false"}),
+			errorMsg: "on_error edges cannot have a condition",
+		},
+		{
+			name:     "unknown_handler",
+			schema:   handled(WorkflowEdge{From: "A", To: "nope", OnError: true}),
+			errorMsg: "edge references non-existent node: nope",
+		},
+		{
+			name:     "handler_is_source",
+			schema:   handled(WorkflowEdge{From: "C", To: "C", OnError: true}),
+			errorMsg: "node C: on_error cannot route to itself",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWorkflowSchema(tt.schema, NewMockCASClient())
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Fatalf("Expected error containing '%s', got: %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+// TestCompileWorkflowSchema_NodeConfig tests node configs are validated against their type's schema
+func TestCompileWorkflowSchema_NodeConfig(t *testing.T) {
+	single := func(nodeType string, config map[string]interface{}) *WorkflowSchema {
+		return &WorkflowSchema{
+			Nodes: []WorkflowNode{{ID: "X", Type: nodeType, Config: config}},
+			Edges: []WorkflowEdge{},
+		}
+	}
+
+	ir, err := CompileWorkflowSchema(single("agent", map[string]interface{}{
+		"model":       "gpt-4",
+		"task":        "Summarize the report",
+		"temperature": 0.3,
+		"tools":       []string{"execute_pipeline"},
+	}), NewMockCASClient())
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed for a valid agent config: %v", err)
+	}
+	if ir.Nodes["X"].Config["model"] != "gpt-4" {
+		t.Errorf("Node X: expected config to be kept, got %v", ir.Nodes["X"].Config)
+	}
+
+	// The model is optional, the agent runner falls back to its own
+	if _, err := CompileWorkflowSchema(single("agent", map[string]interface{}{"task": "Summarize the report"}), NewMockCASClient()); err != nil {
+		t.Fatalf("CompileWorkflowSchema failed for an agent config without a model: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		schema   *WorkflowSchema
+		errorMsg string
+	}{
+		{
+			name:     "http_missing_url",
+			schema:   single("http", map[string]interface{}{"method": "POST"}),
+			errorMsg: "node X: config invalid: missing required field url",
+		},
+		{
+			name:     "http_empty_url",
+			schema:   single("http", map[string]interface{}{"url": ""}),
+			errorMsg: "node X: config invalid: missing required field url",
+		},
+		{
+			name:     "agent_wrong_type",
+			schema:   single("agent", map[string]interface{}{"model": 4}),
+			errorMsg: "node X: config invalid: field model must be a string, got number",
+		},
+		{
+			name:     "agent_object_model",
+			schema:   single("agent", map[string]interface{}{"model": map[string]interface{}{"name": "gpt"}}),
+			errorMsg: "node X: config invalid: field model must be a string, got object",
+		},
+		{
+			name:     "http_array_headers",
+			schema:   single("http", map[string]interface{}{"url": "https://example.com", "headers": []interface{}{"X-A"}}),
+			errorMsg: "node X: config invalid: field headers must be an object, got array",
+		},
+		{
+			name:     "webhook_unknown_mode",
+			schema:   single("webhook", map[string]interface{}{"url": "https://example.com/hook", "mode": "later"}),
+			errorMsg: `node X: config invalid: field mode must be one of sync, async, got "later"`,
+		},
+		{
+			name:     "loop_fractional_iterations",
+			schema:   single("loop", map[string]interface{}{"max_iterations": 2.5, "loop_back_to": "X"}),
+			errorMsg: "node X: config invalid: field max_iterations must be an integer, got number 2.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileWorkflowSchema(tt.schema, NewMockCASClient())
+			if err == nil || err.Error() != tt.errorMsg {
+				t.Fatalf("Expected error '%s', got: %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestCompileWorkflowSchema_InlineConfig(t *testing.T) {
+	single := func(config map[string]interface{}) *WorkflowSchema {
+		return &WorkflowSchema{
+			Nodes: []WorkflowNode{{ID: "X", Type: "function", Config: config}},
+			Edges: []WorkflowEdge{},
+		}
+	}
+	large := strings.Repeat("x", DefaultInlineConfigMaxBytes)
+
+	// Small configs stay inline, without a CAS put
+	casClient := NewMockCASClient()
+	ir, err := CompileWorkflowSchema(single(map[string]interface{}{"handler": "echo"}), casClient)
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed: %v", err)
+	}
+	if ir.Nodes["X"].Config["handler"] != "echo" || ir.Nodes["X"].ConfigRef != "" {
+		t.Errorf("Node X: expected inline config only, got config %v, ref %q", ir.Nodes["X"].Config, ir.Nodes["X"].ConfigRef)
+	}
+	if casClient.puts != 0 {
+		t.Errorf("Expected no CAS puts for a small config, got %d", casClient.puts)
+	}
+
+	// Large configs are externalized to CAS
+	casClient = NewMockCASClient()
+	ir, err = CompileWorkflowSchema(single(map[string]interface{}{"handler": "echo", "code": large}), casClient)
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed: %v", err)
+	}
+	node := ir.Nodes["X"]
+	if node.Config != nil || node.ConfigRef == "" {
+		t.Fatalf("Node X: expected config ref only, got config %v, ref %q", node.Config, node.ConfigRef)
+	}
+	stored, _ := casClient.Get(context.Background(), node.ConfigRef)
+	if config, _ := stored.(map[string]interface{}); config["code"] != large {
+		t.Errorf("Node X: expected CAS to hold the config, got %v", stored)
+	}
+
+	// Large configs with redaction rules stay inline
+	casClient = NewMockCASClient()
+	ir, err = CompileWorkflowSchema(single(map[string]interface{}{
+		"code":   large,
+		"redact": []interface{}{map[string]interface{}{"path": "token"}},
+	}), casClient)
+	if err != nil {
+		t.Fatalf("CompileWorkflowSchema failed: %v", err)
+	}
+	if ir.Nodes["X"].Config["code"] != large || casClient.puts != 0 {
+		t.Errorf("Node X: expected redacted config to stay inline, got ref %q, %d puts", ir.Nodes["X"].ConfigRef, casClient.puts)
+	}
+}
@@ -0,0 +1,241 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Config field types, named after their JSON types
+const (
+	FieldString  = "string"
+	FieldNumber  = "number"
+	FieldInteger = "integer"
+	FieldBoolean = "boolean"
+	FieldObject  = "object"
+	FieldArray   = "array"
+)
+
+// ConfigField describes one field of a node's config
+// An empty Type accepts any value. Required string fields must not be empty.
+type ConfigField struct {
+	Type     string
+	Required bool
+	Enum     []string // Allowed values of a string field
+}
+
+// ConfigSchema describes the config a node type expects, by field name
+// Fields not listed are allowed and not checked.
+type ConfigSchema map[string]ConfigField
+
+// nodeConfigSchemas holds the config schema of each node type
+// Types without a schema (conditional, parallel) accept any config.
+var nodeConfigSchemas = map[string]ConfigSchema{
+	NodeTypeHTTP: {
+		"url":     {Type: FieldString, Required: true},
+		"method":  {Type: FieldString},
+		"headers": {Type: FieldObject},
+		"payload": {Type: FieldString},
+	},
+	NodeTypeAgent: {
+		// Optional: the agent runner uses its own configured model when a node names none
+		"model":       {Type: FieldString},
+		"task":        {Type: FieldString},
+		"prompt":      {Type: FieldString},
+		"temperature": {Type: FieldNumber},
+		"tools":       {Type: FieldArray},
+	},
+	NodeTypeWebhook: {
+		"url":     {Type: FieldString, Required: true},
+		"mode":    {Type: FieldString, Enum: []string{"sync", "async"}},
+		"method":  {Type: FieldString},
+		"headers": {Type: FieldObject},
+		// Async only: how long to wait for the callback before failing the node
+		"callback_timeout_ms": {Type: FieldInteger},
+	},
+	NodeTypeFunction: {
+		"handler": {Type: FieldString},
+	},
+	NodeTypeHITL: {
+		"message": {Type: FieldString},
+	},
+	NodeTypeTransform: {
+		"mapping": {Type: FieldObject, Required: true},
+	},
+	NodeTypeFilter: {
+		"condition": {Type: FieldString, Required: true},
+	},
+	NodeTypeAggregate: {
+		"strategy": {Type: FieldString, Enum: []string{"collect", "sum", "merge"}},
+		"value":    {Type: FieldString},
+	},
+	NodeTypeLoop: {
+		"max_iterations": {Type: FieldInteger, Required: true},
+		"loop_back_to":   {Type: FieldString, Required: true},
+		"condition":      {Type: FieldString},
+		"break_path":     {Type: FieldArray},
+		"timeout_path":   {Type: FieldArray},
+	},
+	NodeTypeMap: {
+		"over": {Type: FieldString, Required: true},
+		"body": {Type: FieldString, Required: true},
+	},
+}
+
+// RegisterConfigSchema sets the config schema for nodeType, replacing any existing one
+// Register schemas at startup, before workflows are compiled.
+func RegisterConfigSchema(nodeType string, schema ConfigSchema) {
+	nodeConfigSchemas[nodeType] = schema
+}
+
+// validateNodeConfig checks a node's config against its type's schema
+func validateNodeConfig(wfNode *WorkflowNode) error {
+	schema, ok := nodeConfigSchemas[wfNode.Type]
+	if !ok {
+		return nil
+	}
+	if err := schema.Validate(wfNode.Config); err != nil {
+		return fmt.Errorf("node %s: config invalid: %w", wfNode.ID, err)
+	}
+	return nil
+}
+
+// Validate checks config against the schema
+// Fields are checked in name order, so the first problem reported is stable.
+func (s ConfigSchema) Validate(config map[string]interface{}) error {
+	if problems := s.Problems(config); len(problems) > 0 {
+		return errors.New(problems[0])
+	}
+	return nil
+}
+
+// Problems returns every way config doesn't match the schema, in field name order
+func (s ConfigSchema) Problems(config map[string]interface{}) []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		field := s[name]
+		value, present := config[name]
+		if !present || value == nil {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("missing required field %s", name))
+			}
+			continue
+		}
+
+		if !hasFieldType(value, field.Type) {
+			problems = append(problems, fmt.Sprintf("field %s must be %s, got %s", name, articled(field.Type), jsonTypeName(value)))
+			continue
+		}
+		if str, ok := value.(string); ok {
+			if field.Required && str == "" {
+				problems = append(problems, fmt.Sprintf("missing required field %s", name))
+			} else if len(field.Enum) > 0 && !containsString(field.Enum, str) {
+				problems = append(problems, fmt.Sprintf("field %s must be one of %s, got %q", name, strings.Join(field.Enum, ", "), str))
+			}
+		}
+	}
+	return problems
+}
+
+// ValidFieldType returns true for a known config field type, or "" for any type
+func ValidFieldType(fieldType string) bool {
+	switch fieldType {
+	case "", FieldString, FieldNumber, FieldInteger, FieldBoolean, FieldObject, FieldArray:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasFieldType returns true if value has the JSON type fieldType
+// Numbers may be any Go numeric type; configs built in Go rather than decoded
+// from JSON keep their native types.
+func hasFieldType(value interface{}, fieldType string) bool {
+	kind := reflect.TypeOf(value).Kind()
+	switch fieldType {
+	case "":
+		return true
+	case FieldString:
+		return kind == reflect.String
+	case FieldBoolean:
+		return kind == reflect.Bool
+	case FieldNumber:
+		return isNumberKind(kind)
+	case FieldInteger:
+		if !isNumberKind(kind) {
+			return false
+		}
+		if kind == reflect.Float32 || kind == reflect.Float64 {
+			f := reflect.ValueOf(value).Float()
+			return f == float64(int64(f))
+		}
+		return true
+	case FieldObject:
+		return kind == reflect.Map
+	case FieldArray:
+		return kind == reflect.Slice || kind == reflect.Array
+	default:
+		return false
+	}
+}
+
+// jsonTypeName names value's type as the JSON type it would be encoded as
+// Problems are shown to API clients, who know JSON types, not Go ones. JSON has
+// no integer type, so a fractional number is named with its value: that's why
+// it isn't an integer.
+func jsonTypeName(value interface{}) string {
+	kind := reflect.TypeOf(value).Kind()
+	switch {
+	case kind == reflect.String:
+		return FieldString
+	case kind == reflect.Bool:
+		return FieldBoolean
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		if f := reflect.ValueOf(value).Float(); f != float64(int64(f)) {
+			return fmt.Sprintf("%s %v", FieldNumber, value)
+		}
+		return FieldNumber
+	case isNumberKind(kind):
+		return FieldNumber
+	case kind == reflect.Slice || kind == reflect.Array:
+		return FieldArray
+	default:
+		return FieldObject
+	}
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// articled prefixes a field type with "a" or "an"
+func articled(fieldType string) string {
+	if strings.IndexAny(fieldType[:1], "aeiou") == 0 {
+		return "an " + fieldType
+	}
+	return "a " + fieldType
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
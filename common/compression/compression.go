@@ -0,0 +1,90 @@
+// Package compression encodes CAS blobs for storage
+// Blobs are addressed by the hash of their uncompressed content, so the codec
+// only affects how bytes are stored, never the CAS ID. The encoding Encode
+// returns is stored beside the blob and handed back to Decode; it is never
+// inferred from the stored bytes.
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs for stored content, matching the cas_blob.content_encoding values
+const (
+	Identity = "identity"
+	Gzip     = "gzip"
+	Zstd     = "zstd"
+	None     = "none"
+)
+
+// Shared zstd coders; EncodeAll and DecodeAll are safe for concurrent use
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// Encode compresses content with codec when it is larger than threshold
+// Returns the stored bytes and their encoding. Falls back to identity when
+// compression is disabled or doesn't make the content smaller.
+func Encode(content []byte, codec string, threshold int) ([]byte, string, error) {
+	if len(content) <= threshold {
+		return content, Identity, nil
+	}
+
+	var stored []byte
+	switch codec {
+	case Gzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(content); err != nil {
+			return nil, "", fmt.Errorf("failed to gzip content: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, "", fmt.Errorf("failed to gzip content: %w", err)
+		}
+		stored = buf.Bytes()
+	case Zstd:
+		stored = zstdEncoder.EncodeAll(content, nil)
+	default:
+		return content, Identity, nil
+	}
+
+	if len(stored) >= len(content) {
+		return content, Identity, nil
+	}
+
+	return stored, codec, nil
+}
+
+// Decode reverses the storage encoding of a blob
+func Decode(stored []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", Identity:
+		return stored, nil
+	case Gzip:
+		zr, err := gzip.NewReader(bytes.NewReader(stored))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip content: %w", err)
+		}
+		defer zr.Close()
+
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip content: %w", err)
+		}
+		return content, nil
+	case Zstd:
+		content, err := zstdDecoder.DecodeAll(stored, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress zstd content: %w", err)
+		}
+		return content, nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
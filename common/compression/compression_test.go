@@ -0,0 +1,59 @@
+package compression
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	large := []byte(strings.Repeat(`{"node":"a","status":"completed"}`, 500))
+
+	for _, codec := range []string{Gzip, Zstd} {
+		stored, encoding, err := Encode(large, codec, 1024)
+		require.NoError(t, err)
+		assert.Equal(t, codec, encoding)
+		assert.Less(t, len(stored), len(large))
+
+		decoded, err := Decode(stored, encoding)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(large, decoded), codec)
+
+		// The encoding is taken as given, never guessed from the bytes
+		raw, err := Decode(stored, Identity)
+		require.NoError(t, err)
+		assert.Equal(t, stored, raw)
+	}
+
+	gzipped, _, err := Encode(large, Gzip, 0)
+	require.NoError(t, err)
+	_, err = Decode(gzipped, Zstd)
+	assert.Error(t, err)
+}
+
+func TestEncodeKeepsSmallAndIncompressibleContent(t *testing.T) {
+	small := []byte(`{"tiny":true}`)
+	stored, encoding, err := Encode(small, Gzip, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, Identity, encoding)
+	assert.Equal(t, small, stored)
+
+	// Disabled compression never encodes
+	large := []byte(strings.Repeat("x", 4096))
+	_, encoding, err = Encode(large, None, 0)
+	require.NoError(t, err)
+	assert.Equal(t, Identity, encoding)
+
+	// Output larger than the input falls back to identity
+	for _, codec := range []string{Gzip, Zstd} {
+		_, encoding, err = Encode([]byte("ab"), codec, 0)
+		require.NoError(t, err)
+		assert.Equal(t, Identity, encoding)
+	}
+
+	_, err = Decode(small, "br")
+	assert.Error(t, err)
+}
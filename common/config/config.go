@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,6 +15,19 @@ type Config struct {
 	Cache      CacheConfig
 	Queue      QueueConfig
 	Telemetry  TelemetryConfig
+	CAS        CASConfig
+	Readiness  ReadinessConfig
+	Compaction CompactionConfig
+	CASGC      CASGCConfig
+	StreamTrim StreamTrimConfig
+	RateLimit  RateLimitConfig
+	Templates  TemplateConfig
+	Admin      AdminConfig
+	Limits     WorkflowLimitsConfig
+	RunState   RunStateConfig
+	CORS       CORSConfig
+	Consumer   ConsumerConfig
+	Routing    RoutingConfig
 	Features   FeatureFlags
 }
 
@@ -24,6 +38,16 @@ type ServiceConfig struct {
 	Environment string
 	LogLevel    string
 	LogFormat   string
+	Workers     int // Messages each stream consumer handles in parallel
+
+	// ShutdownTimeout is how long in-flight requests may run after SIGTERM
+	ShutdownTimeout time.Duration
+
+	// HTTP server limits (orchestrator)
+	RequestTimeout       time.Duration // Deadline on each request's context; 0 disables it
+	ReadHeaderTimeout    time.Duration // How long a client may take to send request headers
+	MaxBodyBytes         int64         // Request body cap
+	MaxWorkflowBodyBytes int64         // Request body cap for workflow uploads and patches
 }
 
 // DatabaseConfig holds Postgres connection settings
@@ -61,7 +85,135 @@ type TelemetryConfig struct {
 	EnableTracing  bool
 	EnableMetrics  bool
 	MetricsPort    int
-	TracingBackend string
+	TracingBackend string // "otlp" exports spans (OTEL_EXPORTER_OTLP_* settings); "none" only propagates them
+}
+
+// CASConfig holds content-addressed storage settings
+type CASConfig struct {
+	Compression          string // "none", "gzip" or "zstd"
+	CompressionThreshold int    // Only blobs larger than this (bytes) are compressed
+
+	// Execution-time CAS (clients.CASClient) used by the runner and workers
+	Backend  string        // "redis", "s3" or "tiered" (Redis cache in front of S3)
+	CacheTTL time.Duration // How long the tiered backend keeps blobs in Redis
+	S3       S3Config
+
+	// SigningKey signs CAS blobs, execution-time and stored, which are verified on read
+	// Every service sharing the CAS must use the same key; empty disables signing.
+	SigningKey string
+}
+
+// S3Config holds settings for an S3-compatible object store
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Region          string
+	Bucket          string
+	Prefix          string // Key prefix for CAS objects
+	AccessKeyID     string
+	SecretAccessKey string
+	UsePathStyle    bool // Address the bucket as endpoint/bucket (required by MinIO)
+}
+
+// ReadinessConfig holds thresholds for the /readyz probe
+// Streams and RequiredGroups are "stream:group" pairs.
+type ReadinessConfig struct {
+	Streams             []string      // Consumer groups whose backlog is checked
+	RequiredGroups      []string      // Consumer groups that must have a live consumer
+	MaxBacklog          int           // Backlog (undelivered + pending) above this is not ready
+	ConsumerIdleTimeout time.Duration // Consumers idle longer than this are not live
+}
+
+// CompactionConfig holds settings for automatic background compaction
+type CompactionConfig struct {
+	Enabled        bool
+	Interval       time.Duration // How often candidates are scanned
+	DepthThreshold int           // Patch chains at or above this depth are compacted
+	DryRun         bool          // Log what would be compacted without changing anything
+	LockTTL        time.Duration // Per-tag lock lifetime while compacting
+}
+
+// CASGCConfig holds settings for background garbage collection of CAS blobs
+type CASGCConfig struct {
+	Enabled     bool
+	Interval    time.Duration // How often unreferenced blobs are collected
+	GracePeriod time.Duration // Unreferenced blobs younger than this are kept
+	DryRun      bool          // Report reclaimable blobs without deleting them
+}
+
+// StreamTrimConfig holds settings for background trimming of Redis streams
+// Streams entries may be exact names or glob patterns (e.g. "wf.tasks.*").
+type StreamTrimConfig struct {
+	Enabled  bool          // Off by default: trimming discards stream history operators may rely on
+	Interval time.Duration // How often streams are trimmed
+	MaxLen   int64         // Target length; entries still needed by a consumer group are kept
+	Streams  []string
+}
+
+// RateLimitConfig holds settings for run rate limiting
+// WorkflowLimits and OrgLimits map a tag or org ("*" for all others) to runs per
+// minute, and are read from "name=limit" lists.
+type RateLimitConfig struct {
+	Algorithm      string // sliding_window, or fixed_window for comparison
+	WorkflowLimits map[string]int64
+	OrgLimits      map[string]int64
+
+	// NodeCostWeights override the default cost units per node type in run estimates
+	NodeCostWeights map[string]int64
+}
+
+// TemplateConfig holds settings for resolving ${...} templates in node config
+type TemplateConfig struct {
+	Strict bool // Fail nodes whose templates reference missing values instead of keeping them as written
+}
+
+// AdminConfig holds settings for admin-only (internal) endpoints
+type AdminConfig struct {
+	Users []string // Usernames (X-User-ID) allowed to call admin endpoints; none if empty
+}
+
+// WorkflowLimitsConfig bounds the size of workflows and patches users can save
+// A limit of 0 disables it.
+type WorkflowLimitsConfig struct {
+	MaxNodes           int
+	MaxEdges           int
+	MaxWorkflowBytes   int // Size of the whole workflow as JSON
+	MaxNodeConfigBytes int // Size of a node's config as JSON
+	MaxPatchOperations int
+}
+
+// RunStateConfig holds settings for runs' coordinator state in Redis
+type RunStateConfig struct {
+	Retention      time.Duration // How long a finished run's state is kept (run details, results, retries)
+	StallThreshold time.Duration // How long a run may go without counter changes before it is flagged stalled
+	MarkStalled    bool          // Also persist the STALLED status for flagged runs
+}
+
+// CORSConfig holds the orchestrator's cross-origin policy for browser clients
+// No origin is allowed unless listed; "*" allows any origin.
+type CORSConfig struct {
+	AllowedOrigins   []string      // Exact origins, e.g. https://app.example.com
+	AllowedMethods   []string      // Methods a preflight may approve
+	AllowedHeaders   []string      // Request headers a preflight may approve
+	ExposedHeaders   []string      // Response headers browsers let scripts read
+	AllowCredentials bool          // Let browsers send cookies and auth headers
+	MaxAge           time.Duration // How long browsers may cache a preflight response
+}
+
+// ConsumerConfig holds settings shared by the stream consumers
+// A failed read pauses BackoffBase, doubling with each failure in a row up to
+// BackoffMax; a successful read resets it.
+type ConsumerConfig struct {
+	Block         time.Duration // How long a read waits for new messages
+	BackoffBase   time.Duration // Pause after the first failed read
+	BackoffMax    time.Duration // Longest pause between failed reads
+	BackoffJitter float64       // Fraction (0-1) of each pause randomly cut, so consumers don't retry in lockstep
+}
+
+// RoutingConfig holds the workflow-runner's node type → worker stream mapping
+// Streams add to or override the built-in mappings (agent, http, hitl, webhook,
+// function), so new worker types can be routed without code changes.
+type RoutingConfig struct {
+	Streams map[string]string // Node type → Redis stream, e.g. transform=wf.tasks.transform
 }
 
 // FeatureFlags for MVP toggles
@@ -81,6 +233,14 @@ func Load(serviceName string) (*Config, error) {
 			Environment: getEnv("ENVIRONMENT", "development"),
 			LogLevel:    getEnv("LOG_LEVEL", "info"),
 			LogFormat:   getEnv("LOG_FORMAT", "text"), // Default to text for development
+			Workers:     getEnvInt("RUNNER_WORKERS", 1),
+
+			ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+			RequestTimeout:       getEnvDuration("REQUEST_TIMEOUT", 30*time.Second),
+			ReadHeaderTimeout:    getEnvDuration("READ_HEADER_TIMEOUT", 10*time.Second),
+			MaxBodyBytes:         int64(getEnvInt("REQUEST_BODY_LIMIT_BYTES", 1<<20)),
+			MaxWorkflowBodyBytes: int64(getEnvInt("WORKFLOW_BODY_LIMIT_BYTES", 10<<20)),
 		},
 		Database: DatabaseConfig{
 			Host:        getEnv("POSTGRES_HOST", "localhost"),
@@ -110,7 +270,105 @@ func Load(serviceName string) (*Config, error) {
 			EnableTracing:  getEnvBool("ENABLE_TRACING", true),
 			EnableMetrics:  getEnvBool("ENABLE_METRICS", true),
 			MetricsPort:    getEnvInt("METRICS_PORT", 9090),
-			TracingBackend: getEnv("TRACING_BACKEND", "stdout"),
+			TracingBackend: getEnv("TRACING_BACKEND", "none"),
+		},
+		CAS: CASConfig{
+			Compression:          getEnv("CAS_COMPRESSION", "none"),
+			CompressionThreshold: getEnvInt("CAS_COMPRESSION_THRESHOLD", 64*1024),
+			Backend:              getEnv("CAS_BACKEND", "redis"),
+			CacheTTL:             getEnvDuration("CAS_CACHE_TTL", time.Hour),
+			S3: S3Config{
+				Endpoint:        getEnv("CAS_S3_ENDPOINT", "https://s3.amazonaws.com"),
+				Region:          getEnv("CAS_S3_REGION", "us-east-1"),
+				Bucket:          getEnv("CAS_S3_BUCKET", ""),
+				Prefix:          getEnv("CAS_S3_PREFIX", "cas/"),
+				AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+				SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+				UsePathStyle:    getEnvBool("CAS_S3_PATH_STYLE", false),
+			},
+			SigningKey: getEnv("CAS_SIGNING_KEY", ""),
+		},
+		Readiness: ReadinessConfig{
+			Streams: getEnvSlice("READYZ_STREAMS", []string{
+				"wf.run.requests:run_executors",
+				"run.status.updates:status_updaters",
+				// Task streams are checked in every priority variant (see redis.PriorityStream)
+				"wf.tasks.agent:agent_workers",
+				"{wf.tasks.agent}.high:agent_workers",
+				"{wf.tasks.agent}.low:agent_workers",
+				"wf.tasks.http:http_workers",
+				"{wf.tasks.http}.high:http_workers",
+				"{wf.tasks.http}.low:http_workers",
+				"wf.tasks.hitl:hitl_request_workers",
+				"{wf.tasks.hitl}.high:hitl_request_workers",
+				"{wf.tasks.hitl}.low:hitl_request_workers",
+				"wf.tasks.function:runner_workers",
+				"{wf.tasks.function}.high:runner_workers",
+				"{wf.tasks.function}.low:runner_workers",
+			}),
+			RequiredGroups:      getEnvSlice("READYZ_REQUIRED_GROUPS", []string{"wf.run.requests:run_executors"}),
+			MaxBacklog:          getEnvInt("READYZ_MAX_BACKLOG", 1000),
+			ConsumerIdleTimeout: getEnvDuration("READYZ_CONSUMER_IDLE_TIMEOUT", 60*time.Second),
+		},
+		Compaction: CompactionConfig{
+			Enabled:        getEnvBool("AUTO_COMPACTION_ENABLED", false),
+			Interval:       getEnvDuration("AUTO_COMPACTION_INTERVAL", 5*time.Minute),
+			DepthThreshold: getEnvInt("AUTO_COMPACTION_DEPTH_THRESHOLD", 20),
+			DryRun:         getEnvBool("AUTO_COMPACTION_DRY_RUN", false),
+			LockTTL:        getEnvDuration("AUTO_COMPACTION_LOCK_TTL", 2*time.Minute),
+		},
+		CASGC: CASGCConfig{
+			Enabled:     getEnvBool("CAS_GC_ENABLED", false),
+			Interval:    getEnvDuration("CAS_GC_INTERVAL", time.Hour),
+			GracePeriod: getEnvDuration("CAS_GC_GRACE_PERIOD", 24*time.Hour),
+			DryRun:      getEnvBool("CAS_GC_DRY_RUN", false),
+		},
+		StreamTrim: StreamTrimConfig{
+			Enabled:  getEnvBool("STREAM_TRIM_ENABLED", false),
+			Interval: getEnvDuration("STREAM_TRIM_INTERVAL", time.Minute),
+			MaxLen:   int64(getEnvInt("STREAM_TRIM_MAXLEN", 100000)),
+			Streams:  getEnvSlice("STREAM_TRIM_STREAMS", []string{"wf.tasks.*", "{wf.tasks.*}.*", "wf.run.requests", "run.status.updates"}),
+		},
+		RateLimit: RateLimitConfig{
+			Algorithm:       getEnv("RATE_LIMIT_ALGORITHM", "sliding_window"),
+			WorkflowLimits:  getEnvLimits("RATE_LIMIT_WORKFLOWS"),
+			OrgLimits:       getEnvLimits("RATE_LIMIT_ORGS"),
+			NodeCostWeights: getEnvLimits("NODE_COST_WEIGHTS"),
+		},
+		Templates: TemplateConfig{
+			Strict: getEnvBool("STRICT_TEMPLATES", false),
+		},
+		Admin: AdminConfig{
+			Users: getEnvSlice("ADMIN_USERS", nil),
+		},
+		Limits: WorkflowLimitsConfig{
+			MaxNodes:           getEnvInt("WORKFLOW_MAX_NODES", 1000),
+			MaxEdges:           getEnvInt("WORKFLOW_MAX_EDGES", 5000),
+			MaxWorkflowBytes:   getEnvInt("WORKFLOW_MAX_BYTES", 4<<20),
+			MaxNodeConfigBytes: getEnvInt("WORKFLOW_MAX_NODE_CONFIG_BYTES", 64*1024),
+			MaxPatchOperations: getEnvInt("WORKFLOW_MAX_PATCH_OPERATIONS", 500),
+		},
+		RunState: RunStateConfig{
+			Retention:      getEnvDuration("RUN_STATE_RETENTION", time.Hour),
+			StallThreshold: getEnvDuration("RUN_STALL_THRESHOLD", 30*time.Minute),
+			MarkStalled:    getEnvBool("RUN_MARK_STALLED", false),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvSlice("CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods:   getEnvSlice("CORS_ALLOWED_METHODS", []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}),
+			AllowedHeaders:   getEnvSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "X-User-ID", "X-Request-ID", "Idempotency-Key"}),
+			ExposedHeaders:   getEnvSlice("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "Idempotent-Replayed"}),
+			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Consumer: ConsumerConfig{
+			Block:         getEnvDuration("CONSUMER_BLOCK", 5*time.Second),
+			BackoffBase:   getEnvDuration("CONSUMER_BACKOFF_BASE", time.Second),
+			BackoffMax:    getEnvDuration("CONSUMER_BACKOFF_MAX", 30*time.Second),
+			BackoffJitter: getEnvFloat("CONSUMER_BACKOFF_JITTER", 0.2),
+		},
+		Routing: RoutingConfig{
+			Streams: getEnvMap("NODE_TYPE_STREAMS"),
 		},
 		Features: FeatureFlags{
 			EnableKafka:            getEnvBool("ENABLE_KAFKA", false),
@@ -129,6 +387,26 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid port: %d", c.Service.Port)
 	}
 
+	if c.Service.Workers < 1 {
+		return fmt.Errorf("invalid worker count: %d (RUNNER_WORKERS must be >= 1)", c.Service.Workers)
+	}
+
+	if c.Service.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdown timeout must be > 0")
+	}
+
+	if c.Service.RequestTimeout < 0 {
+		return fmt.Errorf("request timeout must be >= 0 (0 disables it)")
+	}
+
+	if c.Service.ReadHeaderTimeout <= 0 {
+		return fmt.Errorf("read header timeout must be > 0")
+	}
+
+	if c.Service.MaxBodyBytes < 1 || c.Service.MaxWorkflowBodyBytes < 1 {
+		return fmt.Errorf("request body limits must be >= 1 byte")
+	}
+
 	if c.Database.Host == "" {
 		return fmt.Errorf("database host is required")
 	}
@@ -137,6 +415,105 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max_conns must be >= min_conns")
 	}
 
+	switch c.CAS.Compression {
+	case "none", "gzip", "zstd":
+	default:
+		return fmt.Errorf("invalid CAS compression: %s (expected none, gzip or zstd)", c.CAS.Compression)
+	}
+
+	if c.CAS.CompressionThreshold < 0 {
+		return fmt.Errorf("CAS compression threshold must be >= 0")
+	}
+
+	switch c.CAS.Backend {
+	case "redis":
+	case "s3", "tiered":
+		if c.CAS.S3.Bucket == "" {
+			return fmt.Errorf("CAS_S3_BUCKET is required for the %s CAS backend", c.CAS.Backend)
+		}
+		if c.CAS.Backend == "tiered" && c.CAS.CacheTTL <= 0 {
+			return fmt.Errorf("CAS cache TTL must be > 0")
+		}
+	default:
+		return fmt.Errorf("invalid CAS backend: %s (expected redis, s3 or tiered)", c.CAS.Backend)
+	}
+
+	if c.CAS.SigningKey != "" && len(c.CAS.SigningKey) < 32 {
+		return fmt.Errorf("CAS signing key must be at least 32 bytes")
+	}
+
+	if c.Compaction.Enabled {
+		if c.Compaction.Interval <= 0 {
+			return fmt.Errorf("auto compaction interval must be > 0")
+		}
+		if c.Compaction.DepthThreshold < 1 {
+			return fmt.Errorf("auto compaction depth threshold must be >= 1")
+		}
+	}
+
+	if c.CASGC.Enabled {
+		if c.CASGC.Interval <= 0 {
+			return fmt.Errorf("CAS GC interval must be > 0")
+		}
+		if c.CASGC.GracePeriod <= 0 {
+			return fmt.Errorf("CAS GC grace period must be > 0")
+		}
+	}
+
+	if c.StreamTrim.Enabled {
+		if c.StreamTrim.Interval <= 0 {
+			return fmt.Errorf("stream trim interval must be > 0")
+		}
+		if c.StreamTrim.MaxLen < 1 {
+			return fmt.Errorf("stream trim max length must be >= 1")
+		}
+	}
+
+	switch c.RateLimit.Algorithm {
+	case "sliding_window", "fixed_window":
+	default:
+		return fmt.Errorf("invalid rate limit algorithm: %s (expected sliding_window or fixed_window)", c.RateLimit.Algorithm)
+	}
+
+	for name, limit := range c.RateLimit.WorkflowLimits {
+		if limit < 1 {
+			return fmt.Errorf("invalid rate limit for workflow %s (RATE_LIMIT_WORKFLOWS limits must be >= 1)", name)
+		}
+	}
+	for name, limit := range c.RateLimit.OrgLimits {
+		if limit < 1 {
+			return fmt.Errorf("invalid rate limit for org %s (RATE_LIMIT_ORGS limits must be >= 1)", name)
+		}
+	}
+
+	if c.CORS.AllowCredentials {
+		for _, origin := range c.CORS.AllowedOrigins {
+			if origin == "*" {
+				return fmt.Errorf("CORS_ALLOW_CREDENTIALS cannot be used with a \"*\" origin (list the allowed origins)")
+			}
+		}
+	}
+
+	if c.Consumer.Block <= 0 {
+		return fmt.Errorf("consumer block must be > 0")
+	}
+	if c.Consumer.BackoffBase <= 0 || c.Consumer.BackoffMax < c.Consumer.BackoffBase {
+		return fmt.Errorf("consumer backoff must be > 0 (CONSUMER_BACKOFF_MAX must be >= CONSUMER_BACKOFF_BASE)")
+	}
+	if c.Consumer.BackoffJitter < 0 || c.Consumer.BackoffJitter > 1 {
+		return fmt.Errorf("invalid consumer backoff jitter: %g (CONSUMER_BACKOFF_JITTER must be between 0 and 1)", c.Consumer.BackoffJitter)
+	}
+
+	for nodeType, stream := range c.Routing.Streams {
+		if nodeType == "" || stream == "" {
+			return fmt.Errorf("invalid node type stream %q=%q (NODE_TYPE_STREAMS entries must be type=stream)", nodeType, stream)
+		}
+	}
+
+	if c.Limits.MaxNodes < 0 || c.Limits.MaxEdges < 0 || c.Limits.MaxWorkflowBytes < 0 || c.Limits.MaxNodeConfigBytes < 0 || c.Limits.MaxPatchOperations < 0 {
+		return fmt.Errorf("workflow limits must be >= 0 (0 disables a limit)")
+	}
+
 	return nil
 }
 
@@ -179,6 +556,15 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -192,7 +578,39 @@ func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		// Simple comma-separated parsing
 		// For production, use a proper CSV parser
-		return []string{value}
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvLimits parses a "name=limit,name=limit" list
+// Entries without a valid limit get 0, which Validate rejects.
+func getEnvLimits(key string) map[string]int64 {
+	limits := make(map[string]int64)
+	for _, item := range getEnvSlice(key, nil) {
+		name, value, _ := strings.Cut(item, "=")
+		limit, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			limit = 0
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits
+}
+
+// getEnvMap parses a "name=value,name=value" list
+// Entries without a value map to "", which Validate rejects where it matters.
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, item := range getEnvSlice(key, nil) {
+		name, value, _ := strings.Cut(item, "=")
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
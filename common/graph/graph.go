@@ -0,0 +1,163 @@
+package graph
+
+import (
+	"sort"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+)
+
+// Graph is a directed graph over node IDs
+// Used by the compiler and runtime for reachability and cycle analysis
+type Graph struct {
+	edges map[string][]string
+}
+
+// New creates an empty graph
+func New() *Graph {
+	return &Graph{
+		edges: make(map[string][]string),
+	}
+}
+
+// FromIR builds the forward-edge graph of a compiled workflow
+// Includes static dependents, branch targets, loop exit paths, map bodies, and
+// error handlers (on_error).
+// Loop back-edges (loop_back_to) are intentionally excluded so the result
+// describes forward control flow only.
+func FromIR(ir *sdk.IR) *Graph {
+	g := New()
+
+	for id, node := range ir.Nodes {
+		g.AddNode(id)
+
+		for _, to := range node.Dependents {
+			g.AddEdge(id, to)
+		}
+
+		if node.Branch != nil && node.Branch.Enabled {
+			for _, rule := range node.Branch.Rules {
+				for _, to := range rule.NextNodes {
+					g.AddEdge(id, to)
+				}
+			}
+			for _, to := range node.Branch.Default {
+				g.AddEdge(id, to)
+			}
+		}
+
+		if node.Loop != nil && node.Loop.Enabled {
+			for _, to := range node.Loop.BreakPath {
+				g.AddEdge(id, to)
+			}
+			for _, to := range node.Loop.TimeoutPath {
+				g.AddEdge(id, to)
+			}
+		}
+
+		if node.Map != nil && node.Map.Body != "" {
+			g.AddEdge(id, node.Map.Body)
+		}
+
+		for _, to := range node.OnError {
+			g.AddEdge(id, to)
+		}
+	}
+
+	return g
+}
+
+// AddNode adds a node with no edges (no-op if it exists)
+func (g *Graph) AddNode(id string) {
+	if _, exists := g.edges[id]; !exists {
+		g.edges[id] = nil
+	}
+}
+
+// AddEdge adds a directed edge, ignoring duplicates
+func (g *Graph) AddEdge(from, to string) {
+	g.AddNode(from)
+	g.AddNode(to)
+
+	for _, existing := range g.edges[from] {
+		if existing == to {
+			return
+		}
+	}
+	g.edges[from] = append(g.edges[from], to)
+}
+
+// Successors returns the direct successors of a node
+func (g *Graph) Successors(id string) []string {
+	return g.edges[id]
+}
+
+// Nodes returns all node IDs in sorted order
+func (g *Graph) Nodes() []string {
+	ids := make([]string, 0, len(g.edges))
+	for id := range g.edges {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Reverse returns a new graph with every edge flipped
+func (g *Graph) Reverse() *Graph {
+	reversed := New()
+	for from, tos := range g.edges {
+		reversed.AddNode(from)
+		for _, to := range tos {
+			reversed.AddEdge(to, from)
+		}
+	}
+	return reversed
+}
+
+// Reachable returns every node reachable from start, including start itself
+func (g *Graph) Reachable(start string) map[string]bool {
+	return g.ReachableUntil(start, nil)
+}
+
+// ReachableUntil returns nodes reachable from start without expanding past
+// nodes for which stop returns true. Stop nodes are included in the result,
+// but their successors are not explored (start itself is always expanded).
+func (g *Graph) ReachableUntil(start string, stop func(id string) bool) map[string]bool {
+	seen := map[string]bool{start: true}
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if current != start && stop != nil && stop(current) {
+			continue
+		}
+
+		for _, next := range g.edges[current] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return seen
+}
+
+// Between returns the nodes lying on some path from start to end (inclusive)
+// Paths are not followed beyond end, so end's own successors are excluded
+// unless they also lie on a start→end path.
+func (g *Graph) Between(start, end string) map[string]bool {
+	fromStart := g.ReachableUntil(start, func(id string) bool { return id == end })
+	toEnd := g.Reverse().Reachable(end)
+
+	between := make(map[string]bool)
+	for id := range fromStart {
+		if toEnd[id] {
+			between[id] = true
+		}
+	}
+	between[start] = true
+	between[end] = true
+	return between
+}
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultDependencyTimeout bounds each dependency probe
+const defaultDependencyTimeout = 2 * time.Second
+
+// DependencyProbe checks that a dependency is reachable
+type DependencyProbe func(ctx context.Context) error
+
+// RedisProbe pings a Redis client
+func RedisProbe(client redis.UniversalClient) DependencyProbe {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// DependencyStatus is the readiness of a single dependency
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Ready     bool   `json:"ready"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DependencyReport is the result of a dependency check
+type DependencyReport struct {
+	Status       string             `json:"status"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Ready reports whether every dependency is reachable
+func (r *DependencyReport) Ready() bool {
+	return r.Status == StatusReady
+}
+
+type dependency struct {
+	name  string
+	probe DependencyProbe
+}
+
+// DependencyChecker reports whether a service's dependencies (DB, Redis) are reachable
+// Unlike ReadinessChecker it says nothing about how well consumers keep up,
+// only whether the service could serve a request right now.
+type DependencyChecker struct {
+	dependencies []dependency
+	timeout      time.Duration
+}
+
+// NewDependencyChecker creates a checker with no dependencies
+func NewDependencyChecker() *DependencyChecker {
+	return &DependencyChecker{timeout: defaultDependencyTimeout}
+}
+
+// WithTimeout sets how long each probe may take before the dependency counts as down
+func (c *DependencyChecker) WithTimeout(timeout time.Duration) *DependencyChecker {
+	c.timeout = timeout
+	return c
+}
+
+// Add registers a dependency
+func (c *DependencyChecker) Add(name string, probe DependencyProbe) *DependencyChecker {
+	c.dependencies = append(c.dependencies, dependency{name: name, probe: probe})
+	return c
+}
+
+// Check probes every dependency concurrently
+func (c *DependencyChecker) Check(ctx context.Context) *DependencyReport {
+	report := &DependencyReport{
+		Status:       StatusReady,
+		Dependencies: make([]DependencyStatus, len(c.dependencies)),
+	}
+
+	var wg sync.WaitGroup
+	for i, dep := range c.dependencies {
+		wg.Add(1)
+		go func(i int, dep dependency) {
+			defer wg.Done()
+			report.Dependencies[i] = c.probe(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	for _, status := range report.Dependencies {
+		if !status.Ready {
+			report.Status = StatusNotReady
+		}
+	}
+
+	return report
+}
+
+// probe runs one dependency's probe under the checker's timeout
+func (c *DependencyChecker) probe(ctx context.Context, dep dependency) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dep.probe(ctx)
+	status := DependencyStatus{
+		Name:      dep.name,
+		Ready:     err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Handler serves the dependency report: 200 when ready, 503 otherwise
+func (c *DependencyChecker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Ready() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveReady calls a checker's handler and decodes its report
+func serveReady(t *testing.T, checker *DependencyChecker) (int, DependencyReport) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	checker.Handler()(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	var report DependencyReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, report
+}
+
+func TestDependencyChecker_RedisDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+
+	checker := NewDependencyChecker().
+		Add("database", func(ctx context.Context) error { return nil }).
+		Add("redis", RedisProbe(rdb))
+
+	code, report := serveReady(t, checker)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Ready())
+	require.Len(t, report.Dependencies, 2)
+	assert.True(t, report.Dependencies[1].Ready)
+
+	// Redis goes away
+	mr.Close()
+
+	code, report = serveReady(t, checker)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusNotReady, report.Status)
+	require.Len(t, report.Dependencies, 2)
+
+	assert.Equal(t, "database", report.Dependencies[0].Name)
+	assert.True(t, report.Dependencies[0].Ready)
+	assert.Empty(t, report.Dependencies[0].Error)
+
+	assert.Equal(t, "redis", report.Dependencies[1].Name)
+	assert.False(t, report.Dependencies[1].Ready)
+	assert.NotEmpty(t, report.Dependencies[1].Error)
+}
+
+func TestDependencyChecker_ProbeTimeout(t *testing.T) {
+	checker := NewDependencyChecker().
+		WithTimeout(50*time.Millisecond).
+		Add("database", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		Add("queue", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Dependencies[0].Error)
+	assert.Equal(t, "connection refused", report.Dependencies[1].Error)
+}
+
+func TestDependencyChecker_NoDependencies(t *testing.T) {
+	code, report := serveReady(t, NewDependencyChecker())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusReady, report.Status)
+	assert.Empty(t, report.Dependencies)
+}
@@ -0,0 +1,200 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// Readiness statuses
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// GroupStatus is the readiness of a single stream consumer group
+type GroupStatus struct {
+	Stream        string `json:"stream"`
+	Group         string `json:"group"`
+	Backlog       int64  `json:"backlog"` // Entries not yet delivered + delivered but unacked
+	MaxBacklog    int64  `json:"max_backlog"`
+	Consumers     int64  `json:"consumers"`
+	LiveConsumers int64  `json:"live_consumers"`
+	Required      bool   `json:"required"` // Must have at least one live consumer
+	Ready         bool   `json:"ready"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Report is the result of a readiness check
+type Report struct {
+	Status string        `json:"status"`
+	Groups []GroupStatus `json:"groups"`
+}
+
+// Ready reports whether every checked group is ready
+func (r *Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// groupCheck is a parsed "stream:group" entry
+type groupCheck struct {
+	stream   string
+	group    string
+	required bool
+}
+
+// ReadinessChecker reports whether stream consumers are keeping up
+// Backlog comes from XINFO GROUPS (lag + pending); a consumer is live if it
+// interacted with Redis (e.g. a blocking XREADGROUP) within the idle timeout.
+type ReadinessChecker struct {
+	redis       redis.UniversalClient
+	checks      []groupCheck
+	maxBacklog  int64
+	idleTimeout time.Duration
+}
+
+// NewReadinessChecker creates a readiness checker from config
+func NewReadinessChecker(redisClient redis.UniversalClient, cfg config.ReadinessConfig) (*ReadinessChecker, error) {
+	required := make(map[string]bool, len(cfg.RequiredGroups))
+	for _, pair := range cfg.RequiredGroups {
+		if _, _, err := parseGroupPair(pair); err != nil {
+			return nil, err
+		}
+		required[pair] = true
+	}
+
+	// Required groups are always checked, even if not listed in Streams
+	pairs := append([]string{}, cfg.Streams...)
+	for _, pair := range cfg.RequiredGroups {
+		if !contains(pairs, pair) {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	checks := make([]groupCheck, 0, len(pairs))
+	for _, pair := range pairs {
+		stream, group, err := parseGroupPair(pair)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, groupCheck{stream: stream, group: group, required: required[pair]})
+	}
+
+	return &ReadinessChecker{
+		redis:       redisClient,
+		checks:      checks,
+		maxBacklog:  int64(cfg.MaxBacklog),
+		idleTimeout: cfg.ConsumerIdleTimeout,
+	}, nil
+}
+
+// Check inspects every configured consumer group
+func (c *ReadinessChecker) Check(ctx context.Context) *Report {
+	report := &Report{
+		Status: StatusReady,
+		Groups: make([]GroupStatus, 0, len(c.checks)),
+	}
+
+	for _, check := range c.checks {
+		status := c.checkGroup(ctx, check)
+		if !status.Ready {
+			report.Status = StatusNotReady
+		}
+		report.Groups = append(report.Groups, status)
+	}
+
+	return report
+}
+
+// checkGroup computes backlog and consumer liveness for one group
+func (c *ReadinessChecker) checkGroup(ctx context.Context, check groupCheck) GroupStatus {
+	status := GroupStatus{
+		Stream:     check.stream,
+		Group:      check.group,
+		MaxBacklog: c.maxBacklog,
+		Required:   check.required,
+	}
+
+	// 1. Backlog from the group summary
+	groups, err := c.redis.XInfoGroups(ctx, check.stream).Result()
+	if err != nil && !isNoSuchKey(err) {
+		status.Reason = fmt.Sprintf("failed to inspect stream: %v", err)
+		return status
+	}
+
+	found := false
+	for _, g := range groups {
+		if g.Name == check.group {
+			found = true
+			status.Consumers = g.Consumers
+			status.Backlog = g.Pending
+			if g.Lag > 0 {
+				status.Backlog += g.Lag
+			}
+			break
+		}
+	}
+
+	if !found {
+		// Group not created yet: nothing is queued for it, but nobody consumes it either
+		status.Ready = !check.required
+		if check.required {
+			status.Reason = "consumer group does not exist"
+		}
+		return status
+	}
+
+	// 2. Live consumers (recently seen by Redis)
+	consumers, err := c.redis.XInfoConsumers(ctx, check.stream, check.group).Result()
+	if err != nil {
+		status.Reason = fmt.Sprintf("failed to inspect consumers: %v", err)
+		return status
+	}
+
+	for _, consumer := range consumers {
+		// Idle is -1 when the server doesn't track it; count those as live
+		if consumer.Idle <= c.idleTimeout {
+			status.LiveConsumers++
+		}
+	}
+
+	// 3. Verdict
+	switch {
+	case check.required && status.LiveConsumers == 0:
+		status.Reason = "no live consumers"
+	case c.maxBacklog > 0 && status.Backlog > c.maxBacklog:
+		status.Reason = fmt.Sprintf("backlog %d exceeds %d", status.Backlog, c.maxBacklog)
+	default:
+		status.Ready = true
+	}
+
+	return status
+}
+
+// parseGroupPair splits a "stream:group" entry
+// The group is taken after the last colon so stream names may contain colons.
+func parseGroupPair(pair string) (string, string, error) {
+	idx := strings.LastIndex(pair, ":")
+	if idx <= 0 || idx == len(pair)-1 {
+		return "", "", fmt.Errorf("invalid stream group %q (expected stream:group)", pair)
+	}
+	return pair[:idx], pair[idx+1:], nil
+}
+
+// isNoSuchKey reports whether err is Redis' missing-stream error
+func isNoSuchKey(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "no such key")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
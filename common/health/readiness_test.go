@@ -0,0 +1,91 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+func newTestChecker(t *testing.T, cfg config.ReadinessConfig) (*ReadinessChecker, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	checker, err := NewReadinessChecker(rdb, cfg)
+	require.NoError(t, err)
+	return checker, rdb
+}
+
+func TestReadiness_RequiredGroupWithoutLiveConsumers(t *testing.T) {
+	ctx := context.Background()
+	checker, rdb := newTestChecker(t, config.ReadinessConfig{
+		RequiredGroups:      []string{"wf.run.requests:run_executors"},
+		MaxBacklog:          100,
+		ConsumerIdleTimeout: time.Minute,
+	})
+
+	// 1. Group missing entirely
+	report := checker.Check(ctx)
+	assert.False(t, report.Ready())
+	require.Len(t, report.Groups, 1)
+	assert.Equal(t, "consumer group does not exist", report.Groups[0].Reason)
+
+	// 2. Group exists but has no members
+	require.NoError(t, rdb.XGroupCreateMkStream(ctx, "wf.run.requests", "run_executors", "0").Err())
+	report = checker.Check(ctx)
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusNotReady, report.Status)
+	assert.Equal(t, "no live consumers", report.Groups[0].Reason)
+	assert.Equal(t, int64(0), report.Groups[0].LiveConsumers)
+
+	// 3. A consumer reads from the group and becomes live
+	require.NoError(t, rdb.XAdd(ctx, &redis.XAddArgs{Stream: "wf.run.requests", Values: map[string]interface{}{"run_id": "r1"}}).Err())
+	_, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "run_executors",
+		Consumer: "executor-1",
+		Streams:  []string{"wf.run.requests", ">"},
+		Count:    1,
+		Block:    -1,
+	}).Result()
+	require.NoError(t, err)
+
+	report = checker.Check(ctx)
+	assert.True(t, report.Ready(), "report: %+v", report)
+	assert.Equal(t, int64(1), report.Groups[0].LiveConsumers)
+}
+
+func TestReadiness_BacklogThreshold(t *testing.T) {
+	ctx := context.Background()
+	checker, rdb := newTestChecker(t, config.ReadinessConfig{
+		Streams:             []string{"wf.tasks.http:http_workers"},
+		MaxBacklog:          3,
+		ConsumerIdleTimeout: time.Minute,
+	})
+
+	// Optional group that doesn't exist yet is not a failure
+	assert.True(t, checker.Check(ctx).Ready())
+
+	require.NoError(t, rdb.XGroupCreateMkStream(ctx, "wf.tasks.http", "http_workers", "0").Err())
+	for i := 0; i < 5; i++ {
+		require.NoError(t, rdb.XAdd(ctx, &redis.XAddArgs{Stream: "wf.tasks.http", Values: map[string]interface{}{"i": i}}).Err())
+	}
+
+	report := checker.Check(ctx)
+	assert.False(t, report.Ready())
+	assert.Equal(t, int64(5), report.Groups[0].Backlog)
+	assert.Contains(t, report.Groups[0].Reason, "backlog 5 exceeds 3")
+}
+
+func TestNewReadinessChecker_InvalidPair(t *testing.T) {
+	_, err := NewReadinessChecker(nil, config.ReadinessConfig{Streams: []string{"no-group"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Registry holds service-level metrics on a Prometheus registry
+// Metrics are registered once (usually as package variables) and updated by
+// passing label values positionally, in the order the labels were declared.
+// A metric without labels is exposed from registration; a labelled one once
+// its first series is updated.
+type Registry struct {
+	prom *prometheus.Registry
+
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// Default is the registry shared by all packages of a service
+// Besides the service metrics, it exposes the Go runtime and process collectors.
+var Default = newDefaultRegistry()
+
+// DefaultBuckets are latency buckets in seconds, from 1ms to 10s
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		prom:    prometheus.NewRegistry(),
+		metrics: make(map[string]metric),
+	}
+}
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.prom.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// Handler serves the Default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// Handler serves the registry's metrics over HTTP
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.prom, promhttp.HandlerOpts{})
+}
+
+// Counter registers (or returns the already registered) counter
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return r.register(name, func() metric {
+		c := &CounterVec{vec: prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels), labels: labels}
+		if len(labels) == 0 {
+			c.vec.WithLabelValues() // Exposed at zero before the first update
+		}
+		return c
+	}).(*CounterVec)
+}
+
+// Gauge registers (or returns the already registered) gauge
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return r.register(name, func() metric {
+		g := &GaugeVec{vec: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels), labels: labels}
+		if len(labels) == 0 {
+			g.vec.WithLabelValues()
+		}
+		return g
+	}).(*GaugeVec)
+}
+
+// Histogram registers (or returns the already registered) histogram
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return r.register(name, func() metric {
+		sorted := append([]float64(nil), buckets...)
+		sort.Float64s(sorted)
+		opts := prometheus.HistogramOpts{Name: name, Help: help, Buckets: sorted}
+		h := &HistogramVec{vec: prometheus.NewHistogramVec(opts, labels), labels: labels}
+		if len(labels) == 0 {
+			h.vec.WithLabelValues()
+		}
+		return h
+	}).(*HistogramVec)
+}
+
+// register returns the metric called name, creating it on first use
+// Registering one name as two different kinds is a programming error and panics.
+func (r *Registry) register(name string, create func() metric) metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := create()
+	if existing, ok := r.metrics[name]; ok {
+		if existing.kind() != created.kind() {
+			panic(fmt.Sprintf("metrics: %s already registered as a %s", name, existing.kind()))
+		}
+		return existing
+	}
+
+	r.prom.MustRegister(created.collector())
+	r.metrics[name] = created
+	return created
+}
+
+type metric interface {
+	kind() string
+	collector() prometheus.Collector
+}
+
+// lookup returns the current sample of the series with labelValues, or nil
+// before its first update
+// Reading through WithLabelValues would create the series, so the collector's
+// samples are searched instead.
+func lookup(c prometheus.Collector, labels, labelValues []string) *dto.Metric {
+	if len(labelValues) != len(labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(labels), len(labelValues)))
+	}
+	want := make(map[string]string, len(labels))
+	for i, label := range labels {
+		want[label] = labelValues[i]
+	}
+
+	samples := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(samples)
+		close(samples)
+	}()
+
+	var found *dto.Metric
+	for sample := range samples {
+		var m dto.Metric
+		if found != nil || sample.Write(&m) != nil {
+			continue // Drain the channel so Collect returns
+		}
+		if matches(&m, want) {
+			found = &m
+		}
+	}
+	return found
+}
+
+func matches(m *dto.Metric, want map[string]string) bool {
+	if len(m.GetLabel()) != len(want) {
+		return false
+	}
+	for _, pair := range m.GetLabel() {
+		if value, ok := want[pair.GetName()]; !ok || value != pair.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+// CounterVec is a monotonically increasing metric
+type CounterVec struct {
+	vec    *prometheus.CounterVec
+	labels []string
+}
+
+func (c *CounterVec) kind() string                    { return "counter" }
+func (c *CounterVec) collector() prometheus.Collector { return c.vec }
+
+// Inc adds one to the series
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (which must not be negative) to the series
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.vec.WithLabelValues(labelValues...).Add(v)
+}
+
+// Value returns the series' current value
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	if m := lookup(c.vec, c.labels, labelValues); m != nil {
+		return m.GetCounter().GetValue()
+	}
+	return 0
+}
+
+// GaugeVec is a metric that can go up and down
+type GaugeVec struct {
+	vec    *prometheus.GaugeVec
+	labels []string
+}
+
+func (g *GaugeVec) kind() string                    { return "gauge" }
+func (g *GaugeVec) collector() prometheus.Collector { return g.vec }
+
+// Set sets the series to v
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Set(v)
+}
+
+// Add adds v (possibly negative) to the series
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	g.vec.WithLabelValues(labelValues...).Add(v)
+}
+
+// Inc adds one to the series
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts one from the series
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns the series' current value
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	if m := lookup(g.vec, g.labels, labelValues); m != nil {
+		return m.GetGauge().GetValue()
+	}
+	return 0
+}
+
+// HistogramVec samples observations (usually latencies in seconds) into buckets
+type HistogramVec struct {
+	vec    *prometheus.HistogramVec
+	labels []string
+}
+
+func (h *HistogramVec) kind() string                    { return "histogram" }
+func (h *HistogramVec) collector() prometheus.Collector { return h.vec }
+
+// Observe records one observation
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.vec.WithLabelValues(labelValues...).Observe(v)
+}
+
+// ObserveSince records the seconds elapsed since start
+// Meant for defer, which evaluates time.Now() when the defer runs:
+//
+//	defer metrics.CASOperationDuration.ObserveSince(time.Now(), "get")
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns how many observations the series has
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	if m := lookup(h.vec, h.labels, labelValues); m != nil {
+		return m.GetHistogram().GetSampleCount()
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestRegistry_CounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	requests := r.Counter("requests_total", "Requests served.", "method", "path")
+	inflight := r.Gauge("inflight", "In-flight requests.")
+
+	requests.Inc("GET", "/runs")
+	requests.Add(2, "GET", "/runs")
+	requests.Inc("POST", `/say "hi"`+"\n")
+	requests.Add(-5, "GET", "/runs") // counters never go down
+	inflight.Inc()
+	inflight.Inc()
+	inflight.Dec()
+
+	assert.Equal(t, float64(3), requests.Value("GET", "/runs"))
+	assert.Equal(t, float64(0), requests.Value("DELETE", "/runs"))
+
+	// Families are exposed in name order
+	assert.Equal(t, `# HELP inflight In-flight requests.
+# TYPE inflight gauge
+inflight 1
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{method="GET",path="/runs"} 3
+requests_total{method="POST",path="/say \"hi\"\n"} 1
+`, scrape(t, r))
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	r := NewRegistry()
+	latency := r.Histogram("latency_seconds", "Latency.", []float64{0.5, 0.1, 1}, "op")
+
+	latency.Observe(0.05, "get")
+	latency.Observe(0.1, "get") // upper bounds are inclusive
+	latency.Observe(0.7, "get")
+	latency.Observe(3, "get")
+
+	assert.Equal(t, uint64(4), latency.Count("get"))
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 2
+latency_seconds_bucket{op="get",le="0.5"} 2
+latency_seconds_bucket{op="get",le="1"} 3
+latency_seconds_bucket{op="get",le="+Inf"} 4
+latency_seconds_sum{op="get"} 3.85
+latency_seconds_count{op="get"} 4
+`, scrape(t, r))
+}
+
+func TestRegistry_Registration(t *testing.T) {
+	r := NewRegistry()
+	first := r.Counter("events_total", "Events.", "type")
+
+	// Registering again returns the same metric
+	assert.Same(t, first, r.Counter("events_total", "Events.", "type"))
+
+	// A name can't change kind, and label counts must match
+	assert.Panics(t, func() { r.Gauge("events_total", "Events.") })
+	assert.Panics(t, func() { first.Inc() })
+
+	// The failed update didn't leave the metric locked
+	first.Inc("created")
+	assert.Equal(t, float64(1), first.Value("created"))
+}
+
+func TestDefaultRegistryExposesRuntimeMetrics(t *testing.T) {
+	body := scrape(t, Default)
+	assert.Contains(t, body, "# TYPE go_goroutines gauge")
+	assert.Contains(t, body, "# TYPE hitl_pending_approvals gauge")
+}
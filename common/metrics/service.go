@@ -0,0 +1,52 @@
+package metrics
+
+// Service-level metrics shared by the orchestrator and workers
+// Each process exposes its own values on /metrics; aggregate across instances
+// in Prometheus (e.g. sum by stream).
+var (
+	// RunsCreated counts runs accepted by the orchestrator, by rate limit tier
+	RunsCreated = Default.Counter("runs_created_total",
+		"Runs created, by workflow rate limit tier.", "tier")
+
+	// StreamMessagesConsumed counts messages read from streams by consumer groups
+	StreamMessagesConsumed = Default.Counter("stream_messages_consumed_total",
+		"Messages read from Redis streams by consumer groups.", "stream")
+
+	// StreamMessagesAcked counts acknowledged stream messages
+	StreamMessagesAcked = Default.Counter("stream_messages_acked_total",
+		"Stream messages acknowledged by consumer groups.", "stream")
+
+	// CoordinatorRoutingDuration measures routing a completed node to its dependents
+	CoordinatorRoutingDuration = Default.Histogram("coordinator_routing_duration_seconds",
+		"Time the coordinator takes to route a completed node to its next nodes.", DefaultBuckets)
+
+	// RateLimitRejections counts requests rejected by a rate limit
+	RateLimitRejections = Default.Counter("ratelimit_rejections_total",
+		"Requests rejected by rate limits, by limit scope (global, user, workflow, tier, org).", "scope")
+
+	// HITLPendingApprovals tracks approvals created minus approvals decided
+	// A request and its decision may be handled by different workers, so only
+	// the sum across instances is meaningful.
+	HITLPendingApprovals = Default.Gauge("hitl_pending_approvals",
+		"Approval requests created minus approvals decided by this process; sum across instances.")
+
+	// RunsStalled is the number of runs flagged as stalled by the last scan
+	RunsStalled = Default.Gauge("runs_stalled",
+		"Runs with tokens in flight whose counter hasn't changed within the stall threshold, as of the last scan.")
+
+	// RunStallsDetected counts runs newly flagged as stalled
+	RunStallsDetected = Default.Counter("run_stalls_detected_total",
+		"Runs flagged as stalled; a run that progresses and stalls again counts again.")
+
+	// CASOperationDuration measures CAS reads and writes
+	CASOperationDuration = Default.Histogram("cas_operation_duration_seconds",
+		"CAS operation latency, by operation (get, get_bulk, put).", DefaultBuckets, "operation")
+
+	// RedisCommands counts Redis operations made through the common client
+	RedisCommands = Default.Counter("redis_commands_total",
+		"Redis operations issued through the common client, by command.", "command")
+
+	// RedisCommandErrors counts failed Redis operations (not found is not an error)
+	RedisCommandErrors = Default.Counter("redis_command_errors_total",
+		"Redis operations through the common client that failed, by command.", "command")
+)
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Audit actions, named <target>.<verb>
+const (
+	AuditActionWorkflowCreate   = "workflow.create"
+	AuditActionWorkflowPatch    = "workflow.patch"
+	AuditActionWorkflowRollback = "workflow.rollback"
+	AuditActionWorkflowClone    = "workflow.clone"
+	AuditActionWorkflowTemplate = "workflow.from_template"
+	AuditActionTagDelete        = "tag.delete"
+	AuditActionTagUndo          = "tag.undo"
+	AuditActionTagRedo          = "tag.redo"
+	AuditActionRunCreate        = "run.create"
+	AuditActionRunPatch         = "run.patch"
+	AuditActionRunPause         = "run.pause"
+	AuditActionRunResume        = "run.resume"
+	AuditActionRunRetry         = "run.retry"
+	AuditActionRunCancelNode    = "run.cancel_node"
+	AuditActionUserPurge        = "user.purge"
+)
+
+// Audit target kinds
+const (
+	AuditTargetTag      = "tag"      // Target ID is owner/name
+	AuditTargetArtifact = "artifact" // Target ID is the artifact UUID
+	AuditTargetRun      = "run"      // Target ID is the run UUID
+	AuditTargetUser     = "user"     // Target ID is the username
+)
+
+// AuditEntry is one mutation in the audit log
+// Maps to: audit_log table (append-only)
+type AuditEntry struct {
+	ID     int64  `db:"id" json:"id"`
+	Actor  string `db:"actor" json:"actor"`
+	Action string `db:"action" json:"action"`
+
+	// What the action was done to
+	TargetKind string `db:"target_kind" json:"target_kind"`
+	TargetID   string `db:"target_id" json:"target_id"`
+
+	// The workflow tag involved, if any
+	TagOwner *string `db:"tag_owner" json:"tag_owner,omitempty"`
+	TagName  *string `db:"tag_name" json:"tag_name,omitempty"`
+
+	RequestID *string                `db:"request_id" json:"request_id,omitempty"`
+	Details   map[string]interface{} `db:"details" json:"details,omitempty"`
+	CreatedAt time.Time              `db:"created_at" json:"created_at"`
+}
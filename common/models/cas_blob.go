@@ -25,10 +25,24 @@ type CASBlob struct {
 	// Inline storage (NULL if stored externally)
 	Content []byte `db:"content" json:"content,omitempty"`
 
+	// Encoding of Content as stored ("identity" or "gzip")
+	// SizeBytes and CasID always describe the uncompressed content.
+	ContentEncoding string `db:"content_encoding" json:"content_encoding"`
+
+	// HMAC-SHA256 of CasID and the uncompressed content ("" if unsigned)
+	Signature string `db:"signature" json:"-"`
+
 	// External storage URL (S3, MinIO, etc.)
 	StorageURL *string `db:"storage_url" json:"storage_url,omitempty"`
 }
 
+// Content encodings for stored blobs
+const (
+	ContentEncodingIdentity = "identity"
+	ContentEncodingGzip     = "gzip"
+	ContentEncodingZstd     = "zstd"
+)
+
 // Media types for different artifact types
 const (
 	MediaTypeDAG         = "application/json;type=dag"
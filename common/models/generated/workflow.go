@@ -14,6 +14,10 @@ type Edge struct {
 	// Source node ID
 	From string `json:"from" yaml:"from" mapstructure:"from"`
 
+	// Traverse only when the source node fails, routing its failure to an error
+	// handler
+	OnError bool `json:"on_error,omitempty" yaml:"on_error,omitempty" mapstructure:"on_error,omitempty"`
+
 	// Target node ID
 	To string `json:"to" yaml:"to" mapstructure:"to"`
 }
@@ -64,8 +68,11 @@ const NodeTypeFilter NodeType = "filter"
 const NodeTypeFunction NodeType = "function"
 const NodeTypeHttp NodeType = "http"
 const NodeTypeLoop NodeType = "loop"
+const NodeTypeMap NodeType = "map"
 const NodeTypeParallel NodeType = "parallel"
+const NodeTypeSubworkflow NodeType = "subworkflow"
 const NodeTypeTransform NodeType = "transform"
+const NodeTypeWebhook NodeType = "webhook"
 
 var enumValues_NodeType = []interface{}{
 	"function",
@@ -76,6 +83,9 @@ var enumValues_NodeType = []interface{}{
 	"transform",
 	"aggregate",
 	"filter",
+	"webhook",
+	"subworkflow",
+	"map",
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -16,6 +16,8 @@ const (
 	StatusCompleted           RunStatus = "COMPLETED"
 	StatusFailed              RunStatus = "FAILED"
 	StatusCancelled           RunStatus = "CANCELLED"
+	StatusPaused              RunStatus = "PAUSED"
+	StatusStalled             RunStatus = "STALLED"
 )
 
 // BaseKind represents the type of base reference
@@ -6,6 +6,13 @@ import (
 	"github.com/google/uuid"
 )
 
+// Tag move actions
+const (
+	TagMoveActionMove = "move"
+	TagMoveActionUndo = "undo"
+	TagMoveActionRedo = "redo"
+)
+
 // TagMove represents an audit log entry for tag movements (undo/redo history)
 // Maps to: tag_move table
 type TagMove struct {
@@ -21,14 +28,18 @@ type TagMove struct {
 	// Previous target
 	FromKind *ArtifactKind `db:"from_kind" json:"from_kind,omitempty"`
 	FromID   *uuid.UUID    `db:"from_id" json:"from_id,omitempty"`
+	FromHash *string       `db:"from_hash" json:"from_hash,omitempty"`
 
 	// New target
 	ToKind ArtifactKind `db:"to_kind" json:"to_kind"`
 	ToID   uuid.UUID    `db:"to_id" json:"to_id"`
 
-	// Expected hash (for CAS validation)
+	// Expected hash (for CAS validation); the new target's hash
 	ExpectedHash *string `db:"expected_hash" json:"expected_hash,omitempty"`
 
+	// move, undo or redo (see TagMoveAction*)
+	Action string `db:"action" json:"action"`
+
 	// Audit fields
 	MovedBy *string   `db:"moved_by" json:"moved_by,omitempty"`
 	MovedAt time.Time `db:"moved_at" json:"moved_at"`
@@ -10,8 +10,9 @@ import (
 // This includes base DAG + optional patch chain
 type WorkflowComponents struct {
 	// Tag information
-	Username string `json:"username"`
-	TagName  string `json:"tag_name"`
+	Username   string `json:"username"`
+	TagName    string `json:"tag_name"`
+	TagVersion int64  `json:"tag_version"` // Tag version read; patches pass it as expected_version
 
 	// Artifact metadata
 	ArtifactID uuid.UUID    `json:"artifact_id"`
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+)
+
+// Dimension names what a composite check counts a request against
+type Dimension string
+
+const (
+	DimensionUser     Dimension = "user"     // Per user and workflow tier
+	DimensionWorkflow Dimension = "workflow" // Per workflow tag
+	DimensionOrg      Dimension = "org"      // Per org, across all its users
+)
+
+// WildcardLimit keys the limit for tags or orgs without a limit of their own
+const WildcardLimit = "*"
+
+// compositeWindowSeconds matches the window of the tier limits
+const compositeWindowSeconds = 60
+
+// CompositeLimits holds the workflow tag and org limits, in runs per minute
+// Tags and orgs without an entry fall back to the WildcardLimit entry, if any,
+// and are otherwise not limited.
+type CompositeLimits struct {
+	Workflows map[string]int64
+	Orgs      map[string]int64
+}
+
+// limitFor returns the limit configured for name, or 0 if there is none
+func limitFor(limits map[string]int64, name string) int64 {
+	if limit, ok := limits[name]; ok {
+		return limit
+	}
+	return limits[WildcardLimit]
+}
+
+// CompositeRequest identifies everything a run is counted against
+type CompositeRequest struct {
+	Username    string
+	Tier        WorkflowTier
+	WorkflowTag string
+	Org         string // Optional; no org limit applies without one
+}
+
+// WithCompositeLimits sets the workflow tag and org limits
+func (r *RateLimiter) WithCompositeLimits(limits CompositeLimits) *RateLimiter {
+	r.composite = limits
+	return r
+}
+
+// compositeCheck is one limit of a composite check
+type compositeCheck struct {
+	dimension Dimension
+	scope     string // Metrics label, as for the single checks
+	target    string
+	key       string
+	limit     int64
+}
+
+// CheckCompositeLimit checks the user's tier limit together with any workflow tag
+// and org limit
+// A request is counted against all of them or none: when one rejects it, the
+// requests already counted against the others are given back. Returns the first
+// rejecting limit's result, or the user tier's if every limit allows the request.
+func (r *RateLimiter) CheckCompositeLimit(ctx context.Context, req CompositeRequest) (*RateLimitResult, error) {
+	checks := []compositeCheck{{
+		dimension: DimensionUser,
+		scope:     "tier",
+		target:    req.Username,
+		key:       fmt.Sprintf("rate_limit:user:%s:tier:%s", req.Username, req.Tier),
+		limit:     GetLimitForTier(req.Tier),
+	}}
+	if limit := limitFor(r.composite.Workflows, req.WorkflowTag); limit > 0 && req.WorkflowTag != "" {
+		checks = append(checks, compositeCheck{
+			dimension: DimensionWorkflow,
+			scope:     "workflow",
+			target:    req.WorkflowTag,
+			key:       fmt.Sprintf("rate_limit:workflow:%s:%s", req.Username, req.WorkflowTag),
+			limit:     limit,
+		})
+	}
+	if limit := limitFor(r.composite.Orgs, req.Org); limit > 0 && req.Org != "" {
+		checks = append(checks, compositeCheck{
+			dimension: DimensionOrg,
+			scope:     "org",
+			target:    req.Org,
+			key:       fmt.Sprintf("rate_limit:org:%s", req.Org),
+			limit:     limit,
+		})
+	}
+
+	type counted struct {
+		key    string
+		member string
+	}
+	var taken []counted
+	giveBack := func() {
+		for _, c := range taken {
+			if err := r.release(ctx, c.key, c.member); err != nil {
+				r.logger.Warn("failed to give back rate limit count", "key", c.key, "error", err)
+			}
+		}
+	}
+
+	var userResult *RateLimitResult
+	for _, check := range checks {
+		result, member, err := r.consume(ctx, check.scope, check.key, check.limit, compositeWindowSeconds)
+		if err != nil {
+			giveBack()
+			return nil, err
+		}
+		result.Dimension = check.dimension
+		result.Target = check.target
+
+		if !result.Allowed {
+			giveBack()
+			return result, nil
+		}
+		taken = append(taken, counted{key: check.key, member: member})
+		if userResult == nil {
+			userResult = result
+		}
+	}
+
+	return userResult, nil
+}
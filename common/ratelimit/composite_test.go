@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompositeLimit_WorkflowLimitDeniesWhatUserLimitAllows(t *testing.T) {
+	for _, algorithm := range []Algorithm{AlgorithmSlidingWindow, AlgorithmFixedWindow} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter, _ := testLimiter(t, algorithm)
+			limiter.WithCompositeLimits(CompositeLimits{
+				Workflows: map[string]int64{"expensive": 2},
+			})
+			ctx := context.Background()
+			req := CompositeRequest{Username: "alice", Tier: TierSimple, WorkflowTag: "expensive"}
+
+			for i := 0; i < 2; i++ {
+				result, err := limiter.CheckCompositeLimit(ctx, req)
+				require.NoError(t, err)
+				require.True(t, result.Allowed)
+				assert.Equal(t, DimensionUser, result.Dimension)
+			}
+
+			// The simple tier allows 100 runs a minute, the tag only 2
+			result, err := limiter.CheckCompositeLimit(ctx, req)
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Equal(t, DimensionWorkflow, result.Dimension)
+			assert.Equal(t, "expensive", result.Target)
+			assert.Equal(t, int64(2), result.Limit)
+			assert.Positive(t, result.RetryAfterSeconds)
+
+			// The rejected run was given back to the user's tier
+			count, err := limiter.GetCurrentCount(ctx, "rate_limit:user:alice:tier:simple")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), count)
+
+			// Other tags are not limited
+			result, err = limiter.CheckCompositeLimit(ctx, CompositeRequest{Username: "alice", Tier: TierSimple, WorkflowTag: "cheap"})
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+		})
+	}
+}
+
+func TestCompositeLimit_OrgLimitSpansUsers(t *testing.T) {
+	limiter, _ := testLimiter(t, AlgorithmSlidingWindow)
+	limiter.WithCompositeLimits(CompositeLimits{
+		Orgs: map[string]int64{"acme": 3},
+	})
+	ctx := context.Background()
+
+	for _, username := range []string{"alice", "alice", "bob"} {
+		result, err := limiter.CheckCompositeLimit(ctx, CompositeRequest{Username: username, Tier: TierSimple, WorkflowTag: "etl", Org: "acme"})
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := limiter.CheckCompositeLimit(ctx, CompositeRequest{Username: "bob", Tier: TierSimple, WorkflowTag: "etl", Org: "acme"})
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, DimensionOrg, result.Dimension)
+	assert.Equal(t, "acme", result.Target)
+
+	// Runs without an org, or of another org, are not counted against acme
+	result, err = limiter.CheckCompositeLimit(ctx, CompositeRequest{Username: "bob", Tier: TierSimple, WorkflowTag: "etl"})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = limiter.CheckCompositeLimit(ctx, CompositeRequest{Username: "bob", Tier: TierSimple, WorkflowTag: "etl", Org: "globex"})
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestCompositeLimit_UserTierStillApplies(t *testing.T) {
+	limiter, _ := testLimiter(t, AlgorithmSlidingWindow)
+	limiter.WithCompositeLimits(CompositeLimits{
+		Workflows: map[string]int64{WildcardLimit: 50},
+	})
+	ctx := context.Background()
+	req := CompositeRequest{Username: "alice", Tier: TierHeavy, WorkflowTag: "agents"}
+
+	for i := int64(0); i < GetLimitForTier(TierHeavy); i++ {
+		result, err := limiter.CheckCompositeLimit(ctx, req)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := limiter.CheckCompositeLimit(ctx, req)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, DimensionUser, result.Dimension)
+	assert.Equal(t, "alice", result.Target)
+
+	// The wildcard tag limit only counted the allowed runs
+	count, err := limiter.GetCurrentCount(ctx, "rate_limit:workflow:alice:agents")
+	require.NoError(t, err)
+	assert.Equal(t, GetLimitForTier(TierHeavy), count)
+}
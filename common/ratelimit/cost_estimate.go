@@ -0,0 +1,83 @@
+package ratelimit
+
+// DefaultNodeCostWeights are the estimated cost units of one execution of each node type
+// Agent nodes (LLM calls) dominate; other node types are cheap compute.
+var DefaultNodeCostWeights = map[string]int64{
+	"agent":    100,
+	"http":     5,
+	"webhook":  5,
+	"hitl":     1,
+	"function": 1,
+}
+
+// DefaultNodeCostWeight is the cost of a node type without a weight
+const DefaultNodeCostWeight int64 = 1
+
+// NodeTypeCost is the estimated cost of a workflow's nodes of one type
+type NodeTypeCost struct {
+	Count  int   `json:"count"`
+	Weight int64 `json:"weight"` // Cost units per execution
+	Cost   int64 `json:"cost"`
+}
+
+// CostEstimate is the estimated cost of one run of a workflow, in cost units
+// Every node is counted as executing once: loops and skipped branches are not modeled.
+type CostEstimate struct {
+	TotalCost int64                    `json:"total_cost"`
+	ByType    map[string]*NodeTypeCost `json:"by_type"`
+}
+
+// EstimateCost sums the weights of a workflow's nodes
+// weights override DefaultNodeCostWeights per node type.
+func EstimateCost(workflow map[string]interface{}, weights map[string]int64) CostEstimate {
+	estimate := CostEstimate{ByType: make(map[string]*NodeTypeCost)}
+
+	for _, nodeType := range nodeTypes(workflow) {
+		cost, ok := estimate.ByType[nodeType]
+		if !ok {
+			cost = &NodeTypeCost{Weight: nodeCostWeight(nodeType, weights)}
+			estimate.ByType[nodeType] = cost
+		}
+		cost.Count++
+		cost.Cost += cost.Weight
+		estimate.TotalCost += cost.Weight
+	}
+
+	return estimate
+}
+
+// nodeCostWeight returns the weight of a node type
+func nodeCostWeight(nodeType string, weights map[string]int64) int64 {
+	if weight, ok := weights[nodeType]; ok {
+		return weight
+	}
+	if weight, ok := DefaultNodeCostWeights[nodeType]; ok {
+		return weight
+	}
+	return DefaultNodeCostWeight
+}
+
+// nodeTypes returns the type of each of a workflow's nodes
+// Handles both the schema (nodes array) and IR (nodes map) formats.
+func nodeTypes(workflow map[string]interface{}) []string {
+	var nodes []interface{}
+	switch v := workflow["nodes"].(type) {
+	case []interface{}:
+		nodes = v
+	case map[string]interface{}:
+		for _, node := range v {
+			nodes = append(nodes, node)
+		}
+	}
+
+	types := make([]string, 0, len(nodes))
+	for _, nodeInterface := range nodes {
+		node, ok := nodeInterface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		nodeType, _ := node["type"].(string)
+		types = append(types, nodeType)
+	}
+	return types
+}
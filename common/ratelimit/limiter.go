@@ -4,13 +4,35 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
 //go:embed rate_limit.lua
 var rateLimitScript string
 
+//go:embed sliding_window.lua
+var slidingWindowScript string
+
+// Algorithm selects how requests are counted against a limit
+type Algorithm string
+
+const (
+	// AlgorithmSlidingWindow counts requests in the window ending now
+	AlgorithmSlidingWindow Algorithm = "sliding_window"
+
+	// AlgorithmFixedWindow counts requests per window starting at the first one
+	// Up to twice the limit can pass around a window boundary.
+	AlgorithmFixedWindow Algorithm = "fixed_window"
+)
+
+// slidingKeySuffix keeps sliding window logs apart from fixed window counters
+// The two use different Redis types, so switching algorithms must not reuse keys.
+const slidingKeySuffix = ":log"
+
 // Logger interface for logging
 type Logger interface {
 	Info(msg string, keysAndValues ...interface{})
@@ -21,44 +43,71 @@ type Logger interface {
 
 // RateLimitResult contains the result of a rate limit check
 type RateLimitResult struct {
-	Allowed           bool  // Whether the request is allowed
-	CurrentCount      int64 // Current count in the window
-	Limit             int64 // The limit that was checked
-	RetryAfterSeconds int64 // Seconds until the limit resets (0 if allowed)
+	Allowed           bool      // Whether the request is allowed
+	CurrentCount      int64     // Current count in the window
+	Limit             int64     // The limit that was checked
+	RetryAfterSeconds int64     // Seconds until the limit resets (0 if allowed)
+	Dimension         Dimension // Limit that decided a composite check (see CheckCompositeLimit)
+	Target            string    // Tag or org of a workflow or org dimension
 }
 
 // RateLimiter provides workflow-aware rate limiting using Redis + Lua
 type RateLimiter struct {
-	redis  *redis.Client
-	script *redis.Script
-	logger Logger
+	redis         redis.UniversalClient
+	script        *redis.Script
+	slidingScript *redis.Script
+	algorithm     Algorithm
+	composite     CompositeLimits
+	now           func() time.Time
+	logger        Logger
 }
 
-// NewRateLimiter creates a new rate limiter with embedded Lua script
-func NewRateLimiter(redisClient *redis.Client, logger Logger) *RateLimiter {
+// NewRateLimiter creates a new rate limiter with embedded Lua scripts
+// Limits are enforced with a sliding window unless WithAlgorithm says otherwise.
+func NewRateLimiter(redisClient redis.UniversalClient, logger Logger) *RateLimiter {
 	return &RateLimiter{
-		redis:  redisClient,
-		script: redis.NewScript(rateLimitScript),
-		logger: logger,
+		redis:         redisClient,
+		script:        redis.NewScript(rateLimitScript),
+		slidingScript: redis.NewScript(slidingWindowScript),
+		algorithm:     AlgorithmSlidingWindow,
+		now:           time.Now,
+		logger:        logger,
 	}
 }
 
+// WithAlgorithm sets how requests are counted
+// Unknown algorithms fall back to the sliding window.
+func (r *RateLimiter) WithAlgorithm(algorithm Algorithm) *RateLimiter {
+	switch algorithm {
+	case AlgorithmFixedWindow:
+		r.algorithm = algorithm
+	default:
+		r.algorithm = AlgorithmSlidingWindow
+	}
+	return r
+}
+
+// Algorithm returns how requests are counted
+func (r *RateLimiter) Algorithm() Algorithm {
+	return r.algorithm
+}
+
 // CheckGlobalLimit checks the global service-wide rate limit
 func (r *RateLimiter) CheckGlobalLimit(ctx context.Context, limit int64) (*RateLimitResult, error) {
 	key := "rate_limit:global"
-	return r.checkLimit(ctx, key, limit, 60) // 1 minute window
+	return r.checkLimit(ctx, "global", key, limit, 60) // 1 minute window
 }
 
 // CheckUserLimit checks rate limit for a specific user
 func (r *RateLimiter) CheckUserLimit(ctx context.Context, username string, limit int64, windowSec int) (*RateLimitResult, error) {
 	key := fmt.Sprintf("rate_limit:user:%s", username)
-	return r.checkLimit(ctx, key, limit, windowSec)
+	return r.checkLimit(ctx, "user", key, limit, windowSec)
 }
 
 // CheckWorkflowLimit checks rate limit for a specific workflow
 func (r *RateLimiter) CheckWorkflowLimit(ctx context.Context, username, workflowTag string, limit int64, windowSec int) (*RateLimitResult, error) {
 	key := fmt.Sprintf("rate_limit:workflow:%s:%s", username, workflowTag)
-	return r.checkLimit(ctx, key, limit, windowSec)
+	return r.checkLimit(ctx, "workflow", key, limit, windowSec)
 }
 
 // CheckTieredLimit checks rate limit based on workflow tier
@@ -66,22 +115,40 @@ func (r *RateLimiter) CheckWorkflowLimit(ctx context.Context, username, workflow
 func (r *RateLimiter) CheckTieredLimit(ctx context.Context, username string, tier WorkflowTier) (*RateLimitResult, error) {
 	key := fmt.Sprintf("rate_limit:user:%s:tier:%s", username, tier)
 	limit := GetLimitForTier(tier)
-	return r.checkLimit(ctx, key, limit, 60) // 1 minute window
+	return r.checkLimit(ctx, "tier", key, limit, 60) // 1 minute window
 }
 
 // checkLimit executes the rate limit Lua script
-func (r *RateLimiter) checkLimit(ctx context.Context, key string, limit int64, windowSec int) (*RateLimitResult, error) {
+// scope labels rejections in metrics (global, user, workflow, tier, org)
+func (r *RateLimiter) checkLimit(ctx context.Context, scope, key string, limit int64, windowSec int) (*RateLimitResult, error) {
+	result, _, err := r.consume(ctx, scope, key, limit, windowSec)
+	return result, err
+}
+
+// consume counts one request against a limit
+// Returns the sliding window log member recorded for the request, which release
+// needs to give the request back.
+func (r *RateLimiter) consume(ctx context.Context, scope, key string, limit int64, windowSec int) (*RateLimitResult, string, error) {
 	// Run Lua script atomically
-	result, err := r.script.Run(ctx, r.redis, []string{key}, limit, windowSec).Result()
+	var result interface{}
+	var member string
+	var err error
+	if r.algorithm == AlgorithmFixedWindow {
+		result, err = r.script.Run(ctx, r.redis, []string{key}, limit, windowSec).Result()
+	} else {
+		member = uuid.NewString()
+		result, err = r.slidingScript.Run(ctx, r.redis, []string{key + slidingKeySuffix},
+			limit, windowSec, r.now().UnixMilli(), member).Result()
+	}
 	if err != nil {
 		r.logger.Error("rate limit check failed", "key", key, "error", err)
-		return nil, fmt.Errorf("rate limit check failed: %w", err)
+		return nil, "", fmt.Errorf("rate limit check failed: %w", err)
 	}
 
 	// Parse result array: {allowed, current_count, limit, retry_after}
 	resultArray, ok := result.([]interface{})
 	if !ok || len(resultArray) != 4 {
-		return nil, fmt.Errorf("unexpected script result format")
+		return nil, "", fmt.Errorf("unexpected script result format")
 	}
 
 	allowed := resultArray[0].(int64) == 1
@@ -97,6 +164,7 @@ func (r *RateLimiter) checkLimit(ctx context.Context, key string, limit int64, w
 	}
 
 	if !allowed {
+		metrics.RateLimitRejections.Inc(scope)
 		r.logger.Warn("rate limit exceeded",
 			"key", key,
 			"current", currentCount,
@@ -109,11 +177,33 @@ func (r *RateLimiter) checkLimit(ctx context.Context, key string, limit int64, w
 			"limit", limit)
 	}
 
-	return rateLimitResult, nil
+	return rateLimitResult, member, nil
+}
+
+// releaseFixedScript gives back one request of a fixed window counter
+// A counter that expired meanwhile is left alone rather than recreated without TTL.
+var releaseFixedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+    return redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+// release gives back a request consume counted
+func (r *RateLimiter) release(ctx context.Context, key, member string) error {
+	if r.algorithm == AlgorithmFixedWindow {
+		return releaseFixedScript.Run(ctx, r.redis, []string{key}).Err()
+	}
+	return r.redis.ZRem(ctx, key+slidingKeySuffix, member).Err()
 }
 
 // GetCurrentCount returns current count without incrementing (for monitoring)
+// With the sliding window this may include requests that already left the
+// window but were not pruned by a check yet.
 func (r *RateLimiter) GetCurrentCount(ctx context.Context, key string) (int64, error) {
+	if r.algorithm != AlgorithmFixedWindow {
+		return r.redis.ZCard(ctx, key+slidingKeySuffix).Result()
+	}
 	count, err := r.redis.Get(ctx, key).Int64()
 	if err == redis.Nil {
 		return 0, nil // Key doesn't exist = no requests yet
@@ -123,5 +213,11 @@ func (r *RateLimiter) GetCurrentCount(ctx context.Context, key string) (int64, e
 
 // ResetLimit clears a rate limit counter (for testing/admin)
 func (r *RateLimiter) ResetLimit(ctx context.Context, key string) error {
-	return r.redis.Del(ctx, key).Err()
+	// Deleted one at a time: the two keys may live in different cluster slots
+	for _, k := range []string{key, key + slidingKeySuffix} {
+		if err := r.redis.Del(ctx, k).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (noopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+// testLimiter returns a limiter on miniredis and a func moving both clocks forward
+func testLimiter(t *testing.T, algorithm Algorithm) (*RateLimiter, func(time.Duration)) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(rdb, noopLogger{}).WithAlgorithm(algorithm)
+	limiter.now = func() time.Time { return now }
+
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		mr.FastForward(d)
+	}
+	return limiter, advance
+}
+
+// sendAcrossEdge sends 1 request, 9 more at 59s and 10 more at 61s
+// A fixed window opened by the first request resets in between.
+func sendAcrossEdge(t *testing.T, limiter *RateLimiter, advance func(time.Duration)) (allowed int) {
+	ctx := context.Background()
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			result, err := limiter.CheckUserLimit(ctx, "alice", 10, 60)
+			require.NoError(t, err)
+			if result.Allowed {
+				allowed++
+			}
+		}
+	}
+
+	send(1)
+	advance(59 * time.Second)
+	send(9)
+	advance(2 * time.Second)
+	send(10)
+	return allowed
+}
+
+func TestSlidingWindow_NoBoundaryBurst(t *testing.T) {
+	limiter, advance := testLimiter(t, AlgorithmSlidingWindow)
+
+	// Only the first request has left the window at 61s, so one slot is free
+	assert.Equal(t, 11, sendAcrossEdge(t, limiter, advance))
+}
+
+func TestFixedWindow_AllowsBoundaryBurst(t *testing.T) {
+	limiter, advance := testLimiter(t, AlgorithmFixedWindow)
+
+	// The counter resets at 60s: 19 requests pass within two seconds
+	assert.Equal(t, 20, sendAcrossEdge(t, limiter, advance))
+}
+
+func TestSlidingWindow_TwentyAtEdgeOnlyTenPass(t *testing.T) {
+	limiter, advance := testLimiter(t, AlgorithmSlidingWindow)
+	ctx := context.Background()
+
+	var allowed int
+	for i := 0; i < 10; i++ {
+		result, err := limiter.CheckUserLimit(ctx, "alice", 10, 60)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(i+1), result.CurrentCount)
+		allowed++
+	}
+
+	// Ten more right after a minute boundary are all rejected
+	advance(2 * time.Second)
+	for i := 0; i < 10; i++ {
+		result, err := limiter.CheckUserLimit(ctx, "alice", 10, 60)
+		require.NoError(t, err)
+		if result.Allowed {
+			allowed++
+			continue
+		}
+		assert.Equal(t, int64(10), result.Limit)
+		assert.Equal(t, int64(10), result.CurrentCount)
+		assert.Equal(t, int64(58), result.RetryAfterSeconds)
+	}
+	assert.Equal(t, 10, allowed)
+
+	// Once the first batch leaves the window requests pass again
+	advance(58 * time.Second)
+	result, err := limiter.CheckUserLimit(ctx, "alice", 10, 60)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(1), result.CurrentCount)
+}
+
+func TestSlidingWindow_TiersAreSeparate(t *testing.T) {
+	limiter, _ := testLimiter(t, AlgorithmSlidingWindow)
+	ctx := context.Background()
+
+	heavy := GetLimitForTier(TierHeavy)
+	for i := int64(0); i < heavy; i++ {
+		result, err := limiter.CheckTieredLimit(ctx, "alice", TierHeavy)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := limiter.CheckTieredLimit(ctx, "alice", TierHeavy)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, heavy, result.Limit)
+	assert.Equal(t, int64(60), result.RetryAfterSeconds)
+
+	// Heavy workflows don't block simple ones or other users
+	result, err = limiter.CheckTieredLimit(ctx, "alice", TierSimple)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.CheckTieredLimit(ctx, "bob", TierHeavy)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_ResetLimit(t *testing.T) {
+	for _, algorithm := range []Algorithm{AlgorithmSlidingWindow, AlgorithmFixedWindow} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter, _ := testLimiter(t, algorithm)
+			ctx := context.Background()
+
+			for i := 0; i < 3; i++ {
+				_, err := limiter.CheckUserLimit(ctx, "alice", 10, 60)
+				require.NoError(t, err)
+			}
+			count, err := limiter.GetCurrentCount(ctx, "rate_limit:user:alice")
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), count)
+
+			require.NoError(t, limiter.ResetLimit(ctx, "rate_limit:user:alice"))
+			count, err = limiter.GetCurrentCount(ctx, "rate_limit:user:alice")
+			require.NoError(t, err)
+			assert.Zero(t, count)
+		})
+	}
+}
+
+func TestWithAlgorithm_UnknownFallsBackToSliding(t *testing.T) {
+	limiter := NewRateLimiter(nil, noopLogger{})
+	assert.Equal(t, AlgorithmSlidingWindow, limiter.Algorithm())
+	assert.Equal(t, AlgorithmFixedWindow, limiter.WithAlgorithm(AlgorithmFixedWindow).Algorithm())
+	assert.Equal(t, AlgorithmSlidingWindow, limiter.WithAlgorithm("token_bucket").Algorithm())
+}
@@ -1,4 +1,4 @@
--- Atomic rate limiting with sliding window
+-- Atomic rate limiting with a fixed window counter
 --
 -- KEYS[1]: Redis key for rate limit counter
 -- ARGV[1]: Limit (max requests allowed)
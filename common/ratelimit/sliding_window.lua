@@ -0,0 +1,45 @@
+-- Atomic rate limiting with a sliding window log
+--
+-- Every allowed request is recorded in a sorted set scored by its timestamp, so
+-- the limit holds for any window-long span, not just per calendar window.
+-- Rejected requests are not recorded.
+--
+-- KEYS[1]: Redis key for the request log (sorted set)
+-- ARGV[1]: Limit (max requests allowed)
+-- ARGV[2]: Window in seconds
+-- ARGV[3]: Current time in milliseconds
+-- ARGV[4]: Unique member for this request
+--
+-- Returns: {allowed (1/0), current_count, limit, retry_after_seconds}
+
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local window_ms = tonumber(ARGV[2]) * 1000
+local now = tonumber(ARGV[3])
+local member = ARGV[4]
+
+-- Drop requests that left the window
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window_ms)
+
+local count = redis.call('ZCARD', key)
+
+if count >= limit then
+    -- A slot frees up once the oldest request leaves the window
+    local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+    local retry_after = 1
+    if oldest[2] then
+        retry_after = math.ceil((tonumber(oldest[2]) + window_ms - now) / 1000)
+        if retry_after < 1 then
+            retry_after = 1
+        end
+    end
+
+    -- Return: not allowed, current count, limit, retry after
+    return {0, count, limit, retry_after}
+end
+
+redis.call('ZADD', key, now, member)
+redis.call('PEXPIRE', key, window_ms)
+
+-- Return: allowed, current count, limit, no retry needed
+return {1, count + 1, limit, 0}
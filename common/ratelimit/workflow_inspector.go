@@ -11,10 +11,10 @@ const (
 
 // WorkflowProfile contains analysis of a workflow's complexity
 type WorkflowProfile struct {
-	Tier          WorkflowTier // Determined tier
-	AgentCount    int          // Number of agent nodes
-	HasAgentNodes bool         // Whether workflow has any agents
-	TotalNodes    int          // Total node count
+	Tier          WorkflowTier `json:"tier"`            // Determined tier
+	AgentCount    int          `json:"agent_count"`     // Number of agent nodes
+	HasAgentNodes bool         `json:"has_agent_nodes"` // Whether workflow has any agents
+	TotalNodes    int          `json:"total_nodes"`     // Total node count
 }
 
 // InspectWorkflow analyzes a workflow and determines its complexity tier
@@ -26,40 +26,14 @@ func InspectWorkflow(workflow map[string]interface{}) WorkflowProfile {
 		TotalNodes:    0,
 	}
 
-	// Get nodes (handles both array and map formats)
-	nodes := workflow["nodes"]
+	// Get node types (handles both array and map formats)
+	types := nodeTypes(workflow)
+	profile.TotalNodes = len(types)
 
-	if nodesList, ok := nodes.([]interface{}); ok {
-		// Workflow schema format: nodes is an array
-		profile.TotalNodes = len(nodesList)
-
-		for _, nodeInterface := range nodesList {
-			node, ok := nodeInterface.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			nodeType, _ := node["type"].(string)
-			if nodeType == "agent" {
-				profile.AgentCount++
-				profile.HasAgentNodes = true
-			}
-		}
-	} else if nodesMap, ok := nodes.(map[string]interface{}); ok {
-		// IR format: nodes is a map[nodeID]Node
-		profile.TotalNodes = len(nodesMap)
-
-		for _, nodeInterface := range nodesMap {
-			node, ok := nodeInterface.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			nodeType, _ := node["type"].(string)
-			if nodeType == "agent" {
-				profile.AgentCount++
-				profile.HasAgentNodes = true
-			}
+	for _, nodeType := range types {
+		if nodeType == "agent" {
+			profile.AgentCount++
+			profile.HasAgentNodes = true
 		}
 	}
 
@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lyzr/orchestrator/common/bulk"
+	"github.com/redis/go-redis/v9"
+)
+
+// Bulk fetch defaults
+const (
+	DefaultBulkChunkSize   = 500 // Keys per pipeline
+	DefaultBulkConcurrency = 4   // Pipelines in flight at once
+)
+
+// WithBulkFetch sets how GetBulk splits large fetches
+// Each pipeline carries at most chunkSize keys, and at most concurrency
+// pipelines are in flight at once. Values <= 0 keep the defaults.
+func WithBulkFetch(chunkSize, concurrency int) ClientOption {
+	return func(c *Client) {
+		if chunkSize > 0 {
+			c.bulkChunkSize = chunkSize
+		}
+		if concurrency > 0 {
+			c.bulkConcurrency = concurrency
+		}
+	}
+}
+
+// BulkGetResult is the outcome of a GetBulk
+type BulkGetResult struct {
+	Found   map[string]string // Value of each key that exists
+	Missing []string          // Keys that don't exist, in request order
+}
+
+// GetBulk retrieves many keys, reporting which were found and which are missing
+// Keys are fetched in pipelines of bounded size, a few in parallel, so a huge
+// fetch neither builds one giant pipeline nor holds a connection for long.
+// Each pipeline gets its own operation timeout. If any pipeline fails, the
+// others are cancelled and the error is returned.
+func (c *Client) GetBulk(ctx context.Context, keys []string) (*BulkGetResult, error) {
+	result := &BulkGetResult{Found: make(map[string]string, len(keys)), Missing: []string{}}
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	chunks := bulk.Chunks(keys, c.bulkChunkSize)
+	chunkResults := make([]*BulkGetResult, len(chunks))
+	err := bulk.ForEach(ctx, chunks, c.bulkConcurrency, func(ctx context.Context, i int, chunk []string) error {
+		chunkResult, err := c.getChunk(ctx, chunk)
+		chunkResults[i] = chunkResult
+		return err
+	})
+	if err != nil {
+		c.logger.Error("redis bulk GET failed", "key_count", len(keys), "chunks", len(chunks), "error", err)
+		return nil, fmt.Errorf("failed to get multiple keys: %w", err)
+	}
+
+	// Merged in chunk order, so Missing keeps the request order
+	for _, chunkResult := range chunkResults {
+		for key, val := range chunkResult.Found {
+			result.Found[key] = val
+		}
+		result.Missing = append(result.Missing, chunkResult.Missing...)
+	}
+
+	c.logger.Debug("redis bulk GET",
+		"requested", len(keys),
+		"found", len(result.Found),
+		"missing", len(result.Missing),
+		"chunks", len(chunks))
+	return result, nil
+}
+
+// getChunk fetches one chunk of keys in a single pipeline
+func (c *Client) getChunk(ctx context.Context, keys []string) (*BulkGetResult, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	pipe := c.redis.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(keys))
+
+	// Queue one single-key MGET per key: a missing key comes back as a nil value
+	// instead of the redis.Nil error a GET would carry, so every error left on a
+	// command is a real failure
+	for i, key := range keys {
+		cmds[i] = pipe.MGet(ctx, key)
+	}
+
+	_, err := pipe.Exec(ctx)
+	err = c.finish(ctx, "get_multiple", err)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	result := &BulkGetResult{Found: make(map[string]string, len(keys))}
+	for i, cmd := range cmds {
+		vals, err := cmd.Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get key %s: %w", keys[i], err)
+		}
+		if val, ok := vals[0].(string); ok {
+			result.Found[keys[i]] = val
+		} else {
+			result.Missing = append(result.Missing, keys[i])
+		}
+	}
+	return result, nil
+}
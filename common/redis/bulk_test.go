@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipelineRecorder records the size of each MGET pipeline and how many ran at once
+// Other pipelines, like the connection handshake, are ignored.
+type pipelineRecorder struct {
+	mu          sync.Mutex
+	sizes       []int
+	inflight    int
+	maxInflight int
+}
+
+func (*pipelineRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (*pipelineRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (r *pipelineRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if len(cmds) == 0 || cmds[0].Name() != "mget" {
+			return next(ctx, cmds)
+		}
+		r.mu.Lock()
+		r.sizes = append(r.sizes, len(cmds))
+		r.inflight++
+		r.maxInflight = max(r.maxInflight, r.inflight)
+		r.mu.Unlock()
+
+		defer func() {
+			r.mu.Lock()
+			r.inflight--
+			r.mu.Unlock()
+		}()
+		return next(ctx, cmds)
+	}
+}
+
+func TestGetBulk_Chunked(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	recorder := &pipelineRecorder{}
+	client.GetUnderlying().AddHook(recorder)
+
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("cas:artifact://%05d", i)
+		mr.Set(keys[i], fmt.Sprintf(`{"n":%d}`, i))
+	}
+
+	result, err := client.GetBulk(context.Background(), keys)
+	require.NoError(t, err)
+	assert.Len(t, result.Found, len(keys))
+	assert.Empty(t, result.Missing)
+	assert.Equal(t, `{"n":9999}`, result.Found["cas:artifact://09999"])
+
+	// 20 pipelines of 500 keys, never more than DefaultBulkConcurrency at once
+	require.Len(t, recorder.sizes, 20)
+	for _, size := range recorder.sizes {
+		assert.Equal(t, DefaultBulkChunkSize, size)
+	}
+	assert.LessOrEqual(t, recorder.maxInflight, DefaultBulkConcurrency)
+}
+
+func TestGetBulk_FoundAndMissing(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	client = NewClient(client.GetUnderlying(), noopLogger{}, WithBulkFetch(3, 2))
+	recorder := &pipelineRecorder{}
+	client.GetUnderlying().AddHook(recorder)
+
+	keys := make([]string, 10)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+		if i%3 != 0 {
+			mr.Set(keys[i], fmt.Sprint(i))
+		}
+	}
+
+	result, err := client.GetBulk(context.Background(), keys)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"key:1": "1", "key:2": "2", "key:4": "4", "key:5": "5", "key:7": "7", "key:8": "8",
+	}, result.Found)
+	// Missing keys keep the request order across chunks
+	assert.Equal(t, []string{"key:0", "key:3", "key:6", "key:9"}, result.Missing)
+	assert.ElementsMatch(t, []int{3, 3, 3, 1}, recorder.sizes)
+
+	// A failed pipeline fails the whole fetch
+	mr.Close()
+	_, err = client.GetBulk(context.Background(), keys)
+	assert.Error(t, err)
+}
@@ -2,9 +2,12 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/lyzr/orchestrator/common/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -16,28 +19,55 @@ type Logger interface {
 	Debug(msg string, keysAndValues ...interface{})
 }
 
-// Client wraps redis.Client with common operations and instrumentation
+var (
+	// ErrKeyNotFound is returned (wrapped, with the key) by Get for a missing key
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrFieldNotFound is returned (wrapped, with the key and field) by GetHash for a missing hash field
+	ErrFieldNotFound = errors.New("field not found")
+)
+
+// Client wraps redis.UniversalClient with common operations and instrumentation
+// Works against standalone, sentinel, and cluster deployments (see NewUniversalClient)
 type Client struct {
-	redis  *redis.Client
-	logger Logger
+	redis     redis.UniversalClient
+	logger    Logger
+	opTimeout time.Duration // Per-operation timeout for non-blocking operations
+
+	bulkChunkSize   int // Keys per GetBulk pipeline
+	bulkConcurrency int // GetBulk pipelines in flight at once
 }
 
 // NewClient creates a new Redis client wrapper
-func NewClient(redisClient *redis.Client, logger Logger) *Client {
-	return &Client{
-		redis:  redisClient,
-		logger: logger,
+// Non-blocking operations are bounded by DefaultOperationTimeout unless
+// WithOperationTimeout says otherwise.
+func NewClient(redisClient redis.UniversalClient, logger Logger, opts ...ClientOption) *Client {
+	c := &Client{
+		redis:     redisClient,
+		logger:    logger,
+		opTimeout: DefaultOperationTimeout,
+
+		bulkChunkSize:   DefaultBulkChunkSize,
+		bulkConcurrency: DefaultBulkConcurrency,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-// GetUnderlying returns the underlying redis.Client for advanced operations
-func (c *Client) GetUnderlying() *redis.Client {
+// GetUnderlying returns the underlying redis.UniversalClient for advanced operations
+func (c *Client) GetUnderlying() redis.UniversalClient {
 	return c.redis
 }
 
 // SetWithExpiry sets a key with expiration
 func (c *Client) SetWithExpiry(ctx context.Context, key, value string, expiry time.Duration) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	err := c.redis.Set(ctx, key, value, expiry).Err()
+	err = c.finish(ctx, "set", err)
 	if err != nil {
 		c.logger.Error("redis SET failed", "key", key, "error", err)
 		return fmt.Errorf("failed to set key %s: %w", key, err)
@@ -48,10 +78,14 @@ func (c *Client) SetWithExpiry(ctx context.Context, key, value string, expiry ti
 
 // Get retrieves a value by key
 func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	val, err := c.redis.Get(ctx, key).Result()
+	err = c.finish(ctx, "get", err)
 	if err == redis.Nil {
 		c.logger.Debug("redis GET key not found", "key", key)
-		return "", fmt.Errorf("key not found: %s", key)
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 	}
 	if err != nil {
 		c.logger.Error("redis GET failed", "key", key, "error", err)
@@ -61,50 +95,24 @@ func (c *Client) Get(ctx context.Context, key string) (string, error) {
 	return val, nil
 }
 
-// GetMultiple retrieves multiple keys using pipeline (single network round-trip)
-// Returns a map of key -> value. Keys that don't exist are omitted from result.
+// GetMultiple retrieves multiple keys using pipelines (see GetBulk)
+// Returns a map of key -> value. Keys that don't exist are omitted from result;
+// use GetBulk to tell them apart.
 func (c *Client) GetMultiple(ctx context.Context, keys []string) (map[string]string, error) {
-	if len(keys) == 0 {
-		return make(map[string]string), nil
-	}
-
-	pipe := c.redis.Pipeline()
-	cmds := make([]*redis.StringCmd, len(keys))
-
-	// Queue all GET commands
-	for i, key := range keys {
-		cmds[i] = pipe.Get(ctx, key)
-	}
-
-	// Execute pipeline
-	_, err := pipe.Exec(ctx)
-	if err != nil && err != redis.Nil {
-		c.logger.Error("redis pipeline GET failed", "key_count", len(keys), "error", err)
-		return nil, fmt.Errorf("failed to get multiple keys: %w", err)
-	}
-
-	// Collect results
-	result := make(map[string]string)
-	for i, cmd := range cmds {
-		val, err := cmd.Result()
-		if err == redis.Nil {
-			// Key doesn't exist, skip it
-			continue
-		}
-		if err != nil {
-			c.logger.Warn("redis GET failed for key in pipeline", "key", keys[i], "error", err)
-			continue
-		}
-		result[keys[i]] = val
+	result, err := c.GetBulk(ctx, keys)
+	if err != nil {
+		return nil, err
 	}
-
-	c.logger.Debug("redis pipeline GET", "requested", len(keys), "found", len(result))
-	return result, nil
+	return result.Found, nil
 }
 
 // SetNX sets a key only if it doesn't exist (for idempotency checks)
 func (c *Client) SetNX(ctx context.Context, key, value string, expiry time.Duration) (bool, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	wasSet, err := c.redis.SetNX(ctx, key, value, expiry).Result()
+	err = c.finish(ctx, "setnx", err)
 	if err != nil {
 		c.logger.Error("redis SETNX failed", "key", key, "error", err)
 		return false, fmt.Errorf("failed to setnx key %s: %w", key, err)
@@ -114,8 +122,23 @@ func (c *Client) SetNX(ctx context.Context, key, value string, expiry time.Durat
 }
 
 // Delete removes a key
+// In cluster mode a multi-key DEL across slots is rejected with CROSSSLOT, so
+// keys are grouped by slot and deleted with one DEL per slot in a pipeline.
 func (c *Client) Delete(ctx context.Context, keys ...string) error {
-	err := c.redis.Del(ctx, keys...).Err()
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var err error
+	if groups := groupBySlot(keys); isCluster(c.redis) && len(groups) > 1 {
+		pipe := c.redis.Pipeline()
+		for _, group := range groups {
+			pipe.Del(ctx, group...)
+		}
+		_, err = pipe.Exec(ctx)
+	} else {
+		err = c.redis.Del(ctx, keys...).Err()
+	}
+	err = c.finish(ctx, "del", err)
 	if err != nil {
 		c.logger.Error("redis DEL failed", "keys", keys, "error", err)
 		return fmt.Errorf("failed to delete keys: %w", err)
@@ -124,12 +147,34 @@ func (c *Client) Delete(ctx context.Context, keys ...string) error {
 	return nil
 }
 
+// Expire sets keys to expire after ttl; missing keys are skipped
+// One EXPIRE per key in a pipeline, so keys may span cluster slots.
+func (c *Client) Expire(ctx context.Context, ttl time.Duration, keys ...string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	pipe := c.redis.Pipeline()
+	for _, key := range keys {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	err = c.finish(ctx, "expire", err)
+	if err != nil {
+		c.logger.Error("redis EXPIRE failed", "keys", keys, "error", err)
+		return fmt.Errorf("failed to expire keys: %w", err)
+	}
+	c.logger.Debug("redis EXPIRE", "keys", keys, "ttl", ttl)
+	return nil
+}
+
 // AddToStream adds a message to a Redis stream
-func (c *Client) AddToStream(ctx context.Context, stream string, values map[string]interface{}) (string, error) {
-	id, err := c.redis.XAdd(ctx, &redis.XAddArgs{
-		Stream: stream,
-		Values: values,
-	}).Result()
+// Pass WithMaxLen to cap the stream length as part of the XADD.
+func (c *Client) AddToStream(ctx context.Context, stream string, values map[string]interface{}, opts ...StreamOption) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	id, err := c.redis.XAdd(ctx, xAddArgs(ctx, stream, values, opts)).Result()
+	err = c.finish(ctx, "xadd", err)
 	if err != nil {
 		c.logger.Error("redis XADD failed", "stream", stream, "error", err)
 		return "", fmt.Errorf("failed to add to stream %s: %w", stream, err)
@@ -140,7 +185,11 @@ func (c *Client) AddToStream(ctx context.Context, stream string, values map[stri
 
 // PublishEvent publishes an event to a Redis channel
 func (c *Client) PublishEvent(ctx context.Context, channel string, message string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	err := c.redis.Publish(ctx, channel, message).Err()
+	err = c.finish(ctx, "publish", err)
 	if err != nil {
 		c.logger.Error("redis PUBLISH failed", "channel", channel, "error", err)
 		return fmt.Errorf("failed to publish to channel %s: %w", channel, err)
@@ -151,7 +200,11 @@ func (c *Client) PublishEvent(ctx context.Context, channel string, message strin
 
 // IncrementHash increments a hash field and returns the new value
 func (c *Client) IncrementHash(ctx context.Context, key, field string, increment int64) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	val, err := c.redis.HIncrBy(ctx, key, field, increment).Result()
+	err = c.finish(ctx, "hincrby", err)
 	if err != nil {
 		c.logger.Error("redis HINCRBY failed", "key", key, "field", field, "error", err)
 		return 0, fmt.Errorf("failed to increment hash %s field %s: %w", key, field, err)
@@ -162,10 +215,14 @@ func (c *Client) IncrementHash(ctx context.Context, key, field string, increment
 
 // GetHash retrieves a hash field value
 func (c *Client) GetHash(ctx context.Context, key, field string) (string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	val, err := c.redis.HGet(ctx, key, field).Result()
+	err = c.finish(ctx, "hget", err)
 	if err == redis.Nil {
 		c.logger.Debug("redis HGET field not found", "key", key, "field", field)
-		return "", fmt.Errorf("field not found: %s.%s", key, field)
+		return "", fmt.Errorf("%w: %s.%s", ErrFieldNotFound, key, field)
 	}
 	if err != nil {
 		c.logger.Error("redis HGET failed", "key", key, "field", field, "error", err)
@@ -177,7 +234,11 @@ func (c *Client) GetHash(ctx context.Context, key, field string) (string, error)
 
 // SetHash sets a hash field value
 func (c *Client) SetHash(ctx context.Context, key, field, value string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	err := c.redis.HSet(ctx, key, field, value).Err()
+	err = c.finish(ctx, "hset", err)
 	if err != nil {
 		c.logger.Error("redis HSET failed", "key", key, "field", field, "error", err)
 		return fmt.Errorf("failed to set hash %s field %s: %w", key, field, err)
@@ -188,7 +249,11 @@ func (c *Client) SetHash(ctx context.Context, key, field, value string) error {
 
 // GetAllHash retrieves all fields and values of a hash
 func (c *Client) GetAllHash(ctx context.Context, key string) (map[string]string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	val, err := c.redis.HGetAll(ctx, key).Result()
+	err = c.finish(ctx, "hgetall", err)
 	if err != nil {
 		c.logger.Error("redis HGETALL failed", "key", key, "error", err)
 		return nil, fmt.Errorf("failed to get all hash fields %s: %w", key, err)
@@ -199,7 +264,11 @@ func (c *Client) GetAllHash(ctx context.Context, key string) (map[string]string,
 
 // Set sets a key with optional expiration (0 = no expiration)
 func (c *Client) Set(ctx context.Context, key, value string, expiry time.Duration) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	err := c.redis.Set(ctx, key, value, expiry).Err()
+	err = c.finish(ctx, "set", err)
 	if err != nil {
 		c.logger.Error("redis SET failed", "key", key, "error", err)
 		return fmt.Errorf("failed to set key %s: %w", key, err)
@@ -214,7 +283,11 @@ func (c *Client) Set(ctx context.Context, key, value string, expiry time.Duratio
 
 // PushToList pushes values to the right of a list
 func (c *Client) PushToList(ctx context.Context, key string, values ...interface{}) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	err := c.redis.RPush(ctx, key, values...).Err()
+	err = c.finish(ctx, "rpush", err)
 	if err != nil {
 		c.logger.Error("redis RPUSH failed", "key", key, "error", err)
 		return fmt.Errorf("failed to rpush to %s: %w", key, err)
@@ -224,8 +297,14 @@ func (c *Client) PushToList(ctx context.Context, key string, values ...interface
 }
 
 // BlockingPopList blocks and pops from a list (left side)
+// In cluster mode all keys must share a hash slot (see HashTag).
 func (c *Client) BlockingPopList(ctx context.Context, timeout time.Duration, keys ...string) ([]string, error) {
+	if isCluster(c.redis) && len(groupBySlot(keys)) > 1 {
+		return nil, fmt.Errorf("failed to blpop from %v: keys span multiple cluster slots", keys)
+	}
+
 	result, err := c.redis.BLPop(ctx, timeout, keys...).Result()
+	err = c.finish(ctx, "blpop", err)
 	if err == redis.Nil {
 		// Timeout - not an error
 		return nil, nil
@@ -257,12 +336,14 @@ func (p *Pipeline) SetWithExpiry(ctx context.Context, key, value string, expiry
 	p.pipe.Set(ctx, key, value, expiry)
 }
 
+// Delete queues a DEL operation in the pipeline
+func (p *Pipeline) Delete(ctx context.Context, keys ...string) {
+	p.pipe.Del(ctx, keys...)
+}
+
 // AddToStream queues an XADD operation in the pipeline
-func (p *Pipeline) AddToStream(ctx context.Context, stream string, values map[string]interface{}) {
-	p.pipe.XAdd(ctx, &redis.XAddArgs{
-		Stream: stream,
-		Values: values,
-	})
+func (p *Pipeline) AddToStream(ctx context.Context, stream string, values map[string]interface{}, opts ...StreamOption) {
+	p.pipe.XAdd(ctx, xAddArgs(ctx, stream, values, opts))
 }
 
 // PublishEvent queues a PUBLISH operation in the pipeline
@@ -272,7 +353,11 @@ func (p *Pipeline) PublishEvent(ctx context.Context, channel string, message str
 
 // Exec executes all queued operations in the pipeline
 func (p *Pipeline) Exec(ctx context.Context) error {
+	ctx, cancel := p.client.withTimeout(ctx)
+	defer cancel()
+
 	_, err := p.pipe.Exec(ctx)
+	err = p.client.finish(ctx, "pipeline", err)
 	if err != nil {
 		p.client.logger.Error("redis pipeline exec failed", "error", err)
 		return fmt.Errorf("failed to execute pipeline: %w", err)
@@ -283,7 +368,11 @@ func (p *Pipeline) Exec(ctx context.Context) error {
 
 // Increment increments a counter and returns the new value
 func (c *Client) Increment(ctx context.Context, key string) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	val, err := c.redis.Incr(ctx, key).Result()
+	err = c.finish(ctx, "incr", err)
 	if err != nil {
 		c.logger.Error("redis INCR failed", "key", key, "error", err)
 		return 0, fmt.Errorf("failed to increment key %s: %w", key, err)
@@ -294,7 +383,11 @@ func (c *Client) Increment(ctx context.Context, key string) (int64, error) {
 
 // Decrement decrements a counter and returns the new value
 func (c *Client) Decrement(ctx context.Context, key string) (int64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	val, err := c.redis.Decr(ctx, key).Result()
+	err = c.finish(ctx, "decr", err)
 	if err != nil {
 		c.logger.Error("redis DECR failed", "key", key, "error", err)
 		return 0, fmt.Errorf("failed to decrement key %s: %w", key, err)
@@ -305,14 +398,29 @@ func (c *Client) Decrement(ctx context.Context, key string) (int64, error) {
 
 // ReadFromStreamGroup reads messages from a stream using consumer groups
 func (c *Client) ReadFromStreamGroup(ctx context.Context, group, consumer, stream string, count int64, block time.Duration) ([]redis.XStream, error) {
-	streams, err := c.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+	return c.ReadFromStreamGroups(ctx, group, consumer, []string{stream}, count, block)
+}
+
+// ReadFromStreamGroups reads new messages from several streams in one XREADGROUP
+// count applies to each stream, and a negative block returns at once rather
+// than waiting. In cluster mode the streams must share a hash slot.
+func (c *Client) ReadFromStreamGroups(ctx context.Context, group, consumer string, streams []string, count int64, block time.Duration) ([]redis.XStream, error) {
+	args := make([]string, 0, 2*len(streams))
+	args = append(args, streams...)
+	for range streams {
+		args = append(args, ">")
+	}
+
+	read, err := c.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Group:    group,
 		Consumer: consumer,
-		Streams:  []string{stream, ">"},
+		Streams:  args,
 		Count:    count,
 		Block:    block,
 	}).Result()
+	err = c.finish(ctx, "xreadgroup", err)
 
+	stream := strings.Join(streams, ",")
 	if err == redis.Nil {
 		// Timeout/no messages - not an error
 		return nil, nil
@@ -322,25 +430,40 @@ func (c *Client) ReadFromStreamGroup(ctx context.Context, group, consumer, strea
 		return nil, fmt.Errorf("failed to read from stream %s: %w", stream, err)
 	}
 
-	c.logger.Debug("redis XREADGROUP", "stream", stream, "group", group, "message_count", len(streams))
-	return streams, nil
+	for _, s := range read {
+		metrics.StreamMessagesConsumed.Add(float64(len(s.Messages)), s.Stream)
+	}
+	c.logger.Debug("redis XREADGROUP", "stream", stream, "group", group, "message_count", len(read))
+	return read, nil
 }
 
 // AckStreamMessage acknowledges a message in a stream
 func (c *Client) AckStreamMessage(ctx context.Context, stream, group, messageID string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	err := c.redis.XAck(ctx, stream, group, messageID).Err()
+	err = c.finish(ctx, "xack", err)
 	if err != nil {
 		c.logger.Error("redis XACK failed", "stream", stream, "group", group, "message_id", messageID, "error", err)
 		return fmt.Errorf("failed to ack message %s: %w", messageID, err)
 	}
+	metrics.StreamMessagesAcked.Inc(stream)
 	c.logger.Debug("redis XACK", "stream", stream, "group", group, "message_id", messageID)
 	return nil
 }
 
 // CreateStreamGroup creates a consumer group for a stream
 func (c *Client) CreateStreamGroup(ctx context.Context, stream, group string) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
 	err := c.redis.XGroupCreateMkStream(ctx, stream, group, "0").Err()
-	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
+	if err != nil && err.Error() == "BUSYGROUP Consumer Group name already exists" {
+		err = nil
+	}
+	err = c.finish(ctx, "xgroup_create", err)
+	if err != nil {
 		c.logger.Error("redis XGROUP CREATE failed", "stream", stream, "group", group, "error", err)
 		return fmt.Errorf("failed to create consumer group %s: %w", group, err)
 	}
@@ -349,16 +472,25 @@ func (c *Client) CreateStreamGroup(ctx context.Context, stream, group string) er
 }
 
 // Transaction represents a Redis transaction for atomic operations
+// go-redis rejects cluster transactions whose keys span several hash slots
+// (CROSSSLOT), so in cluster mode Exec runs one MULTI/EXEC per slot: atomicity
+// then only spans keys that share a hash tag (see HashTag).
 type Transaction struct {
-	pipe   redis.Pipeliner
 	client *Client
+	ops    []txOp
 	cmds   map[string]redis.Cmder // Store commands by label for result retrieval
 }
 
+// txOp is a queued command, added to a TxPipeline at Exec time
+type txOp struct {
+	key   string
+	label string
+	queue func(pipe redis.Pipeliner) redis.Cmder
+}
+
 // NewTransaction creates a new transaction (TxPipeline)
 func (c *Client) NewTransaction() *Transaction {
 	return &Transaction{
-		pipe:   c.redis.TxPipeline(),
 		client: c,
 		cmds:   make(map[string]redis.Cmder),
 	}
@@ -367,38 +499,79 @@ func (c *Client) NewTransaction() *Transaction {
 // SetNX queues a SETNX operation and returns a label for retrieving the result
 func (t *Transaction) SetNX(ctx context.Context, key, value string, expiry time.Duration) string {
 	label := fmt.Sprintf("setnx_%s", key)
-	cmd := t.pipe.SetNX(ctx, key, value, expiry)
-	t.cmds[label] = cmd
+	t.ops = append(t.ops, txOp{key: key, label: label, queue: func(pipe redis.Pipeliner) redis.Cmder {
+		return pipe.SetNX(ctx, key, value, expiry)
+	}})
 	return label
 }
 
 // Incr queues an INCR operation and returns a label for retrieving the result
 func (t *Transaction) Incr(ctx context.Context, key string) string {
 	label := fmt.Sprintf("incr_%s", key)
-	cmd := t.pipe.Incr(ctx, key)
-	t.cmds[label] = cmd
+	t.ops = append(t.ops, txOp{key: key, label: label, queue: func(pipe redis.Pipeliner) redis.Cmder {
+		return pipe.Incr(ctx, key)
+	}})
 	return label
 }
 
 // Decr queues a DECR operation and returns a label for retrieving the result
 func (t *Transaction) Decr(ctx context.Context, key string) string {
 	label := fmt.Sprintf("decr_%s", key)
-	cmd := t.pipe.Decr(ctx, key)
-	t.cmds[label] = cmd
+	t.ops = append(t.ops, txOp{key: key, label: label, queue: func(pipe redis.Pipeliner) redis.Cmder {
+		return pipe.Decr(ctx, key)
+	}})
 	return label
 }
 
 // Exec executes all queued operations atomically
+// In cluster mode, operations on different slots run as separate transactions;
+// if one fails, those already executed are not rolled back.
 func (t *Transaction) Exec(ctx context.Context) error {
-	_, err := t.pipe.Exec(ctx)
-	if err != nil {
-		t.client.logger.Error("redis transaction exec failed", "error", err)
-		return fmt.Errorf("failed to execute transaction: %w", err)
+	ctx, cancel := t.client.withTimeout(ctx)
+	defer cancel()
+
+	batches := [][]txOp{t.ops}
+	if isCluster(t.client.redis) {
+		batches = t.batchBySlot()
+		if len(batches) > 1 {
+			t.client.logger.Warn("redis transaction spans multiple cluster slots, atomicity is per slot",
+				"slots", len(batches))
+		}
 	}
+
+	for _, batch := range batches {
+		pipe := t.client.redis.TxPipeline()
+		for _, op := range batch {
+			t.cmds[op.label] = op.queue(pipe)
+		}
+		_, err := pipe.Exec(ctx)
+		if err = t.client.finish(ctx, "multi", err); err != nil {
+			t.client.logger.Error("redis transaction exec failed", "error", err)
+			return fmt.Errorf("failed to execute transaction: %w", err)
+		}
+	}
+
 	t.client.logger.Debug("redis transaction executed successfully")
 	return nil
 }
 
+// batchBySlot groups queued operations by cluster slot, preserving order within a slot
+func (t *Transaction) batchBySlot() [][]txOp {
+	index := make(map[int]int)
+	var batches [][]txOp
+	for _, op := range t.ops {
+		slot := KeySlot(op.key)
+		i, ok := index[slot]
+		if !ok {
+			i = len(batches)
+			index[slot] = i
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], op)
+	}
+	return batches
+}
+
 // GetBoolResult retrieves a boolean result from a labeled command (for SETNX)
 func (t *Transaction) GetBoolResult(label string) (bool, error) {
 	cmd, exists := t.cmds[label]
@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMultiple_SkipsMissingKeys(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	mr.Set("b", "2")
+	mr.Set("d", "")
+
+	// A missing first key must not hide the keys after it
+	values, err := client.GetMultiple(context.Background(), []string{"a", "b", "c", "d"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"b": "2", "d": ""}, values)
+
+	values, err = client.GetMultiple(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestGet_MissingKey(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	mr.Set("present", "1")
+	mr.HSet("hash", "present", "1")
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, "ir:missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.EqualError(t, err, "key not found: ir:missing")
+
+	_, err = client.GetHash(ctx, "hash", "missing")
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+
+	value, err := client.Get(ctx, "present")
+	require.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	// Other failures are not mistaken for a missing key
+	mr.SetError("READONLY")
+	_, err = client.Get(ctx, "present")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrKeyNotFound)
+}
@@ -0,0 +1,289 @@
+package redis
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+const (
+	// DefaultConsumerWorkers handles one message at a time
+	DefaultConsumerWorkers = 1
+
+	// DefaultConsumerBlock is how long a read waits for new messages
+	DefaultConsumerBlock = 5 * time.Second
+
+	// DefaultConsumerBackoffBase is how long the read loop pauses after a failed read
+	// The pause doubles with each failure in a row, up to DefaultConsumerBackoffMax.
+	DefaultConsumerBackoffBase = 1 * time.Second
+
+	// DefaultConsumerBackoffMax is the longest pause between failed reads
+	DefaultConsumerBackoffMax = 30 * time.Second
+
+	// DefaultConsumerBackoffJitter is the fraction of each pause randomly cut
+	DefaultConsumerBackoffJitter = 0.2
+)
+
+// ConsumerPoolOption customizes a ConsumerPool
+type ConsumerPoolOption func(*ConsumerPool)
+
+// WithConsumerWorkers sets how many messages are handled in parallel
+// Values below 1 keep the default.
+func WithConsumerWorkers(workers int) ConsumerPoolOption {
+	return func(p *ConsumerPool) {
+		if workers > 0 {
+			p.workers = workers
+		}
+	}
+}
+
+// WithConsumerBlock sets how long a read waits for new messages
+func WithConsumerBlock(block time.Duration) ConsumerPoolOption {
+	return func(p *ConsumerPool) {
+		p.block = block
+	}
+}
+
+// WithConsumerConfig applies the shared consumer settings: read block and error backoff
+// Zero values keep the defaults.
+func WithConsumerConfig(cfg config.ConsumerConfig) ConsumerPoolOption {
+	return func(p *ConsumerPool) {
+		if cfg.Block > 0 {
+			p.block = cfg.Block
+		}
+		if cfg.BackoffBase > 0 {
+			p.backoff.base = cfg.BackoffBase
+		}
+		if cfg.BackoffMax > 0 {
+			p.backoff.max = cfg.BackoffMax
+		}
+		if cfg.BackoffJitter > 0 {
+			p.backoff.jitter = cfg.BackoffJitter
+		}
+	}
+}
+
+// WithPriorities makes the pool consume every priority variant of its stream
+// (see PriorityStreams), most urgent first. Every fairness-th read starts from
+// the least urgent instead, so low priorities are never starved; fairness < 1
+// uses DefaultPriorityFairness.
+func WithPriorities(fairness int) ConsumerPoolOption {
+	return func(p *ConsumerPool) {
+		if fairness < 1 {
+			fairness = DefaultPriorityFairness
+		}
+		p.streams = PriorityStreams(p.stream)
+		p.fairness = fairness
+	}
+}
+
+// ConsumerPool reads a stream's consumer group and handles messages in parallel
+// At most workers messages are in flight at once. The read loop takes a slot of
+// that semaphore for every message it reads and only asks XREADGROUP for as many
+// messages as there are free slots, so nothing sits claimed but unhandled while
+// the workers are busy. Each message is acknowledged on its own as soon as its
+// handler returns, failed or not. Messages are handled in no particular order,
+// so handlers must not depend on stream order.
+type ConsumerPool struct {
+	client   *Client
+	stream   string
+	group    string
+	consumer string
+	handle   MessageHandler
+	workers  int
+	block    time.Duration
+	backoff  backoff
+
+	// Priority consumption (see WithPriorities)
+	streams  []string // Streams read, most urgent first
+	fairness int
+	reads    int
+}
+
+// NewConsumerPool creates a consumer pool for one stream's consumer group
+func NewConsumerPool(client *Client, stream, group, consumer string, handle MessageHandler, opts ...ConsumerPoolOption) *ConsumerPool {
+	p := &ConsumerPool{
+		client:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+		handle:   handle,
+		streams:  []string{stream},
+		workers:  DefaultConsumerWorkers,
+		block:    DefaultConsumerBlock,
+		backoff: backoff{
+			base:   DefaultConsumerBackoffBase,
+			max:    DefaultConsumerBackoffMax,
+			jitter: DefaultConsumerBackoffJitter,
+			random: rand.Float64,
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Workers returns how many messages are handled in parallel
+func (p *ConsumerPool) Workers() int {
+	return p.workers
+}
+
+// Run reads and handles messages until ctx is cancelled
+// Messages still being handled are waited for before Run returns.
+func (p *ConsumerPool) Run(ctx context.Context) {
+	p.client.logger.Info("stream consumer pool started",
+		"stream", p.stream,
+		"group", p.group,
+		"consumer", p.consumer,
+		"workers", p.workers)
+
+	slots := make(chan struct{}, p.workers)
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+
+	for {
+		// Wait for a free worker, then take every other free one too
+		select {
+		case <-ctx.Done():
+			p.client.logger.Info("stream consumer pool stopped", "stream", p.stream)
+			return
+		case slots <- struct{}{}:
+		}
+		acquired := 1
+	acquire:
+		for acquired < p.workers {
+			select {
+			case slots <- struct{}{}:
+				acquired++
+			default:
+				break acquire
+			}
+		}
+
+		streams, err := p.read(ctx, int64(acquired))
+		if err != nil && ctx.Err() == nil {
+			delay := p.backoff.next()
+			p.client.logger.Error("failed to read from stream", "stream", p.stream, "error", err, "retry_in", delay)
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+		} else if err == nil {
+			p.backoff.reset()
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				if acquired == 0 {
+					// A blocking priority read may return one message per stream
+					slots <- struct{}{}
+					acquired++
+				}
+				acquired--
+				inflight.Add(1)
+				go func(stream string, message redis.XMessage) {
+					defer func() {
+						<-slots
+						inflight.Done()
+					}()
+					p.process(ctx, stream, message)
+				}(stream.Stream, message)
+			}
+		}
+
+		// Give back the slots no message was read for
+		for ; acquired > 0; acquired-- {
+			<-slots
+		}
+	}
+}
+
+// read claims up to count new messages
+// With priorities, the streams are read without blocking, most urgent first
+// (least urgent first on every fairness-th read), until count messages are
+// claimed. Only when all are empty does the read block, on all of them at once.
+func (p *ConsumerPool) read(ctx context.Context, count int64) ([]redis.XStream, error) {
+	if len(p.streams) == 1 {
+		return p.client.ReadFromStreamGroup(ctx, p.group, p.consumer, p.stream, count, p.block)
+	}
+
+	order := p.streams
+	p.reads++
+	if p.reads%p.fairness == 0 {
+		order = make([]string, len(p.streams))
+		for i, stream := range p.streams {
+			order[len(order)-1-i] = stream
+		}
+	}
+
+	var read []redis.XStream
+	for _, stream := range order {
+		streams, err := p.client.ReadFromStreamGroup(ctx, p.group, p.consumer, stream, count, -1)
+		if err != nil {
+			return read, err
+		}
+		for _, s := range streams {
+			count -= int64(len(s.Messages))
+		}
+		read = append(read, streams...)
+		if count <= 0 {
+			break
+		}
+	}
+	if len(read) > 0 {
+		return read, nil
+	}
+
+	return p.client.ReadFromStreamGroups(ctx, p.group, p.consumer, p.streams, 1, p.block)
+}
+
+// process handles one message and acknowledges it on the stream it was read from
+func (p *ConsumerPool) process(ctx context.Context, stream string, message redis.XMessage) {
+	if err := p.handle(ctx, message); err != nil {
+		p.client.logger.Error("failed to handle message", "stream", stream, "message_id", message.ID, "error", err)
+		// Acknowledged anyway: a failing message would otherwise be redelivered forever
+	}
+
+	if err := p.client.AckStreamMessage(ctx, stream, p.group, message.ID); err != nil {
+		p.client.logger.Error("failed to ACK message", "stream", stream, "message_id", message.ID, "error", err)
+	}
+}
+
+// backoff computes pauses between failed reads: exponential, capped, with jitter
+type backoff struct {
+	base     time.Duration
+	max      time.Duration
+	jitter   float64
+	random   func() float64 // [0, 1)
+	failures int
+}
+
+// next records a failure and returns how long to pause
+// The nth failure in a row waits base * 2^(n-1), capped at max, less a random
+// cut of up to jitter of that, so the cap is never exceeded.
+func (b *backoff) next() time.Duration {
+	delay := b.base
+	for i := 0; i < b.failures && delay < b.max; i++ {
+		delay *= 2
+	}
+	if delay > b.max {
+		delay = b.max
+	}
+	b.failures++
+
+	if b.jitter > 0 && b.random != nil {
+		delay -= time.Duration(float64(delay) * b.jitter * b.random())
+	}
+	return delay
+}
+
+// reset starts over from base after a successful read
+func (b *backoff) reset() {
+	b.failures = 0
+}
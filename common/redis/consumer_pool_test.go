@@ -0,0 +1,331 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+// publishTestMessages adds n messages to a fresh stream and consumer group
+func publishTestMessages(t testing.TB, client *Client, stream, group string, n int) {
+	ctx := context.Background()
+	require.NoError(t, client.CreateStreamGroup(ctx, stream, group))
+	for i := 0; i < n; i++ {
+		_, err := client.AddToStream(ctx, stream, map[string]interface{}{"token": fmt.Sprintf("t%d", i)})
+		require.NoError(t, err)
+	}
+}
+
+// drainWithPools runs one pool per consumer until n messages were handled
+// Returns how long that took.
+func drainWithPools(t testing.TB, client *Client, stream, group string, n int, handle MessageHandler, consumers []string, workers int) time.Duration {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var handled atomic.Int64
+	done := make(chan struct{})
+	counting := func(ctx context.Context, message redis.XMessage) error {
+		err := handle(ctx, message)
+		if handled.Add(1) == int64(n) {
+			close(done)
+		}
+		return err
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, consumer := range consumers {
+		pool := NewConsumerPool(client, stream, group, consumer, counting,
+			WithConsumerWorkers(workers),
+			WithConsumerBlock(20*time.Millisecond))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.Run(ctx)
+		}()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("handled %d of %d messages", handled.Load(), n)
+	}
+	elapsed := time.Since(start)
+
+	cancel()
+	wg.Wait()
+	return elapsed
+}
+
+func TestConsumerPool_ParallelWorkers(t *testing.T) {
+	const messages = 20
+	const workers = 4
+	const work = 50 * time.Millisecond
+
+	run := func(workers int) (time.Duration, map[string]int, int64) {
+		_, client := newLockTestClient(t)
+		publishTestMessages(t, client, "wf.tasks.test", "workers", messages)
+
+		var mu sync.Mutex
+		seen := make(map[string]int)
+		var inflight, peak atomic.Int64
+		elapsed := drainWithPools(t, client, "wf.tasks.test", "workers", messages,
+			func(ctx context.Context, message redis.XMessage) error {
+				current := inflight.Add(1)
+				defer inflight.Add(-1)
+				for {
+					max := peak.Load()
+					if current <= max || peak.CompareAndSwap(max, current) {
+						break
+					}
+				}
+
+				time.Sleep(work)
+
+				mu.Lock()
+				seen[message.Values["token"].(string)]++
+				mu.Unlock()
+				return nil
+			}, []string{"worker_a"}, workers)
+
+		pending, err := client.GetUnderlying().XPending(context.Background(), "wf.tasks.test", "workers").Result()
+		require.NoError(t, err)
+		assert.Zero(t, pending.Count, "every message is acknowledged")
+
+		return elapsed, seen, peak.Load()
+	}
+
+	serial, serialSeen, serialPeak := run(1)
+	parallel, parallelSeen, parallelPeak := run(workers)
+
+	for _, seen := range []map[string]int{serialSeen, parallelSeen} {
+		require.Len(t, seen, messages)
+		for token, count := range seen {
+			assert.Equal(t, 1, count, "token %s handled once", token)
+		}
+	}
+
+	assert.Equal(t, int64(1), serialPeak)
+	assert.Equal(t, int64(workers), parallelPeak, "never more than workers in flight")
+
+	// 20 × 50ms is a full second serially; four workers need about a quarter of it
+	assert.GreaterOrEqual(t, serial, messages*work)
+	assert.Less(t, parallel, serial/2, "serial %s, parallel %s", serial, parallel)
+}
+
+func TestConsumerPool_NoDoubleProcessingAcrossConsumers(t *testing.T) {
+	const messages = 40
+	_, client := newLockTestClient(t)
+	ctx := context.Background()
+	publishTestMessages(t, client, "wf.tasks.test", "workers", messages)
+
+	// Handlers claim each token with an idempotency key, as the workers do
+	var duplicates atomic.Int64
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	drainWithPools(t, client, "wf.tasks.test", "workers", messages,
+		func(ctx context.Context, message redis.XMessage) error {
+			token := message.Values["token"].(string)
+			claimed, err := client.SetNX(ctx, "processed:"+token, message.ID, time.Minute)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				duplicates.Add(1)
+				return nil
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			seen[token]++
+			mu.Unlock()
+			return nil
+		}, []string{"worker_a", "worker_b"}, 4)
+
+	assert.Zero(t, duplicates.Load())
+	require.Len(t, seen, messages)
+	for token, count := range seen {
+		assert.Equal(t, 1, count, "token %s handled once", token)
+	}
+
+	pending, err := client.GetUnderlying().XPending(ctx, "wf.tasks.test", "workers").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestConsumerPool_AcksFailedMessages(t *testing.T) {
+	_, client := newLockTestClient(t)
+	ctx := context.Background()
+	publishTestMessages(t, client, "wf.tasks.test", "workers", 3)
+
+	drainWithPools(t, client, "wf.tasks.test", "workers", 3,
+		func(ctx context.Context, message redis.XMessage) error {
+			return fmt.Errorf("handler failed")
+		}, []string{"worker_a"}, 2)
+
+	pending, err := client.GetUnderlying().XPending(ctx, "wf.tasks.test", "workers").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func TestConsumerPool_BackoffGrowsAndCaps(t *testing.T) {
+	b := &backoff{base: 100 * time.Millisecond, max: time.Second}
+
+	var delays []time.Duration
+	for i := 0; i < 7; i++ {
+		delays = append(delays, b.next())
+	}
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+		time.Second,
+	}, delays)
+
+	// A successful read starts over
+	b.reset()
+	assert.Equal(t, 100*time.Millisecond, b.next())
+}
+
+func TestConsumerPool_BackoffJitter(t *testing.T) {
+	random := 0.0
+	b := &backoff{base: 100 * time.Millisecond, max: time.Second, jitter: 0.5, random: func() float64 { return random }}
+
+	// Jitter only ever shortens a pause, so the cap holds
+	random = 0.999
+	for i := 0; i < 10; i++ {
+		delay := b.next()
+		assert.LessOrEqual(t, delay, time.Second)
+		assert.Greater(t, delay, 0*time.Millisecond)
+	}
+
+	random = 0.5
+	b.reset()
+	assert.Equal(t, 75*time.Millisecond, b.next())
+	assert.Equal(t, 150*time.Millisecond, b.next())
+}
+
+func TestConsumerPool_WithConsumerConfig(t *testing.T) {
+	_, client := newLockTestClient(t)
+	handle := func(ctx context.Context, message redis.XMessage) error { return nil }
+
+	pool := NewConsumerPool(client, "wf.tasks.test", "workers", "worker_a", handle, WithConsumerConfig(config.ConsumerConfig{
+		Block:       time.Second,
+		BackoffBase: 50 * time.Millisecond,
+		BackoffMax:  2 * time.Second,
+	}))
+	assert.Equal(t, time.Second, pool.block)
+	assert.Equal(t, 50*time.Millisecond, pool.backoff.base)
+	assert.Equal(t, 2*time.Second, pool.backoff.max)
+	assert.Equal(t, DefaultConsumerBackoffJitter, pool.backoff.jitter, "zero values keep the default")
+}
+
+// runPriorityPool consumes stream's priority variants with one worker until n
+// messages were handled, returning their tokens in handling order
+func runPriorityPool(t *testing.T, client *Client, stream string, n int, opts ...ConsumerPoolOption) []string {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{})
+	handle := func(ctx context.Context, message redis.XMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, message.Values["token"].(string))
+		if len(order) == n {
+			close(done)
+		}
+		return nil
+	}
+
+	opts = append([]ConsumerPoolOption{WithConsumerBlock(20 * time.Millisecond)}, opts...)
+	pool := NewConsumerPool(client, stream, "workers", "worker_a", handle, opts...)
+	finished := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(finished)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("handled %d of %d messages", len(order), n)
+	}
+	cancel()
+	<-finished
+	return order
+}
+
+func TestConsumerPool_PriorityFirst(t *testing.T) {
+	_, client := newLockTestClient(t)
+	ctx := context.Background()
+	for _, stream := range PriorityStreams("wf.tasks.test") {
+		require.NoError(t, client.CreateStreamGroup(ctx, stream, "workers"))
+	}
+
+	// The low-priority token is added first, the high-priority one after it
+	publish := func(priority, token string) {
+		_, err := client.AddToStream(ctx, PriorityStream("wf.tasks.test", priority), map[string]interface{}{"token": token})
+		require.NoError(t, err)
+	}
+	publish(PriorityLow, "low")
+	publish(PriorityNormal, "normal")
+	publish(PriorityHigh, "high")
+
+	order := runPriorityPool(t, client, "wf.tasks.test", 3, WithPriorities(0))
+	assert.Equal(t, []string{"high", "normal", "low"}, order)
+
+	// Every message is acknowledged on the stream it came from
+	for _, stream := range PriorityStreams("wf.tasks.test") {
+		pending, err := client.GetUnderlying().XPending(ctx, stream, "workers").Result()
+		require.NoError(t, err)
+		assert.Zero(t, pending.Count, stream)
+	}
+}
+
+func TestConsumerPool_PriorityFairness(t *testing.T) {
+	_, client := newLockTestClient(t)
+	ctx := context.Background()
+	for _, stream := range PriorityStreams("wf.tasks.test") {
+		require.NoError(t, client.CreateStreamGroup(ctx, stream, "workers"))
+	}
+
+	_, err := client.AddToStream(ctx, PriorityStream("wf.tasks.test", PriorityLow), map[string]interface{}{"token": "low"})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err := client.AddToStream(ctx, PriorityStream("wf.tasks.test", PriorityHigh), map[string]interface{}{"token": fmt.Sprintf("high%d", i)})
+		require.NoError(t, err)
+	}
+
+	// Every third read serves the least urgent stream first
+	order := runPriorityPool(t, client, "wf.tasks.test", 6, WithPriorities(3))
+	assert.Equal(t, []string{"high0", "high1", "low", "high2", "high3", "high4"}, order)
+}
+
+func BenchmarkConsumerPool(b *testing.B) {
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			_, client := newLockTestClient(b)
+			publishTestMessages(b, client, "wf.tasks.bench", "workers", b.N)
+			b.ResetTimer()
+			drainWithPools(b, client, "wf.tasks.bench", "workers", b.N,
+				func(ctx context.Context, message redis.XMessage) error {
+					time.Sleep(time.Millisecond)
+					return nil
+				}, []string{"worker_a"}, workers)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLockNotAcquired is returned when the lock is held by someone else
+	ErrLockNotAcquired = errors.New("lock not acquired")
+
+	// ErrLockNotHeld is returned when releasing or refreshing a lock that expired or was taken over
+	ErrLockNotHeld = errors.New("lock not held")
+)
+
+// Lock retry backoff for AcquireLockWait
+const (
+	lockRetryMin = 10 * time.Millisecond
+	lockRetryMax = 200 * time.Millisecond
+)
+
+// releaseScript deletes the lock only if it still holds our token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// refreshScript extends the lock TTL only if it still holds our token
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Lock is a distributed lock held via a Redis key
+// The key stores a random token so only the holder can release or refresh it,
+// even after the TTL expired and another caller acquired the lock.
+type Lock struct {
+	client *Client
+	key    string
+	token  string
+}
+
+// AcquireLock tries once to take the lock for ttl
+// Returns ErrLockNotAcquired if another holder has it.
+func (c *Client) AcquireLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	token := uuid.NewString()
+
+	acquired, err := c.redis.SetNX(ctx, key, token, ttl).Result()
+	err = c.finish(ctx, "lock_acquire", err)
+	if err != nil {
+		c.logger.Error("redis lock acquire failed", "key", key, "error", err)
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	if !acquired {
+		return nil, ErrLockNotAcquired
+	}
+
+	c.logger.Debug("redis lock acquired", "key", key, "ttl", ttl)
+	return &Lock{client: c, key: key, token: token}, nil
+}
+
+// AcquireLockWait retries AcquireLock until it succeeds, timeout elapses or ctx is done
+// Returns ErrLockNotAcquired on timeout.
+func (c *Client) AcquireLockWait(ctx context.Context, key string, ttl, timeout time.Duration) (*Lock, error) {
+	deadline := time.Now().Add(timeout)
+	backoff := lockRetryMin
+
+	for {
+		lock, err := c.AcquireLock(ctx, key, ttl)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, ErrLockNotAcquired
+		}
+
+		wait := backoff
+		if wait > remaining {
+			wait = remaining
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > lockRetryMax {
+			backoff = lockRetryMax
+		}
+	}
+}
+
+// Key returns the locked key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Release deletes the lock if it is still ours
+// Returns ErrLockNotHeld if it expired or another holder took it over.
+func (l *Lock) Release(ctx context.Context) error {
+	ctx, cancel := l.client.withTimeout(ctx)
+	defer cancel()
+
+	released, err := releaseScript.Run(ctx, l.client.redis, []string{l.key}, l.token).Int64()
+	err = l.client.finish(ctx, "lock_release", err)
+	if err != nil {
+		l.client.logger.Error("redis lock release failed", "key", l.key, "error", err)
+		return fmt.Errorf("failed to release lock %s: %w", l.key, err)
+	}
+	if released == 0 {
+		return ErrLockNotHeld
+	}
+
+	l.client.logger.Debug("redis lock released", "key", l.key)
+	return nil
+}
+
+// Refresh resets the lock TTL for long-running work
+// Returns ErrLockNotHeld if it expired or another holder took it over.
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	ctx, cancel := l.client.withTimeout(ctx)
+	defer cancel()
+
+	refreshed, err := refreshScript.Run(ctx, l.client.redis, []string{l.key}, l.token, ttl.Milliseconds()).Int64()
+	err = l.client.finish(ctx, "lock_refresh", err)
+	if err != nil {
+		l.client.logger.Error("redis lock refresh failed", "key", l.key, "error", err)
+		return fmt.Errorf("failed to refresh lock %s: %w", l.key, err)
+	}
+	if refreshed == 0 {
+		return ErrLockNotHeld
+	}
+
+	l.client.logger.Debug("redis lock refreshed", "key", l.key, "ttl", ttl)
+	return nil
+}
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLockTestClient(t testing.TB) (*miniredis.Miniredis, *Client) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, NewClient(rdb, noopLogger{})
+}
+
+func TestLock_Contention(t *testing.T) {
+	_, client := newLockTestClient(t)
+	ctx := context.Background()
+
+	lock, err := client.AcquireLock(ctx, "lock:job", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "lock:job", lock.Key())
+
+	// Second acquire fails while held
+	_, err = client.AcquireLock(ctx, "lock:job", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+	// Other keys are independent
+	other, err := client.AcquireLock(ctx, "lock:other", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx))
+
+	// Free again after release
+	require.NoError(t, lock.Release(ctx))
+	again, err := client.AcquireLock(ctx, "lock:job", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, again.Release(ctx))
+}
+
+func TestLock_ReleaseOnlyByHolder(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	ctx := context.Background()
+
+	stale, err := client.AcquireLock(ctx, "lock:job", time.Second)
+	require.NoError(t, err)
+
+	// The TTL runs out and someone else takes the lock
+	mr.FastForward(2 * time.Second)
+	current, err := client.AcquireLock(ctx, "lock:job", time.Minute)
+	require.NoError(t, err)
+
+	// The previous holder can neither release nor extend it
+	assert.ErrorIs(t, stale.Release(ctx), ErrLockNotHeld)
+	assert.ErrorIs(t, stale.Refresh(ctx, time.Minute), ErrLockNotHeld)
+	assert.True(t, mr.Exists("lock:job"))
+
+	require.NoError(t, current.Release(ctx))
+	assert.False(t, mr.Exists("lock:job"))
+
+	// Releasing twice reports the lock is gone
+	assert.ErrorIs(t, current.Release(ctx), ErrLockNotHeld)
+}
+
+func TestLock_ExpiryAndRefresh(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	ctx := context.Background()
+
+	lock, err := client.AcquireLock(ctx, "lock:job", 2*time.Second)
+	require.NoError(t, err)
+
+	// Refresh extends the TTL past the original expiry
+	require.NoError(t, lock.Refresh(ctx, 10*time.Second))
+	assert.Equal(t, 10*time.Second, mr.TTL("lock:job"))
+	mr.FastForward(5 * time.Second)
+	_, err = client.AcquireLock(ctx, "lock:job", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+	// Once expired, the lock can be taken again
+	mr.FastForward(6 * time.Second)
+	assert.False(t, mr.Exists("lock:job"))
+	next, err := client.AcquireLock(ctx, "lock:job", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, next.Release(ctx))
+}
+
+func TestLock_AcquireWait(t *testing.T) {
+	_, client := newLockTestClient(t)
+	ctx := context.Background()
+
+	held, err := client.AcquireLock(ctx, "lock:job", time.Minute)
+	require.NoError(t, err)
+
+	// Times out while the holder keeps the lock
+	start := time.Now()
+	_, err = client.AcquireLockWait(ctx, "lock:job", time.Minute, 100*time.Millisecond)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// Succeeds once the holder releases
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		held.Release(ctx)
+	}()
+	lock, err := client.AcquireLockWait(ctx, "lock:job", time.Minute, 2*time.Second)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release(ctx))
+
+	// Gives up when the context is cancelled
+	held, err = client.AcquireLock(ctx, "lock:job", time.Minute)
+	require.NoError(t, err)
+	cancelCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = client.AcquireLockWait(cancelCtx, "lock:job", time.Minute, 5*time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, held.Release(ctx))
+}
@@ -0,0 +1,49 @@
+package redis
+
+// Run priorities
+// A run's tokens go to its priority's variant of each worker stream (see
+// PriorityStream); consumer pools with WithPriorities drain them most urgent first.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// DefaultPriorityFairness is how often a priority pool serves the least urgent
+// stream first: every DefaultPriorityFairness-th read, so a steady flow of
+// high-priority tokens can delay low-priority ones but never starve them.
+const DefaultPriorityFairness = 10
+
+// priorityOrder lists the priorities from most to least urgent
+var priorityOrder = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+// ValidPriority returns true for a known priority, or "" for normal
+func ValidPriority(priority string) bool {
+	switch priority {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return true
+	default:
+		return false
+	}
+}
+
+// PriorityStream returns the stream tokens of the given priority are added to
+// Normal priority ("" included) uses stream itself, so workers that don't read
+// priorities keep consuming it; the others get a variant hash-tagged with the
+// base name, e.g. {wf.tasks.http}.high, which keeps every variant in the base
+// stream's cluster slot so one XREADGROUP can block on all of them.
+func PriorityStream(stream, priority string) string {
+	if priority == "" || priority == PriorityNormal {
+		return stream
+	}
+	return "{" + stream + "}." + priority
+}
+
+// PriorityStreams returns every priority variant of stream, most urgent first
+func PriorityStreams(stream string) []string {
+	streams := make([]string, len(priorityOrder))
+	for i, priority := range priorityOrder {
+		streams[i] = PriorityStream(stream, priority)
+	}
+	return streams
+}
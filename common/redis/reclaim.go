@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultReclaimMinIdle is how long a message may stay unacknowledged before
+	// its consumer is presumed dead. It must exceed the longest time a healthy
+	// consumer spends handling one message.
+	DefaultReclaimMinIdle = 2 * time.Minute
+
+	// DefaultReclaimInterval is how often pending entries are checked
+	DefaultReclaimInterval = 30 * time.Second
+
+	// reclaimBatchSize is the XAUTOCLAIM COUNT
+	reclaimBatchSize = 10
+)
+
+// MessageHandler processes one stream message
+type MessageHandler func(ctx context.Context, message redis.XMessage) error
+
+// ClaimIdleMessages takes over messages pending longer than minIdle (XAUTOCLAIM)
+// Scanning starts at start ("0-0" for the whole pending list); the returned
+// cursor continues the scan and is "0-0" once the list has been covered.
+func (c *Client) ClaimIdleMessages(ctx context.Context, stream, group, consumer string, minIdle time.Duration, start string, count int64) ([]redis.XMessage, string, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	messages, next, err := c.redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    start,
+		Count:    count,
+	}).Result()
+	err = c.finish(ctx, "xautoclaim", err)
+	if err != nil {
+		c.logger.Error("redis XAUTOCLAIM failed", "stream", stream, "group", group, "error", err)
+		return nil, "", fmt.Errorf("failed to claim idle messages from %s: %w", stream, err)
+	}
+
+	c.logger.Debug("redis XAUTOCLAIM", "stream", stream, "group", group, "consumer", consumer, "claimed", len(messages))
+	return messages, next, nil
+}
+
+// ReclaimOption customizes a Reclaimer
+type ReclaimOption func(*Reclaimer)
+
+// WithReclaimMinIdle sets how long a message must be pending before it is reclaimed
+func WithReclaimMinIdle(minIdle time.Duration) ReclaimOption {
+	return func(r *Reclaimer) {
+		r.minIdle = minIdle
+	}
+}
+
+// WithReclaimInterval sets how often pending entries are checked
+func WithReclaimInterval(interval time.Duration) ReclaimOption {
+	return func(r *Reclaimer) {
+		r.interval = interval
+	}
+}
+
+// Reclaimer hands messages orphaned by crashed consumers to a live one
+// A consumer that dies between XREADGROUP and XACK leaves its messages in the
+// group's pending list, where no other consumer ever sees them. The reclaimer
+// periodically claims entries idle longer than minIdle for its own consumer,
+// runs them through the same handler as the read loop and acknowledges them.
+// Handlers must therefore tolerate redelivery (e.g. SETNX idempotency keys).
+type Reclaimer struct {
+	client   *Client
+	stream   string
+	group    string
+	consumer string
+	handle   MessageHandler
+	minIdle  time.Duration
+	interval time.Duration
+}
+
+// NewReclaimer creates a reclaimer for one stream's consumer group
+func NewReclaimer(client *Client, stream, group, consumer string, handle MessageHandler, opts ...ReclaimOption) *Reclaimer {
+	r := &Reclaimer{
+		client:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+		handle:   handle,
+		minIdle:  DefaultReclaimMinIdle,
+		interval: DefaultReclaimInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run reclaims on every interval until ctx is cancelled
+func (r *Reclaimer) Run(ctx context.Context) {
+	r.client.logger.Info("pending message reclaim started",
+		"stream", r.stream,
+		"group", r.group,
+		"min_idle", r.minIdle,
+		"interval", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.client.logger.Info("pending message reclaim stopped", "stream", r.stream)
+			return
+		case <-ticker.C:
+			if _, err := r.ReclaimOnce(ctx); err != nil {
+				r.client.logger.Error("pending message reclaim failed", "stream", r.stream, "error", err)
+			}
+		}
+	}
+}
+
+// ReclaimOnce claims and processes every idle pending message, returning how many were handled
+func (r *Reclaimer) ReclaimOnce(ctx context.Context) (int, error) {
+	handled := 0
+	start := "0-0"
+
+	for {
+		messages, next, err := r.client.ClaimIdleMessages(ctx, r.stream, r.group, r.consumer, r.minIdle, start, reclaimBatchSize)
+		if err != nil {
+			return handled, err
+		}
+
+		for _, message := range messages {
+			// Entries trimmed from the stream come back without values; just ack them
+			if message.Values != nil {
+				r.client.logger.Warn("reclaimed pending message",
+					"stream", r.stream,
+					"group", r.group,
+					"message_id", message.ID)
+
+				metrics.StreamMessagesConsumed.Inc(r.stream)
+				if err := r.handle(ctx, message); err != nil {
+					r.client.logger.Error("failed to handle reclaimed message", "stream", r.stream, "message_id", message.ID, "error", err)
+				}
+				handled++
+			}
+
+			if err := r.client.AckStreamMessage(ctx, r.stream, r.group, message.ID); err != nil {
+				r.client.logger.Error("failed to ACK reclaimed message", "stream", r.stream, "message_id", message.ID, "error", err)
+			}
+		}
+
+		if next == "" || next == "0-0" || ctx.Err() != nil {
+			return handled, ctx.Err()
+		}
+		start = next
+	}
+}
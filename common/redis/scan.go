@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultScanBatch is the COUNT hint of each SCAN call unless a batch is given
+const DefaultScanBatch int64 = 100
+
+// ScanKeys lists keys matching pattern, optionally only those of keyType ("" for any)
+// See ScanKeysFunc; prefer it when the matches may not fit in memory.
+func (c *Client) ScanKeys(ctx context.Context, pattern, keyType string) ([]string, error) {
+	var keys []string
+	err := c.ScanKeysFunc(ctx, pattern, keyType, DefaultScanBatch, func(batch []string) error {
+		keys = append(keys, batch...)
+		return nil
+	})
+	return keys, err
+}
+
+// ScanKeysFunc calls fn with each page of keys matching pattern, as SCAN returns them
+// Optionally only keys of keyType ("" for any). batch is the COUNT hint of each
+// SCAN call (DefaultScanBatch if 0): pages may hold fewer or more keys, and are
+// never empty. Unlike KEYS, SCAN doesn't block Redis while the keyspace is
+// walked. In cluster mode every master is scanned, since SCAN only covers one
+// node; fn is never called concurrently. An error from fn stops the scan.
+// Not bounded by the operation timeout: a full keyspace walk may take a while.
+func (c *Client) ScanKeysFunc(ctx context.Context, pattern, keyType string, batch int64, fn func(keys []string) error) error {
+	if batch <= 0 {
+		batch = DefaultScanBatch
+	}
+
+	var mu sync.Mutex
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := client.ScanType(ctx, cursor, pattern, batch, keyType).Result()
+			err = c.finish(ctx, "scan", err)
+			if err != nil {
+				c.logger.Error("redis SCAN failed", "pattern", pattern, "error", err)
+				return fmt.Errorf("failed to scan keys matching %s: %w", pattern, err)
+			}
+			if len(keys) > 0 {
+				mu.Lock()
+				err := fn(keys)
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	cluster, ok := c.redis.(*redis.ClusterClient)
+	if !ok {
+		return scan(ctx, c.redis)
+	}
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scan(ctx, node)
+	})
+}
+
+// DeleteByPattern deletes every key matching pattern and returns how many it found
+// SCAN may report a key twice, so the count is an upper bound. Keys are found with SCAN and deleted a page at a time (see Delete for
+// cluster mode), so matches never have to fit in memory. Keys created
+// matching pattern while it runs may survive.
+func (c *Client) DeleteByPattern(ctx context.Context, pattern string) (int64, error) {
+	var deleted int64
+	err := c.ScanKeysFunc(ctx, pattern, "", DefaultScanBatch, func(keys []string) error {
+		if err := c.Delete(ctx, keys...); err != nil {
+			return err
+		}
+		deleted += int64(len(keys))
+		return nil
+	})
+	if err != nil {
+		return deleted, err
+	}
+	c.logger.Debug("redis DEL by pattern", "pattern", pattern, "deleted", deleted)
+	return deleted, nil
+}
+
+// ExpireByPattern sets every key matching pattern to expire after ttl
+// Returns how many keys it found, like DeleteByPattern.
+func (c *Client) ExpireByPattern(ctx context.Context, pattern string, ttl time.Duration) (int64, error) {
+	var expired int64
+	err := c.ScanKeysFunc(ctx, pattern, "", DefaultScanBatch, func(keys []string) error {
+		if err := c.Expire(ctx, ttl, keys...); err != nil {
+			return err
+		}
+		expired += int64(len(keys))
+		return nil
+	})
+	return expired, err
+}
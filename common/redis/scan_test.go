@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedScan makes SCAN return at most size keys per call, with a real cursor
+// miniredis returns every match at once, which would never exercise paging.
+// Like Redis, keys present for the whole scan are returned even if others are
+// deleted meanwhile: pages are cut from the matches when the scan started.
+type pagedScan struct {
+	size     int
+	snapshot []string
+}
+
+func (*pagedScan) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (*pagedScan) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *pagedScan) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		scan, ok := cmd.(*redis.ScanCmd)
+		if !ok {
+			return next(ctx, cmd)
+		}
+		offset := int(cmd.Args()[1].(uint64))
+		if offset == 0 {
+			if err := next(ctx, cmd); err != nil {
+				return err
+			}
+			h.snapshot, _ = scan.Val()
+		}
+
+		end := min(offset+h.size, len(h.snapshot))
+		cursor := uint64(end)
+		if end == len(h.snapshot) {
+			cursor = 0
+		}
+		scan.SetVal(h.snapshot[offset:end], cursor)
+		return nil
+	}
+}
+
+func TestScanKeysFunc_AllPages(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	client.GetUnderlying().AddHook(&pagedScan{size: 20})
+	want := make([]string, 0, 250)
+	for i := 0; i < 250; i++ {
+		key := fmt.Sprintf("context:run-%03d", i)
+		mr.Set(key, "{}")
+		want = append(want, key)
+	}
+	for i := 0; i < 50; i++ {
+		mr.Set(fmt.Sprintf("ir:run-%03d", i), "{}")
+	}
+
+	var got []string
+	pages := 0
+	err := client.ScanKeysFunc(context.Background(), "context:*", "", 20, func(keys []string) error {
+		assert.NotEmpty(t, keys)
+		pages++
+		got = append(got, keys...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Greater(t, pages, 1, "expected several SCAN iterations")
+	assert.ElementsMatch(t, want, got)
+
+	keys, err := client.ScanKeys(context.Background(), "context:*", "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, keys)
+
+	t.Run("callback error stops the scan", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := client.ScanKeysFunc(context.Background(), "context:*", "", 20, func([]string) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestDeleteByPattern(t *testing.T) {
+	mr, client := newLockTestClient(t)
+	client.GetUnderlying().AddHook(&pagedScan{size: 50})
+	for i := 0; i < 120; i++ {
+		mr.Set(fmt.Sprintf("loop:run-1:node-%03d", i), "1")
+	}
+	mr.Set("loop:run-2:node-000", "1")
+	mr.Set("ir:run-1", "{}")
+
+	deleted, err := client.DeleteByPattern(context.Background(), "loop:run-1:*")
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), deleted)
+
+	assert.Equal(t, []string{"ir:run-1", "loop:run-2:node-000"}, mr.Keys())
+}
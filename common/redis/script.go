@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Script is a Lua script bound to a client
+// It runs by SHA (EVALSHA) and falls back to EVAL when the server's script
+// cache doesn't have it yet (first run, restart, failover, SCRIPT FLUSH). EVAL
+// also loads the script, so later runs go back to EVALSHA.
+type Script struct {
+	client *Client
+	src    string
+	sha    string
+}
+
+// LoadScript prepares a Lua script for execution
+// The SHA is computed locally; nothing is sent to Redis until the first Run.
+func (c *Client) LoadScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{
+		client: c,
+		src:    src,
+		sha:    hex.EncodeToString(sum[:]),
+	}
+}
+
+// SHA returns the script's SHA1 digest
+func (s *Script) SHA() string {
+	return s.sha
+}
+
+// Run executes the script with EVALSHA, retrying with EVAL on NOSCRIPT
+// In cluster mode all keys must hash to the same slot (see HashTag).
+func (s *Script) Run(ctx context.Context, keys []string, args ...interface{}) *redis.Cmd {
+	ctx, cancel := s.client.withTimeout(ctx)
+	defer cancel()
+
+	cmd := s.client.redis.EvalSha(ctx, s.sha, keys, args...)
+	if err := cmd.Err(); err != nil && isNoScript(err) {
+		s.client.logger.Debug("redis script not cached, falling back to EVAL", "sha", s.sha)
+		cmd = s.client.redis.Eval(ctx, s.src, keys, args...)
+	}
+	cmd.SetErr(s.client.finish(ctx, "evalsha", cmd.Err()))
+
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		s.client.logger.Error("redis script failed", "sha", s.sha, "keys", keys, "error", err)
+	}
+	return cmd
+}
+
+func isNoScript(err error) bool {
+	return strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
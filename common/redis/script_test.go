@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const incrByScript = `
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+return value
+`
+
+func TestScript_RunWithEvalFallback(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	client := NewClient(rdb, noopLogger{})
+	ctx := context.Background()
+
+	script := client.LoadScript(incrByScript)
+	loaded, err := rdb.ScriptLoad(ctx, incrByScript).Result()
+	require.NoError(t, err)
+	assert.Equal(t, loaded, script.SHA())
+
+	// Runs by SHA while cached
+	value, err := script.Run(ctx, []string{"counter"}, 5).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+
+	// After a flush EVALSHA fails with NOSCRIPT and Run falls back to EVAL
+	require.NoError(t, rdb.ScriptFlush(ctx).Err())
+	err = rdb.EvalSha(ctx, script.SHA(), []string{"counter"}, 1).Err()
+	require.Error(t, err)
+	assert.True(t, isNoScript(err))
+
+	value, err = script.Run(ctx, []string{"counter"}, 3).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), value)
+
+	// The fallback reloads the script for subsequent EVALSHA calls
+	exists, err := rdb.ScriptExists(ctx, script.SHA()).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true}, exists)
+}
+
+func TestScript_RunErrors(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	client := NewClient(rdb, noopLogger{})
+	ctx := context.Background()
+
+	// Script errors surface as is and aren't retried as NOSCRIPT
+	mr.Set("counter", "not a number")
+	err := client.LoadScript(incrByScript).Run(ctx, []string{"counter"}, 1).Err()
+	require.Error(t, err)
+	assert.False(t, isNoScript(err))
+
+	// Scripts returning nil report redis.Nil
+	err = client.LoadScript(`return redis.call("GET", KEYS[1])`).Run(ctx, []string{"missing"}).Err()
+	assert.ErrorIs(t, err, redis.Nil)
+}
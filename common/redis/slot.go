@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// clusterSlots is the number of hash slots in a Redis Cluster
+const clusterSlots = 16384
+
+// KeySlot returns the cluster hash slot for a key, computed locally
+// Follows the Redis Cluster spec: if the key contains a non-empty {...} section,
+// only that part is hashed (see HashTag).
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// groupBySlot splits keys into per-slot batches, preserving order within each batch
+func groupBySlot(keys []string) [][]string {
+	index := make(map[int]int)
+	var groups [][]string
+	for _, key := range keys {
+		slot := KeySlot(key)
+		i, ok := index[slot]
+		if !ok {
+			i = len(groups)
+			index[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// isCluster reports whether the client talks to a Redis Cluster
+func isCluster(client redis.UniversalClient) bool {
+	_, ok := client.(*redis.ClusterClient)
+	return ok
+}
+
+// crc16 is CRC-16/XMODEM (poly 0x1021, init 0), the checksum Redis Cluster uses
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
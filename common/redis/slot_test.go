@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger captures warnings
+type recordingLogger struct {
+	noopLogger
+	mu    sync.Mutex
+	warns []string
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, msg)
+}
+
+func TestKeySlot(t *testing.T) {
+	// Reference values from CLUSTER KEYSLOT on a real cluster
+	assert.Equal(t, 12182, KeySlot("foo"))
+	assert.Equal(t, 5061, KeySlot("bar"))
+	assert.Equal(t, 11058, KeySlot("somekey"))
+	assert.Equal(t, 0, KeySlot(""))
+	// CRC16/XMODEM check value 0x31C3, already below 16384
+	assert.Equal(t, 12739, KeySlot("123456789"))
+
+	// Only the hash tag is hashed
+	assert.Equal(t, 3443, KeySlot("user1000"))
+	assert.Equal(t, 3443, KeySlot("{user1000}.following"))
+	assert.Equal(t, 2515, KeySlot("foo{hash_tag}"))
+	assert.Equal(t, KeySlot("run_1"), KeySlot("counter:"+HashTag("run_1")))
+	assert.Equal(t, KeySlot("run_1"), KeySlot("applied:"+HashTag("run_1")))
+
+	// Empty tags hash the whole key, and only the first {...} counts
+	assert.Equal(t, 8363, KeySlot("foo{}{bar}"))
+	assert.Equal(t, 15257, KeySlot("{}"))
+	assert.Equal(t, 5061, KeySlot("foo{bar}{zap}"))
+}
+
+func TestClusterClient_SlotAwareMultiKeyOps(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	rdb, err := NewUniversalClient(&Config{Mode: ModeCluster, ClusterAddrs: []string{mr.Addr()}})
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	log := &recordingLogger{}
+	client := NewClient(rdb, log)
+
+	// DEL across slots is split per slot
+	require.NotEqual(t, KeySlot("foo"), KeySlot("bar"))
+	require.NoError(t, client.Set(ctx, "foo", "1", time.Minute))
+	require.NoError(t, client.Set(ctx, "bar", "2", time.Minute))
+	require.NoError(t, client.Delete(ctx, "foo", "bar"))
+	assert.False(t, mr.Exists("foo"))
+	assert.False(t, mr.Exists("bar"))
+
+	// BLPOP across slots is rejected up front
+	_, err = client.BlockingPopList(ctx, 10*time.Millisecond, "foo", "bar")
+	assert.ErrorContains(t, err, "multiple cluster slots")
+
+	// BLPOP on same-slot keys works
+	require.NoError(t, client.PushToList(ctx, "queue:"+HashTag("run_1"), "a"))
+	popped, err := client.BlockingPopList(ctx, 10*time.Millisecond, "queue:"+HashTag("run_1"), "retry:"+HashTag("run_1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"queue:" + HashTag("run_1"), "a"}, popped)
+
+	// Same-slot transaction: no warning
+	tx := client.NewTransaction()
+	tx.Incr(ctx, "pending:"+HashTag("run_1"))
+	tx.Incr(ctx, "applied:"+HashTag("run_1"))
+	require.NoError(t, tx.Exec(ctx))
+	assert.Empty(t, log.warns)
+
+	// Cross-slot transaction runs as one MULTI/EXEC per slot and is flagged as non-atomic
+	tx = client.NewTransaction()
+	fooLabel := tx.Incr(ctx, "foo")
+	barLabel := tx.Decr(ctx, "bar")
+	require.NoError(t, tx.Exec(ctx))
+	assert.Len(t, log.warns, 1)
+	foo, err := tx.GetIntResult(fooLabel)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), foo)
+	bar, err := tx.GetIntResult(barLabel)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), bar)
+}
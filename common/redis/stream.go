@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/lyzr/orchestrator/common/tracing"
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamOption customizes an XADD
+type StreamOption func(*redis.XAddArgs)
+
+// WithMaxLen caps the stream at roughly maxLen entries (XADD MAXLEN ~)
+// The approximate form lets Redis trim whole macro nodes, so the stream may
+// briefly hold a few more entries than maxLen. Like any MAXLEN trim it drops
+// the oldest entries whether or not consumer groups acknowledged them; use it
+// on streams without consumer groups, or with a cap far above the expected
+// backlog, and rely on StreamTrimmer for consumer group streams.
+func WithMaxLen(maxLen int64) StreamOption {
+	return func(args *redis.XAddArgs) {
+		args.MaxLen = maxLen
+		args.Approx = true
+	}
+}
+
+// xAddArgs builds the XADD for AddToStream
+// The current span, if any, travels with the message so consumers can continue the trace.
+func xAddArgs(ctx context.Context, stream string, values map[string]interface{}, opts []StreamOption) *redis.XAddArgs {
+	if tracing.HasSpan(ctx) {
+		traced := make(map[string]interface{}, len(values)+1)
+		for key, value := range values {
+			traced[key] = value
+		}
+		tracing.InjectValues(ctx, traced)
+		values = traced
+	}
+
+	args := &redis.XAddArgs{
+		Stream: stream,
+		Values: values,
+	}
+	for _, opt := range opts {
+		opt(args)
+	}
+	return args
+}
@@ -0,0 +1,164 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+func newStreamTestClient(t *testing.T) (redis.UniversalClient, *Client) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, NewClient(rdb, noopLogger{})
+}
+
+func newTestTrimmer(client *Client, maxLen int64, streams ...string) *StreamTrimmer {
+	return NewStreamTrimmer(client, config.StreamTrimConfig{
+		Enabled:  true,
+		Interval: time.Minute,
+		MaxLen:   maxLen,
+		Streams:  streams,
+	})
+}
+
+func TestAddToStream_MaxLenBoundsLength(t *testing.T) {
+	rdb, client := newStreamTestClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 500; i++ {
+		_, err := client.AddToStream(ctx, "events", map[string]interface{}{"i": i}, WithMaxLen(50))
+		require.NoError(t, err)
+
+		pipe := client.NewPipeline()
+		pipe.AddToStream(ctx, "events:piped", map[string]interface{}{"i": i}, WithMaxLen(50))
+		require.NoError(t, pipe.Exec(ctx))
+	}
+
+	// Approximate trimming may keep a few extra entries, but never grows unbounded
+	for _, stream := range []string{"events", "events:piped"} {
+		length, err := rdb.XLen(ctx, stream).Result()
+		require.NoError(t, err)
+		assert.LessOrEqual(t, length, int64(150), stream)
+		assert.GreaterOrEqual(t, length, int64(50), stream)
+	}
+
+	// Without the option nothing is trimmed
+	for i := 0; i < 200; i++ {
+		_, err := client.AddToStream(ctx, "uncapped", map[string]interface{}{"i": i})
+		require.NoError(t, err)
+	}
+	length, err := rdb.XLen(ctx, "uncapped").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), length)
+}
+
+func TestStreamTrimmer_BoundsStreams(t *testing.T) {
+	rdb, client := newStreamTestClient(t)
+	ctx := context.Background()
+	trimmer := newTestTrimmer(client, 20, "wf.tasks.*", "wf.run.requests", "missing")
+
+	// A plain key matching the pattern is not a stream and is left alone
+	require.NoError(t, rdb.Set(ctx, "wf.tasks.config", "x", 0).Err())
+
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 100; i++ {
+			for _, stream := range []string{"wf.tasks.agent", "wf.tasks.http", "wf.run.requests", "other"} {
+				_, err := client.AddToStream(ctx, stream, map[string]interface{}{"i": i})
+				require.NoError(t, err)
+			}
+		}
+
+		_, err := trimmer.TrimOnce(ctx)
+		require.NoError(t, err)
+
+		for _, stream := range []string{"wf.tasks.agent", "wf.tasks.http", "wf.run.requests"} {
+			length, err := rdb.XLen(ctx, stream).Result()
+			require.NoError(t, err)
+			assert.Equal(t, int64(20), length, stream)
+		}
+	}
+
+	// Streams outside the configured set are untouched
+	length, err := rdb.XLen(ctx, "other").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), length)
+}
+
+func TestStreamTrimmer_KeepsUnackedEntries(t *testing.T) {
+	rdb, client := newStreamTestClient(t)
+	ctx := context.Background()
+	trimmer := newTestTrimmer(client, 10, "wf.tasks.agent")
+
+	require.NoError(t, rdb.XGroupCreateMkStream(ctx, "wf.tasks.agent", "agent_workers", "0").Err())
+	ids := make([]string, 100)
+	for i := range ids {
+		id, err := client.AddToStream(ctx, "wf.tasks.agent", map[string]interface{}{"i": i})
+		require.NoError(t, err)
+		ids[i] = id
+	}
+
+	// Entries 0-49 delivered, 0-29 acknowledged; 30-49 pending, 50-99 undelivered
+	_, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "agent_workers", Consumer: "w1", Streams: []string{"wf.tasks.agent", ">"}, Count: 50,
+	}).Result()
+	require.NoError(t, err)
+	require.NoError(t, rdb.XAck(ctx, "wf.tasks.agent", "agent_workers", ids[:30]...).Err())
+
+	trimmed, err := trimmer.TrimOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), trimmed["wf.tasks.agent"])
+
+	// Only acknowledged entries went; the oldest pending entry is now the head
+	entries, err := rdb.XRange(ctx, "wf.tasks.agent", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 70)
+	assert.Equal(t, ids[30], entries[0].ID)
+
+	pending, err := rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: "wf.tasks.agent", Group: "agent_workers", Start: "-", End: "+", Count: 100,
+	}).Result()
+	require.NoError(t, err)
+	assert.Len(t, pending, 20)
+
+	// Once everything is delivered and acknowledged the cap applies
+	_, err = rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group: "agent_workers", Consumer: "w1", Streams: []string{"wf.tasks.agent", ">"}, Count: 100,
+	}).Result()
+	require.NoError(t, err)
+	require.NoError(t, rdb.XAck(ctx, "wf.tasks.agent", "agent_workers", ids[30:]...).Err())
+
+	_, err = trimmer.TrimOnce(ctx)
+	require.NoError(t, err)
+	length, err := rdb.XLen(ctx, "wf.tasks.agent").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), length)
+
+	// A second group that hasn't read anything yet holds everything back
+	require.NoError(t, rdb.XGroupCreate(ctx, "wf.tasks.agent", "auditors", "0").Err())
+	for i := 0; i < 20; i++ {
+		_, err := client.AddToStream(ctx, "wf.tasks.agent", map[string]interface{}{"i": fmt.Sprint("late", i)})
+		require.NoError(t, err)
+	}
+	_, err = trimmer.TrimOnce(ctx)
+	require.NoError(t, err)
+	length, err = rdb.XLen(ctx, "wf.tasks.agent").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), length)
+}
+
+func TestCompareStreamIDs(t *testing.T) {
+	assert.Equal(t, -1, compareStreamIDs("1-0", "2-0"))
+	assert.Equal(t, -1, compareStreamIDs("5-2", "5-10"))
+	assert.Equal(t, 1, compareStreamIDs("10-0", "9-99"))
+	assert.Equal(t, 0, compareStreamIDs("7-3", "7-3"))
+	assert.Equal(t, -1, compareStreamIDs("0-0", "1-1"))
+}
@@ -0,0 +1,210 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/config"
+)
+
+// StreamTrimmer periodically trims streams to a target length
+// Consumer groups are respected: an entry is only trimmed once every group has
+// had it delivered and acknowledged, so a stream whose consumers lag behind
+// stays above MaxLen until they catch up. Entries below that floor (the oldest
+// pending entry or the last delivered one, whichever is older, across groups)
+// are never dropped.
+type StreamTrimmer struct {
+	client   *Client
+	interval time.Duration
+	maxLen   int64
+	streams  []string
+}
+
+// NewStreamTrimmer creates a stream trimmer from config
+func NewStreamTrimmer(client *Client, cfg config.StreamTrimConfig) *StreamTrimmer {
+	return &StreamTrimmer{
+		client:   client,
+		interval: cfg.Interval,
+		maxLen:   cfg.MaxLen,
+		streams:  cfg.Streams,
+	}
+}
+
+// Run trims on every interval until ctx is cancelled
+func (t *StreamTrimmer) Run(ctx context.Context) {
+	t.client.logger.Info("stream trimming started", "interval", t.interval, "max_len", t.maxLen, "streams", t.streams)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.client.logger.Info("stream trimming stopped")
+			return
+		case <-ticker.C:
+			if _, err := t.TrimOnce(ctx); err != nil {
+				t.client.logger.Error("stream trim pass failed", "error", err)
+			}
+		}
+	}
+}
+
+// TrimOnce trims every configured stream and returns the entries removed per stream
+func (t *StreamTrimmer) TrimOnce(ctx context.Context) (map[string]int64, error) {
+	streams, err := t.resolveStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := make(map[string]int64, len(streams))
+	for _, stream := range streams {
+		n, err := t.trimStream(ctx, stream)
+		if err != nil {
+			t.client.logger.Warn("failed to trim stream", "stream", stream, "error", err)
+			continue
+		}
+		if n > 0 {
+			trimmed[stream] = n
+		}
+	}
+
+	t.client.logger.Debug("stream trim pass complete", "streams", len(streams), "trimmed", trimmed)
+	return trimmed, nil
+}
+
+// trimStream trims one stream down to maxLen without crossing the consumer group floor
+func (t *StreamTrimmer) trimStream(ctx context.Context, stream string) (int64, error) {
+	// 1. Nothing to do while under the cap
+	length, err := t.client.redis.XLen(ctx, stream).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stream length: %w", err)
+	}
+	if length <= t.maxLen {
+		return 0, nil
+	}
+
+	// 2. The oldest entry kept by the cap
+	newest, err := t.client.redis.XRevRangeN(ctx, stream, "+", "-", t.maxLen).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stream tail: %w", err)
+	}
+	if len(newest) == 0 {
+		return 0, nil
+	}
+	minID := newest[len(newest)-1].ID
+
+	// 3. Never trim past what a consumer group still needs
+	floor, err := t.groupFloor(ctx, stream)
+	if err != nil {
+		return 0, err
+	}
+	if floor != "" && compareStreamIDs(floor, minID) < 0 {
+		minID = floor
+	}
+
+	// 4. Exact MINID trim: everything strictly older than minID goes
+	removed, err := t.client.redis.XTrimMinID(ctx, stream, minID).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim stream: %w", err)
+	}
+
+	if removed > 0 {
+		t.client.logger.Debug("redis XTRIM", "stream", stream, "min_id", minID, "removed", removed)
+	}
+	return removed, nil
+}
+
+// groupFloor returns the oldest entry any consumer group may still need
+// For each group that is its oldest pending (delivered, unacked) entry, or its
+// last delivered entry when nothing is pending. Returns "" without groups.
+func (t *StreamTrimmer) groupFloor(ctx context.Context, stream string) (string, error) {
+	groups, err := t.client.redis.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect consumer groups: %w", err)
+	}
+
+	floor := ""
+	for _, group := range groups {
+		candidate := group.LastDeliveredID
+		if group.Pending > 0 {
+			pending, err := t.client.redis.XPending(ctx, stream, group.Name).Result()
+			if err != nil {
+				return "", fmt.Errorf("failed to inspect pending entries of %s: %w", group.Name, err)
+			}
+			if compareStreamIDs(pending.Lower, candidate) < 0 {
+				candidate = pending.Lower
+			}
+		}
+
+		if floor == "" || compareStreamIDs(candidate, floor) < 0 {
+			floor = candidate
+		}
+	}
+
+	return floor, nil
+}
+
+// resolveStreams expands glob patterns into existing stream keys
+func (t *StreamTrimmer) resolveStreams(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var streams []string
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			streams = append(streams, key)
+		}
+	}
+
+	for _, pattern := range t.streams {
+		if !strings.ContainsAny(pattern, "*?[") {
+			add(pattern)
+			continue
+		}
+
+		keys, err := t.scanStreams(ctx, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan streams %s: %w", pattern, err)
+		}
+		for _, key := range keys {
+			add(key)
+		}
+	}
+
+	return streams, nil
+}
+
+// scanStreams lists stream keys matching pattern
+func (t *StreamTrimmer) scanStreams(ctx context.Context, pattern string) ([]string, error) {
+	return t.client.ScanKeys(ctx, pattern, "stream")
+}
+
+// compareStreamIDs orders two "ms-seq" stream IDs
+func compareStreamIDs(a, b string) int {
+	aMs, aSeq := parseStreamID(a)
+	bMs, bSeq := parseStreamID(b)
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseStreamID(id string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
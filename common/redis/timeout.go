@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultOperationTimeout bounds each non-blocking Client operation
+const DefaultOperationTimeout = 2 * time.Second
+
+// ErrOperationTimeout is returned when an operation exceeds the client's operation timeout
+// A caller's own deadline or cancellation is reported as the context error instead.
+var ErrOperationTimeout = errors.New("redis operation timed out")
+
+// ClientOption customizes a Client
+type ClientOption func(*Client)
+
+// WithOperationTimeout sets the per-operation timeout (0 disables it)
+// Blocking reads (XREADGROUP with BLOCK, BLPOP) are not bounded by it: they
+// carry their own block timeout.
+func WithOperationTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.opTimeout = timeout
+	}
+}
+
+// withTimeout derives the context for one non-blocking operation
+// The caller's deadline still wins when it is earlier.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, c.opTimeout, ErrOperationTimeout)
+}
+
+// timeoutError reports err as ErrOperationTimeout when the operation timeout cut it short
+// go-redis surfaces an expired deadline as a network timeout or a context
+// error depending on where it hit, so the context's cause is checked instead.
+// The socket deadline can fire just before the context's timer, so once the
+// deadline has passed the context is given the moment it needs to close.
+func timeoutError(ctx context.Context, err error) error {
+	if err == nil || err == redis.Nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		<-ctx.Done()
+	}
+	if errors.Is(context.Cause(ctx), ErrOperationTimeout) {
+		return fmt.Errorf("%w: %v", ErrOperationTimeout, err)
+	}
+	return err
+}
+
+// finish reports operation timeouts and counts the operation in metrics
+func (c *Client) finish(ctx context.Context, command string, err error) error {
+	err = timeoutError(ctx, err)
+	metrics.RedisCommands.Inc(command)
+	if err != nil && err != redis.Nil {
+		metrics.RedisCommandErrors.Inc(command)
+	}
+	return err
+}
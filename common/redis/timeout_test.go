@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHungServer accepts connections but never replies, like a stalled Redis
+func newHungServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func newHungClient(t *testing.T, opts ...ClientOption) *Client {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:                  newHungServer(t),
+		ContextTimeoutEnabled: true,
+		ReadTimeout:           time.Minute, // only the operation timeout may end the call
+		MaxRetries:            -1,
+	})
+	t.Cleanup(func() { rdb.Close() })
+	return NewClient(rdb, noopLogger{}, opts...)
+}
+
+func TestClient_OperationTimeout(t *testing.T) {
+	client := newHungClient(t, WithOperationTimeout(100*time.Millisecond))
+	ctx := context.Background()
+
+	operations := map[string]func() error{
+		"get": func() error {
+			_, err := client.Get(ctx, "key")
+			return err
+		},
+		"set": func() error {
+			return client.Set(ctx, "key", "value", 0)
+		},
+		"xadd": func() error {
+			_, err := client.AddToStream(ctx, "stream", map[string]interface{}{"k": "v"})
+			return err
+		},
+		"pipeline": func() error {
+			pipe := client.NewPipeline()
+			pipe.SetWithExpiry(ctx, "key", "value", time.Minute)
+			return pipe.Exec(ctx)
+		},
+		"transaction": func() error {
+			tx := client.NewTransaction()
+			tx.Incr(ctx, "counter")
+			return tx.Exec(ctx)
+		},
+		"script": func() error {
+			return client.LoadScript("return 1").Run(ctx, nil).Err()
+		},
+	}
+
+	for name, op := range operations {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			err := op()
+			assert.ErrorIs(t, err, ErrOperationTimeout)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestClient_CallerDeadlineWins(t *testing.T) {
+	client := newHungClient(t, WithOperationTimeout(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The caller's own deadline is not reported as an operation timeout
+	_, err := client.Get(ctx, "key")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrOperationTimeout))
+}
+
+func TestClient_BlockingReadsIgnoreOperationTimeout(t *testing.T) {
+	_, client := newLockTestClient(t)
+	client.opTimeout = 50 * time.Millisecond
+	ctx := context.Background()
+
+	require.NoError(t, client.CreateStreamGroup(ctx, "stream", "group"))
+
+	// Blocking for longer than the operation timeout is an empty read, not an error
+	streams, err := client.ReadFromStreamGroup(ctx, "group", "consumer", "stream", 1, 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.Nil(t, streams)
+
+	result, err := client.BlockingPopList(ctx, time.Second, "list")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lyzr/orchestrator/common/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStreamCarriesTraceContext publishes inside a span and checks the
+// consumer's span becomes its child across the stream
+func TestStreamCarriesTraceContext(t *testing.T) {
+	_, client := newLockTestClient(t)
+	recorder, restore := tracing.Record()
+	defer restore()
+
+	ctx := context.Background()
+	require.NoError(t, client.CreateStreamGroup(ctx, "wf.tasks.http", "workers"))
+
+	values := map[string]interface{}{"token": `{"run_id":"run-1","to_node":"A"}`}
+	pubCtx, publish := tracing.StartSpan(ctx, "token.publish", tracing.AttrRunID, "run-1", tracing.AttrStream, "wf.tasks.http")
+	_, err := client.AddToStream(pubCtx, "wf.tasks.http", values)
+	require.NoError(t, err)
+	publish.End()
+	assert.NotContains(t, values, tracing.TraceParentField, "caller's values must not be modified")
+
+	streams, err := client.ReadFromStreamGroup(ctx, "workers", "worker-1", "wf.tasks.http", 1, 0)
+	require.NoError(t, err)
+	require.Len(t, streams, 1)
+	require.Len(t, streams[0].Messages, 1)
+	message := streams[0].Messages[0]
+	assert.Equal(t, tracing.TraceParent(pubCtx), message.Values[tracing.TraceParentField])
+
+	consumeCtx := tracing.ExtractValues(ctx, message.Values)
+	_, execute := tracing.StartSpan(consumeCtx, "node.execute",
+		tracing.AttrRunID, "run-1",
+		tracing.AttrNodeID, "A",
+		tracing.AttrNodeType, "http",
+		tracing.AttrStream, "wf.tasks.http")
+	execute.End()
+
+	spans := recorder.SpansNamed("node.execute")
+	require.Len(t, spans, 1)
+	assert.Equal(t, publish.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	assert.Equal(t, publish.SpanContext().TraceID(), spans[0].SpanContext.TraceID())
+	assert.True(t, spans[0].Parent.IsRemote())
+	assert.Equal(t, "A", tracing.Attribute(spans[0], tracing.AttrNodeID))
+	assert.Equal(t, "http", tracing.Attribute(spans[0], tracing.AttrNodeType))
+
+	// Without a span nothing is injected
+	_, err = client.AddToStream(ctx, "wf.tasks.http", map[string]interface{}{"token": "{}"})
+	require.NoError(t, err)
+	streams, err = client.ReadFromStreamGroup(ctx, "workers", "worker-1", "wf.tasks.http", 1, 0)
+	require.NoError(t, err)
+	assert.NotContains(t, streams[0].Messages[0].Values, tracing.TraceParentField)
+}
@@ -0,0 +1,290 @@
+package redis
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Mode selects the Redis deployment topology
+type Mode string
+
+const (
+	// ModeStandalone connects to a single Redis server (default)
+	ModeStandalone Mode = "standalone"
+	// ModeSentinel connects to a master discovered through Redis Sentinel
+	ModeSentinel Mode = "sentinel"
+	// ModeCluster connects to a Redis Cluster
+	ModeCluster Mode = "cluster"
+)
+
+// Config holds connection settings for any supported topology
+//
+// Cluster mode note: multi-key commands (Lua scripts, MULTI/EXEC) only work when
+// every key hashes to the same slot. Per-run keys that are touched together must
+// share a hash tag, e.g. "counter:{run_123}" and "applied:{run_123}". See HashTag.
+type Config struct {
+	Mode          Mode
+	Addr          string   // standalone: host:port
+	SentinelAddrs []string // sentinel: sentinel host:port list
+	MasterName    string   // sentinel: monitored master name
+	ClusterAddrs  []string // cluster: seed node host:port list
+	Username      string   // ACL username (Redis 6+)
+	Password      string
+	DB            int // ignored in cluster mode
+
+	// OperationTimeout bounds each non-blocking Client operation (0 disables it)
+	OperationTimeout time.Duration
+
+	// TLS settings. CACert, ClientCert, and ClientKey are PEM file paths.
+	// With TLS enabled and no CACert, the system root pool is used.
+	TLS        bool
+	CACert     string
+	ClientCert string
+	ClientKey  string
+}
+
+// ConfigFromEnv builds a Config from environment variables
+//
+//	REDIS_MODE            standalone | sentinel | cluster (default: standalone)
+//	REDIS_HOST/REDIS_PORT standalone address (default: localhost:6379)
+//	REDIS_SENTINEL_ADDRS  comma-separated sentinel addresses
+//	REDIS_MASTER_NAME     sentinel master name
+//	REDIS_CLUSTER_ADDRS   comma-separated cluster seed addresses
+//	REDIS_USERNAME        ACL username
+//	REDIS_PASSWORD        password for the data nodes
+//	REDIS_TLS             "true" to enable TLS
+//	REDIS_CA_CERT         CA bundle used to verify the server
+//	REDIS_CLIENT_CERT     client certificate for mutual TLS (requires REDIS_CLIENT_KEY)
+//	REDIS_CLIENT_KEY      client private key for mutual TLS (requires REDIS_CLIENT_CERT)
+//	REDIS_OPERATION_TIMEOUT per-operation timeout, e.g. "500ms" (default: 2s, "0" disables)
+func ConfigFromEnv() (*Config, error) {
+	useTLS := false
+	if value := os.Getenv("REDIS_TLS"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_TLS value %q: %w", value, err)
+		}
+		useTLS = parsed
+	}
+
+	operationTimeout := DefaultOperationTimeout
+	if value := os.Getenv("REDIS_OPERATION_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid REDIS_OPERATION_TIMEOUT value %q", value)
+		}
+		operationTimeout = parsed
+	}
+
+	cfg := &Config{
+		Mode:          Mode(strings.ToLower(getEnv("REDIS_MODE", string(ModeStandalone)))),
+		Addr:          fmt.Sprintf("%s:%s", getEnv("REDIS_HOST", "localhost"), getEnv("REDIS_PORT", "6379")),
+		SentinelAddrs: splitAddrs(os.Getenv("REDIS_SENTINEL_ADDRS")),
+		MasterName:    os.Getenv("REDIS_MASTER_NAME"),
+		ClusterAddrs:  splitAddrs(os.Getenv("REDIS_CLUSTER_ADDRS")),
+		Username:      os.Getenv("REDIS_USERNAME"),
+		Password:      os.Getenv("REDIS_PASSWORD"),
+		DB:            0,
+		TLS:           useTLS,
+		CACert:        os.Getenv("REDIS_CA_CERT"),
+		ClientCert:    os.Getenv("REDIS_CLIENT_CERT"),
+		ClientKey:     os.Getenv("REDIS_CLIENT_KEY"),
+
+		OperationTimeout: operationTimeout,
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the settings required by the selected mode are present
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case ModeStandalone:
+		if c.Addr == "" {
+			return fmt.Errorf("redis standalone mode requires an address")
+		}
+	case ModeSentinel:
+		if len(c.SentinelAddrs) == 0 {
+			return fmt.Errorf("redis sentinel mode requires REDIS_SENTINEL_ADDRS")
+		}
+		if c.MasterName == "" {
+			return fmt.Errorf("redis sentinel mode requires REDIS_MASTER_NAME")
+		}
+	case ModeCluster:
+		if len(c.ClusterAddrs) == 0 {
+			return fmt.Errorf("redis cluster mode requires REDIS_CLUSTER_ADDRS")
+		}
+	default:
+		return fmt.Errorf("unsupported REDIS_MODE: %q (must be standalone, sentinel, or cluster)", c.Mode)
+	}
+
+	return c.validateTLS()
+}
+
+// validateTLS rejects certificate settings that would otherwise be ignored
+// and checks that referenced files exist, so misconfiguration fails at startup
+// instead of silently falling back to plaintext
+func (c *Config) validateTLS() error {
+	if !c.TLS {
+		if c.CACert != "" || c.ClientCert != "" || c.ClientKey != "" {
+			return fmt.Errorf("redis TLS certificates configured but REDIS_TLS is not enabled")
+		}
+		return nil
+	}
+
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return fmt.Errorf("redis mutual TLS requires both REDIS_CLIENT_CERT and REDIS_CLIENT_KEY")
+	}
+
+	files := []struct{ name, path string }{
+		{"REDIS_CA_CERT", c.CACert},
+		{"REDIS_CLIENT_CERT", c.ClientCert},
+		{"REDIS_CLIENT_KEY", c.ClientKey},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			return fmt.Errorf("redis TLS file %s=%s not readable: %w", f.name, f.path, err)
+		}
+	}
+
+	return nil
+}
+
+// TLSConfig builds the tls.Config for the connection (nil when TLS is disabled)
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	if !c.TLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if c.CACert != "" {
+		caPEM, err := os.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in redis CA cert %s", c.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// UniversalOptions converts the config into go-redis universal options
+func (c *Config) UniversalOptions() (*redis.UniversalOptions, error) {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// ContextTimeoutEnabled makes go-redis honour context deadlines on the
+	// socket, which the Client's per-operation timeout relies on
+	opts := &redis.UniversalOptions{
+		Username:              c.Username,
+		Password:              c.Password,
+		DB:                    c.DB,
+		TLSConfig:             tlsConfig,
+		ContextTimeoutEnabled: true,
+	}
+
+	switch c.Mode {
+	case ModeSentinel:
+		opts.Addrs = c.SentinelAddrs
+		opts.MasterName = c.MasterName
+	case ModeCluster:
+		opts.Addrs = c.ClusterAddrs
+		opts.DB = 0
+	default:
+		opts.Addrs = []string{c.Addr}
+	}
+
+	return opts, nil
+}
+
+// NewUniversalClient creates a client for the configured topology
+// The concrete client is chosen explicitly by mode rather than inferred from the
+// number of addresses, so a cluster with a single seed node still gets a ClusterClient.
+func NewUniversalClient(cfg *Config) (redis.UniversalClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts, err := cfg.UniversalOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Mode {
+	case ModeSentinel:
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case ModeCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return redis.NewClient(opts.Simple()), nil
+	}
+}
+
+// NewUniversalClientFromEnv creates a client using ConfigFromEnv
+func NewUniversalClientFromEnv() (redis.UniversalClient, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis config: %w", err)
+	}
+	return NewUniversalClient(cfg)
+}
+
+// HashTag wraps an ID in braces so that every key containing it maps to the
+// same cluster slot (e.g. fmt.Sprintf("counter:%s", HashTag(runID)))
+func HashTag(id string) string {
+	return "{" + id + "}"
+}
+
+// splitAddrs parses a comma-separated address list, dropping blanks
+func splitAddrs(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var addrs []string
+	for _, addr := range strings.Split(value, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// getEnv gets an environment variable or returns a default
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
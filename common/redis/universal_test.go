@@ -0,0 +1,260 @@
+package redis
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (noopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (noopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+func TestConfigFromEnv_Standalone(t *testing.T) {
+	t.Setenv("REDIS_MODE", "")
+	t.Setenv("REDIS_HOST", "redis.local")
+	t.Setenv("REDIS_PORT", "6380")
+	t.Setenv("REDIS_PASSWORD", "secret")
+
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+
+	assert.Equal(t, ModeStandalone, cfg.Mode)
+	opts, err := cfg.UniversalOptions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"redis.local:6380"}, opts.Addrs)
+	assert.Equal(t, "secret", opts.Password)
+	assert.Empty(t, opts.MasterName)
+	assert.Nil(t, opts.TLSConfig)
+}
+
+func TestConfigFromEnv_Sentinel(t *testing.T) {
+	t.Setenv("REDIS_MODE", "sentinel")
+	t.Setenv("REDIS_SENTINEL_ADDRS", "s1:26379, s2:26379,,s3:26379")
+	t.Setenv("REDIS_MASTER_NAME", "mymaster")
+
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+
+	opts, err := cfg.UniversalOptions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s1:26379", "s2:26379", "s3:26379"}, opts.Addrs)
+	assert.Equal(t, "mymaster", opts.MasterName)
+}
+
+func TestConfigFromEnv_Invalid(t *testing.T) {
+	tests := map[string]map[string]string{
+		"unknown mode":          {"REDIS_MODE": "replicated"},
+		"sentinel no addrs":     {"REDIS_MODE": "sentinel", "REDIS_MASTER_NAME": "m"},
+		"sentinel no master":    {"REDIS_MODE": "sentinel", "REDIS_SENTINEL_ADDRS": "s1:26379"},
+		"cluster no seed addr":  {"REDIS_MODE": "cluster"},
+		"bad operation timeout": {"REDIS_OPERATION_TIMEOUT": "soon"},
+		"negative timeout":      {"REDIS_OPERATION_TIMEOUT": "-1s"},
+	}
+
+	for name, env := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range redisEnvKeys {
+				t.Setenv(key, env[key])
+			}
+			_, err := ConfigFromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
+
+var redisEnvKeys = []string{
+	"REDIS_MODE", "REDIS_SENTINEL_ADDRS", "REDIS_MASTER_NAME", "REDIS_CLUSTER_ADDRS",
+	"REDIS_USERNAME", "REDIS_TLS", "REDIS_CA_CERT", "REDIS_CLIENT_CERT", "REDIS_CLIENT_KEY",
+	"REDIS_OPERATION_TIMEOUT",
+}
+
+func TestConfigFromEnv_TLSAndAuth(t *testing.T) {
+	certPath, keyPath := writeTestCert(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantErr     string
+		wantTLS     bool
+		wantRootCAs bool
+		wantCerts   int
+	}{
+		{
+			name: "plaintext with ACL username",
+			env:  map[string]string{"REDIS_USERNAME": "app"},
+		},
+		{
+			name:    "tls with system roots",
+			env:     map[string]string{"REDIS_TLS": "true"},
+			wantTLS: true,
+		},
+		{
+			name:        "tls with custom CA",
+			env:         map[string]string{"REDIS_TLS": "true", "REDIS_CA_CERT": certPath},
+			wantTLS:     true,
+			wantRootCAs: true,
+		},
+		{
+			name:        "mutual tls",
+			env:         map[string]string{"REDIS_TLS": "1", "REDIS_CA_CERT": certPath, "REDIS_CLIENT_CERT": certPath, "REDIS_CLIENT_KEY": keyPath},
+			wantTLS:     true,
+			wantRootCAs: true,
+			wantCerts:   1,
+		},
+		{
+			name:    "invalid REDIS_TLS value",
+			env:     map[string]string{"REDIS_TLS": "yes please"},
+			wantErr: "invalid REDIS_TLS",
+		},
+		{
+			name:    "cert configured without TLS",
+			env:     map[string]string{"REDIS_CA_CERT": certPath},
+			wantErr: "REDIS_TLS is not enabled",
+		},
+		{
+			name:    "client cert without key",
+			env:     map[string]string{"REDIS_TLS": "true", "REDIS_CLIENT_CERT": certPath},
+			wantErr: "requires both",
+		},
+		{
+			name:    "missing CA file",
+			env:     map[string]string{"REDIS_TLS": "true", "REDIS_CA_CERT": missing},
+			wantErr: "REDIS_CA_CERT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range redisEnvKeys {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg, err := ConfigFromEnv()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			opts, err := cfg.UniversalOptions()
+			require.NoError(t, err)
+			assert.Equal(t, tt.env["REDIS_USERNAME"], opts.Username)
+
+			if !tt.wantTLS {
+				assert.Nil(t, opts.TLSConfig)
+				return
+			}
+			require.NotNil(t, opts.TLSConfig)
+			assert.Equal(t, uint16(tls.VersionTLS12), opts.TLSConfig.MinVersion)
+			assert.Equal(t, tt.wantRootCAs, opts.TLSConfig.RootCAs != nil)
+			assert.Len(t, opts.TLSConfig.Certificates, tt.wantCerts)
+		})
+	}
+}
+
+// writeTestCert writes a self-signed certificate and key as PEM files
+func writeTestCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certPath, keyPath
+}
+
+func TestNewUniversalClient_ClusterOperations(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	rdb, err := NewUniversalClient(&Config{Mode: ModeCluster, ClusterAddrs: []string{mr.Addr()}})
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	_, isCluster := rdb.(*redis.ClusterClient)
+	require.True(t, isCluster, "cluster mode must produce a ClusterClient even with one seed")
+
+	client := NewClient(rdb, noopLogger{})
+
+	// Streams
+	require.NoError(t, client.CreateStreamGroup(ctx, "wf.tasks.http", "workers"))
+	_, err = client.AddToStream(ctx, "wf.tasks.http", map[string]interface{}{"token": "t1"})
+	require.NoError(t, err)
+	streams, err := client.ReadFromStreamGroup(ctx, "workers", "w1", "wf.tasks.http", 10, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, streams, 1)
+	require.NoError(t, client.AckStreamMessage(ctx, "wf.tasks.http", "workers", streams[0].Messages[0].ID))
+
+	// Pipeline
+	pipe := client.NewPipeline()
+	pipe.SetWithExpiry(ctx, "run:status:run_1", "RUNNING", time.Minute)
+	pipe.AddToStream(ctx, "run.status.updates", map[string]interface{}{"run_id": "run_1"})
+	require.NoError(t, pipe.Exec(ctx))
+
+	// Transaction on hash-tagged keys (same slot)
+	tx := client.NewTransaction()
+	setLabel := tx.SetNX(ctx, "approval:"+HashTag("run_1"), "pending", time.Minute)
+	incrLabel := tx.Incr(ctx, "pending:"+HashTag("run_1"))
+	require.NoError(t, tx.Exec(ctx))
+	wasSet, err := tx.GetBoolResult(setLabel)
+	require.NoError(t, err)
+	assert.True(t, wasSet)
+	count, err := tx.GetIntResult(incrLabel)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestHashTaggedKeysShareSlot(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	rdb, err := NewUniversalClient(&Config{Mode: ModeCluster, ClusterAddrs: []string{mr.Addr()}})
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	counterSlot, err := rdb.ClusterKeySlot(ctx, "counter:"+HashTag("run_1")).Result()
+	require.NoError(t, err)
+	appliedSlot, err := rdb.ClusterKeySlot(ctx, "applied:"+HashTag("run_1")).Result()
+	require.NoError(t, err)
+	assert.Equal(t, counterSlot, appliedSlot)
+}
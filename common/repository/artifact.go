@@ -223,6 +223,7 @@ func (r *ArtifactRepository) ListByKind(ctx context.Context, kind string, limit
 }
 
 // GetPatchChain retrieves the full patch chain for a head artifact
+// Patches are ordered by depth, their position in the chain, then by seq.
 func (r *ArtifactRepository) GetPatchChain(ctx context.Context, headID uuid.UUID) ([]*models.Artifact, error) {
 	query := `
 		SELECT
@@ -232,7 +233,7 @@ func (r *ArtifactRepository) GetPatchChain(ctx context.Context, headID uuid.UUID
 		FROM artifact a
 		INNER JOIN patch_chain_member pcm ON a.artifact_id = pcm.member_id
 		WHERE pcm.head_id = $1
-		ORDER BY pcm.seq ASC
+		ORDER BY a.depth ASC, pcm.seq ASC
 	`
 
 	rows, err := r.db.Query(ctx, query, headID)
@@ -274,6 +275,121 @@ func (r *ArtifactRepository) GetPatchChain(ctx context.Context, headID uuid.UUID
 	return artifacts, nil
 }
 
+// GetByIDs retrieves several artifacts in one query
+// Missing IDs are simply absent from the result.
+func (r *ArtifactRepository) GetByIDs(ctx context.Context, artifactIDs []uuid.UUID) (map[uuid.UUID]*models.Artifact, error) {
+	result := make(map[uuid.UUID]*models.Artifact, len(artifactIDs))
+	if len(artifactIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT
+			artifact_id, kind, cas_id, name, plan_hash, version_hash,
+			base_version, depth, op_count, nodes_count, edges_count,
+			compacted_from_id, meta, created_by, created_at
+		FROM artifact
+		WHERE artifact_id = ANY($1)
+	`
+
+	rows, err := r.db.Query(ctx, query, artifactIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		artifact := &models.Artifact{}
+		err := rows.Scan(
+			&artifact.ArtifactID,
+			&artifact.Kind,
+			&artifact.CasID,
+			&artifact.Name,
+			&artifact.PlanHash,
+			&artifact.VersionHash,
+			&artifact.BaseVersion,
+			&artifact.Depth,
+			&artifact.OpCount,
+			&artifact.NodesCount,
+			&artifact.EdgesCount,
+			&artifact.CompactedFromID,
+			&artifact.Meta,
+			&artifact.CreatedBy,
+			&artifact.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artifact: %w", err)
+		}
+		result[artifact.ArtifactID] = artifact
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating artifacts: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetPatchChains retrieves the patch chains of several heads in one query
+// Each chain is ordered by depth, like GetPatchChain.
+func (r *ArtifactRepository) GetPatchChains(ctx context.Context, headIDs []uuid.UUID) (map[uuid.UUID][]*models.Artifact, error) {
+	result := make(map[uuid.UUID][]*models.Artifact, len(headIDs))
+	if len(headIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT
+			pcm.head_id,
+			a.artifact_id, a.kind, a.cas_id, a.name, a.plan_hash, a.version_hash,
+			a.base_version, a.depth, a.op_count, a.nodes_count, a.edges_count,
+			a.compacted_from_id, a.meta, a.created_by, a.created_at
+		FROM artifact a
+		INNER JOIN patch_chain_member pcm ON a.artifact_id = pcm.member_id
+		WHERE pcm.head_id = ANY($1)
+		ORDER BY pcm.head_id, a.depth ASC, pcm.seq ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, headIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patch chains: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var headID uuid.UUID
+		artifact := &models.Artifact{}
+		err := rows.Scan(
+			&headID,
+			&artifact.ArtifactID,
+			&artifact.Kind,
+			&artifact.CasID,
+			&artifact.Name,
+			&artifact.PlanHash,
+			&artifact.VersionHash,
+			&artifact.BaseVersion,
+			&artifact.Depth,
+			&artifact.OpCount,
+			&artifact.NodesCount,
+			&artifact.EdgesCount,
+			&artifact.CompactedFromID,
+			&artifact.Meta,
+			&artifact.CreatedBy,
+			&artifact.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan artifact in patch chain: %w", err)
+		}
+		result[headID] = append(result[headID], artifact)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating patch chains: %w", err)
+	}
+
+	return result, nil
+}
+
 // FindCompactedBase finds a dag_version that was compacted from a specific patch
 // Returns nil if no compacted version exists
 func (r *ArtifactRepository) FindCompactedBase(ctx context.Context, patchID uuid.UUID) (*models.Artifact, error) {
@@ -341,6 +457,41 @@ func (r *ArtifactRepository) InsertPatchChain(ctx context.Context, headID uuid.U
 	return nil
 }
 
+// Statements of DeletePatch, run in this order in one transaction
+const (
+	deletePatchChainQuery    = `DELETE FROM patch_chain_member WHERE head_id = $1`
+	deletePatchArtifactQuery = `DELETE FROM artifact WHERE artifact_id = $1 AND kind = 'patch_set'`
+)
+
+// DeletePatch deletes a patch set artifact nothing refers to, with its chain
+// For undoing a patch whose tag move lost a race, before any tag or run saw it.
+// Anything that does refer to it (a run, a later patch) makes the delete fail
+// rather than cascade. The patch's CAS blob is left to CAS GC, as another
+// artifact may share it.
+func (r *ArtifactRepository) DeletePatch(ctx context.Context, artifactID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin patch delete: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op once committed
+
+	if _, err := tx.Exec(ctx, deletePatchChainQuery, artifactID); err != nil {
+		return fmt.Errorf("failed to delete patch chain: %w", err)
+	}
+	result, err := tx.Exec(ctx, deletePatchArtifactQuery, artifactID)
+	if err != nil {
+		return fmt.Errorf("failed to delete patch artifact: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("patch artifact %s not found", artifactID)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit patch delete: %w", err)
+	}
+	return nil
+}
+
 // GetCompactionCandidates returns patches exceeding depth threshold
 func (r *ArtifactRepository) GetCompactionCandidates(ctx context.Context, depthThreshold int) ([]*models.Artifact, error) {
 	query := `
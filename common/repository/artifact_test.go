@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeletePatchQueries(t *testing.T) {
+	// The head's own chain rows go first; rows listing it as a member of another
+	// head's chain are left, so their RESTRICT key fails the delete
+	assert.Equal(t, "DELETE FROM patch_chain_member WHERE head_id = $1", deletePatchChainQuery)
+	assert.NotContains(t, deletePatchChainQuery, "member_id")
+
+	// Only patch sets: a DAG version is never deleted this way
+	assert.Equal(t, "DELETE FROM artifact WHERE artifact_id = $1 AND kind = 'patch_set'", deletePatchArtifactQuery)
+}
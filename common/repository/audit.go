@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/db"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// Audit listing limits
+const (
+	DefaultAuditListLimit = 50
+	MaxAuditListLimit     = 200
+)
+
+// AuditRepository handles database operations for the audit log
+// The log is append-only: there is no update or delete.
+type AuditRepository struct {
+	db *db.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(database *db.DB) *AuditRepository {
+	return &AuditRepository{db: database}
+}
+
+// AuditListOptions filters and pages an audit log listing
+type AuditListOptions struct {
+	Actor    string    // Empty matches every actor
+	TagOwner string    // Empty matches every owner's tags
+	TagName  string    // Empty matches every tag; names are per owner, so set TagOwner too
+	Since    time.Time // Inclusive; zero means unbounded
+	Limit    int       // Defaults to DefaultAuditListLimit, capped at MaxAuditListLimit
+	Cursor   string    // NextCursor of the previous page
+}
+
+// AuditPage is one page of an audit log listing, newest first
+type AuditPage struct {
+	Entries    []*models.AuditEntry `json:"entries"`
+	NextCursor string               `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// Append inserts an entry into the audit log
+func (r *AuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (actor, action, target_kind, target_id, tag_owner, tag_name, request_id, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		entry.Actor,
+		entry.Action,
+		entry.TargetKind,
+		entry.TargetID,
+		entry.TagOwner,
+		entry.TagName,
+		entry.RequestID,
+		entry.Details,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves a page of the audit log, newest first
+// Pages use keyset pagination on the entry id, so they stay stable while new
+// entries are appended.
+func (r *AuditRepository) List(ctx context.Context, opts AuditListOptions) (*AuditPage, error) {
+	query, args, limit, err := buildListAuditQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*models.AuditEntry, 0, limit)
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Actor,
+			&entry.Action,
+			&entry.TargetKind,
+			&entry.TargetID,
+			&entry.TagOwner,
+			&entry.TagName,
+			&entry.RequestID,
+			&entry.Details,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	// One extra row is fetched to tell whether another page follows
+	page := &AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeIDCursor(page.Entries[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+// buildListAuditQuery builds the keyset-paginated audit log query
+// Returns the query, its arguments and the effective page size.
+func buildListAuditQuery(opts AuditListOptions) (string, []interface{}, int, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultAuditListLimit
+	}
+	if limit > MaxAuditListLimit {
+		limit = MaxAuditListLimit
+	}
+
+	var args []interface{}
+	conditions := []string{"TRUE"}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if opts.Actor != "" {
+		conditions = append(conditions, "actor = "+arg(opts.Actor))
+	}
+	if opts.TagOwner != "" {
+		conditions = append(conditions, "tag_owner = "+arg(opts.TagOwner))
+	}
+	if opts.TagName != "" {
+		conditions = append(conditions, "tag_name = "+arg(opts.TagName))
+	}
+	if !opts.Since.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(opts.Since))
+	}
+	if opts.Cursor != "" {
+		id, err := decodeIDCursor(opts.Cursor)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		conditions = append(conditions, "id < "+arg(id))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, actor, action, target_kind, target_id, tag_owner, tag_name, request_id, details, created_at
+		FROM audit_log
+		WHERE %s
+		ORDER BY id DESC
+		LIMIT %s
+	`, strings.Join(conditions, "\n\t\t  AND "), arg(limit+1))
+
+	return query, args, limit, nil
+}
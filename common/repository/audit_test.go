@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditCursorRoundTrip(t *testing.T) {
+	id, err := decodeIDCursor(encodeIDCursor(42))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	for _, cursor := range []string{"not base64!", "YWJj", encodeIDCursor(0)} {
+		_, err := decodeIDCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestBuildListAuditQuery(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args, limit, err := buildListAuditQuery(AuditListOptions{
+		Actor:    "bob",
+		TagOwner: "alice",
+		TagName:  "main",
+		Since:    since,
+		Limit:    1000,
+		Cursor:   encodeIDCursor(7),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, MaxAuditListLimit, limit)
+	assert.Contains(t, query, "actor = $1")
+	assert.Contains(t, query, "tag_owner = $2")
+	assert.Contains(t, query, "tag_name = $3")
+	assert.Contains(t, query, "created_at >= $4")
+	assert.Contains(t, query, "id < $5")
+	assert.Contains(t, query, "ORDER BY id DESC")
+	assert.Contains(t, query, "LIMIT $6")
+	assert.Equal(t, []interface{}{"bob", "alice", "main", since, int64(7), MaxAuditListLimit + 1}, args)
+
+	// No filters: every entry, default page size
+	query, args, limit, err = buildListAuditQuery(AuditListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAuditListLimit, limit)
+	assert.Contains(t, query, "LIMIT $1")
+	assert.Equal(t, []interface{}{DefaultAuditListLimit + 1}, args)
+
+	_, _, _, err = buildListAuditQuery(AuditListOptions{Cursor: "garbage"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
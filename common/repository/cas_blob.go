@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/lyzr/orchestrator/common/models"
 	"github.com/lyzr/orchestrator/common/db"
@@ -21,16 +22,23 @@ func NewCASBlobRepository(db *db.DB) *CASBlobRepository {
 // Create inserts a new CAS blob
 func (r *CASBlobRepository) Create(ctx context.Context, blob *models.CASBlob) error {
 	query := `
-		INSERT INTO cas_blob (cas_id, media_type, size_bytes, content, storage_url, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO cas_blob (cas_id, media_type, size_bytes, content, content_encoding, signature, storage_url, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
 		ON CONFLICT (cas_id) DO NOTHING
 	`
 
+	encoding := blob.ContentEncoding
+	if encoding == "" {
+		encoding = models.ContentEncodingIdentity
+	}
+
 	_, err := r.db.Exec(ctx, query,
 		blob.CasID,
 		blob.MediaType,
 		blob.SizeBytes,
 		blob.Content,
+		encoding,
+		blob.Signature,
 		blob.StorageURL,
 		blob.CreatedAt,
 	)
@@ -45,7 +53,7 @@ func (r *CASBlobRepository) Create(ctx context.Context, blob *models.CASBlob) er
 // GetByID retrieves a CAS blob by its ID
 func (r *CASBlobRepository) GetByID(ctx context.Context, casID string) (*models.CASBlob, error) {
 	query := `
-		SELECT cas_id, media_type, size_bytes, content, storage_url, created_at
+		SELECT cas_id, media_type, size_bytes, content, content_encoding, COALESCE(signature, ''), storage_url, created_at
 		FROM cas_blob
 		WHERE cas_id = $1
 	`
@@ -56,6 +64,8 @@ func (r *CASBlobRepository) GetByID(ctx context.Context, casID string) (*models.
 		&blob.MediaType,
 		&blob.SizeBytes,
 		&blob.Content,
+		&blob.ContentEncoding,
+		&blob.Signature,
 		&blob.StorageURL,
 		&blob.CreatedAt,
 	)
@@ -80,27 +90,29 @@ func (r *CASBlobRepository) Exists(ctx context.Context, casID string) (bool, err
 	return exists, nil
 }
 
-// GetContentByID retrieves only the content of a CAS blob
-func (r *CASBlobRepository) GetContentByID(ctx context.Context, casID string) ([]byte, error) {
-	query := `SELECT content FROM cas_blob WHERE cas_id = $1`
+// GetContentByID retrieves only the stored content, its encoding and signature
+// The returned blob only has CasID, Content, ContentEncoding and Signature populated.
+func (r *CASBlobRepository) GetContentByID(ctx context.Context, casID string) (*models.CASBlob, error) {
+	query := `SELECT content, content_encoding, COALESCE(signature, '') FROM cas_blob WHERE cas_id = $1`
 
-	var content []byte
-	err := r.db.QueryRow(ctx, query, casID).Scan(&content)
+	blob := &models.CASBlob{CasID: casID}
+	err := r.db.QueryRow(ctx, query, casID).Scan(&blob.Content, &blob.ContentEncoding, &blob.Signature)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get CAS blob content: %w", err)
 	}
 
-	return content, nil
+	return blob, nil
 }
 
-// GetContentBulk retrieves content for multiple CAS blobs in a single query
-func (r *CASBlobRepository) GetContentBulk(ctx context.Context, casIDs []string) (map[string][]byte, error) {
+// GetContentBulk retrieves stored content for multiple CAS blobs in a single query
+// Returned blobs only have CasID, Content, ContentEncoding and Signature populated.
+func (r *CASBlobRepository) GetContentBulk(ctx context.Context, casIDs []string) (map[string]*models.CASBlob, error) {
 	if len(casIDs) == 0 {
-		return make(map[string][]byte), nil
+		return make(map[string]*models.CASBlob), nil
 	}
 
 	query := `
-		SELECT cas_id, content
+		SELECT cas_id, content, content_encoding, COALESCE(signature, '')
 		FROM cas_blob
 		WHERE cas_id = ANY($1)
 	`
@@ -111,14 +123,13 @@ func (r *CASBlobRepository) GetContentBulk(ctx context.Context, casIDs []string)
 	}
 	defer rows.Close()
 
-	results := make(map[string][]byte, len(casIDs))
+	results := make(map[string]*models.CASBlob, len(casIDs))
 	for rows.Next() {
-		var casID string
-		var content []byte
-		if err := rows.Scan(&casID, &content); err != nil {
+		blob := &models.CASBlob{}
+		if err := rows.Scan(&blob.CasID, &blob.Content, &blob.ContentEncoding, &blob.Signature); err != nil {
 			return nil, fmt.Errorf("failed to scan CAS blob content: %w", err)
 		}
-		results[casID] = content
+		results[blob.CasID] = blob
 	}
 
 	if err := rows.Err(); err != nil {
@@ -131,7 +142,7 @@ func (r *CASBlobRepository) GetContentBulk(ctx context.Context, casIDs []string)
 // ListByMediaType lists CAS blobs by media type
 func (r *CASBlobRepository) ListByMediaType(ctx context.Context, mediaType string, limit int) ([]*models.CASBlob, error) {
 	query := `
-		SELECT cas_id, media_type, size_bytes, content, storage_url, created_at
+		SELECT cas_id, media_type, size_bytes, content, content_encoding, COALESCE(signature, ''), storage_url, created_at
 		FROM cas_blob
 		WHERE media_type = $1
 		ORDER BY created_at DESC
@@ -152,6 +163,8 @@ func (r *CASBlobRepository) ListByMediaType(ctx context.Context, mediaType strin
 			&blob.MediaType,
 			&blob.SizeBytes,
 			&blob.Content,
+			&blob.ContentEncoding,
+			&blob.Signature,
 			&blob.StorageURL,
 			&blob.CreatedAt,
 		)
@@ -167,3 +180,108 @@ func (r *CASBlobRepository) ListByMediaType(ctx context.Context, mediaType strin
 
 	return blobs, nil
 }
+
+// ListReferencedIDs returns every cas_id referenced from the database
+// Covers artifacts (workflow versions, patches, snapshots), large agent results
+// and node execution I/O.
+func (r *CASBlobRepository) ListReferencedIDs(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT cas_id FROM artifact
+		UNION
+		SELECT cas_id FROM agent_results WHERE cas_id IS NOT NULL
+		UNION
+		SELECT input_cas_ref FROM node_executions WHERE input_cas_ref IS NOT NULL
+		UNION
+		SELECT output_cas_ref FROM node_executions WHERE output_cas_ref IS NOT NULL
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referenced CAS IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan referenced CAS ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating referenced CAS IDs: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ListCreatedBefore lists blobs created before cutoff, without their content
+func (r *CASBlobRepository) ListCreatedBefore(ctx context.Context, cutoff time.Time) ([]*models.CASBlob, error) {
+	query := `
+		SELECT cas_id, media_type, size_bytes, created_at
+		FROM cas_blob
+		WHERE created_at < $1
+	`
+
+	rows, err := r.db.Query(ctx, query, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CAS blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var blobs []*models.CASBlob
+	for rows.Next() {
+		blob := &models.CASBlob{}
+		if err := rows.Scan(&blob.CasID, &blob.MediaType, &blob.SizeBytes, &blob.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan CAS blob: %w", err)
+		}
+		blobs = append(blobs, blob)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating CAS blobs: %w", err)
+	}
+
+	return blobs, nil
+}
+
+// DeleteUnreferenced deletes the given blobs if they are older than cutoff and
+// still not referenced by an artifact. Returns the deleted blobs (CasID and SizeBytes).
+// Re-checking inside the DELETE closes the race with an artifact created after
+// the references were collected.
+func (r *CASBlobRepository) DeleteUnreferenced(ctx context.Context, casIDs []string, cutoff time.Time) ([]*models.CASBlob, error) {
+	if len(casIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		DELETE FROM cas_blob b
+		WHERE b.cas_id = ANY($1)
+		  AND b.created_at < $2
+		  AND NOT EXISTS (SELECT 1 FROM artifact a WHERE a.cas_id = b.cas_id)
+		RETURNING b.cas_id, b.size_bytes
+	`
+
+	rows, err := r.db.Query(ctx, query, casIDs, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete CAS blobs: %w", err)
+	}
+	defer rows.Close()
+
+	var deleted []*models.CASBlob
+	for rows.Next() {
+		blob := &models.CASBlob{}
+		if err := rows.Scan(&blob.CasID, &blob.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted CAS blob: %w", err)
+		}
+		deleted = append(deleted, blob)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deleted CAS blobs: %w", err)
+	}
+
+	return deleted, nil
+}
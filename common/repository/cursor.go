@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// encodeIDCursor encodes a row id as an opaque keyset pagination cursor
+func encodeIDCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// decodeIDCursor decodes a cursor produced by encodeIDCursor
+func decodeIDCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
@@ -2,7 +2,11 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lyzr/orchestrator/common/models"
@@ -87,23 +91,83 @@ func (r *RunRepository) UpdateStatus(ctx context.Context, runID uuid.UUID, statu
 	return nil
 }
 
-// ListByUser retrieves runs submitted by a specific user
-func (r *RunRepository) ListByUser(ctx context.Context, username string, limit int) ([]*models.Run, error) {
+// GetStatuses retrieves the status of many runs in a single query
+// Runs that don't exist are omitted from the result.
+func (r *RunRepository) GetStatuses(ctx context.Context, runIDs []uuid.UUID) (map[uuid.UUID]models.RunStatus, error) {
+	statuses := make(map[uuid.UUID]models.RunStatus, len(runIDs))
+	if len(runIDs) == 0 {
+		return statuses, nil
+	}
+
 	query := `
-		SELECT run_id, base_kind, base_ref, tags_snapshot, status, submitted_by, submitted_at
+		SELECT run_id, status
 		FROM run
-		WHERE submitted_by = $1
-		ORDER BY submitted_at DESC
-		LIMIT $2
+		WHERE run_id = ANY($1)
 	`
 
-	rows, err := r.db.Query(ctx, query, username, limit)
+	rows, err := r.db.Query(ctx, query, runIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run statuses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var runID uuid.UUID
+		var status models.RunStatus
+		if err := rows.Scan(&runID, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan run status: %w", err)
+		}
+		statuses[runID] = status
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating run statuses: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// ErrInvalidCursor is returned for a malformed pagination cursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Run listing limits
+const (
+	DefaultRunListLimit = 20
+	MaxRunListLimit     = 100
+)
+
+// RunListOptions filters, sorts and pages a run listing
+type RunListOptions struct {
+	Statuses        []models.RunStatus // Empty matches every status
+	SubmittedAfter  time.Time          // Inclusive; zero means unbounded
+	SubmittedBefore time.Time          // Exclusive; zero means unbounded
+	Ascending       bool               // Oldest first (default newest first)
+	Limit           int                // Defaults to DefaultRunListLimit, capped at MaxRunListLimit
+	Cursor          string             // NextCursor of the previous page
+}
+
+// RunPage is one page of a run listing
+type RunPage struct {
+	Runs       []*models.Run `json:"runs"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// ListByUser retrieves a page of runs submitted by a specific user
+// Pages use keyset pagination on (submitted_at, run_id): the cursor is the sort
+// key of the last run returned, so pages stay stable while new runs are inserted.
+func (r *RunRepository) ListByUser(ctx context.Context, username string, opts RunListOptions) (*RunPage, error) {
+	query, args, limit, err := buildListByUserQuery(username, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list runs: %w", err)
 	}
 	defer rows.Close()
 
-	var runs []*models.Run
+	runs := make([]*models.Run, 0, limit)
 	for rows.Next() {
 		run := &models.Run{}
 		err := rows.Scan(
@@ -125,7 +189,98 @@ func (r *RunRepository) ListByUser(ctx context.Context, username string, limit i
 		return nil, fmt.Errorf("error iterating runs: %w", err)
 	}
 
-	return runs, nil
+	// One extra row is fetched to tell whether another page follows
+	page := &RunPage{Runs: runs}
+	if len(runs) > limit {
+		page.Runs = runs[:limit]
+		last := page.Runs[limit-1]
+		page.NextCursor = encodeRunCursor(last.SubmittedAt, last.RunID)
+	}
+
+	return page, nil
+}
+
+// buildListByUserQuery builds the keyset-paginated run listing query
+// Returns the query, its arguments and the effective page size.
+func buildListByUserQuery(username string, opts RunListOptions) (string, []interface{}, int, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultRunListLimit
+	}
+	if limit > MaxRunListLimit {
+		limit = MaxRunListLimit
+	}
+
+	args := []interface{}{username}
+	conditions := []string{"submitted_by = $1"}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(opts.Statuses) > 0 {
+		statuses := make([]string, len(opts.Statuses))
+		for i, status := range opts.Statuses {
+			statuses[i] = string(status)
+		}
+		conditions = append(conditions, "status = ANY("+arg(statuses)+"::text[])")
+	}
+	if !opts.SubmittedAfter.IsZero() {
+		conditions = append(conditions, "submitted_at >= "+arg(opts.SubmittedAfter))
+	}
+	if !opts.SubmittedBefore.IsZero() {
+		conditions = append(conditions, "submitted_at < "+arg(opts.SubmittedBefore))
+	}
+
+	direction, comparison := "DESC", "<"
+	if opts.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if opts.Cursor != "" {
+		submittedAt, runID, err := decodeRunCursor(opts.Cursor)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(submitted_at, run_id) %s (%s, %s)", comparison, arg(submittedAt), arg(runID)))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT run_id, base_kind, base_ref, tags_snapshot, status, submitted_by, submitted_at
+		FROM run
+		WHERE %s
+		ORDER BY submitted_at %s, run_id %s
+		LIMIT %s
+	`, strings.Join(conditions, "\n\t\t  AND "), direction, direction, arg(limit+1))
+
+	return query, args, limit, nil
+}
+
+// encodeRunCursor encodes a run's sort key as an opaque cursor
+func encodeRunCursor(submittedAt time.Time, runID uuid.UUID) string {
+	key := submittedAt.UTC().Format(time.RFC3339Nano) + "|" + runID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// decodeRunCursor decodes a cursor produced by encodeRunCursor
+func decodeRunCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	submittedAt, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	runID, err := uuid.Parse(id)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return submittedAt, runID, nil
 }
 
 // ListByWorkflowTag retrieves runs for a specific workflow tag
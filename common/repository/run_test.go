@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/db"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+func TestRunCursorRoundTrip(t *testing.T) {
+	submittedAt := time.Date(2025, 6, 1, 12, 0, 0, 123456789, time.UTC)
+	runID := uuid.New()
+
+	gotAt, gotID, err := decodeRunCursor(encodeRunCursor(submittedAt, runID))
+	require.NoError(t, err)
+	assert.True(t, submittedAt.Equal(gotAt))
+	assert.Equal(t, runID, gotID)
+
+	for _, cursor := range []string{"not base64!", "bm8tc2VwYXJhdG9y", encodeRunCursor(submittedAt, uuid.Nil)[:10]} {
+		_, _, err := decodeRunCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestBuildListByUserQuery(t *testing.T) {
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursorAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	cursorID := uuid.New()
+
+	query, args, limit, err := buildListByUserQuery("alice", RunListOptions{
+		Statuses:       []models.RunStatus{models.StatusFailed, models.StatusRunning},
+		SubmittedAfter: after,
+		Limit:          500,
+		Cursor:         encodeRunCursor(cursorAt, cursorID),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, MaxRunListLimit, limit)
+	assert.Contains(t, query, "submitted_by = $1")
+	assert.Contains(t, query, "status = ANY($2::text[])")
+	assert.Contains(t, query, "submitted_at >= $3")
+	assert.Contains(t, query, "(submitted_at, run_id) < ($4, $5)")
+	assert.Contains(t, query, "ORDER BY submitted_at DESC, run_id DESC")
+	assert.Contains(t, query, "LIMIT $6")
+	require.Len(t, args, 6)
+	assert.Equal(t, []string{"FAILED", "RUNNING"}, args[1])
+	assert.Equal(t, cursorID, args[4])
+	assert.Equal(t, MaxRunListLimit+1, args[5])
+
+	// Ascending pages walk forward from the cursor
+	query, _, limit, err = buildListByUserQuery("alice", RunListOptions{Ascending: true, Cursor: encodeRunCursor(cursorAt, cursorID)})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRunListLimit, limit)
+	assert.Contains(t, query, "(submitted_at, run_id) > ($2, $3)")
+	assert.Contains(t, query, "ORDER BY submitted_at ASC, run_id ASC")
+
+	_, _, _, err = buildListByUserQuery("alice", RunListOptions{Cursor: "garbage"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+// newTestRunRepository connects to the database in TEST_DATABASE_URL
+// The database must have the migrations applied.
+func newTestRunRepository(t *testing.T) *RunRepository {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("Skipping run repository test. Set TEST_DATABASE_URL to a migrated database to run")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return NewRunRepository(&db.DB{Pool: pool})
+}
+
+func createTestRun(t *testing.T, repo *RunRepository, username string, status models.RunStatus, submittedAt time.Time) *models.Run {
+	t.Helper()
+	run := &models.Run{
+		RunID:        uuid.New(),
+		BaseKind:     models.BaseKindTag,
+		BaseRef:      "main",
+		TagsSnapshot: map[string]string{},
+		Status:       status,
+		SubmittedBy:  &username,
+		SubmittedAt:  submittedAt,
+	}
+	require.NoError(t, repo.Create(context.Background(), run))
+	return run
+}
+
+func TestRunRepository_ListByUserPaginatesStably(t *testing.T) {
+	repo := newTestRunRepository(t)
+	ctx := context.Background()
+	username := fmt.Sprintf("list-test-%s", uuid.NewString())
+	t.Cleanup(func() {
+		repo.db.Exec(context.Background(), "DELETE FROM run WHERE submitted_by = $1", username)
+	})
+
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var created []*models.Run
+	for i := 0; i < 5; i++ {
+		created = append(created, createTestRun(t, repo, username, models.StatusCompleted, base.Add(time.Duration(i)*time.Minute)))
+	}
+	// Two runs sharing a timestamp are ordered by run_id
+	created = append(created, createTestRun(t, repo, username, models.StatusCompleted, base.Add(4*time.Minute)))
+
+	first, err := repo.ListByUser(ctx, username, RunListOptions{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, first.Runs, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	// A run inserted between pages doesn't shift the following pages
+	createTestRun(t, repo, username, models.StatusQueued, base.Add(time.Hour))
+
+	seen := map[uuid.UUID]bool{}
+	for _, run := range first.Runs {
+		seen[run.RunID] = true
+	}
+	cursor := first.NextCursor
+	for cursor != "" {
+		page, err := repo.ListByUser(ctx, username, RunListOptions{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		for _, run := range page.Runs {
+			assert.False(t, seen[run.RunID], "run %s returned twice", run.RunID)
+			seen[run.RunID] = true
+		}
+		cursor = page.NextCursor
+	}
+
+	assert.Len(t, seen, len(created))
+	for _, run := range created {
+		assert.True(t, seen[run.RunID], "run %s missing", run.RunID)
+	}
+}
+
+func TestRunRepository_ListByUserFiltersByStatus(t *testing.T) {
+	repo := newTestRunRepository(t)
+	ctx := context.Background()
+	username := fmt.Sprintf("filter-test-%s", uuid.NewString())
+	t.Cleanup(func() {
+		repo.db.Exec(context.Background(), "DELETE FROM run WHERE submitted_by = $1", username)
+	})
+
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	failed := createTestRun(t, repo, username, models.StatusFailed, base)
+	createTestRun(t, repo, username, models.StatusCompleted, base.Add(time.Minute))
+	running := createTestRun(t, repo, username, models.StatusRunning, base.Add(2*time.Minute))
+
+	page, err := repo.ListByUser(ctx, username, RunListOptions{
+		Statuses: []models.RunStatus{models.StatusFailed, models.StatusRunning},
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Runs, 2)
+	assert.Equal(t, running.RunID, page.Runs[0].RunID)
+	assert.Equal(t, failed.RunID, page.Runs[1].RunID)
+	assert.Empty(t, page.NextCursor)
+
+	// Time range and ascending order combine with the status filter
+	page, err = repo.ListByUser(ctx, username, RunListOptions{
+		Statuses:        []models.RunStatus{models.StatusFailed, models.StatusRunning},
+		SubmittedBefore: base.Add(2 * time.Minute),
+		Ascending:       true,
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Runs, 1)
+	assert.Equal(t, failed.RunID, page.Runs[0].RunID)
+}
+
+func TestRunRepository_GetStatuses(t *testing.T) {
+	repo := newTestRunRepository(t)
+	ctx := context.Background()
+	username := fmt.Sprintf("status-test-%s", uuid.NewString())
+	t.Cleanup(func() {
+		repo.db.Exec(context.Background(), "DELETE FROM run WHERE submitted_by = $1", username)
+	})
+
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	failed := createTestRun(t, repo, username, models.StatusFailed, base)
+	running := createTestRun(t, repo, username, models.StatusRunning, base.Add(time.Minute))
+	missing := uuid.New()
+
+	statuses, err := repo.GetStatuses(ctx, []uuid.UUID{failed.RunID, running.RunID, missing})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]models.RunStatus{
+		failed.RunID:  models.StatusFailed,
+		running.RunID: models.StatusRunning,
+	}, statuses)
+}
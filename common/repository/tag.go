@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/lyzr/orchestrator/common/models"
 	"github.com/lyzr/orchestrator/common/db"
 )
@@ -121,10 +123,13 @@ func (r *TagRepository) CompareAndSwap(ctx context.Context, username, tagName st
 		movedBy,
 	).Scan(&newVersion)
 
-	if err != nil {
-		// If no rows affected, CAS failed
+	if errors.Is(err, pgx.ErrNoRows) {
+		// No row at the expected version: the tag moved (or is gone)
 		return false, nil
 	}
+	if err != nil {
+		return false, fmt.Errorf("failed to compare and swap tag: %w", err)
+	}
 
 	return true, nil
 }
@@ -232,6 +237,48 @@ func (r *TagRepository) ListByUsername(ctx context.Context, username string) ([]
 	return tags, nil
 }
 
+// ListByTarget retrieves all tags currently pointing at an artifact (uses idx_tag_target)
+func (r *TagRepository) ListByTarget(ctx context.Context, targetKind string, targetID uuid.UUID) ([]*models.Tag, error) {
+	query := `
+		SELECT username, tag_name, target_kind, target_id, target_hash, version, created_by, moved_by, moved_at
+		FROM tag
+		WHERE target_kind = $1 AND target_id = $2
+		ORDER BY username ASC, tag_name ASC
+	`
+
+	rows, err := r.db.Query(ctx, query, targetKind, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags by target: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*models.Tag
+	for rows.Next() {
+		tag := &models.Tag{}
+		err := rows.Scan(
+			&tag.Username,
+			&tag.TagName,
+			&tag.TargetKind,
+			&tag.TargetID,
+			&tag.TargetHash,
+			&tag.Version,
+			&tag.CreatedBy,
+			&tag.MovedBy,
+			&tag.MovedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %w", err)
+	}
+
+	return tags, nil
+}
+
 // Exists checks if a tag exists for a specific user
 func (r *TagRepository) Exists(ctx context.Context, username, tagName string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM tag WHERE username = $1 AND tag_name = $2)`
@@ -245,13 +292,42 @@ func (r *TagRepository) Exists(ctx context.Context, username, tagName string) (b
 	return exists, nil
 }
 
+// RecordMove appends an entry to the tag move history
+func (r *TagRepository) RecordMove(ctx context.Context, move *models.TagMove) error {
+	query := `
+		INSERT INTO tag_move (username, tag_name, from_kind, from_id, from_hash, to_kind, to_id, expected_hash, action, moved_by, moved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		move.Username,
+		move.TagName,
+		move.FromKind,
+		move.FromID,
+		move.FromHash,
+		move.ToKind,
+		move.ToID,
+		move.ExpectedHash,
+		move.Action,
+		move.MovedBy,
+		move.MovedAt,
+	).Scan(&move.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to record tag move: %w", err)
+	}
+
+	return nil
+}
+
 // GetHistory retrieves the tag move history for a specific user's tag
 func (r *TagRepository) GetHistory(ctx context.Context, username, tagName string, limit int) ([]*models.TagMove, error) {
 	query := `
-		SELECT id, username, tag_name, from_kind, from_id, to_kind, to_id, expected_hash, moved_by, moved_at
+		SELECT id, username, tag_name, from_kind, from_id, from_hash, to_kind, to_id, expected_hash, action, moved_by, moved_at
 		FROM tag_move
 		WHERE username = $1 AND tag_name = $2
-		ORDER BY moved_at DESC
+		ORDER BY moved_at DESC, id DESC
 		LIMIT $3
 	`
 
@@ -270,9 +346,11 @@ func (r *TagRepository) GetHistory(ctx context.Context, username, tagName string
 			&move.TagName,
 			&move.FromKind,
 			&move.FromID,
+			&move.FromHash,
 			&move.ToKind,
 			&move.ToID,
 			&move.ExpectedHash,
+			&move.Action,
 			&move.MovedBy,
 			&move.MovedAt,
 		)
@@ -288,3 +366,102 @@ func (r *TagRepository) GetHistory(ctx context.Context, username, tagName string
 
 	return history, nil
 }
+
+// Tag history listing limits
+const (
+	DefaultTagHistoryLimit = 50
+	MaxTagHistoryLimit     = 200
+)
+
+// TagHistoryPage is one page of a tag's move history, newest first
+type TagHistoryPage struct {
+	Moves      []*models.TagMove `json:"moves"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// ListHistory retrieves a page of a user's tag move history, newest first
+// Pages use keyset pagination on the move id, so they stay stable while the
+// tag keeps moving. limit defaults to DefaultTagHistoryLimit and is capped at
+// MaxTagHistoryLimit; cursor is the NextCursor of the previous page.
+func (r *TagRepository) ListHistory(ctx context.Context, username, tagName string, limit int, cursor string) (*TagHistoryPage, error) {
+	query, args, limit, err := buildListHistoryQuery(username, tagName, limit, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tag history: %w", err)
+	}
+	defer rows.Close()
+
+	moves := make([]*models.TagMove, 0, limit)
+	for rows.Next() {
+		move := &models.TagMove{}
+		err := rows.Scan(
+			&move.ID,
+			&move.Username,
+			&move.TagName,
+			&move.FromKind,
+			&move.FromID,
+			&move.FromHash,
+			&move.ToKind,
+			&move.ToID,
+			&move.ExpectedHash,
+			&move.Action,
+			&move.MovedBy,
+			&move.MovedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag move: %w", err)
+		}
+		moves = append(moves, move)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag history: %w", err)
+	}
+
+	return newTagHistoryPage(moves, limit), nil
+}
+
+// buildListHistoryQuery builds the keyset-paginated tag history query
+// Returns the query, its arguments and the effective page size.
+func buildListHistoryQuery(username, tagName string, limit int, cursor string) (string, []interface{}, int, error) {
+	if limit <= 0 {
+		limit = DefaultTagHistoryLimit
+	}
+	if limit > MaxTagHistoryLimit {
+		limit = MaxTagHistoryLimit
+	}
+
+	var beforeID int64
+	if cursor != "" {
+		id, err := decodeIDCursor(cursor)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		beforeID = id
+	}
+
+	query := `
+		SELECT id, username, tag_name, from_kind, from_id, from_hash, to_kind, to_id, expected_hash, action, moved_by, moved_at
+		FROM tag_move
+		WHERE username = $1 AND tag_name = $2 AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	return query, []interface{}{username, tagName, beforeID, limit + 1}, limit, nil
+}
+
+// newTagHistoryPage cuts the rows of buildListHistoryQuery down to a page
+// One extra row is fetched to tell whether another page follows; if it was,
+// the cursor points past the page's last move.
+func newTagHistoryPage(moves []*models.TagMove, limit int) *TagHistoryPage {
+	page := &TagHistoryPage{Moves: moves}
+	if len(moves) > limit {
+		page.Moves = moves[:limit]
+		page.NextCursor = encodeIDCursor(page.Moves[limit-1].ID)
+	}
+	return page
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+func TestBuildListHistoryQuery(t *testing.T) {
+	query, args, limit, err := buildListHistoryQuery("alice", "main", 1000, encodeIDCursor(7))
+	require.NoError(t, err)
+
+	assert.Equal(t, MaxTagHistoryLimit, limit)
+	assert.Contains(t, query, "FROM tag_move")
+	assert.Contains(t, query, "username = $1 AND tag_name = $2")
+	assert.Contains(t, query, "($3 = 0 OR id < $3)")
+	assert.Contains(t, query, "ORDER BY id DESC")
+	assert.Contains(t, query, "LIMIT $4")
+	assert.Equal(t, []interface{}{"alice", "main", int64(7), MaxTagHistoryLimit + 1}, args)
+
+	// First page: no lower bound, default page size
+	_, args, limit, err = buildListHistoryQuery("alice", "main", 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTagHistoryLimit, limit)
+	assert.Equal(t, []interface{}{"alice", "main", int64(0), DefaultTagHistoryLimit + 1}, args)
+
+	for _, cursor := range []string{"garbage", "7", encodeIDCursor(0)} {
+		_, _, _, err = buildListHistoryQuery("alice", "main", 10, cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestTagHistoryPageCursor(t *testing.T) {
+	moves := func(ids ...int64) []*models.TagMove {
+		result := make([]*models.TagMove, len(ids))
+		for i, id := range ids {
+			result[i] = &models.TagMove{ID: id}
+		}
+		return result
+	}
+
+	// The extra row is dropped, and the cursor resumes below the page's last move
+	page := newTagHistoryPage(moves(9, 8, 7), 2)
+	require.Len(t, page.Moves, 2)
+	assert.Equal(t, int64(8), page.Moves[1].ID)
+	require.NotEmpty(t, page.NextCursor)
+	_, args, _, err := buildListHistoryQuery("alice", "main", 2, page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), args[2])
+
+	// Without the extra row this is the last page
+	page = newTagHistoryPage(moves(7), 2)
+	assert.Len(t, page.Moves, 1)
+	assert.Empty(t, page.NextCursor)
+	page = newTagHistoryPage(moves(8, 7), 2)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestTagRepository_ListHistoryPaginates(t *testing.T) {
+	repo := NewTagRepository(newTestRunRepository(t).db)
+	ctx := context.Background()
+	username := fmt.Sprintf("history-test-%s", uuid.NewString())
+	other := fmt.Sprintf("history-other-%s", uuid.NewString())
+	t.Cleanup(func() {
+		repo.db.Exec(context.Background(), "DELETE FROM tag_move WHERE username = ANY($1::text[])", []string{username, other})
+	})
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var recorded []int64
+	for i := 0; i < 5; i++ {
+		move := &models.TagMove{
+			Username: username,
+			TagName:  "main",
+			ToKind:   models.KindDAGVersion,
+			ToID:     uuid.New(),
+			Action:   models.TagMoveActionMove,
+			MovedAt:  start.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, repo.RecordMove(ctx, move))
+		recorded = append(recorded, move.ID)
+	}
+	// Same tag name, another owner: never listed
+	require.NoError(t, repo.RecordMove(ctx, &models.TagMove{
+		Username: other,
+		TagName:  "main",
+		ToKind:   models.KindDAGVersion,
+		ToID:     uuid.New(),
+		Action:   models.TagMoveActionMove,
+		MovedAt:  start,
+	}))
+
+	// Pages of two walk the history newest first, and a move recorded between
+	// pages doesn't shift them
+	var listed []int64
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		page, err := repo.ListHistory(ctx, username, "main", 2, cursor)
+		require.NoError(t, err)
+		for _, move := range page.Moves {
+			assert.Equal(t, username, move.Username)
+			listed = append(listed, move.ID)
+		}
+		if pages == 0 {
+			require.NoError(t, repo.RecordMove(ctx, &models.TagMove{
+				Username: username,
+				TagName:  "main",
+				ToKind:   models.KindDAGVersion,
+				ToID:     uuid.New(),
+				Action:   models.TagMoveActionMove,
+				MovedAt:  start.Add(time.Hour),
+			}))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, []int64{recorded[4], recorded[3], recorded[2], recorded[1], recorded[0]}, listed)
+
+	_, err := repo.ListHistory(ctx, username, "main", 2, "garbage")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
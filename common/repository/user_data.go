@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lyzr/orchestrator/common/db"
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+// ErrUserHasActiveRuns is returned by PurgeUser while any of the user's runs
+// hasn't finished; cancel them first
+var ErrUserHasActiveRuns = errors.New("user has active runs")
+
+// Statements of a user purge, run in this order in one transaction
+// Tags are matched on their username column, as ListByUsername does: a tag
+// name prefix (the LIKE 'alice%' of the former ListByPrefix) would also catch
+// users whose names start the same way. Run patches and agent results store
+// the run ID as text, hence the text[] array.
+const (
+	lockUserRunsQuery       = `SELECT run_id, status FROM run WHERE submitted_by = $1 FOR UPDATE`
+	deleteUserTagMovesQuery = `DELETE FROM tag_move WHERE username = $1`
+	deleteUserTagsQuery     = `DELETE FROM tag WHERE username = $1 RETURNING tag_name`
+	deleteUserRunsQuery     = `DELETE FROM run WHERE submitted_by = $1 RETURNING run_id`
+	deleteRunPatchesQuery   = `DELETE FROM run_patches WHERE run_id = ANY($1::text[])`
+	deleteAgentResultsQuery = `DELETE FROM agent_results WHERE run_id = ANY($1::text[])`
+)
+
+// finishedRunStatus reports whether a run with status will never run again
+func finishedRunStatus(status models.RunStatus) bool {
+	switch status {
+	case models.StatusCompleted, models.StatusFailed, models.StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// UserDataRepository handles operations spanning all of a user's data
+type UserDataRepository struct {
+	db *db.DB
+}
+
+// NewUserDataRepository creates a new user data repository
+func NewUserDataRepository(database *db.DB) *UserDataRepository {
+	return &UserDataRepository{db: database}
+}
+
+// UserPurge is what PurgeUser deleted
+type UserPurge struct {
+	Tags     []string    `json:"tags"`      // Names of the user's deleted tags
+	Runs     []uuid.UUID `json:"runs"`      // IDs of the runs the user submitted
+	TagMoves int64       `json:"tag_moves"` // Move history rows of the deleted tags
+}
+
+// PurgeUser deletes a user's tags, their move history and the user's runs
+// Everything is deleted in one transaction, so a failure leaves the user's data
+// intact. The user's runs are locked first; if any hasn't finished, nothing is
+// deleted and ErrUserHasActiveRuns is returned, as a run still executing would
+// keep writing state for rows that are gone. Run execution rows cascade from the run; run patches and agent results,
+// keyed by run ID without a foreign key, are deleted explicitly. Artifacts and
+// CAS blobs are content-addressed and may be shared, so they're left in place.
+func (r *UserDataRepository) PurgeUser(ctx context.Context, username string) (*UserPurge, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin user purge: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op once committed
+
+	purge := &UserPurge{Tags: []string{}, Runs: []uuid.UUID{}}
+
+	// Locked until the purge commits, so a retry can't restart a finished run
+	rows, err := tx.Query(ctx, lockUserRunsQuery, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock runs: %w", err)
+	}
+	var active []uuid.UUID
+	for rows.Next() {
+		var runID uuid.UUID
+		var status models.RunStatus
+		if err := rows.Scan(&runID, &status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		if !finishedRunStatus(status) {
+			active = append(active, runID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock runs: %w", err)
+	}
+	if len(active) > 0 {
+		return nil, fmt.Errorf("%w: %d still running, e.g. %s", ErrUserHasActiveRuns, len(active), active[0])
+	}
+
+	result, err := tx.Exec(ctx, deleteUserTagMovesQuery, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete tag history: %w", err)
+	}
+	purge.TagMoves = result.RowsAffected()
+
+	rows, err = tx.Query(ctx, deleteUserTagsQuery, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete tags: %w", err)
+	}
+	for rows.Next() {
+		var tagName string
+		if err := rows.Scan(&tagName); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deleted tag: %w", err)
+		}
+		purge.Tags = append(purge.Tags, tagName)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete tags: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, deleteUserRunsQuery, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete runs: %w", err)
+	}
+	for rows.Next() {
+		var runID uuid.UUID
+		if err := rows.Scan(&runID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deleted run: %w", err)
+		}
+		purge.Runs = append(purge.Runs, runID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete runs: %w", err)
+	}
+
+	if len(purge.Runs) > 0 {
+		runIDs := runIDStrings(purge.Runs)
+		if _, err := tx.Exec(ctx, deleteRunPatchesQuery, runIDs); err != nil {
+			return nil, fmt.Errorf("failed to delete run patches: %w", err)
+		}
+		if _, err := tx.Exec(ctx, deleteAgentResultsQuery, runIDs); err != nil {
+			return nil, fmt.Errorf("failed to delete agent results: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit user purge: %w", err)
+	}
+
+	return purge, nil
+}
+
+// runIDStrings returns run IDs as the text the run_id columns keyed by text hold
+func runIDStrings(runIDs []uuid.UUID) []string {
+	ids := make([]string, len(runIDs))
+	for i, runID := range runIDs {
+		ids[i] = runID.String()
+	}
+	return ids
+}
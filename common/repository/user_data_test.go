@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lyzr/orchestrator/common/models"
+)
+
+func TestPurgeUserQueries(t *testing.T) {
+	// Everything the user owns is matched on the exact username, never a name prefix
+	assert.Contains(t, lockUserRunsQuery, "WHERE submitted_by = $1")
+	assert.Contains(t, lockUserRunsQuery, "FOR UPDATE")
+	assert.Equal(t, "DELETE FROM tag_move WHERE username = $1", deleteUserTagMovesQuery)
+	assert.Equal(t, "DELETE FROM tag WHERE username = $1 RETURNING tag_name", deleteUserTagsQuery)
+	assert.Equal(t, "DELETE FROM run WHERE submitted_by = $1 RETURNING run_id", deleteUserRunsQuery)
+	for _, query := range []string{deleteUserTagMovesQuery, deleteUserTagsQuery, deleteUserRunsQuery} {
+		assert.NotContains(t, query, "LIKE")
+	}
+
+	// Tables keyed by a text run ID are deleted for the whole list at once
+	assert.Equal(t, "DELETE FROM run_patches WHERE run_id = ANY($1::text[])", deleteRunPatchesQuery)
+	assert.Equal(t, "DELETE FROM agent_results WHERE run_id = ANY($1::text[])", deleteAgentResultsQuery)
+
+	runID := uuid.New()
+	assert.Equal(t, []string{runID.String()}, runIDStrings([]uuid.UUID{runID}))
+	assert.Empty(t, runIDStrings(nil))
+
+	for status, finished := range map[models.RunStatus]bool{
+		models.StatusCompleted:          true,
+		models.StatusFailed:             true,
+		models.StatusCancelled:          true,
+		models.StatusQueued:             false,
+		models.StatusRunning:            false,
+		models.StatusPaused:             false,
+		models.StatusStalled:            false,
+		models.StatusWaitingForApproval: false,
+	} {
+		assert.Equal(t, finished, finishedRunStatus(status), status)
+	}
+}
+
+func TestUserDataRepository_PurgeUser(t *testing.T) {
+	runs := newTestRunRepository(t)
+	repo := NewUserDataRepository(runs.db)
+	ctx := context.Background()
+	username := fmt.Sprintf("purge-test-%s", uuid.NewString())
+	other := fmt.Sprintf("purge-other-%s", uuid.NewString())
+	t.Cleanup(func() {
+		runs.db.Exec(context.Background(), "DELETE FROM run WHERE submitted_by = ANY($1::text[])", []string{username, other})
+	})
+
+	addResult := func(runID uuid.UUID) {
+		_, err := runs.db.Exec(ctx,
+			`INSERT INTO agent_results (job_id, run_id, node_id, status) VALUES ($1, $2, 'agent', 'completed')`,
+			uuid.New(), runID.String())
+		require.NoError(t, err)
+	}
+	countResults := func(runID uuid.UUID) int {
+		var count int
+		require.NoError(t, runs.db.QueryRow(ctx, `SELECT count(*) FROM agent_results WHERE run_id = $1`, runID.String()).Scan(&count))
+		return count
+	}
+
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	done := createTestRun(t, runs, username, models.StatusCompleted, base)
+	running := createTestRun(t, runs, username, models.StatusRunning, base.Add(time.Minute))
+	kept := createTestRun(t, runs, other, models.StatusRunning, base)
+	for _, run := range []*models.Run{done, running, kept} {
+		addResult(run.RunID)
+	}
+
+	// Refused while a run is active, deleting nothing
+	_, err := repo.PurgeUser(ctx, username)
+	assert.ErrorIs(t, err, ErrUserHasActiveRuns)
+	assert.Equal(t, 1, countResults(done.RunID))
+
+	_, err = runs.db.Exec(ctx, `UPDATE run SET status = $1 WHERE run_id = $2`, string(models.StatusCancelled), running.RunID)
+	require.NoError(t, err)
+
+	purge, err := repo.PurgeUser(ctx, username)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{done.RunID, running.RunID}, purge.Runs)
+	assert.Zero(t, countResults(done.RunID))
+	assert.Zero(t, countResults(running.RunID))
+
+	// Another user's run with its results is untouched
+	assert.Equal(t, 1, countResults(kept.RunID))
+	statuses, err := runs.GetStatuses(ctx, []uuid.UUID{done.RunID, kept.RunID})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]models.RunStatus{kept.RunID: models.StatusRunning}, statuses)
+
+	runs.db.Exec(ctx, `DELETE FROM agent_results WHERE run_id = $1`, kept.RunID.String())
+}
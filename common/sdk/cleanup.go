@@ -0,0 +1,127 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+// RunStateTTL is how long an active run's coordinator state lives in Redis
+// Set when each key is created. Once the run finishes, ExpireRunState
+// shortens it to the configured retention.
+const RunStateTTL = 24 * time.Hour
+
+// RunStateKeys returns the fixed keys of a run's coordinator state
+func RunStateKeys(runID string) []string {
+	return []string{
+		fmt.Sprintf("ir:%s", runID),
+		IRSourceKey(runID),
+		fmt.Sprintf("context:%s", runID),
+		InputsKey(runID),
+		CounterKey(runID),
+		AppliedKey(runID),
+		VarsKey(runID),
+		ConcurrencySlotsKey(runID),
+		ConcurrencyJobsKey(runID),
+		ReadyQueueKey(runID),
+		RunPausedKey(runID),
+		PausedSignalsKey(runID),
+		RunStalledKey(runID),
+		RunFinalizedKey(runID),
+		TimedOutJobsKey(runID),
+		NodeStartedKey(runID),
+		fmt.Sprintf("run:%s:pending_approvals", runID),
+		fmt.Sprintf("run:%s:status", runID),
+		RunKeysKey(runID),
+	}
+}
+
+// RunKeysKey returns the set of a run's per-node and per-loop keys
+// Node statuses, loop state and history, webhooks waiting for a callback and
+// node logs are named after their node, so they're added to the set when
+// created (see TrackRunKeys). The run's state can then be expired or deleted
+// without scanning the keyspace.
+func RunKeysKey(runID string) string {
+	return fmt.Sprintf("run:%s:keys", runID)
+}
+
+// TrackRunKeys adds keys created for a run to its key set
+func TrackRunKeys(ctx context.Context, rdb redis.UniversalClient, runID string, keys ...string) error {
+	pipe := rdb.Pipeline()
+	trackRunKeys(ctx, pipe, runID, keys...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to track run keys: %w", err)
+	}
+	return nil
+}
+
+// trackRunKeys queues adding keys to the run's key set on pipe
+// Not for transactions: the set and the keys may be in different cluster slots.
+func trackRunKeys(ctx context.Context, pipe redis.Pipeliner, runID string, keys ...string) {
+	members := make([]interface{}, len(keys))
+	for i, key := range keys {
+		members[i] = key
+	}
+	pipe.SAdd(ctx, RunKeysKey(runID), members...)
+	pipe.Expire(ctx, RunKeysKey(runID), RunStateTTL)
+}
+
+// runStateKeys returns every key of a run's coordinator state
+// The fixed keys followed by the members of the run's key set.
+func runStateKeys(ctx context.Context, client *rediscommon.Client, runID string) ([]string, error) {
+	tracked, err := client.GetUnderlying().SMembers(ctx, RunKeysKey(runID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run keys: %w", err)
+	}
+	return append(RunStateKeys(runID), tracked...), nil
+}
+
+// ExpireRunState sets all of a run's coordinator state to expire after ttl
+// The hot run status and the run's event log keep their own TTLs.
+func ExpireRunState(ctx context.Context, client *rediscommon.Client, runID string, ttl time.Duration) error {
+	keys, err := runStateKeys(ctx, client, runID)
+	if err != nil {
+		return fmt.Errorf("failed to expire run state: %w", err)
+	}
+	if err := client.Expire(ctx, ttl, keys...); err != nil {
+		return fmt.Errorf("failed to expire run state: %w", err)
+	}
+	return nil
+}
+
+// DeleteRunState deletes a run's coordinator state from Redis
+// The hot run status and the run's event log are kept: both outlive the run's state.
+func DeleteRunState(ctx context.Context, client *rediscommon.Client, runID string) error {
+	keys, err := runStateKeys(ctx, client, runID)
+	if err != nil {
+		return fmt.Errorf("failed to delete run state: %w", err)
+	}
+	if err := client.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to delete run state: %w", err)
+	}
+	return nil
+}
+
+// RunRecordKeys returns the keys of a run's hot status and event log
+func RunRecordKeys(runID string) []string {
+	return []string{
+		fmt.Sprintf("run:status:%s", runID),
+		fmt.Sprintf("run:events:%s", runID),
+	}
+}
+
+// PurgeRunState deletes everything Redis holds for a run
+// Unlike DeleteRunState it also deletes the hot run status and event log, for
+// runs that are being deleted rather than cleaned up after finishing.
+func PurgeRunState(ctx context.Context, client *rediscommon.Client, runID string) error {
+	if err := DeleteRunState(ctx, client, runID); err != nil {
+		return err
+	}
+	if err := client.Delete(ctx, RunRecordKeys(runID)...); err != nil {
+		return fmt.Errorf("failed to delete run records: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxConcurrencyKey is the IR metadata key capping a run's dispatched tokens
+// Set from the workflow's metadata, or per run when it is submitted.
+const MaxConcurrencyKey = "max_concurrency"
+
+// ConcurrencySlotsKey returns the count of a run's tokens holding a concurrency slot
+// Concurrency keys are hash-tagged with the run ID so the scripts are cluster-safe.
+func ConcurrencySlotsKey(runID string) string {
+	return fmt.Sprintf("concurrency:{%s}", runID)
+}
+
+// ConcurrencyJobsKey returns the set of job IDs holding a run's concurrency slots
+func ConcurrencyJobsKey(runID string) string {
+	return fmt.Sprintf("concurrency:{%s}:jobs", runID)
+}
+
+// ReadyQueueKey returns the list of a run's tokens waiting for a concurrency slot
+func ReadyQueueKey(runID string) string {
+	return fmt.Sprintf("ready:{%s}", runID)
+}
+
+// ReadyToken is a token ready to be added to its worker stream
+// Tokens of a run at its concurrency limit wait in the run's ready queue until
+// a slot is released.
+type ReadyToken struct {
+	JobID    string                 `json:"job_id"`
+	Stream   string                 `json:"stream"`
+	Values   map[string]interface{} `json:"values"`             // Stream entry fields
+	Inflight *InflightToken         `json:"inflight,omitempty"` // Tracked once dispatched, if set
+}
+
+// acquireSlotScript takes a concurrency slot for a job, or queues its token
+// KEYS[1] = slot count, KEYS[2] = slot jobs, KEYS[3] = ready queue
+// ARGV[1] = limit, ARGV[2] = job ID, ARGV[3] = ready token JSON, ARGV[4] = TTL (ms)
+// Returns 1 if the slot was taken, 0 if the token was queued.
+var acquireSlotScript = redis.NewScript(`
+local acquired = 0
+if tonumber(redis.call('GET', KEYS[1]) or '0') < tonumber(ARGV[1]) then
+    redis.call('INCR', KEYS[1])
+    redis.call('SADD', KEYS[2], ARGV[2])
+    acquired = 1
+else
+    redis.call('RPUSH', KEYS[3], ARGV[3])
+end
+for i = 1, 3 do
+    if redis.call('EXISTS', KEYS[i]) == 1 then
+        redis.call('PEXPIRE', KEYS[i], ARGV[4])
+    end
+end
+return acquired
+`)
+
+// releaseSlotScript releases a job's concurrency slot
+// The slot passes to the first queued token, if any.
+// KEYS as acquireSlotScript, ARGV[1] = job ID
+// Returns the queued token's JSON, or nil if the job held no slot or none was queued.
+var releaseSlotScript = redis.NewScript(`
+if redis.call('SREM', KEYS[2], ARGV[1]) == 0 then
+    return false
+end
+local entry = redis.call('LPOP', KEYS[3])
+if not entry then
+    redis.call('DECR', KEYS[1])
+    return false
+end
+redis.call('SADD', KEYS[2], cjson.decode(entry)['job_id'])
+return entry
+`)
+
+// MaxConcurrency returns a run's concurrency limit, or 0 if it has none
+func MaxConcurrency(ir *IR) int {
+	if ir == nil {
+		return 0
+	}
+	switch v := ir.Metadata[MaxConcurrencyKey].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// AcquireSlot takes one of a run's limit concurrency slots for a token
+// Returns false if all slots are taken; the token is then queued, and
+// dispatched when ReleaseSlot hands it a slot.
+func AcquireSlot(ctx context.Context, rdb redis.UniversalClient, runID string, limit int, token *ReadyToken) (bool, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal ready token: %w", err)
+	}
+
+	keys := []string{ConcurrencySlotsKey(runID), ConcurrencyJobsKey(runID), ReadyQueueKey(runID)}
+	acquired, err := acquireSlotScript.Run(ctx, rdb, keys, limit, token.JobID, data, RunStateTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire concurrency slot: %w", err)
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseSlot releases the concurrency slot a job holds
+// Returns the queued token the slot passed to, which the caller must dispatch,
+// or nil. Releasing a job without a slot does nothing, so repeated completions
+// of the same job release once.
+func ReleaseSlot(ctx context.Context, rdb redis.UniversalClient, runID, jobID string) (*ReadyToken, error) {
+	keys := []string{ConcurrencySlotsKey(runID), ConcurrencyJobsKey(runID), ReadyQueueKey(runID)}
+	data, err := releaseSlotScript.Run(ctx, rdb, keys, jobID).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to release concurrency slot: %w", err)
+	}
+
+	var token ReadyToken
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ready token: %w", err)
+	}
+	return &token, nil
+}
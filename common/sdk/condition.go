@@ -0,0 +1,93 @@
+package sdk
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+)
+
+// NewConditionEnv creates the CEL environment conditions and expressions run in
+// Used for branch and loop conditions, map selectors and the transform, filter
+// and aggregate expressions. Expressions can reference `output` (current node
+// output), `ctx` (previous node outputs), `vars` (run-level variables, see
+// SetVar) and, in loop conditions, `iteration` and `history` (the outputs of
+// earlier iterations, oldest first; 0 and empty elsewhere). Every expression can
+// also read any completed node's output through `nodes`, e.g.
+// nodes.score_a.output.score; only the nodes an expression references are
+// loaded (see ConditionNodeRefs). The compiler checks conditions against this
+// same environment so a workflow that compiles also evaluates.
+func NewConditionEnv() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("output", cel.DynType),
+		cel.Variable("ctx", cel.DynType),
+		cel.Variable("vars", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("iteration", cel.IntType),
+		cel.Variable("history", cel.ListType(cel.DynType)),
+		cel.Variable("nodes", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL env: %w", err)
+	}
+	return env, nil
+}
+
+// NormalizeConditionExpression converts JSONPath-style $.field to CEL output.field
+// This allows workflows to use $.approved instead of output.approved
+func NormalizeConditionExpression(expr string) string {
+	return strings.ReplaceAll(expr, "$.", "output.")
+}
+
+// nodeRefPattern matches node references such as nodes.fetch or nodes["fetch-2"]
+var nodeRefPattern = regexp.MustCompile(`\bnodes(?:\.([A-Za-z_][A-Za-z0-9_]*)|\[\s*["']([^"']+)["']\s*\])`)
+
+// ConditionNodeRefs returns the IDs of the nodes expr reads through `nodes`
+// IDs are returned once each, in order of first reference.
+func ConditionNodeRefs(expr string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, match := range nodeRefPattern.FindAllStringSubmatch(expr, -1) {
+		nodeID := match[1]
+		if nodeID == "" {
+			nodeID = match[2]
+		}
+		if !seen[nodeID] {
+			seen[nodeID] = true
+			refs = append(refs, nodeID)
+		}
+	}
+	return refs
+}
+
+// CheckCondition parses and type-checks a CEL condition in env
+// The expression must produce a boolean (or a dynamic value resolved at runtime).
+func CheckCondition(env *cel.Env, expr string) (*cel.Ast, error) {
+	ast, issues := env.Compile(NormalizeConditionExpression(expr))
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if out := ast.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression must return bool, got %s", out)
+	}
+
+	return ast, nil
+}
+
+// CheckSelector parses and type-checks a CEL collection selector in env
+// Used by map nodes; the expression must produce a list (or a dynamic value
+// resolved at runtime).
+func CheckSelector(env *cel.Env, expr string) (*cel.Ast, error) {
+	ast, issues := env.Compile(NormalizeConditionExpression(expr))
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if out := ast.OutputType(); out.Kind() != types.ListKind && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression must return a list, got %s", out)
+	}
+
+	return ast, nil
+}
@@ -0,0 +1,51 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RunFixture captures a finished run so its routing can be replayed in a test
+// The IR is the run's final (patched) IR and each executed node's recorded
+// output becomes its mock: replaying the fixture feeds those outputs back to
+// the coordinator, so branches and joins resolve exactly as they did in the run.
+//
+// Fixtures are taken from the same data the API returns, so masked fields
+// (see RedactionRules) are masked in the mocks too. Loop nodes keep only the
+// output of their last iteration, and variables their final values.
+type RunFixture struct {
+	RunID  string                            `json:"run_id"`
+	IR     *IR                               `json:"ir"`
+	Inputs map[string]interface{}            `json:"inputs,omitempty"`
+	Vars   map[string]interface{}            `json:"vars,omitempty"`
+	Mocks  map[string]map[string]interface{} `json:"mocks"`
+
+	// Terminal node outputs of the recorded run, for asserting a replay
+	Result map[string]interface{} `json:"result"`
+}
+
+// InputsKey returns the key holding a run's inputs
+func InputsKey(runID string) string {
+	return fmt.Sprintf("inputs:%s", runID)
+}
+
+// LoadInputs returns the inputs a run was created with, or nil if there are none
+// The orchestrator stores them at run creation (see InputsKey).
+func (s *SDK) LoadInputs(ctx context.Context, runID string) (map[string]interface{}, error) {
+	raw, err := s.redis.Get(ctx, InputsKey(runID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run inputs: %w", err)
+	}
+
+	var inputs map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &inputs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run inputs: %w", err)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,109 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SignalStatusTimeout is the completion status the timeout detector sends
+// when a dispatched token outlives its node's timeout
+const SignalStatusTimeout = "timeout"
+
+// InflightTokensKey is the hash of tokens dispatched to workers and not yet completed
+// Fields are job IDs, values JSON-encoded InflightToken records.
+const InflightTokensKey = "inflight_tokens"
+
+// TimedOutJobsKey returns the set of a run's job IDs given up on by the timeout detector
+func TimedOutJobsKey(runID string) string {
+	return fmt.Sprintf("run:%s:timed_out", runID)
+}
+
+// InflightToken records a token dispatched to a worker
+type InflightToken struct {
+	JobID      string `json:"job_id"`
+	RunID      string `json:"run_id"`
+	NodeID     string `json:"node_id"`
+	FromNode   string `json:"from_node"`
+	PayloadRef string `json:"payload_ref"`
+	SentAt     string `json:"sent_at"` // RFC3339Nano, as on the token
+	Attempt    int    `json:"attempt"` // 1 for the first dispatch
+}
+
+// TrackInflight records a dispatched token until its completion arrives
+func TrackInflight(ctx context.Context, rdb redis.UniversalClient, token *InflightToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal in-flight token: %w", err)
+	}
+	if err := rdb.HSet(ctx, InflightTokensKey, token.JobID, data).Err(); err != nil {
+		return fmt.Errorf("failed to track in-flight token: %w", err)
+	}
+	return nil
+}
+
+// ClearInflight stops tracking a token
+// Returns false if the token was not in flight (never tracked, or already
+// completed or claimed by ClaimTimedOut).
+func ClearInflight(ctx context.Context, rdb redis.UniversalClient, jobID string) (bool, error) {
+	n, err := rdb.HDel(ctx, InflightTokensKey, jobID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear in-flight token: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListInflight returns every tracked token
+// Records that fail to decode are skipped.
+func ListInflight(ctx context.Context, rdb redis.UniversalClient) ([]*InflightToken, error) {
+	entries, err := rdb.HGetAll(ctx, InflightTokensKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list in-flight tokens: %w", err)
+	}
+
+	tokens := make([]*InflightToken, 0, len(entries))
+	for _, data := range entries {
+		var token InflightToken
+		if err := json.Unmarshal([]byte(data), &token); err != nil {
+			continue
+		}
+		tokens = append(tokens, &token)
+	}
+	return tokens, nil
+}
+
+// ClaimTimedOut marks an in-flight token as timed out
+// The job is added to the run's timed-out set before it stops being tracked;
+// whoever removes the tracking entry first wins. Returns false if the token's
+// completion got there first, in which case the timeout must be dropped.
+func ClaimTimedOut(ctx context.Context, rdb redis.UniversalClient, token *InflightToken) (bool, error) {
+	key := TimedOutJobsKey(token.RunID)
+
+	pipe := rdb.Pipeline()
+	pipe.SAdd(ctx, key, token.JobID)
+	pipe.Expire(ctx, key, NodeStatusTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to mark token timed out: %w", err)
+	}
+
+	cleared, err := ClearInflight(ctx, rdb, token.JobID)
+	if err != nil {
+		return false, err
+	}
+	if !cleared {
+		rdb.SRem(ctx, key, token.JobID)
+		return false, nil
+	}
+	return true, nil
+}
+
+// IsJobTimedOut reports whether a job was claimed by ClaimTimedOut
+func IsJobTimedOut(ctx context.Context, rdb redis.UniversalClient, runID, jobID string) (bool, error) {
+	timedOut, err := rdb.SIsMember(ctx, TimedOutJobsKey(runID), jobID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check timed out token: %w", err)
+	}
+	return timedOut, nil
+}
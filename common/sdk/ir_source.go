@@ -0,0 +1,58 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IRSourceTTL is how long a run's IR source is kept in Redis
+// Longer than RunStateTTL, so a run that outlives its IR can still rebuild it.
+const IRSourceTTL = 7 * 24 * time.Hour
+
+// IRSourceKey returns the key of the record a run's IR was compiled from
+func IRSourceKey(runID string) string {
+	return fmt.Sprintf("ir:%s:source", runID)
+}
+
+// IRSource is what a run's IR is compiled from
+// If the IR expires while the run is still going, the coordinator recompiles
+// the artifact (with the run's patches applied) and restores Metadata, the
+// runtime metadata (username, tag, priority, ...) added when the run started.
+type IRSource struct {
+	ArtifactID string                 `json:"artifact_id"`
+	Username   string                 `json:"username"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// StoreIRSource records what a run's IR was compiled from
+func StoreIRSource(ctx context.Context, rdb redis.UniversalClient, runID string, source *IRSource) error {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal IR source: %w", err)
+	}
+	if err := rdb.Set(ctx, IRSourceKey(runID), data, IRSourceTTL).Err(); err != nil {
+		return fmt.Errorf("failed to store IR source: %w", err)
+	}
+	return nil
+}
+
+// LoadIRSource returns what a run's IR was compiled from, or nil if it isn't recorded
+func LoadIRSource(ctx context.Context, rdb redis.UniversalClient, runID string) (*IRSource, error) {
+	data, err := rdb.Get(ctx, IRSourceKey(runID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load IR source: %w", err)
+	}
+
+	var source IRSource
+	if err := json.Unmarshal([]byte(data), &source); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal IR source: %w", err)
+	}
+	return &source, nil
+}
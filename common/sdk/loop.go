@@ -0,0 +1,100 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LoopHistoryTTL is how long a loop node's iteration history lives after the last iteration
+const LoopHistoryTTL = 24 * time.Hour
+
+// Loop decisions, recorded for every iteration of a loop node
+const (
+	LoopDecisionContinue      = "continue"       // Looped back for another iteration
+	LoopDecisionBreak         = "break"          // Condition no longer held: exited via break_path
+	LoopDecisionMaxIterations = "max_iterations" // Ran out of iterations: exited via timeout_path
+)
+
+// Loop exit reasons, recorded when a loop node stops looping
+const (
+	LoopExitBreak   = "break"   // Condition no longer held (or couldn't be evaluated): took break_path
+	LoopExitTimeout = "timeout" // max_iterations reached: took timeout_path
+)
+
+// LoopIteration is one completion of a loop node and what the loop did next
+type LoopIteration struct {
+	Iteration   int64     `json:"iteration"`
+	ResultRef   string    `json:"result_ref"`       // CAS ref of the iteration's output
+	Decision    string    `json:"decision"`         // One of the LoopDecision* values
+	Reason      string    `json:"reason,omitempty"` // The condition evaluation behind the decision
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// LoopHistoryKey returns the list of a loop node's iterations, oldest first
+// Unlike the loop state hash (loop:<run>:<node>), it survives the loop exiting.
+func LoopHistoryKey(runID, nodeID string) string {
+	return fmt.Sprintf("loop:%s:%s:history", runID, nodeID)
+}
+
+// LoopExitKey returns why a loop node last exited, one of the LoopExit* values
+func LoopExitKey(runID, nodeID string) string {
+	return fmt.Sprintf("loop:%s:%s:exit", runID, nodeID)
+}
+
+// RecordLoopExit stores why a loop node exited
+func (s *SDK) RecordLoopExit(ctx context.Context, runID, nodeID, reason string) error {
+	key := LoopExitKey(runID, nodeID)
+	pipe := s.redis.Pipeline()
+	pipe.Set(ctx, key, reason, LoopHistoryTTL)
+	trackRunKeys(ctx, pipe, runID, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record loop exit: %w", err)
+	}
+	return nil
+}
+
+// ClearLoopExit forgets a loop node's exit reason, for when the loop is entered again
+func (s *SDK) ClearLoopExit(ctx context.Context, runID, nodeID string) error {
+	if err := s.redis.Del(ctx, LoopExitKey(runID, nodeID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear loop exit: %w", err)
+	}
+	return nil
+}
+
+// AppendLoopIteration adds an iteration to a loop node's history
+func (s *SDK) AppendLoopIteration(ctx context.Context, runID, nodeID string, iteration *LoopIteration) error {
+	data, err := json.Marshal(iteration)
+	if err != nil {
+		return fmt.Errorf("failed to marshal loop iteration: %w", err)
+	}
+
+	key := LoopHistoryKey(runID, nodeID)
+	pipe := s.redis.Pipeline()
+	pipe.RPush(ctx, key, string(data))
+	pipe.Expire(ctx, key, LoopHistoryTTL)
+	trackRunKeys(ctx, pipe, runID, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append loop iteration: %w", err)
+	}
+	return nil
+}
+
+// LoadLoopHistory returns a loop node's iterations, oldest first
+func (s *SDK) LoadLoopHistory(ctx context.Context, runID, nodeID string) ([]*LoopIteration, error) {
+	raw, err := s.redis.LRange(ctx, LoopHistoryKey(runID, nodeID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loop history: %w", err)
+	}
+
+	history := make([]*LoopIteration, 0, len(raw))
+	for _, entry := range raw {
+		var iteration LoopIteration
+		if err := json.Unmarshal([]byte(entry), &iteration); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal loop iteration: %w", err)
+		}
+		history = append(history, &iteration)
+	}
+	return history, nil
+}
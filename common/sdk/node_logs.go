@@ -0,0 +1,98 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Node log levels, from least to most severe
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// logLevelSeverity orders the node log levels
+var logLevelSeverity = map[string]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// NodeLogMaxEntries caps a node's log; older lines are trimmed as new ones arrive
+const NodeLogMaxEntries = 1000
+
+// NodeLogMaxMessageLength caps a log line's message; longer ones are truncated
+const NodeLogMaxMessageLength = 4096
+
+// NodeLogKey returns the list holding a node's log lines, oldest first
+// Node logs live for RunStateTTL after their last line, and are expired with the
+// rest of the run's state once it finishes (see RunKeysKey).
+func NodeLogKey(runID, nodeID string) string {
+	return fmt.Sprintf("logs:%s:%s", runID, nodeID)
+}
+
+// NodeLogEntry is one structured log line of a node execution
+type NodeLogEntry struct {
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// ValidLogLevel returns true for a known node log level
+func ValidLogLevel(level string) bool {
+	_, ok := logLevelSeverity[level]
+	return ok
+}
+
+// AppendNodeLog adds a line to a node's log, trimming it to NodeLogMaxEntries
+func AppendNodeLog(ctx context.Context, rdb redis.UniversalClient, runID, nodeID string, entry *NodeLogEntry) error {
+	if len(entry.Message) > NodeLogMaxMessageLength {
+		entry.Message = entry.Message[:NodeLogMaxMessageLength]
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal node log entry: %w", err)
+	}
+
+	key := NodeLogKey(runID, nodeID)
+	pipe := rdb.Pipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, -NodeLogMaxEntries, -1)
+	pipe.Expire(ctx, key, RunStateTTL)
+	trackRunKeys(ctx, pipe, runID, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append node log: %w", err)
+	}
+	return nil
+}
+
+// LoadNodeLogs returns a node's log lines at minLevel or above, oldest first
+// An empty minLevel returns every line. Lines that fail to decode are skipped.
+func LoadNodeLogs(ctx context.Context, rdb redis.UniversalClient, runID, nodeID, minLevel string) ([]NodeLogEntry, error) {
+	values, err := rdb.LRange(ctx, NodeLogKey(runID, nodeID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load node logs: %w", err)
+	}
+
+	entries := make([]NodeLogEntry, 0, len(values))
+	for _, value := range values {
+		var entry NodeLogEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			continue
+		}
+		if minLevel != "" && logLevelSeverity[entry.Level] < logLevelSeverity[minLevel] {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,115 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Node statuses tracked in Redis while a run is in flight
+const (
+	NodeStatusRunning            = "running"
+	NodeStatusWaitingForApproval = "waiting_for_approval"
+	NodeStatusWaitingForCallback = "waiting_for_callback"
+	NodeStatusCompleted          = "completed"
+	NodeStatusFailed             = "failed"
+	NodeStatusCancelled          = "cancelled"
+)
+
+// NodeStatusTTL is how long per-node status keys live
+const NodeStatusTTL = 24 * time.Hour
+
+// NodeStatusKey returns the status key for a node in a run
+func NodeStatusKey(runID, nodeID string) string {
+	return fmt.Sprintf("run:%s:node:%s:status", runID, nodeID)
+}
+
+// setNodeStatusScript sets a node status unless the node was cancelled
+// KEYS[1] = status key, ARGV[1] = status, ARGV[2] = ttl seconds
+// Returns 1 if set, 0 if the node is cancelled.
+var setNodeStatusScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == 'cancelled' then
+    return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[2])
+return 1
+`)
+
+// cancelNodeScript marks an in-flight node cancelled
+// KEYS[1] = status key, ARGV[1] = ttl seconds
+// Returns {1, previous} if cancelled, {0, previous} otherwise.
+var cancelNodeScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1]) or ''
+if current ~= 'running' and current ~= 'waiting_for_approval' and current ~= 'waiting_for_callback' then
+    return {0, current}
+end
+redis.call('SET', KEYS[1], 'cancelled', 'EX', ARGV[1])
+return {1, current}
+`)
+
+// SetNodeStatus records a node status without overwriting a cancellation
+// Returns false if the node has been cancelled (the caller should drop its update).
+func SetNodeStatus(ctx context.Context, rdb redis.UniversalClient, runID, nodeID, status string) (bool, error) {
+	key := NodeStatusKey(runID, nodeID)
+	set, err := setNodeStatusScript.Run(ctx, rdb, []string{key}, status, int(NodeStatusTTL.Seconds())).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to set node status: %w", err)
+	}
+	if set == 1 {
+		if err := TrackRunKeys(ctx, rdb, runID, key); err != nil {
+			return true, err
+		}
+	}
+	return set == 1, nil
+}
+
+// GetNodeStatus returns a node's recorded status, or "" if it has none
+func GetNodeStatus(ctx context.Context, rdb redis.UniversalClient, runID, nodeID string) (string, error) {
+	status, err := rdb.Get(ctx, NodeStatusKey(runID, nodeID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get node status: %w", err)
+	}
+	return status, nil
+}
+
+// CancelNode atomically marks a running node as cancelled
+// Only nodes that are running or waiting for approval/a callback can be cancelled.
+// Returns the status the node had before the call.
+func CancelNode(ctx context.Context, rdb redis.UniversalClient, runID, nodeID string) (bool, string, error) {
+	key := NodeStatusKey(runID, nodeID)
+	result, err := cancelNodeScript.Run(ctx, rdb, []string{key}, int(NodeStatusTTL.Seconds())).Slice()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to cancel node: %w", err)
+	}
+	if len(result) != 2 {
+		return false, "", fmt.Errorf("unexpected result format from cancel script")
+	}
+
+	cancelled, _ := result[0].(int64)
+	previous, _ := result[1].(string)
+	return cancelled == 1, previous, nil
+}
+
+// NodeStartedKey returns the hash recording when each node of a run started
+// Fields are node IDs, values RFC3339Nano timestamps written by the workers.
+func NodeStartedKey(runID string) string {
+	return fmt.Sprintf("run:%s:node_started", runID)
+}
+
+// RecordNodeStarted records when a worker started executing a node
+// A later attempt overwrites the time of an earlier one.
+func RecordNodeStarted(ctx context.Context, rdb redis.UniversalClient, runID, nodeID string, at time.Time) error {
+	key := NodeStartedKey(runID)
+	pipe := rdb.Pipeline()
+	pipe.HSet(ctx, key, nodeID, at.UTC().Format(time.RFC3339Nano))
+	pipe.Expire(ctx, key, NodeStatusTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record node start: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RunPausedKey returns the flag set while a run is paused
+// The run ID is hash-tagged so the flag and the signal buffer share a cluster slot.
+func RunPausedKey(runID string) string {
+	return fmt.Sprintf("run:{%s}:paused", runID)
+}
+
+// PausedSignalsKey returns the list buffering completion signals of a paused run
+func PausedSignalsKey(runID string) string {
+	return fmt.Sprintf("run:{%s}:paused_signals", runID)
+}
+
+// bufferIfPausedScript buffers a completion signal if the run is paused
+// KEYS[1] = paused flag, KEYS[2] = buffer list, ARGV[1] = signal JSON
+// Returns 1 if buffered, 0 if the run is not paused.
+var bufferIfPausedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return 0
+end
+redis.call('RPUSH', KEYS[2], ARGV[1])
+return 1
+`)
+
+// unpauseIfDrainedScript clears the paused flag once the buffer is empty
+// KEYS[1] = paused flag, KEYS[2] = buffer list
+// Returns 1 if cleared, 0 if signals are still buffered, -1 if the run is not paused.
+var unpauseIfDrainedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+    return -1
+end
+if redis.call('LLEN', KEYS[2]) > 0 then
+    return 0
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
+// PauseRun sets a run's paused flag
+// Returns false if the run was already paused.
+func PauseRun(ctx context.Context, rdb redis.UniversalClient, runID string) (bool, error) {
+	set, err := rdb.SetNX(ctx, RunPausedKey(runID), "1", 0).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to pause run: %w", err)
+	}
+	return set, nil
+}
+
+// IsRunPaused reports whether a run is paused
+func IsRunPaused(ctx context.Context, rdb redis.UniversalClient, runID string) (bool, error) {
+	n, err := rdb.Exists(ctx, RunPausedKey(runID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check paused run: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ResumeRun clears a run's paused flag and replays its buffered signals
+// Buffered completion signals are moved back onto the completion queue in the
+// order they arrived. The completion queue lives in another cluster slot than
+// the run's keys, so signals are moved one at a time while the run stays
+// paused, and the flag is only cleared once the buffer is empty; signals
+// arriving meanwhile are buffered and moved too, keeping their order.
+// Returns false if the run was not paused.
+func ResumeRun(ctx context.Context, rdb redis.UniversalClient, runID string) (bool, int, error) {
+	paused, err := IsRunPaused(ctx, rdb, runID)
+	if err != nil || !paused {
+		return false, 0, err
+	}
+
+	keys := []string{RunPausedKey(runID), PausedSignalsKey(runID)}
+	count := 0
+	for {
+		signal, err := rdb.LPop(ctx, PausedSignalsKey(runID)).Result()
+		if err == nil {
+			if err := rdb.RPush(ctx, "completion_signals", signal).Err(); err != nil {
+				// Put it back, so a retried resume replays it
+				rdb.LPush(ctx, PausedSignalsKey(runID), signal)
+				return false, count, fmt.Errorf("failed to requeue paused signal: %w", err)
+			}
+			count++
+			continue
+		}
+		if err != redis.Nil {
+			return false, count, fmt.Errorf("failed to replay paused signals: %w", err)
+		}
+
+		cleared, err := unpauseIfDrainedScript.Run(ctx, rdb, keys).Int()
+		if err != nil {
+			return false, count, fmt.Errorf("failed to resume run: %w", err)
+		}
+		switch cleared {
+		case 1:
+			return true, count, nil
+		case -1:
+			// Resumed concurrently
+			return count > 0, count, nil
+		}
+		// A signal was buffered since the last pop
+	}
+}
+
+// BufferSignalIfPaused holds a completion signal while its run is paused
+// The check and the buffering are atomic, so a signal can't slip in between a
+// resume draining the buffer and clearing the flag. Returns true if buffered.
+func BufferSignalIfPaused(ctx context.Context, rdb redis.UniversalClient, runID string, signal []byte) (bool, error) {
+	keys := []string{RunPausedKey(runID), PausedSignalsKey(runID)}
+	buffered, err := bufferIfPausedScript.Run(ctx, rdb, keys, signal).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to check paused run: %w", err)
+	}
+	return buffered == 1, nil
+}
@@ -0,0 +1,106 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RunProgressKey is the sorted set of runs by when their counter last changed
+// Scored by unix milliseconds; the stall detector scans it for runs that stopped
+// progressing and removes runs that have finished.
+const RunProgressKey = "runs:progress"
+
+// RunStalledKey returns the flag set while a run is flagged as stalled
+// Holds the unix milliseconds of the progress the run stalled at. Cleared when
+// the run's counter changes again.
+func RunStalledKey(runID string) string {
+	return fmt.Sprintf("run:%s:stalled", runID)
+}
+
+// RecordProgress notes that a run's counter changed at the given time
+func RecordProgress(ctx context.Context, rdb redis.UniversalClient, runID string, at time.Time) error {
+	pipe := rdb.TxPipeline()
+	pipe.ZAdd(ctx, RunProgressKey, redis.Z{Score: float64(at.UnixMilli()), Member: runID})
+	pipe.Del(ctx, RunStalledKey(runID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record run progress: %w", err)
+	}
+	return nil
+}
+
+// IdleRuns returns the runs whose counter hasn't changed since before, with the
+// time of their last change
+func IdleRuns(ctx context.Context, rdb redis.UniversalClient, before time.Time) (map[string]time.Time, error) {
+	entries, err := rdb.ZRangeByScoreWithScores(ctx, RunProgressKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(before.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list idle runs: %w", err)
+	}
+
+	runs := make(map[string]time.Time, len(entries))
+	for _, entry := range entries {
+		if runID, ok := entry.Member.(string); ok {
+			runs[runID] = time.UnixMilli(int64(entry.Score))
+		}
+	}
+	return runs, nil
+}
+
+// TrackedRuns returns every run whose progress is tracked, i.e. every unfinished run
+func TrackedRuns(ctx context.Context, rdb redis.UniversalClient) ([]string, error) {
+	runs, err := rdb.ZRange(ctx, RunProgressKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tracked runs: %w", err)
+	}
+	return runs, nil
+}
+
+// ForgetProgress stops tracking a run's progress, once it has finished
+func ForgetProgress(ctx context.Context, rdb redis.UniversalClient, runID string) error {
+	if err := rdb.ZRem(ctx, RunProgressKey, runID).Err(); err != nil {
+		return fmt.Errorf("failed to forget run progress: %w", err)
+	}
+	return nil
+}
+
+// FlagStalled marks a run as stalled at its last progress
+// Returns false if the run was already flagged, so each stall is reported once.
+func FlagStalled(ctx context.Context, rdb redis.UniversalClient, runID string, lastProgress time.Time) (bool, error) {
+	set, err := rdb.SetNX(ctx, RunStalledKey(runID), lastProgress.UnixMilli(), RunStateTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to flag stalled run: %w", err)
+	}
+	return set, nil
+}
+
+// RunFinalizedKey returns the claim of the supervisor that marked a run completed
+func RunFinalizedKey(runID string) string {
+	return fmt.Sprintf("run:%s:finalized", runID)
+}
+
+// ClaimFinalization records that a run is being marked completed
+// Every workflow-runner replica receives each completion event; only the one
+// whose claim succeeds persists the status and publishes workflow_completed.
+// Returns false if the run was already claimed.
+func ClaimFinalization(ctx context.Context, rdb redis.UniversalClient, runID string) (bool, error) {
+	set, err := rdb.SetNX(ctx, RunFinalizedKey(runID), time.Now().UnixMilli(), RunStateTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim run finalization: %w", err)
+	}
+	return set, nil
+}
+
+// ReleaseFinalization drops a run's finalization claim, so it can be finalized again
+// Used when finalizing failed, and when a run is reactivated to run again.
+func ReleaseFinalization(ctx context.Context, rdb redis.UniversalClient, runID string) error {
+	if err := rdb.Del(ctx, RunFinalizedKey(runID)).Err(); err != nil {
+		return fmt.Errorf("failed to release run finalization: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// SignalStatusRetry is the completion signal status asking the coordinator to
+// run a node again (see worker.SignalRetry)
+const SignalStatusRetry = "retry"
+
+// ReactivateNodes resets nodes so they can run again within the same run
+// Used to retry a failed run from nodeID. The node and its downstream nodes
+// lose their status, start time, output and failure context, and their
+// consume operations are forgotten so their next completions count again.
+// The counter is settled for the whole reset set, so that afterwards it holds
+// exactly nodeID's slot for it: nodeID's slot is restored if its token was
+// already consumed (it completed before), and downstream nodes that still hold
+// a slot (they were dispatched and failed or are still in flight) release it,
+// since routing emits them afresh when it reaches them again.
+// Outputs of nodes outside the reset set are left alone and reused.
+func (s *SDK) ReactivateNodes(ctx context.Context, runID, nodeID string, downstream []string) error {
+	contextKey := fmt.Sprintf("context:%s", runID)
+
+	pipe := s.redis.Pipeline()
+	reclaimed := pipe.SRem(ctx, AppliedKey(runID), fmt.Sprintf("consume:%s:%s", runID, nodeID))
+	downstreamReclaimed := make([]*redis.IntCmd, len(downstream))
+	downstreamStatus := make([]*redis.StringCmd, len(downstream))
+	for i, id := range downstream {
+		downstreamReclaimed[i] = pipe.SRem(ctx, AppliedKey(runID), fmt.Sprintf("consume:%s:%s", runID, id))
+		// Read before the reset below deletes it
+		downstreamStatus[i] = pipe.Get(ctx, NodeStatusKey(runID, id))
+	}
+	for _, id := range append([]string{nodeID}, downstream...) {
+		pipe.Del(ctx, NodeStatusKey(runID, id))
+		pipe.HDel(ctx, contextKey, id+":output", id+":failure:output")
+		pipe.HDel(ctx, NodeStartedKey(runID), id)
+	}
+	// The run is active again and will need finalizing once more
+	pipe.Del(ctx, RunFinalizedKey(runID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to reset nodes: %w", err)
+	}
+
+	// A token that was never consumed (the node failed) is still counted
+	delta := 0
+	if reclaimed.Val() == 1 {
+		delta++
+	}
+	var released []string
+	for i, id := range downstream {
+		dispatched := downstreamStatus[i].Val() != ""
+		if dispatched && downstreamReclaimed[i].Val() == 0 {
+			delta--
+			released = append(released, id)
+		}
+	}
+	if delta == 0 {
+		return nil
+	}
+
+	opKey := fmt.Sprintf("reactivate:%s:%s:%s", runID, nodeID, uuid.New().String())
+	result, err := s.ApplyDelta(ctx, runID, opKey, delta)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("counter settled for reactivated nodes",
+		"run_id", runID,
+		"node_id", nodeID,
+		"released", released,
+		"delta", delta,
+		"counter", result.CounterValue)
+
+	return nil
+}
+
+// LoadOutputRef returns the CAS reference of a node's output, or "" if none
+func (s *SDK) LoadOutputRef(ctx context.Context, runID, nodeID string) (string, error) {
+	ref, err := s.redis.HGet(ctx, fmt.Sprintf("context:%s", runID), nodeID+":output").Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get node output reference: %w", err)
+	}
+	return ref, nil
+}
@@ -0,0 +1,132 @@
+package sdk
+
+import (
+	"strings"
+)
+
+// Redaction keeps sensitive fields of node outputs out of storage or API responses.
+// Node configs declare rules under "redact":
+//
+//	"redact": [
+//	  {"path": "body.ssn", "action": "drop"},
+//	  {"path": "body.users.*.email", "action": "mask"}
+//	]
+//
+// Paths are dot-separated; "*" matches every key of an object or element of
+// an array.
+//   - drop: the field is removed before the output is stored in CAS, so it
+//     is never persisted and downstream nodes don't see it. The API masks it
+//     as well, for outputs stored before the rule was added.
+//   - mask: the field is stored (downstream nodes can use it) but its value is
+//     replaced with RedactedValue wherever run outputs are returned by the API.
+
+// ConfigKeyRedact is the node config key holding redaction rules
+const ConfigKeyRedact = "redact"
+
+// Redaction actions
+const (
+	RedactActionDrop = "drop"
+	RedactActionMask = "mask"
+)
+
+// RedactedValue replaces masked fields
+const RedactedValue = "[REDACTED]"
+
+// RedactionRule is a single field redaction
+type RedactionRule struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+}
+
+// RedactionRules returns the node's redaction rules with the given action
+// Malformed entries are ignored; a rule without an action defaults to mask.
+func RedactionRules(config map[string]interface{}, action string) []RedactionRule {
+	raw, _ := config[ConfigKeyRedact].([]interface{})
+
+	var rules []RedactionRule
+	for _, entry := range raw {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path, _ := fields["path"].(string)
+		if path == "" {
+			continue
+		}
+		ruleAction, _ := fields["action"].(string)
+		if ruleAction == "" {
+			ruleAction = RedactActionMask
+		}
+		if ruleAction == action {
+			rules = append(rules, RedactionRule{Path: path, Action: ruleAction})
+		}
+	}
+	return rules
+}
+
+// Redact applies rules to output in place and returns how many fields were redacted
+func Redact(output map[string]interface{}, rules []RedactionRule) int {
+	redacted := 0
+	for _, rule := range rules {
+		redacted += redactPath(output, strings.Split(rule.Path, "."), rule.Action)
+	}
+	return redacted
+}
+
+// redactPath walks one path segment at a time
+func redactPath(value interface{}, segments []string, action string) int {
+	segment, last := segments[0], len(segments) == 1
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := []string{segment}
+		if segment == "*" {
+			keys = keys[:0]
+			for key := range v {
+				keys = append(keys, key)
+			}
+		}
+
+		redacted := 0
+		for _, key := range keys {
+			child, ok := v[key]
+			if !ok {
+				continue
+			}
+			if !last {
+				redacted += redactPath(child, segments[1:], action)
+				continue
+			}
+			if action == RedactActionDrop {
+				delete(v, key)
+			} else {
+				v[key] = RedactedValue
+			}
+			redacted++
+		}
+		return redacted
+
+	case []interface{}:
+		if segment != "*" {
+			return 0
+		}
+
+		redacted := 0
+		for i := range v {
+			if !last {
+				redacted += redactPath(v[i], segments[1:], action)
+				continue
+			}
+			// Array elements can't be removed without shifting indexes; drop empties them
+			if action == RedactActionDrop {
+				v[i] = nil
+			} else {
+				v[i] = RedactedValue
+			}
+			redacted++
+		}
+		return redacted
+	}
+
+	return 0
+}
@@ -0,0 +1,52 @@
+package sdk
+
+import "time"
+
+// Retry defaults from workflow.schema.json#/definitions/RetryPolicy
+const (
+	DefaultRetryMaxAttempts       = 3
+	DefaultRetryBackoffMS         = 1000
+	DefaultRetryBackoffMultiplier = 2.0
+)
+
+// RetryPolicy controls how a worker retries a failed node execution
+// Schema: common/schema/workflow.schema.json#/definitions/RetryPolicy
+type RetryPolicy struct {
+	MaxAttempts       int     `json:"max_attempts,omitempty"`
+	BackoffMS         int     `json:"backoff_ms,omitempty"`
+	BackoffMultiplier float64 `json:"backoff_multiplier,omitempty"`
+}
+
+// Attempts returns the total number of attempts allowed (including the first)
+// A nil policy means a single attempt; unset fields fall back to schema defaults.
+func (p *RetryPolicy) Attempts() int {
+	if p == nil {
+		return 1
+	}
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// Backoff returns the delay before the given retry (1 = first retry)
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	if p == nil || retry < 1 {
+		return 0
+	}
+
+	backoffMS := p.BackoffMS
+	if backoffMS <= 0 {
+		backoffMS = DefaultRetryBackoffMS
+	}
+	multiplier := p.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = DefaultRetryBackoffMultiplier
+	}
+
+	delay := float64(backoffMS)
+	for i := 1; i < retry; i++ {
+		delay *= multiplier
+	}
+	return time.Duration(delay) * time.Millisecond
+}
@@ -3,19 +3,22 @@ package sdk
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lyzr/orchestrator/common/clients"
+	rediscommon "github.com/lyzr/orchestrator/common/redis"
 	"github.com/redis/go-redis/v9"
 )
 
 // SDK provides core workflow execution capabilities
 type SDK struct {
-	redis     *redis.Client
+	redis     redis.UniversalClient
 	CASClient clients.CASClient
 	logger    Logger
-	script    *redis.Script
+	script    *rediscommon.Script
 }
 
 // Logger interface for SDK logging
@@ -27,25 +30,41 @@ type Logger interface {
 }
 
 // NewSDK creates a new SDK instance
-func NewSDK(redisClient *redis.Client, casClient clients.CASClient, logger Logger, luaScript string) *SDK {
+func NewSDK(redisClient redis.UniversalClient, casClient clients.CASClient, logger Logger, luaScript string) *SDK {
 	return &SDK{
 		redis:     redisClient,
 		CASClient: casClient,
 		logger:    logger,
-		script:    redis.NewScript(luaScript),
+		script:    rediscommon.NewClient(redisClient, logger).LoadScript(luaScript),
 	}
 }
 
+// CompletionEventsChannel is the pub/sub channel apply_delta.lua publishes a
+// run ID to when the run's counter reaches zero
+const CompletionEventsChannel = "completion_events"
+
+// CounterKey returns the token counter key for a run
+// The run ID is hash-tagged so the counter and applied set share a cluster slot.
+func CounterKey(runID string) string {
+	return fmt.Sprintf("counter:{%s}", runID)
+}
+
+// AppliedKey returns the applied-operations set key for a run
+func AppliedKey(runID string) string {
+	return fmt.Sprintf("applied:{%s}", runID)
+}
+
 // ApplyDelta applies a counter operation (idempotent)
-// Returns (counter_value, hit_zero, error)
+// Returns (counter_value, hit_zero, error). Applied operations record the run's
+// progress (see RecordProgress) for stall detection, and renew the counter and
+// applied set for another RunStateTTL.
 func (s *SDK) ApplyDelta(ctx context.Context, runID string, opKey string, delta int) (*ApplyDeltaResult, error) {
-	appliedSet := fmt.Sprintf("applied:%s", runID)
-	counterKey := fmt.Sprintf("counter:%s", runID)
-
-	keys := []string{appliedSet, counterKey, runID}
-	args := []interface{}{opKey, delta}
+	// Both keys carry the same hash tag, so the script is cluster-safe.
+	// run_id is passed as an argument (not a key) to avoid CROSSSLOT errors.
+	keys := []string{AppliedKey(runID), CounterKey(runID)}
+	args := []interface{}{opKey, delta, runID, RunStateTTL.Milliseconds()}
 
-	result, err := s.script.Run(ctx, s.redis, keys, args...).Result()
+	result, err := s.script.Run(ctx, keys, args...).Result()
 	if err != nil {
 		return nil, fmt.Errorf("apply delta failed: %w", err)
 	}
@@ -71,6 +90,13 @@ func (s *SDK) ApplyDelta(ctx context.Context, runID string, opKey string, delta
 		return nil, fmt.Errorf("invalid hit_zero flag type")
 	}
 
+	// Best effort: a missed record only makes the run look idle for longer
+	if changed == 1 {
+		if err := RecordProgress(ctx, s.redis, runID, time.Now()); err != nil {
+			s.logger.Warn("failed to record run progress", "run_id", runID, "error", err)
+		}
+	}
+
 	return &ApplyDeltaResult{
 		CounterValue: int(counterValue),
 		Changed:      changed == 1,
@@ -101,6 +127,55 @@ func (s *SDK) Consume(ctx context.Context, runID, nodeID string) error {
 	return nil
 }
 
+// ConsumeJob applies -1 to counter for one execution of a node
+// Used for nodes that run several times concurrently (map bodies), where the
+// per-node key of Consume would drop all but the first completion.
+func (s *SDK) ConsumeJob(ctx context.Context, runID, nodeID, jobID string) error {
+	opKey := fmt.Sprintf("consume:%s:%s:%s", runID, nodeID, jobID)
+
+	result, err := s.ApplyDelta(ctx, runID, opKey, -1)
+	if err != nil {
+		return err
+	}
+
+	if result.Changed {
+		s.logger.Info("token consumed",
+			"run_id", runID,
+			"node_id", nodeID,
+			"job_id", jobID,
+			"counter", result.CounterValue)
+	} else {
+		s.logger.Info("token already consumed (idempotent)",
+			"run_id", runID,
+			"node_id", nodeID,
+			"job_id", jobID)
+	}
+
+	return nil
+}
+
+// ClaimCompletion records that a completion signal is being handled
+// Returns false if a signal with the same key was claimed before, so a worker
+// retrying its completion cannot consume and route the node twice. The key
+// shares the applied set with counter operations and expires with it.
+func (s *SDK) ClaimCompletion(ctx context.Context, runID, key string) (bool, error) {
+	pipe := s.redis.TxPipeline()
+	added := pipe.SAdd(ctx, AppliedKey(runID), "completion:"+key)
+	pipe.Expire(ctx, AppliedKey(runID), RunStateTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to claim completion: %w", err)
+	}
+	return added.Val() == 1, nil
+}
+
+// ReleaseCompletion forgets a claimed completion so the signal can be handled again
+func (s *SDK) ReleaseCompletion(ctx context.Context, runID, key string) error {
+	if err := s.redis.SRem(ctx, AppliedKey(runID), "completion:"+key).Err(); err != nil {
+		return fmt.Errorf("failed to release completion: %w", err)
+	}
+	return nil
+}
+
 // Emit applies +N to counter (don't publish tokens - coordinator does that)
 func (s *SDK) Emit(ctx context.Context, runID, fromNode string, toNodes []string, payloadRef string) error {
 	if len(toNodes) == 0 {
@@ -138,8 +213,10 @@ func (s *SDK) Emit(ctx context.Context, runID, fromNode string, toNodes []string
 func (s *SDK) StoreContext(ctx context.Context, runID, nodeID, outputRef string) error {
 	contextKey := fmt.Sprintf("context:%s", runID)
 
-	err := s.redis.HSet(ctx, contextKey, nodeID+":output", outputRef).Err()
-	if err != nil {
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, contextKey, nodeID+":output", outputRef)
+	pipe.Expire(ctx, contextKey, RunStateTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to store context: %w", err)
 	}
 
@@ -183,6 +260,10 @@ func (s *SDK) LoadContext(ctx context.Context, runID string) (map[string]interfa
 	return context, nil
 }
 
+// ErrNodeOutputNotFound is returned when a node has no output in the run's context
+// The node hasn't completed yet, was skipped, or doesn't exist.
+var ErrNodeOutputNotFound = errors.New("node output not found")
+
 // LoadNodeOutput loads a specific node's output from context
 func (s *SDK) LoadNodeOutput(ctx context.Context, runID, nodeID string) (interface{}, error) {
 	contextKey := fmt.Sprintf("context:%s", runID)
@@ -191,7 +272,7 @@ func (s *SDK) LoadNodeOutput(ctx context.Context, runID, nodeID string) (interfa
 	// Get CAS reference for this node's output
 	casRef, err := s.redis.HGet(ctx, contextKey, outputKey).Result()
 	if err == redis.Nil {
-		return nil, fmt.Errorf("node output not found: %s", nodeID)
+		return nil, fmt.Errorf("%w: %s", ErrNodeOutputNotFound, nodeID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get node output reference: %w", err)
@@ -251,9 +332,7 @@ func (s *SDK) StoreOutput(ctx context.Context, output interface{}) (string, erro
 
 // GetCounter returns the current counter value
 func (s *SDK) GetCounter(ctx context.Context, runID string) (int, error) {
-	counterKey := fmt.Sprintf("counter:%s", runID)
-
-	val, err := s.redis.Get(ctx, counterKey).Int()
+	val, err := s.redis.Get(ctx, CounterKey(runID)).Int()
 	if err == redis.Nil {
 		return 0, nil
 	}
@@ -265,14 +344,18 @@ func (s *SDK) GetCounter(ctx context.Context, runID string) (int, error) {
 }
 
 // InitializeCounter initializes the counter for a new run
+// The counter lives for RunStateTTL; each counter operation renews it, and the
+// stall detector renews the state of runs waiting on a person or a callback.
 func (s *SDK) InitializeCounter(ctx context.Context, runID string, initialValue int) error {
-	counterKey := fmt.Sprintf("counter:%s", runID)
-
-	err := s.redis.Set(ctx, counterKey, initialValue, 0).Err()
+	err := s.redis.Set(ctx, CounterKey(runID), initialValue, RunStateTTL).Err()
 	if err != nil {
 		return fmt.Errorf("failed to initialize counter: %w", err)
 	}
 
+	if err := RecordProgress(ctx, s.redis, runID, time.Now()); err != nil {
+		s.logger.Warn("failed to record run progress", "run_id", runID, "error", err)
+	}
+
 	s.logger.Info("counter initialized",
 		"run_id", runID,
 		"value", initialValue)
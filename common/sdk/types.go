@@ -28,6 +28,9 @@ type Token struct {
 	// without implementing resolvers in each language
 	Config map[string]interface{} `json:"config,omitempty"`
 
+	// Run priority: high, normal or low ("" is normal); see redis.PriorityStream
+	Priority string `json:"priority,omitempty"`
+
 	// Hop count (for tracking traversal depth)
 	Hop int `json:"hop"`
 
@@ -65,14 +68,18 @@ type NodeContext struct {
 type Node struct {
 	ID           string                 `json:"id"`
 	Type         string                 `json:"type"`
-	ConfigRef    string                 `json:"config_ref,omitempty"`           // CAS reference for config
-	Config       map[string]interface{} `json:"config,omitempty"`               // Inline config (fallback if no CAS)
+	ConfigRef    string                 `json:"config_ref,omitempty"`           // CAS reference for a config too large to inline
+	Config       map[string]interface{} `json:"config,omitempty"`               // Inline config, preferred over ConfigRef
 	Dependencies []string               `json:"dependencies"`
 	Dependents   []string               `json:"dependents"`
 	WaitForAll   bool                   `json:"wait_for_all"` // Join pattern
 	IsTerminal   bool                   `json:"is_terminal"`  // Pre-computed terminal flag
 	Loop         *LoopConfig            `json:"loop,omitempty"`
 	Branch       *BranchConfig          `json:"branch,omitempty"`
+	Retry        *RetryPolicy           `json:"retry,omitempty"`      // Worker-side retry/backoff; also bounds timeout re-dispatches
+	TimeoutMS    int                    `json:"timeout_ms,omitempty"` // Max time in flight before the timeout detector steps in
+	Map          *MapConfig             `json:"map,omitempty"`        // Fan-out over a runtime collection
+	OnError      []string               `json:"on_error,omitempty"`   // Error handlers the node's failure is routed to
 }
 
 // IsExecutableType returns true if this node requires a worker to execute
@@ -87,6 +94,7 @@ func (n *Node) IsExecutableType() bool {
 		"transform": true,
 		"aggregate": true,
 		"filter":    true,
+		"webhook":   true,
 	}
 	return executableTypes[n.Type]
 }
@@ -99,6 +107,19 @@ func (n *Node) IsAbsorber() bool {
 	return hasBranchOrLoop && !n.IsExecutableType()
 }
 
+// IsMap returns true if this node fans out over a collection
+// Map nodes are handled by the coordinator: the body node runs once per element
+// and the map node completes with the joined results.
+func (n *Node) IsMap() bool {
+	return n.Map != nil
+}
+
+// MapConfig defines fan-out behavior for a map node
+type MapConfig struct {
+	Over string `json:"over"` // CEL selector evaluated against the upstream output, e.g. "output.items"
+	Body string `json:"body"` // Node executed once per element
+}
+
 // LoopConfig defines loop behavior for a node
 type LoopConfig struct {
 	Enabled       bool       `json:"enabled"`
@@ -139,6 +160,31 @@ type IR struct {
 	Version  string                 `json:"version"`
 	Nodes    map[string]*Node       `json:"nodes"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Warnings []string               `json:"warnings,omitempty"` // Non-fatal compile findings
+}
+
+// MetadataAllowRuntimePatch is the workflow metadata flag that controls mid-run patching
+const MetadataAllowRuntimePatch = "allow_runtime_patch"
+
+// MetadataResultMapping is the workflow metadata key holding the run result mapping
+const MetadataResultMapping = "result_mapping"
+
+// MetadataUnreachableNodes is the workflow metadata key that controls how the
+// compiler treats nodes no entry node can reach: "error" (default) or "warn"
+const MetadataUnreachableNodes = "unreachable_nodes"
+
+// WarnsOnUnreachableNodes reports whether unreachable nodes are compile warnings
+// rather than errors
+func (ir *IR) WarnsOnUnreachableNodes() bool {
+	mode, _ := ir.Metadata[MetadataUnreachableNodes].(string)
+	return mode == "warn"
+}
+
+// AllowsRuntimePatch reports whether the run may be patched while in flight
+// Patching is allowed unless the workflow metadata sets allow_runtime_patch to false.
+func (ir *IR) AllowsRuntimePatch() bool {
+	allowed, ok := ir.Metadata[MetadataAllowRuntimePatch].(bool)
+	return !ok || allowed
 }
 
 // ApplyDeltaResult holds the result from the Lua script
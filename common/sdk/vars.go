@@ -0,0 +1,111 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Run variables are a run-scoped key/value store for state shared between
+// nodes that are not connected by edges (e.g. an accumulator updated by
+// parallel branches). They are readable from CEL conditions as `vars.<name>`.
+// Nodes write them through their completion signal (see worker.VarWrites): the
+// coordinator applies a completed node's writes before routing its dependents.
+//
+// Consistency semantics:
+//   - Each SetVar/IncrVar is atomic on its own (single HSET / HINCRBY).
+//   - IncrVar is safe under concurrent writers; SetVar is last-write-wins.
+//   - There is no read-modify-write transaction across variables. Use IncrVar
+//     for counters instead of GetVar followed by SetVar.
+//   - A condition sees the writes of every node it depends on. Writes from
+//     branches that are still running may or may not be visible, so only read
+//     vars written by nodes the condition depends on.
+//   - Variables expire together with the run state (VarsTTL after last write).
+
+// VarsTTL is how long run variables live after the last write
+const VarsTTL = 24 * time.Hour
+
+// VarsKey returns the run variable hash key for a run
+func VarsKey(runID string) string {
+	return fmt.Sprintf("vars:{%s}", runID)
+}
+
+// SetVar stores a JSON-serializable value under name (last write wins)
+func (s *SDK) SetVar(ctx context.Context, runID, name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal var %s: %w", name, err)
+	}
+
+	key := VarsKey(runID)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, name, string(data))
+	pipe.Expire(ctx, key, VarsTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set var %s: %w", name, err)
+	}
+
+	s.logger.Debug("run var set", "run_id", runID, "name", name)
+	return nil
+}
+
+// GetVar returns the value of a variable, or nil if it is not set
+func (s *SDK) GetVar(ctx context.Context, runID, name string) (interface{}, error) {
+	raw, err := s.redis.HGet(ctx, VarsKey(runID), name).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get var %s: %w", name, err)
+	}
+
+	return decodeVar(name, raw)
+}
+
+// IncrVar atomically adds delta to an integer variable and returns the new value
+// A missing variable is treated as 0.
+func (s *SDK) IncrVar(ctx context.Context, runID, name string, delta int64) (int64, error) {
+	key := VarsKey(runID)
+	pipe := s.redis.TxPipeline()
+	incr := pipe.HIncrBy(ctx, key, name, delta)
+	pipe.Expire(ctx, key, VarsTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment var %s: %w", name, err)
+	}
+
+	value := incr.Val()
+	s.logger.Debug("run var incremented", "run_id", runID, "name", name, "value", value)
+	return value, nil
+}
+
+// GetVars returns all variables for a run (empty map if none)
+func (s *SDK) GetVars(ctx context.Context, runID string) (map[string]interface{}, error) {
+	raw, err := s.redis.HGetAll(ctx, VarsKey(runID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vars: %w", err)
+	}
+
+	vars := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		decoded, err := decodeVar(name, value)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = decoded
+	}
+
+	return vars, nil
+}
+
+// decodeVar parses a stored JSON value
+// Counters written by HINCRBY are plain integers, which are valid JSON numbers.
+func decodeVar(name, raw string) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, fmt.Errorf("failed to decode var %s: %w", name, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,128 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Webhook streams shared by the orchestrator and the webhook worker
+const (
+	WebhookTaskStream     = "wf.tasks.webhook"
+	WebhookCallbackStream = "wf.tasks.webhook.callbacks"
+)
+
+// Webhook pending statuses
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusCompleted = "completed"
+	WebhookStatusFailed    = "failed"
+)
+
+// WebhookPendingTTL is how long an async webhook's record is kept
+// Callback timeouts are capped to it, so the record outlives the deadline.
+const WebhookPendingTTL = 24 * time.Hour
+
+// DefaultWebhookCallbackTimeout is how long an async webhook waits for its
+// callback when the node sets no callback_timeout_ms
+const DefaultWebhookCallbackTimeout = time.Hour
+
+// WebhookDeadlinesKey is the sorted set of pending webhook keys by callback deadline
+// Scored by unix milliseconds; the webhook worker fails the nodes whose
+// callback hasn't arrived by then.
+const WebhookDeadlinesKey = "webhook:deadlines"
+
+// WebhookPendingKey returns the key of an async webhook waiting for its callback
+func WebhookPendingKey(runID, nodeID string) string {
+	return fmt.Sprintf("webhook:pending:%s:%s", runID, nodeID)
+}
+
+// WebhookPending is an async webhook node suspended until its callback arrives
+// CallbackToken is the secret embedded in the callback URL; only callers
+// presenting it may complete the node.
+type WebhookPending struct {
+	RunID         string `json:"run_id"`
+	NodeID        string `json:"node_id"`
+	TokenID       string `json:"token_id"`
+	CallbackToken string `json:"callback_token"`
+	URL           string `json:"url"`
+	Status        string `json:"status"`
+	CreatedAt     int64  `json:"created_at"`
+	DeadlineAt    int64  `json:"deadline_at,omitempty"` // Unix seconds; the node fails if no callback arrived by then
+	ProcessedAt   int64  `json:"processed_at,omitempty"`
+}
+
+// WebhookCallback is the result an external system delivered for an async webhook
+type WebhookCallback struct {
+	RunID  string                 `json:"run_id"`
+	NodeID string                 `json:"node_id"`
+	Status string                 `json:"status"` // "completed" or "failed"
+	Result map[string]interface{} `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// HasPendingWebhook returns true if any async webhook of the run still waits for its callback
+// Pending webhooks are found through the run's key set (see RunKeysKey).
+// Processed webhooks keep their record, with a completed or failed status, so
+// each record's status is checked.
+func HasPendingWebhook(ctx context.Context, rdb redis.UniversalClient, runID string) (bool, error) {
+	keys, err := rdb.SMembers(ctx, RunKeysKey(runID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to load run keys: %w", err)
+	}
+	prefix := fmt.Sprintf("webhook:pending:%s:", runID)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		data, err := rdb.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get pending webhook: %w", err)
+		}
+
+		var pending WebhookPending
+		if err := json.Unmarshal([]byte(data), &pending); err != nil {
+			return false, fmt.Errorf("failed to unmarshal pending webhook: %w", err)
+		}
+		if pending.Status == WebhookStatusPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// TrackWebhookDeadline records when a pending webhook's callback is due
+func TrackWebhookDeadline(ctx context.Context, rdb redis.UniversalClient, pendingKey string, deadline time.Time) error {
+	if err := rdb.ZAdd(ctx, WebhookDeadlinesKey, redis.Z{Score: float64(deadline.UnixMilli()), Member: pendingKey}).Err(); err != nil {
+		return fmt.Errorf("failed to track webhook deadline: %w", err)
+	}
+	return nil
+}
+
+// ExpiredWebhooks returns the pending webhook keys whose callback was due before now
+func ExpiredWebhooks(ctx context.Context, rdb redis.UniversalClient, now time.Time) ([]string, error) {
+	keys, err := rdb.ZRangeByScore(ctx, WebhookDeadlinesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired webhooks: %w", err)
+	}
+	return keys, nil
+}
+
+// ForgetWebhookDeadline stops tracking a webhook's deadline, once its callback is handled
+func ForgetWebhookDeadline(ctx context.Context, rdb redis.UniversalClient, pendingKey string) error {
+	if err := rdb.ZRem(ctx, WebhookDeadlinesKey, pendingKey).Err(); err != nil {
+		return fmt.Errorf("failed to forget webhook deadline: %w", err)
+	}
+	return nil
+}
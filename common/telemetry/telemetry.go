@@ -7,36 +7,78 @@ import (
 	_ "net/http/pprof"
 	"time"
 
+	"github.com/lyzr/orchestrator/common/config"
 	"github.com/lyzr/orchestrator/common/logger"
+	"github.com/lyzr/orchestrator/common/metrics"
+	"github.com/lyzr/orchestrator/common/tracing"
 )
 
 // Telemetry holds observability components
 type Telemetry struct {
-	log        *logger.Logger
-	pprofAddr  string
-	metricsAddr string
+	log         *logger.Logger
+	pprofAddr   string // Empty when pprof is disabled
+	metricsAddr string // Empty when metrics are disabled
 }
 
 // New creates telemetry components
-func New(pprofPort, metricsPort int, log *logger.Logger) *Telemetry {
-	return &Telemetry{
-		log:         log,
-		pprofAddr:   fmt.Sprintf("localhost:%d", pprofPort),
-		metricsAddr: fmt.Sprintf("localhost:%d", metricsPort),
+// pprof stays on localhost; /metrics listens on all interfaces so Prometheus
+// can scrape it from outside the container.
+func New(cfg config.TelemetryConfig, log *logger.Logger) *Telemetry {
+	t := &Telemetry{log: log}
+	if cfg.EnablePprof {
+		t.pprofAddr = fmt.Sprintf("localhost:%d", cfg.PprofPort)
+	}
+	if cfg.EnableMetrics {
+		t.metricsAddr = fmt.Sprintf(":%d", cfg.MetricsPort)
+	}
+	return t
+}
+
+// InitTracing installs the process-wide tracer provider for the configured backend
+// Spans propagate across streams either way; they are only exported with
+// TRACING_BACKEND=otlp. The returned function flushes spans still buffered.
+func InitTracing(ctx context.Context, serviceName string, cfg config.TelemetryConfig, log *logger.Logger) func() error {
+	shutdown, err := tracing.Init(ctx, serviceName, cfg.TracingBackend)
+	if err != nil {
+		log.Warn("tracing disabled", "error", err)
+		return func() error { return nil }
+	}
+	log.Info("tracing enabled", "backend", cfg.TracingBackend)
+
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		return shutdown(ctx)
 	}
 }
 
+// tracingShutdownTimeout bounds how long exporting the last spans may delay shutdown
+const tracingShutdownTimeout = 5 * time.Second
+
 // Start starts telemetry endpoints
 func (t *Telemetry) Start(ctx context.Context) error {
 	// Start pprof server
-	go func() {
-		t.log.Info("pprof server starting", "addr", t.pprofAddr)
-		if err := http.ListenAndServe(t.pprofAddr, nil); err != nil {
-			t.log.Error("pprof server error", "error", err)
-		}
-	}()
-
-	// TODO: Add Prometheus metrics endpoint on metricsAddr
+	if t.pprofAddr != "" {
+		go func() {
+			t.log.Info("pprof server starting", "addr", t.pprofAddr)
+			if err := http.ListenAndServe(t.pprofAddr, nil); err != nil {
+				t.log.Error("pprof server error", "error", err)
+			}
+		}()
+	}
+
+	// Start Prometheus metrics server
+	if t.metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+
+		go func() {
+			t.log.Info("metrics server starting", "addr", t.metricsAddr)
+			if err := http.ListenAndServe(t.metricsAddr, mux); err != nil {
+				t.log.Error("metrics server error", "error", err)
+			}
+		}()
+	}
 
 	return nil
 }
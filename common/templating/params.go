@@ -0,0 +1,99 @@
+package templating
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ParamsRoot is the template root of workflow template parameters: ${params.name}
+const ParamsRoot = "params"
+
+// SubstituteParams resolves the ${params.*} templates in a value, at any depth
+// Parameters are substituted when a workflow is created from a template, so
+// every other template (and every $${...} escape) is left for run time. As at
+// run time, a string that is exactly one template becomes the parameter's
+// value itself, and paths are gjson paths. A missing parameter is ErrMissingPath.
+func SubstituteParams(value interface{}, params map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return substituteParamsString(v, params)
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			item, err := SubstituteParams(item, params)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = item
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			item, err := SubstituteParams(item, params)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = item
+		}
+		return resolved, nil
+	default:
+		return value, nil
+	}
+}
+
+// substituteParamsString resolves the ${params.*} templates in a string
+func substituteParamsString(str string, params map[string]interface{}) (interface{}, error) {
+	if !strings.Contains(str, "${") {
+		return str, nil
+	}
+
+	matches := Pattern.FindAllStringSubmatchIndex(str, -1)
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(str) && !strings.HasPrefix(str, "$$") {
+		if path, ok := paramPath(str[matches[0][2]:matches[0][3]]); ok {
+			return LookupPath(params, path, "template parameters")
+		}
+		return str, nil
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, match := range matches {
+		placeholder := str[match[0]:match[1]]
+		path, ok := paramPath(str[match[2]:match[3]])
+		if strings.HasPrefix(placeholder, "$$") || !ok {
+			continue // Resolved (or unescaped) at run time
+		}
+
+		value, err := LookupPath(params, path, "template parameters")
+		if err != nil {
+			return nil, err
+		}
+		result.WriteString(str[last:match[0]])
+		last = match[1]
+
+		switch v := value.(type) {
+		case string:
+			result.WriteString(v)
+		default:
+			jsonBytes, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal parameter %s: %w", path, err)
+			}
+			result.Write(jsonBytes)
+		}
+	}
+	result.WriteString(str[last:])
+
+	return result.String(), nil
+}
+
+// paramPath returns the path of a ${params.path} expression
+func paramPath(expr string) (string, bool) {
+	root, path, _ := strings.Cut(strings.TrimSpace(expr), ".")
+	if root != ParamsRoot || path == "" {
+		return "", false
+	}
+	return path, true
+}
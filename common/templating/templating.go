@@ -0,0 +1,40 @@
+// Package templating holds the ${...} template syntax shared by the runner,
+// which resolves node configs at run time, and the orchestrator, which
+// substitutes template parameters when a workflow is created from a template.
+package templating
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/tidwall/gjson"
+)
+
+// ErrMissingPath is returned for a template referencing a value that doesn't exist
+var ErrMissingPath = errors.New("template path not found")
+
+// Pattern matches ${expr} templates and their $${expr} escapes
+// The first submatch is the expression.
+var Pattern = regexp.MustCompile(`\$?\$\{([^}]+)\}`)
+
+// LookupPath returns the value at a gjson path of a decoded JSON value
+// An empty path returns the value itself. source names the value in errors.
+func LookupPath(value interface{}, path, source string) (interface{}, error) {
+	if path == "" {
+		return value, nil
+	}
+
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", source, err)
+	}
+
+	result := gjson.GetBytes(valueJSON, path)
+	if !result.Exists() {
+		return nil, fmt.Errorf("%w: field not found: %s in %s", ErrMissingPath, path, source)
+	}
+
+	return result.Value(), nil
+}
@@ -0,0 +1,55 @@
+package templating
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstituteParams(t *testing.T) {
+	params := map[string]interface{}{
+		"channel": "#ops",
+		"limit":   float64(10),
+		"owner":   map[string]interface{}{"email": "ada@example.com"},
+	}
+
+	resolved, err := SubstituteParams(map[string]interface{}{
+		"channel": "${params.channel}",
+		"limit":   "${ params.limit }",
+		"email":   "${params.owner.email}",
+		"subject": "Top ${params.limit} for ${output.customer}",
+		"escaped": "$${params.channel}",
+		"items":   []interface{}{"${params.owner}", "${nodes.fetch.output.url}"},
+	}, params)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"channel": "#ops",
+		"limit":   float64(10),
+		"email":   "ada@example.com",
+		// Run-time templates and escapes are left for the runner
+		"subject": "Top 10 for ${output.customer}",
+		"escaped": "$${params.channel}",
+		"items":   []interface{}{map[string]interface{}{"email": "ada@example.com"}, "${nodes.fetch.output.url}"},
+	}, resolved)
+
+	_, err = SubstituteParams(map[string]interface{}{"to": "x ${params.missing}"}, params)
+	assert.ErrorIs(t, err, ErrMissingPath)
+}
+
+func TestLookupPath(t *testing.T) {
+	value := map[string]interface{}{"order": map[string]interface{}{"items": []interface{}{"a", "b"}}}
+
+	got, err := LookupPath(value, "order.items.1", "upstream output")
+	require.NoError(t, err)
+	assert.Equal(t, "b", got)
+
+	got, err = LookupPath(value, "", "upstream output")
+	require.NoError(t, err)
+	assert.Equal(t, value, got)
+
+	_, err = LookupPath(value, "order.total", "upstream output")
+	assert.ErrorIs(t, err, ErrMissingPath)
+	assert.ErrorContains(t, err, "field not found: order.total in upstream output")
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Recorder keeps finished spans in memory, for tests
+type Recorder struct {
+	exporter *tracetest.InMemoryExporter
+}
+
+// Record installs a tracer provider recording spans in memory
+// The returned function restores the previous provider.
+func Record() (*Recorder, func()) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	return &Recorder{exporter: exporter}, func() { otel.SetTracerProvider(previous) }
+}
+
+// Spans returns the finished spans in the order they ended
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	return r.exporter.GetSpans()
+}
+
+// SpansNamed returns the finished spans with the given name
+func (r *Recorder) SpansNamed(name string) tracetest.SpanStubs {
+	var out tracetest.SpanStubs
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			out = append(out, span)
+		}
+	}
+	return out
+}
+
+// Attribute returns a span attribute's value ("" if unset)
+func Attribute(span tracetest.SpanStub, key string) string {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value.Emit()
+		}
+	}
+	return ""
+}
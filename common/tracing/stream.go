@@ -0,0 +1,20 @@
+package tracing
+
+import "context"
+
+// TraceParentField is the stream message field carrying the publisher's span
+const TraceParentField = "traceparent"
+
+// InjectValues adds the current span to stream message values
+// Values are left untouched when ctx has no span.
+func InjectValues(ctx context.Context, values map[string]interface{}) {
+	if traceParent := TraceParent(ctx); traceParent != "" {
+		values[TraceParentField] = traceParent
+	}
+}
+
+// ExtractValues returns ctx with the publisher's span (if any) as the parent of the next span
+func ExtractValues(ctx context.Context, values map[string]interface{}) context.Context {
+	traceParent, _ := values[TraceParentField].(string)
+	return ContextWithTraceParent(ctx, traceParent)
+}
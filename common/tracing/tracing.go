@@ -0,0 +1,105 @@
+// Package tracing follows a run across process boundaries with OpenTelemetry
+//
+// Spans are propagated in W3C Trace Context format ("traceparent"), so a
+// stream message published by one service and consumed by another ends up in
+// one trace:
+//
+//	orchestrator run.create → run consumer run.start → node.execute (worker)
+//	→ coordinator.route → node.execute → ... → run.status_update
+//
+// Publishers inject the current span into stream message values (see
+// InjectValues); consumers extract it (ExtractValues) and start their span as
+// its child. Spans come from the global OpenTelemetry tracer provider, which
+// Init installs; finished spans are exported over OTLP only when configured.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attribute keys shared by all services
+const (
+	AttrRunID    = "run_id"
+	AttrNodeID   = "node_id"
+	AttrNodeType = "node_type"
+	AttrStream   = "stream"
+)
+
+// instrumentationName names the tracer spans are started with
+const instrumentationName = "github.com/lyzr/orchestrator/common/tracing"
+
+// propagator reads and writes traceparent values
+var propagator = propagation.TraceContext{}
+
+// Init installs the global tracer provider for a TRACING_BACKEND value
+// "otlp" exports spans over OTLP/HTTP, configured by the standard
+// OTEL_EXPORTER_OTLP_* variables; "none" (or "") records spans without
+// exporting them, so trace context still propagates across streams. The
+// returned function flushes and stops the provider.
+func Init(ctx context.Context, serviceName, backend string) (func(context.Context) error, error) {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	}
+
+	switch backend {
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case "", "none":
+	default:
+		return nil, fmt.Errorf("unsupported tracing backend: %s", backend)
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span as a child of the span (or extracted parent) in ctx
+// Attributes are given as key/value pairs.
+func StartSpan(ctx context.Context, name string, keysAndValues ...string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(Attributes(keysAndValues...)...))
+}
+
+// Attributes converts key/value pairs to span attributes
+func Attributes(keysAndValues ...string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(keysAndValues)/2)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		attrs = append(attrs, attribute.String(keysAndValues[i], keysAndValues[i+1]))
+	}
+	return attrs
+}
+
+// HasSpan reports whether ctx carries a span that can be propagated
+func HasSpan(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// TraceParent returns the traceparent of the current span ("" without one)
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get(TraceParentField)
+}
+
+// ContextWithTraceParent makes a traceparent received from another process the parent of the next span
+// Invalid or empty values leave ctx unchanged, so the next span starts a new trace.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{TraceParentField: traceParent})
+}
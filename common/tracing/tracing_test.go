@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	_, restore := Record()
+	defer restore()
+
+	ctx, span := StartSpan(context.Background(), "root")
+	traceParent := TraceParent(ctx)
+	require.NotEmpty(t, traceParent)
+
+	parsed := trace.SpanContextFromContext(ContextWithTraceParent(context.Background(), traceParent))
+	assert.Equal(t, span.SpanContext().TraceID(), parsed.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), parsed.SpanID())
+	assert.True(t, parsed.IsRemote())
+
+	for _, value := range []string{
+		"00-abc-def-01",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-zzzzzzzzzzzzzzzz-01",
+	} {
+		ctx := ContextWithTraceParent(context.Background(), value)
+		assert.False(t, HasSpan(ctx), value)
+	}
+}
+
+func TestSpansNestAndRecord(t *testing.T) {
+	recorder, restore := Record()
+	defer restore()
+
+	ctx, parent := StartSpan(context.Background(), "parent", AttrRunID, "run-1")
+	_, child := StartSpan(ctx, "child", AttrNodeID, "A")
+	child.End()
+	parent.End()
+	parent.End()
+
+	spans := recorder.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, parent.SpanContext(), spans[0].Parent)
+	assert.Equal(t, parent.SpanContext().TraceID(), spans[0].SpanContext.TraceID())
+	assert.NotEqual(t, parent.SpanContext().SpanID(), spans[0].SpanContext.SpanID())
+	assert.False(t, spans[0].Parent.IsRemote())
+	assert.False(t, spans[1].Parent.IsValid())
+	assert.Equal(t, "run-1", Attribute(spans[1], AttrRunID))
+	assert.Equal(t, "A", Attribute(spans[0], AttrNodeID))
+}
+
+func TestExtractValuesIgnoresMalformedParent(t *testing.T) {
+	recorder, restore := Record()
+	defer restore()
+
+	ctx := ExtractValues(context.Background(), map[string]interface{}{TraceParentField: "garbage"})
+	_, span := StartSpan(ctx, "consume")
+	span.End()
+	assert.False(t, recorder.Spans()[0].Parent.IsValid())
+
+	values := map[string]interface{}{"token": "{}"}
+	InjectValues(context.Background(), values)
+	assert.NotContains(t, values, TraceParentField)
+}
+
+func TestInit(t *testing.T) {
+	for _, backend := range []string{"", "none"} {
+		shutdown, err := Init(context.Background(), "test", backend)
+		require.NoError(t, err, backend)
+
+		// Spans get IDs to propagate even though they aren't exported
+		ctx, span := StartSpan(context.Background(), "root")
+		span.End()
+		assert.True(t, HasSpan(ctx), backend)
+		require.NoError(t, shutdown(context.Background()), backend)
+	}
+
+	_, err := Init(context.Background(), "test", "stdout")
+	assert.ErrorContains(t, err, "unsupported tracing backend: stdout")
+}
+
+func TestInitExportsOverOTLP(t *testing.T) {
+	var exports atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			exports.Add(1)
+		}
+	}))
+	defer collector.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", collector.URL)
+
+	shutdown, err := Init(context.Background(), "test", "otlp")
+	require.NoError(t, err)
+	_, span := StartSpan(context.Background(), "root")
+	span.End()
+
+	// Shutdown flushes the batch
+	require.NoError(t, shutdown(context.Background()))
+	assert.Equal(t, int32(1), exports.Load())
+}
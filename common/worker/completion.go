@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/lyzr/orchestrator/common/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,6 +17,7 @@ type CompletionOpts struct {
 	Status     string                 // "completed" or "failed"
 	ResultData map[string]interface{} // Actual result data (coordinator stores in CAS)
 	Metadata   map[string]interface{} // Additional metadata
+	Vars       *VarWrites             // Run variable writes, applied if the node completes (optional)
 }
 
 // Validate checks if all required fields are present
@@ -48,7 +51,7 @@ func (opts *CompletionOpts) Validate() error {
 
 // SignalCompletion sends a completion signal to the coordinator
 // Uses Option B architecture: sends result_data, coordinator stores in CAS
-func SignalCompletion(ctx context.Context, redis *redis.Client, logger sdk.Logger, opts *CompletionOpts) error {
+func SignalCompletion(ctx context.Context, redis redis.UniversalClient, logger sdk.Logger, opts *CompletionOpts) error {
 	// Validate options
 	if err := opts.Validate(); err != nil {
 		return fmt.Errorf("invalid completion opts: %w", err)
@@ -73,6 +76,20 @@ func SignalCompletion(ctx context.Context, redis *redis.Client, logger sdk.Logge
 		signal["metadata"] = opts.Metadata
 	}
 
+	// Run variable writes, applied by the coordinator before routing
+	set, incr := opts.Vars.signalFields()
+	if set != nil {
+		signal["vars"] = set
+	}
+	if incr != nil {
+		signal["var_increments"] = incr
+	}
+
+	// Let the coordinator continue the trace of this node execution
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		signal["traceparent"] = traceParent
+	}
+
 	// Marshal to JSON
 	signalJSON, err := json.Marshal(signal)
 	if err != nil {
@@ -93,3 +110,113 @@ func SignalCompletion(ctx context.Context, redis *redis.Client, logger sdk.Logge
 
 	return nil
 }
+
+// SignalCancellation sends a synthetic "cancelled" completion for a node
+// The coordinator consumes the node's token without routing to its dependents,
+// so the run counter stays balanced while other branches continue.
+// Callers must first claim the node with sdk.CancelNode.
+func SignalCancellation(ctx context.Context, redis redis.UniversalClient, logger sdk.Logger, runID, nodeID string, metadata map[string]interface{}) error {
+	if runID == "" || nodeID == "" {
+		return fmt.Errorf("run ID and node ID are required")
+	}
+
+	signal := map[string]interface{}{
+		"version":  "1.0",
+		"job_id":   fmt.Sprintf("%s-%s-cancelled", runID, nodeID),
+		"run_id":   runID,
+		"node_id":  nodeID,
+		"status":   sdk.NodeStatusCancelled,
+		"metadata": metadata,
+	}
+
+	signalJSON, err := json.Marshal(signal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signal: %w", err)
+	}
+
+	if err := redis.RPush(ctx, "completion_signals", signalJSON).Err(); err != nil {
+		return fmt.Errorf("failed to push cancellation signal: %w", err)
+	}
+
+	logger.Info("signaled cancellation",
+		"run_id", runID,
+		"node_id", nodeID)
+
+	return nil
+}
+
+// SignalRetry asks the coordinator to run a node again within its run
+// The coordinator resets the node and everything downstream of it, then
+// re-emits the node's token using its upstream output from the run context.
+func SignalRetry(ctx context.Context, redis redis.UniversalClient, logger sdk.Logger, runID, nodeID string, metadata map[string]interface{}) error {
+	if runID == "" || nodeID == "" {
+		return fmt.Errorf("run ID and node ID are required")
+	}
+
+	signal := map[string]interface{}{
+		"version":  "1.0",
+		"job_id":   fmt.Sprintf("%s-%s-retry-%d", runID, nodeID, time.Now().UnixNano()),
+		"run_id":   runID,
+		"node_id":  nodeID,
+		"status":   sdk.SignalStatusRetry,
+		"metadata": metadata,
+	}
+
+	signalJSON, err := json.Marshal(signal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signal: %w", err)
+	}
+
+	if err := redis.RPush(ctx, "completion_signals", signalJSON).Err(); err != nil {
+		return fmt.Errorf("failed to push retry signal: %w", err)
+	}
+
+	logger.Info("signaled retry",
+		"run_id", runID,
+		"node_id", nodeID)
+
+	return nil
+}
+
+// SignalTimeout tells the coordinator a dispatched token outlived its node's timeout
+// Callers must first claim the token with sdk.ClaimTimedOut, which makes the
+// coordinator drop the token's own completion should it still arrive. The
+// coordinator either dispatches the node again or fails it.
+func SignalTimeout(ctx context.Context, redis redis.UniversalClient, logger sdk.Logger, token *sdk.InflightToken, timeout time.Duration) error {
+	if token.RunID == "" || token.NodeID == "" || token.JobID == "" {
+		return fmt.Errorf("run ID, node ID and job ID are required")
+	}
+
+	signal := map[string]interface{}{
+		"version": "1.0",
+		"job_id":  token.JobID,
+		"run_id":  token.RunID,
+		"node_id": token.NodeID,
+		"status":  sdk.SignalStatusTimeout,
+		"metadata": map[string]interface{}{
+			"from_node":   token.FromNode,
+			"payload_ref": token.PayloadRef,
+			"sent_at":     token.SentAt,
+			"attempt":     token.Attempt,
+			"timeout_ms":  timeout.Milliseconds(),
+		},
+	}
+
+	signalJSON, err := json.Marshal(signal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signal: %w", err)
+	}
+
+	if err := redis.RPush(ctx, "completion_signals", signalJSON).Err(); err != nil {
+		return fmt.Errorf("failed to push timeout signal: %w", err)
+	}
+
+	logger.Info("signaled timeout",
+		"run_id", token.RunID,
+		"node_id", token.NodeID,
+		"job_id", token.JobID,
+		"attempt", token.Attempt,
+		"timeout", timeout)
+
+	return nil
+}
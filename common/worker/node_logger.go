@@ -0,0 +1,104 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lyzr/orchestrator/common/sdk"
+	"github.com/redis/go-redis/v9"
+)
+
+// NodeLogger logs for one node execution
+// Lines go to the worker's logger, tagged with the run and node, and are also
+// appended to the node's log (see sdk.AppendNodeLog) so users can read them
+// through the run's logs endpoint.
+type NodeLogger struct {
+	redis  redis.UniversalClient
+	logger sdk.Logger
+	runID  string
+	nodeID string
+}
+
+// NewNodeLogger creates a logger for the node execution of token
+func NewNodeLogger(redis redis.UniversalClient, logger sdk.Logger, token *sdk.Token) *NodeLogger {
+	return &NodeLogger{
+		redis:  redis,
+		logger: logger,
+		runID:  token.RunID,
+		nodeID: token.ToNode,
+	}
+}
+
+// Debug logs at debug level
+func (l *NodeLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Debug(msg, l.tagged(keysAndValues)...)
+	l.append(sdk.LogLevelDebug, msg, keysAndValues)
+}
+
+// Info logs at info level
+func (l *NodeLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Info(msg, l.tagged(keysAndValues)...)
+	l.append(sdk.LogLevelInfo, msg, keysAndValues)
+}
+
+// Warn logs at warn level
+func (l *NodeLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.logger.Warn(msg, l.tagged(keysAndValues)...)
+	l.append(sdk.LogLevelWarn, msg, keysAndValues)
+}
+
+// Error logs at error level
+func (l *NodeLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.logger.Error(msg, l.tagged(keysAndValues)...)
+	l.append(sdk.LogLevelError, msg, keysAndValues)
+}
+
+// tagged prefixes keysAndValues with the run and node IDs
+func (l *NodeLogger) tagged(keysAndValues []interface{}) []interface{} {
+	return append([]interface{}{"run_id", l.runID, "node_id", l.nodeID}, keysAndValues...)
+}
+
+// append records a line in the node's log
+// Failures are logged and otherwise ignored: the node's work matters more than its log.
+func (l *NodeLogger) append(level, msg string, keysAndValues []interface{}) {
+	entry := &sdk.NodeLogEntry{
+		Level:     level,
+		Message:   msg,
+		Fields:    logFields(keysAndValues),
+		Timestamp: time.Now().UTC(),
+	}
+
+	// Detached from the execution's context, so lines logged as it is cancelled are kept
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := sdk.AppendNodeLog(ctx, l.redis, l.runID, l.nodeID, entry); err != nil {
+		l.logger.Warn("failed to append node log",
+			"run_id", l.runID,
+			"node_id", l.nodeID,
+			"error", err)
+	}
+}
+
+// logFields turns alternating keys and values into a map
+// Errors are stored as their message; a trailing key without a value is kept
+// with a nil value.
+func logFields(keysAndValues []interface{}) map[string]interface{} {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		fields[key] = value
+	}
+	return fields
+}
@@ -0,0 +1,65 @@
+package worker
+
+import "sync"
+
+// VarWrites collects the run variable writes a node makes (see sdk.SetVar)
+// They travel on the node's completion signal, and the coordinator applies them
+// before routing its dependents, so their conditions see them. Writes of a node
+// that fails or is cancelled are dropped. A nil VarWrites drops every write,
+// for handlers run outside of a run.
+type VarWrites struct {
+	mu   sync.Mutex
+	set  map[string]interface{}
+	incr map[string]int64
+}
+
+// NewVarWrites creates an empty set of writes
+func NewVarWrites() *VarWrites {
+	return &VarWrites{
+		set:  make(map[string]interface{}),
+		incr: make(map[string]int64),
+	}
+}
+
+// Set stores a JSON-serializable value under name (last write wins across nodes)
+func (v *VarWrites) Set(name string, value interface{}) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.set[name] = value
+}
+
+// Incr adds delta to an integer variable
+// Safe across parallel nodes: each node's delta is added atomically.
+func (v *VarWrites) Incr(name string, delta int64) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.incr[name] += delta
+}
+
+// signalFields returns the writes as completion signal fields
+func (v *VarWrites) signalFields() (set map[string]interface{}, incr map[string]int64) {
+	if v == nil {
+		return nil, nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.set) > 0 {
+		set = make(map[string]interface{}, len(v.set))
+		for name, value := range v.set {
+			set[name] = value
+		}
+	}
+	if len(v.incr) > 0 {
+		incr = make(map[string]int64, len(v.incr))
+		for name, delta := range v.incr {
+			incr[name] = delta
+		}
+	}
+	return set, incr
+}
@@ -51,8 +51,7 @@ export const mockWorkflows = {
                 type: "transform",
                 config: {
                   name: "Extract Key Insights",
-                  operation: "map",
-                  field: "insights"
+                  mapping: { insights: "output.insights" }
                 }
               }
             ],
@@ -112,8 +111,7 @@ export const mockWorkflows = {
                 type: "transform",
                 config: {
                   name: "Extract Key Insights",
-                  operation: "map",
-                  field: "insights"
+                  mapping: { insights: "output.insights" }
                 }
               }
             ],
@@ -170,7 +168,7 @@ export const mockWorkflows = {
                 type: "aggregate",
                 config: {
                   name: "Aggregate Analysis",
-                  operation: "merge"
+                  strategy: "merge"
                 }
               }
             ],
@@ -289,8 +287,7 @@ export const mockWorkflows = {
                 type: "transform",
                 config: {
                   name: "Transform Records",
-                  operation: "map",
-                  field: "records"
+                  mapping: { records: "output.records" }
                 }
               },
               {
@@ -298,7 +295,7 @@ export const mockWorkflows = {
                 type: "filter",
                 config: {
                   name: "Filter Valid Records",
-                  condition: "valid === true"
+                  condition: "output.valid == true"
                 }
               },
               {
@@ -306,7 +303,7 @@ export const mockWorkflows = {
                 type: "aggregate",
                 config: {
                   name: "Combine Results",
-                  operation: "merge"
+                  strategy: "merge"
                 }
               }
             ],
@@ -1,4 +1,5 @@
 import { useEffect, useRef, useState, useCallback } from 'react';
+import { getWebSocketToken } from '../services/api';
 
 const FANOUT_WS_URL = import.meta.env.VITE_FANOUT_WS_URL || 'ws://localhost:8084';
 const RECONNECT_DELAY = 3000; // 3 seconds
@@ -20,6 +21,7 @@ export function useWorkflowWebSocket(username, onEvent) {
   const reconnectTimeoutRef = useRef(null);
   const shouldReconnect = useRef(true);
   const isConnecting = useRef(false); // Guard against duplicate connections
+  const lastEventId = useRef(null); // Replay cursor for missed events on reconnect
 
   // Store onEvent callback in a ref to avoid re-creating connect function
   const onEventRef = useRef(onEvent);
@@ -27,7 +29,7 @@ export function useWorkflowWebSocket(username, onEvent) {
     onEventRef.current = onEvent;
   }, [onEvent]);
 
-  const connect = useCallback(() => {
+  const connect = useCallback(async () => {
     if (!username) {
       console.warn('useWorkflowWebSocket: No username provided, skipping connection');
       return;
@@ -48,8 +50,13 @@ export function useWorkflowWebSocket(username, onEvent) {
     }
 
     try {
-      const wsUrl = `${FANOUT_WS_URL}/ws?username=${encodeURIComponent(username)}`;
-      console.log('[WebSocket] Connecting to:', wsUrl);
+      // Fanout requires a token issued by the orchestrator for this user
+      const token = await getWebSocketToken();
+      let wsUrl = `${FANOUT_WS_URL}/ws?token=${encodeURIComponent(token)}`;
+      if (lastEventId.current) {
+        wsUrl += `&last_event_id=${encodeURIComponent(lastEventId.current)}`;
+      }
+      console.log('[WebSocket] Connecting to:', FANOUT_WS_URL);
 
       const ws = new WebSocket(wsUrl);
       wsRef.current = ws;
@@ -65,6 +72,9 @@ export function useWorkflowWebSocket(username, onEvent) {
       ws.onmessage = (event) => {
         try {
           const data = JSON.parse(event.data);
+          if (data.event_id) {
+            lastEventId.current = data.event_id;
+          }
           console.log('[WebSocket] Event received:', data);
 
           // Use the ref to get the latest callback without causing re-renders
@@ -251,7 +251,7 @@ export default function RunDetail() {
             </Button>
             <Heading size="lg">Run Details</Heading>
           </HStack>
-          {details.run && <StatusBadge status={details.run.status} />}
+          {details.run && <StatusBadge status={details.derived_status?.status || details.run.status} />}
         </HStack>
 
         {/* Run Info */}
@@ -165,6 +165,27 @@ export async function getRunDetails(runId) {
   return await apiRequest(`/runs/${runId}/details`);
 }
 
+/**
+ * Get the compact status of many runs in one request
+ * @param {Array<string>} runIds - Run IDs (at most 100)
+ * @returns {Promise<Object>} Map of run ID to {status, completed_nodes, total_nodes}
+ */
+export async function getRunStatuses(runIds) {
+  return await apiRequest('/runs/status', {
+    method: 'POST',
+    body: JSON.stringify({ run_ids: runIds }),
+  });
+}
+
+/**
+ * Get a short-lived token for the fanout WebSocket
+ * @returns {Promise<string>} Token bound to the current user
+ */
+export async function getWebSocketToken() {
+  const data = await apiRequest('/auth/ws-token', { method: 'POST' });
+  return data.token;
+}
+
 export default {
   listWorkflows,
   getWorkflow,
@@ -175,4 +196,6 @@ export default {
   runWorkflow,
   listWorkflowRuns,
   getRunDetails,
+  getRunStatuses,
+  getWebSocketToken,
 };
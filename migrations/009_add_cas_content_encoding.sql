@@ -0,0 +1,12 @@
+-- Migration: Add content_encoding to cas_blob
+-- Description: Large blobs may be stored compressed; the encoding tells readers how to decode them.
+-- cas_id and size_bytes keep describing the uncompressed content, so dedup is unaffected.
+
+ALTER TABLE cas_blob
+ADD COLUMN content_encoding TEXT NOT NULL DEFAULT 'identity';
+
+ALTER TABLE cas_blob
+ADD CONSTRAINT cas_blob_content_encoding_check
+    CHECK (content_encoding IN ('identity', 'gzip'));
+
+COMMENT ON COLUMN cas_blob.content_encoding IS 'Encoding of content as stored (identity or gzip)';
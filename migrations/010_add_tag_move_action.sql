@@ -0,0 +1,17 @@
+-- Migration: Record undo/redo in tag_move
+-- Description: Tag moves are replayed to build the undo and redo stacks, so each row
+-- records whether it was a regular move, an undo or a redo, plus the hash of the
+-- previous target so an undo can restore it exactly.
+
+ALTER TABLE tag_move
+ADD COLUMN action TEXT NOT NULL DEFAULT 'move';
+
+ALTER TABLE tag_move
+ADD CONSTRAINT tag_move_action_check
+    CHECK (action IN ('move', 'undo', 'redo'));
+
+ALTER TABLE tag_move
+ADD COLUMN from_hash TEXT;
+
+COMMENT ON COLUMN tag_move.action IS 'move, undo or redo';
+COMMENT ON COLUMN tag_move.from_hash IS 'Target hash of the previous target';
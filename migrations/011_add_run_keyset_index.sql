@@ -0,0 +1,8 @@
+-- Migration: Keyset index for run listing
+-- Description: Run listings page with a (submitted_at, run_id) cursor per user, so the
+-- index covers the full sort key and each page is a single index range scan.
+
+CREATE INDEX IF NOT EXISTS idx_run_submitted_by_keyset
+    ON run(submitted_by, submitted_at DESC, run_id DESC);
+
+COMMENT ON INDEX idx_run_submitted_by_keyset IS 'Keyset pagination of runs by user (submitted_at, run_id)';
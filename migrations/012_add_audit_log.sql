@@ -0,0 +1,36 @@
+-- Migration: Audit log of workflow, tag and run mutations
+-- Description: Append-only record of who created, patched, deleted or ran what.
+-- Rows are only ever inserted; tag_move remains the source of truth for undo/redo.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Who did it (X-User-ID of the request)
+    actor TEXT NOT NULL,
+
+    -- What they did, e.g. 'workflow.patch' or 'run.create'
+    action TEXT NOT NULL,
+
+    -- What it was done to
+    target_kind TEXT NOT NULL CHECK (target_kind IN ('tag', 'artifact', 'run')),
+    target_id TEXT NOT NULL,
+
+    -- The workflow tag involved, if any
+    tag_owner TEXT,
+    tag_name TEXT,
+
+    request_id TEXT,
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Listings are newest first, optionally filtered by actor or tag
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_tag ON audit_log(tag_name, id DESC)
+    WHERE tag_name IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+
+COMMENT ON TABLE audit_log IS 'Append-only audit trail of workflow, tag and run mutations';
+COMMENT ON COLUMN audit_log.target_kind IS 'tag, artifact or run';
+COMMENT ON COLUMN audit_log.target_id IS 'owner/name for tags, the UUID for artifacts and runs';
+COMMENT ON COLUMN audit_log.request_id IS 'X-Request-ID of the request that made the change';
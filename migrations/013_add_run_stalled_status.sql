@@ -0,0 +1,8 @@
+-- Migration: Stalled run status
+-- Description: The workflow runner's stall detector can mark runs whose token counter
+-- hasn't changed for too long as STALLED, so the status check allows it.
+
+ALTER TABLE run DROP CONSTRAINT IF EXISTS run_status_check;
+
+ALTER TABLE run ADD CONSTRAINT run_status_check
+    CHECK (status IN ('QUEUED', 'RUNNING', 'COMPLETED', 'FAILED', 'CANCELLED', 'STALLED'));
@@ -0,0 +1,9 @@
+-- Migration: Add signature to cas_blob
+-- Description: With CAS_SIGNING_KEY set, each blob is stored with an HMAC-SHA256 of its
+-- CAS ID and content, and reads fail closed on a missing or wrong signature.
+-- Blobs stored before signing was enabled have no signature.
+
+ALTER TABLE cas_blob
+ADD COLUMN signature TEXT;
+
+COMMENT ON COLUMN cas_blob.signature IS 'HMAC-SHA256 of cas_id and content (NULL if unsigned)';
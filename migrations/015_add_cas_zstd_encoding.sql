@@ -0,0 +1,12 @@
+-- Migration: Allow zstd as a cas_blob content_encoding
+-- Description: CAS_COMPRESSION=zstd stores large blobs zstd-compressed; the encoding
+-- column records the codec, so readers never infer it from the content.
+
+ALTER TABLE cas_blob
+DROP CONSTRAINT cas_blob_content_encoding_check;
+
+ALTER TABLE cas_blob
+ADD CONSTRAINT cas_blob_content_encoding_check
+    CHECK (content_encoding IN ('identity', 'gzip', 'zstd'));
+
+COMMENT ON COLUMN cas_blob.content_encoding IS 'Encoding of content as stored (identity, gzip or zstd)';
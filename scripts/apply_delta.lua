@@ -6,12 +6,17 @@
 --   - Idempotent: Each op_key can only be applied once
 --   - Atomic: Counter update + applied set update in single operation
 --   - Event-driven: Publishes to completion_events when counter hits 0
+--   - Sliding TTL: Every applied operation renews both keys' TTL, so a run
+--     that keeps progressing never loses its counter
 --
 -- Usage:
---   EVAL script 3 applied_set_key counter_key run_id op_key delta
+--   EVAL script 2 applied_set_key counter_key op_key delta run_id ttl_ms
 --
 -- Example:
---   EVAL "..." 3 applied:run_123 counter:run_123 run_123 consume:run_123:A->B -1
+--   EVAL "..." 2 applied:{run_123} counter:{run_123} consume:run_123:A->B -1 run_123 86400000
+--
+-- Cluster mode: both keys are hash-tagged with the run ID so they live in the
+-- same slot. run_id is an ARGV (not a KEY) because it is not a Redis key.
 --
 -- Returns:
 --   [new_counter_value, changed, hit_zero]
@@ -20,11 +25,12 @@
 --   - hit_zero: 1 if counter reached 0 after this operation, 0 otherwise
 -- ============================================================================
 
-local applied_set = KEYS[1]       -- "applied:run_123"
-local counter_key = KEYS[2]       -- "counter:run_123"
-local run_id = KEYS[3]            -- "run_123" (for publishing)
+local applied_set = KEYS[1]       -- "applied:{run_123}"
+local counter_key = KEYS[2]       -- "counter:{run_123}"
 local op_key = ARGV[1]            -- "consume:run_123:A->B" or "emit:run_123:A:uuid"
 local delta = tonumber(ARGV[2])   -- -1 for consume, +N for emit
+local run_id = ARGV[3]            -- "run_123" (for publishing)
+local ttl_ms = tonumber(ARGV[4])  -- TTL of both keys (see sdk.RunStateTTL)
 
 -- 1. Check idempotency: Has this operation already been applied?
 if redis.call('SISMEMBER', applied_set, op_key) == 1 then
@@ -43,7 +49,11 @@ redis.call('SADD', applied_set, op_key)
 -- 3. Update counter
 local new_value = redis.call('INCRBY', counter_key, delta)
 
--- 4. Check if counter hit zero and publish completion event
+-- 4. Renew both keys: the applied set expires with the counter
+redis.call('PEXPIRE', counter_key, ttl_ms)
+redis.call('PEXPIRE', applied_set, ttl_ms)
+
+-- 5. Check if counter hit zero and publish completion event
 if new_value == 0 then
     -- Publish to completion_events channel
     -- Supervisor listens to this channel for event-driven completion